@@ -0,0 +1,35 @@
+package main
+
+import (
+	"github.com/cane/research-institute-system/backend/models"
+)
+
+// Analytics Methods
+
+// GetDashboardStats returns the dashboard statistics
+func (a *App) GetDashboardStats() (models.DashboardStats, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return models.DashboardStats{}, err
+	}
+
+	if a.analyticsService == nil {
+		return models.DashboardStats{}, errNotConnected
+	}
+
+	return a.analyticsService.GetDashboardStats(ctx)
+}
+
+// GetActivityLogs returns the most recent activity log entries
+func (a *App) GetActivityLogs(limit int) ([]models.LogAktivnosti, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return nil, err
+	}
+
+	if a.analyticsService == nil {
+		return nil, errNotConnected
+	}
+
+	return a.analyticsService.GetActivityLogs(ctx, limit)
+}
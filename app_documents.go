@@ -0,0 +1,203 @@
+package main
+
+import (
+	"github.com/cane/research-institute-system/backend/models"
+)
+
+// Document Management Methods
+
+// GetAllDocuments returns all documents
+func (a *App) GetAllDocuments() ([]models.Dokumenti, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return nil, err
+	}
+
+	if a.documentService == nil {
+		return nil, errNotConnected
+	}
+
+	return a.documentService.GetAllDocuments(ctx)
+}
+
+// GetDocumentsByProject returns the documents of a project
+func (a *App) GetDocumentsByProject(projectID int) ([]models.Dokumenti, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return nil, err
+	}
+
+	if a.documentService == nil {
+		return nil, errNotConnected
+	}
+
+	return a.documentService.GetDocumentsByProject(ctx, projectID)
+}
+
+// GetDocumentByID returns a specific document by ID
+func (a *App) GetDocumentByID(documentID int) (models.Dokumenti, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return models.Dokumenti{}, err
+	}
+
+	if a.documentService == nil {
+		return models.Dokumenti{}, errNotConnected
+	}
+
+	return a.documentService.GetDocumentByID(ctx, documentID)
+}
+
+// GetDocumentVersions returns all versions of a document
+func (a *App) GetDocumentVersions(documentID int) ([]models.VerzijeDokumenata, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return nil, err
+	}
+
+	if a.documentService == nil {
+		return nil, errNotConnected
+	}
+
+	return a.documentService.GetDocumentVersions(ctx, documentID)
+}
+
+// GetDocumentTags returns all tags for a document
+func (a *App) GetDocumentTags(documentID int) ([]models.Tagovi, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return nil, err
+	}
+
+	if a.documentService == nil {
+		return nil, errNotConnected
+	}
+
+	return a.documentService.GetDocumentTags(ctx, documentID)
+}
+
+// UploadDocument uploads a new document
+func (a *App) UploadDocument(req models.UploadDocumentRequest, fileData []byte, fileName string) error {
+	ctx, err := a.callContext()
+	if err != nil {
+		return err
+	}
+
+	if a.documentService == nil {
+		return errNotConnected
+	}
+
+	return a.documentService.UploadDocument(ctx, req, fileData, fileName)
+}
+
+// UpdateDocument updates an existing document
+func (a *App) UpdateDocument(documentID int, req models.UploadDocumentRequest) error {
+	ctx, err := a.callContext()
+	if err != nil {
+		return err
+	}
+
+	if a.documentService == nil {
+		return errNotConnected
+	}
+
+	return a.documentService.UpdateDocument(ctx, documentID, req)
+}
+
+// DeleteDocument deletes a document
+func (a *App) DeleteDocument(documentID int) error {
+	ctx, err := a.callContext()
+	if err != nil {
+		return err
+	}
+
+	if a.documentService == nil {
+		return errNotConnected
+	}
+
+	return a.documentService.DeleteDocument(ctx, documentID)
+}
+
+// AddDocumentTag adds a tag to a document, creating the tag if needed
+func (a *App) AddDocumentTag(documentID int, tagName string) error {
+	ctx, err := a.callContext()
+	if err != nil {
+		return err
+	}
+
+	if a.documentService == nil {
+		return errNotConnected
+	}
+
+	return a.documentService.AddDocumentTag(ctx, documentID, tagName)
+}
+
+// RemoveDocumentTag removes a tag from a document
+func (a *App) RemoveDocumentTag(documentID, tagID int) error {
+	ctx, err := a.callContext()
+	if err != nil {
+		return err
+	}
+
+	if a.documentService == nil {
+		return errNotConnected
+	}
+
+	return a.documentService.RemoveDocumentTag(ctx, documentID, tagID)
+}
+
+// GetDocumentMetadata returns the metadata entries of a document
+func (a *App) GetDocumentMetadata(documentID int) ([]models.MetaPodaci, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return nil, err
+	}
+
+	if a.documentService == nil {
+		return nil, errNotConnected
+	}
+
+	return a.documentService.GetDocumentMetadata(ctx, documentID)
+}
+
+// UpdateDocumentMetadata replaces the metadata entries of a document
+func (a *App) UpdateDocumentMetadata(documentID int, metadata []models.MetaPodaci) error {
+	ctx, err := a.callContext()
+	if err != nil {
+		return err
+	}
+
+	if a.documentService == nil {
+		return errNotConnected
+	}
+
+	return a.documentService.UpdateDocumentMetadata(ctx, documentID, metadata)
+}
+
+// GetMyFolders returns the folders owned by the current user
+func (a *App) GetMyFolders() ([]models.Folderi, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return nil, err
+	}
+
+	if a.documentService == nil {
+		return nil, errNotConnected
+	}
+
+	return a.documentService.GetAllFolders(ctx)
+}
+
+// CreateFolder creates a new folder
+func (a *App) CreateFolder(folder models.Folderi) error {
+	ctx, err := a.callContext()
+	if err != nil {
+		return err
+	}
+
+	if a.documentService == nil {
+		return errNotConnected
+	}
+
+	return a.documentService.CreateFolder(ctx, folder)
+}
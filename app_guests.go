@@ -0,0 +1,73 @@
+package main
+
+import (
+	"time"
+
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/services"
+)
+
+// Guest Account Methods
+
+// CreateGuest creates a guest account for an external partner that ends at
+// req.Istice and returns its one-time activation code
+func (a *App) CreateGuest(req services.GuestRequest) (*services.ActivationCode, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return nil, err
+	}
+
+	return a.guestService.CreateGuest(ctx, req)
+}
+
+// ExtendGuest moves the end of a guest account
+func (a *App) ExtendGuest(userID int, until time.Time) error {
+	ctx, err := a.callContext()
+	if err != nil {
+		return err
+	}
+
+	return a.guestService.ExtendGuest(ctx, userID, until)
+}
+
+// ShareWithGuest shares one project, folder or document with a guest
+func (a *App) ShareWithGuest(userID int, req services.GuestShareRequest) (*models.GuestShare, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return nil, err
+	}
+
+	return a.guestService.ShareWithGuest(ctx, userID, req)
+}
+
+// RevokeGuestShare takes back one share from a guest
+func (a *App) RevokeGuestShare(shareID int) error {
+	ctx, err := a.callContext()
+	if err != nil {
+		return err
+	}
+
+	return a.guestService.RevokeGuestShare(ctx, shareID)
+}
+
+// GetGuestAccess returns every active guest account with what is shared
+// with it, for the administrators' overview
+func (a *App) GetGuestAccess() ([]services.GuestOverview, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return nil, err
+	}
+
+	return a.guestService.GetGuestAccess(ctx)
+}
+
+// GetSharedWithMe returns the projects and documents shared with the
+// signed-in guest
+func (a *App) GetSharedWithMe() (*services.SharedWithGuest, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return nil, err
+	}
+
+	return a.guestService.GetSharedWithMe(ctx)
+}
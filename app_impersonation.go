@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"runtime"
+	"strings"
+	"unicode"
+
+	"github.com/cane/research-institute-system/backend/services"
+)
+
+// Impersonation Methods
+
+// ImpersonateUser lets the current administrator act as another user in
+// this window until StopImpersonation or the end of auth.impersonation_ttl.
+// The administrator's own session is kept for when it ends
+func (a *App) ImpersonateUser(userID int, reason string) (*services.LoginResponse, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := a.authService.StartImpersonation(ctx, userID, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	a.adminToken, a.sessionToken = a.sessionToken, response.Token
+	a.mu.Unlock()
+
+	return response, nil
+}
+
+// StopImpersonation ends acting as another user and returns this window to
+// the administrator's own session, also once the impersonation has expired
+func (a *App) StopImpersonation() error {
+	if a.authService == nil {
+		return errNotConnected
+	}
+
+	a.mu.Lock()
+	token, admin := a.sessionToken, a.adminToken
+	if admin == "" {
+		a.mu.Unlock()
+		return errors.New("ne radite u ime drugog korisnika")
+	}
+	a.sessionToken, a.adminToken = admin, ""
+	a.mu.Unlock()
+
+	ctx := a.requestContext()
+	principal, err := a.authService.Authenticate(ctx, token)
+	if err != nil {
+		a.authService.Logout(token)
+		return nil
+	}
+
+	return a.authService.StopImpersonation(services.WithPrincipal(ctx, principal), token)
+}
+
+// GetImpersonation returns the administrator acting as the current user in
+// this window, or nil, so the frontend can show it at all times
+func (a *App) GetImpersonation() (*services.Impersonation, error) {
+	if a.authService == nil {
+		return nil, errNotConnected
+	}
+
+	principal, err := a.authService.Authenticate(a.requestContext(), a.currentToken())
+	if err != nil {
+		return nil, err
+	}
+
+	return principal.Impersonation, nil
+}
+
+// boundMethod names the App method the frontend called, the outermost
+// exported App method on the stack, for the activity log
+func boundMethod() string {
+	pc := make([]uintptr, 16)
+	frames := runtime.CallersFrames(pc[:runtime.Callers(2, pc)])
+
+	method := ""
+	for {
+		frame, more := frames.Next()
+		if name, ok := strings.CutPrefix(frame.Function, "main.(*App)."); ok && name != "" && unicode.IsUpper(rune(name[0])) {
+			method = name
+		}
+		if !more {
+			break
+		}
+	}
+	return method
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/services"
+)
+
+// Profile Methods
+
+// GetMyProfile returns the current user with preferences and the email
+// change waiting for confirmation
+func (a *App) GetMyProfile() (*services.Profile, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return nil, err
+	}
+
+	if a.profileService == nil {
+		return nil, errNotConnected
+	}
+
+	return a.profileService.GetProfile(ctx)
+}
+
+// UpdateMyProfile changes the first and last name of the current user
+func (a *App) UpdateMyProfile(update services.ProfileUpdate) (*models.User, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return nil, err
+	}
+
+	if a.profileService == nil {
+		return nil, errNotConnected
+	}
+
+	return a.profileService.UpdateProfile(ctx, update)
+}
+
+// RequestEmailChange sends a confirmation code to the new address of the
+// current user
+func (a *App) RequestEmailChange(email string) (*models.EmailChange, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return nil, err
+	}
+
+	if a.profileService == nil {
+		return nil, errNotConnected
+	}
+
+	return a.profileService.RequestEmailChange(ctx, email)
+}
+
+// ConfirmEmailChange switches to the new address with the code sent there
+func (a *App) ConfirmEmailChange(code string) (*models.User, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return nil, err
+	}
+
+	if a.profileService == nil {
+		return nil, errNotConnected
+	}
+
+	return a.profileService.ConfirmEmailChange(ctx, code)
+}
+
+// CancelEmailChange drops the pending email change of the current user
+func (a *App) CancelEmailChange() error {
+	ctx, err := a.callContext()
+	if err != nil {
+		return err
+	}
+
+	if a.profileService == nil {
+		return errNotConnected
+	}
+
+	return a.profileService.CancelEmailChange(ctx)
+}
+
+// GetMyPreferences returns the preferences of the current user, shared with
+// the web client
+func (a *App) GetMyPreferences() (*models.Preferences, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return nil, err
+	}
+
+	if a.profileService == nil {
+		return nil, errNotConnected
+	}
+
+	return a.profileService.GetPreferences(ctx)
+}
+
+// UpdateMyPreferences saves the preferences of the current user
+func (a *App) UpdateMyPreferences(prefs models.Preferences) (*models.Preferences, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return nil, err
+	}
+
+	if a.profileService == nil {
+		return nil, errNotConnected
+	}
+
+	return a.profileService.UpdatePreferences(ctx, prefs)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"github.com/cane/research-institute-system/backend/models"
+)
+
+// Project Management Methods
+
+// GetUserProjects returns the projects the current user leads or is a member of
+func (a *App) GetUserProjects() ([]models.Project, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return nil, err
+	}
+
+	if a.projectService == nil {
+		return nil, errNotConnected
+	}
+
+	return a.projectService.GetMyProjects(ctx)
+}
+
+// GetAllProjects returns all projects
+func (a *App) GetAllProjects() ([]models.Projekti, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return nil, err
+	}
+
+	if a.projectService == nil {
+		return nil, errNotConnected
+	}
+
+	return a.projectService.GetAllProjects(ctx)
+}
+
+// GetProjectByID returns a specific project by ID
+func (a *App) GetProjectByID(projectID int) (models.Projekti, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return models.Projekti{}, err
+	}
+
+	if a.projectService == nil {
+		return models.Projekti{}, errNotConnected
+	}
+
+	return a.projectService.GetProjectByID(ctx, projectID)
+}
+
+// CreateProject creates a new project led by the current user
+func (a *App) CreateProject(req models.CreateProjectRequest) (models.Projekti, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return models.Projekti{}, err
+	}
+
+	if a.projectService == nil {
+		return models.Projekti{}, errNotConnected
+	}
+
+	return a.projectService.CreateProject(ctx, req)
+}
+
+// UpdateProject updates an existing project
+func (a *App) UpdateProject(projectID int, project models.Projekti) error {
+	ctx, err := a.callContext()
+	if err != nil {
+		return err
+	}
+
+	if a.projectService == nil {
+		return errNotConnected
+	}
+
+	return a.projectService.UpdateProject(ctx, projectID, project)
+}
+
+// SetProjectWorkflow links a project to a workflow, or unlinks it when workflowID is nil
+func (a *App) SetProjectWorkflow(projectID int, workflowID *int) error {
+	ctx, err := a.callContext()
+	if err != nil {
+		return err
+	}
+
+	if a.projectService == nil {
+		return errNotConnected
+	}
+
+	return a.projectService.SetProjectWorkflow(ctx, projectID, workflowID)
+}
+
+// DeleteProject deletes a project
+func (a *App) DeleteProject(projectID int) error {
+	ctx, err := a.callContext()
+	if err != nil {
+		return err
+	}
+
+	if a.projectService == nil {
+		return errNotConnected
+	}
+
+	return a.projectService.DeleteProject(ctx, projectID)
+}
+
+// GetProjectMembers returns the team members of a project
+func (a *App) GetProjectMembers(projectID int) ([]models.Korisnici, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return nil, err
+	}
+
+	if a.projectService == nil {
+		return nil, errNotConnected
+	}
+
+	return a.projectService.GetProjectMembers(ctx, projectID)
+}
+
+// AddProjectMember adds a user to the project team
+func (a *App) AddProjectMember(projectID, userID int) error {
+	ctx, err := a.callContext()
+	if err != nil {
+		return err
+	}
+
+	if a.projectService == nil {
+		return errNotConnected
+	}
+
+	return a.projectService.AddProjectMember(ctx, projectID, userID)
+}
+
+// RemoveProjectMember removes a user from the project team
+func (a *App) RemoveProjectMember(projectID, userID int) error {
+	ctx, err := a.callContext()
+	if err != nil {
+		return err
+	}
+
+	if a.projectService == nil {
+		return errNotConnected
+	}
+
+	return a.projectService.RemoveProjectMember(ctx, projectID, userID)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"github.com/cane/research-institute-system/backend/models"
+)
+
+// Task Management Methods
+
+// GetTasksByProject returns the tasks of a project
+func (a *App) GetTasksByProject(projectID int) ([]models.Zadaci, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return nil, err
+	}
+
+	if a.taskService == nil {
+		return nil, errNotConnected
+	}
+
+	return a.taskService.GetTasksByProject(ctx, projectID)
+}
+
+// GetTasksByUser returns the tasks assigned to a user
+func (a *App) GetTasksByUser(userID int) ([]models.Zadaci, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return nil, err
+	}
+
+	if a.taskService == nil {
+		return nil, errNotConnected
+	}
+
+	return a.taskService.GetTasksByUser(ctx, userID)
+}
+
+// GetTaskByID returns a specific task by ID
+func (a *App) GetTaskByID(taskID int) (models.Zadaci, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return models.Zadaci{}, err
+	}
+
+	if a.taskService == nil {
+		return models.Zadaci{}, errNotConnected
+	}
+
+	return a.taskService.GetTaskByID(ctx, taskID)
+}
+
+// CreateTask creates a new task in the first phase of the project workflow
+func (a *App) CreateTask(req models.CreateTaskRequest) error {
+	ctx, err := a.callContext()
+	if err != nil {
+		return err
+	}
+
+	if a.taskService == nil {
+		return errNotConnected
+	}
+
+	return a.taskService.CreateTask(ctx, req)
+}
+
+// UpdateTask updates task fields; moving a Kanban card sets faza_id
+func (a *App) UpdateTask(taskID int, req models.UpdateTaskRequest) error {
+	ctx, err := a.callContext()
+	if err != nil {
+		return err
+	}
+
+	if a.taskService == nil {
+		return errNotConnected
+	}
+
+	return a.taskService.UpdateTask(ctx, taskID, req)
+}
+
+// DeleteTask deletes a task
+func (a *App) DeleteTask(taskID int) error {
+	ctx, err := a.callContext()
+	if err != nil {
+		return err
+	}
+
+	if a.taskService == nil {
+		return errNotConnected
+	}
+
+	return a.taskService.DeleteTask(ctx, taskID)
+}
+
+// GetTaskComments returns the comments of a task
+func (a *App) GetTaskComments(taskID int) ([]models.KomentariZadataka, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return nil, err
+	}
+
+	if a.taskService == nil {
+		return nil, errNotConnected
+	}
+
+	return a.taskService.GetTaskComments(ctx, taskID)
+}
+
+// AddTaskComment adds a comment to a task
+func (a *App) AddTaskComment(taskID int, comment string) error {
+	ctx, err := a.callContext()
+	if err != nil {
+		return err
+	}
+
+	if a.taskService == nil {
+		return errNotConnected
+	}
+
+	return a.taskService.AddTaskComment(ctx, taskID, comment)
+}
+
+// GetMyTasks returns the tasks assigned to the current user
+func (a *App) GetMyTasks() ([]models.Zadaci, error) {
+	user, err := a.currentUser()
+	if err != nil {
+		return nil, err
+	}
+
+	return a.GetTasksByUser(user.KorisnikID)
+}
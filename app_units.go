@@ -0,0 +1,80 @@
+package main
+
+import (
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/services"
+)
+
+// Organizational Unit Methods
+
+// GetUnits returns every organizational unit; the hierarchy follows
+// nadredjena_jedinica_id
+func (a *App) GetUnits() ([]models.Unit, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return nil, err
+	}
+
+	return a.unitService.GetUnits(ctx)
+}
+
+// GetUnitMembers returns the users who belong to a unit
+func (a *App) GetUnitMembers(unitID int) ([]models.Korisnici, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return nil, err
+	}
+
+	return a.unitService.GetUnitMembers(ctx, unitID)
+}
+
+// CreateUnit creates a department, lab or group
+func (a *App) CreateUnit(req services.UnitRequest) (*models.Unit, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return nil, err
+	}
+
+	return a.unitService.CreateUnit(ctx, req)
+}
+
+// UpdateUnit renames a unit, changes its head or moves it in the hierarchy
+func (a *App) UpdateUnit(unitID int, req services.UnitRequest) (*models.Unit, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return nil, err
+	}
+
+	return a.unitService.UpdateUnit(ctx, unitID, req)
+}
+
+// DeleteUnit removes a unit without subunits
+func (a *App) DeleteUnit(unitID int) error {
+	ctx, err := a.callContext()
+	if err != nil {
+		return err
+	}
+
+	return a.unitService.DeleteUnit(ctx, unitID)
+}
+
+// SetUserUnit moves a user to a unit, or out of any unit when unitID is nil
+func (a *App) SetUserUnit(userID int, unitID *int) error {
+	ctx, err := a.callContext()
+	if err != nil {
+		return err
+	}
+
+	return a.unitService.SetUserUnit(ctx, userID, unitID)
+}
+
+// SetProjectUnit moves a project to a unit, or out of any unit when unitID
+// is nil
+func (a *App) SetProjectUnit(projectID int, unitID *int) error {
+	ctx, err := a.callContext()
+	if err != nil {
+		return err
+	}
+
+	return a.unitService.SetProjectUnit(ctx, projectID, unitID)
+}
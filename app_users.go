@@ -0,0 +1,239 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/services"
+)
+
+// User Management Methods
+
+// CreateUser creates a new user and returns the activation code to hand over
+func (a *App) CreateUser(user *models.User) (*services.ActivationCode, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return nil, err
+	}
+
+	if a.authService == nil {
+		return nil, errNotConnected
+	}
+
+	return a.authService.CreateUser(ctx, user)
+}
+
+// GetAllUsers returns all users
+func (a *App) GetAllUsers() ([]models.Korisnici, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return nil, err
+	}
+
+	if a.userService == nil {
+		return nil, errNotConnected
+	}
+
+	return a.userService.GetAllUsers(ctx)
+}
+
+// UpdateUser updates the account data of a user
+func (a *App) UpdateUser(userID int, user models.Korisnici) error {
+	ctx, err := a.callContext()
+	if err != nil {
+		return err
+	}
+
+	if a.userService == nil {
+		return errNotConnected
+	}
+
+	return a.userService.UpdateUser(ctx, userID, user)
+}
+
+// DeleteUser deletes a user nothing refers to
+func (a *App) DeleteUser(userID int) error {
+	ctx, err := a.callContext()
+	if err != nil {
+		return err
+	}
+
+	if a.userService == nil {
+		return errNotConnected
+	}
+
+	return a.userService.DeleteUser(ctx, userID)
+}
+
+// ImportUsers creates users from CSV text and returns the report with their
+// activation codes. A dry run only checks the file; perRow creates the valid
+// rows even if other rows are invalid.
+func (a *App) ImportUsers(data string, dryRun, perRow bool) (*services.UserImportReport, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return nil, err
+	}
+
+	if a.authService == nil {
+		return nil, errNotConnected
+	}
+
+	return a.authService.ImportUsers(ctx, strings.NewReader(data), services.UserImportOptions{DryRun: dryRun, PerRow: perRow})
+}
+
+// ExportUsers returns all users with their roles as CSV text
+func (a *App) ExportUsers() (string, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return "", err
+	}
+
+	if a.userService == nil {
+		return "", errNotConnected
+	}
+
+	data, err := a.userService.ExportUsers(ctx)
+	return string(data), err
+}
+
+// OffboardUser deactivates a user and hands the user's work over
+func (a *App) OffboardUser(userID int, handover models.Handover) (*models.HandoverReport, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return nil, err
+	}
+
+	if a.authService == nil {
+		return nil, errNotConnected
+	}
+
+	return a.authService.OffboardUser(ctx, userID, handover)
+}
+
+// GetAllRoles returns the roles that can be assigned
+func (a *App) GetAllRoles() ([]models.Uloge, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return nil, err
+	}
+
+	if a.userService == nil {
+		return nil, errNotConnected
+	}
+
+	return a.userService.GetAllRoles(ctx)
+}
+
+// GetRoles returns every role with its permissions, retired roles included
+func (a *App) GetRoles() ([]models.Role, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return nil, err
+	}
+
+	return a.roleService.GetRoles(ctx)
+}
+
+// CreateRole creates a role with the given permissions
+func (a *App) CreateRole(req services.RoleRequest) (*models.Role, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return nil, err
+	}
+
+	return a.roleService.CreateRole(ctx, req)
+}
+
+// CloneRole creates a role with the permissions of an existing one
+func (a *App) CloneRole(roleID int, name string) (*models.Role, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return nil, err
+	}
+
+	return a.roleService.CloneRole(ctx, roleID, name)
+}
+
+// UpdateRole renames a role and replaces its permissions
+func (a *App) UpdateRole(roleID int, req services.RoleRequest) (*models.Role, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return nil, err
+	}
+
+	return a.roleService.UpdateRole(ctx, roleID, req)
+}
+
+// RetireRole keeps a role nobody holds from being assigned
+func (a *App) RetireRole(roleID int) error {
+	ctx, err := a.callContext()
+	if err != nil {
+		return err
+	}
+
+	return a.roleService.RetireRole(ctx, roleID)
+}
+
+// AssignRole gives a user another role
+func (a *App) AssignRole(userID, roleID int) error {
+	ctx, err := a.callContext()
+	if err != nil {
+		return err
+	}
+
+	return a.roleService.AssignRole(ctx, userID, roleID)
+}
+
+// ResetUserPassword disables the password of a user and returns a new
+// activation code
+func (a *App) ResetUserPassword(userID int) (*services.ActivationCode, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return nil, err
+	}
+
+	if a.authService == nil {
+		return nil, errNotConnected
+	}
+
+	return a.authService.ResetPassword(ctx, userID)
+}
+
+// UnlockUser lifts the sign-in lockout of a user
+func (a *App) UnlockUser(userID int) error {
+	ctx, err := a.callContext()
+	if err != nil {
+		return err
+	}
+
+	if a.authService == nil {
+		return errNotConnected
+	}
+
+	return a.authService.UnlockAccount(ctx, userID)
+}
+
+// ResetUserTwoFactor removes the second factor and recovery codes of a user
+// who lost them
+func (a *App) ResetUserTwoFactor(userID int) error {
+	ctx, err := a.callContext()
+	if err != nil {
+		return err
+	}
+
+	if a.authService == nil {
+		return errNotConnected
+	}
+
+	return a.authService.ResetTwoFactor(ctx, userID)
+}
+
+// GetUserAccessTokens lists the personal access tokens of a user
+func (a *App) GetUserAccessTokens(userID int) ([]models.AccessToken, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return nil, err
+	}
+
+	return a.authService.ListAccessTokens(ctx, userID)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"github.com/cane/research-institute-system/backend/models"
+)
+
+// Workflow Management Methods
+
+// GetAllWorkflows returns all workflows
+func (a *App) GetAllWorkflows() ([]models.RadniTokovi, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return nil, err
+	}
+
+	if a.workflowService == nil {
+		return nil, errNotConnected
+	}
+
+	return a.workflowService.GetAllWorkflows(ctx)
+}
+
+// GetWorkflowPhases returns the phases of a workflow in order
+func (a *App) GetWorkflowPhases(workflowID int) ([]models.Faze, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return nil, err
+	}
+
+	if a.workflowService == nil {
+		return nil, errNotConnected
+	}
+
+	return a.workflowService.GetWorkflowPhases(ctx, workflowID)
+}
+
+// CreateWorkflow creates a new workflow
+func (a *App) CreateWorkflow(workflow models.RadniTokovi) error {
+	ctx, err := a.callContext()
+	if err != nil {
+		return err
+	}
+
+	if a.workflowService == nil {
+		return errNotConnected
+	}
+
+	return a.workflowService.CreateWorkflow(ctx, workflow)
+}
+
+// CreatePhase adds a phase to a workflow
+func (a *App) CreatePhase(phase models.Faze) error {
+	ctx, err := a.callContext()
+	if err != nil {
+		return err
+	}
+
+	if a.workflowService == nil {
+		return errNotConnected
+	}
+
+	return a.workflowService.CreatePhase(ctx, phase)
+}
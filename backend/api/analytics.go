@@ -0,0 +1,41 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/cane/research-institute-system/backend/models"
+)
+
+const (
+	defaultActivityLimit = 100
+	maxActivityLimit     = 1000
+)
+
+func (s *Server) analyticsRoutes() {
+	s.add(route{
+		method: "GET", path: "/dashboard", name: "getDashboardStats", tag: "analytics",
+		summary: "Statistike kontrolne table",
+		result:  models.DashboardStats{},
+		handle: func(r *http.Request) (interface{}, error) {
+			return s.svc.Analytics.GetDashboardStats(r.Context())
+		},
+	})
+	s.add(route{
+		method: "GET", path: "/activity", name: "listActivityLogs", tag: "analytics",
+		summary: "Poslednje aktivnosti, najnovije prve",
+		result:  []models.ActivityLog{}, list: true,
+		query: []param{{name: "limit", kind: "integer", description: "broj najnovijih zapisa koji se učitavaju (podrazumevano 100, najviše 1000)"}},
+		handle: func(r *http.Request) (interface{}, error) {
+			limit := defaultActivityLimit
+			if value := r.URL.Query().Get("limit"); value != "" {
+				n, err := strconv.Atoi(value)
+				if err != nil || n < 1 || n > maxActivityLimit {
+					return nil, badRequest("limit mora biti ceo broj od 1 do " + strconv.Itoa(maxActivityLimit))
+				}
+				limit = n
+			}
+			return s.svc.Analytics.GetActivityLogs(r.Context(), limit)
+		},
+	})
+}
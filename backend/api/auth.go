@@ -0,0 +1,247 @@
+package api
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/services"
+)
+
+type changePasswordRequest struct {
+	NovaLozinka string `json:"nova_lozinka"`
+}
+
+type twoFactorCodeRequest struct {
+	Kod string `json:"kod"` // from the authenticator app, or a recovery code
+}
+
+func (s *Server) authRoutes() {
+	s.add(route{
+		method: "POST", path: "/auth/login", name: "login", tag: "auth", public: true,
+		summary: "Prijava; vraća token za zaglavlje Authorization",
+		body:    services.LoginRequest{}, result: services.LoginResponse{},
+		handle: s.login,
+	})
+	s.add(route{
+		method: "POST", path: "/auth/activate", name: "completeActivation", tag: "auth", public: true,
+		summary: "Postavljanje prve lozinke sa tokenom aktivacione prijave; vraća novi token",
+		body:    changePasswordRequest{}, result: services.LoginResponse{},
+		handle: func(r *http.Request) (interface{}, error) {
+			var req changePasswordRequest
+			if err := decodeJSON(r, &req); err != nil {
+				return nil, err
+			}
+			token, _ := bearerToken(r)
+			return s.svc.Auth.CompleteActivation(r.Context(), token, req.NovaLozinka)
+		},
+	})
+	s.add(route{
+		method: "POST", path: "/auth/2fa/verify", name: "verifyTwoFactor", tag: "auth", public: true,
+		summary: "Potvrda prijave kodom drugog faktora ili rezervnim kodom; vraća novi token",
+		body:    twoFactorCodeRequest{}, result: services.LoginResponse{},
+		handle: s.verifyTwoFactor,
+	})
+	s.add(route{
+		method: "POST", path: "/auth/2fa/enroll", name: "enrollTwoFactor", tag: "auth", public: true,
+		summary: "Nova tajna drugog faktora za aplikaciju za autentifikaciju (otpauth URL)",
+		result:  services.TwoFactorEnrollment{},
+		handle: func(r *http.Request) (interface{}, error) {
+			token, _ := bearerToken(r)
+			return s.svc.Auth.EnrollTwoFactor(r.Context(), token)
+		},
+	})
+	s.add(route{
+		method: "POST", path: "/auth/2fa/confirm", name: "confirmTwoFactor", tag: "auth", public: true,
+		summary: "Uključivanje drugog faktora trenutnim kodom; vraća rezervne kodove",
+		body:    twoFactorCodeRequest{}, result: services.TwoFactorSetup{},
+		handle: func(r *http.Request) (interface{}, error) {
+			var req twoFactorCodeRequest
+			if err := decodeJSON(r, &req); err != nil {
+				return nil, err
+			}
+			token, _ := bearerToken(r)
+			return s.svc.Auth.ConfirmTwoFactor(r.Context(), token, req.Kod)
+		},
+	})
+	s.add(route{
+		method: "POST", path: "/auth/logout", name: "logout", tag: "auth",
+		summary: "Odjava; poništava token zahteva",
+		handle: func(r *http.Request) (interface{}, error) {
+			s.svc.Auth.Logout(callerToken(r))
+			return nil, nil
+		},
+	})
+	s.add(route{
+		method: "GET", path: "/me", name: "getCurrentUser", tag: "auth",
+		summary: "Prijavljeni korisnik",
+		result:  models.User{},
+		handle: func(r *http.Request) (interface{}, error) {
+			return caller(r).User, nil
+		},
+	})
+	s.add(route{
+		method: "GET", path: "/me/permissions", name: "getMyPermissions", tag: "auth",
+		summary: "Dozvole prijavljenog korisnika",
+		result:  []services.Permission{},
+		handle: func(r *http.Request) (interface{}, error) {
+			return s.svc.Authz.PermissionsOf(caller(r)), nil
+		},
+	})
+	s.add(route{
+		method: "GET", path: "/me/impersonation", name: "getMyImpersonation", tag: "auth",
+		summary: "Administrator koji radi u ime prijavljenog korisnika; 204 van takve sesije",
+		result:  services.Impersonation{},
+		handle: func(r *http.Request) (interface{}, error) {
+			if impersonation := caller(r).Impersonation; impersonation != nil {
+				return impersonation, nil
+			}
+			return nil, nil
+		},
+	})
+	s.add(route{
+		method: "DELETE", path: "/me/impersonation", name: "stopImpersonation", tag: "auth",
+		summary: "Kraj rada u ime drugog korisnika; poništava token zahteva",
+		handle: func(r *http.Request) (interface{}, error) {
+			return nil, s.svc.Auth.StopImpersonation(r.Context(), callerToken(r))
+		},
+	})
+	s.add(route{
+		method: "GET", path: "/me/2fa", name: "getMyTwoFactor", tag: "auth",
+		summary: "Stanje drugog faktora prijavljenog korisnika",
+		result:  services.TwoFactorStatus{},
+		handle: func(r *http.Request) (interface{}, error) {
+			return s.svc.Auth.GetTwoFactorStatus(r.Context(), caller(r).User.KorisnikID)
+		},
+	})
+
+	s.add(route{
+		method: "GET", path: "/me/tokens", name: "listMyAccessTokens", tag: "auth",
+		summary: "Pristupni tokeni prijavljenog korisnika",
+		result:  []models.AccessToken{}, list: true,
+		handle: func(r *http.Request) (interface{}, error) {
+			return s.svc.Auth.ListAccessTokens(r.Context(), caller(r).User.KorisnikID)
+		},
+	})
+	s.add(route{
+		method: "POST", path: "/me/tokens", name: "createAccessToken", tag: "auth",
+		summary: "Novi pristupni token za skripte; token se prikazuje samo u ovom odgovoru, a opseg koji uloga daje samo u nekim projektima važi samo u njima",
+		body:    services.AccessTokenRequest{}, result: services.NewAccessToken{}, status: http.StatusCreated,
+		handle: func(r *http.Request) (interface{}, error) {
+			var req services.AccessTokenRequest
+			if err := decodeJSON(r, &req); err != nil {
+				return nil, err
+			}
+			return s.svc.Auth.CreateAccessToken(r.Context(), req)
+		},
+	})
+	s.add(route{
+		method: "DELETE", path: "/tokens/{id}", name: "revokeAccessToken", tag: "auth",
+		summary: "Opoziv pristupnog tokena",
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			return nil, s.svc.Auth.RevokeAccessToken(r.Context(), id)
+		},
+	})
+
+	s.add(route{
+		method: "GET", path: "/policy", name: "getPermissionPolicy", tag: "auth",
+		summary: "Dozvole koje uloge daju u svim projektima",
+		result:  services.Policy{},
+		handle: func(r *http.Request) (interface{}, error) {
+			return s.svc.Roles.GetPolicy(r.Context())
+		},
+	})
+	s.add(route{
+		method: "PUT", path: "/policy", name: "updatePermissionPolicy", tag: "auth",
+		summary: "Zamena dozvola navedenih uloga u svim projektima; dozvole u pojedinačnim projektima ostaju",
+		body:    services.Policy{},
+		handle: func(r *http.Request) (interface{}, error) {
+			var policy services.Policy
+			if err := decodeJSON(r, &policy); err != nil {
+				return nil, err
+			}
+			return nil, s.svc.Roles.UpdatePolicy(r.Context(), policy)
+		},
+	})
+	s.add(route{
+		method: "GET", path: "/sessions", name: "listSessions", tag: "auth",
+		summary: "Aktivne sesije",
+		result:  []services.Session{}, list: true,
+		handle: func(r *http.Request) (interface{}, error) {
+			if _, err := s.svc.Authz.Require(r.Context(), services.PermSessionManage); err != nil {
+				return nil, err
+			}
+			return s.svc.Sessions.List(), nil
+		},
+	})
+	s.add(route{
+		method: "DELETE", path: "/sessions/{sessionID}", name: "revokeSession", tag: "auth",
+		summary:  "Završavanje sesije",
+		textPath: []string{"sessionID"},
+		handle: func(r *http.Request) (interface{}, error) {
+			if _, err := s.svc.Authz.Require(r.Context(), services.PermSessionManage); err != nil {
+				return nil, err
+			}
+			if !s.svc.Sessions.RevokeByID(r.PathValue("sessionID")) {
+				return nil, &apiError{status: http.StatusNotFound, code: "not_found", message: "sesija nije pronađena"}
+			}
+			return nil, nil
+		},
+	})
+}
+
+// login answers failed logins with 401 so clients need not inspect the
+// success flag of the response, and attempts refused because of earlier
+// failures with 429 and a Retry-After header. Failures are counted per
+// client address as seen by the server.
+func (s *Server) login(r *http.Request) (interface{}, error) {
+	var req services.LoginRequest
+	if err := decodeJSON(r, &req); err != nil {
+		return nil, err
+	}
+	req.Source = clientAddress(r)
+
+	response, err := s.svc.Auth.Login(r.Context(), req)
+	if err != nil {
+		return nil, err
+	}
+	return loginResult(response, "invalid_credentials")
+}
+
+// verifyTwoFactor answers like login, with 401 invalid_code for a wrong
+// code.
+func (s *Server) verifyTwoFactor(r *http.Request) (interface{}, error) {
+	var req twoFactorCodeRequest
+	if err := decodeJSON(r, &req); err != nil {
+		return nil, err
+	}
+	token, _ := bearerToken(r)
+
+	response, err := s.svc.Auth.VerifyTwoFactor(r.Context(), token, req.Kod, clientAddress(r))
+	if err != nil {
+		return nil, err
+	}
+	return loginResult(response, "invalid_code")
+}
+
+func loginResult(response *services.LoginResponse, failure string) (interface{}, error) {
+	if response.RetryAfter > 0 {
+		return nil, &apiError{status: http.StatusTooManyRequests, code: "too_many_attempts", message: response.Message, retryAfter: response.RetryAfter}
+	}
+	if !response.Success {
+		return nil, &apiError{status: http.StatusUnauthorized, code: failure, message: response.Message}
+	}
+	return response, nil
+}
+
+// clientAddress is the host of the client as seen by the server.
+func clientAddress(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
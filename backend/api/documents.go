@@ -0,0 +1,204 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/cane/research-institute-system/backend/models"
+)
+
+// uploadOverhead is allowed on top of storage.max_file_size for the other
+// multipart fields and boundaries.
+const uploadOverhead = 1 << 20
+
+type documentTagRequest struct {
+	NazivTaga string `json:"naziv_taga"`
+}
+
+func (s *Server) documentRoutes() {
+	s.add(route{
+		method: "GET", path: "/documents", name: "listDocuments", tag: "documents",
+		summary: "Svi dokumenti",
+		result:  []models.Document{}, list: true,
+		handle: func(r *http.Request) (interface{}, error) {
+			return s.svc.Documents.GetAllDocuments(r.Context())
+		},
+	})
+	s.add(route{
+		method: "POST", path: "/documents", name: "uploadDocument", tag: "documents",
+		summary:   "Otpremanje dokumenta (multipart: polje document sa JSON podacima i polje file)",
+		multipart: true, status: http.StatusCreated,
+		handle: s.uploadDocument,
+	})
+	s.add(route{
+		method: "GET", path: "/documents/{id}", name: "getDocument", tag: "documents",
+		summary: "Dokument",
+		result:  models.Document{},
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			return s.svc.Documents.GetDocumentByID(r.Context(), id)
+		},
+	})
+	s.add(route{
+		method: "PUT", path: "/documents/{id}", name: "updateDocument", tag: "documents",
+		summary: "Izmena podataka dokumenta",
+		body:    models.UploadDocumentRequest{},
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			var req models.UploadDocumentRequest
+			if err := decodeJSON(r, &req); err != nil {
+				return nil, err
+			}
+			return nil, s.svc.Documents.UpdateDocument(r.Context(), id, req)
+		},
+	})
+	s.add(route{
+		method: "DELETE", path: "/documents/{id}", name: "deleteDocument", tag: "documents",
+		summary: "Brisanje dokumenta sa svim verzijama",
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			return nil, s.svc.Documents.DeleteDocument(r.Context(), id)
+		},
+	})
+	s.add(route{
+		method: "GET", path: "/documents/{id}/versions", name: "listDocumentVersions", tag: "documents",
+		summary: "Verzije dokumenta",
+		result:  []models.DocumentVersion{}, list: true,
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			return s.svc.Documents.GetDocumentVersions(r.Context(), id)
+		},
+	})
+	s.add(route{
+		method: "GET", path: "/documents/{id}/tags", name: "listDocumentTags", tag: "documents",
+		summary: "Tagovi dokumenta",
+		result:  []models.Tag{}, list: true,
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			return s.svc.Documents.GetDocumentTags(r.Context(), id)
+		},
+	})
+	s.add(route{
+		method: "POST", path: "/documents/{id}/tags", name: "addDocumentTag", tag: "documents",
+		summary: "Dodavanje taga dokumentu",
+		body:    documentTagRequest{},
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			var req documentTagRequest
+			if err := decodeJSON(r, &req); err != nil {
+				return nil, err
+			}
+			return nil, s.svc.Documents.AddDocumentTag(r.Context(), id, req.NazivTaga)
+		},
+	})
+	s.add(route{
+		method: "DELETE", path: "/documents/{id}/tags/{tagID}", name: "removeDocumentTag", tag: "documents",
+		summary: "Uklanjanje taga sa dokumenta",
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			tagID, err := pathID(r, "tagID")
+			if err != nil {
+				return nil, err
+			}
+			return nil, s.svc.Documents.RemoveDocumentTag(r.Context(), id, tagID)
+		},
+	})
+	s.add(route{
+		method: "GET", path: "/documents/{id}/metadata", name: "getDocumentMetadata", tag: "documents",
+		summary: "Metapodaci dokumenta",
+		result:  []models.Metadata{},
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			return s.svc.Documents.GetDocumentMetadata(r.Context(), id)
+		},
+	})
+	s.add(route{
+		method: "PUT", path: "/documents/{id}/metadata", name: "updateDocumentMetadata", tag: "documents",
+		summary: "Zamena metapodataka dokumenta",
+		body:    []models.Metadata{},
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			var metadata []models.Metadata
+			if err := decodeJSON(r, &metadata); err != nil {
+				return nil, err
+			}
+			return nil, s.svc.Documents.UpdateDocumentMetadata(r.Context(), id, metadata)
+		},
+	})
+	s.add(route{
+		method: "GET", path: "/folders", name: "listFolders", tag: "documents",
+		summary: "Folderi",
+		result:  []models.Folder{}, list: true,
+		handle: func(r *http.Request) (interface{}, error) {
+			return s.svc.Documents.GetAllFolders(r.Context())
+		},
+	})
+	s.add(route{
+		method: "POST", path: "/folders", name: "createFolder", tag: "documents",
+		summary: "Kreiranje foldera",
+		body:    models.Folder{}, status: http.StatusCreated,
+		handle: func(r *http.Request) (interface{}, error) {
+			var folder models.Folder
+			if err := decodeJSON(r, &folder); err != nil {
+				return nil, err
+			}
+			return nil, s.svc.Documents.CreateFolder(r.Context(), folder)
+		},
+	})
+}
+
+// uploadDocument reads a multipart upload: the "document" field holds the
+// UploadDocumentRequest as JSON and the "file" field the content.
+func (s *Server) uploadDocument(r *http.Request) (interface{}, error) {
+	r.Body = http.MaxBytesReader(nil, r.Body, s.svc.Config.Storage.MaxFileSize+uploadOverhead)
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		return nil, badRequest("neispravan multipart zahtev: " + err.Error())
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	var req models.UploadDocumentRequest
+	if err := json.Unmarshal([]byte(r.FormValue("document")), &req); err != nil {
+		return nil, badRequest("polje document mora sadržati JSON podatke dokumenta")
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		return nil, badRequest("polje file je obavezno")
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, badRequest("fajl nije pročitan: " + err.Error())
+	}
+
+	return nil, s.svc.Documents.UploadDocument(r.Context(), req, data, header.Filename)
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/cane/research-institute-system/backend/repositories"
+	"github.com/cane/research-institute-system/backend/services"
+)
+
+// apiError is a failure detected by the API layer itself.
+type apiError struct {
+	status  int
+	code    string
+	message string
+
+	retryAfter int // seconds, sent as the Retry-After header when set
+	violations []services.PasswordViolation
+}
+
+func (e *apiError) Error() string { return e.message }
+
+func badRequest(message string) error {
+	return &apiError{status: http.StatusBadRequest, code: "bad_request", message: message}
+}
+
+// errorBody is the JSON shape of every failed response.
+type errorBody struct {
+	Error errorDetail `json:"error"`
+}
+
+type errorDetail struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+
+	// Violations lists the broken rules of a password_policy error.
+	Violations []services.PasswordViolation `json:"violations,omitempty"`
+}
+
+// writeError maps service and store errors to a status and a stable code.
+// Errors of unknown kind may carry database details, so they are logged and
+// answered with a generic message.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var apiErr *apiError
+	var policyErr *services.PasswordPolicyError
+	switch {
+	case errors.As(err, &apiErr):
+	case errors.As(err, &policyErr):
+		apiErr = &apiError{status: http.StatusBadRequest, code: "password_policy", message: err.Error(), violations: policyErr.Violations}
+	case errors.Is(err, services.ErrNoSession), errors.Is(err, services.ErrSessionExpired), errors.Is(err, services.ErrTokenExpired):
+		w.Header().Set("WWW-Authenticate", `Bearer realm="riis"`)
+		apiErr = &apiError{status: http.StatusUnauthorized, code: "unauthorized", message: err.Error()}
+	case errors.Is(err, services.ErrPasswordChangeRequired):
+		apiErr = &apiError{status: http.StatusForbidden, code: "password_change_required", message: err.Error()}
+	case errors.Is(err, services.ErrTwoFactorRequired):
+		apiErr = &apiError{status: http.StatusForbidden, code: "two_factor_required", message: err.Error()}
+	case errors.Is(err, services.ErrForbidden):
+		apiErr = &apiError{status: http.StatusForbidden, code: "forbidden", message: services.ErrForbidden.Error()}
+	case errors.Is(err, repositories.ErrNotFound):
+		apiErr = &apiError{status: http.StatusNotFound, code: "not_found", message: "traženi podatak ne postoji"}
+	case errors.Is(err, services.ErrInvalidInput):
+		apiErr = &apiError{status: http.StatusBadRequest, code: "invalid_input", message: err.Error()}
+	case errors.Is(err, repositories.ErrConflict):
+		apiErr = &apiError{status: http.StatusConflict, code: "conflict", message: err.Error()}
+	default:
+		slog.ErrorContext(r.Context(), "api request failed", "method", r.Method, "path", r.URL.Path, "error", err)
+		apiErr = &apiError{status: http.StatusInternalServerError, code: "internal_error", message: "interna greška servera"}
+	}
+
+	if apiErr.retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(apiErr.retryAfter))
+	}
+	writeJSON(w, apiErr.status, errorBody{Error: errorDetail{Code: apiErr.code, Message: apiErr.message, Violations: apiErr.violations}})
+}
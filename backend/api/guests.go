@@ -0,0 +1,87 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/services"
+)
+
+type guestExpiryRequest struct {
+	Istice time.Time `json:"istice"`
+}
+
+func (s *Server) guestRoutes() {
+	s.add(route{
+		method: "POST", path: "/guests", name: "createGuest", tag: "guests",
+		summary: "Kreiranje gostujućeg naloga spoljnog partnera do zadatog isteka; vraća jednokratni aktivacioni kod",
+		body:    services.GuestRequest{}, result: services.ActivationCode{}, status: http.StatusCreated,
+		handle: func(r *http.Request) (interface{}, error) {
+			var req services.GuestRequest
+			if err := decodeJSON(r, &req); err != nil {
+				return nil, err
+			}
+			return s.svc.Guests.CreateGuest(r.Context(), req)
+		},
+	})
+	s.add(route{
+		method: "GET", path: "/guests/access", name: "listGuestAccess", tag: "guests",
+		summary: "Aktivni gostujući nalozi sa svim deljenjima, prvo oni koji ističu najranije",
+		result:  []services.GuestOverview{}, list: true,
+		handle: func(r *http.Request) (interface{}, error) {
+			return s.svc.Guests.GetGuestAccess(r.Context())
+		},
+	})
+	s.add(route{
+		method: "PUT", path: "/guests/{id}/expiry", name: "extendGuest", tag: "guests",
+		summary: "Pomeranje isteka gostujućeg naloga, najviše auth.guest_max_ttl od sada",
+		body:    guestExpiryRequest{},
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			var req guestExpiryRequest
+			if err := decodeJSON(r, &req); err != nil {
+				return nil, err
+			}
+			return nil, s.svc.Guests.ExtendGuest(r.Context(), id, req.Istice)
+		},
+	})
+	s.add(route{
+		method: "POST", path: "/guests/{id}/shares", name: "shareWithGuest", tag: "guests",
+		summary: "Deljenje tačno jednog projekta, foldera ili dokumenta sa gostom",
+		body:    services.GuestShareRequest{}, result: models.GuestShare{}, status: http.StatusCreated,
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			var req services.GuestShareRequest
+			if err := decodeJSON(r, &req); err != nil {
+				return nil, err
+			}
+			return s.svc.Guests.ShareWithGuest(r.Context(), id, req)
+		},
+	})
+	s.add(route{
+		method: "DELETE", path: "/guests/shares/{id}", name: "revokeGuestShare", tag: "guests",
+		summary: "Ukidanje jednog deljenja sa gostom",
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			return nil, s.svc.Guests.RevokeGuestShare(r.Context(), id)
+		},
+	})
+	s.add(route{
+		method: "GET", path: "/me/shared", name: "getSharedWithMe", tag: "guests",
+		summary: "Projekti i dokumenti podeljeni sa gostom koji je prijavljen",
+		result:  services.SharedWithGuest{},
+		handle: func(r *http.Request) (interface{}, error) {
+			return s.svc.Guests.GetSharedWithMe(r.Context())
+		},
+	})
+}
@@ -0,0 +1,247 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// object is a JSON object of the OpenAPI document.
+type object = map[string]interface{}
+
+var pathParamPattern = regexp.MustCompile(`\{(\w+)\}`)
+
+// buildOpenAPI renders the OpenAPI 3.0 document from the route table, so it
+// cannot drift from the routes actually served. Schemas are derived from the
+// json tags of the request and response types.
+func (s *Server) buildOpenAPI() []byte {
+	schemas := &schemaSet{components: object{}}
+
+	paths := object{}
+	for _, rt := range s.routes {
+		item, ok := paths[Prefix+rt.path].(object)
+		if !ok {
+			item = object{}
+			paths[Prefix+rt.path] = item
+		}
+		item[strings.ToLower(rt.method)] = schemas.operation(rt)
+	}
+
+	paths[Prefix+"/openapi.json"] = object{"get": object{
+		"operationId": "getOpenAPI",
+		"tags":        []string{"system"},
+		"summary":     "Ovaj OpenAPI dokument",
+		"security":    []object{},
+		"responses":   object{"200": object{"description": "OpenAPI 3.0 dokument", "content": object{"application/json": object{"schema": object{"type": "object"}}}}},
+	}}
+
+	schemas.components["Error"] = schemas.structSchema(reflect.TypeOf(errorBody{}))
+	schemas.components["PageMeta"] = schemas.structSchema(reflect.TypeOf(pageMeta{}))
+
+	doc := object{
+		"openapi": "3.0.3",
+		"info": object{
+			"title":       "Research Institute Information System API",
+			"version":     "1",
+			"description": "REST pristup servisima informacionog sistema instituta. Token iz /auth/login šalje se u zaglavlju Authorization: Bearer <token>.",
+		},
+		"paths": paths,
+		"components": object{
+			"schemas": schemas.components,
+			"securitySchemes": object{
+				"bearerAuth": object{"type": "http", "scheme": "bearer"},
+			},
+			"responses": object{
+				"Error": object{
+					"description": "Greška",
+					"content":     object{"application/json": object{"schema": ref("Error")}},
+				},
+			},
+		},
+		"security": []object{{"bearerAuth": []string{}}},
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		panic("api: OpenAPI document cannot be encoded: " + err.Error())
+	}
+	return data
+}
+
+func ref(name string) object {
+	return object{"$ref": "#/components/schemas/" + name}
+}
+
+// schemaSet collects the named schemas referenced by the operations.
+type schemaSet struct {
+	components object
+}
+
+func (s *schemaSet) operation(rt route) object {
+	op := object{
+		"operationId": rt.name,
+		"tags":        []string{rt.tag},
+		"summary":     rt.summary,
+	}
+	if rt.public {
+		op["security"] = []object{}
+	}
+
+	params := []object{}
+	for _, match := range pathParamPattern.FindAllStringSubmatch(rt.path, -1) {
+		kind := "integer"
+		for _, name := range rt.textPath {
+			if name == match[1] {
+				kind = "string"
+			}
+		}
+		params = append(params, object{"name": match[1], "in": "path", "required": true, "schema": object{"type": kind}})
+	}
+	query := rt.query
+	if rt.list {
+		query = append(query,
+			param{name: "page", kind: "integer", description: "broj stranice, od 1 do 1000000"},
+			param{name: "per_page", kind: "integer", description: "broj stavki po stranici (podrazumevano 50, najviše 500)"})
+	}
+	for _, q := range query {
+		params = append(params, object{"name": q.name, "in": "query", "description": q.description, "schema": object{"type": q.kind}})
+	}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+
+	switch {
+	case rt.multipart:
+		op["requestBody"] = object{"required": true, "content": object{"multipart/form-data": object{"schema": object{
+			"type":     "object",
+			"required": []string{"document", "file"},
+			"properties": object{
+				"document": object{"type": "string", "description": "UploadDocumentRequest kao JSON"},
+				"file":     object{"type": "string", "format": "binary"},
+			},
+		}}}}
+	case rt.csvBody:
+		op["requestBody"] = object{"required": true, "content": object{"text/csv": object{"schema": object{"type": "string"}}}}
+	case rt.body != nil:
+		op["requestBody"] = object{"required": true, "content": object{"application/json": object{
+			"schema": s.schemaOf(reflect.TypeOf(rt.body)),
+		}}}
+	}
+
+	status := rt.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	responses := object{"default": object{"$ref": "#/components/responses/Error"}}
+	switch {
+	case rt.csvResult:
+		responses[strconv.Itoa(status)] = object{
+			"description": http.StatusText(status),
+			"content":     object{"text/csv": object{"schema": object{"type": "string"}}},
+		}
+	case rt.result == nil:
+		if status == http.StatusOK {
+			status = http.StatusNoContent
+		}
+		responses[strconv.Itoa(status)] = object{"description": http.StatusText(status)}
+	default:
+		properties := object{"data": s.schemaOf(reflect.TypeOf(rt.result))}
+		required := []string{"data"}
+		if rt.list {
+			properties["meta"] = ref("PageMeta")
+			required = append(required, "meta")
+		}
+		responses[strconv.Itoa(status)] = object{
+			"description": http.StatusText(status),
+			"content": object{"application/json": object{"schema": object{
+				"type": "object", "required": required, "properties": properties,
+			}}},
+		}
+	}
+	op["responses"] = responses
+
+	return op
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaOf returns the schema of t. Named structs become components and are
+// referenced, so recursive and shared types are described once.
+func (s *schemaSet) schemaOf(t reflect.Type) object {
+	if t == timeType {
+		return object{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		inner := s.schemaOf(t.Elem())
+		if _, isRef := inner["$ref"]; isRef {
+			return object{"allOf": []object{inner}, "nullable": true}
+		}
+		inner["nullable"] = true
+		return inner
+	case reflect.Bool:
+		return object{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return object{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return object{"type": "number"}
+	case reflect.String:
+		return object{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return object{"type": "string", "format": "byte"}
+		}
+		return object{"type": "array", "items": s.schemaOf(t.Elem())}
+	case reflect.Map:
+		return object{"type": "object", "additionalProperties": s.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.structSchema(t)
+		}
+		if _, done := s.components[t.Name()]; !done {
+			s.components[t.Name()] = object{} // placeholder against recursion
+			s.components[t.Name()] = s.structSchema(t)
+		}
+		return ref(t.Name())
+	default:
+		return object{}
+	}
+}
+
+func (s *schemaSet) structSchema(t reflect.Type) object {
+	properties := object{}
+	s.addFields(t, properties)
+	return object{"type": "object", "properties": properties}
+}
+
+// addFields adds the JSON fields of t, flattening embedded structs the way
+// encoding/json does.
+func (s *schemaSet) addFields(t reflect.Type, properties object) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			s.addFields(field.Type, properties)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = s.schemaOf(field.Type)
+	}
+}
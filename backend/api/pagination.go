@@ -0,0 +1,80 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+)
+
+const (
+	defaultPerPage = 50
+	maxPerPage     = 500
+	maxPage        = 1000000
+)
+
+// pageRequest is the page asked for with ?page= and ?per_page=.
+type pageRequest struct {
+	Page    int
+	PerPage int
+}
+
+// pageMeta describes the page returned in a list response.
+type pageMeta struct {
+	Page    int `json:"page"`
+	PerPage int `json:"per_page"`
+	Total   int `json:"total"`
+}
+
+type listEnvelope struct {
+	Data interface{} `json:"data"`
+	Meta pageMeta    `json:"meta"`
+}
+
+func parsePage(r *http.Request) (pageRequest, error) {
+	page := pageRequest{Page: 1, PerPage: defaultPerPage}
+	query := r.URL.Query()
+
+	if value := query.Get("page"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxPage {
+			return page, badRequest("page mora biti ceo broj od 1 do " + strconv.Itoa(maxPage))
+		}
+		page.Page = n
+	}
+
+	if value := query.Get("per_page"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxPerPage {
+			return page, badRequest("per_page mora biti ceo broj od 1 do " + strconv.Itoa(maxPerPage))
+		}
+		page.PerPage = n
+	}
+
+	return page, nil
+}
+
+// paginate cuts one page out of a slice result. The services return whole
+// lists, so paging happens here rather than in SQL.
+func paginate(items interface{}, page pageRequest) listEnvelope {
+	value := reflect.ValueOf(items)
+	if value.IsNil() {
+		value = reflect.MakeSlice(value.Type(), 0, 0) // [] rather than null
+	}
+	total := value.Len()
+
+	// parsePage bounds both numbers, so the offset cannot overflow; clamp
+	// anyway for pages built elsewhere
+	start := (page.Page - 1) * page.PerPage
+	if start < 0 || start > total {
+		start = total
+	}
+	end := start + page.PerPage
+	if end < start || end > total {
+		end = total
+	}
+
+	return listEnvelope{
+		Data: value.Slice(start, end).Interface(),
+		Meta: pageMeta{Page: page.Page, PerPage: page.PerPage, Total: total},
+	}
+}
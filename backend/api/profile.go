@@ -0,0 +1,107 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/services"
+)
+
+type ownPasswordRequest struct {
+	TrenutnaLozinka string `json:"trenutna_lozinka"`
+	NovaLozinka     string `json:"nova_lozinka"`
+}
+
+type emailChangeRequest struct {
+	Email string `json:"email"`
+}
+
+type emailConfirmRequest struct {
+	Kod string `json:"kod"` // sent to the new address
+}
+
+func (s *Server) profileRoutes() {
+	s.add(route{
+		method: "GET", path: "/me/profile", name: "getMyProfile", tag: "profile",
+		summary: "Profil prijavljenog korisnika sa podešavanjima i promenom email adrese koja čeka potvrdu",
+		result:  services.Profile{},
+		handle: func(r *http.Request) (interface{}, error) {
+			return s.svc.Profile.GetProfile(r.Context())
+		},
+	})
+	s.add(route{
+		method: "PUT", path: "/me/profile", name: "updateMyProfile", tag: "profile",
+		summary: "Izmena imena i prezimena",
+		body:    services.ProfileUpdate{}, result: models.User{},
+		handle: func(r *http.Request) (interface{}, error) {
+			var req services.ProfileUpdate
+			if err := decodeJSON(r, &req); err != nil {
+				return nil, err
+			}
+			return s.svc.Profile.UpdateProfile(r.Context(), req)
+		},
+	})
+	s.add(route{
+		method: "POST", path: "/me/password", name: "changePassword", tag: "profile",
+		summary: "Promena lozinke uz trenutnu lozinku; ostale sesije korisnika se završavaju",
+		body:    ownPasswordRequest{},
+		handle: func(r *http.Request) (interface{}, error) {
+			var req ownPasswordRequest
+			if err := decodeJSON(r, &req); err != nil {
+				return nil, err
+			}
+			return nil, s.svc.Profile.ChangePassword(r.Context(), req.TrenutnaLozinka, req.NovaLozinka, clientAddress(r), callerToken(r))
+		},
+	})
+	s.add(route{
+		method: "POST", path: "/me/email", name: "requestEmailChange", tag: "profile",
+		summary: "Slanje koda za potvrdu na novu email adresu; adresa se menja tek posle potvrde",
+		body:    emailChangeRequest{}, result: models.EmailChange{}, status: http.StatusAccepted,
+		handle: func(r *http.Request) (interface{}, error) {
+			var req emailChangeRequest
+			if err := decodeJSON(r, &req); err != nil {
+				return nil, err
+			}
+			return s.svc.Profile.RequestEmailChange(r.Context(), req.Email)
+		},
+	})
+	s.add(route{
+		method: "POST", path: "/me/email/confirm", name: "confirmEmailChange", tag: "profile",
+		summary: "Potvrda nove email adrese kodom poslatim na nju",
+		body:    emailConfirmRequest{}, result: models.User{},
+		handle: func(r *http.Request) (interface{}, error) {
+			var req emailConfirmRequest
+			if err := decodeJSON(r, &req); err != nil {
+				return nil, err
+			}
+			return s.svc.Profile.ConfirmEmailChange(r.Context(), req.Kod)
+		},
+	})
+	s.add(route{
+		method: "DELETE", path: "/me/email", name: "cancelEmailChange", tag: "profile",
+		summary: "Odustajanje od promene email adrese",
+		handle: func(r *http.Request) (interface{}, error) {
+			return nil, s.svc.Profile.CancelEmailChange(r.Context())
+		},
+	})
+	s.add(route{
+		method: "GET", path: "/me/preferences", name: "getMyPreferences", tag: "profile",
+		summary: "Podešavanja prijavljenog korisnika, zajednička za sve klijente",
+		result:  models.Preferences{},
+		handle: func(r *http.Request) (interface{}, error) {
+			return s.svc.Profile.GetPreferences(r.Context())
+		},
+	})
+	s.add(route{
+		method: "PUT", path: "/me/preferences", name: "updateMyPreferences", tag: "profile",
+		summary: "Izmena jezika, podrazumevanog projekta, formata datuma i obaveštenja",
+		body:    models.Preferences{}, result: models.Preferences{},
+		handle: func(r *http.Request) (interface{}, error) {
+			var prefs models.Preferences
+			if err := decodeJSON(r, &prefs); err != nil {
+				return nil, err
+			}
+			return s.svc.Profile.UpdatePreferences(r.Context(), prefs)
+		},
+	})
+}
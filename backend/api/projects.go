@@ -0,0 +1,164 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/cane/research-institute-system/backend/models"
+)
+
+type projectWorkflowRequest struct {
+	RadniTokID *int `json:"radni_tok_id"`
+}
+
+type projectMemberRequest struct {
+	KorisnikID int `json:"korisnik_id"`
+}
+
+func (s *Server) projectRoutes() {
+	s.add(route{
+		method: "GET", path: "/projects", name: "listProjects", tag: "projects",
+		summary: "Projekti; sa mine=true samo projekti prijavljenog korisnika",
+		result:  []models.Project{}, list: true,
+		query: []param{{name: "mine", kind: "boolean", description: "samo projekti u kojima je korisnik rukovodilac ili član"}},
+		handle: func(r *http.Request) (interface{}, error) {
+			if r.URL.Query().Get("mine") == "true" {
+				return s.svc.Projects.GetMyProjects(r.Context())
+			}
+			return s.svc.Projects.GetAllProjects(r.Context())
+		},
+	})
+	s.add(route{
+		method: "POST", path: "/projects", name: "createProject", tag: "projects",
+		summary: "Kreiranje projekta; pozivalac postaje rukovodilac",
+		body:    models.CreateProjectRequest{}, result: models.Project{}, status: http.StatusCreated,
+		handle: func(r *http.Request) (interface{}, error) {
+			var req models.CreateProjectRequest
+			if err := decodeJSON(r, &req); err != nil {
+				return nil, err
+			}
+			return s.svc.Projects.CreateProject(r.Context(), req)
+		},
+	})
+	s.add(route{
+		method: "GET", path: "/projects/{id}", name: "getProject", tag: "projects",
+		summary: "Projekat",
+		result:  models.Project{},
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			return s.svc.Projects.GetProjectByID(r.Context(), id)
+		},
+	})
+	s.add(route{
+		method: "PUT", path: "/projects/{id}", name: "updateProject", tag: "projects",
+		summary: "Izmena projekta",
+		body:    models.Project{},
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			var project models.Project
+			if err := decodeJSON(r, &project); err != nil {
+				return nil, err
+			}
+			return nil, s.svc.Projects.UpdateProject(r.Context(), id, project)
+		},
+	})
+	s.add(route{
+		method: "DELETE", path: "/projects/{id}", name: "deleteProject", tag: "projects",
+		summary: "Brisanje projekta",
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			return nil, s.svc.Projects.DeleteProject(r.Context(), id)
+		},
+	})
+	s.add(route{
+		method: "PUT", path: "/projects/{id}/workflow", name: "setProjectWorkflow", tag: "projects",
+		summary: "Dodela radnog toka projektu; null uklanja tok",
+		body:    projectWorkflowRequest{},
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			var req projectWorkflowRequest
+			if err := decodeJSON(r, &req); err != nil {
+				return nil, err
+			}
+			return nil, s.svc.Projects.SetProjectWorkflow(r.Context(), id, req.RadniTokID)
+		},
+	})
+	s.add(route{
+		method: "GET", path: "/projects/{id}/members", name: "listProjectMembers", tag: "projects",
+		summary: "Članovi projekta",
+		result:  []models.User{}, list: true,
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			return s.svc.Projects.GetProjectMembers(r.Context(), id)
+		},
+	})
+	s.add(route{
+		method: "POST", path: "/projects/{id}/members", name: "addProjectMember", tag: "projects",
+		summary: "Dodavanje člana projekta",
+		body:    projectMemberRequest{},
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			var req projectMemberRequest
+			if err := decodeJSON(r, &req); err != nil {
+				return nil, err
+			}
+			return nil, s.svc.Projects.AddProjectMember(r.Context(), id, req.KorisnikID)
+		},
+	})
+	s.add(route{
+		method: "DELETE", path: "/projects/{id}/members/{userID}", name: "removeProjectMember", tag: "projects",
+		summary: "Uklanjanje člana projekta",
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			userID, err := pathID(r, "userID")
+			if err != nil {
+				return nil, err
+			}
+			return nil, s.svc.Projects.RemoveProjectMember(r.Context(), id, userID)
+		},
+	})
+	s.add(route{
+		method: "GET", path: "/projects/{id}/tasks", name: "listProjectTasks", tag: "tasks",
+		summary: "Zadaci projekta",
+		result:  []models.Task{}, list: true,
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			return s.svc.Tasks.GetTasksByProject(r.Context(), id)
+		},
+	})
+	s.add(route{
+		method: "GET", path: "/projects/{id}/documents", name: "listProjectDocuments", tag: "documents",
+		summary: "Dokumenti projekta",
+		result:  []models.Document{}, list: true,
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			return s.svc.Documents.GetDocumentsByProject(r.Context(), id)
+		},
+	})
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/services"
+)
+
+type roleCloneRequest struct {
+	Naziv string `json:"naziv"`
+}
+
+type roleAssignmentRequest struct {
+	UlogaID int `json:"uloga_id"`
+}
+
+func (s *Server) roleRoutes() {
+	s.add(route{
+		method: "GET", path: "/roles/matrix", name: "listRolePermissions", tag: "roles",
+		summary: "Sve uloge sa dozvolama, i povučene",
+		result:  []models.Role{}, list: true,
+		handle: func(r *http.Request) (interface{}, error) {
+			return s.svc.Roles.GetRoles(r.Context())
+		},
+	})
+	s.add(route{
+		method: "POST", path: "/roles", name: "createRole", tag: "roles",
+		summary: "Kreiranje uloge; dozvola sa projekat_id važi samo u tom projektu",
+		body:    services.RoleRequest{}, result: models.Role{}, status: http.StatusCreated,
+		handle: func(r *http.Request) (interface{}, error) {
+			var req services.RoleRequest
+			if err := decodeJSON(r, &req); err != nil {
+				return nil, err
+			}
+			return s.svc.Roles.CreateRole(r.Context(), req)
+		},
+	})
+	s.add(route{
+		method: "GET", path: "/roles/{id}", name: "getRole", tag: "roles",
+		summary: "Uloga sa dozvolama",
+		result:  models.Role{},
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			return s.svc.Roles.GetRole(r.Context(), id)
+		},
+	})
+	s.add(route{
+		method: "PUT", path: "/roles/{id}", name: "updateRole", tag: "roles",
+		summary: "Izmena naziva, opisa i dozvola uloge",
+		body:    services.RoleRequest{}, result: models.Role{},
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			var req services.RoleRequest
+			if err := decodeJSON(r, &req); err != nil {
+				return nil, err
+			}
+			return s.svc.Roles.UpdateRole(r.Context(), id, req)
+		},
+	})
+	s.add(route{
+		method: "POST", path: "/roles/{id}/clone", name: "cloneRole", tag: "roles",
+		summary: "Nova uloga sa opisom i dozvolama postojeće",
+		body:    roleCloneRequest{}, result: models.Role{}, status: http.StatusCreated,
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			var req roleCloneRequest
+			if err := decodeJSON(r, &req); err != nil {
+				return nil, err
+			}
+			return s.svc.Roles.CloneRole(r.Context(), id, req.Naziv)
+		},
+	})
+	s.add(route{
+		method: "POST", path: "/roles/{id}/retire", name: "retireRole", tag: "roles",
+		summary: "Povlačenje uloge koju nijedan korisnik nema; povučena uloga se više ne dodeljuje",
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			return nil, s.svc.Roles.RetireRole(r.Context(), id)
+		},
+	})
+	s.add(route{
+		method: "PUT", path: "/users/{id}/role", name: "assignRole", tag: "roles",
+		summary: "Dodela uloge korisniku; važi od sledećeg zahteva njegovih sesija",
+		body:    roleAssignmentRequest{},
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			var req roleAssignmentRequest
+			if err := decodeJSON(r, &req); err != nil {
+				return nil, err
+			}
+			return nil, s.svc.Roles.AssignRole(r.Context(), id, req.UlogaID)
+		},
+	})
+}
@@ -0,0 +1,364 @@
+// Package api exposes the service layer over JSON/HTTP for clients without
+// the desktop build. Every route runs through the same services, and
+// therefore the same authorization policy, as the Wails bindings on App.
+//
+// All routes live under /api/v1. Callers log in at /api/v1/auth/login and
+// send the returned token as "Authorization: Bearer <token>". Successful
+// responses wrap their payload in {"data": ...}; list responses add a "meta"
+// object with the pagination state. Failures return
+// {"error": {"code": ..., "message": ...}}. The user import reads a text/csv
+// body and the user export returns text/csv. The OpenAPI document describing
+// all of this is served at /api/v1/openapi.json.
+//
+// A login with an activation code returns the message FIRST_TIME_LOGIN and a
+// token that other routes refuse with 403 password_change_required; it is
+// exchanged for a normal token at /api/v1/auth/activate. Logins refused
+// because of repeated failures get 429 too_many_attempts with Retry-After.
+// A password refused by the password policy gets 400 password_policy, with
+// the broken rules listed in the "violations" array of the error.
+//
+// A login of an account with a second factor returns TWO_FACTOR_REQUIRED,
+// and of an account whose role needs one it has not set up
+// TWO_FACTOR_SETUP_REQUIRED. Other routes refuse that token with 403
+// two_factor_required until it is exchanged at /api/v1/auth/2fa/verify, or
+// at /api/v1/auth/2fa/enroll and /api/v1/auth/2fa/confirm.
+//
+// Scripts authenticate with a personal access token (riis_...) created at
+// /api/v1/me/tokens, sent the same way as a session token. A token may use
+// only the permissions in its scopes, and cannot change passwords or manage
+// tokens.
+//
+// An administrator with user.impersonate gets a token acting as another user
+// from /api/v1/users/{id}/impersonate. It expires after
+// auth.impersonation_ttl, every response to it carries an X-Impersonated-By
+// header naming the administrator, routes that change passwords, tokens,
+// accounts, roles or sessions refuse it with 403, and every request made
+// with it is written to the activity log under both users. DELETE
+// /api/v1/me/impersonation ends it.
+//
+// Every response carries an X-Request-ID header, taken from the request when
+// the client sent a usable one. The same ID appears on every log line the
+// request causes, in the API and in the services.
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cane/research-institute-system/backend/logging"
+	"github.com/cane/research-institute-system/backend/services"
+)
+
+// Prefix is the path prefix of every API route.
+const Prefix = "/api/v1"
+
+// maxBodySize limits JSON request bodies; uploads are limited by storage.max_file_size.
+const maxBodySize = 1 << 20
+
+// handler serves one route. A nil result with a nil error means the
+// operation has nothing to return.
+type handler func(r *http.Request) (interface{}, error)
+
+// route describes an endpoint for both the mux and the OpenAPI document.
+type route struct {
+	method  string
+	path    string // below Prefix, in net/http pattern syntax
+	name    string // OpenAPI operationId
+	tag     string
+	summary string
+	public  bool // reachable without a session
+	status  int  // success status, 200 (or 204 without a result) by default
+
+	body      interface{} // zero value of the JSON request body, if any
+	multipart bool        // the request is a document upload
+	csvBody   bool        // the request body is CSV text
+	result    interface{} // zero value of the response payload, if any
+	csvResult bool        // the result is a *csvFile, served as is
+	list      bool        // result is a slice served page by page
+	query     []param
+	textPath  []string // path parameters that are not integer IDs
+
+	handle handler
+}
+
+// param documents a query parameter.
+type param struct {
+	name        string
+	kind        string // OpenAPI type
+	description string
+}
+
+// Server is the http.Handler of the REST API.
+type Server struct {
+	svc     *services.Services
+	mux     *http.ServeMux
+	routes  []route
+	openAPI []byte
+}
+
+// New builds the API on a fully wired service layer.
+func New(svc *services.Services) *Server {
+	s := &Server{svc: svc, mux: http.NewServeMux()}
+
+	s.authRoutes()
+	s.profileRoutes()
+	s.projectRoutes()
+	s.taskRoutes()
+	s.documentRoutes()
+	s.workflowRoutes()
+	s.userRoutes()
+	s.roleRoutes()
+	s.unitRoutes()
+	s.guestRoutes()
+	s.analyticsRoutes()
+
+	s.add(route{
+		method: "GET", path: "/health", name: "health", tag: "system", public: true,
+		summary: "Provera rada servera",
+		result:  map[string]string{},
+		handle: func(r *http.Request) (interface{}, error) {
+			return map[string]string{"status": "ok"}, nil
+		},
+	})
+
+	s.openAPI = s.buildOpenAPI()
+	s.mux.HandleFunc("GET "+Prefix+"/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(s.openAPI)
+	})
+	s.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, r, &apiError{status: http.StatusNotFound, code: "not_found", message: "ruta nije pronađena"})
+	})
+
+	return s
+}
+
+// RequestIDHeader carries the correlation ID of a request and its response.
+const RequestIDHeader = "X-Request-ID"
+
+// ImpersonatedByHeader names the administrator acting as the caller in
+// responses to an impersonation token.
+const ImpersonatedByHeader = "X-Impersonated-By"
+
+// ServeHTTP assigns the request ID, dispatches the request to its route and
+// logs the outcome.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	id := r.Header.Get(RequestIDHeader)
+	if !validRequestID(id) {
+		id = logging.NewRequestID()
+	}
+	w.Header().Set(RequestIDHeader, id)
+	r = r.WithContext(logging.WithRequestID(r.Context(), id))
+
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK, ctx: r.Context()}
+	s.mux.ServeHTTP(rec, r)
+	s.svc.Auth.RecordImpersonatedCall(rec.ctx, fmt.Sprintf("%s %s: %d", r.Method, r.URL.Path, rec.status))
+
+	level := slog.LevelInfo
+	if rec.status >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
+	slog.Log(rec.ctx, level, "api request", "method", r.Method, "path", r.URL.Path,
+		"status", rec.status, "duration", time.Since(start).Round(time.Millisecond))
+}
+
+// validRequestID accepts client IDs that are safe to echo and log.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Server) add(rt route) {
+	s.routes = append(s.routes, rt)
+	s.mux.HandleFunc(rt.method+" "+Prefix+rt.path, s.serve(rt))
+}
+
+// serve adapts a route to net/http: it authenticates the caller, runs the
+// handler and writes the envelope.
+func (s *Server) serve(rt route) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !rt.public {
+			ctx, err := s.authenticate(r)
+			if err != nil {
+				writeError(w, r, err)
+				return
+			}
+			r = r.WithContext(ctx)
+			if rec, ok := w.(*statusRecorder); ok {
+				rec.ctx = ctx
+			}
+			if impersonation := caller(r).Impersonation; impersonation != nil {
+				w.Header().Set(ImpersonatedByHeader, impersonation.Administrator)
+			}
+		}
+
+		var page pageRequest
+		if rt.list {
+			var err error
+			if page, err = parsePage(r); err != nil {
+				writeError(w, r, err)
+				return
+			}
+		}
+
+		result, err := rt.handle(r)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		status := rt.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		switch {
+		case result == nil:
+			if status == http.StatusOK {
+				status = http.StatusNoContent
+			}
+			w.WriteHeader(status)
+		case rt.csvResult:
+			writeCSV(w, status, result.(*csvFile))
+		case rt.list:
+			writeJSON(w, status, paginate(result, page))
+		default:
+			writeJSON(w, status, envelope{Data: result})
+		}
+	}
+}
+
+type tokenKey struct{}
+
+// authenticate resolves the bearer token, a session token or a personal
+// access token, and returns the request context carrying the caller as
+// services.Principal.
+func (s *Server) authenticate(r *http.Request) (context.Context, error) {
+	token, ok := bearerToken(r)
+	if !ok {
+		return nil, services.ErrNoSession
+	}
+
+	principal, err := s.svc.Auth.Authenticate(r.Context(), token)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := services.WithPrincipal(r.Context(), principal)
+	return context.WithValue(ctx, tokenKey{}, token), nil
+}
+
+// bearerToken returns the token of the Authorization header.
+func bearerToken(r *http.Request) (string, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return token, ok && token != ""
+}
+
+// caller returns the authenticated user of a non-public route.
+func caller(r *http.Request) *services.Principal {
+	principal, _ := services.PrincipalFrom(r.Context())
+	return principal
+}
+
+// callerToken returns the session token the request was authenticated with.
+func callerToken(r *http.Request) string {
+	token, _ := r.Context().Value(tokenKey{}).(string)
+	return token
+}
+
+type envelope struct {
+	Data interface{} `json:"data"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Warn("api: writing response failed", "error", err)
+	}
+}
+
+// csvFile is a result served as a CSV download instead of the JSON envelope.
+type csvFile struct {
+	name string
+	data []byte
+}
+
+func writeCSV(w http.ResponseWriter, status int, file *csvFile) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+file.name+`"`)
+	w.WriteHeader(status)
+	if _, err := w.Write(file.data); err != nil {
+		slog.Warn("api: writing response failed", "error", err)
+	}
+}
+
+// readCSV returns the CSV request body.
+func readCSV(r *http.Request) (io.Reader, error) {
+	data, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, maxBodySize))
+	if err != nil {
+		return nil, badRequest("telo zahteva nije pročitano: " + err.Error())
+	}
+	return bytes.NewReader(data), nil
+}
+
+// queryBool parses an optional boolean query parameter.
+func queryBool(r *http.Request, name string) (bool, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, badRequest("parametar " + name + " mora biti true ili false")
+	}
+	return b, nil
+}
+
+// decodeJSON reads the request body into v.
+func decodeJSON(r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxBodySize))
+	if err := decoder.Decode(v); err != nil {
+		return badRequest("neispravno JSON telo zahteva: " + err.Error())
+	}
+	return nil
+}
+
+// pathID parses an integer path parameter.
+func pathID(r *http.Request, name string) (int, error) {
+	id, err := strconv.Atoi(r.PathValue(name))
+	if err != nil {
+		return 0, badRequest("neispravan parametar putanje " + name)
+	}
+	return id, nil
+}
+
+// statusRecorder remembers the response status and the latest request
+// context, so the request log names the authenticated caller.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	ctx    context.Context
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/cane/research-institute-system/backend/models"
+)
+
+type taskCommentRequest struct {
+	TekstKomentara string `json:"tekst_komentara"`
+}
+
+func (s *Server) taskRoutes() {
+	s.add(route{
+		method: "GET", path: "/me/tasks", name: "listMyTasks", tag: "tasks",
+		summary: "Zadaci dodeljeni prijavljenom korisniku",
+		result:  []models.Task{}, list: true,
+		handle: func(r *http.Request) (interface{}, error) {
+			return s.svc.Tasks.GetTasksByUser(r.Context(), caller(r).User.KorisnikID)
+		},
+	})
+	s.add(route{
+		method: "GET", path: "/users/{id}/tasks", name: "listUserTasks", tag: "tasks",
+		summary: "Zadaci dodeljeni korisniku",
+		result:  []models.Task{}, list: true,
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			return s.svc.Tasks.GetTasksByUser(r.Context(), id)
+		},
+	})
+	s.add(route{
+		method: "POST", path: "/tasks", name: "createTask", tag: "tasks",
+		summary: "Kreiranje zadatka u prvoj fazi toka projekta",
+		body:    models.CreateTaskRequest{}, status: http.StatusCreated,
+		handle: func(r *http.Request) (interface{}, error) {
+			var req models.CreateTaskRequest
+			if err := decodeJSON(r, &req); err != nil {
+				return nil, err
+			}
+			return nil, s.svc.Tasks.CreateTask(r.Context(), req)
+		},
+	})
+	s.add(route{
+		method: "GET", path: "/tasks/{id}", name: "getTask", tag: "tasks",
+		summary: "Zadatak",
+		result:  models.Task{},
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			return s.svc.Tasks.GetTaskByID(r.Context(), id)
+		},
+	})
+	s.add(route{
+		method: "PATCH", path: "/tasks/{id}", name: "updateTask", tag: "tasks",
+		summary: "Izmena zadatka; menjaju se samo poslata polja",
+		body:    models.UpdateTaskRequest{},
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			var req models.UpdateTaskRequest
+			if err := decodeJSON(r, &req); err != nil {
+				return nil, err
+			}
+			return nil, s.svc.Tasks.UpdateTask(r.Context(), id, req)
+		},
+	})
+	s.add(route{
+		method: "DELETE", path: "/tasks/{id}", name: "deleteTask", tag: "tasks",
+		summary: "Brisanje zadatka",
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			return nil, s.svc.Tasks.DeleteTask(r.Context(), id)
+		},
+	})
+	s.add(route{
+		method: "GET", path: "/tasks/{id}/comments", name: "listTaskComments", tag: "tasks",
+		summary: "Komentari zadatka, najnoviji prvi",
+		result:  []models.TaskComment{}, list: true,
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			return s.svc.Tasks.GetTaskComments(r.Context(), id)
+		},
+	})
+	s.add(route{
+		method: "POST", path: "/tasks/{id}/comments", name: "addTaskComment", tag: "tasks",
+		summary: "Komentar na zadatak u ime prijavljenog korisnika",
+		body:    taskCommentRequest{}, status: http.StatusCreated,
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			var req taskCommentRequest
+			if err := decodeJSON(r, &req); err != nil {
+				return nil, err
+			}
+			return nil, s.svc.Tasks.AddTaskComment(r.Context(), id, req.TekstKomentara)
+		},
+	})
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/services"
+)
+
+type unitAssignmentRequest struct {
+	JedinicaID *int `json:"jedinica_id"`
+}
+
+func (s *Server) unitRoutes() {
+	s.add(route{
+		method: "GET", path: "/units", name: "listUnits", tag: "units",
+		summary: "Sve organizacione jedinice; hijerarhija sledi nadredjena_jedinica_id",
+		result:  []models.Unit{}, list: true,
+		handle: func(r *http.Request) (interface{}, error) {
+			return s.svc.Units.GetUnits(r.Context())
+		},
+	})
+	s.add(route{
+		method: "POST", path: "/units", name: "createUnit", tag: "units",
+		summary: "Kreiranje jedinice (institut, odeljenje, laboratorija ili grupa)",
+		body:    services.UnitRequest{}, result: models.Unit{}, status: http.StatusCreated,
+		handle: func(r *http.Request) (interface{}, error) {
+			var req services.UnitRequest
+			if err := decodeJSON(r, &req); err != nil {
+				return nil, err
+			}
+			return s.svc.Units.CreateUnit(r.Context(), req)
+		},
+	})
+	s.add(route{
+		method: "GET", path: "/units/{id}", name: "getUnit", tag: "units",
+		summary: "Jedinica sa brojem članova i projekata",
+		result:  models.Unit{},
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			return s.svc.Units.GetUnit(r.Context(), id)
+		},
+	})
+	s.add(route{
+		method: "PUT", path: "/units/{id}", name: "updateUnit", tag: "units",
+		summary: "Izmena jedinice; promena nadređene jedinice premešta i podjedinice",
+		body:    services.UnitRequest{}, result: models.Unit{},
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			var req services.UnitRequest
+			if err := decodeJSON(r, &req); err != nil {
+				return nil, err
+			}
+			return s.svc.Units.UpdateUnit(r.Context(), id, req)
+		},
+	})
+	s.add(route{
+		method: "DELETE", path: "/units/{id}", name: "deleteUnit", tag: "units",
+		summary: "Brisanje jedinice bez podjedinica; članovi i projekti ostaju bez jedinice",
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			return nil, s.svc.Units.DeleteUnit(r.Context(), id)
+		},
+	})
+	s.add(route{
+		method: "GET", path: "/units/{id}/members", name: "listUnitMembers", tag: "units",
+		summary: "Korisnici koji pripadaju jedinici",
+		result:  []models.User{}, list: true,
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			return s.svc.Units.GetUnitMembers(r.Context(), id)
+		},
+	})
+	s.add(route{
+		method: "PUT", path: "/users/{id}/unit", name: "setUserUnit", tag: "units",
+		summary: "Premeštanje korisnika u jedinicu; null ga uklanja iz jedinice",
+		body:    unitAssignmentRequest{},
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			var req unitAssignmentRequest
+			if err := decodeJSON(r, &req); err != nil {
+				return nil, err
+			}
+			return nil, s.svc.Units.SetUserUnit(r.Context(), id, req.JedinicaID)
+		},
+	})
+	s.add(route{
+		method: "PUT", path: "/projects/{id}/unit", name: "setProjectUnit", tag: "units",
+		summary: "Premeštanje projekta u jedinicu; null ga uklanja iz jedinice",
+		body:    unitAssignmentRequest{},
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			var req unitAssignmentRequest
+			if err := decodeJSON(r, &req); err != nil {
+				return nil, err
+			}
+			return nil, s.svc.Units.SetProjectUnit(r.Context(), id, req.JedinicaID)
+		},
+	})
+}
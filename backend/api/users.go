@@ -0,0 +1,184 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/services"
+)
+
+type impersonationRequest struct {
+	Razlog string `json:"razlog"`
+}
+
+func (s *Server) userRoutes() {
+	s.add(route{
+		method: "GET", path: "/users", name: "listUsers", tag: "users",
+		summary: "Korisnici",
+		result:  []models.User{}, list: true,
+		handle: func(r *http.Request) (interface{}, error) {
+			return s.svc.Users.GetAllUsers(r.Context())
+		},
+	})
+	s.add(route{
+		method: "POST", path: "/users", name: "createUser", tag: "users",
+		summary: "Kreiranje korisnika; vraća jednokratni aktivacioni kod",
+		body:    models.User{}, result: services.ActivationCode{}, status: http.StatusCreated,
+		handle: func(r *http.Request) (interface{}, error) {
+			var user models.User
+			if err := decodeJSON(r, &user); err != nil {
+				return nil, err
+			}
+			return s.svc.Auth.CreateUser(r.Context(), &user)
+		},
+	})
+	s.add(route{
+		method: "POST", path: "/users/import", name: "importUsers", tag: "users",
+		summary: "Uvoz korisnika iz CSV-a (korisnicko_ime, email, ime, prezime, uloga); vraća izveštaj po redovima sa aktivacionim kodovima",
+		csvBody: true, result: services.UserImportReport{},
+		query: []param{
+			{name: "dry_run", kind: "boolean", description: "samo provera fajla, bez kreiranja korisnika"},
+			{name: "per_row", kind: "boolean", description: "kreiraj ispravne redove i kada drugi redovi imaju greške"},
+		},
+		handle: func(r *http.Request) (interface{}, error) {
+			var opts services.UserImportOptions
+			var err error
+			if opts.DryRun, err = queryBool(r, "dry_run"); err != nil {
+				return nil, err
+			}
+			if opts.PerRow, err = queryBool(r, "per_row"); err != nil {
+				return nil, err
+			}
+			data, err := readCSV(r)
+			if err != nil {
+				return nil, err
+			}
+			return s.svc.Auth.ImportUsers(r.Context(), data, opts)
+		},
+	})
+	s.add(route{
+		method: "GET", path: "/users/export", name: "exportUsers", tag: "users",
+		summary:   "Izvoz korisnika sa ulogama u CSV",
+		csvResult: true,
+		handle: func(r *http.Request) (interface{}, error) {
+			data, err := s.svc.Users.ExportUsers(r.Context())
+			if err != nil {
+				return nil, err
+			}
+			return &csvFile{name: "korisnici.csv", data: data}, nil
+		},
+	})
+	s.add(route{
+		method: "POST", path: "/users/{id}/impersonate", name: "impersonateUser", tag: "users",
+		summary: "Rad u ime korisnika uz razlog; vraća token vremenski ograničene sesije u kojoj su izmene bezbednosti zabranjene",
+		body:    impersonationRequest{}, result: services.LoginResponse{},
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			var req impersonationRequest
+			if err := decodeJSON(r, &req); err != nil {
+				return nil, err
+			}
+			return s.svc.Auth.StartImpersonation(r.Context(), id, req.Razlog)
+		},
+	})
+	s.add(route{
+		method: "PUT", path: "/users/{id}", name: "updateUser", tag: "users",
+		summary: "Izmena podataka korisnika; izostavljena polja ostaju, deaktivacija završava sesije",
+		body:    models.User{},
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			var user models.User
+			if err := decodeJSON(r, &user); err != nil {
+				return nil, err
+			}
+			return nil, s.svc.Users.UpdateUser(r.Context(), id, user)
+		},
+	})
+	s.add(route{
+		method: "DELETE", path: "/users/{id}", name: "deleteUser", tag: "users",
+		summary: "Brisanje korisnika na koga se ništa više ne odnosi",
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			return nil, s.svc.Users.DeleteUser(r.Context(), id)
+		},
+	})
+	s.add(route{
+		method: "POST", path: "/users/{id}/offboard", name: "offboardUser", tag: "users",
+		summary: "Deaktivacija korisnika i predaja njegovih projekata, otvorenih zadataka, foldera i dokumenata",
+		body:    models.Handover{}, result: models.HandoverReport{},
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			var handover models.Handover
+			if err := decodeJSON(r, &handover); err != nil {
+				return nil, err
+			}
+			return s.svc.Auth.OffboardUser(r.Context(), id, handover)
+		},
+	})
+	s.add(route{
+		method: "POST", path: "/users/{id}/reset-password", name: "resetUserPassword", tag: "users",
+		summary: "Poništavanje lozinke i novi aktivacioni kod; sve sesije korisnika se završavaju",
+		result:  services.ActivationCode{},
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			return s.svc.Auth.ResetPassword(r.Context(), id)
+		},
+	})
+	s.add(route{
+		method: "POST", path: "/users/{id}/unlock", name: "unlockUser", tag: "users",
+		summary: "Otključavanje naloga zaključanog posle neuspešnih prijava",
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			return nil, s.svc.Auth.UnlockAccount(r.Context(), id)
+		},
+	})
+	s.add(route{
+		method: "DELETE", path: "/users/{id}/2fa", name: "resetUserTwoFactor", tag: "users",
+		summary: "Uklanjanje drugog faktora i rezervnih kodova korisnika; sve sesije korisnika se završavaju",
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			return nil, s.svc.Auth.ResetTwoFactor(r.Context(), id)
+		},
+	})
+	s.add(route{
+		method: "GET", path: "/users/{id}/tokens", name: "listUserAccessTokens", tag: "users",
+		summary: "Pristupni tokeni korisnika",
+		result:  []models.AccessToken{}, list: true,
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			return s.svc.Auth.ListAccessTokens(r.Context(), id)
+		},
+	})
+	s.add(route{
+		method: "GET", path: "/roles", name: "listRoles", tag: "users",
+		summary: "Uloge",
+		result:  []models.Role{}, list: true,
+		handle: func(r *http.Request) (interface{}, error) {
+			return s.svc.Users.GetAllRoles(r.Context())
+		},
+	})
+}
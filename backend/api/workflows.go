@@ -0,0 +1,59 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/cane/research-institute-system/backend/models"
+)
+
+func (s *Server) workflowRoutes() {
+	s.add(route{
+		method: "GET", path: "/workflows", name: "listWorkflows", tag: "workflows",
+		summary: "Radni tokovi",
+		result:  []models.Workflow{}, list: true,
+		handle: func(r *http.Request) (interface{}, error) {
+			return s.svc.Workflows.GetAllWorkflows(r.Context())
+		},
+	})
+	s.add(route{
+		method: "POST", path: "/workflows", name: "createWorkflow", tag: "workflows",
+		summary: "Kreiranje radnog toka",
+		body:    models.Workflow{}, status: http.StatusCreated,
+		handle: func(r *http.Request) (interface{}, error) {
+			var workflow models.Workflow
+			if err := decodeJSON(r, &workflow); err != nil {
+				return nil, err
+			}
+			return nil, s.svc.Workflows.CreateWorkflow(r.Context(), workflow)
+		},
+	})
+	s.add(route{
+		method: "GET", path: "/workflows/{id}/phases", name: "listWorkflowPhases", tag: "workflows",
+		summary: "Faze radnog toka po redosledu",
+		result:  []models.Phase{}, list: true,
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			return s.svc.Workflows.GetWorkflowPhases(r.Context(), id)
+		},
+	})
+	s.add(route{
+		method: "POST", path: "/workflows/{id}/phases", name: "createPhase", tag: "workflows",
+		summary: "Dodavanje faze radnom toku",
+		body:    models.Phase{}, status: http.StatusCreated,
+		handle: func(r *http.Request) (interface{}, error) {
+			id, err := pathID(r, "id")
+			if err != nil {
+				return nil, err
+			}
+			var phase models.Phase
+			if err := decodeJSON(r, &phase); err != nil {
+				return nil, err
+			}
+			phase.RadniTokID = id
+			return nil, s.svc.Workflows.CreatePhase(r.Context(), phase)
+		},
+	})
+}
@@ -1,12 +1,467 @@
+// Package config builds the typed configuration shared by the desktop app,
+// the REST server and the command line tools.
+//
+// Every setting has a key such as "database.host" and most have an
+// environment variable such as DB_HOST. Layers apply in increasing priority:
+//
+//  1. built-in defaults (development values)
+//  2. the JSON config file: -config, else $RIIS_CONFIG, else ./riis.json if present
+//  3. environment variables, including .env once the caller has loaded it
+//  4. command line overrides: -set key=value, repeatable
+//
+// The result is validated as a whole, and all problems are reported at once.
 package config
 
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultFile is read when neither -config nor RIIS_CONFIG names a file.
+const DefaultFile = "riis.json"
+
+// Config is the complete application configuration.
 type Config struct {
-	UploadPath string
+	Database DatabaseConfig
+	Server   ServerConfig
+	Storage  StorageConfig
+	Auth     AuthConfig
+	LDAP     LDAPConfig
+	Mail     MailConfig
+	Log      LogConfig
+
+	sources map[string]string // setting key -> layer that set it
+}
+
+// DatabaseConfig holds the PostgreSQL connection and pool settings.
+type DatabaseConfig struct {
+	URL             string // postgres:// URL; overrides the individual fields
+	Host            string
+	Port            int
+	User            string
+	Password        string
+	Name            string
+	SSLMode         string
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	AutoMigrate     bool // apply pending migrations at startup without asking
+}
+
+// ServerConfig holds the settings of the headless REST API server.
+type ServerConfig struct {
+	Addr string // listen address, e.g. ":8080"
+}
+
+// StorageConfig controls where uploaded documents go and what is accepted.
+type StorageConfig struct {
+	UploadPath       string
+	MaxFileSize      int64    // bytes
+	AllowedFileTypes []string // lower-case extensions without the dot; empty allows all
+}
+
+// AuthConfig holds the legacy permission policy file, the session
+// lifetimes, the validity of activation codes, the brute-force limits of
+// sign-in, the password policy, the second factor, personal access tokens
+// and impersonation.
+type AuthConfig struct {
+	PolicyPath         string // policy file of earlier versions, read only by riis-admin role import-policy
+	SessionIdleTimeout time.Duration
+	SessionMaxLifetime time.Duration
+	ActivationTTL      time.Duration // how long a new or reset account's code is valid
+
+	// After each failed sign-in the next attempt waits LoginDelay, doubled
+	// with every further failure. LockoutThreshold failures lock the account
+	// and SourceLockoutThreshold failures block the client address, both for
+	// LockoutDuration. A zero delay or threshold turns that limit off.
+	LoginDelay             time.Duration
+	LockoutThreshold       int
+	SourceLockoutThreshold int
+	LockoutDuration        time.Duration
+
+	// New passwords need PasswordMinLength characters from at least
+	// PasswordMinClasses of lower case, upper case, digits and other
+	// characters, and must differ from the last PasswordHistory passwords
+	// of the user (0 allows reuse).
+	PasswordMinLength  int
+	PasswordMinClasses int
+	PasswordHistory    int
+
+	// Cost of new argon2id password hashes. Stored hashes with other
+	// parameters, and legacy bcrypt hashes, are replaced at the next
+	// successful sign-in.
+	Argon2Memory      int // KiB
+	Argon2Iterations  int
+	Argon2Parallelism int
+
+	// Accounts with a role in TwoFactorRoles must enroll a TOTP second
+	// factor before they get a full session. System roles are named by
+	// their key (Uloge.sistemska), other roles by name. TwoFactorIssuer
+	// names the institute in authenticator apps.
+	TwoFactorRoles  []string
+	TwoFactorIssuer string
+
+	AccessTokenMaxTTL time.Duration // longest validity of a personal access token
+	ImpersonationTTL  time.Duration // how long an administrator may act as another user
+
+	// Guest accounts of external partners expire at most GuestMaxTTL after
+	// they are created or extended; a sweep every GuestSweepInterval
+	// deactivates expired ones.
+	GuestMaxTTL        time.Duration
+	GuestSweepInterval time.Duration
+}
+
+// LDAPConfig holds the directory that accounts may sign in against. LDAP
+// sign-in is disabled while URL is empty.
+type LDAPConfig struct {
+	URL          string // ldap:// or ldaps://
+	StartTLS     bool
+	BindDN       string // service account that searches for users; empty binds anonymously
+	BindPassword string
+	BaseDN       string
+	UserFilter   string // %s is replaced by the escaped username
+	Timeout      time.Duration
+
+	// GroupRoles maps directory groups to roles as "group:role" entries,
+	// the first match winning. A group is matched by its name, the first
+	// value of its DN in GroupAttribute. Members of no mapped group get
+	// DefaultRole, or cannot sign in while it is empty.
+	GroupAttribute string
+	GroupRoles     []string
+	DefaultRole    string
+
+	// Provision creates the account of a directory user at the first
+	// sign-in.
+	Provision bool
+}
+
+// MailConfig holds the SMTP settings for notifications. Mail is disabled
+// while Host is empty.
+type MailConfig struct {
+	Host     string
+	Port     int
+	User     string
+	Password string
+	From     string
+}
+
+// LogConfig controls the application log.
+type LogConfig struct {
+	Level      string // debug, info, warn or error
+	Format     string // text (logfmt) or json
+	File       string // also write to this file, rotated by size; empty logs to stderr only
+	MaxSize    int64  // bytes before the file is rotated
+	MaxBackups int    // rotated files kept next to File
+}
+
+// Default returns the development configuration every layer starts from.
+func Default() *Config {
+	return &Config{
+		Database: DatabaseConfig{
+			Host:            "localhost",
+			Port:            5432,
+			User:            "postgres",
+			Password:        "123",
+			Name:            "research_institute",
+			SSLMode:         "disable",
+			MaxOpenConns:    10,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 30 * time.Minute,
+		},
+		Server: ServerConfig{Addr: ":8080"},
+		Storage: StorageConfig{
+			UploadPath:       "./uploads",
+			MaxFileSize:      10 << 20,
+			AllowedFileTypes: []string{"pdf", "doc", "docx", "xls", "xlsx", "ppt", "pptx", "txt"},
+		},
+		Auth: AuthConfig{
+			PolicyPath:         "./policy.json",
+			SessionIdleTimeout: 30 * time.Minute,
+			SessionMaxLifetime: 12 * time.Hour,
+			ActivationTTL:      72 * time.Hour,
+
+			LoginDelay:             time.Second,
+			LockoutThreshold:       5,
+			SourceLockoutThreshold: 20,
+			LockoutDuration:        15 * time.Minute,
+
+			PasswordMinLength:  8,
+			PasswordMinClasses: 2,
+			PasswordHistory:    5,
+
+			Argon2Memory:      64 * 1024,
+			Argon2Iterations:  1,
+			Argon2Parallelism: 4,
+
+			TwoFactorRoles:  []string{"administrator", "rukovodilac"},
+			TwoFactorIssuer: "RIIS",
+
+			AccessTokenMaxTTL: 90 * 24 * time.Hour,
+			ImpersonationTTL:  30 * time.Minute,
+
+			GuestMaxTTL:        90 * 24 * time.Hour,
+			GuestSweepInterval: 15 * time.Minute,
+		},
+		LDAP: LDAPConfig{
+			UserFilter:     "(uid=%s)",
+			Timeout:        10 * time.Second,
+			GroupAttribute: "memberOf",
+			Provision:      true,
+		},
+		Mail: MailConfig{Port: 587},
+		Log:  LogConfig{Level: "info", Format: "text", MaxSize: 10 << 20, MaxBackups: 5},
+	}
+}
+
+// Load builds the configuration from all layers. args are the leading
+// command line arguments; Load consumes -config and -set and returns the
+// arguments after them, so commands can follow. Pass nil where there is no
+// command line, as in the desktop app.
+func Load(args []string) (*Config, []string, error) {
+	flags := flag.NewFlagSet("config", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	file := flags.String("config", "", "JSON config file")
+	var overrides []string
+	flags.Func("set", "override a setting, key=value", func(value string) error {
+		overrides = append(overrides, value)
+		return nil
+	})
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, fmt.Errorf("config: %w", err)
+	}
+
+	cfg := Default()
+	cfg.sources = map[string]string{}
+	registry := cfg.settings()
+
+	path, required := *file, true
+	if path == "" {
+		path = os.Getenv("RIIS_CONFIG")
+	}
+	if path == "" {
+		path, required = DefaultFile, false
+	}
+	if err := cfg.loadFile(registry, path, required); err != nil {
+		return nil, nil, err
+	}
+
+	var problems []error
+	for _, s := range registry {
+		if s.env == "" {
+			continue
+		}
+		if value, ok := os.LookupEnv(s.env); ok && value != "" {
+			if err := s.set(value); err != nil {
+				problems = append(problems, fmt.Errorf("%s (%s): %w", s.env, s.key, err))
+				continue
+			}
+			cfg.sources[s.key] = "env " + s.env
+		}
+	}
+
+	for _, override := range overrides {
+		key, value, ok := strings.Cut(override, "=")
+		s := registry.find(key)
+		if !ok || s == nil {
+			problems = append(problems, fmt.Errorf("-set %s: expected key=value with a known key", override))
+			continue
+		}
+		if err := s.set(value); err != nil {
+			problems = append(problems, fmt.Errorf("-set %s: %w", key, err))
+			continue
+		}
+		cfg.sources[key] = "flag"
+	}
+
+	problems = append(problems, cfg.Validate()...)
+	if len(problems) > 0 {
+		return nil, nil, invalid(problems)
+	}
+
+	return cfg, flags.Args(), nil
+}
+
+// Validate reports every setting that cannot work.
+func (c *Config) Validate() []error {
+	var problems []error
+	check := func(ok bool, key, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+		}
+	}
+
+	db := c.Database
+	if db.URL != "" {
+		u, err := url.Parse(db.URL)
+		check(err == nil && (u.Scheme == "postgres" || u.Scheme == "postgresql"), "database.url", "must be a postgres:// URL")
+	} else {
+		check(db.Host != "", "database.host", "must not be empty")
+		check(db.Port > 0 && db.Port < 65536, "database.port", "must be between 1 and 65535, got %d", db.Port)
+		check(db.Name != "", "database.name", "must not be empty")
+	}
+	check(oneOf(db.SSLMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full"), "database.sslmode", "unknown mode %q", db.SSLMode)
+	check(db.MaxOpenConns >= 0, "database.max_open_conns", "must not be negative")
+	check(db.MaxIdleConns >= 0, "database.max_idle_conns", "must not be negative")
+	check(db.MaxOpenConns == 0 || db.MaxIdleConns <= db.MaxOpenConns, "database.max_idle_conns", "must not exceed database.max_open_conns (%d)", db.MaxOpenConns)
+	check(db.ConnMaxLifetime >= 0, "database.conn_max_lifetime", "must not be negative")
+
+	_, _, err := net.SplitHostPort(c.Server.Addr)
+	check(err == nil, "server.addr", "must be host:port or :port, got %q", c.Server.Addr)
+
+	check(c.Storage.UploadPath != "", "storage.upload_path", "must not be empty")
+	check(c.Storage.MaxFileSize > 0, "storage.max_file_size", "must be positive")
+	for _, ext := range c.Storage.AllowedFileTypes {
+		check(ext != "" && !strings.ContainsAny(ext, "./\\ "), "storage.allowed_file_types", "%q is not a file extension", ext)
+	}
+
+	check(c.Auth.SessionIdleTimeout > 0, "auth.session_idle_timeout", "must be positive")
+	check(c.Auth.SessionMaxLifetime >= c.Auth.SessionIdleTimeout, "auth.session_max_lifetime", "must not be shorter than auth.session_idle_timeout")
+	check(c.Auth.ActivationTTL > 0, "auth.activation_ttl", "must be positive")
+	check(c.Auth.LoginDelay >= 0, "auth.login_delay", "must not be negative")
+	check(c.Auth.LockoutThreshold >= 0, "auth.lockout_threshold", "must not be negative")
+	check(c.Auth.SourceLockoutThreshold >= 0, "auth.source_lockout_threshold", "must not be negative")
+	locking := c.Auth.LockoutThreshold > 0 || c.Auth.SourceLockoutThreshold > 0
+	check(!locking || c.Auth.LockoutDuration > 0, "auth.lockout_duration", "must be positive while a lockout threshold is set")
+	check(c.Auth.PasswordMinLength >= 8, "auth.password_min_length", "must be at least 8, got %d", c.Auth.PasswordMinLength)
+	check(c.Auth.PasswordMinClasses >= 1 && c.Auth.PasswordMinClasses <= 4, "auth.password_min_classes", "must be between 1 and 4, got %d", c.Auth.PasswordMinClasses)
+	check(c.Auth.PasswordHistory >= 0, "auth.password_history", "must not be negative")
+	check(c.Auth.Argon2Iterations >= 1, "auth.argon2_iterations", "must be at least 1")
+	check(c.Auth.Argon2Parallelism >= 1 && c.Auth.Argon2Parallelism <= 255, "auth.argon2_parallelism", "must be between 1 and 255, got %d", c.Auth.Argon2Parallelism)
+	check(c.Auth.Argon2Memory >= 8*c.Auth.Argon2Parallelism && c.Auth.Argon2Memory <= 4<<20, "auth.argon2_memory",
+		"must be between 8 KiB per thread of auth.argon2_parallelism and 4 GiB, got %d KiB", c.Auth.Argon2Memory)
+	check(c.Auth.TwoFactorIssuer != "" && !strings.Contains(c.Auth.TwoFactorIssuer, ":"), "auth.two_factor_issuer", "must be non-empty and contain no colon, got %q", c.Auth.TwoFactorIssuer)
+	check(c.Auth.AccessTokenMaxTTL > 0, "auth.access_token_max_ttl", "must be positive")
+	check(c.Auth.ImpersonationTTL > 0, "auth.impersonation_ttl", "must be positive")
+	check(c.Auth.GuestMaxTTL > 0, "auth.guest_max_ttl", "must be positive")
+	check(c.Auth.GuestSweepInterval > 0, "auth.guest_sweep_interval", "must be positive")
+
+	if c.LDAP.URL != "" {
+		ldapURL, err := url.Parse(c.LDAP.URL)
+		check(err == nil && (ldapURL.Scheme == "ldap" || ldapURL.Scheme == "ldaps") && ldapURL.Host != "", "ldap.url", "must be an ldap:// or ldaps:// URL, got %q", c.LDAP.URL)
+		check(c.LDAP.BaseDN != "", "ldap.base_dn", "must not be empty when ldap.url is set")
+		check(strings.Count(c.LDAP.UserFilter, "%s") == 1, "ldap.user_filter", "must contain %%s exactly once, got %q", c.LDAP.UserFilter)
+		check(c.LDAP.GroupAttribute != "", "ldap.group_attribute", "must not be empty when ldap.url is set")
+		check(c.LDAP.Timeout > 0, "ldap.timeout", "must be positive")
+	}
+	for _, entry := range c.LDAP.GroupRoles {
+		group, role, ok := strings.Cut(entry, ":")
+		check(ok && group != "" && role != "", "ldap.group_roles", "%q is not group:role", entry)
+	}
+
+	if c.Mail.Host != "" {
+		check(c.Mail.Port > 0 && c.Mail.Port < 65536, "mail.port", "must be between 1 and 65535, got %d", c.Mail.Port)
+		check(strings.Contains(c.Mail.From, "@"), "mail.from", "must be an e-mail address when mail.host is set")
+	}
+
+	check(oneOf(c.Log.Level, "debug", "info", "warn", "error"), "log.level", "must be debug, info, warn or error, got %q", c.Log.Level)
+	check(oneOf(c.Log.Format, "text", "json"), "log.format", "must be text or json, got %q", c.Log.Format)
+	if c.Log.File != "" {
+		check(c.Log.MaxSize > 0, "log.max_size", "must be positive")
+		check(c.Log.MaxBackups >= 0, "log.max_backups", "must not be negative")
+	}
+
+	return problems
+}
+
+// Source returns the layer that set a key: "default", "file <path>",
+// "env <VAR>" or "flag".
+func (c *Config) Source(key string) string {
+	if source, ok := c.sources[key]; ok {
+		return source
+	}
+	return "default"
+}
+
+// Setting is one effective setting, as shown to operators.
+type Setting struct {
+	Key    string `json:"key"`
+	Env    string `json:"env,omitempty"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// Settings lists the effective value and origin of every setting, with
+// secrets masked.
+func (c *Config) Settings() []Setting {
+	registry := c.settings()
+	out := make([]Setting, 0, len(registry))
+	for _, s := range registry {
+		value := s.get()
+		if s.secret && value != "" {
+			value = "***"
+		}
+		out = append(out, Setting{Key: s.key, Env: s.env, Value: value, Source: c.Source(s.key)})
+	}
+	return out
+}
+
+// DSN returns the lib/pq connection string.
+func (c DatabaseConfig) DSN() string {
+	if c.URL != "" {
+		return c.URL
+	}
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		c.Host, c.Port, c.User, c.Password, c.Name, c.SSLMode)
+}
+
+// Target names the database without credentials, for log lines.
+func (c DatabaseConfig) Target() string {
+	if c.URL != "" {
+		if u, err := url.Parse(c.URL); err == nil {
+			return u.Host + u.Path
+		}
+		return "(database.url)"
+	}
+	return c.User + "@" + c.Host + ":" + strconv.Itoa(c.Port) + "/" + c.Name
+}
+
+// Open opens the database with the configured pool limits. The caller must
+// import the driver.
+func (c DatabaseConfig) Open() (*sql.DB, error) {
+	db, err := sql.Open("postgres", c.DSN())
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetMaxOpenConns(c.MaxOpenConns)
+	db.SetMaxIdleConns(c.MaxIdleConns)
+	db.SetConnMaxLifetime(c.ConnMaxLifetime)
+	return db, nil
+}
+
+// Error lists every problem found while loading the configuration.
+type Error struct {
+	Problems []error
+}
+
+func (e *Error) Error() string {
+	lines := make([]string, 0, len(e.Problems)+1)
+	lines = append(lines, fmt.Sprintf("invalid configuration (%d problem(s)):", len(e.Problems)))
+	for _, p := range e.Problems {
+		lines = append(lines, "  "+p.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (e *Error) Unwrap() []error { return e.Problems }
+
+func invalid(problems []error) error {
+	return &Error{Problems: problems}
 }
 
-func LoadConfig() Config {
-	// You can expand this to read from a file or environment variables
-	return Config{
-		UploadPath: "./uploads", // Default upload path
+func oneOf(value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
 	}
+	return false
 }
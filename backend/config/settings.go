@@ -0,0 +1,239 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// setting binds a key and an environment variable to a field of Config.
+type setting struct {
+	key    string
+	env    string
+	secret bool        // masked in Settings
+	ptr    interface{} // *string, *int, *int64, *bool, *time.Duration or *[]string
+}
+
+type registry []setting
+
+func (r registry) find(key string) *setting {
+	for i := range r {
+		if r[i].key == key {
+			return &r[i]
+		}
+	}
+	return nil
+}
+
+// settings lists every configurable field. Adding a field to Config means
+// adding one line here; the file, env and flag layers pick it up.
+func (c *Config) settings() registry {
+	return registry{
+		{key: "database.url", env: "DATABASE_URL", secret: true, ptr: &c.Database.URL},
+		{key: "database.host", env: "DB_HOST", ptr: &c.Database.Host},
+		{key: "database.port", env: "DB_PORT", ptr: &c.Database.Port},
+		{key: "database.user", env: "DB_USER", ptr: &c.Database.User},
+		{key: "database.password", env: "DB_PASSWORD", secret: true, ptr: &c.Database.Password},
+		{key: "database.name", env: "DB_NAME", ptr: &c.Database.Name},
+		{key: "database.sslmode", env: "DB_SSLMODE", ptr: &c.Database.SSLMode},
+		{key: "database.max_open_conns", env: "DB_MAX_OPEN_CONNS", ptr: &c.Database.MaxOpenConns},
+		{key: "database.max_idle_conns", env: "DB_MAX_IDLE_CONNS", ptr: &c.Database.MaxIdleConns},
+		{key: "database.conn_max_lifetime", env: "DB_CONN_MAX_LIFETIME", ptr: &c.Database.ConnMaxLifetime},
+		{key: "database.auto_migrate", env: "DB_AUTO_MIGRATE", ptr: &c.Database.AutoMigrate},
+
+		{key: "server.addr", env: "API_ADDR", ptr: &c.Server.Addr},
+
+		{key: "storage.upload_path", env: "UPLOAD_PATH", ptr: &c.Storage.UploadPath},
+		{key: "storage.max_file_size", env: "MAX_FILE_SIZE", ptr: &c.Storage.MaxFileSize},
+		{key: "storage.allowed_file_types", env: "ALLOWED_FILE_TYPES", ptr: &c.Storage.AllowedFileTypes},
+
+		{key: "auth.policy_path", env: "POLICY_PATH", ptr: &c.Auth.PolicyPath},
+		{key: "auth.session_idle_timeout", env: "SESSION_IDLE_TIMEOUT", ptr: &c.Auth.SessionIdleTimeout},
+		{key: "auth.session_max_lifetime", env: "SESSION_MAX_LIFETIME", ptr: &c.Auth.SessionMaxLifetime},
+		{key: "auth.activation_ttl", env: "ACTIVATION_TTL", ptr: &c.Auth.ActivationTTL},
+		{key: "auth.login_delay", env: "LOGIN_DELAY", ptr: &c.Auth.LoginDelay},
+		{key: "auth.lockout_threshold", env: "LOCKOUT_THRESHOLD", ptr: &c.Auth.LockoutThreshold},
+		{key: "auth.source_lockout_threshold", env: "SOURCE_LOCKOUT_THRESHOLD", ptr: &c.Auth.SourceLockoutThreshold},
+		{key: "auth.lockout_duration", env: "LOCKOUT_DURATION", ptr: &c.Auth.LockoutDuration},
+		{key: "auth.password_min_length", env: "PASSWORD_MIN_LENGTH", ptr: &c.Auth.PasswordMinLength},
+		{key: "auth.password_min_classes", env: "PASSWORD_MIN_CLASSES", ptr: &c.Auth.PasswordMinClasses},
+		{key: "auth.password_history", env: "PASSWORD_HISTORY", ptr: &c.Auth.PasswordHistory},
+		{key: "auth.argon2_memory", env: "ARGON2_MEMORY", ptr: &c.Auth.Argon2Memory},
+		{key: "auth.argon2_iterations", env: "ARGON2_ITERATIONS", ptr: &c.Auth.Argon2Iterations},
+		{key: "auth.argon2_parallelism", env: "ARGON2_PARALLELISM", ptr: &c.Auth.Argon2Parallelism},
+		{key: "auth.two_factor_roles", env: "TWO_FACTOR_ROLES", ptr: &c.Auth.TwoFactorRoles},
+		{key: "auth.two_factor_issuer", env: "TWO_FACTOR_ISSUER", ptr: &c.Auth.TwoFactorIssuer},
+		{key: "auth.access_token_max_ttl", env: "ACCESS_TOKEN_MAX_TTL", ptr: &c.Auth.AccessTokenMaxTTL},
+		{key: "auth.impersonation_ttl", env: "IMPERSONATION_TTL", ptr: &c.Auth.ImpersonationTTL},
+		{key: "auth.guest_max_ttl", env: "GUEST_MAX_TTL", ptr: &c.Auth.GuestMaxTTL},
+		{key: "auth.guest_sweep_interval", env: "GUEST_SWEEP_INTERVAL", ptr: &c.Auth.GuestSweepInterval},
+
+		{key: "ldap.url", env: "LDAP_URL", ptr: &c.LDAP.URL},
+		{key: "ldap.start_tls", env: "LDAP_START_TLS", ptr: &c.LDAP.StartTLS},
+		{key: "ldap.bind_dn", env: "LDAP_BIND_DN", ptr: &c.LDAP.BindDN},
+		{key: "ldap.bind_password", env: "LDAP_BIND_PASSWORD", secret: true, ptr: &c.LDAP.BindPassword},
+		{key: "ldap.base_dn", env: "LDAP_BASE_DN", ptr: &c.LDAP.BaseDN},
+		{key: "ldap.user_filter", env: "LDAP_USER_FILTER", ptr: &c.LDAP.UserFilter},
+		{key: "ldap.timeout", env: "LDAP_TIMEOUT", ptr: &c.LDAP.Timeout},
+		{key: "ldap.group_attribute", env: "LDAP_GROUP_ATTRIBUTE", ptr: &c.LDAP.GroupAttribute},
+		{key: "ldap.group_roles", env: "LDAP_GROUP_ROLES", ptr: &c.LDAP.GroupRoles},
+		{key: "ldap.default_role", env: "LDAP_DEFAULT_ROLE", ptr: &c.LDAP.DefaultRole},
+		{key: "ldap.provision", env: "LDAP_PROVISION", ptr: &c.LDAP.Provision},
+
+		{key: "mail.host", env: "SMTP_HOST", ptr: &c.Mail.Host},
+		{key: "mail.port", env: "SMTP_PORT", ptr: &c.Mail.Port},
+		{key: "mail.user", env: "SMTP_USER", ptr: &c.Mail.User},
+		{key: "mail.password", env: "SMTP_PASS", secret: true, ptr: &c.Mail.Password},
+		{key: "mail.from", env: "SMTP_FROM", ptr: &c.Mail.From},
+
+		{key: "log.level", env: "LOG_LEVEL", ptr: &c.Log.Level},
+		{key: "log.format", env: "LOG_FORMAT", ptr: &c.Log.Format},
+		{key: "log.file", env: "LOG_FILE", ptr: &c.Log.File},
+		{key: "log.max_size", env: "LOG_MAX_SIZE", ptr: &c.Log.MaxSize},
+		{key: "log.max_backups", env: "LOG_MAX_BACKUPS", ptr: &c.Log.MaxBackups},
+	}
+}
+
+// set parses a textual value into the field. Lists are comma separated.
+func (s *setting) set(value string) error {
+	value = strings.TrimSpace(value)
+
+	switch p := s.ptr.(type) {
+	case *string:
+		*p = value
+	case *int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a whole number", value)
+		}
+		*p = n
+	case *int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a whole number", value)
+		}
+		*p = n
+	case *bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q is not true or false", value)
+		}
+		*p = b
+	case *time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration such as 30m or 12h", value)
+		}
+		*p = d
+	case *[]string:
+		list := []string{}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+				list = append(list, item)
+			}
+		}
+		*p = list
+	default:
+		return fmt.Errorf("unsupported setting type %T", s.ptr)
+	}
+	return nil
+}
+
+// get formats the field the way set reads it.
+func (s *setting) get() string {
+	switch p := s.ptr.(type) {
+	case *string:
+		return *p
+	case *int:
+		return strconv.Itoa(*p)
+	case *int64:
+		return strconv.FormatInt(*p, 10)
+	case *bool:
+		return strconv.FormatBool(*p)
+	case *time.Duration:
+		return p.String()
+	case *[]string:
+		return strings.Join(*p, ",")
+	}
+	return ""
+}
+
+// loadFile applies a JSON config file. Sections are objects keyed like the
+// settings, e.g. {"database": {"host": "db"}}; values may be strings,
+// numbers, booleans or, for lists, arrays. Unknown keys are an error so
+// typos do not go unnoticed.
+func (c *Config) loadFile(settings registry, path string, required bool) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !required {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	values := map[string]string{}
+	var problems []error
+	flatten("", doc, values, &problems)
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := settings.find(key)
+		if s == nil {
+			problems = append(problems, fmt.Errorf("%s: unknown key %q", path, key))
+			continue
+		}
+		if err := s.set(values[key]); err != nil {
+			problems = append(problems, fmt.Errorf("%s: %s: %w", path, key, err))
+			continue
+		}
+		c.sources[key] = "file " + path
+	}
+
+	if len(problems) > 0 {
+		return invalid(problems)
+	}
+	return nil
+}
+
+func flatten(prefix string, node map[string]interface{}, out map[string]string, problems *[]error) {
+	for name, value := range node {
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+
+		switch v := value.(type) {
+		case map[string]interface{}:
+			flatten(key, v, out, problems)
+		case string:
+			out[key] = v
+		case float64:
+			out[key] = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			out[key] = strconv.FormatBool(v)
+		case []interface{}:
+			items := make([]string, 0, len(v))
+			for _, item := range v {
+				items = append(items, fmt.Sprint(item))
+			}
+			out[key] = strings.Join(items, ",")
+		default:
+			*problems = append(*problems, fmt.Errorf("%s: unsupported value %v", key, value))
+		}
+	}
+}
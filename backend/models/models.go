@@ -6,10 +6,24 @@ import "time"
 // Modul 1: Upravljanje Korisnicima i Ulogama
 // =============================================================================
 
-// Uloge represents user roles in the system
+// Uloge represents user roles in the system. A retired role (Povucena set)
+// can no longer be assigned. Sistemska is the stable key of a role the
+// application relies on; such a role cannot be renamed or retired.
 type Uloge struct {
-	UlogaID    int    `json:"uloga_id" db:"uloga_id"`
-	NazivUloge string `json:"naziv_uloge" db:"naziv_uloge"`
+	UlogaID    int            `json:"uloga_id" db:"uloga_id"`
+	NazivUloge string         `json:"naziv_uloge" db:"naziv_uloge"`
+	Opis       *string        `json:"opis,omitempty" db:"opis"`
+	Sistemska  *string        `json:"sistemska,omitempty" db:"sistemska"`
+	Povucena   *time.Time     `json:"povucena,omitempty" db:"povucena" ts_type:"string"`
+	Dozvole    []DozvolaUloge `json:"dozvole,omitempty"`
+}
+
+// DozvolaUloge grants a permission to a role in every project or, when
+// ProjekatID is set, only within that project. Dozvola "*" grants every
+// permission.
+type DozvolaUloge struct {
+	Dozvola    string `json:"dozvola" db:"dozvola"`
+	ProjekatID *int   `json:"projekat_id,omitempty" db:"projekat_id"`
 }
 
 // Korisnici represents system users
@@ -25,10 +39,158 @@ type Korisnici struct {
 	PoslednajaPrijava *time.Time `json:"poslednja_prijava" db:"poslednja_prijava" ts_type:"string"`
 	KreiranDatuma     time.Time  `json:"kreiran_datuma" db:"kreiran_datuma" ts_type:"string"`
 
+	// MoraPromenitiLozinku is set for new and reset accounts; they sign in
+	// with an activation code and must choose a password first
+	MoraPromenitiLozinku bool `json:"mora_promeniti_lozinku" db:"mora_promeniti_lozinku"`
+
+	// Failed sign-ins since the last successful one; enough of them lock the
+	// account until ZakljucanDo
+	NeuspesnePrijave          int        `json:"neuspesne_prijave" db:"neuspesne_prijave"`
+	PoslednjaNeuspesnaPrijava *time.Time `json:"poslednja_neuspesna_prijava" db:"poslednja_neuspesna_prijava" ts_type:"string"`
+	ZakljucanDo               *time.Time `json:"zakljucan_do" db:"zakljucan_do" ts_type:"string"`
+
+	// IzvorPrijave names the provider that checks the password, IzvorLokalni
+	// for accounts with a hash_sifre
+	IzvorPrijave string `json:"izvor_prijave" db:"izvor_prijave"`
+
+	// JedinicaID is the organizational unit the user belongs to, or nil
+	JedinicaID *int `json:"jedinica_id" db:"jedinica_id"`
+
+	// GostDo is set for guest accounts of external partners. They see only
+	// what is shared with them and stop working at this time
+	GostDo *time.Time `json:"gost_do" db:"gost_do" ts_type:"string"`
+
+	// SesijeNevazeceOd ends the sessions opened before it, in every process
+	SesijeNevazeceOd *time.Time `json:"-" db:"sesije_nevazece_od"`
+
 	// Joined fields
 	NazivUloge string `json:"naziv_uloge,omitempty" db:"naziv_uloge"`
 }
 
+// IzvorLokalni is the izvor_prijave of accounts whose password hash is
+// stored in Korisnici, the default for new accounts
+const IzvorLokalni = "lokalni"
+
+// IzvorLDAP is the izvor_prijave of accounts that sign in against the
+// institute directory
+const IzvorLDAP = "ldap"
+
+// AktivacijeNaloga is a single-use activation code of an account. Only the
+// hash of the code is stored
+type AktivacijeNaloga struct {
+	AktivacijaID      int        `json:"aktivacija_id" db:"aktivacija_id"`
+	KorisnikID        int        `json:"korisnik_id" db:"korisnik_id"`
+	HashKoda          string     `json:"-" db:"hash_koda"`
+	Istice            time.Time  `json:"istice" db:"istice" ts_type:"string"`
+	Iskoriscena       *time.Time `json:"iskoriscena" db:"iskoriscena" ts_type:"string"`
+	KreiraoKorisnikID *int       `json:"kreirao_korisnik_id" db:"kreirao_korisnik_id"`
+	KreiranDatuma     time.Time  `json:"kreiran_datuma" db:"kreiran_datuma" ts_type:"string"`
+}
+
+// DvaFaktora is the TOTP second factor of an account. It takes effect once
+// the user confirms it with a first code
+type DvaFaktora struct {
+	KorisnikID     int        `json:"korisnik_id" db:"korisnik_id"`
+	Tajna          string     `json:"-" db:"tajna"`
+	Potvrdjen      *time.Time `json:"potvrdjen" db:"potvrdjen" ts_type:"string"`
+	PoslednjiKorak int64      `json:"-" db:"poslednji_korak"`
+	KreiranDatuma  time.Time  `json:"kreiran_datuma" db:"kreiran_datuma" ts_type:"string"`
+}
+
+// PristupniTokeni is a personal access token for scripts. It acts for its
+// user, limited to the permissions in Opsezi
+type PristupniTokeni struct {
+	TokenID            int        `json:"token_id" db:"token_id"`
+	KorisnikID         int        `json:"korisnik_id" db:"korisnik_id"`
+	Naziv              string     `json:"naziv" db:"naziv"`
+	Prefiks            string     `json:"prefiks" db:"prefiks"`
+	HashTokena         string     `json:"-" db:"hash_tokena"`
+	Opsezi             []string   `json:"opsezi" db:"opsezi"`
+	Istice             time.Time  `json:"istice" db:"istice" ts_type:"string"`
+	PoslednjeKorisceno *time.Time `json:"poslednje_korisceno" db:"poslednje_korisceno" ts_type:"string"`
+	Opozvan            *time.Time `json:"opozvan" db:"opozvan" ts_type:"string"`
+	KreiranDatuma      time.Time  `json:"kreiran_datuma" db:"kreiran_datuma" ts_type:"string"`
+}
+
+// PodesavanjaKorisnika are the preferences of a user. Every client reads
+// them from here, so they follow the user between the desktop app and the
+// web
+type PodesavanjaKorisnika struct {
+	KorisnikID              int       `json:"korisnik_id" db:"korisnik_id"`
+	Jezik                   string    `json:"jezik" db:"jezik"`
+	PodrazumevaniProjekatID *int      `json:"podrazumevani_projekat_id" db:"podrazumevani_projekat_id"`
+	FormatDatuma            string    `json:"format_datuma" db:"format_datuma"`
+	ObavestenjaEmail        bool      `json:"obavestenja_email" db:"obavestenja_email"`
+	ObavestenjaZadaci       bool      `json:"obavestenja_zadaci" db:"obavestenja_zadaci"`
+	ObavestenjaKomentari    bool      `json:"obavestenja_komentari" db:"obavestenja_komentari"`
+	Izmenjeno               time.Time `json:"izmenjeno" db:"izmenjeno" ts_type:"string"`
+}
+
+// PromeneEmaila is a change of email address waiting for the code sent to
+// the new address. Only the hash of the code is stored
+type PromeneEmaila struct {
+	KorisnikID    int       `json:"korisnik_id" db:"korisnik_id"`
+	NoviEmail     string    `json:"novi_email" db:"novi_email"`
+	HashKoda      string    `json:"-" db:"hash_koda"`
+	Istice        time.Time `json:"istice" db:"istice" ts_type:"string"`
+	Pokusaji      int       `json:"-" db:"pokusaji"`
+	KreiranDatuma time.Time `json:"kreiran_datuma" db:"kreiran_datuma" ts_type:"string"`
+}
+
+// Primopredaja names who takes over the work of a departing user. Work of a
+// kind whose target is nil stays with the deactivated account
+type Primopredaja struct {
+	RukovodilacID *int `json:"rukovodilac_id"` // new leader of the user's projects
+	IzvrsilacID   *int `json:"izvrsilac_id"`   // new assignee of the user's open tasks
+	VlasnikID     *int `json:"vlasnik_id"`     // new owner of the user's folders and documents
+}
+
+// OrganizacioneJedinice represents a department, lab or group of the
+// institute. Units form a tree through NadredjenaJedinicaID
+type OrganizacioneJedinice struct {
+	JedinicaID           int       `json:"jedinica_id" db:"jedinica_id"`
+	Naziv                string    `json:"naziv" db:"naziv"`
+	Tip                  string    `json:"tip" db:"tip"` // institut, odeljenje, laboratorija, grupa
+	NadredjenaJedinicaID *int      `json:"nadredjena_jedinica_id" db:"nadredjena_jedinica_id"`
+	RukovodilacID        *int      `json:"rukovodilac_id" db:"rukovodilac_id"`
+	KreiranDatuma        time.Time `json:"kreiran_datuma" db:"kreiran_datuma" ts_type:"string"`
+
+	// Joined fields
+	RukovodilacIme string `json:"rukovodilac_ime,omitempty" db:"rukovodilac_ime"`
+	BrojClanova    int    `json:"broj_clanova" db:"broj_clanova"`
+	BrojProjekata  int    `json:"broj_projekata" db:"broj_projekata"`
+}
+
+// GostujuciPristup shares one project, folder or document with a guest
+// account. Exactly one of ProjekatID, FolderID and DokumentID is set
+type GostujuciPristup struct {
+	PristupID     int       `json:"pristup_id" db:"pristup_id"`
+	KorisnikID    int       `json:"korisnik_id" db:"korisnik_id"`
+	ProjekatID    *int      `json:"projekat_id" db:"projekat_id"`
+	FolderID      *int      `json:"folder_id" db:"folder_id"`
+	DokumentID    *int      `json:"dokument_id" db:"dokument_id"`
+	OdobrioID     *int      `json:"odobrio_id" db:"odobrio_id"`
+	KreiranDatuma time.Time `json:"kreiran_datuma" db:"kreiran_datuma" ts_type:"string"`
+
+	// Joined fields
+	KorisnickoIme string     `json:"korisnicko_ime,omitempty" db:"korisnicko_ime"`
+	GostDo        *time.Time `json:"gost_do,omitempty" db:"gost_do" ts_type:"string"`
+	Naziv         string     `json:"naziv" db:"naziv"` // name of the shared project, folder or document
+	OdobrioIme    string     `json:"odobrio_ime,omitempty" db:"odobrio_ime"`
+}
+
+// IzvestajPrimopredaje lists what moved when a user was offboarded
+type IzvestajPrimopredaje struct {
+	KorisnikID     int          `json:"korisnik_id"`
+	Primopredaja   Primopredaja `json:"primopredaja"`
+	Projekti       []int        `json:"projekti"`
+	Zadaci         []int        `json:"zadaci"`
+	Folderi        []int        `json:"folderi"`
+	Dokumenti      []int        `json:"dokumenti"`
+	OpozvaniTokeni int          `json:"opozvani_tokeni"`
+	ZavrseneSesije int          `json:"zavrsene_sesije"`
+}
+
 // =============================================================================
 // Modul 2: Upravljanje Projektima, Zadacima i Dokumentacijom
 // =============================================================================
@@ -60,6 +222,7 @@ type Projekti struct {
 	Status         string     `json:"status" db:"status"`
 	RukovodilaID   *int       `json:"rukovodilac_id" db:"rukovodilac_id"`
 	RadniTokID     *int       `json:"radni_tok_id" db:"radni_tok_id"`
+	JedinicaID     *int       `json:"jedinica_id" db:"jedinica_id"` // organizational unit, nil for institute-wide projects
 
 	// Joined fields
 	RukovodilaIme string `json:"rukovodilac_ime,omitempty" db:"rukovodilac_ime"`
@@ -223,8 +386,13 @@ type LogAktivnosti struct {
 	CiljaniID      *int      `json:"ciljani_id" db:"ciljani_id"`
 	Datuma         time.Time `json:"datuma" db:"datuma"`
 
+	// StvarniKorisnikID is the administrator who did the activity while
+	// acting as KorisnikID, or nil
+	StvarniKorisnikID *int `json:"stvarni_korisnik_id,omitempty" db:"stvarni_korisnik_id"`
+
 	// Joined fields
-	ImeKorisnika string `json:"ime_korisnika,omitempty" db:"ime_korisnika"`
+	ImeKorisnika         string `json:"ime_korisnika,omitempty" db:"ime_korisnika"`
+	ImeStvarnogKorisnika string `json:"ime_stvarnog_korisnika,omitempty" db:"ime_stvarnog_korisnika"`
 }
 
 // =============================================================================
@@ -260,6 +428,7 @@ type CreateProjectRequest struct {
 	DatumPocetka   *time.Time `json:"datum_pocetka"`
 	DatumZavrsetka *time.Time `json:"datum_zavrsetka"`
 	RadniTokID     *int       `json:"radni_tok_id"`
+	JedinicaID     *int       `json:"jedinica_id"`
 	ClanoviTima    []int      `json:"clanovi_tima"`
 }
 
@@ -302,6 +471,7 @@ type UploadDocumentRequest struct {
 // Type aliases for English names
 type User = Korisnici
 type Role = Uloge
+type RoleGrant = DozvolaUloge
 type Project = Projekti
 type Task = Zadaci
 type Document = Dokumenti
@@ -319,3 +489,12 @@ type DocumentTag = DokumentTagovi
 type DocumentPermission = DozvoleDokumenata
 type DocumentPhaseHistory = IstorijaFazaDokumenta
 type ActivityLog = LogAktivnosti
+type Activation = AktivacijeNaloga
+type TwoFactor = DvaFaktora
+type AccessToken = PristupniTokeni
+type Preferences = PodesavanjaKorisnika
+type EmailChange = PromeneEmaila
+type Handover = Primopredaja
+type HandoverReport = IzvestajPrimopredaje
+type Unit = OrganizacioneJedinice
+type GuestShare = GostujuciPristup
@@ -0,0 +1,141 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/schemacheck"
+)
+
+type AccessTokenRepository struct {
+	db *sql.DB
+}
+
+func NewAccessTokenRepository(db *sql.DB) *AccessTokenRepository {
+	return &AccessTokenRepository{db: db}
+}
+
+const accessTokenColumns = `token_id, korisnik_id, naziv, prefiks, hash_tokena, opsezi, istice,
+	poslednje_korisceno, opozvan, kreiran_datuma`
+
+var accessTokenCreateQuery = schemacheck.Register("AccessTokenRepository.Create", `
+	INSERT INTO PristupniTokeni (korisnik_id, naziv, prefiks, hash_tokena, opsezi, istice)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING token_id, kreiran_datuma
+`)
+
+// Create writes the expiry in UTC, which is how the driver reads a TIMESTAMP
+// without time zone back.
+func (r *AccessTokenRepository) Create(ctx context.Context, token *models.AccessToken) error {
+	err := r.db.QueryRowContext(ctx, accessTokenCreateQuery, token.KorisnikID, token.Naziv, token.Prefiks,
+		token.HashTokena, strings.Join(token.Opsezi, ","), token.Istice.UTC()).Scan(&token.TokenID, &token.KreiranDatuma)
+	if err != nil {
+		return err
+	}
+
+	token.PoslednjeKorisceno = nil
+	token.Opozvan = nil
+	return nil
+}
+
+var accessTokenGetByIDQuery = schemacheck.Register("AccessTokenRepository.GetByID",
+	`SELECT `+accessTokenColumns+` FROM PristupniTokeni WHERE token_id = $1`)
+
+func (r *AccessTokenRepository) GetByID(ctx context.Context, id int) (*models.AccessToken, error) {
+	token, err := scanAccessToken(r.db.QueryRowContext(ctx, accessTokenGetByIDQuery, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound("access token", id)
+	}
+	return token, err
+}
+
+var accessTokenGetByHashQuery = schemacheck.Register("AccessTokenRepository.GetByHash",
+	`SELECT `+accessTokenColumns+` FROM PristupniTokeni WHERE hash_tokena = $1`)
+
+func (r *AccessTokenRepository) GetByHash(ctx context.Context, hash string) (*models.AccessToken, error) {
+	token, err := scanAccessToken(r.db.QueryRowContext(ctx, accessTokenGetByHashQuery, hash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("access token %w", ErrNotFound)
+	}
+	return token, err
+}
+
+var accessTokenListByUserQuery = schemacheck.Register("AccessTokenRepository.ListByUser",
+	`SELECT `+accessTokenColumns+` FROM PristupniTokeni WHERE korisnik_id = $1 ORDER BY token_id DESC`)
+
+func (r *AccessTokenRepository) ListByUser(ctx context.Context, userID int) ([]models.AccessToken, error) {
+	rows, err := r.db.QueryContext(ctx, accessTokenListByUserQuery, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []models.AccessToken{}
+	for rows.Next() {
+		token, err := scanAccessToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, *token)
+	}
+	return tokens, rows.Err()
+}
+
+func scanAccessToken(row rowScanner) (*models.AccessToken, error) {
+	var token models.AccessToken
+	var scopes string
+	var lastUsed, revoked sql.NullTime
+
+	err := row.Scan(&token.TokenID, &token.KorisnikID, &token.Naziv, &token.Prefiks, &token.HashTokena,
+		&scopes, &token.Istice, &lastUsed, &revoked, &token.KreiranDatuma)
+	if err != nil {
+		return nil, err
+	}
+
+	token.Opsezi = strings.Split(scopes, ",")
+	token.PoslednjeKorisceno = nullTime(lastUsed)
+	token.Opozvan = nullTime(revoked)
+	return &token, nil
+}
+
+var accessTokenRevokeQuery = schemacheck.Register("AccessTokenRepository.Revoke", `
+	UPDATE PristupniTokeni SET opozvan = $1 WHERE token_id = $2 AND opozvan IS NULL
+`)
+
+func (r *AccessTokenRepository) Revoke(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, accessTokenRevokeQuery, time.Now().UTC(), id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		if _, err := r.GetByID(ctx, id); err != nil {
+			return err
+		}
+		return fmt.Errorf("access token %d is already revoked: %w", id, ErrConflict)
+	}
+
+	return nil
+}
+
+var accessTokenMarkUsedQuery = schemacheck.Register("AccessTokenRepository.MarkUsed", `
+	UPDATE PristupniTokeni SET poslednje_korisceno = $1 WHERE token_id = $2
+`)
+
+func (r *AccessTokenRepository) MarkUsed(ctx context.Context, id int, at time.Time) error {
+	result, err := r.db.ExecContext(ctx, accessTokenMarkUsedQuery, at.UTC(), id)
+	if err != nil {
+		return err
+	}
+
+	return expectAffected(result, "access token", id)
+}
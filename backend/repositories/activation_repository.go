@@ -0,0 +1,111 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/schemacheck"
+)
+
+type ActivationRepository struct {
+	db *sql.DB
+}
+
+func NewActivationRepository(db *sql.DB) *ActivationRepository {
+	return &ActivationRepository{db: db}
+}
+
+var activationRevokeQuery = schemacheck.Register("ActivationRepository.Revoke", `
+	DELETE FROM AktivacijeNaloga WHERE korisnik_id = $1 AND iskoriscena IS NULL
+`)
+
+var activationCreateQuery = schemacheck.Register("ActivationRepository.Create", `
+	INSERT INTO AktivacijeNaloga (korisnik_id, hash_koda, istice, kreirao_korisnik_id)
+	VALUES ($1, $2, $3, $4)
+	RETURNING aktivacija_id, kreiran_datuma
+`)
+
+// Create writes the expiry in UTC, which is how the driver reads a TIMESTAMP
+// without time zone back.
+func (r *ActivationRepository) Create(ctx context.Context, activation *models.Activation) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, activationRevokeQuery, activation.KorisnikID); err != nil {
+		return err
+	}
+
+	err = tx.QueryRowContext(ctx, activationCreateQuery, activation.KorisnikID, activation.HashKoda,
+		activation.Istice.UTC(), activation.KreiraoKorisnikID).Scan(&activation.AktivacijaID, &activation.KreiranDatuma)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+var activationGetPendingQuery = schemacheck.Register("ActivationRepository.GetPending", `
+	SELECT aktivacija_id, korisnik_id, hash_koda, istice, kreirao_korisnik_id, kreiran_datuma
+	FROM AktivacijeNaloga
+	WHERE korisnik_id = $1 AND iskoriscena IS NULL
+	ORDER BY aktivacija_id DESC
+	LIMIT 1
+`)
+
+func (r *ActivationRepository) GetPending(ctx context.Context, userID int) (*models.Activation, error) {
+	var activation models.Activation
+	var createdBy sql.NullInt64
+
+	err := r.db.QueryRowContext(ctx, activationGetPendingQuery, userID).Scan(
+		&activation.AktivacijaID, &activation.KorisnikID, &activation.HashKoda,
+		&activation.Istice, &createdBy, &activation.KreiranDatuma,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound("activation of user", userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if createdBy.Valid {
+		id := int(createdBy.Int64)
+		activation.KreiraoKorisnikID = &id
+	}
+
+	return &activation, nil
+}
+
+var activationConsumeQuery = schemacheck.Register("ActivationRepository.Consume", `
+	UPDATE AktivacijeNaloga SET iskoriscena = $1 WHERE aktivacija_id = $2 AND iskoriscena IS NULL
+`)
+
+// Consume relies on the iskoriscena IS NULL condition, so of two concurrent
+// sign-ins with the same code only one changes the row.
+func (r *ActivationRepository) Consume(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, activationConsumeQuery, time.Now().UTC(), id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("activation %d is used or does not exist: %w", id, ErrConflict)
+	}
+
+	return nil
+}
+
+func (r *ActivationRepository) Revoke(ctx context.Context, userID int) error {
+	_, err := r.db.ExecContext(ctx, activationRevokeQuery, userID)
+	return err
+}
@@ -0,0 +1,126 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/schemacheck"
+	"github.com/lib/pq"
+)
+
+type AnalyticsRepository struct {
+	db *sql.DB
+}
+
+func NewAnalyticsRepository(db *sql.DB) *AnalyticsRepository {
+	return &AnalyticsRepository{db: db}
+}
+
+// The dashboard queries take the unit IDs as $1; NULL counts every row.
+var (
+	analyticsActiveProjectsQuery = schemacheck.Register("AnalyticsRepository.GetDashboardStats:projects", `
+		SELECT COUNT(*) FROM projekti
+		WHERE status = 'Aktivan' AND ($1::int[] IS NULL OR jedinica_id = ANY($1))
+	`)
+	analyticsTotalDocumentsQuery = schemacheck.Register("AnalyticsRepository.GetDashboardStats:documents", `
+		SELECT COUNT(*) FROM dokumenti d
+		LEFT JOIN projekti p ON d.projekat_id = p.projekat_id
+		WHERE $1::int[] IS NULL OR p.jedinica_id = ANY($1)
+	`)
+	analyticsTasksInProgressQuery = schemacheck.Register("AnalyticsRepository.GetDashboardStats:tasks", `
+		SELECT COUNT(*) FROM zadaci z
+		JOIN projekti p ON z.projekat_id = p.projekat_id
+		WHERE z.progres < 100 AND ($1::int[] IS NULL OR p.jedinica_id = ANY($1))
+	`)
+	analyticsActiveUsersQuery = schemacheck.Register("AnalyticsRepository.GetDashboardStats:users", `
+		SELECT COUNT(*) FROM korisnici
+		WHERE poslednja_prijava > CURRENT_TIMESTAMP - INTERVAL '30 days'
+		  AND ($1::int[] IS NULL OR jedinica_id = ANY($1))
+	`)
+)
+
+func (r *AnalyticsRepository) GetDashboardStats(ctx context.Context, unitIDs []int) (models.DashboardStats, error) {
+	var stats models.DashboardStats
+
+	var units pq.Int64Array
+	if unitIDs != nil {
+		units = make(pq.Int64Array, len(unitIDs))
+		for i, id := range unitIDs {
+			units[i] = int64(id)
+		}
+	}
+
+	// Count active projects
+	err := r.db.QueryRowContext(ctx, analyticsActiveProjectsQuery, units).Scan(&stats.AktivniProjekti)
+	if err != nil {
+		return stats, err
+	}
+
+	// Count total documents
+	err = r.db.QueryRowContext(ctx, analyticsTotalDocumentsQuery, units).Scan(&stats.UkupnoDokumenata)
+	if err != nil {
+		return stats, err
+	}
+
+	// Count tasks in progress
+	err = r.db.QueryRowContext(ctx, analyticsTasksInProgressQuery, units).Scan(&stats.ZadaciUToku)
+	if err != nil {
+		return stats, err
+	}
+
+	// Count active users (logged in last 30 days)
+	err = r.db.QueryRowContext(ctx, analyticsActiveUsersQuery, units).Scan(&stats.AktivniKorisnici)
+	if err != nil {
+		return stats, err
+	}
+
+	return stats, nil
+}
+
+var analyticsGetActivityLogsQuery = schemacheck.Register("AnalyticsRepository.GetActivityLogs", `
+	SELECT l.log_id, l.korisnik_id, l.tip_aktivnosti, l.opis,
+	       l.ciljani_entitet, l.ciljani_id, l.datuma, l.stvarni_korisnik_id,
+	       COALESCE(k.korisnicko_ime, 'System') as ime_korisnika,
+	       COALESCE(s.korisnicko_ime, '') as ime_stvarnog_korisnika
+	FROM LogAktivnosti l
+	LEFT JOIN korisnici k ON l.korisnik_id = k.korisnik_id
+	LEFT JOIN korisnici s ON l.stvarni_korisnik_id = s.korisnik_id
+	ORDER BY l.datuma DESC, l.log_id DESC
+	LIMIT $1
+`)
+
+func (r *AnalyticsRepository) GetActivityLogs(ctx context.Context, limit int) ([]models.ActivityLog, error) {
+	rows, err := r.db.QueryContext(ctx, analyticsGetActivityLogsQuery, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	logs := []models.ActivityLog{}
+	for rows.Next() {
+		var log models.ActivityLog
+		err := rows.Scan(
+			&log.LogID, &log.KorisnikID, &log.TipAktivnosti, &log.Opis,
+			&log.CiljaniEntitet, &log.CiljaniID, &log.Datuma, &log.StvarniKorisnikID,
+			&log.ImeKorisnika, &log.ImeStvarnogKorisnika,
+		)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, log)
+	}
+
+	return logs, rows.Err()
+}
+
+var analyticsLogActivityQuery = schemacheck.Register("AnalyticsRepository.LogActivity", `
+	INSERT INTO LogAktivnosti (korisnik_id, tip_aktivnosti, opis, ciljani_entitet, ciljani_id, stvarni_korisnik_id)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING log_id, datuma
+`)
+
+func (r *AnalyticsRepository) LogActivity(ctx context.Context, entry *models.ActivityLog) error {
+	return r.db.QueryRowContext(ctx, analyticsLogActivityQuery, entry.KorisnikID, entry.TipAktivnosti,
+		entry.Opis, entry.CiljaniEntitet, entry.CiljaniID, entry.StvarniKorisnikID).Scan(&entry.LogID, &entry.Datuma)
+}
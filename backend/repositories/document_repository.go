@@ -0,0 +1,446 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/schemacheck"
+)
+
+type DocumentRepository struct {
+	db *sql.DB
+}
+
+func NewDocumentRepository(db *sql.DB) *DocumentRepository {
+	return &DocumentRepository{db: db}
+}
+
+// documentSelect joins the project, author and phase names and counts the
+// versions of each document.
+const documentSelect = `
+	SELECT d.dokument_id, d.projekat_id, d.naziv_dokumenta, d.folder_id,
+	       d.opis, d.tip_dokumenta, d.jezik_dokumenta, d.radni_tok_id,
+	       d.trenutna_faza_id, d.kreirao_korisnik_id, d.datuma_postavke,
+	       d.poslednja_izmena,
+	       COALESCE(p.naziv_projekta, '') as naziv_projekta,
+	       k.korisnicko_ime as ime_kreirao,
+	       COALESCE(f.naziv_faze, '') as naziv_faze,
+	       COALESCE(v.version_count, 0) as broj_verzija
+	FROM dokumenti d
+	LEFT JOIN projekti p ON d.projekat_id = p.projekat_id
+	JOIN korisnici k ON d.kreirao_korisnik_id = k.korisnik_id
+	LEFT JOIN faze f ON d.trenutna_faza_id = f.faza_id
+	LEFT JOIN (
+		SELECT dokument_id, COUNT(*) as version_count
+		FROM verzijedokumenata
+		GROUP BY dokument_id
+	) v ON d.dokument_id = v.dokument_id
+`
+
+func scanDocument(row rowScanner) (models.Document, error) {
+	var doc models.Document
+	err := row.Scan(
+		&doc.DokumentID, &doc.ProjekatID, &doc.NazivDokumenta, &doc.FolderID,
+		&doc.Opis, &doc.TipDokumenta, &doc.JezikDokumenta, &doc.RadniTokID,
+		&doc.TrenutnaFazaID, &doc.KreiraoKorisnikID, &doc.DatumaPostavke,
+		&doc.PoslednjaIzmena, &doc.NazivProjekta, &doc.ImeKreirao,
+		&doc.NazivFaze, &doc.BrojVerzija,
+	)
+	return doc, err
+}
+
+func (r *DocumentRepository) queryDocuments(ctx context.Context, query string, args ...interface{}) ([]models.Document, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	documents := []models.Document{}
+	for rows.Next() {
+		doc, err := scanDocument(rows)
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, doc)
+	}
+
+	return documents, rows.Err()
+}
+
+var documentGetAllQuery = schemacheck.Register("DocumentRepository.GetAll", documentSelect+`
+	ORDER BY d.datuma_postavke DESC, d.dokument_id DESC
+`)
+
+func (r *DocumentRepository) GetAll(ctx context.Context) ([]models.Document, error) {
+	return r.queryDocuments(ctx, documentGetAllQuery)
+}
+
+var documentGetByProjectQuery = schemacheck.Register("DocumentRepository.GetByProject", documentSelect+`
+	WHERE d.projekat_id = $1
+	ORDER BY d.datuma_postavke DESC, d.dokument_id DESC
+`)
+
+func (r *DocumentRepository) GetByProject(ctx context.Context, projectID int) ([]models.Document, error) {
+	return r.queryDocuments(ctx, documentGetByProjectQuery, projectID)
+}
+
+var documentGetByIDQuery = schemacheck.Register("DocumentRepository.GetByID", documentSelect+`
+	WHERE d.dokument_id = $1
+`)
+
+func (r *DocumentRepository) GetByID(ctx context.Context, id int) (*models.Document, error) {
+	doc, err := scanDocument(r.db.QueryRowContext(ctx, documentGetByIDQuery, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound("document", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+var (
+	documentCreateQuery = schemacheck.Register("DocumentRepository.Create", `
+		INSERT INTO dokumenti (projekat_id, naziv_dokumenta, folder_id, opis,
+		                      tip_dokumenta, jezik_dokumenta, kreirao_korisnik_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING dokument_id, datuma_postavke
+	`)
+	documentCreateVersionQuery = schemacheck.Register("DocumentRepository.Create:version", `
+		INSERT INTO verzijedokumenata (dokument_id, verzija_oznaka, putanja_do_fajla,
+		                               velicina_fajla_mb, postavio_korisnik_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING verzija_id, datuma_postavke
+	`)
+)
+
+func (r *DocumentRepository) Create(ctx context.Context, doc *models.Document, version *models.DocumentVersion, tags []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, documentCreateQuery, doc.ProjekatID, doc.NazivDokumenta, doc.FolderID,
+		doc.Opis, doc.TipDokumenta, doc.JezikDokumenta, doc.KreiraoKorisnikID).
+		Scan(&doc.DokumentID, &doc.DatumaPostavke)
+	if err != nil {
+		return err
+	}
+
+	version.DokumentID = doc.DokumentID
+	err = tx.QueryRowContext(ctx, documentCreateVersionQuery, version.DokumentID, version.VerzijaOznaka,
+		version.PutanjaDoFajla, version.VelicinafajlaMB, version.PostavioKorisnikID).
+		Scan(&version.VerzijaID, &version.DatumaPostavke)
+	if err != nil {
+		return err
+	}
+
+	for _, tagName := range tags {
+		if err := addTag(ctx, tx, doc.DokumentID, tagName); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+var (
+	documentFindTagQuery = schemacheck.Register("DocumentRepository.addTag:find",
+		"SELECT tag_id FROM tagovi WHERE naziv_taga = $1")
+	documentCreateTagQuery = schemacheck.Register("DocumentRepository.addTag:create",
+		"INSERT INTO tagovi (naziv_taga) VALUES ($1) RETURNING tag_id")
+	documentLinkTagQuery = schemacheck.Register("DocumentRepository.addTag:link",
+		"INSERT INTO dokumenttagovi (dokument_id, tag_id) VALUES ($1, $2) ON CONFLICT DO NOTHING")
+)
+
+// addTag links the named tag to a document, creating the tag if needed.
+func addTag(ctx context.Context, q queryer, documentID int, tagName string) error {
+	var tagID int
+	err := q.QueryRowContext(ctx, documentFindTagQuery, tagName).Scan(&tagID)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = q.QueryRowContext(ctx, documentCreateTagQuery, tagName).Scan(&tagID)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = q.ExecContext(ctx, documentLinkTagQuery, documentID, tagID)
+	return err
+}
+
+var documentUpdateQuery = schemacheck.Register("DocumentRepository.Update", `
+	UPDATE dokumenti
+	SET naziv_dokumenta = $1, projekat_id = $2, folder_id = $3, opis = $4,
+	    tip_dokumenta = $5, jezik_dokumenta = $6, poslednja_izmena = CURRENT_TIMESTAMP
+	WHERE dokument_id = $7
+`)
+
+func (r *DocumentRepository) Update(ctx context.Context, doc *models.Document) error {
+	result, err := r.db.ExecContext(ctx, documentUpdateQuery, doc.NazivDokumenta, doc.ProjekatID, doc.FolderID,
+		doc.Opis, doc.TipDokumenta, doc.JezikDokumenta, doc.DokumentID)
+	if err != nil {
+		return err
+	}
+
+	return expectAffected(result, "document", doc.DokumentID)
+}
+
+var (
+	documentDeleteFilesQuery = schemacheck.Register("DocumentRepository.Delete:files",
+		"SELECT putanja_do_fajla FROM verzijedokumenata WHERE dokument_id = $1 ORDER BY putanja_do_fajla")
+	documentDeleteQuery = schemacheck.Register("DocumentRepository.Delete",
+		"DELETE FROM dokumenti WHERE dokument_id = $1")
+)
+
+func (r *DocumentRepository) Delete(ctx context.Context, id int) ([]string, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, documentDeleteFilesQuery, id)
+	if err != nil {
+		return nil, err
+	}
+
+	filePaths := []string{}
+	for rows.Next() {
+		var filePath string
+		if err := rows.Scan(&filePath); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		filePaths = append(filePaths, filePath)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Versions, tags and metadata go with the document (ON DELETE CASCADE)
+	result, err := tx.ExecContext(ctx, documentDeleteQuery, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := expectAffected(result, "document", id); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return filePaths, nil
+}
+
+var documentGetVersionsQuery = schemacheck.Register("DocumentRepository.GetVersions", `
+	SELECT v.verzija_id, v.dokument_id, v.verzija_oznaka, v.putanja_do_fajla,
+	       v.velicina_fajla_mb, v.postavio_korisnik_id, v.datuma_postavke
+	FROM verzijedokumenata v
+	WHERE v.dokument_id = $1
+	ORDER BY v.datuma_postavke DESC, v.verzija_id DESC
+`)
+
+func (r *DocumentRepository) GetVersions(ctx context.Context, documentID int) ([]models.DocumentVersion, error) {
+	rows, err := r.db.QueryContext(ctx, documentGetVersionsQuery, documentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := []models.DocumentVersion{}
+	for rows.Next() {
+		var version models.DocumentVersion
+		err := rows.Scan(
+			&version.VerzijaID, &version.DokumentID, &version.VerzijaOznaka,
+			&version.PutanjaDoFajla, &version.VelicinafajlaMB, &version.PostavioKorisnikID,
+			&version.DatumaPostavke,
+		)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+
+	return versions, rows.Err()
+}
+
+var documentGetTagsQuery = schemacheck.Register("DocumentRepository.GetTags", `
+	SELECT t.tag_id, t.naziv_taga
+	FROM tagovi t
+	JOIN dokumenttagovi dt ON t.tag_id = dt.tag_id
+	WHERE dt.dokument_id = $1
+	ORDER BY t.naziv_taga
+`)
+
+func (r *DocumentRepository) GetTags(ctx context.Context, documentID int) ([]models.Tag, error) {
+	rows, err := r.db.QueryContext(ctx, documentGetTagsQuery, documentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []models.Tag{}
+	for rows.Next() {
+		var tag models.Tag
+		if err := rows.Scan(&tag.TagID, &tag.NazivTaga); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, rows.Err()
+}
+
+func (r *DocumentRepository) AddTag(ctx context.Context, documentID int, tagName string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := addTag(ctx, tx, documentID, tagName); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+var documentRemoveTagQuery = schemacheck.Register("DocumentRepository.RemoveTag", `DELETE FROM dokumenttagovi WHERE dokument_id = $1 AND tag_id = $2`)
+
+func (r *DocumentRepository) RemoveTag(ctx context.Context, documentID, tagID int) error {
+	_, err := r.db.ExecContext(ctx, documentRemoveTagQuery, documentID, tagID)
+	return err
+}
+
+var documentGetMetadataQuery = schemacheck.Register("DocumentRepository.GetMetadata", `
+	SELECT meta_id, dokument_id, kljuc, vrednost
+	FROM metapodaci
+	WHERE dokument_id = $1
+	ORDER BY kljuc
+`)
+
+func (r *DocumentRepository) GetMetadata(ctx context.Context, documentID int) ([]models.Metadata, error) {
+	rows, err := r.db.QueryContext(ctx, documentGetMetadataQuery, documentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	metadata := []models.Metadata{}
+	for rows.Next() {
+		var meta models.Metadata
+		if err := rows.Scan(&meta.MetaID, &meta.DokumentID, &meta.Kljuc, &meta.Vrednost); err != nil {
+			return nil, err
+		}
+		metadata = append(metadata, meta)
+	}
+
+	return metadata, rows.Err()
+}
+
+var (
+	documentClearMetadataQuery = schemacheck.Register("DocumentRepository.ReplaceMetadata:clear",
+		"DELETE FROM metapodaci WHERE dokument_id = $1")
+	documentInsertMetadataQuery = schemacheck.Register("DocumentRepository.ReplaceMetadata",
+		"INSERT INTO metapodaci (dokument_id, kljuc, vrednost) VALUES ($1, $2, $3)")
+)
+
+func (r *DocumentRepository) ReplaceMetadata(ctx context.Context, documentID int, metadata []models.Metadata) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, documentClearMetadataQuery, documentID); err != nil {
+		return err
+	}
+
+	for _, meta := range metadata {
+		if _, err := tx.ExecContext(ctx, documentInsertMetadataQuery, documentID, meta.Kljuc, meta.Vrednost); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+var documentGetFoldersQuery = schemacheck.Register("DocumentRepository.GetFolders", `
+	SELECT folder_id, naziv_foldera, roditelj_folder_id, vlasnik_id
+	FROM folderi
+	WHERE vlasnik_id = $1
+	ORDER BY naziv_foldera
+`)
+
+func (r *DocumentRepository) GetFolders(ctx context.Context, ownerID int) ([]models.Folder, error) {
+	rows, err := r.db.QueryContext(ctx, documentGetFoldersQuery, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	folders := []models.Folder{}
+	for rows.Next() {
+		var folder models.Folder
+		err := rows.Scan(&folder.FolderID, &folder.NazivFoldera,
+			&folder.RoditeljFolderID, &folder.VlasnikID)
+		if err != nil {
+			return nil, err
+		}
+		folders = append(folders, folder)
+	}
+
+	return folders, rows.Err()
+}
+
+var documentGetFolderQuery = schemacheck.Register("DocumentRepository.GetFolder", `
+	SELECT folder_id, naziv_foldera, roditelj_folder_id, vlasnik_id
+	FROM folderi
+	WHERE folder_id = $1
+`)
+
+func (r *DocumentRepository) GetFolder(ctx context.Context, id int) (*models.Folder, error) {
+	var folder models.Folder
+	err := r.db.QueryRowContext(ctx, documentGetFolderQuery, id).Scan(&folder.FolderID, &folder.NazivFoldera,
+		&folder.RoditeljFolderID, &folder.VlasnikID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound("folder", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &folder, nil
+}
+
+var documentCreateFolderQuery = schemacheck.Register("DocumentRepository.CreateFolder", `
+	INSERT INTO folderi (naziv_foldera, roditelj_folder_id, vlasnik_id)
+	VALUES ($1, $2, $3)
+	RETURNING folder_id
+`)
+
+func (r *DocumentRepository) CreateFolder(ctx context.Context, folder *models.Folder) error {
+	return r.db.QueryRowContext(ctx, documentCreateFolderQuery, folder.NazivFoldera,
+		folder.RoditeljFolderID, folder.VlasnikID).Scan(&folder.FolderID)
+}
+
+var documentDeleteFolderQuery = schemacheck.Register("DocumentRepository.DeleteFolder", `DELETE FROM folderi WHERE folder_id = $1`)
+
+func (r *DocumentRepository) DeleteFolder(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, documentDeleteFolderQuery, id)
+	if err != nil {
+		return err
+	}
+
+	return expectAffected(result, "folder", id)
+}
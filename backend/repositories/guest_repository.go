@@ -0,0 +1,256 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/schemacheck"
+	"github.com/lib/pq"
+)
+
+type GuestRepository struct {
+	db *sql.DB
+}
+
+func NewGuestRepository(db *sql.DB) *GuestRepository {
+	return &GuestRepository{db: db}
+}
+
+// guestShareSelect joins the guest, the name of the shared object and the
+// user who shared it.
+const guestShareSelect = `
+	SELECT g.pristup_id, g.korisnik_id, g.projekat_id, g.folder_id, g.dokument_id,
+	       g.odobrio_id, g.kreiran_datuma, k.korisnicko_ime, k.gost_do,
+	       COALESCE(p.naziv_projekta, f.naziv_foldera, d.naziv_dokumenta, '') as naziv,
+	       COALESCE(o.korisnicko_ime, '') as odobrio_ime
+	FROM GostujuciPristup g
+	JOIN Korisnici k ON g.korisnik_id = k.korisnik_id
+	LEFT JOIN Projekti p ON g.projekat_id = p.projekat_id
+	LEFT JOIN Folderi f ON g.folder_id = f.folder_id
+	LEFT JOIN Dokumenti d ON g.dokument_id = d.dokument_id
+	LEFT JOIN Korisnici o ON g.odobrio_id = o.korisnik_id`
+
+func scanGuestShare(row rowScanner) (*models.GuestShare, error) {
+	var share models.GuestShare
+	var guestUntil sql.NullTime
+	err := row.Scan(
+		&share.PristupID, &share.KorisnikID, &share.ProjekatID, &share.FolderID, &share.DokumentID,
+		&share.OdobrioID, &share.KreiranDatuma, &share.KorisnickoIme, &guestUntil,
+		&share.Naziv, &share.OdobrioIme,
+	)
+	if err != nil {
+		return nil, err
+	}
+	share.GostDo = nullTime(guestUntil)
+	return &share, nil
+}
+
+func (r *GuestRepository) queryShares(ctx context.Context, query string, args ...interface{}) ([]models.GuestShare, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shares := []models.GuestShare{}
+	for rows.Next() {
+		share, err := scanGuestShare(rows)
+		if err != nil {
+			return nil, err
+		}
+		shares = append(shares, *share)
+	}
+	return shares, rows.Err()
+}
+
+var guestGetSharesQuery = schemacheck.Register("GuestRepository.GetShares", guestShareSelect+`
+	WHERE g.korisnik_id = $1
+	ORDER BY g.kreiran_datuma, g.pristup_id
+`)
+
+func (r *GuestRepository) GetShares(ctx context.Context, userID int) ([]models.GuestShare, error) {
+	return r.queryShares(ctx, guestGetSharesQuery, userID)
+}
+
+var guestGetAllSharesQuery = schemacheck.Register("GuestRepository.GetAllShares", guestShareSelect+`
+	WHERE k.gost_do IS NOT NULL AND k.status = 'aktivan'
+	ORDER BY k.korisnicko_ime, g.kreiran_datuma, g.pristup_id
+`)
+
+func (r *GuestRepository) GetAllShares(ctx context.Context) ([]models.GuestShare, error) {
+	return r.queryShares(ctx, guestGetAllSharesQuery)
+}
+
+var guestGetShareQuery = schemacheck.Register("GuestRepository.GetShare", guestShareSelect+`
+	WHERE g.pristup_id = $1
+`)
+
+func (r *GuestRepository) GetShare(ctx context.Context, id int) (*models.GuestShare, error) {
+	share, err := scanGuestShare(r.db.QueryRowContext(ctx, guestGetShareQuery, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound("guest share", id)
+	}
+	return share, err
+}
+
+var guestShareQuery = schemacheck.Register("GuestRepository.Share", `
+	INSERT INTO GostujuciPristup (korisnik_id, projekat_id, folder_id, dokument_id, odobrio_id)
+	VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT DO NOTHING
+	RETURNING pristup_id, kreiran_datuma
+`)
+
+func (r *GuestRepository) Share(ctx context.Context, share *models.GuestShare) error {
+	err := r.db.QueryRowContext(ctx, guestShareQuery, share.KorisnikID, share.ProjekatID, share.FolderID,
+		share.DokumentID, share.OdobrioID).Scan(&share.PristupID, &share.KreiranDatuma)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("already shared with user %d: %w", share.KorisnikID, ErrConflict)
+	}
+	return err
+}
+
+var guestUnshareQuery = schemacheck.Register("GuestRepository.Unshare",
+	`DELETE FROM GostujuciPristup WHERE pristup_id = $1`)
+
+func (r *GuestRepository) Unshare(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, guestUnshareQuery, id)
+	if err != nil {
+		return err
+	}
+
+	return expectAffected(result, "guest share", id)
+}
+
+var guestSetExpiryQuery = schemacheck.Register("GuestRepository.SetExpiry", `
+	UPDATE Korisnici SET gost_do = $1
+	WHERE korisnik_id = $2 AND gost_do IS NOT NULL
+`)
+
+func (r *GuestRepository) SetExpiry(ctx context.Context, userID int, until time.Time) error {
+	result, err := r.db.ExecContext(ctx, guestSetExpiryQuery, until.UTC(), userID)
+	if err != nil {
+		return err
+	}
+
+	return expectAffected(result, "guest", userID)
+}
+
+var (
+	guestExpireQuery = schemacheck.Register("GuestRepository.ExpireGuests", `
+		UPDATE Korisnici SET status = 'neaktivan'
+		WHERE gost_do IS NOT NULL AND gost_do <= $1 AND status = 'aktivan'
+		RETURNING korisnik_id
+	`)
+	guestExpireSharesQuery = schemacheck.Register("GuestRepository.ExpireGuests.shares",
+		`DELETE FROM GostujuciPristup WHERE korisnik_id = ANY($1)`)
+)
+
+func (r *GuestRepository) ExpireGuests(ctx context.Context, at time.Time) ([]int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, guestExpireQuery, at.UTC())
+	if err != nil {
+		return nil, err
+	}
+	expired := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		expired = append(expired, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(expired) == 0 {
+		return expired, nil
+	}
+
+	ids := make(pq.Int64Array, len(expired))
+	for i, id := range expired {
+		ids[i] = int64(id)
+	}
+	if _, err := tx.ExecContext(ctx, guestExpireSharesQuery, ids); err != nil {
+		return nil, err
+	}
+
+	return expired, tx.Commit()
+}
+
+var (
+	guestFoldersQuery = schemacheck.Register("GuestRepository.GetSharedDocuments.folders", `
+		SELECT f.folder_id, f.roditelj_folder_id, g.pristup_id IS NOT NULL as podeljen
+		FROM Folderi f
+		LEFT JOIN GostujuciPristup g ON g.folder_id = f.folder_id AND g.korisnik_id = $1
+	`)
+	guestDocumentsQuery = schemacheck.Register("GuestRepository.GetSharedDocuments", documentSelect+`
+		WHERE d.projekat_id IN (SELECT projekat_id FROM GostujuciPristup WHERE korisnik_id = $1)
+		   OR d.folder_id = ANY($2)
+		   OR d.dokument_id IN (SELECT dokument_id FROM GostujuciPristup WHERE korisnik_id = $1)
+		ORDER BY d.datuma_postavke DESC, d.dokument_id DESC
+	`)
+)
+
+func (r *GuestRepository) GetSharedDocuments(ctx context.Context, userID int) ([]models.Document, error) {
+	rows, err := r.db.QueryContext(ctx, guestFoldersQuery, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	children := map[int][]int{}
+	var shared []int
+	for rows.Next() {
+		var id int
+		var parent *int
+		var isShared bool
+		if err := rows.Scan(&id, &parent, &isShared); err != nil {
+			return nil, err
+		}
+		if parent != nil {
+			children[*parent] = append(children[*parent], id)
+		}
+		if isShared {
+			shared = append(shared, id)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	folders := pq.Int64Array{}
+	for _, id := range sharedFolders(shared, children) {
+		folders = append(folders, int64(id))
+	}
+
+	documents := &DocumentRepository{db: r.db}
+	return documents.queryDocuments(ctx, guestDocumentsQuery, userID, folders)
+}
+
+// sharedFolders returns the shared folders with all of their subfolders,
+// given the subfolders of each folder.
+func sharedFolders(shared []int, children map[int][]int) []int {
+	seen := map[int]bool{}
+	all := []int{}
+	for len(shared) > 0 {
+		id := shared[len(shared)-1]
+		shared = shared[:len(shared)-1]
+		if !seen[id] {
+			seen[id] = true
+			all = append(all, id)
+			shared = append(shared, children[id]...)
+		}
+	}
+	return all
+}
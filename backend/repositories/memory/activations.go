@@ -0,0 +1,87 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/repositories"
+)
+
+type activationStore struct{ *state }
+
+func (s *activationStore) Create(ctx context.Context, activation *models.Activation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.requireUser(activation.KorisnikID); err != nil {
+		return err
+	}
+	if err := s.requireOptionalUser(activation.KreiraoKorisnikID); err != nil {
+		return err
+	}
+	for _, other := range s.activations {
+		if other.HashKoda == activation.HashKoda {
+			return violation("activation code hash already exists")
+		}
+	}
+
+	s.revoke(activation.KorisnikID)
+
+	activation.AktivacijaID = s.next("aktivacijenaloga")
+	activation.Iskoriscena = nil
+	activation.KreiranDatuma = now()
+	s.activations[activation.AktivacijaID] = *activation
+	return nil
+}
+
+func (s *activationStore) GetPending(ctx context.Context, userID int) (*models.Activation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var pending *models.Activation
+	for _, activation := range s.activations {
+		if activation.KorisnikID != userID || activation.Iskoriscena != nil {
+			continue
+		}
+		if pending == nil || activation.AktivacijaID > pending.AktivacijaID {
+			activation := activation
+			pending = &activation
+		}
+	}
+	if pending == nil {
+		return nil, notFound("activation of user", userID)
+	}
+	return pending, nil
+}
+
+func (s *activationStore) Consume(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	activation, ok := s.activations[id]
+	if !ok || activation.Iskoriscena != nil {
+		return fmt.Errorf("activation %d is used or does not exist: %w", id, repositories.ErrConflict)
+	}
+
+	used := now()
+	activation.Iskoriscena = &used
+	s.activations[id] = activation
+	return nil
+}
+
+func (s *activationStore) Revoke(ctx context.Context, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.revoke(userID)
+	return nil
+}
+
+func (s *activationStore) revoke(userID int) {
+	for id, activation := range s.activations {
+		if activation.KorisnikID == userID && activation.Iskoriscena == nil {
+			delete(s.activations, id)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/cane/research-institute-system/backend/models"
+)
+
+type analyticsStore struct{ *state }
+
+func (s *analyticsStore) GetDashboardStats(ctx context.Context, unitIDs []int) (models.DashboardStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	units := map[int]bool{}
+	for _, id := range unitIDs {
+		units[id] = true
+	}
+	counted := func(unitID *int) bool {
+		return unitIDs == nil || unitID != nil && units[*unitID]
+	}
+	projectCounted := func(projectID *int) bool {
+		if unitIDs == nil {
+			return true
+		}
+		return projectID != nil && counted(s.projects[*projectID].JedinicaID)
+	}
+
+	var stats models.DashboardStats
+	for _, project := range s.projects {
+		if project.Status == "Aktivan" && counted(project.JedinicaID) {
+			stats.AktivniProjekti++
+		}
+	}
+	for _, doc := range s.documents {
+		if projectCounted(doc.ProjekatID) {
+			stats.UkupnoDokumenata++
+		}
+	}
+	for _, task := range s.tasks {
+		if task.Progres < 100 && projectCounted(&task.ProjekatID) {
+			stats.ZadaciUToku++
+		}
+	}
+	since := time.Now().AddDate(0, 0, -30)
+	for _, user := range s.users {
+		if user.PoslednajaPrijava != nil && user.PoslednajaPrijava.After(since) && counted(user.JedinicaID) {
+			stats.AktivniKorisnici++
+		}
+	}
+
+	return stats, nil
+}
+
+func (s *analyticsStore) GetActivityLogs(ctx context.Context, limit int) ([]models.ActivityLog, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	logs := []models.ActivityLog{}
+	for _, entry := range s.logs {
+		entry.ImeKorisnika = "System"
+		if entry.KorisnikID != nil {
+			entry.ImeKorisnika = s.users[*entry.KorisnikID].KorisnickoIme
+		}
+		entry.ImeStvarnogKorisnika = s.username(entry.StvarniKorisnikID)
+		logs = append(logs, entry)
+	}
+	sort.Slice(logs, func(i, j int) bool {
+		if !logs[i].Datuma.Equal(logs[j].Datuma) {
+			return logs[i].Datuma.After(logs[j].Datuma)
+		}
+		return logs[i].LogID > logs[j].LogID
+	})
+	if limit >= 0 && len(logs) > limit {
+		logs = logs[:limit]
+	}
+
+	return logs, nil
+}
+
+func (s *analyticsStore) LogActivity(ctx context.Context, entry *models.ActivityLog) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.requireOptionalUser(entry.KorisnikID); err != nil {
+		return err
+	}
+	if err := s.requireOptionalUser(entry.StvarniKorisnikID); err != nil {
+		return err
+	}
+
+	entry.LogID = int64(s.next("logaktivnosti"))
+	entry.Datuma = now()
+
+	stored := *entry
+	stored.ImeKorisnika, stored.ImeStvarnogKorisnika = "", ""
+	s.logs[entry.LogID] = stored
+	return nil
+}
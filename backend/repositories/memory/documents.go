@@ -0,0 +1,362 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/cane/research-institute-system/backend/models"
+)
+
+type documentStore struct{ *state }
+
+// joined returns a copy of the document with project, author and phase names
+// and its version count.
+func (s *documentStore) joined(doc models.Document) models.Document {
+	doc.NazivProjekta = ""
+	if doc.ProjekatID != nil {
+		doc.NazivProjekta = s.projects[*doc.ProjekatID].NazivProjekta
+	}
+	doc.ImeKreirao = s.users[doc.KreiraoKorisnikID].KorisnickoIme
+	doc.NazivFaze = ""
+	if doc.TrenutnaFazaID != nil {
+		doc.NazivFaze = s.phases[*doc.TrenutnaFazaID].NazivFaze
+	}
+	doc.BrojVerzija = 0
+	for _, version := range s.versions {
+		if version.DokumentID == doc.DokumentID {
+			doc.BrojVerzija++
+		}
+	}
+	return doc
+}
+
+func (s *documentStore) list(keep func(models.Document) bool) []models.Document {
+	documents := []models.Document{}
+	for _, doc := range s.documents {
+		if keep(doc) {
+			documents = append(documents, s.joined(doc))
+		}
+	}
+	sort.Slice(documents, func(i, j int) bool {
+		if !documents[i].DatumaPostavke.Equal(documents[j].DatumaPostavke) {
+			return documents[i].DatumaPostavke.After(documents[j].DatumaPostavke)
+		}
+		return documents[i].DokumentID > documents[j].DokumentID
+	})
+	return documents
+}
+
+func (s *documentStore) GetAll(ctx context.Context) ([]models.Document, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.list(func(models.Document) bool { return true }), nil
+}
+
+func (s *documentStore) GetByProject(ctx context.Context, projectID int) ([]models.Document, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.list(func(doc models.Document) bool {
+		return doc.ProjekatID != nil && *doc.ProjekatID == projectID
+	}), nil
+}
+
+func (s *documentStore) GetByID(ctx context.Context, id int) (*models.Document, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc, ok := s.documents[id]
+	if !ok {
+		return nil, notFound("document", id)
+	}
+	doc = s.joined(doc)
+	return &doc, nil
+}
+
+func (s *documentStore) checkReferences(doc *models.Document) error {
+	if doc.ProjekatID != nil {
+		if _, ok := s.projects[*doc.ProjekatID]; !ok {
+			return violation("project %d does not exist", *doc.ProjekatID)
+		}
+	}
+	if doc.FolderID != nil {
+		if _, ok := s.folders[*doc.FolderID]; !ok {
+			return violation("folder %d does not exist", *doc.FolderID)
+		}
+	}
+	return nil
+}
+
+func (s *documentStore) Create(ctx context.Context, doc *models.Document, version *models.DocumentVersion, tags []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkReferences(doc); err != nil {
+		return err
+	}
+	if err := s.requireUser(doc.KreiraoKorisnikID); err != nil {
+		return err
+	}
+	if err := s.requireUser(version.PostavioKorisnikID); err != nil {
+		return err
+	}
+
+	created := now()
+	doc.DokumentID = s.next("dokumenti")
+	doc.DatumaPostavke = created
+
+	stored := *doc
+	stored.NazivProjekta, stored.ImeKreirao, stored.NazivFaze, stored.BrojVerzija = "", "", "", 0
+	s.documents[doc.DokumentID] = stored
+
+	version.VerzijaID = s.next("verzijedokumenata")
+	version.DokumentID = doc.DokumentID
+	version.DatumaPostavke = created
+	s.versions[version.VerzijaID] = *version
+
+	for _, tagName := range tags {
+		s.addTag(doc.DokumentID, tagName)
+	}
+	return nil
+}
+
+// addTag links the named tag to a document, creating the tag if needed.
+func (s *documentStore) addTag(documentID int, tagName string) {
+	tagID := 0
+	for id, tag := range s.tags {
+		if tag.NazivTaga == tagName {
+			tagID = id
+			break
+		}
+	}
+	if tagID == 0 {
+		tagID = s.next("tagovi")
+		s.tags[tagID] = models.Tag{TagID: tagID, NazivTaga: tagName}
+	}
+	s.docTags[pair{documentID, tagID}] = true
+}
+
+func (s *documentStore) Update(ctx context.Context, doc *models.Document) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.documents[doc.DokumentID]
+	if !ok {
+		return notFound("document", doc.DokumentID)
+	}
+	if err := s.checkReferences(doc); err != nil {
+		return err
+	}
+
+	changed := now()
+	stored.NazivDokumenta = doc.NazivDokumenta
+	stored.ProjekatID = doc.ProjekatID
+	stored.FolderID = doc.FolderID
+	stored.Opis = doc.Opis
+	stored.TipDokumenta = doc.TipDokumenta
+	stored.JezikDokumenta = doc.JezikDokumenta
+	stored.PoslednjaIzmena = &changed
+	s.documents[doc.DokumentID] = stored
+	return nil
+}
+
+func (s *documentStore) Delete(ctx context.Context, id int) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.documents[id]; !ok {
+		return nil, notFound("document", id)
+	}
+
+	filePaths := []string{}
+	for versionID, version := range s.versions {
+		if version.DokumentID == id {
+			filePaths = append(filePaths, version.PutanjaDoFajla)
+			delete(s.versions, versionID)
+		}
+	}
+	for key := range s.docTags {
+		if key.a == id {
+			delete(s.docTags, key)
+		}
+	}
+	for metaID, meta := range s.metadata {
+		if meta.DokumentID == id {
+			delete(s.metadata, metaID)
+		}
+	}
+	delete(s.documents, id)
+	s.deleteGuestShares(func(share models.GuestShare) bool { return share.DokumentID != nil && *share.DokumentID == id })
+
+	sort.Strings(filePaths)
+	return filePaths, nil
+}
+
+func (s *documentStore) GetVersions(ctx context.Context, documentID int) ([]models.DocumentVersion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	versions := []models.DocumentVersion{}
+	for _, version := range s.versions {
+		if version.DokumentID == documentID {
+			versions = append(versions, version)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		if !versions[i].DatumaPostavke.Equal(versions[j].DatumaPostavke) {
+			return versions[i].DatumaPostavke.After(versions[j].DatumaPostavke)
+		}
+		return versions[i].VerzijaID > versions[j].VerzijaID
+	})
+	return versions, nil
+}
+
+func (s *documentStore) GetTags(ctx context.Context, documentID int) ([]models.Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tags := []models.Tag{}
+	for key := range s.docTags {
+		if key.a == documentID {
+			tags = append(tags, s.tags[key.b])
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].NazivTaga < tags[j].NazivTaga })
+	return tags, nil
+}
+
+func (s *documentStore) AddTag(ctx context.Context, documentID int, tagName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.documents[documentID]; !ok {
+		return violation("document %d does not exist", documentID)
+	}
+
+	s.addTag(documentID, tagName)
+	return nil
+}
+
+func (s *documentStore) RemoveTag(ctx context.Context, documentID, tagID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.docTags, pair{documentID, tagID})
+	return nil
+}
+
+func (s *documentStore) GetMetadata(ctx context.Context, documentID int) ([]models.Metadata, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	metadata := []models.Metadata{}
+	for _, meta := range s.metadata {
+		if meta.DokumentID == documentID {
+			metadata = append(metadata, meta)
+		}
+	}
+	sort.Slice(metadata, func(i, j int) bool {
+		if metadata[i].Kljuc != metadata[j].Kljuc {
+			return metadata[i].Kljuc < metadata[j].Kljuc
+		}
+		return metadata[i].MetaID < metadata[j].MetaID
+	})
+	return metadata, nil
+}
+
+func (s *documentStore) ReplaceMetadata(ctx context.Context, documentID int, metadata []models.Metadata) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.documents[documentID]; !ok && len(metadata) > 0 {
+		return violation("document %d does not exist", documentID)
+	}
+
+	for metaID, meta := range s.metadata {
+		if meta.DokumentID == documentID {
+			delete(s.metadata, metaID)
+		}
+	}
+	for _, meta := range metadata {
+		id := s.next("metapodaci")
+		s.metadata[id] = models.Metadata{MetaID: id, DokumentID: documentID, Kljuc: meta.Kljuc, Vrednost: meta.Vrednost}
+	}
+	return nil
+}
+
+func (s *documentStore) GetFolders(ctx context.Context, ownerID int) ([]models.Folder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	folders := []models.Folder{}
+	for _, folder := range s.folders {
+		if folder.VlasnikID == ownerID {
+			folders = append(folders, folder)
+		}
+	}
+	sort.Slice(folders, func(i, j int) bool {
+		if folders[i].NazivFoldera != folders[j].NazivFoldera {
+			return folders[i].NazivFoldera < folders[j].NazivFoldera
+		}
+		return folders[i].FolderID < folders[j].FolderID
+	})
+	return folders, nil
+}
+
+func (s *documentStore) GetFolder(ctx context.Context, id int) (*models.Folder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	folder, ok := s.folders[id]
+	if !ok {
+		return nil, notFound("folder", id)
+	}
+	return &folder, nil
+}
+
+func (s *documentStore) CreateFolder(ctx context.Context, folder *models.Folder) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.requireUser(folder.VlasnikID); err != nil {
+		return err
+	}
+	if folder.RoditeljFolderID != nil {
+		if _, ok := s.folders[*folder.RoditeljFolderID]; !ok {
+			return violation("folder %d does not exist", *folder.RoditeljFolderID)
+		}
+	}
+
+	folder.FolderID = s.next("folderi")
+	s.folders[folder.FolderID] = *folder
+	return nil
+}
+
+func (s *documentStore) DeleteFolder(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.folders[id]; !ok {
+		return notFound("folder", id)
+	}
+	s.deleteFolder(id)
+	return nil
+}
+
+// deleteFolder cascades to subfolders and unsets the folder of documents.
+func (s *documentStore) deleteFolder(id int) {
+	delete(s.folders, id)
+	s.deleteGuestShares(func(share models.GuestShare) bool { return share.FolderID != nil && *share.FolderID == id })
+	for docID, doc := range s.documents {
+		if doc.FolderID != nil && *doc.FolderID == id {
+			doc.FolderID = nil
+			s.documents[docID] = doc
+		}
+	}
+	for childID, child := range s.folders {
+		if child.RoditeljFolderID != nil && *child.RoditeljFolderID == id {
+			s.deleteFolder(childID)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/repositories"
+)
+
+type guestStore struct{ *state }
+
+// joined returns a copy of the share with the guest, the name of the shared
+// object and the user who shared it.
+func (s *guestStore) joined(share models.GuestShare) models.GuestShare {
+	guest := s.users[share.KorisnikID]
+	share.KorisnickoIme = guest.KorisnickoIme
+	share.GostDo = guest.GostDo
+	switch {
+	case share.ProjekatID != nil:
+		share.Naziv = s.projects[*share.ProjekatID].NazivProjekta
+	case share.FolderID != nil:
+		share.Naziv = s.folders[*share.FolderID].NazivFoldera
+	case share.DokumentID != nil:
+		share.Naziv = s.documents[*share.DokumentID].NazivDokumenta
+	}
+	share.OdobrioIme = s.username(share.OdobrioID)
+	return share
+}
+
+func (s *guestStore) list(keep func(models.GuestShare) bool) []models.GuestShare {
+	shares := []models.GuestShare{}
+	for _, share := range s.guestShares {
+		if keep(share) {
+			shares = append(shares, s.joined(share))
+		}
+	}
+	sort.Slice(shares, func(i, j int) bool {
+		if shares[i].KorisnickoIme != shares[j].KorisnickoIme {
+			return shares[i].KorisnickoIme < shares[j].KorisnickoIme
+		}
+		return shares[i].PristupID < shares[j].PristupID
+	})
+	return shares
+}
+
+func (s *guestStore) GetShares(ctx context.Context, userID int) ([]models.GuestShare, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.list(func(share models.GuestShare) bool { return share.KorisnikID == userID }), nil
+}
+
+func (s *guestStore) GetAllShares(ctx context.Context) ([]models.GuestShare, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.list(func(share models.GuestShare) bool {
+		guest := s.users[share.KorisnikID]
+		return guest.GostDo != nil && guest.Status == "aktivan"
+	}), nil
+}
+
+func (s *guestStore) GetShare(ctx context.Context, id int) (*models.GuestShare, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	share, ok := s.guestShares[id]
+	if !ok {
+		return nil, notFound("guest share", id)
+	}
+	share = s.joined(share)
+	return &share, nil
+}
+
+// Share mirrors the check on the shared object, the foreign keys and the
+// unique indexes of GostujuciPristup.
+func (s *guestStore) Share(ctx context.Context, share *models.GuestShare) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	targets := 0
+	if share.ProjekatID != nil {
+		targets++
+		if _, ok := s.projects[*share.ProjekatID]; !ok {
+			return violation("project %d does not exist", *share.ProjekatID)
+		}
+	}
+	if share.FolderID != nil {
+		targets++
+		if _, ok := s.folders[*share.FolderID]; !ok {
+			return violation("folder %d does not exist", *share.FolderID)
+		}
+	}
+	if share.DokumentID != nil {
+		targets++
+		if _, ok := s.documents[*share.DokumentID]; !ok {
+			return violation("document %d does not exist", *share.DokumentID)
+		}
+	}
+	if targets != 1 {
+		return violation("a guest share needs exactly one project, folder or document")
+	}
+	if err := s.requireUser(share.KorisnikID); err != nil {
+		return err
+	}
+	if err := s.requireOptionalUser(share.OdobrioID); err != nil {
+		return err
+	}
+	for _, other := range s.guestShares {
+		if other.KorisnikID == share.KorisnikID && sameInt(other.ProjekatID, share.ProjekatID) &&
+			sameInt(other.FolderID, share.FolderID) && sameInt(other.DokumentID, share.DokumentID) {
+			return fmt.Errorf("already shared with user %d: %w", share.KorisnikID, repositories.ErrConflict)
+		}
+	}
+
+	share.PristupID = s.next("gostujuci_pristup")
+	share.KreiranDatuma = now()
+
+	stored := *share
+	stored.KorisnickoIme, stored.GostDo, stored.Naziv, stored.OdobrioIme = "", nil, "", ""
+	s.guestShares[share.PristupID] = stored
+	return nil
+}
+
+func (s *guestStore) Unshare(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.guestShares[id]; !ok {
+		return notFound("guest share", id)
+	}
+	delete(s.guestShares, id)
+	return nil
+}
+
+func (s *guestStore) SetExpiry(ctx context.Context, userID int, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok || user.GostDo == nil {
+		return notFound("guest", userID)
+	}
+	until = until.UTC()
+	user.GostDo = &until
+	s.users[userID] = user
+	return nil
+}
+
+func (s *guestStore) ExpireGuests(ctx context.Context, at time.Time) ([]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expired := []int{}
+	for id, user := range s.users {
+		if user.GostDo != nil && !user.GostDo.After(at) && user.Status == "aktivan" {
+			user.Status = "neaktivan"
+			s.users[id] = user
+			expired = append(expired, id)
+		}
+	}
+	sort.Ints(expired)
+	for _, id := range expired {
+		s.deleteGuestShares(func(share models.GuestShare) bool { return share.KorisnikID == id })
+	}
+	return expired, nil
+}
+
+func (s *guestStore) GetSharedDocuments(ctx context.Context, userID int) ([]models.Document, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	projects, documents := map[int]bool{}, map[int]bool{}
+	var shared []int
+	for _, share := range s.guestShares {
+		if share.KorisnikID != userID {
+			continue
+		}
+		switch {
+		case share.ProjekatID != nil:
+			projects[*share.ProjekatID] = true
+		case share.FolderID != nil:
+			shared = append(shared, *share.FolderID)
+		case share.DokumentID != nil:
+			documents[*share.DokumentID] = true
+		}
+	}
+
+	children := map[int][]int{}
+	for id, folder := range s.folders {
+		if folder.RoditeljFolderID != nil {
+			children[*folder.RoditeljFolderID] = append(children[*folder.RoditeljFolderID], id)
+		}
+	}
+	folders := map[int]bool{}
+	for len(shared) > 0 {
+		id := shared[len(shared)-1]
+		shared = shared[:len(shared)-1]
+		if !folders[id] {
+			folders[id] = true
+			shared = append(shared, children[id]...)
+		}
+	}
+
+	docs := &documentStore{s.state}
+	return docs.list(func(doc models.Document) bool {
+		return doc.ProjekatID != nil && projects[*doc.ProjekatID] ||
+			doc.FolderID != nil && folders[*doc.FolderID] || documents[doc.DokumentID]
+	}), nil
+}
+
+// deleteGuestShares removes the shares that match, as the cascades of
+// GostujuciPristup do.
+func (s *state) deleteGuestShares(match func(models.GuestShare) bool) {
+	for id, share := range s.guestShares {
+		if match(share) {
+			delete(s.guestShares, id)
+		}
+	}
+}
+
+func sameInt(a, b *int) bool {
+	return a == nil && b == nil || a != nil && b != nil && *a == *b
+}
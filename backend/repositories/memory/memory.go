@@ -0,0 +1,191 @@
+// Package memory implements the repository stores in process memory. It
+// mirrors the constraints, joins and ordering of the PostgreSQL repositories
+// closely enough that services can be unit tested without a database, and it
+// is checked against the same contract suite (repositories/repotest).
+package memory
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/repositories"
+)
+
+// pair keys the many-to-many tables (project members, document tags).
+type pair struct{ a, b int }
+
+// state is the shared in-memory database behind every store of one
+// NewStores call. A single mutex keeps cross-store checks consistent.
+type state struct {
+	mu  sync.Mutex
+	seq map[string]int
+
+	roles         map[int]models.Role
+	roleVersion   int64
+	users         map[int]models.User
+	activations   map[int]models.Activation
+	passwords     map[int]passwordEntry
+	factors       map[int]models.TwoFactor
+	recoveryCodes map[int]recoveryCode
+	tokens        map[int]models.AccessToken
+	preferences   map[int]models.Preferences
+	emailChanges  map[int]models.EmailChange
+	workflows     map[int]models.Workflow
+	phases        map[int]models.Phase
+	projects      map[int]models.Project
+	members       map[pair]bool
+	tasks         map[int]models.Task
+	comments      map[int]models.TaskComment
+	folders       map[int]models.Folder
+	documents     map[int]models.Document
+	versions      map[int]models.DocumentVersion
+	tags          map[int]models.Tag
+	docTags       map[pair]bool
+	metadata      map[int]models.Metadata
+	units         map[int]models.Unit
+	guestShares   map[int]models.GuestShare
+	logs          map[int64]models.ActivityLog
+}
+
+// NewStores returns an empty in-memory database seeded with the same roles,
+// role grants, workflows and phases as the schema migrations.
+func NewStores() repositories.Stores {
+	s := &state{
+		seq:           map[string]int{},
+		roles:         map[int]models.Role{},
+		users:         map[int]models.User{},
+		activations:   map[int]models.Activation{},
+		passwords:     map[int]passwordEntry{},
+		factors:       map[int]models.TwoFactor{},
+		recoveryCodes: map[int]recoveryCode{},
+		tokens:        map[int]models.AccessToken{},
+		preferences:   map[int]models.Preferences{},
+		emailChanges:  map[int]models.EmailChange{},
+		workflows:     map[int]models.Workflow{},
+		phases:        map[int]models.Phase{},
+		projects:      map[int]models.Project{},
+		members:       map[pair]bool{},
+		tasks:         map[int]models.Task{},
+		comments:      map[int]models.TaskComment{},
+		folders:       map[int]models.Folder{},
+		documents:     map[int]models.Document{},
+		versions:      map[int]models.DocumentVersion{},
+		tags:          map[int]models.Tag{},
+		docTags:       map[pair]bool{},
+		metadata:      map[int]models.Metadata{},
+		units:         map[int]models.Unit{},
+		guestShares:   map[int]models.GuestShare{},
+		logs:          map[int64]models.ActivityLog{},
+	}
+	s.seed()
+
+	return repositories.Stores{
+		Users:       &userStore{s},
+		Roles:       &roleStore{s},
+		Activations: &activationStore{s},
+		Passwords:   &passwordHistoryStore{s},
+		TwoFactor:   &twoFactorStore{s},
+		Tokens:      &accessTokenStore{s},
+		Profiles:    &profileStore{s},
+		Projects:    &projectStore{s},
+		Tasks:       &taskStore{s},
+		Documents:   &documentStore{s},
+		Workflows:   &workflowStore{s},
+		Units:       &unitStore{s},
+		Guests:      &guestStore{s},
+		Analytics:   &analyticsStore{s},
+	}
+}
+
+func (s *state) seed() {
+	viewer := []string{"project.view", "task.view", "task.comment", "workflow.view", "document.view", "unit.view"}
+	roles := []struct {
+		name, key string
+		perms     []string
+	}{
+		{"Administrator", "administrator", []string{"*"}},
+		{"Rukovodilac projekta", "rukovodilac", append([]string{"user.view", "project.create", "project.update", "project.delete",
+			"project.members", "task.create", "task.update", "task.delete", "workflow.manage", "document.upload",
+			"document.update", "document.delete", "analytics.view"}, viewer...)},
+		{"Istrazivac", "istrazivac", append([]string{"task.update", "document.upload", "document.update"}, viewer...)},
+		{"Organizator projekta", "organizator", append([]string{"user.view", "project.update", "project.members", "task.create",
+			"task.update", "document.upload", "document.update", "analytics.view"}, viewer...)},
+		{"Gost", "gost", nil},
+	}
+	for _, r := range roles {
+		id := s.next("uloge")
+		key := r.key
+		role := models.Role{UlogaID: id, NazivUloge: r.name, Sistemska: &key}
+		for _, perm := range r.perms {
+			role.Dozvole = append(role.Dozvole, models.RoleGrant{Dozvola: perm})
+		}
+		sortGrants(role.Dozvole)
+		s.roles[id] = role
+		s.roleVersion++
+	}
+
+	workflows := []struct {
+		name, kind, description string
+		phases                  []string
+	}{
+		{"Standardni projektni tok", "PROJEKAT", "Osnovni radni tok za projekte",
+			[]string{"Planiranje", "Analiza", "Razvoj", "Testiranje", "Završeno"}},
+		{"Istrazivacki tok", "PROJEKAT", "Tok za istrazivacke projekte",
+			[]string{"Definisanje istrazivanja", "Prikupljanje podataka", "Analiza podataka", "Pisanje izvestaja", "Publikovanje"}},
+		{"Dokumentacioni tok", "DOKUMENTACIJA", "Tok za upravljanje dokumentima",
+			[]string{"Kreiranje", "Revizija", "Odobravanje", "Finalizovanje", "Arhiviranje"}},
+	}
+	for _, w := range workflows {
+		description := w.description
+		id := s.next("radnitokovi")
+		s.workflows[id] = models.Workflow{RadniTokID: id, Naziv: w.name, TipToka: w.kind, Opis: &description, DaLiJeSablon: true}
+		for i, name := range w.phases {
+			phaseID := s.next("faze")
+			s.phases[phaseID] = models.Phase{FazaID: phaseID, RadniTokID: id, NazivFaze: name, Redosled: i + 1}
+		}
+	}
+}
+
+// next returns the next value of a table's serial column.
+func (s *state) next(table string) int {
+	s.seq[table]++
+	return s.seq[table]
+}
+
+// now truncates to microseconds, the resolution of a PostgreSQL timestamp.
+func now() time.Time {
+	return time.Now().Truncate(time.Microsecond)
+}
+
+// violation reports a broken constraint the way the database would refuse
+// the statement.
+func violation(format string, args ...interface{}) error {
+	return fmt.Errorf("constraint violation: "+format, args...)
+}
+
+func notFound(entity string, id interface{}) error {
+	return fmt.Errorf("%s with ID %v %w", entity, id, repositories.ErrNotFound)
+}
+
+func (s *state) requireUser(id int) error {
+	if _, ok := s.users[id]; !ok {
+		return violation("user %d does not exist", id)
+	}
+	return nil
+}
+
+func (s *state) requireOptionalUser(id *int) error {
+	if id == nil {
+		return nil
+	}
+	return s.requireUser(*id)
+}
+
+func (s *state) username(id *int) string {
+	if id == nil {
+		return ""
+	}
+	return s.users[*id].KorisnickoIme
+}
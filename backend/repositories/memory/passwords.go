@@ -0,0 +1,57 @@
+package memory
+
+import (
+	"context"
+	"sort"
+)
+
+// passwordEntry is a row of IstorijaLozinki.
+type passwordEntry struct {
+	userID int
+	hash   string
+}
+
+type passwordHistoryStore struct{ *state }
+
+func (s *passwordHistoryStore) Add(ctx context.Context, userID int, hash string, keep int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.requireUser(userID); err != nil {
+		return err
+	}
+
+	s.passwords[s.next("istorijalozinki")] = passwordEntry{userID: userID, hash: hash}
+	for i, id := range s.passwordIDs(userID) {
+		if i >= keep {
+			delete(s.passwords, id)
+		}
+	}
+	return nil
+}
+
+func (s *passwordHistoryStore) Recent(ctx context.Context, userID, limit int) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var hashes []string
+	for _, id := range s.passwordIDs(userID) {
+		if len(hashes) == limit {
+			break
+		}
+		hashes = append(hashes, s.passwords[id].hash)
+	}
+	return hashes, nil
+}
+
+// passwordIDs returns the history entries of the user, newest first.
+func (s *passwordHistoryStore) passwordIDs(userID int) []int {
+	var ids []int
+	for id, entry := range s.passwords {
+		if entry.userID == userID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] > ids[j] })
+	return ids
+}
@@ -0,0 +1,105 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/cane/research-institute-system/backend/models"
+)
+
+type profileStore struct{ *state }
+
+func (s *profileStore) GetPreferences(ctx context.Context, userID int) (*models.Preferences, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prefs, ok := s.preferences[userID]
+	if !ok {
+		return nil, notFound("preferences of user", userID)
+	}
+	return &prefs, nil
+}
+
+func (s *profileStore) SavePreferences(ctx context.Context, prefs *models.Preferences) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.requireUser(prefs.KorisnikID); err != nil {
+		return err
+	}
+	if prefs.PodrazumevaniProjekatID != nil {
+		if _, ok := s.projects[*prefs.PodrazumevaniProjekatID]; !ok {
+			return violation("project %d does not exist", *prefs.PodrazumevaniProjekatID)
+		}
+	}
+
+	prefs.Izmenjeno = now()
+	s.preferences[prefs.KorisnikID] = *prefs
+	return nil
+}
+
+func (s *profileStore) BeginEmailChange(ctx context.Context, change *models.EmailChange) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.requireUser(change.KorisnikID); err != nil {
+		return err
+	}
+
+	change.Pokusaji = 0
+	change.KreiranDatuma = now()
+	s.emailChanges[change.KorisnikID] = *change
+	return nil
+}
+
+func (s *profileStore) GetEmailChange(ctx context.Context, userID int) (*models.EmailChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	change, ok := s.emailChanges[userID]
+	if !ok {
+		return nil, notFound("email change of user", userID)
+	}
+	return &change, nil
+}
+
+func (s *profileStore) FailEmailChange(ctx context.Context, userID int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	change, ok := s.emailChanges[userID]
+	if !ok {
+		return 0, notFound("email change of user", userID)
+	}
+	change.Pokusaji++
+	s.emailChanges[userID] = change
+	return change.Pokusaji, nil
+}
+
+func (s *profileStore) CompleteEmailChange(ctx context.Context, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	change, ok := s.emailChanges[userID]
+	user, exists := s.users[userID]
+	if !ok || !exists {
+		return notFound("email change of user", userID)
+	}
+	for id, other := range s.users {
+		if id != userID && other.Email == change.NoviEmail {
+			return violation("email %q already exists", change.NoviEmail)
+		}
+	}
+
+	user.Email = change.NoviEmail
+	s.users[userID] = user
+	delete(s.emailChanges, userID)
+	return nil
+}
+
+func (s *profileStore) CancelEmailChange(ctx context.Context, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.emailChanges, userID)
+	return nil
+}
@@ -0,0 +1,286 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/repositories"
+)
+
+type projectStore struct{ *state }
+
+// joined returns a copy of the project with the leader name and the task and
+// member counts filled in.
+func (s *projectStore) joined(project models.Project) models.Project {
+	project.RukovodilaIme = s.username(project.RukovodilaID)
+	project.BrojZadataka = 0
+	for _, task := range s.tasks {
+		if task.ProjekatID == project.ProjekatID {
+			project.BrojZadataka++
+		}
+	}
+	project.BrojClanova = 0
+	for key := range s.members {
+		if key.a == project.ProjekatID {
+			project.BrojClanova++
+		}
+	}
+	return project
+}
+
+func (s *projectStore) list(keep func(models.Project) bool) []models.Project {
+	projects := []models.Project{}
+	for _, project := range s.projects {
+		if keep(project) {
+			projects = append(projects, s.joined(project))
+		}
+	}
+	sort.Slice(projects, func(i, j int) bool { return projects[i].ProjekatID > projects[j].ProjekatID })
+	return projects
+}
+
+func (s *projectStore) GetAll(ctx context.Context) ([]models.Project, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.list(func(models.Project) bool { return true }), nil
+}
+
+func (s *projectStore) GetByID(ctx context.Context, id int) (*models.Project, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	project, ok := s.projects[id]
+	if !ok {
+		return nil, notFound("project", id)
+	}
+	project = s.joined(project)
+	return &project, nil
+}
+
+func (s *projectStore) GetByUserID(ctx context.Context, userID int) ([]models.Project, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.list(func(project models.Project) bool {
+		return s.isMember(project.ProjekatID, userID)
+	}), nil
+}
+
+func (s *projectStore) checkReferences(project *models.Project) error {
+	if err := s.requireOptionalUser(project.RukovodilaID); err != nil {
+		return err
+	}
+	if project.RadniTokID != nil {
+		if _, ok := s.workflows[*project.RadniTokID]; !ok {
+			return violation("workflow %d does not exist", *project.RadniTokID)
+		}
+	}
+	return nil
+}
+
+func (s *projectStore) Create(ctx context.Context, project *models.Project, memberIDs []int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkReferences(project); err != nil {
+		return err
+	}
+	if err := s.requireOptionalUnit(project.JedinicaID); err != nil {
+		return err
+	}
+	if project.RukovodilaID != nil {
+		memberIDs = append([]int{*project.RukovodilaID}, memberIDs...)
+	}
+	for _, memberID := range memberIDs {
+		if err := s.requireUser(memberID); err != nil {
+			return err
+		}
+	}
+
+	if project.Status == "" {
+		project.Status = "Aktivan"
+	}
+	project.ProjekatID = s.next("projekti")
+
+	stored := *project
+	stored.RukovodilaIme, stored.BrojZadataka, stored.BrojClanova = "", 0, 0
+	s.projects[project.ProjekatID] = stored
+	for _, memberID := range memberIDs {
+		s.members[pair{project.ProjekatID, memberID}] = true
+	}
+	return nil
+}
+
+func (s *projectStore) Update(ctx context.Context, project *models.Project) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.projects[project.ProjekatID]
+	if !ok {
+		return notFound("project", project.ProjekatID)
+	}
+	if err := s.checkReferences(project); err != nil {
+		return err
+	}
+
+	stored.NazivProjekta = project.NazivProjekta
+	stored.Opis = project.Opis
+	stored.DatumPocetka = project.DatumPocetka
+	stored.DatumZavrsetka = project.DatumZavrsetka
+	stored.Status = project.Status
+	stored.RukovodilaID = project.RukovodilaID
+	stored.RadniTokID = project.RadniTokID
+	s.projects[project.ProjekatID] = stored
+	if project.RukovodilaID != nil {
+		s.members[pair{project.ProjekatID, *project.RukovodilaID}] = true
+	}
+	return nil
+}
+
+// Delete cascades to tasks and team members like the schema does; documents
+// keep a plain foreign key and block the delete.
+func (s *projectStore) Delete(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.projects[id]; !ok {
+		return notFound("project", id)
+	}
+	for _, doc := range s.documents {
+		if doc.ProjekatID != nil && *doc.ProjekatID == id {
+			return violation("project %d is referenced by document %d", id, doc.DokumentID)
+		}
+	}
+
+	delete(s.projects, id)
+	s.deleteGuestShares(func(share models.GuestShare) bool { return share.ProjekatID != nil && *share.ProjekatID == id })
+	for key := range s.members {
+		if key.a == id {
+			delete(s.members, key)
+		}
+	}
+	for taskID, task := range s.tasks {
+		if task.ProjekatID == id {
+			s.deleteTask(taskID)
+		}
+	}
+	for roleID, role := range s.roles {
+		var grants []models.RoleGrant
+		for _, grant := range role.Dozvole {
+			if grant.ProjekatID == nil || *grant.ProjekatID != id {
+				grants = append(grants, grant)
+			}
+		}
+		role.Dozvole = grants
+		s.roles[roleID] = role
+	}
+	for userID, prefs := range s.preferences {
+		if prefs.PodrazumevaniProjekatID != nil && *prefs.PodrazumevaniProjekatID == id {
+			prefs.PodrazumevaniProjekatID = nil
+			s.preferences[userID] = prefs
+		}
+	}
+	return nil
+}
+
+func (s *projectStore) GetMembers(ctx context.Context, projectID int) ([]models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	members := []models.User{}
+	for key := range s.members {
+		if key.a != projectID {
+			continue
+		}
+		user := s.users[key.b]
+		members = append(members, models.User{
+			KorisnikID:    user.KorisnikID,
+			KorisnickoIme: user.KorisnickoIme,
+			Email:         user.Email,
+			Ime:           user.Ime,
+			Prezime:       user.Prezime,
+			UlogaID:       user.UlogaID,
+			Status:        user.Status,
+			NazivUloge:    s.roles[user.UlogaID].NazivUloge,
+		})
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].KorisnickoIme < members[j].KorisnickoIme })
+
+	return members, nil
+}
+
+func (s *projectStore) AddMember(ctx context.Context, projectID, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.projects[projectID]; !ok {
+		return violation("project %d does not exist", projectID)
+	}
+	if err := s.requireUser(userID); err != nil {
+		return err
+	}
+
+	s.members[pair{projectID, userID}] = true
+	return nil
+}
+
+func (s *projectStore) RemoveMember(ctx context.Context, projectID, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.members, pair{projectID, userID})
+	return nil
+}
+
+func (s *projectStore) IsMember(ctx context.Context, projectID, userID int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.isMember(projectID, userID), nil
+}
+
+func (s *state) isMember(projectID, userID int) bool {
+	if project, ok := s.projects[projectID]; ok && project.RukovodilaID != nil && *project.RukovodilaID == userID {
+		return true
+	}
+	return s.members[pair{projectID, userID}]
+}
+
+func (s *projectStore) SetWorkflow(ctx context.Context, projectID int, workflowID *int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if workflowID != nil {
+		workflow, ok := s.workflows[*workflowID]
+		if !ok {
+			return notFound("workflow", *workflowID)
+		}
+		if workflow.TipToka != "PROJEKAT" {
+			return fmt.Errorf("workflow %d is not a project workflow: %w", *workflowID, repositories.ErrConflict)
+		}
+	}
+
+	stranded := 0
+	for _, task := range s.tasks {
+		if task.ProjekatID != projectID {
+			continue
+		}
+		if workflowID == nil || s.phases[task.FazaID].RadniTokID != *workflowID {
+			stranded++
+		}
+	}
+	if stranded > 0 {
+		return fmt.Errorf("project %d has %d tasks in phases of another workflow: %w", projectID, stranded, repositories.ErrConflict)
+	}
+
+	project, ok := s.projects[projectID]
+	if !ok {
+		return notFound("project", projectID)
+	}
+	project.RadniTokID = workflowID
+	s.projects[projectID] = project
+	return nil
+}
@@ -0,0 +1,153 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/repositories"
+)
+
+type roleStore struct{ *state }
+
+func (s *roleStore) GetAll(ctx context.Context) ([]models.Role, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	roles := []models.Role{}
+	for _, role := range s.roles {
+		roles = append(roles, copyRole(role))
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i].UlogaID < roles[j].UlogaID })
+	return roles, nil
+}
+
+func (s *roleStore) GetByID(ctx context.Context, id int) (*models.Role, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	role, ok := s.roles[id]
+	if !ok {
+		return nil, notFound("role", id)
+	}
+	role = copyRole(role)
+	return &role, nil
+}
+
+func (s *roleStore) Create(ctx context.Context, role *models.Role) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkRole(role); err != nil {
+		return err
+	}
+
+	role.UlogaID = s.next("uloge")
+	role.Sistemska = nil
+	role.Povucena = nil
+	sortGrants(role.Dozvole)
+	s.roles[role.UlogaID] = copyRole(*role)
+	s.roleVersion++
+	return nil
+}
+
+func (s *roleStore) Update(ctx context.Context, role *models.Role) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.roles[role.UlogaID]
+	if !ok {
+		return notFound("role", role.UlogaID)
+	}
+	if err := s.checkRole(role); err != nil {
+		return err
+	}
+
+	sortGrants(role.Dozvole)
+	updated := copyRole(*role)
+	updated.Sistemska = stored.Sistemska
+	updated.Povucena = stored.Povucena
+	s.roles[role.UlogaID] = updated
+	s.roleVersion++
+	return nil
+}
+
+// checkRole mirrors the unique name, the unique grants and the project
+// foreign key of the schema.
+func (s *state) checkRole(role *models.Role) error {
+	for _, other := range s.roles {
+		if other.UlogaID != role.UlogaID && other.NazivUloge == role.NazivUloge {
+			return violation("role %q already exists", role.NazivUloge)
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, grant := range role.Dozvole {
+		key := grant.Dozvola
+		if grant.ProjekatID != nil {
+			if _, ok := s.projects[*grant.ProjekatID]; !ok {
+				return violation("project %d does not exist", *grant.ProjekatID)
+			}
+			key = fmt.Sprintf("%s@%d", key, *grant.ProjekatID)
+		}
+		if seen[key] {
+			return violation("grant %s of role %q repeats", key, role.NazivUloge)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+func (s *roleStore) Retire(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	role, ok := s.roles[id]
+	if !ok {
+		return notFound("role", id)
+	}
+	held := false
+	for _, user := range s.users {
+		held = held || user.UlogaID == id
+	}
+	if role.Povucena != nil || held {
+		return fmt.Errorf("role %d is retired or still held: %w", id, repositories.ErrConflict)
+	}
+
+	retired := now()
+	role.Povucena = &retired
+	s.roles[id] = role
+	s.roleVersion++
+	return nil
+}
+
+func (s *roleStore) Version(ctx context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.roleVersion, nil
+}
+
+// copyRole keeps callers from changing stored grants through the slice.
+func copyRole(role models.Role) models.Role {
+	role.Dozvole = append([]models.RoleGrant(nil), role.Dozvole...)
+	return role
+}
+
+// sortGrants orders grants by permission, global grants first, as the
+// repository reads them.
+func sortGrants(grants []models.RoleGrant) {
+	project := func(grant models.RoleGrant) int {
+		if grant.ProjekatID == nil {
+			return 0
+		}
+		return *grant.ProjekatID
+	}
+	sort.Slice(grants, func(i, j int) bool {
+		if grants[i].Dozvola != grants[j].Dozvola {
+			return grants[i].Dozvola < grants[j].Dozvola
+		}
+		return project(grants[i]) < project(grants[j])
+	})
+}
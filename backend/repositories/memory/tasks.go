@@ -0,0 +1,225 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/cane/research-institute-system/backend/models"
+)
+
+type taskStore struct{ *state }
+
+// joined returns a copy of the task with project, phase and assignee names.
+func (s *taskStore) joined(task models.Task) models.Task {
+	task.NazivProjekta = s.projects[task.ProjekatID].NazivProjekta
+	task.NazivFaze = s.phases[task.FazaID].NazivFaze
+	task.DodjeljenKorisniku = s.username(task.DodjeljenKorisnikuID)
+	return task
+}
+
+func (s *taskStore) list(keep func(models.Task) bool) []models.Task {
+	tasks := []models.Task{}
+	for _, task := range s.tasks {
+		if keep(task) {
+			tasks = append(tasks, s.joined(task))
+		}
+	}
+	return tasks
+}
+
+func (s *taskStore) GetByProject(ctx context.Context, projectID int) ([]models.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tasks := s.list(func(task models.Task) bool { return task.ProjekatID == projectID })
+	sort.Slice(tasks, func(i, j int) bool {
+		if !tasks[i].KreiranDatuma.Equal(tasks[j].KreiranDatuma) {
+			return tasks[i].KreiranDatuma.After(tasks[j].KreiranDatuma)
+		}
+		return tasks[i].ZadatakID > tasks[j].ZadatakID
+	})
+	return tasks, nil
+}
+
+// GetByUser orders by deadline (missing last), then priority descending with
+// missing priorities first, as PostgreSQL sorts NULLs in a DESC key.
+func (s *taskStore) GetByUser(ctx context.Context, userID int) ([]models.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tasks := s.list(func(task models.Task) bool {
+		return task.DodjeljenKorisnikuID != nil && *task.DodjeljenKorisnikuID == userID
+	})
+	sort.SliceStable(tasks, func(i, j int) bool {
+		a, b := tasks[i], tasks[j]
+		if (a.Rok == nil) != (b.Rok == nil) {
+			return b.Rok == nil
+		}
+		if a.Rok != nil && !a.Rok.Equal(*b.Rok) {
+			return a.Rok.Before(*b.Rok)
+		}
+		if (a.Prioritet == nil) != (b.Prioritet == nil) {
+			return a.Prioritet == nil
+		}
+		if a.Prioritet != nil && *a.Prioritet != *b.Prioritet {
+			return *a.Prioritet > *b.Prioritet
+		}
+		return a.ZadatakID < b.ZadatakID
+	})
+	return tasks, nil
+}
+
+func (s *taskStore) GetByID(ctx context.Context, id int) (*models.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := s.tasks[id]
+	if !ok {
+		return nil, notFound("task", id)
+	}
+	task = s.joined(task)
+	return &task, nil
+}
+
+func (s *taskStore) Create(ctx context.Context, task *models.Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	project, ok := s.projects[task.ProjekatID]
+	if !ok {
+		return violation("project %d does not exist", task.ProjekatID)
+	}
+
+	if task.FazaID == 0 {
+		// First phase of the project workflow, phase 1 without a workflow
+		task.FazaID = 1
+		if project.RadniTokID != nil {
+			first := 0
+			for _, phase := range s.phases {
+				if phase.RadniTokID == *project.RadniTokID && (first == 0 || phase.Redosled < s.phases[first].Redosled) {
+					first = phase.FazaID
+				}
+			}
+			if first != 0 {
+				task.FazaID = first
+			}
+		}
+	}
+
+	if _, ok := s.phases[task.FazaID]; !ok {
+		return violation("phase %d does not exist", task.FazaID)
+	}
+	if err := s.requireOptionalUser(task.DodjeljenKorisnikuID); err != nil {
+		return err
+	}
+
+	task.ZadatakID = s.next("zadaci")
+	task.KreiranDatuma = now()
+
+	stored := *task
+	stored.NazivProjekta, stored.NazivFaze, stored.DodjeljenKorisniku = "", "", ""
+	s.tasks[task.ZadatakID] = stored
+	return nil
+}
+
+func (s *taskStore) Update(ctx context.Context, id int, req models.UpdateTaskRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := s.tasks[id]
+	if !ok {
+		return notFound("task", id)
+	}
+
+	if req.FazaID != nil {
+		if _, ok := s.phases[*req.FazaID]; !ok {
+			return violation("phase %d does not exist", *req.FazaID)
+		}
+		task.FazaID = *req.FazaID
+	}
+	if req.DodjeljenKorisnikuID != nil {
+		if err := s.requireUser(*req.DodjeljenKorisnikuID); err != nil {
+			return err
+		}
+		task.DodjeljenKorisnikuID = req.DodjeljenKorisnikuID
+	}
+	if req.NazivZadatka != nil {
+		task.NazivZadatka = *req.NazivZadatka
+	}
+	if req.Opis != nil {
+		task.Opis = req.Opis
+	}
+	if req.Rok != nil {
+		task.Rok = req.Rok
+	}
+	if req.Prioritet != nil {
+		task.Prioritet = req.Prioritet
+	}
+	if req.Progres != nil {
+		task.Progres = *req.Progres
+	}
+
+	s.tasks[id] = task
+	return nil
+}
+
+func (s *taskStore) Delete(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.tasks[id]; !ok {
+		return notFound("task", id)
+	}
+	s.deleteTask(id)
+	return nil
+}
+
+// deleteTask removes a task and, like ON DELETE CASCADE, its comments.
+func (s *state) deleteTask(id int) {
+	delete(s.tasks, id)
+	for commentID, comment := range s.comments {
+		if comment.ZadatakID == id {
+			delete(s.comments, commentID)
+		}
+	}
+}
+
+func (s *taskStore) GetComments(ctx context.Context, taskID int) ([]models.TaskComment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	comments := []models.TaskComment{}
+	for _, comment := range s.comments {
+		if comment.ZadatakID == taskID {
+			comment.ImeKorisnika = s.users[comment.KorisnikID].KorisnickoIme
+			comments = append(comments, comment)
+		}
+	}
+	sort.Slice(comments, func(i, j int) bool {
+		if !comments[i].DatumaKreiranja.Equal(comments[j].DatumaKreiranja) {
+			return comments[i].DatumaKreiranja.After(comments[j].DatumaKreiranja)
+		}
+		return comments[i].KomentarID > comments[j].KomentarID
+	})
+	return comments, nil
+}
+
+func (s *taskStore) AddComment(ctx context.Context, comment *models.TaskComment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.tasks[comment.ZadatakID]; !ok {
+		return violation("task %d does not exist", comment.ZadatakID)
+	}
+	if err := s.requireUser(comment.KorisnikID); err != nil {
+		return err
+	}
+
+	comment.KomentarID = s.next("komentarizadataka")
+	comment.DatumaKreiranja = now()
+
+	stored := *comment
+	stored.ImeKorisnika = ""
+	s.comments[comment.KomentarID] = stored
+	return nil
+}
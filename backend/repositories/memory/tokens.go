@@ -0,0 +1,105 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/repositories"
+)
+
+type accessTokenStore struct{ *state }
+
+func (s *accessTokenStore) Create(ctx context.Context, token *models.AccessToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.requireUser(token.KorisnikID); err != nil {
+		return err
+	}
+	for _, other := range s.tokens {
+		if other.HashTokena == token.HashTokena {
+			return violation("access token hash already exists")
+		}
+	}
+
+	token.TokenID = s.next("pristupnitokeni")
+	token.Opsezi = append([]string(nil), token.Opsezi...)
+	token.PoslednjeKorisceno = nil
+	token.Opozvan = nil
+	token.KreiranDatuma = now()
+	s.tokens[token.TokenID] = *token
+	return nil
+}
+
+func (s *accessTokenStore) GetByID(ctx context.Context, id int) (*models.AccessToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.tokens[id]
+	if !ok {
+		return nil, notFound("access token", id)
+	}
+	return &token, nil
+}
+
+func (s *accessTokenStore) GetByHash(ctx context.Context, hash string) (*models.AccessToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, token := range s.tokens {
+		if token.HashTokena == hash {
+			return &token, nil
+		}
+	}
+	return nil, fmt.Errorf("access token %w", repositories.ErrNotFound)
+}
+
+func (s *accessTokenStore) ListByUser(ctx context.Context, userID int) ([]models.AccessToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokens := []models.AccessToken{}
+	for _, token := range s.tokens {
+		if token.KorisnikID == userID {
+			tokens = append(tokens, token)
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].TokenID > tokens[j].TokenID })
+	return tokens, nil
+}
+
+func (s *accessTokenStore) Revoke(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.tokens[id]
+	if !ok {
+		return notFound("access token", id)
+	}
+	if token.Opozvan != nil {
+		return fmt.Errorf("access token %d is already revoked: %w", id, repositories.ErrConflict)
+	}
+
+	revoked := now()
+	token.Opozvan = &revoked
+	s.tokens[id] = token
+	return nil
+}
+
+func (s *accessTokenStore) MarkUsed(ctx context.Context, id int, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.tokens[id]
+	if !ok {
+		return notFound("access token", id)
+	}
+
+	used := at.Truncate(time.Microsecond)
+	token.PoslednjeKorisceno = &used
+	s.tokens[id] = token
+	return nil
+}
@@ -0,0 +1,128 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/repositories"
+)
+
+// recoveryCode is a row of RezervniKodovi.
+type recoveryCode struct {
+	userID int
+	hash   string
+	used   *time.Time
+}
+
+type twoFactorStore struct{ *state }
+
+func (s *twoFactorStore) Get(ctx context.Context, userID int) (*models.TwoFactor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	factor, ok := s.factors[userID]
+	if !ok {
+		return nil, notFound("second factor of user", userID)
+	}
+	return &factor, nil
+}
+
+func (s *twoFactorStore) Begin(ctx context.Context, factor *models.TwoFactor) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.requireUser(factor.KorisnikID); err != nil {
+		return err
+	}
+	if existing, ok := s.factors[factor.KorisnikID]; ok && existing.Potvrdjen != nil {
+		return fmt.Errorf("user %d already has a second factor: %w", factor.KorisnikID, repositories.ErrConflict)
+	}
+
+	factor.Potvrdjen = nil
+	factor.PoslednjiKorak = 0
+	factor.KreiranDatuma = now()
+	s.factors[factor.KorisnikID] = *factor
+	return nil
+}
+
+func (s *twoFactorStore) Confirm(ctx context.Context, userID int, step int64, recoveryHashes []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	factor, ok := s.factors[userID]
+	if !ok || factor.Potvrdjen != nil {
+		return fmt.Errorf("user %d has no second factor to confirm: %w", userID, repositories.ErrConflict)
+	}
+
+	confirmed := now()
+	factor.Potvrdjen = &confirmed
+	factor.PoslednjiKorak = step
+	s.factors[userID] = factor
+
+	s.deleteRecoveryCodes(userID)
+	for _, hash := range recoveryHashes {
+		s.recoveryCodes[s.next("rezervnikodovi")] = recoveryCode{userID: userID, hash: hash}
+	}
+	return nil
+}
+
+func (s *twoFactorStore) UseStep(ctx context.Context, userID int, step int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	factor, ok := s.factors[userID]
+	if !ok || factor.Potvrdjen == nil || factor.PoslednjiKorak >= step {
+		return fmt.Errorf("time step %d of user %d is already used: %w", step, userID, repositories.ErrConflict)
+	}
+
+	factor.PoslednjiKorak = step
+	s.factors[userID] = factor
+	return nil
+}
+
+func (s *twoFactorStore) UseRecoveryCode(ctx context.Context, userID int, hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, code := range s.recoveryCodes {
+		if code.userID == userID && code.hash == hash && code.used == nil {
+			used := now()
+			code.used = &used
+			s.recoveryCodes[id] = code
+			return nil
+		}
+	}
+	return fmt.Errorf("user %d has no such unused recovery code: %w", userID, repositories.ErrConflict)
+}
+
+func (s *twoFactorStore) RecoveryCodesLeft(ctx context.Context, userID int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for _, code := range s.recoveryCodes {
+		if code.userID == userID && code.used == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *twoFactorStore) Delete(ctx context.Context, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.factors, userID)
+	s.deleteRecoveryCodes(userID)
+	return nil
+}
+
+func (s *state) deleteRecoveryCodes(userID int) {
+	for id, code := range s.recoveryCodes {
+		if code.userID == userID {
+			delete(s.recoveryCodes, id)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/repositories"
+)
+
+type unitStore struct{ *state }
+
+// joined returns a copy of the unit with its head's name and its counts.
+func (s *unitStore) joined(unit models.Unit) models.Unit {
+	unit.RukovodilacIme = s.username(unit.RukovodilacID)
+	unit.BrojClanova, unit.BrojProjekata = 0, 0
+	for _, user := range s.users {
+		if user.JedinicaID != nil && *user.JedinicaID == unit.JedinicaID {
+			unit.BrojClanova++
+		}
+	}
+	for _, project := range s.projects {
+		if project.JedinicaID != nil && *project.JedinicaID == unit.JedinicaID {
+			unit.BrojProjekata++
+		}
+	}
+	return unit
+}
+
+func (s *unitStore) GetAll(ctx context.Context) ([]models.Unit, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	units := []models.Unit{}
+	for _, unit := range s.units {
+		units = append(units, s.joined(unit))
+	}
+	sort.Slice(units, func(i, j int) bool { return units[i].Naziv < units[j].Naziv })
+	return units, nil
+}
+
+func (s *unitStore) GetByID(ctx context.Context, id int) (*models.Unit, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unit, ok := s.units[id]
+	if !ok {
+		return nil, notFound("unit", id)
+	}
+	unit = s.joined(unit)
+	return &unit, nil
+}
+
+// checkUnit mirrors the unique name, the type check and the foreign keys
+// of the schema.
+func (s *unitStore) checkUnit(unit *models.Unit) error {
+	switch unit.Tip {
+	case "institut", "odeljenje", "laboratorija", "grupa":
+	default:
+		return violation("invalid unit type %q", unit.Tip)
+	}
+	for id, other := range s.units {
+		if id != unit.JedinicaID && other.Naziv == unit.Naziv {
+			return violation("unit %q already exists", unit.Naziv)
+		}
+	}
+	if err := s.requireOptionalUnit(unit.NadredjenaJedinicaID); err != nil {
+		return err
+	}
+	return s.requireOptionalUser(unit.RukovodilacID)
+}
+
+func (s *unitStore) Create(ctx context.Context, unit *models.Unit) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unit.JedinicaID = 0
+	if err := s.checkUnit(unit); err != nil {
+		return err
+	}
+
+	unit.JedinicaID = s.next("jedinice")
+	unit.KreiranDatuma = now()
+
+	stored := *unit
+	stored.RukovodilacIme, stored.BrojClanova, stored.BrojProjekata = "", 0, 0
+	s.units[unit.JedinicaID] = stored
+	return nil
+}
+
+func (s *unitStore) Update(ctx context.Context, unit *models.Unit) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.units[unit.JedinicaID]
+	if !ok {
+		return notFound("unit", unit.JedinicaID)
+	}
+	if err := s.checkUnit(unit); err != nil {
+		return err
+	}
+
+	stored.Naziv = unit.Naziv
+	stored.Tip = unit.Tip
+	stored.NadredjenaJedinicaID = unit.NadredjenaJedinicaID
+	stored.RukovodilacID = unit.RukovodilacID
+	s.units[unit.JedinicaID] = stored
+	return nil
+}
+
+// Delete sets the unit of its members and projects to NULL like the schema
+// does.
+func (s *unitStore) Delete(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.units[id]; !ok {
+		return notFound("unit", id)
+	}
+	for _, unit := range s.units {
+		if unit.NadredjenaJedinicaID != nil && *unit.NadredjenaJedinicaID == id {
+			return fmt.Errorf("unit %d has subunits: %w", id, repositories.ErrConflict)
+		}
+	}
+
+	delete(s.units, id)
+	for userID, user := range s.users {
+		if user.JedinicaID != nil && *user.JedinicaID == id {
+			user.JedinicaID = nil
+			s.users[userID] = user
+		}
+	}
+	for projectID, project := range s.projects {
+		if project.JedinicaID != nil && *project.JedinicaID == id {
+			project.JedinicaID = nil
+			s.projects[projectID] = project
+		}
+	}
+	return nil
+}
+
+func (s *unitStore) GetMembers(ctx context.Context, unitID int) ([]models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	members := []models.User{}
+	for _, user := range s.users {
+		if user.JedinicaID == nil || *user.JedinicaID != unitID {
+			continue
+		}
+		members = append(members, models.User{
+			KorisnikID:    user.KorisnikID,
+			KorisnickoIme: user.KorisnickoIme,
+			Email:         user.Email,
+			Ime:           user.Ime,
+			Prezime:       user.Prezime,
+			UlogaID:       user.UlogaID,
+			Status:        user.Status,
+			JedinicaID:    user.JedinicaID,
+			NazivUloge:    s.roles[user.UlogaID].NazivUloge,
+		})
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].KorisnickoIme < members[j].KorisnickoIme })
+
+	return members, nil
+}
+
+func (s *unitStore) SetUserUnit(ctx context.Context, userID int, unitID *int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return notFound("user", userID)
+	}
+	if err := s.requireOptionalUnit(unitID); err != nil {
+		return err
+	}
+
+	user.JedinicaID = unitID
+	s.users[userID] = user
+	return nil
+}
+
+func (s *unitStore) SetProjectUnit(ctx context.Context, projectID int, unitID *int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	project, ok := s.projects[projectID]
+	if !ok {
+		return notFound("project", projectID)
+	}
+	if err := s.requireOptionalUnit(unitID); err != nil {
+		return err
+	}
+
+	project.JedinicaID = unitID
+	s.projects[projectID] = project
+	return nil
+}
+
+func (s *state) requireOptionalUnit(id *int) error {
+	if id == nil {
+		return nil
+	}
+	if _, ok := s.units[*id]; !ok {
+		return violation("unit %d does not exist", *id)
+	}
+	return nil
+}
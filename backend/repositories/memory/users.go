@@ -0,0 +1,473 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/cane/research-institute-system/backend/models"
+)
+
+type userStore struct{ *state }
+
+// joined returns a copy of the user with its role name filled in.
+func (s *userStore) joined(user models.User) models.User {
+	user.NazivUloge = s.roles[user.UlogaID].NazivUloge
+	return user
+}
+
+func (s *userStore) GetByID(ctx context.Context, id int) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return nil, notFound("user", id)
+	}
+	user = s.joined(user)
+	return &user, nil
+}
+
+func (s *userStore) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, user := range s.users {
+		if user.KorisnickoIme == username {
+			user = s.joined(user)
+			return &user, nil
+		}
+	}
+	return nil, notFound("user", username)
+}
+
+func (s *userStore) GetAll(ctx context.Context) ([]models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	users := []models.User{}
+	for _, user := range s.users {
+		user = s.joined(user)
+		user.HashSifre = ""
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		if !users[i].KreiranDatuma.Equal(users[j].KreiranDatuma) {
+			return users[i].KreiranDatuma.After(users[j].KreiranDatuma)
+		}
+		return users[i].KorisnikID > users[j].KorisnikID
+	})
+
+	return users, nil
+}
+
+// checkUnique enforces the UNIQUE username and email columns.
+func (s *userStore) checkUnique(user *models.User) error {
+	for id, other := range s.users {
+		if id == user.KorisnikID {
+			continue
+		}
+		if other.KorisnickoIme == user.KorisnickoIme {
+			return violation("username %q already exists", user.KorisnickoIme)
+		}
+		if other.Email == user.Email {
+			return violation("email %q already exists", user.Email)
+		}
+	}
+	if _, ok := s.roles[user.UlogaID]; !ok {
+		return violation("role %d does not exist", user.UlogaID)
+	}
+	return nil
+}
+
+func (s *userStore) Create(ctx context.Context, user *models.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user.KorisnikID = 0
+	if err := s.checkUnique(user); err != nil {
+		return err
+	}
+
+	user.KorisnikID = s.next("korisnici")
+	user.KreiranDatuma = now()
+	if user.IzvorPrijave == "" {
+		user.IzvorPrijave = models.IzvorLokalni
+	}
+
+	user.JedinicaID = nil
+	stored := *user
+	stored.NazivUloge = ""
+	if user.GostDo != nil {
+		until := user.GostDo.UTC()
+		stored.GostDo = &until
+	}
+	s.users[user.KorisnikID] = stored
+	return nil
+}
+
+func (s *userStore) Update(ctx context.Context, user *models.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.users[user.KorisnikID]
+	if !ok {
+		return notFound("user", user.KorisnikID)
+	}
+	if err := s.checkUnique(user); err != nil {
+		return err
+	}
+
+	stored.KorisnickoIme = user.KorisnickoIme
+	stored.Email = user.Email
+	stored.Ime = user.Ime
+	stored.Prezime = user.Prezime
+	stored.UlogaID = user.UlogaID
+	stored.Status = user.Status
+	s.users[user.KorisnikID] = stored
+	return nil
+}
+
+func (s *userStore) UpdatePassword(ctx context.Context, userID int, passwordHash string, mustChange bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.users[userID]
+	if !ok {
+		return notFound("user", userID)
+	}
+	stored.HashSifre = passwordHash
+	stored.MoraPromenitiLozinku = mustChange
+	s.users[userID] = stored
+	return nil
+}
+
+func (s *userStore) UpdateLastLogin(ctx context.Context, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if stored, ok := s.users[userID]; ok {
+		loggedIn := now()
+		stored.PoslednajaPrijava = &loggedIn
+		s.users[userID] = stored
+	}
+	return nil
+}
+
+func (s *userStore) RecordLoginFailure(ctx context.Context, userID int, at time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.users[userID]
+	if !ok {
+		return 0, notFound("user", userID)
+	}
+	at = at.Truncate(time.Microsecond)
+	stored.NeuspesnePrijave++
+	stored.PoslednjaNeuspesnaPrijava = &at
+	s.users[userID] = stored
+	return stored.NeuspesnePrijave, nil
+}
+
+func (s *userStore) Lock(ctx context.Context, userID int, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.users[userID]
+	if !ok {
+		return notFound("user", userID)
+	}
+	until = until.Truncate(time.Microsecond)
+	stored.ZakljucanDo = &until
+	s.users[userID] = stored
+	return nil
+}
+
+func (s *userStore) RevokeSessions(ctx context.Context, userID int, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.users[userID]
+	if !ok {
+		return notFound("user", userID)
+	}
+	before = before.Truncate(time.Microsecond)
+	stored.SesijeNevazeceOd = &before
+	s.users[userID] = stored
+	return nil
+}
+
+func (s *userStore) ResetLoginFailures(ctx context.Context, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.users[userID]
+	if !ok {
+		return notFound("user", userID)
+	}
+	stored.NeuspesnePrijave = 0
+	stored.PoslednjaNeuspesnaPrijava = nil
+	stored.ZakljucanDo = nil
+	s.users[userID] = stored
+	return nil
+}
+
+func (s *userStore) Delete(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[id]; !ok {
+		return notFound("user", id)
+	}
+	if err := s.checkUnreferenced(id); err != nil {
+		return err
+	}
+
+	s.remove(id)
+	return nil
+}
+
+func (s *userStore) DeleteUnreferenced(ctx context.Context, id int) (map[string]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[id]; !ok {
+		return nil, notFound("user", id)
+	}
+	if references := s.references(id); len(references) > 0 {
+		return references, nil
+	}
+
+	s.remove(id)
+	return nil, nil
+}
+
+// remove deletes the user and what cascades with it; the caller has checked
+// that nothing else refers to the user.
+func (s *userStore) remove(id int) {
+	delete(s.users, id)
+	for key := range s.members {
+		if key.b == id {
+			delete(s.members, key)
+		}
+	}
+	for activationID, activation := range s.activations {
+		if activation.KorisnikID == id {
+			delete(s.activations, activationID)
+		} else if activation.KreiraoKorisnikID != nil && *activation.KreiraoKorisnikID == id {
+			activation.KreiraoKorisnikID = nil
+			s.activations[activationID] = activation
+		}
+	}
+	for entryID, entry := range s.passwords {
+		if entry.userID == id {
+			delete(s.passwords, entryID)
+		}
+	}
+	delete(s.factors, id)
+	s.deleteRecoveryCodes(id)
+	for tokenID, token := range s.tokens {
+		if token.KorisnikID == id {
+			delete(s.tokens, tokenID)
+		}
+	}
+	delete(s.preferences, id)
+	delete(s.emailChanges, id)
+	for unitID, unit := range s.units {
+		if unit.RukovodilacID != nil && *unit.RukovodilacID == id {
+			unit.RukovodilacID = nil
+			s.units[unitID] = unit
+		}
+	}
+	s.deleteGuestShares(func(share models.GuestShare) bool { return share.KorisnikID == id })
+	for shareID, share := range s.guestShares {
+		if share.OdobrioID != nil && *share.OdobrioID == id {
+			share.OdobrioID = nil
+			s.guestShares[shareID] = share
+		}
+	}
+}
+
+// checkUnreferenced mirrors the foreign keys that keep a user row alive.
+// Team memberships and activation codes cascade and are not checked.
+func (s *userStore) checkUnreferenced(id int) error {
+	for _, project := range s.projects {
+		if project.RukovodilaID != nil && *project.RukovodilaID == id {
+			return violation("user %d leads project %d", id, project.ProjekatID)
+		}
+	}
+	for _, task := range s.tasks {
+		if task.DodjeljenKorisnikuID != nil && *task.DodjeljenKorisnikuID == id {
+			return violation("user %d is assigned task %d", id, task.ZadatakID)
+		}
+	}
+	for _, comment := range s.comments {
+		if comment.KorisnikID == id {
+			return violation("user %d wrote comment %d", id, comment.KomentarID)
+		}
+	}
+	for _, doc := range s.documents {
+		if doc.KreiraoKorisnikID == id {
+			return violation("user %d created document %d", id, doc.DokumentID)
+		}
+	}
+	for _, version := range s.versions {
+		if version.PostavioKorisnikID == id {
+			return violation("user %d uploaded version %d", id, version.VerzijaID)
+		}
+	}
+	for _, folder := range s.folders {
+		if folder.VlasnikID == id {
+			return violation("user %d owns folder %d", id, folder.FolderID)
+		}
+	}
+	for _, entry := range s.logs {
+		if entry.KorisnikID != nil && *entry.KorisnikID == id {
+			return violation("user %d has activity log entries", id)
+		}
+	}
+	return nil
+}
+
+func (s *userStore) Offboard(ctx context.Context, userID int, handover models.Handover) (*models.HandoverReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return nil, notFound("user", userID)
+	}
+	for _, target := range []*int{handover.RukovodilacID, handover.IzvrsilacID, handover.VlasnikID} {
+		if target == nil {
+			continue
+		}
+		if err := s.requireUser(*target); err != nil {
+			return nil, err
+		}
+	}
+
+	user.Status = "neaktivan"
+	s.users[userID] = user
+
+	report := &models.HandoverReport{
+		KorisnikID:   userID,
+		Primopredaja: handover,
+		Projekti:     []int{},
+		Zadaci:       []int{},
+		Folderi:      []int{},
+		Dokumenti:    []int{},
+	}
+	if target := handover.RukovodilacID; target != nil {
+		for id, project := range s.projects {
+			if project.RukovodilaID != nil && *project.RukovodilaID == userID {
+				leader := *target
+				project.RukovodilaID = &leader
+				s.projects[id] = project
+				report.Projekti = append(report.Projekti, id)
+			}
+		}
+	}
+	if target := handover.IzvrsilacID; target != nil {
+		for id, task := range s.tasks {
+			if task.DodjeljenKorisnikuID != nil && *task.DodjeljenKorisnikuID == userID && task.Progres < 100 {
+				assignee := *target
+				task.DodjeljenKorisnikuID = &assignee
+				s.tasks[id] = task
+				report.Zadaci = append(report.Zadaci, id)
+			}
+		}
+	}
+	if target := handover.VlasnikID; target != nil {
+		for id, folder := range s.folders {
+			if folder.VlasnikID == userID {
+				folder.VlasnikID = *target
+				s.folders[id] = folder
+				report.Folderi = append(report.Folderi, id)
+			}
+		}
+		for id, doc := range s.documents {
+			if doc.KreiraoKorisnikID == userID {
+				doc.KreiraoKorisnikID = *target
+				s.documents[id] = doc
+				report.Dokumenti = append(report.Dokumenti, id)
+			}
+		}
+	}
+	for _, ids := range [][]int{report.Projekti, report.Zadaci, report.Folderi, report.Dokumenti} {
+		sort.Ints(ids)
+	}
+
+	revoked := now()
+	for id, token := range s.tokens {
+		if token.KorisnikID == userID && token.Opozvan == nil {
+			token.Opozvan = &revoked
+			s.tokens[id] = token
+			report.OpozvaniTokeni++
+		}
+	}
+
+	return report, nil
+}
+
+func (s *userStore) References(ctx context.Context, userID int) (map[string]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.references(userID), nil
+}
+
+func (s *userStore) references(userID int) map[string]int {
+	references := map[string]int{}
+	for _, project := range s.projects {
+		if project.RukovodilaID != nil && *project.RukovodilaID == userID {
+			references["Projekti"]++
+		}
+	}
+	for _, task := range s.tasks {
+		if task.DodjeljenKorisnikuID != nil && *task.DodjeljenKorisnikuID == userID {
+			references["Zadaci"]++
+		}
+	}
+	for _, comment := range s.comments {
+		if comment.KorisnikID == userID {
+			references["KomentariZadataka"]++
+		}
+	}
+	for _, folder := range s.folders {
+		if folder.VlasnikID == userID {
+			references["Folderi"]++
+		}
+	}
+	for _, doc := range s.documents {
+		if doc.KreiraoKorisnikID == userID {
+			references["Dokumenti"]++
+		}
+	}
+	for _, version := range s.versions {
+		if version.PostavioKorisnikID == userID {
+			references["VerzijeDokumenata"]++
+		}
+	}
+	for _, entry := range s.logs {
+		if (entry.KorisnikID != nil && *entry.KorisnikID == userID) ||
+			(entry.StvarniKorisnikID != nil && *entry.StvarniKorisnikID == userID) {
+			references["LogAktivnosti"]++
+		}
+	}
+	return references
+}
+
+func (s *userStore) GetRoles(ctx context.Context) ([]models.Role, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	roles := []models.Role{}
+	for _, role := range s.roles {
+		if role.Povucena == nil {
+			roles = append(roles, models.Role{UlogaID: role.UlogaID, NazivUloge: role.NazivUloge})
+		}
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i].UlogaID < roles[j].UlogaID })
+
+	return roles, nil
+}
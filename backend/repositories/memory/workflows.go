@@ -0,0 +1,112 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/cane/research-institute-system/backend/models"
+)
+
+type workflowStore struct{ *state }
+
+func (s *workflowStore) GetAll(ctx context.Context) ([]models.Workflow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	workflows := []models.Workflow{}
+	for _, workflow := range s.workflows {
+		workflows = append(workflows, workflow)
+	}
+	sort.Slice(workflows, func(i, j int) bool {
+		if workflows[i].Naziv != workflows[j].Naziv {
+			return workflows[i].Naziv < workflows[j].Naziv
+		}
+		return workflows[i].RadniTokID < workflows[j].RadniTokID
+	})
+	return workflows, nil
+}
+
+func (s *workflowStore) GetPhases(ctx context.Context, workflowID int) ([]models.Phase, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	phases := []models.Phase{}
+	for _, phase := range s.phases {
+		if phase.RadniTokID == workflowID {
+			phases = append(phases, phase)
+		}
+	}
+	sort.Slice(phases, func(i, j int) bool {
+		if phases[i].Redosled != phases[j].Redosled {
+			return phases[i].Redosled < phases[j].Redosled
+		}
+		return phases[i].FazaID < phases[j].FazaID
+	})
+	return phases, nil
+}
+
+func (s *workflowStore) Create(ctx context.Context, workflow *models.Workflow) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if workflow.TipToka != "PROJEKAT" && workflow.TipToka != "DOKUMENTACIJA" {
+		return violation("invalid workflow type %q", workflow.TipToka)
+	}
+	for _, other := range s.workflows {
+		if other.Naziv == workflow.Naziv && other.TipToka == workflow.TipToka {
+			return violation("workflow %q of type %s already exists", workflow.Naziv, workflow.TipToka)
+		}
+	}
+
+	workflow.RadniTokID = s.next("radnitokovi")
+	s.workflows[workflow.RadniTokID] = *workflow
+	return nil
+}
+
+func (s *workflowStore) CreatePhase(ctx context.Context, phase *models.Phase) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.workflows[phase.RadniTokID]; !ok {
+		return violation("workflow %d does not exist", phase.RadniTokID)
+	}
+
+	phase.FazaID = s.next("faze")
+	s.phases[phase.FazaID] = *phase
+	return nil
+}
+
+func (s *workflowStore) Delete(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.workflows[id]; !ok {
+		return notFound("workflow", id)
+	}
+	for _, project := range s.projects {
+		if project.RadniTokID != nil && *project.RadniTokID == id {
+			return violation("workflow %d is used by project %d", id, project.ProjekatID)
+		}
+	}
+	for _, doc := range s.documents {
+		if doc.RadniTokID != nil && *doc.RadniTokID == id {
+			return violation("workflow %d is used by document %d", id, doc.DokumentID)
+		}
+		if doc.TrenutnaFazaID != nil && s.phases[*doc.TrenutnaFazaID].RadniTokID == id {
+			return violation("a phase of workflow %d is used by document %d", id, doc.DokumentID)
+		}
+	}
+	for _, task := range s.tasks {
+		if s.phases[task.FazaID].RadniTokID == id {
+			return violation("a phase of workflow %d is used by task %d", id, task.ZadatakID)
+		}
+	}
+
+	delete(s.workflows, id)
+	for phaseID, phase := range s.phases {
+		if phase.RadniTokID == id {
+			delete(s.phases, phaseID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/cane/research-institute-system/backend/schemacheck"
+)
+
+type PasswordHistoryRepository struct {
+	db *sql.DB
+}
+
+func NewPasswordHistoryRepository(db *sql.DB) *PasswordHistoryRepository {
+	return &PasswordHistoryRepository{db: db}
+}
+
+var passwordHistoryAddQuery = schemacheck.Register("PasswordHistoryRepository.Add", `
+	INSERT INTO IstorijaLozinki (korisnik_id, hash_sifre) VALUES ($1, $2)
+`)
+
+var passwordHistoryPruneQuery = schemacheck.Register("PasswordHistoryRepository.Prune", `
+	DELETE FROM IstorijaLozinki
+	WHERE korisnik_id = $1 AND istorija_id NOT IN (
+		SELECT istorija_id FROM IstorijaLozinki
+		WHERE korisnik_id = $1
+		ORDER BY istorija_id DESC
+		LIMIT $2
+	)
+`)
+
+func (r *PasswordHistoryRepository) Add(ctx context.Context, userID int, hash string, keep int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, passwordHistoryAddQuery, userID, hash); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, passwordHistoryPruneQuery, userID, keep); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+var passwordHistoryRecentQuery = schemacheck.Register("PasswordHistoryRepository.Recent", `
+	SELECT hash_sifre FROM IstorijaLozinki
+	WHERE korisnik_id = $1
+	ORDER BY istorija_id DESC
+	LIMIT $2
+`)
+
+func (r *PasswordHistoryRepository) Recent(ctx context.Context, userID, limit int) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, passwordHistoryRecentQuery, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+
+	return hashes, rows.Err()
+}
@@ -0,0 +1,154 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/schemacheck"
+)
+
+type ProfileRepository struct {
+	db *sql.DB
+}
+
+func NewProfileRepository(db *sql.DB) *ProfileRepository {
+	return &ProfileRepository{db: db}
+}
+
+var profileGetPreferencesQuery = schemacheck.Register("ProfileRepository.GetPreferences", `
+	SELECT korisnik_id, jezik, podrazumevani_projekat_id, format_datuma,
+	       obavestenja_email, obavestenja_zadaci, obavestenja_komentari, izmenjeno
+	FROM PodesavanjaKorisnika
+	WHERE korisnik_id = $1
+`)
+
+func (r *ProfileRepository) GetPreferences(ctx context.Context, userID int) (*models.Preferences, error) {
+	var prefs models.Preferences
+	err := r.db.QueryRowContext(ctx, profileGetPreferencesQuery, userID).Scan(
+		&prefs.KorisnikID, &prefs.Jezik, &prefs.PodrazumevaniProjekatID, &prefs.FormatDatuma,
+		&prefs.ObavestenjaEmail, &prefs.ObavestenjaZadaci, &prefs.ObavestenjaKomentari, &prefs.Izmenjeno,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound("preferences of user", userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &prefs, nil
+}
+
+var profileSavePreferencesQuery = schemacheck.Register("ProfileRepository.SavePreferences", `
+	INSERT INTO PodesavanjaKorisnika (korisnik_id, jezik, podrazumevani_projekat_id, format_datuma,
+		obavestenja_email, obavestenja_zadaci, obavestenja_komentari, izmenjeno)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, CURRENT_TIMESTAMP)
+	ON CONFLICT (korisnik_id) DO UPDATE SET
+		jezik = EXCLUDED.jezik,
+		podrazumevani_projekat_id = EXCLUDED.podrazumevani_projekat_id,
+		format_datuma = EXCLUDED.format_datuma,
+		obavestenja_email = EXCLUDED.obavestenja_email,
+		obavestenja_zadaci = EXCLUDED.obavestenja_zadaci,
+		obavestenja_komentari = EXCLUDED.obavestenja_komentari,
+		izmenjeno = EXCLUDED.izmenjeno
+	RETURNING izmenjeno
+`)
+
+func (r *ProfileRepository) SavePreferences(ctx context.Context, prefs *models.Preferences) error {
+	return r.db.QueryRowContext(ctx, profileSavePreferencesQuery, prefs.KorisnikID, prefs.Jezik,
+		prefs.PodrazumevaniProjekatID, prefs.FormatDatuma, prefs.ObavestenjaEmail, prefs.ObavestenjaZadaci,
+		prefs.ObavestenjaKomentari).Scan(&prefs.Izmenjeno)
+}
+
+var profileBeginEmailChangeQuery = schemacheck.Register("ProfileRepository.BeginEmailChange", `
+	INSERT INTO PromeneEmaila (korisnik_id, novi_email, hash_koda, istice)
+	VALUES ($1, $2, $3, $4)
+	ON CONFLICT (korisnik_id) DO UPDATE SET
+		novi_email = EXCLUDED.novi_email,
+		hash_koda = EXCLUDED.hash_koda,
+		istice = EXCLUDED.istice,
+		pokusaji = 0,
+		kreiran_datuma = CURRENT_TIMESTAMP
+	RETURNING kreiran_datuma
+`)
+
+// BeginEmailChange writes the expiry in UTC, which is how the driver reads a
+// TIMESTAMP without time zone back.
+func (r *ProfileRepository) BeginEmailChange(ctx context.Context, change *models.EmailChange) error {
+	err := r.db.QueryRowContext(ctx, profileBeginEmailChangeQuery, change.KorisnikID, change.NoviEmail,
+		change.HashKoda, change.Istice.UTC()).Scan(&change.KreiranDatuma)
+	if err != nil {
+		return err
+	}
+
+	change.Pokusaji = 0
+	return nil
+}
+
+var profileGetEmailChangeQuery = schemacheck.Register("ProfileRepository.GetEmailChange", `
+	SELECT korisnik_id, novi_email, hash_koda, istice, pokusaji, kreiran_datuma
+	FROM PromeneEmaila
+	WHERE korisnik_id = $1
+`)
+
+func (r *ProfileRepository) GetEmailChange(ctx context.Context, userID int) (*models.EmailChange, error) {
+	var change models.EmailChange
+	err := r.db.QueryRowContext(ctx, profileGetEmailChangeQuery, userID).Scan(
+		&change.KorisnikID, &change.NoviEmail, &change.HashKoda, &change.Istice, &change.Pokusaji, &change.KreiranDatuma,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound("email change of user", userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &change, nil
+}
+
+var profileFailEmailChangeQuery = schemacheck.Register("ProfileRepository.FailEmailChange", `
+	UPDATE PromeneEmaila SET pokusaji = pokusaji + 1 WHERE korisnik_id = $1 RETURNING pokusaji
+`)
+
+func (r *ProfileRepository) FailEmailChange(ctx context.Context, userID int) (int, error) {
+	var attempts int
+	err := r.db.QueryRowContext(ctx, profileFailEmailChangeQuery, userID).Scan(&attempts)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, notFound("email change of user", userID)
+	}
+	return attempts, err
+}
+
+var profileCompleteEmailChangeQuery = schemacheck.Register("ProfileRepository.CompleteEmailChange", `
+	UPDATE Korisnici k SET email = p.novi_email
+	FROM PromeneEmaila p
+	WHERE p.korisnik_id = k.korisnik_id AND k.korisnik_id = $1
+`)
+
+func (r *ProfileRepository) CompleteEmailChange(ctx context.Context, userID int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, profileCompleteEmailChangeQuery, userID)
+	if err != nil {
+		return err
+	}
+	if err := expectAffected(result, "email change of user", userID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, profileCancelEmailChangeQuery, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+var profileCancelEmailChangeQuery = schemacheck.Register("ProfileRepository.CancelEmailChange",
+	`DELETE FROM PromeneEmaila WHERE korisnik_id = $1`)
+
+func (r *ProfileRepository) CancelEmailChange(ctx context.Context, userID int) error {
+	_, err := r.db.ExecContext(ctx, profileCancelEmailChangeQuery, userID)
+	return err
+}
@@ -1,34 +1,331 @@
-﻿package repositories
+package repositories
 
 import (
-"database/sql"
-"github.com/cane/research-institute-system/backend/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/schemacheck"
 )
 
 type ProjectRepository struct {
-db *sql.DB
+	db *sql.DB
 }
 
 func NewProjectRepository(db *sql.DB) *ProjectRepository {
-return &ProjectRepository{db: db}
+	return &ProjectRepository{db: db}
+}
+
+// projectSelect is shared by all project queries so every listing carries the
+// same joined leader name and task/member counts.
+const projectSelect = `
+	SELECT p.projekat_id, p.naziv_projekta, p.opis, p.datum_pocetka,
+	       p.datum_zavrsetka, p.status, p.rukovodilac_id, p.radni_tok_id, p.jedinica_id,
+	       COALESCE(k.korisnicko_ime, '') as rukovodilac_ime,
+	       (SELECT COUNT(*) FROM Zadaci z WHERE z.projekat_id = p.projekat_id) as broj_zadataka,
+	       (SELECT COUNT(*) FROM ClanoviProjekta c WHERE c.projekat_id = p.projekat_id) as broj_clanova
+	FROM Projekti p
+	LEFT JOIN Korisnici k ON p.rukovodilac_id = k.korisnik_id
+`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
 }
 
-func (r *ProjectRepository) Create(project *models.Project) error {
-return nil
+func scanProject(row rowScanner) (models.Project, error) {
+	var project models.Project
+	err := row.Scan(
+		&project.ProjekatID, &project.NazivProjekta, &project.Opis,
+		&project.DatumPocetka, &project.DatumZavrsetka, &project.Status,
+		&project.RukovodilaID, &project.RadniTokID, &project.JedinicaID, &project.RukovodilaIme,
+		&project.BrojZadataka, &project.BrojClanova,
+	)
+	return project, err
 }
 
-func (r *ProjectRepository) GetByID(id int) (*models.Project, error) {
-return &models.Project{}, nil
+func (r *ProjectRepository) queryProjects(ctx context.Context, query string, args ...interface{}) ([]models.Project, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	projects := []models.Project{}
+	for rows.Next() {
+		project, err := scanProject(rows)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, project)
+	}
+
+	return projects, rows.Err()
 }
 
-func (r *ProjectRepository) GetByUserID(userID int) ([]models.Project, error) {
-return []models.Project{}, nil
+var projectGetAllQuery = schemacheck.Register("ProjectRepository.GetAll",
+	projectSelect+` ORDER BY p.projekat_id DESC`)
+
+func (r *ProjectRepository) GetAll(ctx context.Context) ([]models.Project, error) {
+	return r.queryProjects(ctx, projectGetAllQuery)
 }
 
-func (r *ProjectRepository) Update(project *models.Project) error {
-return nil  
+var projectGetByIDQuery = schemacheck.Register("ProjectRepository.GetByID",
+	projectSelect+` WHERE p.projekat_id = $1`)
+
+func (r *ProjectRepository) GetByID(ctx context.Context, id int) (*models.Project, error) {
+	project, err := scanProject(r.db.QueryRowContext(ctx, projectGetByIDQuery, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound("project", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+var projectGetByUserIDQuery = schemacheck.Register("ProjectRepository.GetByUserID", projectSelect+`
+	WHERE p.rukovodilac_id = $1
+	   OR EXISTS (
+	       SELECT 1 FROM ClanoviProjekta cp
+	       WHERE cp.projekat_id = p.projekat_id AND cp.korisnik_id = $1
+	   )
+	ORDER BY p.projekat_id DESC
+`)
+
+// GetByUserID returns the projects the user leads or is a team member of.
+func (r *ProjectRepository) GetByUserID(ctx context.Context, userID int) ([]models.Project, error) {
+	return r.queryProjects(ctx, projectGetByUserIDQuery, userID)
+}
+
+var projectCreateQuery = schemacheck.Register("ProjectRepository.Create", `
+	INSERT INTO Projekti (naziv_projekta, opis, datum_pocetka, datum_zavrsetka,
+	                      status, rukovodilac_id, radni_tok_id, jedinica_id)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	RETURNING projekat_id
+`)
+
+// Create inserts the project and its team in one transaction. The leader is
+// always added as a team member.
+func (r *ProjectRepository) Create(ctx context.Context, project *models.Project, memberIDs []int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if project.Status == "" {
+		project.Status = "Aktivan"
+	}
+
+	err = tx.QueryRowContext(ctx, projectCreateQuery, project.NazivProjekta, project.Opis,
+		project.DatumPocetka, project.DatumZavrsetka, project.Status,
+		project.RukovodilaID, project.RadniTokID, project.JedinicaID).Scan(&project.ProjekatID)
+	if err != nil {
+		return err
+	}
+
+	if project.RukovodilaID != nil {
+		memberIDs = append([]int{*project.RukovodilaID}, memberIDs...)
+	}
+
+	for _, memberID := range memberIDs {
+		if err := addMember(ctx, tx, project.ProjekatID, memberID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+var projectUpdateQuery = schemacheck.Register("ProjectRepository.Update", `
+	UPDATE Projekti
+	SET naziv_projekta = $1, opis = $2, datum_pocetka = $3,
+	    datum_zavrsetka = $4, status = $5, rukovodilac_id = $6, radni_tok_id = $7
+	WHERE projekat_id = $8
+`)
+
+// Update saves the project and, in the same transaction, adds its leader to
+// the team so a new leader is never outside the project.
+func (r *ProjectRepository) Update(ctx context.Context, project *models.Project) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, projectUpdateQuery, project.NazivProjekta, project.Opis,
+		project.DatumPocetka, project.DatumZavrsetka, project.Status,
+		project.RukovodilaID, project.RadniTokID, project.ProjekatID)
+	if err != nil {
+		return err
+	}
+	if err := expectAffected(result, "project", project.ProjekatID); err != nil {
+		return err
+	}
+
+	if project.RukovodilaID != nil {
+		if err := addMember(ctx, tx, project.ProjekatID, *project.RukovodilaID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+var projectDeleteQuery = schemacheck.Register("ProjectRepository.Delete", `DELETE FROM Projekti WHERE projekat_id = $1`)
+
+func (r *ProjectRepository) Delete(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, projectDeleteQuery, id)
+	if err != nil {
+		return err
+	}
+
+	return expectAffected(result, "project", id)
 }
 
-func (r *ProjectRepository) GetMembers(projectID int) ([]models.User, error) {
-return []models.User{}, nil
+var projectGetMembersQuery = schemacheck.Register("ProjectRepository.GetMembers", `
+	SELECT k.korisnik_id, k.korisnicko_ime, k.email, k.ime, k.prezime,
+	       k.uloga_id, k.status, u.naziv_uloge
+	FROM ClanoviProjekta cp
+	JOIN Korisnici k ON cp.korisnik_id = k.korisnik_id
+	JOIN Uloge u ON k.uloga_id = u.uloga_id
+	WHERE cp.projekat_id = $1
+	ORDER BY k.korisnicko_ime
+`)
+
+func (r *ProjectRepository) GetMembers(ctx context.Context, projectID int) ([]models.User, error) {
+	rows, err := r.db.QueryContext(ctx, projectGetMembersQuery, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []models.User{}
+	for rows.Next() {
+		var member models.User
+		err := rows.Scan(
+			&member.KorisnikID, &member.KorisnickoIme, &member.Email,
+			&member.Ime, &member.Prezime, &member.UlogaID, &member.Status,
+			&member.NazivUloge,
+		)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+
+	return members, rows.Err()
+}
+
+func (r *ProjectRepository) AddMember(ctx context.Context, projectID, userID int) error {
+	return addMember(ctx, r.db, projectID, userID)
+}
+
+var projectRemoveMemberQuery = schemacheck.Register("ProjectRepository.RemoveMember", `DELETE FROM ClanoviProjekta WHERE projekat_id = $1 AND korisnik_id = $2`)
+
+func (r *ProjectRepository) RemoveMember(ctx context.Context, projectID, userID int) error {
+	_, err := r.db.ExecContext(ctx, projectRemoveMemberQuery, projectID, userID)
+	return err
+}
+
+var projectIsMemberQuery = schemacheck.Register("ProjectRepository.IsMember", `
+	SELECT EXISTS (
+		SELECT 1 FROM Projekti WHERE projekat_id = $1 AND rukovodilac_id = $2
+		UNION ALL
+		SELECT 1 FROM ClanoviProjekta WHERE projekat_id = $1 AND korisnik_id = $2
+	)
+`)
+
+// IsMember reports whether the user leads or belongs to the project.
+func (r *ProjectRepository) IsMember(ctx context.Context, projectID, userID int) (bool, error) {
+	var member bool
+	err := r.db.QueryRowContext(ctx, projectIsMemberQuery, projectID, userID).Scan(&member)
+	return member, err
+}
+
+var (
+	projectWorkflowTypeQuery = schemacheck.Register("ProjectRepository.SetWorkflow:type",
+		`SELECT tip_toka FROM RadniTokovi WHERE radni_tok_id = $1`)
+	projectStrandedTasksQuery = schemacheck.Register("ProjectRepository.SetWorkflow:stranded", `
+		SELECT COUNT(*)
+		FROM Zadaci z
+		JOIN Faze f ON z.faza_id = f.faza_id
+		WHERE z.projekat_id = $1 AND f.radni_tok_id IS DISTINCT FROM $2::int
+	`)
+	projectSetWorkflowQuery = schemacheck.Register("ProjectRepository.SetWorkflow",
+		`UPDATE Projekti SET radni_tok_id = $1 WHERE projekat_id = $2`)
+)
+
+// SetWorkflow links the project to a project workflow, or unlinks it when
+// workflowID is nil. Existing tasks must already sit in phases of the new
+// workflow, otherwise the Kanban board would lose them.
+func (r *ProjectRepository) SetWorkflow(ctx context.Context, projectID int, workflowID *int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if workflowID != nil {
+		var flowType string
+		err := tx.QueryRowContext(ctx, projectWorkflowTypeQuery, *workflowID).Scan(&flowType)
+		if errors.Is(err, sql.ErrNoRows) {
+			return notFound("workflow", *workflowID)
+		}
+		if err != nil {
+			return err
+		}
+		if flowType != "PROJEKAT" {
+			return fmt.Errorf("workflow %d is not a project workflow: %w", *workflowID, ErrConflict)
+		}
+	}
+
+	var stranded int
+	err = tx.QueryRowContext(ctx, projectStrandedTasksQuery, projectID, workflowID).Scan(&stranded)
+	if err != nil {
+		return err
+	}
+	if stranded > 0 {
+		return fmt.Errorf("project %d has %d tasks in phases of another workflow: %w", projectID, stranded, ErrConflict)
+	}
+
+	result, err := tx.ExecContext(ctx, projectSetWorkflowQuery, workflowID, projectID)
+	if err != nil {
+		return err
+	}
+	if err := expectAffected(result, "project", projectID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+var projectAddMemberQuery = schemacheck.Register("ProjectRepository.addMember", `
+	INSERT INTO ClanoviProjekta (projekat_id, korisnik_id)
+	VALUES ($1, $2)
+	ON CONFLICT (projekat_id, korisnik_id) DO NOTHING
+`)
+
+func addMember(ctx context.Context, db execer, projectID, userID int) error {
+	_, err := db.ExecContext(ctx, projectAddMemberQuery, projectID, userID)
+	return err
+}
+
+func expectAffected(result sql.Result, entity string, id int) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return notFound(entity, id)
+	}
+
+	return nil
 }
@@ -0,0 +1,1614 @@
+// Package repotest is the contract every repositories.Stores backend must
+// satisfy. The PostgreSQL and in-memory backends both run it, which keeps the
+// in-memory stores honest as a stand-in for the database in service tests.
+//
+// The suite may run against a database that already holds data: it creates
+// uniquely named rows, compares counts as differences and removes what it
+// created.
+package repotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/repositories"
+)
+
+// Run executes the contract suite. open is called once per subtest and must
+// return stores of the backend under test.
+func Run(t *testing.T, open func(t *testing.T) repositories.Stores) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, f *fixture)
+	}{
+		{"Users", testUsers},
+		{"Roles", testRoles},
+		{"Activations", testActivations},
+		{"PasswordHistory", testPasswordHistory},
+		{"TwoFactor", testTwoFactor},
+		{"AccessTokens", testAccessTokens},
+		{"Profiles", testProfiles},
+		{"Projects", testProjects},
+		{"ProjectWorkflow", testProjectWorkflow},
+		{"Tasks", testTasks},
+		{"Documents", testDocuments},
+		{"Folders", testFolders},
+		{"Offboarding", testOffboarding},
+		{"Workflows", testWorkflows},
+		{"Units", testUnits},
+		{"Guests", testGuests},
+		{"Analytics", testAnalytics},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, &fixture{Stores: open(t), ctx: context.Background()})
+		})
+	}
+}
+
+var counter int64
+
+// unique returns a name no other run of the suite will use.
+func unique(prefix string) string {
+	return fmt.Sprintf("%s_%d_%d", prefix, time.Now().UnixNano(), atomic.AddInt64(&counter, 1))
+}
+
+func ptr[T any](v T) *T { return &v }
+
+type fixture struct {
+	repositories.Stores
+	ctx context.Context
+}
+
+// user creates a user that is deleted when the test ends.
+func (f *fixture) user(t *testing.T) *models.User {
+	t.Helper()
+
+	roles, err := f.Users.GetRoles(f.ctx)
+	if err != nil || len(roles) == 0 {
+		t.Fatalf("Uloge nisu pročitane: %v", err)
+	}
+
+	name := unique("korisnik")
+	user := &models.User{
+		KorisnickoIme: name,
+		Email:         name + "@test.local",
+		HashSifre:     "hash",
+		Ime:           ptr("Test"),
+		Prezime:       ptr("Korisnik"),
+		UlogaID:       roles[0].UlogaID,
+		Status:        "aktivan",
+	}
+	if err := f.Users.Create(f.ctx, user); err != nil {
+		t.Fatalf("Greška pri kreiranju korisnika: %v", err)
+	}
+	t.Cleanup(func() { f.Users.Delete(f.ctx, user.KorisnikID) })
+
+	return user
+}
+
+// project creates a project led by leader that is deleted when the test ends.
+func (f *fixture) project(t *testing.T, leader *models.User, memberIDs ...int) *models.Project {
+	t.Helper()
+
+	project := &models.Project{NazivProjekta: unique("projekat"), RukovodilaID: &leader.KorisnikID}
+	if err := f.Projects.Create(f.ctx, project, memberIDs); err != nil {
+		t.Fatalf("Greška pri kreiranju projekta: %v", err)
+	}
+	t.Cleanup(func() { f.Projects.Delete(f.ctx, project.ProjekatID) })
+
+	return project
+}
+
+// unit creates an organizational unit that is deleted when the test ends.
+func (f *fixture) unit(t *testing.T, parentID, headID *int) *models.Unit {
+	t.Helper()
+
+	unit := &models.Unit{Naziv: unique("jedinica"), Tip: "odeljenje", NadredjenaJedinicaID: parentID, RukovodilacID: headID}
+	if err := f.Units.Create(f.ctx, unit); err != nil {
+		t.Fatalf("Greška pri kreiranju jedinice: %v", err)
+	}
+	t.Cleanup(func() { f.Units.Delete(f.ctx, unit.JedinicaID) })
+
+	return unit
+}
+
+// projectWorkflow returns a seeded PROJEKAT workflow with its phases.
+func (f *fixture) projectWorkflow(t *testing.T) (models.Workflow, []models.Phase) {
+	t.Helper()
+
+	workflows, err := f.Workflows.GetAll(f.ctx)
+	if err != nil {
+		t.Fatalf("Greška pri čitanju radnih tokova: %v", err)
+	}
+	for _, workflow := range workflows {
+		if workflow.TipToka != "PROJEKAT" {
+			continue
+		}
+		phases, err := f.Workflows.GetPhases(f.ctx, workflow.RadniTokID)
+		if err != nil {
+			t.Fatalf("Greška pri čitanju faza: %v", err)
+		}
+		if len(phases) > 0 {
+			return workflow, phases
+		}
+	}
+
+	t.Skip("Preskačem test - nema projektnog radnog toka sa fazama")
+	return models.Workflow{}, nil
+}
+
+func expectNotFound(t *testing.T, what string, err error) {
+	t.Helper()
+	if !errors.Is(err, repositories.ErrNotFound) {
+		t.Errorf("%s: očekivana ErrNotFound greška, dobijeno %v", what, err)
+	}
+}
+
+// Test korisnika: kreiranje, čitanje, jedinstvenost i brisanje
+func testUsers(t *testing.T, f *fixture) {
+	user := f.user(t)
+
+	if user.KorisnikID == 0 || user.KreiranDatuma.IsZero() {
+		t.Fatalf("Create mora popuniti ID i datum kreiranja: %+v", user)
+	}
+
+	byID, err := f.Users.GetByID(f.ctx, user.KorisnikID)
+	if err != nil {
+		t.Fatalf("GetByID greška: %v", err)
+	}
+	if byID.KorisnickoIme != user.KorisnickoIme || byID.HashSifre != "hash" || byID.NazivUloge == "" {
+		t.Errorf("GetByID vratio pogrešne podatke: %+v", byID)
+	}
+	if byID.PoslednajaPrijava != nil {
+		t.Errorf("Novi korisnik ne sme imati poslednju prijavu")
+	}
+	if user.IzvorPrijave != models.IzvorLokalni || byID.IzvorPrijave != models.IzvorLokalni {
+		t.Errorf("Korisnik bez izvora prijave mora biti lokalni: %q, %q", user.IzvorPrijave, byID.IzvorPrijave)
+	}
+
+	byName, err := f.Users.GetByUsername(f.ctx, user.KorisnickoIme)
+	if err != nil || byName.KorisnikID != user.KorisnikID {
+		t.Errorf("GetByUsername vratio %+v, %v", byName, err)
+	}
+
+	_, err = f.Users.GetByUsername(f.ctx, unique("nepostojeci"))
+	expectNotFound(t, "GetByUsername", err)
+	_, err = f.Users.GetByID(f.ctx, -1)
+	expectNotFound(t, "GetByID", err)
+
+	duplicate := *user
+	duplicate.Email = unique("drugi") + "@test.local"
+	if err := f.Users.Create(f.ctx, &duplicate); err == nil {
+		f.Users.Delete(f.ctx, duplicate.KorisnikID)
+		t.Errorf("Korisničko ime mora biti jedinstveno")
+	}
+	duplicate = *user
+	duplicate.KorisnickoIme = unique("drugi")
+	if err := f.Users.Create(f.ctx, &duplicate); err == nil {
+		f.Users.Delete(f.ctx, duplicate.KorisnikID)
+		t.Errorf("Email mora biti jedinstven")
+	}
+
+	directory := *user
+	directory.KorisnickoIme = unique("imenik")
+	directory.Email = directory.KorisnickoIme + "@test.local"
+	directory.IzvorPrijave = "ldap"
+	if err := f.Users.Create(f.ctx, &directory); err != nil {
+		t.Fatalf("Greška pri kreiranju korisnika iz imenika: %v", err)
+	}
+	if stored, err := f.Users.GetByUsername(f.ctx, directory.KorisnickoIme); err != nil || stored.IzvorPrijave != "ldap" {
+		t.Errorf("Izvor prijave nije sačuvan: %+v, %v", stored, err)
+	}
+	f.Users.Delete(f.ctx, directory.KorisnikID)
+
+	user.Ime = ptr("Promenjeno")
+	user.Status = "neaktivan"
+	if err := f.Users.Update(f.ctx, user); err != nil {
+		t.Fatalf("Update greška: %v", err)
+	}
+	if err := f.Users.UpdatePassword(f.ctx, user.KorisnikID, "novi-hash", true); err != nil {
+		t.Fatalf("UpdatePassword greška: %v", err)
+	}
+	if err := f.Users.UpdateLastLogin(f.ctx, user.KorisnikID); err != nil {
+		t.Fatalf("UpdateLastLogin greška: %v", err)
+	}
+
+	failedAt := time.Now().Add(-time.Minute).Truncate(time.Second)
+	for want := 1; want <= 2; want++ {
+		failures, err := f.Users.RecordLoginFailure(f.ctx, user.KorisnikID, failedAt)
+		if err != nil || failures != want {
+			t.Fatalf("RecordLoginFailure vratio %d (%v), očekivano %d", failures, err, want)
+		}
+	}
+	lockedUntil := time.Now().Add(time.Hour).Truncate(time.Second)
+	if err := f.Users.Lock(f.ctx, user.KorisnikID, lockedUntil); err != nil {
+		t.Fatalf("Lock greška: %v", err)
+	}
+	locked, err := f.Users.GetByUsername(f.ctx, user.KorisnickoIme)
+	if err != nil {
+		t.Fatalf("GetByUsername greška: %v", err)
+	}
+	if locked.NeuspesnePrijave != 2 || locked.ZakljucanDo == nil || !locked.ZakljucanDo.Equal(lockedUntil) ||
+		locked.PoslednjaNeuspesnaPrijava == nil || !locked.PoslednjaNeuspesnaPrijava.Equal(failedAt) {
+		t.Errorf("Neuspešne prijave i zaključavanje nisu sačuvani: %+v", locked)
+	}
+	if err := f.Users.ResetLoginFailures(f.ctx, user.KorisnikID); err != nil {
+		t.Fatalf("ResetLoginFailures greška: %v", err)
+	}
+	_, err = f.Users.RecordLoginFailure(f.ctx, -1, failedAt)
+	expectNotFound(t, "RecordLoginFailure", err)
+	expectNotFound(t, "Lock", f.Users.Lock(f.ctx, -1, lockedUntil))
+
+	revokedAt := time.Now().UTC().Truncate(time.Microsecond)
+	if err := f.Users.RevokeSessions(f.ctx, user.KorisnikID, revokedAt); err != nil {
+		t.Fatalf("RevokeSessions greška: %v", err)
+	}
+	if revoked, _ := f.Users.GetByID(f.ctx, user.KorisnikID); revoked.SesijeNevazeceOd == nil || !revoked.SesijeNevazeceOd.Equal(revokedAt) {
+		t.Errorf("RevokeSessions nije sačuvan: %+v", revoked.SesijeNevazeceOd)
+	}
+	expectNotFound(t, "RevokeSessions", f.Users.RevokeSessions(f.ctx, -1, revokedAt))
+
+	updated, err := f.Users.GetByID(f.ctx, user.KorisnikID)
+	if err != nil {
+		t.Fatalf("GetByID greška: %v", err)
+	}
+	if *updated.Ime != "Promenjeno" || updated.Status != "neaktivan" || updated.HashSifre != "novi-hash" || !updated.MoraPromenitiLozinku {
+		t.Errorf("Izmene nisu sačuvane: %+v", updated)
+	}
+	if updated.PoslednajaPrijava == nil {
+		t.Errorf("Poslednja prijava mora biti postavljena")
+	}
+	if updated.NeuspesnePrijave != 0 || updated.ZakljucanDo != nil || updated.PoslednjaNeuspesnaPrijava != nil {
+		t.Errorf("ResetLoginFailures mora obrisati brojač i zaključavanje: %+v", updated)
+	}
+
+	all, err := f.Users.GetAll(f.ctx)
+	if err != nil {
+		t.Fatalf("GetAll greška: %v", err)
+	}
+	found := false
+	for _, u := range all {
+		if u.KorisnikID == user.KorisnikID {
+			found = true
+			if u.HashSifre != "" {
+				t.Errorf("GetAll ne sme vraćati hash lozinke")
+			}
+		}
+	}
+	if !found {
+		t.Errorf("GetAll ne sadrži novog korisnika")
+	}
+
+	expectNotFound(t, "Update", f.Users.Update(f.ctx, &models.User{KorisnikID: -1, KorisnickoIme: unique("x"), Email: unique("x"), UlogaID: user.UlogaID}))
+	expectNotFound(t, "UpdatePassword", f.Users.UpdatePassword(f.ctx, -1, "x", false))
+
+	if err := f.Users.Delete(f.ctx, user.KorisnikID); err != nil {
+		t.Fatalf("Delete greška: %v", err)
+	}
+	_, err = f.Users.GetByID(f.ctx, user.KorisnikID)
+	expectNotFound(t, "GetByID posle brisanja", err)
+	expectNotFound(t, "Delete", f.Users.Delete(f.ctx, user.KorisnikID))
+}
+
+// Test uloga: dozvole se čuvaju sa ulogom, svaka izmena menja verziju, a
+// povučena uloga se više ne dodeljuje
+func testRoles(t *testing.T, f *fixture) {
+	version, err := f.Roles.Version(f.ctx)
+	if err != nil {
+		t.Fatalf("Version greška: %v", err)
+	}
+
+	roles, err := f.Roles.GetAll(f.ctx)
+	if err != nil || len(roles) == 0 {
+		t.Fatalf("GetAll: %v, %v", roles, err)
+	}
+	system := map[string]models.Role{}
+	for _, role := range roles {
+		if role.NazivUloge == "Administrator" && (len(role.Dozvole) != 1 || role.Dozvole[0].Dozvola != "*") {
+			t.Errorf("Administrator mora imati sve dozvole: %+v", role.Dozvole)
+		}
+		if role.Sistemska != nil {
+			system[*role.Sistemska] = role
+		}
+	}
+	for _, key := range []string{"administrator", "rukovodilac", "istrazivac", "organizator", "gost"} {
+		if _, ok := system[key]; !ok {
+			t.Errorf("Nedostaje sistemska uloga %s: %+v", key, system)
+		}
+	}
+
+	// Izmena sistemske uloge ne briše njen ključ
+	guest := system["gost"]
+	guest.Sistemska = nil
+	if err := f.Roles.Update(f.ctx, &guest); err != nil {
+		t.Fatalf("Update sistemske uloge: %v", err)
+	}
+	if stored, _ := f.Roles.GetByID(f.ctx, guest.UlogaID); stored.Sistemska == nil || *stored.Sistemska != "gost" {
+		t.Errorf("Izmena ne sme obrisati ključ sistemske uloge: %+v", stored)
+	}
+
+	project := f.project(t, f.user(t))
+	role := &models.Role{NazivUloge: unique("uloga"), Opis: ptr("Recenzent"), Sistemska: ptr("recenzent"), Dozvole: []models.RoleGrant{
+		{Dozvola: "task.view"},
+		{Dozvola: "document.view", ProjekatID: &project.ProjekatID},
+		{Dozvola: "document.update", ProjekatID: &project.ProjekatID},
+	}}
+	if err := f.Roles.Create(f.ctx, role); err != nil {
+		t.Fatalf("Create greška: %v", err)
+	}
+	if err := f.Roles.Create(f.ctx, &models.Role{NazivUloge: role.NazivUloge}); err == nil {
+		t.Errorf("Ime uloge mora biti jedinstveno")
+	}
+	if err := f.Roles.Create(f.ctx, &models.Role{NazivUloge: unique("uloga"), Dozvole: []models.RoleGrant{
+		{Dozvola: "task.view", ProjekatID: ptr(-1)},
+	}}); err == nil {
+		t.Errorf("Dozvola u nepostojećem projektu mora biti odbijena")
+	}
+
+	stored, err := f.Roles.GetByID(f.ctx, role.UlogaID)
+	if err != nil || stored.Opis == nil || *stored.Opis != "Recenzent" || stored.Sistemska != nil || stored.Povucena != nil || len(stored.Dozvole) != 3 ||
+		stored.Dozvole[0].Dozvola != "document.update" || stored.Dozvole[2].Dozvola != "task.view" || stored.Dozvole[2].ProjekatID != nil {
+		t.Fatalf("GetByID: %+v, %v", stored, err)
+	}
+	if next, _ := f.Roles.Version(f.ctx); next <= version {
+		t.Errorf("Kreiranje uloge mora promeniti verziju: %d -> %d", version, next)
+	}
+	expectNotFound(t, "GetByID nepostojeće uloge", func() error { _, err := f.Roles.GetByID(f.ctx, -1); return err }())
+
+	// Izmena zamenjuje dozvole
+	version, _ = f.Roles.Version(f.ctx)
+	stored.NazivUloge = unique("uloga")
+	stored.Dozvole = []models.RoleGrant{{Dozvola: "task.comment", ProjekatID: &project.ProjekatID}}
+	if err := f.Roles.Update(f.ctx, stored); err != nil {
+		t.Fatalf("Update greška: %v", err)
+	}
+	updated, _ := f.Roles.GetByID(f.ctx, role.UlogaID)
+	if updated.NazivUloge != stored.NazivUloge || len(updated.Dozvole) != 1 || updated.Dozvole[0].Dozvola != "task.comment" {
+		t.Errorf("Update nije sačuvan: %+v", updated)
+	}
+	if next, _ := f.Roles.Version(f.ctx); next <= version {
+		t.Errorf("Izmena uloge mora promeniti verziju: %d -> %d", version, next)
+	}
+	expectNotFound(t, "Update nepostojeće uloge", f.Roles.Update(f.ctx, &models.Role{UlogaID: -1, NazivUloge: unique("uloga")}))
+
+	// Uloga koju korisnik ima ne može se povući
+	user := f.user(t)
+	user.UlogaID = role.UlogaID
+	if err := f.Users.Update(f.ctx, user); err != nil {
+		t.Fatalf("Dodela uloge nije uspela: %v", err)
+	}
+	if err := f.Roles.Retire(f.ctx, role.UlogaID); !errors.Is(err, repositories.ErrConflict) {
+		t.Errorf("Povlačenje dodeljene uloge: očekivana ErrConflict, dobijeno %v", err)
+	}
+	if err := f.Users.Delete(f.ctx, user.KorisnikID); err != nil {
+		t.Fatalf("Delete greška: %v", err)
+	}
+
+	version, _ = f.Roles.Version(f.ctx)
+	if err := f.Roles.Retire(f.ctx, role.UlogaID); err != nil {
+		t.Fatalf("Retire greška: %v", err)
+	}
+	if retired, _ := f.Roles.GetByID(f.ctx, role.UlogaID); retired.Povucena == nil {
+		t.Errorf("Uloga nije označena kao povučena")
+	}
+	if next, _ := f.Roles.Version(f.ctx); next <= version {
+		t.Errorf("Povlačenje uloge mora promeniti verziju: %d -> %d", version, next)
+	}
+	if err := f.Roles.Retire(f.ctx, role.UlogaID); !errors.Is(err, repositories.ErrConflict) {
+		t.Errorf("Ponovno povlačenje: očekivana ErrConflict, dobijeno %v", err)
+	}
+	expectNotFound(t, "Retire nepostojeće uloge", f.Roles.Retire(f.ctx, -1))
+
+	assignable, _ := f.Users.GetRoles(f.ctx)
+	for _, r := range assignable {
+		if r.UlogaID == role.UlogaID {
+			t.Errorf("Povučena uloga ne sme biti među ulogama za dodelu")
+		}
+	}
+
+	// Brisanje projekta briše i dozvole u njemu
+	if err := f.Projects.Delete(f.ctx, project.ProjekatID); err != nil {
+		t.Fatalf("Brisanje projekta nije uspelo: %v", err)
+	}
+	if cleared, _ := f.Roles.GetByID(f.ctx, role.UlogaID); len(cleared.Dozvole) != 0 {
+		t.Errorf("Dozvole obrisanog projekta moraju nestati: %+v", cleared.Dozvole)
+	}
+}
+
+// Test aktivacionih kodova: samo najnoviji važi i koristi se jednom
+func testActivations(t *testing.T, f *fixture) {
+	user, admin := f.user(t), f.user(t)
+
+	_, err := f.Activations.GetPending(f.ctx, user.KorisnikID)
+	expectNotFound(t, "GetPending bez koda", err)
+
+	first := &models.Activation{KorisnikID: user.KorisnikID, HashKoda: unique("hash"), Istice: time.Now().Add(time.Hour), KreiraoKorisnikID: &admin.KorisnikID}
+	if err := f.Activations.Create(f.ctx, first); err != nil || first.AktivacijaID == 0 {
+		t.Fatalf("Create greška: %v", err)
+	}
+	second := &models.Activation{KorisnikID: user.KorisnikID, HashKoda: unique("hash"), Istice: time.Now().Add(time.Hour)}
+	if err := f.Activations.Create(f.ctx, second); err != nil {
+		t.Fatalf("Create greška: %v", err)
+	}
+
+	pending, err := f.Activations.GetPending(f.ctx, user.KorisnikID)
+	if err != nil || pending.AktivacijaID != second.AktivacijaID || pending.HashKoda != second.HashKoda {
+		t.Fatalf("GetPending mora vratiti najnoviji kod: %+v, %v", pending, err)
+	}
+	if err := f.Activations.Consume(f.ctx, first.AktivacijaID); !errors.Is(err, repositories.ErrConflict) {
+		t.Errorf("Zamenjen kod ne sme biti iskorišćen, dobijeno %v", err)
+	}
+
+	if err := f.Activations.Consume(f.ctx, second.AktivacijaID); err != nil {
+		t.Fatalf("Consume greška: %v", err)
+	}
+	if err := f.Activations.Consume(f.ctx, second.AktivacijaID); !errors.Is(err, repositories.ErrConflict) {
+		t.Errorf("Kod se sme iskoristiti samo jednom, dobijeno %v", err)
+	}
+	_, err = f.Activations.GetPending(f.ctx, user.KorisnikID)
+	expectNotFound(t, "GetPending posle korišćenja", err)
+
+	third := &models.Activation{KorisnikID: user.KorisnikID, HashKoda: unique("hash"), Istice: time.Now().Add(time.Hour)}
+	if err := f.Activations.Create(f.ctx, third); err != nil {
+		t.Fatalf("Create greška: %v", err)
+	}
+	if err := f.Activations.Revoke(f.ctx, user.KorisnikID); err != nil {
+		t.Fatalf("Revoke greška: %v", err)
+	}
+	_, err = f.Activations.GetPending(f.ctx, user.KorisnikID)
+	expectNotFound(t, "GetPending posle opoziva", err)
+
+	// Codes go away with their user
+	fourth := &models.Activation{KorisnikID: user.KorisnikID, HashKoda: unique("hash"), Istice: time.Now().Add(time.Hour)}
+	if err := f.Activations.Create(f.ctx, fourth); err != nil {
+		t.Fatalf("Create greška: %v", err)
+	}
+	if err := f.Users.Delete(f.ctx, user.KorisnikID); err != nil {
+		t.Errorf("Korisnik sa aktivacionim kodom mora moći da se obriše: %v", err)
+	}
+}
+
+// Test istorije lozinki: redosled, ograničenje broja i brisanje sa korisnikom
+func testPasswordHistory(t *testing.T, f *fixture) {
+	user, other := f.user(t), f.user(t)
+
+	if hashes, err := f.Passwords.Recent(f.ctx, user.KorisnikID, 5); err != nil || len(hashes) != 0 {
+		t.Fatalf("Nova istorija mora biti prazna: %v, %v", hashes, err)
+	}
+
+	for _, hash := range []string{"h1", "h2", "h3", "h4"} {
+		if err := f.Passwords.Add(f.ctx, user.KorisnikID, hash, 3); err != nil {
+			t.Fatalf("Add greška: %v", err)
+		}
+	}
+	if err := f.Passwords.Add(f.ctx, other.KorisnikID, "drugi", 3); err != nil {
+		t.Fatalf("Add greška: %v", err)
+	}
+
+	hashes, err := f.Passwords.Recent(f.ctx, user.KorisnikID, 5)
+	if err != nil || strings.Join(hashes, ",") != "h4,h3,h2" {
+		t.Errorf("Očekivana tri najnovija heša, dobijeno %v, %v", hashes, err)
+	}
+	hashes, _ = f.Passwords.Recent(f.ctx, user.KorisnikID, 1)
+	if len(hashes) != 1 || hashes[0] != "h4" {
+		t.Errorf("Recent mora poštovati ograničenje: %v", hashes)
+	}
+
+	if err := f.Passwords.Add(f.ctx, -1, "h", 3); err == nil {
+		t.Errorf("Istorija nepostojećeg korisnika mora biti odbijena")
+	}
+
+	if err := f.Users.Delete(f.ctx, user.KorisnikID); err != nil {
+		t.Fatalf("Korisnik sa istorijom lozinki mora moći da se obriše: %v", err)
+	}
+	if hashes, _ := f.Passwords.Recent(f.ctx, other.KorisnikID, 5); len(hashes) != 1 {
+		t.Errorf("Brisanje korisnika ne sme dirati tuđu istoriju: %v", hashes)
+	}
+}
+
+// Test drugog faktora: potvrda, jednokratni vremenski koraci i rezervni kodovi
+func testTwoFactor(t *testing.T, f *fixture) {
+	user := f.user(t)
+
+	_, err := f.TwoFactor.Get(f.ctx, user.KorisnikID)
+	expectNotFound(t, "Get bez drugog faktora", err)
+
+	factor := &models.TwoFactor{KorisnikID: user.KorisnikID, Tajna: "PRVATAJNA"}
+	if err := f.TwoFactor.Begin(f.ctx, factor); err != nil {
+		t.Fatalf("Begin greška: %v", err)
+	}
+	if err := f.TwoFactor.UseStep(f.ctx, user.KorisnikID, 10); !errors.Is(err, repositories.ErrConflict) {
+		t.Errorf("Nepotvrđen faktor ne prima kodove, dobijeno %v", err)
+	}
+	if err := f.TwoFactor.Begin(f.ctx, &models.TwoFactor{KorisnikID: user.KorisnikID, Tajna: "DRUGATAJNA"}); err != nil {
+		t.Fatalf("Nepotvrđen faktor mora moći da se zameni: %v", err)
+	}
+
+	if err := f.TwoFactor.Confirm(f.ctx, user.KorisnikID, 100, []string{"k1", "k2"}); err != nil {
+		t.Fatalf("Confirm greška: %v", err)
+	}
+	stored, err := f.TwoFactor.Get(f.ctx, user.KorisnikID)
+	if err != nil || stored.Tajna != "DRUGATAJNA" || stored.Potvrdjen == nil || stored.PoslednjiKorak != 100 {
+		t.Fatalf("Get posle potvrde: %+v, %v", stored, err)
+	}
+	if err := f.TwoFactor.Confirm(f.ctx, user.KorisnikID, 101, nil); !errors.Is(err, repositories.ErrConflict) {
+		t.Errorf("Faktor se potvrđuje samo jednom, dobijeno %v", err)
+	}
+	if err := f.TwoFactor.Begin(f.ctx, &models.TwoFactor{KorisnikID: user.KorisnikID, Tajna: "TRECATAJNA"}); !errors.Is(err, repositories.ErrConflict) {
+		t.Errorf("Potvrđen faktor ne sme biti zamenjen, dobijeno %v", err)
+	}
+
+	if err := f.TwoFactor.UseStep(f.ctx, user.KorisnikID, 100); !errors.Is(err, repositories.ErrConflict) {
+		t.Errorf("Iskorišćen korak ne sme važiti ponovo, dobijeno %v", err)
+	}
+	if err := f.TwoFactor.UseStep(f.ctx, user.KorisnikID, 101); err != nil {
+		t.Errorf("Kasniji korak mora važiti: %v", err)
+	}
+
+	if err := f.TwoFactor.UseRecoveryCode(f.ctx, user.KorisnikID, "k1"); err != nil {
+		t.Fatalf("UseRecoveryCode greška: %v", err)
+	}
+	if err := f.TwoFactor.UseRecoveryCode(f.ctx, user.KorisnikID, "k1"); !errors.Is(err, repositories.ErrConflict) {
+		t.Errorf("Rezervni kod se sme iskoristiti samo jednom, dobijeno %v", err)
+	}
+	if left, err := f.TwoFactor.RecoveryCodesLeft(f.ctx, user.KorisnikID); err != nil || left != 1 {
+		t.Errorf("Očekivan jedan neiskorišćen kod, dobijeno %d, %v", left, err)
+	}
+
+	if err := f.TwoFactor.Delete(f.ctx, user.KorisnikID); err != nil {
+		t.Fatalf("Delete greška: %v", err)
+	}
+	_, err = f.TwoFactor.Get(f.ctx, user.KorisnikID)
+	expectNotFound(t, "Get posle brisanja", err)
+	if left, _ := f.TwoFactor.RecoveryCodesLeft(f.ctx, user.KorisnikID); left != 0 {
+		t.Errorf("Brisanje mora ukloniti rezervne kodove, ostalo %d", left)
+	}
+
+	// Drugi faktor nestaje sa korisnikom
+	if err := f.TwoFactor.Begin(f.ctx, &models.TwoFactor{KorisnikID: user.KorisnikID, Tajna: "TAJNA"}); err != nil {
+		t.Fatalf("Begin greška: %v", err)
+	}
+	if err := f.TwoFactor.Confirm(f.ctx, user.KorisnikID, 1, []string{"k3"}); err != nil {
+		t.Fatalf("Confirm greška: %v", err)
+	}
+	if err := f.Users.Delete(f.ctx, user.KorisnikID); err != nil {
+		t.Errorf("Korisnik sa drugim faktorom mora moći da se obriše: %v", err)
+	}
+}
+
+// Test pristupnih tokena: pretraga po hešu, opoziv i brisanje sa korisnikom
+func testAccessTokens(t *testing.T, f *fixture) {
+	user := f.user(t)
+	hash := unique("hash")
+
+	token := &models.AccessToken{
+		KorisnikID: user.KorisnikID, Naziv: "skripta", Prefiks: "riis_abcd", HashTokena: hash,
+		Opsezi: []string{"task.view", "document.upload"}, Istice: time.Now().Add(time.Hour),
+	}
+	if err := f.Tokens.Create(f.ctx, token); err != nil {
+		t.Fatalf("Create greška: %v", err)
+	}
+	if token.TokenID == 0 || token.KreiranDatuma.IsZero() {
+		t.Errorf("Create mora popuniti ID i datum: %+v", token)
+	}
+	if err := f.Tokens.Create(f.ctx, &models.AccessToken{KorisnikID: user.KorisnikID, Naziv: "kopija", Prefiks: "riis_abcd",
+		HashTokena: hash, Opsezi: []string{"task.view"}, Istice: time.Now().Add(time.Hour)}); err == nil {
+		t.Errorf("Heš tokena mora biti jedinstven")
+	}
+
+	stored, err := f.Tokens.GetByHash(f.ctx, hash)
+	if err != nil || stored.TokenID != token.TokenID || len(stored.Opsezi) != 2 || stored.Opsezi[1] != "document.upload" ||
+		stored.PoslednjeKorisceno != nil || stored.Opozvan != nil {
+		t.Fatalf("GetByHash: %+v, %v", stored, err)
+	}
+	_, err = f.Tokens.GetByHash(f.ctx, unique("nepoznat"))
+	expectNotFound(t, "GetByHash nepoznatog tokena", err)
+
+	used := time.Now().Truncate(time.Second)
+	if err := f.Tokens.MarkUsed(f.ctx, token.TokenID, used); err != nil {
+		t.Fatalf("MarkUsed greška: %v", err)
+	}
+	if stored, _ := f.Tokens.GetByID(f.ctx, token.TokenID); stored.PoslednjeKorisceno == nil || !stored.PoslednjeKorisceno.Equal(used) {
+		t.Errorf("Očekivano poslednje korišćenje %v, dobijeno %v", used, stored.PoslednjeKorisceno)
+	}
+
+	second := &models.AccessToken{KorisnikID: user.KorisnikID, Naziv: "drugi", Prefiks: "riis_efgh", HashTokena: unique("hash"),
+		Opsezi: []string{"project.view"}, Istice: time.Now().Add(time.Hour)}
+	if err := f.Tokens.Create(f.ctx, second); err != nil {
+		t.Fatalf("Create greška: %v", err)
+	}
+	if tokens, err := f.Tokens.ListByUser(f.ctx, user.KorisnikID); err != nil || len(tokens) != 2 || tokens[0].TokenID != second.TokenID {
+		t.Errorf("ListByUser mora vratiti tokene od najnovijeg: %+v, %v", tokens, err)
+	}
+
+	if err := f.Tokens.Revoke(f.ctx, token.TokenID); err != nil {
+		t.Fatalf("Revoke greška: %v", err)
+	}
+	if stored, _ := f.Tokens.GetByID(f.ctx, token.TokenID); stored.Opozvan == nil {
+		t.Errorf("Opozvan token mora imati vreme opoziva")
+	}
+	if err := f.Tokens.Revoke(f.ctx, token.TokenID); !errors.Is(err, repositories.ErrConflict) {
+		t.Errorf("Token se opoziva samo jednom, dobijeno %v", err)
+	}
+	expectNotFound(t, "Revoke nepostojećeg tokena", f.Tokens.Revoke(f.ctx, -1))
+
+	// Tokeni nestaju sa korisnikom
+	if err := f.Users.Delete(f.ctx, user.KorisnikID); err != nil {
+		t.Fatalf("Korisnik sa tokenima mora moći da se obriše: %v", err)
+	}
+	_, err = f.Tokens.GetByID(f.ctx, second.TokenID)
+	expectNotFound(t, "GetByID posle brisanja korisnika", err)
+}
+
+// Test podešavanja i promene email adrese: zamena, brojanje pogrešnih kodova,
+// jedinstvena adresa i podrazumevani projekat koji nestaje sa projektom
+func testProfiles(t *testing.T, f *fixture) {
+	user, other := f.user(t), f.user(t)
+	project := f.project(t, user)
+
+	_, err := f.Profiles.GetPreferences(f.ctx, user.KorisnikID)
+	expectNotFound(t, "GetPreferences pre čuvanja", err)
+
+	prefs := &models.Preferences{KorisnikID: user.KorisnikID, Jezik: "en", PodrazumevaniProjekatID: &project.ProjekatID,
+		FormatDatuma: "yyyy-MM-dd", ObavestenjaEmail: true}
+	if err := f.Profiles.SavePreferences(f.ctx, prefs); err != nil {
+		t.Fatalf("SavePreferences greška: %v", err)
+	}
+	prefs.Jezik, prefs.ObavestenjaZadaci = "sr", true
+	if err := f.Profiles.SavePreferences(f.ctx, prefs); err != nil {
+		t.Fatalf("SavePreferences greška: %v", err)
+	}
+	stored, err := f.Profiles.GetPreferences(f.ctx, user.KorisnikID)
+	if err != nil || stored.Jezik != "sr" || !stored.ObavestenjaZadaci || stored.ObavestenjaKomentari ||
+		stored.PodrazumevaniProjekatID == nil || *stored.PodrazumevaniProjekatID != project.ProjekatID || stored.Izmenjeno.IsZero() {
+		t.Fatalf("GetPreferences: %+v, %v", stored, err)
+	}
+	missing := -1
+	if err := f.Profiles.SavePreferences(f.ctx, &models.Preferences{KorisnikID: user.KorisnikID, Jezik: "sr",
+		FormatDatuma: "dd.MM.yyyy", PodrazumevaniProjekatID: &missing}); err == nil {
+		t.Errorf("Podrazumevani projekat mora postojati")
+	}
+
+	change := &models.EmailChange{KorisnikID: user.KorisnikID, NoviEmail: unique("prvi") + "@test.local",
+		HashKoda: unique("hash"), Istice: time.Now().Add(time.Hour)}
+	if err := f.Profiles.BeginEmailChange(f.ctx, change); err != nil {
+		t.Fatalf("BeginEmailChange greška: %v", err)
+	}
+	if attempts, err := f.Profiles.FailEmailChange(f.ctx, user.KorisnikID); err != nil || attempts != 1 {
+		t.Errorf("FailEmailChange: %d, %v", attempts, err)
+	}
+	change.NoviEmail = unique("drugi") + "@test.local"
+	if err := f.Profiles.BeginEmailChange(f.ctx, change); err != nil {
+		t.Fatalf("BeginEmailChange greška: %v", err)
+	}
+	pending, err := f.Profiles.GetEmailChange(f.ctx, user.KorisnikID)
+	if err != nil || pending.NoviEmail != change.NoviEmail || pending.Pokusaji != 0 || pending.HashKoda != change.HashKoda {
+		t.Fatalf("Nova promena mora zameniti staru i poništiti pokušaje: %+v, %v", pending, err)
+	}
+
+	// Adresa drugog korisnika se ne može preuzeti
+	taken := &models.EmailChange{KorisnikID: other.KorisnikID, NoviEmail: change.NoviEmail,
+		HashKoda: unique("hash"), Istice: time.Now().Add(time.Hour)}
+	if err := f.Profiles.BeginEmailChange(f.ctx, taken); err != nil {
+		t.Fatalf("BeginEmailChange greška: %v", err)
+	}
+	if err := f.Profiles.CompleteEmailChange(f.ctx, user.KorisnikID); err != nil {
+		t.Fatalf("CompleteEmailChange greška: %v", err)
+	}
+	if updated, _ := f.Users.GetByID(f.ctx, user.KorisnikID); updated.Email != change.NoviEmail {
+		t.Errorf("Očekivan email %s, dobijeno %s", change.NoviEmail, updated.Email)
+	}
+	_, err = f.Profiles.GetEmailChange(f.ctx, user.KorisnikID)
+	expectNotFound(t, "GetEmailChange posle potvrde", err)
+	expectNotFound(t, "CompleteEmailChange bez zahteva", f.Profiles.CompleteEmailChange(f.ctx, user.KorisnikID))
+	if err := f.Profiles.CompleteEmailChange(f.ctx, other.KorisnikID); err == nil {
+		t.Errorf("Email adresa mora ostati jedinstvena")
+	}
+	if err := f.Profiles.CancelEmailChange(f.ctx, other.KorisnikID); err != nil {
+		t.Fatalf("CancelEmailChange greška: %v", err)
+	}
+	_, err = f.Profiles.FailEmailChange(f.ctx, other.KorisnikID)
+	expectNotFound(t, "FailEmailChange posle otkazivanja", err)
+
+	if err := f.Projects.Delete(f.ctx, project.ProjekatID); err != nil {
+		t.Fatalf("Greška pri brisanju projekta: %v", err)
+	}
+	if stored, _ := f.Profiles.GetPreferences(f.ctx, user.KorisnikID); stored.PodrazumevaniProjekatID != nil {
+		t.Errorf("Obrisan projekat ne sme ostati podrazumevani: %+v", stored)
+	}
+}
+
+// Test projekata: tim, vidljivost po članstvu i kaskadno brisanje
+func testProjects(t *testing.T, f *fixture) {
+	leader, member, outsider := f.user(t), f.user(t), f.user(t)
+	project := f.project(t, leader, member.KorisnikID)
+
+	if project.ProjekatID == 0 || project.Status != "Aktivan" {
+		t.Fatalf("Create mora popuniti ID i podrazumevani status: %+v", project)
+	}
+
+	got, err := f.Projects.GetByID(f.ctx, project.ProjekatID)
+	if err != nil {
+		t.Fatalf("GetByID greška: %v", err)
+	}
+	if got.RukovodilaIme != leader.KorisnickoIme || got.BrojClanova != 2 || got.BrojZadataka != 0 {
+		t.Errorf("GetByID vratio pogrešne spojene podatke: %+v", got)
+	}
+
+	members, err := f.Projects.GetMembers(f.ctx, project.ProjekatID)
+	if err != nil || len(members) != 2 {
+		t.Fatalf("Rukovodilac i član moraju biti u timu, dobijeno %d (%v)", len(members), err)
+	}
+	if members[0].KorisnickoIme > members[1].KorisnickoIme {
+		t.Errorf("Članovi moraju biti sortirani po korisničkom imenu")
+	}
+
+	for _, tc := range []struct {
+		user *models.User
+		want bool
+	}{{leader, true}, {member, true}, {outsider, false}} {
+		isMember, err := f.Projects.IsMember(f.ctx, project.ProjekatID, tc.user.KorisnikID)
+		if err != nil || isMember != tc.want {
+			t.Errorf("IsMember(%s) = %v, %v; očekivano %v", tc.user.KorisnickoIme, isMember, err, tc.want)
+		}
+
+		mine, err := f.Projects.GetByUserID(f.ctx, tc.user.KorisnikID)
+		if err != nil {
+			t.Fatalf("GetByUserID greška: %v", err)
+		}
+		if (len(mine) == 1 && mine[0].ProjekatID == project.ProjekatID) != tc.want {
+			t.Errorf("GetByUserID(%s) vratio %d projekata", tc.user.KorisnickoIme, len(mine))
+		}
+	}
+
+	if err := f.Projects.AddMember(f.ctx, project.ProjekatID, outsider.KorisnikID); err != nil {
+		t.Fatalf("AddMember greška: %v", err)
+	}
+	if err := f.Projects.AddMember(f.ctx, project.ProjekatID, outsider.KorisnikID); err != nil {
+		t.Errorf("Ponovno dodavanje člana mora biti bez greške: %v", err)
+	}
+	if err := f.Projects.RemoveMember(f.ctx, project.ProjekatID, member.KorisnikID); err != nil {
+		t.Fatalf("RemoveMember greška: %v", err)
+	}
+	if isMember, _ := f.Projects.IsMember(f.ctx, project.ProjekatID, member.KorisnikID); isMember {
+		t.Errorf("Uklonjeni član ne sme ostati u timu")
+	}
+
+	project.NazivProjekta = unique("izmenjen")
+	project.Status = "Završen"
+	project.RukovodilaID = &member.KorisnikID
+	if err := f.Projects.Update(f.ctx, project); err != nil {
+		t.Fatalf("Update greška: %v", err)
+	}
+	got, _ = f.Projects.GetByID(f.ctx, project.ProjekatID)
+	if got == nil || got.NazivProjekta != project.NazivProjekta || got.Status != "Završen" {
+		t.Errorf("Izmene projekta nisu sačuvane: %+v", got)
+	}
+	if isMember, _ := f.Projects.IsMember(f.ctx, project.ProjekatID, member.KorisnikID); !isMember {
+		t.Errorf("Novi rukovodilac mora postati član tima")
+	}
+
+	if err := f.Projects.Create(f.ctx, &models.Project{NazivProjekta: unique("los"), RukovodilaID: ptr(-1)}, nil); err == nil {
+		t.Errorf("Nepostojeći rukovodilac mora biti odbijen")
+	}
+
+	doc := &models.Document{ProjekatID: &project.ProjekatID, NazivDokumenta: unique("dok"), KreiraoKorisnikID: leader.KorisnikID}
+	version := &models.DocumentVersion{PutanjaDoFajla: unique("fajl"), PostavioKorisnikID: leader.KorisnikID}
+	if err := f.Documents.Create(f.ctx, doc, version, nil); err != nil {
+		t.Fatalf("Greška pri kreiranju dokumenta: %v", err)
+	}
+	if err := f.Projects.Delete(f.ctx, project.ProjekatID); err == nil {
+		t.Errorf("Projekat sa dokumentima ne sme biti obrisan")
+	}
+	if _, err := f.Documents.Delete(f.ctx, doc.DokumentID); err != nil {
+		t.Fatalf("Greška pri brisanju dokumenta: %v", err)
+	}
+
+	task := &models.Task{ProjekatID: project.ProjekatID, NazivZadatka: unique("zadatak")}
+	if err := f.Tasks.Create(f.ctx, task); err != nil {
+		t.Fatalf("Greška pri kreiranju zadatka: %v", err)
+	}
+	if err := f.Projects.Delete(f.ctx, project.ProjekatID); err != nil {
+		t.Fatalf("Delete greška: %v", err)
+	}
+	_, err = f.Tasks.GetByID(f.ctx, task.ZadatakID)
+	expectNotFound(t, "Zadatak obrisanog projekta", err)
+	_, err = f.Projects.GetByID(f.ctx, project.ProjekatID)
+	expectNotFound(t, "GetByID posle brisanja", err)
+	expectNotFound(t, "Delete", f.Projects.Delete(f.ctx, project.ProjekatID))
+	expectNotFound(t, "Update", f.Projects.Update(f.ctx, project))
+}
+
+// Test povezivanja projekta sa radnim tokom
+func testProjectWorkflow(t *testing.T, f *fixture) {
+	workflow, phases := f.projectWorkflow(t)
+	leader := f.user(t)
+	project := f.project(t, leader)
+
+	if err := f.Projects.SetWorkflow(f.ctx, project.ProjekatID, &workflow.RadniTokID); err != nil {
+		t.Fatalf("SetWorkflow greška: %v", err)
+	}
+
+	task := &models.Task{ProjekatID: project.ProjekatID, NazivZadatka: unique("zadatak")}
+	if err := f.Tasks.Create(f.ctx, task); err != nil {
+		t.Fatalf("Greška pri kreiranju zadatka: %v", err)
+	}
+	if task.FazaID != phases[0].FazaID {
+		t.Errorf("Zadatak mora početi u prvoj fazi toka: faza %d, očekivano %d", task.FazaID, phases[0].FazaID)
+	}
+
+	if err := f.Projects.SetWorkflow(f.ctx, project.ProjekatID, nil); !errors.Is(err, repositories.ErrConflict) {
+		t.Errorf("Uklanjanje toka sa zadacima u njegovim fazama mora biti odbijeno sa ErrConflict, dobijeno %v", err)
+	}
+
+	all, _ := f.Workflows.GetAll(f.ctx)
+	for _, other := range all {
+		if other.TipToka == "DOKUMENTACIJA" {
+			if err := f.Projects.SetWorkflow(f.ctx, project.ProjekatID, &other.RadniTokID); !errors.Is(err, repositories.ErrConflict) {
+				t.Errorf("Dokumentacioni tok ne sme biti dodeljen projektu: %v", err)
+			}
+			break
+		}
+	}
+
+	expectNotFound(t, "SetWorkflow nepostojeći tok", f.Projects.SetWorkflow(f.ctx, project.ProjekatID, ptr(-1)))
+	expectNotFound(t, "SetWorkflow nepostojeći projekat", f.Projects.SetWorkflow(f.ctx, -1, nil))
+}
+
+// Test zadataka i komentara
+func testTasks(t *testing.T, f *fixture) {
+	_, phases := f.projectWorkflow(t)
+	leader, assignee := f.user(t), f.user(t)
+	project := f.project(t, leader, assignee.KorisnikID)
+
+	task := &models.Task{
+		ProjekatID:           project.ProjekatID,
+		FazaID:               phases[0].FazaID,
+		NazivZadatka:         unique("zadatak"),
+		Opis:                 ptr("opis"),
+		DodjeljenKorisnikuID: &assignee.KorisnikID,
+		Prioritet:            ptr("Visok"),
+	}
+	if err := f.Tasks.Create(f.ctx, task); err != nil {
+		t.Fatalf("Create greška: %v", err)
+	}
+	if task.ZadatakID == 0 || task.KreiranDatuma.IsZero() {
+		t.Fatalf("Create mora popuniti ID i datum: %+v", task)
+	}
+
+	got, err := f.Tasks.GetByID(f.ctx, task.ZadatakID)
+	if err != nil {
+		t.Fatalf("GetByID greška: %v", err)
+	}
+	if got.NazivProjekta != project.NazivProjekta || got.NazivFaze != phases[0].NazivFaze ||
+		got.DodjeljenKorisniku != assignee.KorisnickoIme || got.Progres != 0 {
+		t.Errorf("GetByID vratio pogrešne spojene podatke: %+v", got)
+	}
+
+	second := &models.Task{ProjekatID: project.ProjekatID, FazaID: phases[0].FazaID, NazivZadatka: unique("zadatak")}
+	if err := f.Tasks.Create(f.ctx, second); err != nil {
+		t.Fatalf("Create greška: %v", err)
+	}
+
+	byProject, err := f.Tasks.GetByProject(f.ctx, project.ProjekatID)
+	if err != nil || len(byProject) != 2 {
+		t.Fatalf("GetByProject vratio %d zadataka (%v)", len(byProject), err)
+	}
+	if byProject[0].ZadatakID != second.ZadatakID {
+		t.Errorf("Noviji zadatak mora biti prvi")
+	}
+
+	byUser, err := f.Tasks.GetByUser(f.ctx, assignee.KorisnikID)
+	if err != nil || len(byUser) != 1 || byUser[0].ZadatakID != task.ZadatakID {
+		t.Errorf("GetByUser vratio %+v (%v)", byUser, err)
+	}
+
+	if err := f.Tasks.Update(f.ctx, task.ZadatakID, models.UpdateTaskRequest{
+		Progres: ptr(100),
+		FazaID:  &phases[len(phases)-1].FazaID,
+	}); err != nil {
+		t.Fatalf("Update greška: %v", err)
+	}
+	got, _ = f.Tasks.GetByID(f.ctx, task.ZadatakID)
+	if got == nil || got.Progres != 100 || got.FazaID != phases[len(phases)-1].FazaID {
+		t.Errorf("Izmene zadatka nisu sačuvane: %+v", got)
+	}
+	if got != nil && (got.NazivZadatka != task.NazivZadatka || got.Opis == nil || *got.Opis != "opis") {
+		t.Errorf("Update mora zadržati polja koja nisu poslata: %+v", got)
+	}
+	expectNotFound(t, "Update", f.Tasks.Update(f.ctx, -1, models.UpdateTaskRequest{Progres: ptr(1)}))
+
+	if err := f.Tasks.Create(f.ctx, &models.Task{ProjekatID: -1, NazivZadatka: "x"}); err == nil {
+		t.Errorf("Zadatak nepostojećeg projekta mora biti odbijen")
+	}
+
+	first := &models.TaskComment{ZadatakID: task.ZadatakID, KorisnikID: assignee.KorisnikID, TekstKomentara: "prvi"}
+	if err := f.Tasks.AddComment(f.ctx, first); err != nil {
+		t.Fatalf("AddComment greška: %v", err)
+	}
+	latest := &models.TaskComment{ZadatakID: task.ZadatakID, KorisnikID: leader.KorisnikID, TekstKomentara: "drugi"}
+	if err := f.Tasks.AddComment(f.ctx, latest); err != nil {
+		t.Fatalf("AddComment greška: %v", err)
+	}
+
+	comments, err := f.Tasks.GetComments(f.ctx, task.ZadatakID)
+	if err != nil || len(comments) != 2 {
+		t.Fatalf("GetComments vratio %d komentara (%v)", len(comments), err)
+	}
+	if comments[0].KomentarID != latest.KomentarID || comments[0].ImeKorisnika != leader.KorisnickoIme {
+		t.Errorf("Najnoviji komentar mora biti prvi, sa imenom autora: %+v", comments[0])
+	}
+
+	if err := f.Tasks.Delete(f.ctx, task.ZadatakID); err != nil {
+		t.Fatalf("Delete greška: %v", err)
+	}
+	comments, _ = f.Tasks.GetComments(f.ctx, task.ZadatakID)
+	if len(comments) != 0 {
+		t.Errorf("Komentari obrisanog zadatka moraju biti obrisani")
+	}
+	expectNotFound(t, "Delete", f.Tasks.Delete(f.ctx, task.ZadatakID))
+}
+
+// Test dokumenata, verzija, tagova i meta-podataka
+func testDocuments(t *testing.T, f *fixture) {
+	author := f.user(t)
+	project := f.project(t, author)
+
+	before, err := f.Documents.GetAll(f.ctx)
+	if err != nil {
+		t.Fatalf("GetAll greška: %v", err)
+	}
+
+	tagA, tagB := unique("tag_a"), unique("tag_b")
+	doc := &models.Document{
+		ProjekatID:        &project.ProjekatID,
+		NazivDokumenta:    unique("dokument"),
+		Opis:              ptr("opis"),
+		TipDokumenta:      ptr("PDF"),
+		KreiraoKorisnikID: author.KorisnikID,
+	}
+	version := &models.DocumentVersion{
+		VerzijaOznaka:      ptr("1.0"),
+		PutanjaDoFajla:     unique("uploads/fajl"),
+		VelicinafajlaMB:    ptr(0.5),
+		PostavioKorisnikID: author.KorisnikID,
+	}
+	if err := f.Documents.Create(f.ctx, doc, version, []string{tagB, tagA, tagA}); err != nil {
+		t.Fatalf("Create greška: %v", err)
+	}
+	deleted := false
+	t.Cleanup(func() {
+		if !deleted {
+			f.Documents.Delete(f.ctx, doc.DokumentID)
+		}
+	})
+
+	if doc.DokumentID == 0 || version.VerzijaID == 0 || version.DokumentID != doc.DokumentID {
+		t.Fatalf("Create mora popuniti ID dokumenta i verzije: %+v %+v", doc, version)
+	}
+
+	after, _ := f.Documents.GetAll(f.ctx)
+	if len(after) != len(before)+1 || after[0].DokumentID != doc.DokumentID {
+		t.Errorf("GetAll mora sadržati novi dokument na prvom mestu")
+	}
+
+	got, err := f.Documents.GetByID(f.ctx, doc.DokumentID)
+	if err != nil {
+		t.Fatalf("GetByID greška: %v", err)
+	}
+	if got.NazivProjekta != project.NazivProjekta || got.ImeKreirao != author.KorisnickoIme || got.BrojVerzija != 1 {
+		t.Errorf("GetByID vratio pogrešne spojene podatke: %+v", got)
+	}
+
+	byProject, err := f.Documents.GetByProject(f.ctx, project.ProjekatID)
+	if err != nil || len(byProject) != 1 {
+		t.Errorf("GetByProject vratio %d dokumenata (%v)", len(byProject), err)
+	}
+
+	tags, err := f.Documents.GetTags(f.ctx, doc.DokumentID)
+	if err != nil || len(tags) != 2 || tags[0].NazivTaga != tagA || tags[1].NazivTaga != tagB {
+		t.Fatalf("GetTags vratio %+v (%v)", tags, err)
+	}
+	if err := f.Documents.AddTag(f.ctx, doc.DokumentID, tagA); err != nil {
+		t.Errorf("Ponovno dodavanje taga mora biti bez greške: %v", err)
+	}
+	if err := f.Documents.RemoveTag(f.ctx, doc.DokumentID, tags[0].TagID); err != nil {
+		t.Fatalf("RemoveTag greška: %v", err)
+	}
+	tags, _ = f.Documents.GetTags(f.ctx, doc.DokumentID)
+	if len(tags) != 1 || tags[0].NazivTaga != tagB {
+		t.Errorf("Posle uklanjanja očekivan samo %s, dobijeno %+v", tagB, tags)
+	}
+
+	metadata := []models.Metadata{{Kljuc: "b", Vrednost: ptr("2")}, {Kljuc: "a", Vrednost: ptr("1")}}
+	if err := f.Documents.ReplaceMetadata(f.ctx, doc.DokumentID, metadata); err != nil {
+		t.Fatalf("ReplaceMetadata greška: %v", err)
+	}
+	if err := f.Documents.ReplaceMetadata(f.ctx, doc.DokumentID, metadata[:1]); err != nil {
+		t.Fatalf("ReplaceMetadata greška: %v", err)
+	}
+	stored, err := f.Documents.GetMetadata(f.ctx, doc.DokumentID)
+	if err != nil || len(stored) != 1 || stored[0].Kljuc != "b" || stored[0].DokumentID != doc.DokumentID {
+		t.Errorf("ReplaceMetadata mora zameniti sve meta-podatke, dobijeno %+v (%v)", stored, err)
+	}
+
+	doc.NazivDokumenta = unique("izmenjen")
+	if err := f.Documents.Update(f.ctx, doc); err != nil {
+		t.Fatalf("Update greška: %v", err)
+	}
+	got, _ = f.Documents.GetByID(f.ctx, doc.DokumentID)
+	if got == nil || got.NazivDokumenta != doc.NazivDokumenta || got.PoslednjaIzmena == nil {
+		t.Errorf("Izmene dokumenta nisu sačuvane: %+v", got)
+	}
+	expectNotFound(t, "Update", f.Documents.Update(f.ctx, &models.Document{DokumentID: -1, NazivDokumenta: "x"}))
+
+	versions, err := f.Documents.GetVersions(f.ctx, doc.DokumentID)
+	if err != nil || len(versions) != 1 || versions[0].PutanjaDoFajla != version.PutanjaDoFajla {
+		t.Errorf("GetVersions vratio %+v (%v)", versions, err)
+	}
+
+	paths, err := f.Documents.Delete(f.ctx, doc.DokumentID)
+	if err != nil {
+		t.Fatalf("Delete greška: %v", err)
+	}
+	deleted = true
+	if len(paths) != 1 || paths[0] != version.PutanjaDoFajla {
+		t.Errorf("Delete mora vratiti putanje fajlova, dobijeno %v", paths)
+	}
+	if versions, _ := f.Documents.GetVersions(f.ctx, doc.DokumentID); len(versions) != 0 {
+		t.Errorf("Verzije obrisanog dokumenta moraju biti obrisane")
+	}
+	if stored, _ := f.Documents.GetMetadata(f.ctx, doc.DokumentID); len(stored) != 0 {
+		t.Errorf("Meta-podaci obrisanog dokumenta moraju biti obrisani")
+	}
+	_, err = f.Documents.GetByID(f.ctx, doc.DokumentID)
+	expectNotFound(t, "GetByID posle brisanja", err)
+	_, err = f.Documents.Delete(f.ctx, doc.DokumentID)
+	expectNotFound(t, "Delete", err)
+}
+
+// Test foldera vlasnika
+func testFolders(t *testing.T, f *fixture) {
+	owner, other := f.user(t), f.user(t)
+
+	parent := &models.Folder{NazivFoldera: "b_" + unique("folder"), VlasnikID: owner.KorisnikID}
+	if err := f.Documents.CreateFolder(f.ctx, parent); err != nil {
+		t.Fatalf("CreateFolder greška: %v", err)
+	}
+	t.Cleanup(func() { f.Documents.DeleteFolder(f.ctx, parent.FolderID) })
+
+	child := &models.Folder{NazivFoldera: "a_" + unique("folder"), RoditeljFolderID: &parent.FolderID, VlasnikID: owner.KorisnikID}
+	if err := f.Documents.CreateFolder(f.ctx, child); err != nil {
+		t.Fatalf("CreateFolder greška: %v", err)
+	}
+
+	folders, err := f.Documents.GetFolders(f.ctx, owner.KorisnikID)
+	if err != nil || len(folders) != 2 {
+		t.Fatalf("GetFolders vratio %d foldera (%v)", len(folders), err)
+	}
+	if folders[0].FolderID != child.FolderID {
+		t.Errorf("Folderi moraju biti sortirani po nazivu")
+	}
+	if folders, _ := f.Documents.GetFolders(f.ctx, other.KorisnikID); len(folders) != 0 {
+		t.Errorf("Korisnik ne sme videti tuđe foldere")
+	}
+	if folder, err := f.Documents.GetFolder(f.ctx, child.FolderID); err != nil || *folder != *child {
+		t.Errorf("GetFolder vratio %+v (%v)", folder, err)
+	}
+
+	doc := &models.Document{FolderID: &child.FolderID, NazivDokumenta: unique("dok"), KreiraoKorisnikID: owner.KorisnikID}
+	if err := f.Documents.Create(f.ctx, doc, &models.DocumentVersion{PutanjaDoFajla: unique("f"), PostavioKorisnikID: owner.KorisnikID}, nil); err != nil {
+		t.Fatalf("Greška pri kreiranju dokumenta: %v", err)
+	}
+	t.Cleanup(func() { f.Documents.Delete(f.ctx, doc.DokumentID) })
+
+	if err := f.Documents.DeleteFolder(f.ctx, parent.FolderID); err != nil {
+		t.Fatalf("DeleteFolder greška: %v", err)
+	}
+	if folders, _ := f.Documents.GetFolders(f.ctx, owner.KorisnikID); len(folders) != 0 {
+		t.Errorf("Podfolderi moraju biti obrisani sa roditeljem")
+	}
+	got, err := f.Documents.GetByID(f.ctx, doc.DokumentID)
+	if err != nil || got.FolderID != nil {
+		t.Errorf("Dokument mora ostati bez foldera: %+v (%v)", got, err)
+	}
+	expectNotFound(t, "DeleteFolder", f.Documents.DeleteFolder(f.ctx, parent.FolderID))
+	expectNotFound(t, "GetFolder", func() error { _, err := f.Documents.GetFolder(f.ctx, child.FolderID); return err }())
+}
+
+// Test primopredaje: otvoreni posao prelazi na nove korisnike, završeni
+// zadaci i istorija ostaju, a reference sprečavaju brisanje
+func testOffboarding(t *testing.T, f *fixture) {
+	_, phases := f.projectWorkflow(t)
+	leaving, leader, assignee := f.user(t), f.user(t), f.user(t)
+	project := f.project(t, leaving)
+
+	open := &models.Task{ProjekatID: project.ProjekatID, FazaID: phases[0].FazaID, NazivZadatka: unique("zadatak"),
+		DodjeljenKorisnikuID: &leaving.KorisnikID}
+	done := &models.Task{ProjekatID: project.ProjekatID, FazaID: phases[0].FazaID, NazivZadatka: unique("zadatak"),
+		DodjeljenKorisnikuID: &leaving.KorisnikID}
+	for _, task := range []*models.Task{open, done} {
+		if err := f.Tasks.Create(f.ctx, task); err != nil {
+			t.Fatalf("Greška pri kreiranju zadatka: %v", err)
+		}
+	}
+	if err := f.Tasks.Update(f.ctx, done.ZadatakID, models.UpdateTaskRequest{Progres: ptr(100)}); err != nil {
+		t.Fatalf("Update greška: %v", err)
+	}
+
+	folder := &models.Folder{NazivFoldera: unique("folder"), VlasnikID: leaving.KorisnikID}
+	if err := f.Documents.CreateFolder(f.ctx, folder); err != nil {
+		t.Fatalf("CreateFolder greška: %v", err)
+	}
+	t.Cleanup(func() { f.Documents.DeleteFolder(f.ctx, folder.FolderID) })
+	doc := &models.Document{FolderID: &folder.FolderID, NazivDokumenta: unique("dok"), KreiraoKorisnikID: leaving.KorisnikID}
+	if err := f.Documents.Create(f.ctx, doc, &models.DocumentVersion{PutanjaDoFajla: unique("f"), PostavioKorisnikID: leaving.KorisnikID}, nil); err != nil {
+		t.Fatalf("Greška pri kreiranju dokumenta: %v", err)
+	}
+	t.Cleanup(func() { f.Documents.Delete(f.ctx, doc.DokumentID) })
+
+	token := &models.AccessToken{KorisnikID: leaving.KorisnikID, Naziv: "skripta", Prefiks: "riis_abcd", HashTokena: unique("hash"),
+		Opsezi: []string{"task.view"}, Istice: time.Now().Add(time.Hour)}
+	if err := f.Tokens.Create(f.ctx, token); err != nil {
+		t.Fatalf("Greška pri kreiranju tokena: %v", err)
+	}
+
+	references, err := f.Users.References(f.ctx, leaving.KorisnikID)
+	if err != nil || references["Projekti"] != 1 || references["Zadaci"] != 2 || references["Folderi"] != 1 ||
+		references["Dokumenti"] != 1 || references["VerzijeDokumenata"] != 1 {
+		t.Fatalf("References pre primopredaje: %v, %v", references, err)
+	}
+
+	// Primopredaja nepostojećem korisniku ne menja ništa
+	if _, err := f.Users.Offboard(f.ctx, leaving.KorisnikID, models.Handover{
+		RukovodilacID: &leader.KorisnikID, IzvrsilacID: ptr(-1),
+	}); err == nil {
+		t.Errorf("Primopredaja nepostojećem korisniku mora biti odbijena")
+	}
+	if got, _ := f.Projects.GetByID(f.ctx, project.ProjekatID); got == nil || *got.RukovodilaID != leaving.KorisnikID {
+		t.Errorf("Odbijena primopredaja ne sme preneti projekat: %+v", got)
+	}
+	if user, _ := f.Users.GetByID(f.ctx, leaving.KorisnikID); user == nil || user.Status != "aktivan" {
+		t.Errorf("Odbijena primopredaja ne sme deaktivirati nalog: %+v", user)
+	}
+
+	// Projekti i zadaci prelaze na nove korisnike, folderi i dokumenti ostaju
+	report, err := f.Users.Offboard(f.ctx, leaving.KorisnikID, models.Handover{
+		RukovodilacID: &leader.KorisnikID, IzvrsilacID: &assignee.KorisnikID,
+	})
+	if err != nil {
+		t.Fatalf("Offboard greška: %v", err)
+	}
+	if len(report.Projekti) != 1 || report.Projekti[0] != project.ProjekatID || len(report.Zadaci) != 1 ||
+		report.Zadaci[0] != open.ZadatakID || len(report.Folderi) != 0 || len(report.Dokumenti) != 0 || report.OpozvaniTokeni != 1 {
+		t.Errorf("Neispravan izveštaj primopredaje: %+v", report)
+	}
+	if user, _ := f.Users.GetByID(f.ctx, leaving.KorisnikID); user == nil || user.Status != "neaktivan" {
+		t.Errorf("Nalog mora biti deaktiviran: %+v", user)
+	}
+	if got, _ := f.Projects.GetByID(f.ctx, project.ProjekatID); got == nil || got.RukovodilaID == nil || *got.RukovodilaID != leader.KorisnikID {
+		t.Errorf("Projekat mora preći na novog rukovodioca: %+v", got)
+	}
+	if got, _ := f.Tasks.GetByID(f.ctx, open.ZadatakID); got == nil || *got.DodjeljenKorisnikuID != assignee.KorisnikID {
+		t.Errorf("Otvoren zadatak mora preći na novog izvršioca: %+v", got)
+	}
+	if got, _ := f.Tasks.GetByID(f.ctx, done.ZadatakID); got == nil || *got.DodjeljenKorisnikuID != leaving.KorisnikID {
+		t.Errorf("Završen zadatak mora ostati kod korisnika: %+v", got)
+	}
+	if stored, _ := f.Tokens.GetByID(f.ctx, token.TokenID); stored == nil || stored.Opozvan == nil {
+		t.Errorf("Tokeni korisnika moraju biti opozvani")
+	}
+
+	// Ponovna primopredaja prenosi vlasništvo nad folderima i dokumentima
+	report, err = f.Users.Offboard(f.ctx, leaving.KorisnikID, models.Handover{VlasnikID: &leader.KorisnikID})
+	if err != nil || len(report.Folderi) != 1 || len(report.Dokumenti) != 1 || len(report.Projekti) != 0 || report.OpozvaniTokeni != 0 {
+		t.Errorf("Neispravan izveštaj druge primopredaje: %+v, %v", report, err)
+	}
+	if got, _ := f.Documents.GetByID(f.ctx, doc.DokumentID); got == nil || got.KreiraoKorisnikID != leader.KorisnikID {
+		t.Errorf("Dokument mora preći na novog vlasnika: %+v", got)
+	}
+
+	references, err = f.Users.References(f.ctx, leaving.KorisnikID)
+	if err != nil || len(references) != 2 || references["Zadaci"] != 1 || references["VerzijeDokumenata"] != 1 {
+		t.Errorf("References posle primopredaje: %v, %v", references, err)
+	}
+	if blocked, err := f.Users.DeleteUnreferenced(f.ctx, leaving.KorisnikID); err != nil || len(blocked) != len(references) {
+		t.Errorf("DeleteUnreferenced mora vratiti reference umesto brisanja: %v, %v", blocked, err)
+	}
+	if _, err := f.Users.GetByID(f.ctx, leaving.KorisnikID); err != nil {
+		t.Errorf("Korisnik sa referencama ne sme biti obrisan: %v", err)
+	}
+	unused := f.user(t)
+	if blocked, err := f.Users.DeleteUnreferenced(f.ctx, unused.KorisnikID); err != nil || len(blocked) != 0 {
+		t.Fatalf("DeleteUnreferenced greška: %v, %v", blocked, err)
+	}
+	_, err = f.Users.GetByID(f.ctx, unused.KorisnikID)
+	expectNotFound(t, "GetByID posle DeleteUnreferenced", err)
+	_, err = f.Users.DeleteUnreferenced(f.ctx, unused.KorisnikID)
+	expectNotFound(t, "DeleteUnreferenced obrisanog korisnika", err)
+
+	_, err = f.Users.Offboard(f.ctx, -1, models.Handover{})
+	expectNotFound(t, "Offboard nepostojećeg korisnika", err)
+}
+
+// Test radnih tokova i faza
+func testWorkflows(t *testing.T, f *fixture) {
+	workflow := &models.Workflow{Naziv: unique("tok"), TipToka: "PROJEKAT", Opis: ptr("opis")}
+	if err := f.Workflows.Create(f.ctx, workflow); err != nil {
+		t.Fatalf("Create greška: %v", err)
+	}
+	t.Cleanup(func() { f.Workflows.Delete(f.ctx, workflow.RadniTokID) })
+
+	if err := f.Workflows.Create(f.ctx, &models.Workflow{Naziv: workflow.Naziv, TipToka: "PROJEKAT"}); err == nil {
+		t.Errorf("Naziv i tip toka moraju biti jedinstveni")
+	}
+	if err := f.Workflows.Create(f.ctx, &models.Workflow{Naziv: unique("tok"), TipToka: "NEPOZNAT"}); err == nil {
+		t.Errorf("Nepoznat tip toka mora biti odbijen")
+	}
+
+	for _, p := range []models.Phase{{NazivFaze: "Druga", Redosled: 2}, {NazivFaze: "Prva", Redosled: 1}} {
+		p.RadniTokID = workflow.RadniTokID
+		if err := f.Workflows.CreatePhase(f.ctx, &p); err != nil || p.FazaID == 0 {
+			t.Fatalf("CreatePhase greška: %v", err)
+		}
+	}
+
+	phases, err := f.Workflows.GetPhases(f.ctx, workflow.RadniTokID)
+	if err != nil || len(phases) != 2 || phases[0].NazivFaze != "Prva" {
+		t.Errorf("Faze moraju biti sortirane po redosledu, dobijeno %+v (%v)", phases, err)
+	}
+
+	all, err := f.Workflows.GetAll(f.ctx)
+	if err != nil {
+		t.Fatalf("GetAll greška: %v", err)
+	}
+	found := false
+	for _, w := range all {
+		found = found || w.RadniTokID == workflow.RadniTokID
+	}
+	if !found {
+		t.Errorf("GetAll ne sadrži novi tok")
+	}
+
+	leader := f.user(t)
+	project := f.project(t, leader)
+	if err := f.Projects.SetWorkflow(f.ctx, project.ProjekatID, &workflow.RadniTokID); err != nil {
+		t.Fatalf("SetWorkflow greška: %v", err)
+	}
+	if err := f.Workflows.Delete(f.ctx, workflow.RadniTokID); err == nil {
+		t.Errorf("Tok koji koristi projekat ne sme biti obrisan")
+	}
+	if err := f.Projects.SetWorkflow(f.ctx, project.ProjekatID, nil); err != nil {
+		t.Fatalf("SetWorkflow greška: %v", err)
+	}
+
+	if err := f.Workflows.Delete(f.ctx, workflow.RadniTokID); err != nil {
+		t.Fatalf("Delete greška: %v", err)
+	}
+	if phases, _ := f.Workflows.GetPhases(f.ctx, workflow.RadniTokID); len(phases) != 0 {
+		t.Errorf("Faze obrisanog toka moraju biti obrisane")
+	}
+	expectNotFound(t, "Delete", f.Workflows.Delete(f.ctx, workflow.RadniTokID))
+	if err := f.Workflows.CreatePhase(f.ctx, &models.Phase{RadniTokID: workflow.RadniTokID, NazivFaze: "x", Redosled: 1}); err == nil {
+		t.Errorf("Faza nepostojećeg toka mora biti odbijena")
+	}
+}
+
+// Test organizacionih jedinica: hijerarhija, članstvo i brisanje
+func testUnits(t *testing.T, f *fixture) {
+	head := f.user(t)
+	member := f.user(t)
+	project := f.project(t, head)
+	root := f.unit(t, nil, nil)
+	child := f.unit(t, &root.JedinicaID, &head.KorisnikID)
+
+	if child.JedinicaID == 0 || child.KreiranDatuma.IsZero() {
+		t.Fatalf("Create mora popuniti ID i datum kreiranja: %+v", child)
+	}
+	if err := f.Units.Create(f.ctx, &models.Unit{Naziv: child.Naziv, Tip: "odeljenje"}); err == nil {
+		t.Errorf("Dupli naziv jedinice mora biti odbijen")
+	}
+	if err := f.Units.Create(f.ctx, &models.Unit{Naziv: unique("jedinica"), Tip: "sektor"}); err == nil {
+		t.Errorf("Nepoznat tip jedinice mora biti odbijen")
+	}
+
+	if err := f.Units.SetUserUnit(f.ctx, member.KorisnikID, &child.JedinicaID); err != nil {
+		t.Fatalf("SetUserUnit greška: %v", err)
+	}
+	if err := f.Units.SetProjectUnit(f.ctx, project.ProjekatID, &child.JedinicaID); err != nil {
+		t.Fatalf("SetProjectUnit greška: %v", err)
+	}
+
+	stored, err := f.Units.GetByID(f.ctx, child.JedinicaID)
+	if err != nil {
+		t.Fatalf("GetByID greška: %v", err)
+	}
+	if *stored.NadredjenaJedinicaID != root.JedinicaID || stored.RukovodilacIme != head.KorisnickoIme ||
+		stored.BrojClanova != 1 || stored.BrojProjekata != 1 {
+		t.Errorf("GetByID vratio pogrešne podatke: %+v", stored)
+	}
+
+	members, err := f.Units.GetMembers(f.ctx, child.JedinicaID)
+	if err != nil || len(members) != 1 || members[0].KorisnikID != member.KorisnikID || members[0].NazivUloge == "" {
+		t.Errorf("GetMembers vratio %+v (%v)", members, err)
+	}
+
+	// Updates of the user and project keep their unit
+	if err := f.Users.Update(f.ctx, member); err != nil {
+		t.Fatalf("Update korisnika greška: %v", err)
+	}
+	if err := f.Projects.Update(f.ctx, project); err != nil {
+		t.Fatalf("Update projekta greška: %v", err)
+	}
+	if user, _ := f.Users.GetByID(f.ctx, member.KorisnikID); user.JedinicaID == nil || *user.JedinicaID != child.JedinicaID {
+		t.Errorf("Korisnik mora ostati u jedinici: %+v", user.JedinicaID)
+	}
+	if stored, _ := f.Projects.GetByID(f.ctx, project.ProjekatID); stored.JedinicaID == nil || *stored.JedinicaID != child.JedinicaID {
+		t.Errorf("Projekat mora ostati u jedinici: %+v", stored.JedinicaID)
+	}
+
+	if err := f.Units.Delete(f.ctx, root.JedinicaID); !errors.Is(err, repositories.ErrConflict) {
+		t.Errorf("Jedinica sa podjedinicama ne sme biti obrisana, dobijeno %v", err)
+	}
+
+	child.Naziv = unique("preimenovana")
+	child.Tip = "laboratorija"
+	child.NadredjenaJedinicaID = nil
+	if err := f.Units.Update(f.ctx, child); err != nil {
+		t.Fatalf("Update greška: %v", err)
+	}
+	all, err := f.Units.GetAll(f.ctx)
+	if err != nil {
+		t.Fatalf("GetAll greška: %v", err)
+	}
+	found := false
+	for _, unit := range all {
+		if unit.JedinicaID == child.JedinicaID {
+			found = unit.Naziv == child.Naziv && unit.Tip == "laboratorija" && unit.NadredjenaJedinicaID == nil
+		}
+	}
+	if !found {
+		t.Errorf("GetAll ne sadrži izmenjenu jedinicu")
+	}
+
+	if err := f.Units.Delete(f.ctx, root.JedinicaID); err != nil {
+		t.Fatalf("Delete greška: %v", err)
+	}
+	if err := f.Units.Delete(f.ctx, child.JedinicaID); err != nil {
+		t.Fatalf("Delete greška: %v", err)
+	}
+	if user, _ := f.Users.GetByID(f.ctx, member.KorisnikID); user.JedinicaID != nil {
+		t.Errorf("Članovi obrisane jedinice moraju ostati bez jedinice")
+	}
+	if stored, _ := f.Projects.GetByID(f.ctx, project.ProjekatID); stored.JedinicaID != nil {
+		t.Errorf("Projekti obrisane jedinice moraju ostati bez jedinice")
+	}
+
+	expectNotFound(t, "GetByID", func() error { _, err := f.Units.GetByID(f.ctx, child.JedinicaID); return err }())
+	expectNotFound(t, "Delete", f.Units.Delete(f.ctx, child.JedinicaID))
+	expectNotFound(t, "Update", f.Units.Update(f.ctx, child))
+	expectNotFound(t, "SetUserUnit", f.Units.SetUserUnit(f.ctx, -1, nil))
+	expectNotFound(t, "SetProjectUnit", f.Units.SetProjectUnit(f.ctx, -1, nil))
+	if err := f.Units.SetUserUnit(f.ctx, member.KorisnikID, &child.JedinicaID); err == nil {
+		t.Errorf("Nepostojeća jedinica mora biti odbijena")
+	}
+}
+
+// Test gostujućih naloga: deljenje, vidljivi dokumenti i istek
+func testGuests(t *testing.T, f *fixture) {
+	admin := f.user(t)
+	roles, err := f.Users.GetRoles(f.ctx)
+	if err != nil {
+		t.Fatalf("Uloge nisu pročitane: %v", err)
+	}
+
+	name := unique("gost")
+	until := time.Now().Add(time.Hour).Truncate(time.Second)
+	guest := &models.User{KorisnickoIme: name, Email: name + "@partner.local", HashSifre: "hash",
+		UlogaID: roles[0].UlogaID, Status: "aktivan", GostDo: &until}
+	if err := f.Users.Create(f.ctx, guest); err != nil {
+		t.Fatalf("Greška pri kreiranju gosta: %v", err)
+	}
+	t.Cleanup(func() { f.Users.Delete(f.ctx, guest.KorisnikID) })
+	if stored, err := f.Users.GetByID(f.ctx, guest.KorisnikID); err != nil || stored.GostDo == nil || !stored.GostDo.Equal(until) {
+		t.Fatalf("GetByID mora vratiti istek gosta: %+v (%v)", stored, err)
+	}
+
+	project := f.project(t, admin)
+	parent := &models.Folder{NazivFoldera: unique("folder"), VlasnikID: admin.KorisnikID}
+	if err := f.Documents.CreateFolder(f.ctx, parent); err != nil {
+		t.Fatalf("CreateFolder greška: %v", err)
+	}
+	t.Cleanup(func() { f.Documents.DeleteFolder(f.ctx, parent.FolderID) })
+	child := &models.Folder{NazivFoldera: unique("folder"), RoditeljFolderID: &parent.FolderID, VlasnikID: admin.KorisnikID}
+	if err := f.Documents.CreateFolder(f.ctx, child); err != nil {
+		t.Fatalf("CreateFolder greška: %v", err)
+	}
+
+	document := func(projectID, folderID *int) *models.Document {
+		doc := &models.Document{ProjekatID: projectID, FolderID: folderID, NazivDokumenta: unique("dok"), KreiraoKorisnikID: admin.KorisnikID}
+		if err := f.Documents.Create(f.ctx, doc, &models.DocumentVersion{PutanjaDoFajla: unique("f"), PostavioKorisnikID: admin.KorisnikID}, nil); err != nil {
+			t.Fatalf("Greška pri kreiranju dokumenta: %v", err)
+		}
+		t.Cleanup(func() { f.Documents.Delete(f.ctx, doc.DokumentID) })
+		return doc
+	}
+	inProject, inSubfolder, direct, other := document(&project.ProjekatID, nil), document(nil, &child.FolderID), document(nil, nil), document(nil, nil)
+
+	for _, share := range []*models.GuestShare{
+		{KorisnikID: guest.KorisnikID, ProjekatID: &project.ProjekatID, OdobrioID: &admin.KorisnikID},
+		{KorisnikID: guest.KorisnikID, FolderID: &parent.FolderID, OdobrioID: &admin.KorisnikID},
+		{KorisnikID: guest.KorisnikID, DokumentID: &direct.DokumentID, OdobrioID: &admin.KorisnikID},
+	} {
+		if err := f.Guests.Share(f.ctx, share); err != nil {
+			t.Fatalf("Share greška: %v", err)
+		}
+		if share.PristupID == 0 || share.KreiranDatuma.IsZero() {
+			t.Fatalf("Share mora popuniti ID i datum: %+v", share)
+		}
+	}
+	err = f.Guests.Share(f.ctx, &models.GuestShare{KorisnikID: guest.KorisnikID, ProjekatID: &project.ProjekatID})
+	if !errors.Is(err, repositories.ErrConflict) {
+		t.Errorf("Isti projekat ne sme biti podeljen dvaput, dobijeno %v", err)
+	}
+	if err := f.Guests.Share(f.ctx, &models.GuestShare{KorisnikID: guest.KorisnikID}); err == nil {
+		t.Errorf("Deljenje bez projekta, foldera i dokumenta mora biti odbijeno")
+	}
+	if err := f.Guests.Share(f.ctx, &models.GuestShare{KorisnikID: guest.KorisnikID, ProjekatID: &project.ProjekatID, FolderID: &child.FolderID}); err == nil {
+		t.Errorf("Deljenje projekta i foldera odjednom mora biti odbijeno")
+	}
+
+	shares, err := f.Guests.GetShares(f.ctx, guest.KorisnikID)
+	if err != nil || len(shares) != 3 {
+		t.Fatalf("GetShares vratio %+v (%v)", shares, err)
+	}
+	if shares[0].Naziv != project.NazivProjekta || shares[1].Naziv != parent.NazivFoldera || shares[2].Naziv != direct.NazivDokumenta ||
+		shares[0].OdobrioIme != admin.KorisnickoIme || shares[0].KorisnickoIme != name || shares[0].GostDo == nil {
+		t.Errorf("GetShares vratio pogrešne podatke: %+v", shares)
+	}
+	all, err := f.Guests.GetAllShares(f.ctx)
+	if err != nil {
+		t.Fatalf("GetAllShares greška: %v", err)
+	}
+	listed := 0
+	for _, share := range all {
+		if share.KorisnikID == guest.KorisnikID {
+			listed++
+		}
+	}
+	if listed != 3 {
+		t.Errorf("GetAllShares mora sadržati deljenja aktivnog gosta, nađeno %d", listed)
+	}
+
+	documents, err := f.Guests.GetSharedDocuments(f.ctx, guest.KorisnikID)
+	if err != nil {
+		t.Fatalf("GetSharedDocuments greška: %v", err)
+	}
+	visible := map[int]bool{}
+	for _, doc := range documents {
+		visible[doc.DokumentID] = true
+	}
+	if len(documents) != 3 || !visible[inProject.DokumentID] || !visible[inSubfolder.DokumentID] || !visible[direct.DokumentID] || visible[other.DokumentID] {
+		t.Errorf("Gost mora videti dokumente projekta, podfoldera i direktno podeljene, dobijeno %+v", visible)
+	}
+
+	// Deleting a shared document removes its share
+	if _, err := f.Documents.Delete(f.ctx, direct.DokumentID); err != nil {
+		t.Fatalf("Delete dokumenta greška: %v", err)
+	}
+	expectNotFound(t, "GetShare", func() error { _, err := f.Guests.GetShare(f.ctx, shares[2].PristupID); return err }())
+	if err := f.Guests.Unshare(f.ctx, shares[0].PristupID); err != nil {
+		t.Fatalf("Unshare greška: %v", err)
+	}
+	expectNotFound(t, "Unshare", f.Guests.Unshare(f.ctx, shares[0].PristupID))
+
+	expectNotFound(t, "SetExpiry", f.Guests.SetExpiry(f.ctx, admin.KorisnikID, until))
+	expired := time.Now().Add(-time.Minute).Truncate(time.Second)
+	if err := f.Guests.SetExpiry(f.ctx, guest.KorisnikID, expired); err != nil {
+		t.Fatalf("SetExpiry greška: %v", err)
+	}
+
+	ids, err := f.Guests.ExpireGuests(f.ctx, time.Now())
+	if err != nil {
+		t.Fatalf("ExpireGuests greška: %v", err)
+	}
+	found := false
+	for _, id := range ids {
+		found = found || id == guest.KorisnikID
+	}
+	if !found {
+		t.Errorf("ExpireGuests mora vratiti isteklog gosta, dobijeno %v", ids)
+	}
+	stored, _ := f.Users.GetByID(f.ctx, guest.KorisnikID)
+	if stored.Status != "neaktivan" || !stored.GostDo.Equal(expired) {
+		t.Errorf("Istekli gost mora biti deaktiviran: %+v", stored)
+	}
+	if shares, _ := f.Guests.GetShares(f.ctx, guest.KorisnikID); len(shares) != 0 {
+		t.Errorf("Deljenja isteklog gosta moraju biti uklonjena: %+v", shares)
+	}
+	ids, _ = f.Guests.ExpireGuests(f.ctx, time.Now())
+	for _, id := range ids {
+		if id == guest.KorisnikID {
+			t.Errorf("Već deaktiviran gost ne sme ponovo isteći")
+		}
+	}
+	if user, _ := f.Users.GetByID(f.ctx, admin.KorisnikID); user.Status != "aktivan" {
+		t.Errorf("Korisnik koji nije gost ne sme isteći")
+	}
+}
+
+// Test statistike i dnevnika aktivnosti
+func testAnalytics(t *testing.T, f *fixture) {
+	before, err := f.Analytics.GetDashboardStats(f.ctx, nil)
+	if err != nil {
+		t.Fatalf("GetDashboardStats greška: %v", err)
+	}
+
+	user := f.user(t)
+	project := f.project(t, user)
+	if err := f.Users.UpdateLastLogin(f.ctx, user.KorisnikID); err != nil {
+		t.Fatalf("UpdateLastLogin greška: %v", err)
+	}
+	for _, progress := range []int{0, 100} {
+		task := &models.Task{ProjekatID: project.ProjekatID, NazivZadatka: unique("zadatak"), Progres: progress}
+		if err := f.Tasks.Create(f.ctx, task); err != nil {
+			t.Fatalf("Greška pri kreiranju zadatka: %v", err)
+		}
+	}
+
+	after, err := f.Analytics.GetDashboardStats(f.ctx, nil)
+	if err != nil {
+		t.Fatalf("GetDashboardStats greška: %v", err)
+	}
+	if after.AktivniProjekti-before.AktivniProjekti != 1 ||
+		after.ZadaciUToku-before.ZadaciUToku != 1 ||
+		after.AktivniKorisnici-before.AktivniKorisnici != 1 ||
+		after.UkupnoDokumenata != before.UkupnoDokumenata {
+		t.Errorf("Neočekivana promena statistike: pre %+v, posle %+v", before, after)
+	}
+
+	// Stats of units count only what belongs to them
+	unit := f.unit(t, nil, nil)
+	if err := f.Units.SetUserUnit(f.ctx, user.KorisnikID, &unit.JedinicaID); err != nil {
+		t.Fatalf("SetUserUnit greška: %v", err)
+	}
+	if err := f.Units.SetProjectUnit(f.ctx, project.ProjekatID, &unit.JedinicaID); err != nil {
+		t.Fatalf("SetProjectUnit greška: %v", err)
+	}
+	scoped, err := f.Analytics.GetDashboardStats(f.ctx, []int{unit.JedinicaID})
+	if err != nil {
+		t.Fatalf("GetDashboardStats greška: %v", err)
+	}
+	want := models.DashboardStats{AktivniProjekti: 1, ZadaciUToku: 1, AktivniKorisnici: 1}
+	if scoped != want {
+		t.Errorf("Statistika jedinice: očekivano %+v, dobijeno %+v", want, scoped)
+	}
+	if none, err := f.Analytics.GetDashboardStats(f.ctx, []int{}); err != nil || none != (models.DashboardStats{}) {
+		t.Errorf("Statistika bez jedinica mora biti prazna: %+v (%v)", none, err)
+	}
+
+	// Entries without a user do not pin test users in the database
+	kind := unique("TEST")
+	for _, description := range []string{"prvi", "drugi"} {
+		entry := &models.ActivityLog{TipAktivnosti: kind, Opis: ptr(description), CiljaniEntitet: ptr("Test")}
+		if err := f.Analytics.LogActivity(f.ctx, entry); err != nil || entry.LogID == 0 {
+			t.Fatalf("LogActivity greška: %v", err)
+		}
+	}
+
+	logs, err := f.Analytics.GetActivityLogs(f.ctx, 2)
+	if err != nil || len(logs) != 2 {
+		t.Fatalf("GetActivityLogs vratio %d zapisa (%v)", len(logs), err)
+	}
+	if logs[0].TipAktivnosti != kind || *logs[0].Opis != "drugi" || logs[0].ImeKorisnika != "System" {
+		t.Errorf("Najnoviji zapis mora biti prvi, bez korisnika kao System: %+v", logs[0])
+	}
+}
@@ -0,0 +1,238 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/schemacheck"
+)
+
+type RoleRepository struct {
+	db *sql.DB
+}
+
+func NewRoleRepository(db *sql.DB) *RoleRepository {
+	return &RoleRepository{db: db}
+}
+
+var roleGetAllQuery = schemacheck.Register("RoleRepository.GetAll",
+	`SELECT uloga_id, naziv_uloge, opis, sistemska, povucena FROM Uloge ORDER BY uloga_id`)
+
+var roleGetAllGrantsQuery = schemacheck.Register("RoleRepository.GetAll:grants", `
+	SELECT uloga_id, dozvola, projekat_id FROM DozvoleUloga
+	ORDER BY uloga_id, dozvola, COALESCE(projekat_id, 0)
+`)
+
+func (r *RoleRepository) GetAll(ctx context.Context) ([]models.Role, error) {
+	rows, err := r.db.QueryContext(ctx, roleGetAllQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := []models.Role{}
+	index := map[int]int{}
+	for rows.Next() {
+		role, err := scanRole(rows)
+		if err != nil {
+			return nil, err
+		}
+		index[role.UlogaID] = len(roles)
+		roles = append(roles, *role)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	grants, err := r.db.QueryContext(ctx, roleGetAllGrantsQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer grants.Close()
+
+	for grants.Next() {
+		var roleID int
+		var grant models.RoleGrant
+		if err := grants.Scan(&roleID, &grant.Dozvola, &grant.ProjekatID); err != nil {
+			return nil, err
+		}
+		if i, ok := index[roleID]; ok {
+			roles[i].Dozvole = append(roles[i].Dozvole, grant)
+		}
+	}
+	return roles, grants.Err()
+}
+
+var roleGetByIDQuery = schemacheck.Register("RoleRepository.GetByID",
+	`SELECT uloga_id, naziv_uloge, opis, sistemska, povucena FROM Uloge WHERE uloga_id = $1`)
+
+var roleGetGrantsQuery = schemacheck.Register("RoleRepository.GetByID:grants", `
+	SELECT dozvola, projekat_id FROM DozvoleUloga WHERE uloga_id = $1
+	ORDER BY dozvola, COALESCE(projekat_id, 0)
+`)
+
+func (r *RoleRepository) GetByID(ctx context.Context, id int) (*models.Role, error) {
+	role, err := scanRole(r.db.QueryRowContext(ctx, roleGetByIDQuery, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound("role", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, roleGetGrantsQuery, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var grant models.RoleGrant
+		if err := rows.Scan(&grant.Dozvola, &grant.ProjekatID); err != nil {
+			return nil, err
+		}
+		role.Dozvole = append(role.Dozvole, grant)
+	}
+	return role, rows.Err()
+}
+
+func scanRole(row rowScanner) (*models.Role, error) {
+	var role models.Role
+	var retired sql.NullTime
+
+	if err := row.Scan(&role.UlogaID, &role.NazivUloge, &role.Opis, &role.Sistemska, &retired); err != nil {
+		return nil, err
+	}
+
+	role.Povucena = nullTime(retired)
+	return &role, nil
+}
+
+var roleCreateQuery = schemacheck.Register("RoleRepository.Create",
+	`INSERT INTO Uloge (naziv_uloge, opis) VALUES ($1, $2) RETURNING uloga_id`)
+
+func (r *RoleRepository) Create(ctx context.Context, role *models.Role) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRowContext(ctx, roleCreateQuery, role.NazivUloge, role.Opis).Scan(&role.UlogaID); err != nil {
+		return err
+	}
+	if err := insertGrants(ctx, tx, role); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	role.Sistemska = nil
+	role.Povucena = nil
+	return nil
+}
+
+var roleUpdateQuery = schemacheck.Register("RoleRepository.Update", `
+	UPDATE Uloge SET naziv_uloge = $1, opis = $2, verzija = verzija + 1 WHERE uloga_id = $3
+`)
+
+var roleDeleteGrantsQuery = schemacheck.Register("RoleRepository.Update:grants",
+	`DELETE FROM DozvoleUloga WHERE uloga_id = $1`)
+
+func (r *RoleRepository) Update(ctx context.Context, role *models.Role) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, roleUpdateQuery, role.NazivUloge, role.Opis, role.UlogaID)
+	if err != nil {
+		return err
+	}
+	if err := expectAffected(result, "role", role.UlogaID); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, roleDeleteGrantsQuery, role.UlogaID); err != nil {
+		return err
+	}
+	if err := insertGrants(ctx, tx, role); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+var roleInsertGrantQuery = schemacheck.Register("RoleRepository.insertGrants",
+	`INSERT INTO DozvoleUloga (uloga_id, dozvola, projekat_id) VALUES ($1, $2, $3)`)
+
+// insertGrants stores the grants of role in the order GetByID returns them.
+func insertGrants(ctx context.Context, tx *sql.Tx, role *models.Role) error {
+	sortGrants(role.Dozvole)
+	for _, grant := range role.Dozvole {
+		if _, err := tx.ExecContext(ctx, roleInsertGrantQuery, role.UlogaID, grant.Dozvola, grant.ProjekatID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortGrants orders grants by permission, global grants first.
+func sortGrants(grants []models.RoleGrant) {
+	project := func(grant models.RoleGrant) int {
+		if grant.ProjekatID == nil {
+			return 0
+		}
+		return *grant.ProjekatID
+	}
+	sort.Slice(grants, func(i, j int) bool {
+		if grants[i].Dozvola != grants[j].Dozvola {
+			return grants[i].Dozvola < grants[j].Dozvola
+		}
+		return project(grants[i]) < project(grants[j])
+	})
+}
+
+var roleRetireQuery = schemacheck.Register("RoleRepository.Retire", `
+	UPDATE Uloge SET povucena = $1, verzija = verzija + 1
+	WHERE uloga_id = $2 AND povucena IS NULL
+	  AND NOT EXISTS (SELECT 1 FROM Korisnici WHERE uloga_id = $2)
+`)
+
+func (r *RoleRepository) Retire(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, roleRetireQuery, time.Now().UTC(), id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		if _, err := r.GetByID(ctx, id); err != nil {
+			return err
+		}
+		return fmt.Errorf("role %d is retired or still held: %w", id, ErrConflict)
+	}
+
+	return nil
+}
+
+var roleVersionQuery = schemacheck.Register("RoleRepository.Version",
+	`SELECT COALESCE(SUM(verzija), 0) FROM Uloge`)
+
+// Version sums the versions of all roles. Roles are never deleted, so the
+// sum grows with every change.
+func (r *RoleRepository) Version(ctx context.Context) (int64, error) {
+	var version int64
+	err := r.db.QueryRowContext(ctx, roleVersionQuery).Scan(&version)
+	return version, err
+}
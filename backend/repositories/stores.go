@@ -0,0 +1,315 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cane/research-institute-system/backend/models"
+)
+
+// ErrNotFound is returned (wrapped) by every store when the requested row
+// does not exist, whatever the backend.
+var ErrNotFound = errors.New("not found")
+
+// ErrConflict is returned (wrapped) when a change contradicts data that is
+// already stored, for example moving a project off the workflow its tasks use.
+var ErrConflict = errors.New("conflict")
+
+func notFound(entity string, id interface{}) error {
+	return fmt.Errorf("%s with ID %v %w", entity, id, ErrNotFound)
+}
+
+// UserStore persists users and reads the role catalogue.
+type UserStore interface {
+	GetByID(ctx context.Context, id int) (*models.User, error)
+	GetByUsername(ctx context.Context, username string) (*models.User, error)
+	GetAll(ctx context.Context) ([]models.User, error)
+	Create(ctx context.Context, user *models.User) error
+	Update(ctx context.Context, user *models.User) error
+	// UpdatePassword stores a new hash and whether the user must replace it
+	// at the next sign-in.
+	UpdatePassword(ctx context.Context, userID int, passwordHash string, mustChange bool) error
+	UpdateLastLogin(ctx context.Context, userID int) error
+	// RecordLoginFailure counts a failed sign-in made at the given time and
+	// returns the number of failures since the last reset.
+	RecordLoginFailure(ctx context.Context, userID int, at time.Time) (int, error)
+	// Lock keeps the account from signing in until the given time.
+	Lock(ctx context.Context, userID int, until time.Time) error
+	// ResetLoginFailures clears the failure count and any lock.
+	ResetLoginFailures(ctx context.Context, userID int) error
+	// RevokeSessions ends the user's sessions opened before the given time
+	// in every process; GetByID returns it as SesijeNevazeceOd.
+	RevokeSessions(ctx context.Context, userID int, before time.Time) error
+	// Offboard deactivates the user and, in one transaction, hands the
+	// user's projects, open tasks (progress below 100), folders and
+	// documents over to the targets of handover and revokes the user's
+	// access tokens. The report lists what moved; ZavrseneSesije is left 0.
+	Offboard(ctx context.Context, userID int, handover models.Handover) (*models.HandoverReport, error)
+	// References counts the rows that keep the user from being deleted, by
+	// table. Rows that are deleted with the user are not counted.
+	References(ctx context.Context, userID int) (map[string]int, error)
+	// DeleteUnreferenced deletes the user unless rows refer to it, checked
+	// in the same transaction. Otherwise it deletes nothing and returns
+	// References.
+	DeleteUnreferenced(ctx context.Context, id int) (map[string]int, error)
+	Delete(ctx context.Context, id int) error
+	// GetRoles returns the roles that can be assigned, without their grants.
+	// Retired roles are left out.
+	GetRoles(ctx context.Context) ([]models.Role, error)
+}
+
+// RoleStore keeps the roles and the permissions they grant. Every change
+// raises Version, so processes that cache the permissions notice it.
+type RoleStore interface {
+	// GetAll returns every role with its grants, retired roles included.
+	GetAll(ctx context.Context) ([]models.Role, error)
+	GetByID(ctx context.Context, id int) (*models.Role, error)
+	// Create stores the role with its grants. Role names are unique.
+	Create(ctx context.Context, role *models.Role) error
+	// Update renames the role and replaces its description and grants.
+	Update(ctx context.Context, role *models.Role) error
+	// Retire keeps the role from being assigned. It fails with ErrConflict
+	// if the role is already retired or a user still holds it.
+	Retire(ctx context.Context, id int) error
+	// Version changes whenever a role or its grants change.
+	Version(ctx context.Context) (int64, error)
+}
+
+// ActivationStore keeps the single-use activation codes of accounts.
+type ActivationStore interface {
+	// Create stores a new code and discards the user's earlier unused codes,
+	// so only the newest code is valid.
+	Create(ctx context.Context, activation *models.Activation) error
+	// GetPending returns the newest unused code of the user, expired or not.
+	GetPending(ctx context.Context, userID int) (*models.Activation, error)
+	// Consume marks the code used. It fails with ErrConflict unless the code
+	// exists and is still unused, so a code is accepted at most once.
+	Consume(ctx context.Context, id int) error
+	// Revoke discards every unused code of the user.
+	Revoke(ctx context.Context, userID int) error
+}
+
+// TwoFactorStore keeps the TOTP secrets and recovery codes of accounts.
+type TwoFactorStore interface {
+	// Get returns the factor of the user, confirmed or not.
+	Get(ctx context.Context, userID int) (*models.TwoFactor, error)
+	// Begin stores a new unconfirmed secret, replacing an earlier unconfirmed
+	// one. It fails with ErrConflict if the user has a confirmed factor.
+	Begin(ctx context.Context, factor *models.TwoFactor) error
+	// Confirm puts the factor in effect, records step as its last used time
+	// step and replaces the user's recovery codes with the given hashes. It
+	// fails with ErrConflict unless an unconfirmed factor exists.
+	Confirm(ctx context.Context, userID int, step int64, recoveryHashes []string) error
+	// UseStep records an accepted time step. It fails with ErrConflict
+	// unless step is later than the last one, so each code works once.
+	UseStep(ctx context.Context, userID int, step int64) error
+	// UseRecoveryCode marks the unused recovery code with the hash used. It
+	// fails with ErrConflict if the user has no such unused code.
+	UseRecoveryCode(ctx context.Context, userID int, hash string) error
+	// RecoveryCodesLeft counts the user's unused recovery codes.
+	RecoveryCodesLeft(ctx context.Context, userID int) (int, error)
+	// Delete removes the factor and the recovery codes of the user.
+	Delete(ctx context.Context, userID int) error
+}
+
+// AccessTokenStore keeps the personal access tokens of accounts.
+type AccessTokenStore interface {
+	Create(ctx context.Context, token *models.AccessToken) error
+	GetByID(ctx context.Context, id int) (*models.AccessToken, error)
+	// GetByHash returns the token with the hash, expired and revoked ones
+	// included.
+	GetByHash(ctx context.Context, hash string) (*models.AccessToken, error)
+	// ListByUser returns every token of the user, newest first.
+	ListByUser(ctx context.Context, userID int) ([]models.AccessToken, error)
+	// Revoke ends the token. It fails with ErrConflict if the token is
+	// already revoked.
+	Revoke(ctx context.Context, id int) error
+	// MarkUsed records when the token was last used.
+	MarkUsed(ctx context.Context, id int, at time.Time) error
+}
+
+// ProfileStore keeps what users change about their own accounts: their
+// preferences and the change of email address they are confirming.
+type ProfileStore interface {
+	// GetPreferences fails with ErrNotFound while the user has saved none.
+	GetPreferences(ctx context.Context, userID int) (*models.Preferences, error)
+	// SavePreferences replaces the preferences of the user.
+	SavePreferences(ctx context.Context, prefs *models.Preferences) error
+	// BeginEmailChange stores a pending change, replacing an earlier one.
+	BeginEmailChange(ctx context.Context, change *models.EmailChange) error
+	// GetEmailChange returns the pending change of the user, expired or not.
+	GetEmailChange(ctx context.Context, userID int) (*models.EmailChange, error)
+	// FailEmailChange counts a wrong code and returns the count so far.
+	FailEmailChange(ctx context.Context, userID int) (int, error)
+	// CompleteEmailChange gives the user the new address of the pending
+	// change and removes the change, in one transaction.
+	CompleteEmailChange(ctx context.Context, userID int) error
+	// CancelEmailChange removes the pending change, if there is one.
+	CancelEmailChange(ctx context.Context, userID int) error
+}
+
+// PasswordHistoryStore keeps the hashes of the passwords each user has set.
+type PasswordHistoryStore interface {
+	// Add stores a hash and keeps only the newest keep hashes of the user.
+	Add(ctx context.Context, userID int, hash string, keep int) error
+	// Recent returns up to limit of the user's newest hashes, newest first.
+	Recent(ctx context.Context, userID, limit int) ([]string, error)
+}
+
+// ProjectStore persists projects, their teams and workflow links.
+type ProjectStore interface {
+	GetAll(ctx context.Context) ([]models.Project, error)
+	GetByID(ctx context.Context, id int) (*models.Project, error)
+	GetByUserID(ctx context.Context, userID int) ([]models.Project, error)
+	Create(ctx context.Context, project *models.Project, memberIDs []int) error
+	Update(ctx context.Context, project *models.Project) error
+	Delete(ctx context.Context, id int) error
+	GetMembers(ctx context.Context, projectID int) ([]models.User, error)
+	AddMember(ctx context.Context, projectID, userID int) error
+	RemoveMember(ctx context.Context, projectID, userID int) error
+	IsMember(ctx context.Context, projectID, userID int) (bool, error)
+	SetWorkflow(ctx context.Context, projectID int, workflowID *int) error
+}
+
+// TaskStore persists tasks and their comments.
+type TaskStore interface {
+	GetByProject(ctx context.Context, projectID int) ([]models.Task, error)
+	GetByUser(ctx context.Context, userID int) ([]models.Task, error)
+	GetByID(ctx context.Context, id int) (*models.Task, error)
+	// Create places the task in the first phase of its project's workflow
+	// when FazaID is zero.
+	Create(ctx context.Context, task *models.Task) error
+	// Update changes only the fields set in req.
+	Update(ctx context.Context, id int, req models.UpdateTaskRequest) error
+	Delete(ctx context.Context, id int) error
+	GetComments(ctx context.Context, taskID int) ([]models.TaskComment, error)
+	AddComment(ctx context.Context, comment *models.TaskComment) error
+}
+
+// DocumentStore persists documents, versions, tags, metadata and folders.
+type DocumentStore interface {
+	GetAll(ctx context.Context) ([]models.Document, error)
+	GetByProject(ctx context.Context, projectID int) ([]models.Document, error)
+	GetByID(ctx context.Context, id int) (*models.Document, error)
+	// Create stores the document with its first version and tags atomically.
+	Create(ctx context.Context, doc *models.Document, version *models.DocumentVersion, tags []string) error
+	Update(ctx context.Context, doc *models.Document) error
+	// Delete removes the document and returns the paths of its version files.
+	Delete(ctx context.Context, id int) ([]string, error)
+	GetVersions(ctx context.Context, documentID int) ([]models.DocumentVersion, error)
+	GetTags(ctx context.Context, documentID int) ([]models.Tag, error)
+	AddTag(ctx context.Context, documentID int, tagName string) error
+	RemoveTag(ctx context.Context, documentID, tagID int) error
+	GetMetadata(ctx context.Context, documentID int) ([]models.Metadata, error)
+	ReplaceMetadata(ctx context.Context, documentID int, metadata []models.Metadata) error
+	GetFolders(ctx context.Context, ownerID int) ([]models.Folder, error)
+	GetFolder(ctx context.Context, id int) (*models.Folder, error)
+	CreateFolder(ctx context.Context, folder *models.Folder) error
+	// DeleteFolder removes the folder and its subfolders; documents in them
+	// are kept without a folder.
+	DeleteFolder(ctx context.Context, id int) error
+}
+
+// WorkflowStore persists workflows and their phases.
+type WorkflowStore interface {
+	GetAll(ctx context.Context) ([]models.Workflow, error)
+	GetPhases(ctx context.Context, workflowID int) ([]models.Phase, error)
+	Create(ctx context.Context, workflow *models.Workflow) error
+	CreatePhase(ctx context.Context, phase *models.Phase) error
+	// Delete removes a workflow with its phases. It fails while a project,
+	// task or document still uses it.
+	Delete(ctx context.Context, id int) error
+}
+
+// UnitStore persists organizational units and which unit users and
+// projects belong to.
+type UnitStore interface {
+	// GetAll returns every unit with its member and project counts, by name.
+	GetAll(ctx context.Context) ([]models.Unit, error)
+	GetByID(ctx context.Context, id int) (*models.Unit, error)
+	Create(ctx context.Context, unit *models.Unit) error
+	Update(ctx context.Context, unit *models.Unit) error
+	// Delete removes a unit without subunits; its members and projects are
+	// kept without a unit.
+	Delete(ctx context.Context, id int) error
+	GetMembers(ctx context.Context, unitID int) ([]models.User, error)
+	// SetUserUnit moves the user to the unit, or out of any unit when
+	// unitID is nil. SetProjectUnit does the same for a project.
+	SetUserUnit(ctx context.Context, userID int, unitID *int) error
+	SetProjectUnit(ctx context.Context, projectID int, unitID *int) error
+}
+
+// GuestStore keeps what is shared with guest accounts, users whose GostDo
+// is set, and ends the accounts when they expire.
+type GuestStore interface {
+	// GetShares returns what is shared with the guest, oldest first, with
+	// the name of each shared project, folder or document.
+	GetShares(ctx context.Context, userID int) ([]models.GuestShare, error)
+	// GetAllShares returns the shares of every active guest, by guest.
+	GetAllShares(ctx context.Context) ([]models.GuestShare, error)
+	GetShare(ctx context.Context, id int) (*models.GuestShare, error)
+	// Share stores a share of exactly one project, folder or document. It
+	// fails with ErrConflict when the guest already has the same share.
+	Share(ctx context.Context, share *models.GuestShare) error
+	Unshare(ctx context.Context, id int) error
+	// SetExpiry moves the end of a guest account. Users that are not
+	// guests are not found.
+	SetExpiry(ctx context.Context, userID int, until time.Time) error
+	// ExpireGuests deactivates the active guests whose accounts ended by at
+	// and removes their shares in one transaction. It returns their IDs.
+	ExpireGuests(ctx context.Context, at time.Time) ([]int, error)
+	// GetSharedDocuments returns the documents the guest may see: those of
+	// shared projects, those in shared folders and their subfolders, and
+	// those shared directly.
+	GetSharedDocuments(ctx context.Context, userID int) ([]models.Document, error)
+}
+
+// AnalyticsStore computes dashboard figures and keeps the activity log.
+type AnalyticsStore interface {
+	// GetDashboardStats counts what belongs to the given units, everything
+	// when unitIDs is nil. Documents and tasks count with their project.
+	GetDashboardStats(ctx context.Context, unitIDs []int) (models.DashboardStats, error)
+	GetActivityLogs(ctx context.Context, limit int) ([]models.ActivityLog, error)
+	LogActivity(ctx context.Context, entry *models.ActivityLog) error
+}
+
+// Stores bundles one backend's implementation of every store.
+type Stores struct {
+	Users       UserStore
+	Roles       RoleStore
+	Activations ActivationStore
+	Passwords   PasswordHistoryStore
+	TwoFactor   TwoFactorStore
+	Tokens      AccessTokenStore
+	Profiles    ProfileStore
+	Projects    ProjectStore
+	Tasks       TaskStore
+	Documents   DocumentStore
+	Workflows   WorkflowStore
+	Units       UnitStore
+	Guests      GuestStore
+	Analytics   AnalyticsStore
+}
+
+// NewPostgresStores returns the PostgreSQL implementation of every store.
+func NewPostgresStores(db *sql.DB) Stores {
+	return Stores{
+		Users:       NewUserRepository(db),
+		Roles:       NewRoleRepository(db),
+		Activations: NewActivationRepository(db),
+		Passwords:   NewPasswordHistoryRepository(db),
+		TwoFactor:   NewTwoFactorRepository(db),
+		Tokens:      NewAccessTokenRepository(db),
+		Profiles:    NewProfileRepository(db),
+		Projects:    NewProjectRepository(db),
+		Tasks:       NewTaskRepository(db),
+		Documents:   NewDocumentRepository(db),
+		Workflows:   NewWorkflowRepository(db),
+		Units:       NewUnitRepository(db),
+		Guests:      NewGuestRepository(db),
+		Analytics:   NewAnalyticsRepository(db),
+	}
+}
@@ -0,0 +1,201 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/schemacheck"
+)
+
+type TaskRepository struct {
+	db *sql.DB
+}
+
+func NewTaskRepository(db *sql.DB) *TaskRepository {
+	return &TaskRepository{db: db}
+}
+
+// taskSelect joins the project, phase and assignee names shown on the board.
+const taskSelect = `
+	SELECT z.zadatak_id, z.projekat_id, z.faza_id, z.naziv_zadatka, z.opis,
+	       z.dodeljen_korisniku_id, z.rok, z.prioritet, z.progres, z.kreiran_datuma,
+	       p.naziv_projekta, f.naziv_faze,
+	       COALESCE(k.korisnicko_ime, '') as dodeljen_korisniku
+	FROM zadaci z
+	JOIN projekti p ON z.projekat_id = p.projekat_id
+	JOIN faze f ON z.faza_id = f.faza_id
+	LEFT JOIN korisnici k ON z.dodeljen_korisniku_id = k.korisnik_id
+`
+
+func scanTask(row rowScanner) (models.Task, error) {
+	var task models.Task
+	err := row.Scan(
+		&task.ZadatakID, &task.ProjekatID, &task.FazaID, &task.NazivZadatka,
+		&task.Opis, &task.DodjeljenKorisnikuID, &task.Rok, &task.Prioritet,
+		&task.Progres, &task.KreiranDatuma, &task.NazivProjekta,
+		&task.NazivFaze, &task.DodjeljenKorisniku,
+	)
+	return task, err
+}
+
+func (r *TaskRepository) queryTasks(ctx context.Context, query string, args ...interface{}) ([]models.Task, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := []models.Task{}
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+
+	return tasks, rows.Err()
+}
+
+var taskGetByProjectQuery = schemacheck.Register("TaskRepository.GetByProject", taskSelect+`
+	WHERE z.projekat_id = $1
+	ORDER BY z.kreiran_datuma DESC, z.zadatak_id DESC
+`)
+
+func (r *TaskRepository) GetByProject(ctx context.Context, projectID int) ([]models.Task, error) {
+	return r.queryTasks(ctx, taskGetByProjectQuery, projectID)
+}
+
+var taskGetByUserQuery = schemacheck.Register("TaskRepository.GetByUser", taskSelect+`
+	WHERE z.dodeljen_korisniku_id = $1
+	ORDER BY z.rok ASC NULLS LAST, z.prioritet DESC
+`)
+
+func (r *TaskRepository) GetByUser(ctx context.Context, userID int) ([]models.Task, error) {
+	return r.queryTasks(ctx, taskGetByUserQuery, userID)
+}
+
+var taskGetByIDQuery = schemacheck.Register("TaskRepository.GetByID", taskSelect+`
+	WHERE z.zadatak_id = $1
+`)
+
+func (r *TaskRepository) GetByID(ctx context.Context, id int) (*models.Task, error) {
+	task, err := scanTask(r.db.QueryRowContext(ctx, taskGetByIDQuery, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound("task", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+var (
+	taskFirstPhaseQuery = schemacheck.Register("TaskRepository.Create:phase", `
+		SELECT f.faza_id
+		FROM faze f
+		JOIN projekti p ON f.radni_tok_id = p.radni_tok_id
+		WHERE p.projekat_id = $1
+		ORDER BY f.redosled ASC
+		LIMIT 1
+	`)
+	taskCreateQuery = schemacheck.Register("TaskRepository.Create", `
+		INSERT INTO zadaci (projekat_id, faza_id, naziv_zadatka, opis,
+		                   dodeljen_korisniku_id, rok, prioritet, progres)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING zadatak_id, kreiran_datuma
+	`)
+)
+
+func (r *TaskRepository) Create(ctx context.Context, task *models.Task) error {
+	if task.FazaID == 0 {
+		// Get first phase of project workflow
+		err := r.db.QueryRowContext(ctx, taskFirstPhaseQuery, task.ProjekatID).Scan(&task.FazaID)
+		if err != nil {
+			// If no workflow, use default phase 1
+			task.FazaID = 1
+		}
+	}
+
+	return r.db.QueryRowContext(ctx, taskCreateQuery, task.ProjekatID, task.FazaID, task.NazivZadatka,
+		task.Opis, task.DodjeljenKorisnikuID, task.Rok, task.Prioritet, task.Progres).
+		Scan(&task.ZadatakID, &task.KreiranDatuma)
+}
+
+var taskUpdateQuery = schemacheck.Register("TaskRepository.Update", `
+	UPDATE zadaci
+	SET naziv_zadatka = COALESCE($1, naziv_zadatka),
+	    opis = COALESCE($2, opis),
+	    dodeljen_korisniku_id = COALESCE($3, dodeljen_korisniku_id),
+	    rok = COALESCE($4, rok),
+	    prioritet = COALESCE($5, prioritet),
+	    progres = COALESCE($6, progres),
+	    faza_id = COALESCE($7, faza_id)
+	WHERE zadatak_id = $8
+`)
+
+func (r *TaskRepository) Update(ctx context.Context, id int, req models.UpdateTaskRequest) error {
+	result, err := r.db.ExecContext(ctx, taskUpdateQuery, req.NazivZadatka, req.Opis,
+		req.DodjeljenKorisnikuID, req.Rok, req.Prioritet, req.Progres, req.FazaID, id)
+	if err != nil {
+		return err
+	}
+
+	return expectAffected(result, "task", id)
+}
+
+var taskDeleteQuery = schemacheck.Register("TaskRepository.Delete", `DELETE FROM zadaci WHERE zadatak_id = $1`)
+
+func (r *TaskRepository) Delete(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, taskDeleteQuery, id)
+	if err != nil {
+		return err
+	}
+
+	return expectAffected(result, "task", id)
+}
+
+var taskGetCommentsQuery = schemacheck.Register("TaskRepository.GetComments", `
+	SELECT kz.komentar_id, kz.zadatak_id, kz.korisnik_id, kz.tekst_komentara,
+	       kz.datuma_kreiranja, k.korisnicko_ime as ime_korisnika
+	FROM KomentariZadataka kz
+	JOIN korisnici k ON kz.korisnik_id = k.korisnik_id
+	WHERE kz.zadatak_id = $1
+	ORDER BY kz.datuma_kreiranja DESC, kz.komentar_id DESC
+`)
+
+func (r *TaskRepository) GetComments(ctx context.Context, taskID int) ([]models.TaskComment, error) {
+	rows, err := r.db.QueryContext(ctx, taskGetCommentsQuery, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := []models.TaskComment{}
+	for rows.Next() {
+		var comment models.TaskComment
+		err := rows.Scan(
+			&comment.KomentarID, &comment.ZadatakID, &comment.KorisnikID,
+			&comment.TekstKomentara, &comment.DatumaKreiranja, &comment.ImeKorisnika,
+		)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+
+	return comments, rows.Err()
+}
+
+var taskAddCommentQuery = schemacheck.Register("TaskRepository.AddComment", `
+	INSERT INTO KomentariZadataka (zadatak_id, korisnik_id, tekst_komentara)
+	VALUES ($1, $2, $3)
+	RETURNING komentar_id, datuma_kreiranja
+`)
+
+func (r *TaskRepository) AddComment(ctx context.Context, comment *models.TaskComment) error {
+	return r.db.QueryRowContext(ctx, taskAddCommentQuery, comment.ZadatakID, comment.KorisnikID,
+		comment.TekstKomentara).Scan(&comment.KomentarID, &comment.DatumaKreiranja)
+}
@@ -0,0 +1,205 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/schemacheck"
+)
+
+type TwoFactorRepository struct {
+	db *sql.DB
+}
+
+func NewTwoFactorRepository(db *sql.DB) *TwoFactorRepository {
+	return &TwoFactorRepository{db: db}
+}
+
+var twoFactorGetQuery = schemacheck.Register("TwoFactorRepository.Get", `
+	SELECT korisnik_id, tajna, potvrdjen, poslednji_korak, kreiran_datuma
+	FROM DvaFaktora
+	WHERE korisnik_id = $1
+`)
+
+func (r *TwoFactorRepository) Get(ctx context.Context, userID int) (*models.TwoFactor, error) {
+	var factor models.TwoFactor
+	var confirmed sql.NullTime
+
+	err := r.db.QueryRowContext(ctx, twoFactorGetQuery, userID).Scan(
+		&factor.KorisnikID, &factor.Tajna, &confirmed, &factor.PoslednjiKorak, &factor.KreiranDatuma,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound("second factor of user", userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	factor.Potvrdjen = nullTime(confirmed)
+	return &factor, nil
+}
+
+var twoFactorDiscardQuery = schemacheck.Register("TwoFactorRepository.Discard", `
+	DELETE FROM DvaFaktora WHERE korisnik_id = $1 AND potvrdjen IS NULL
+`)
+
+var twoFactorBeginQuery = schemacheck.Register("TwoFactorRepository.Begin", `
+	INSERT INTO DvaFaktora (korisnik_id, tajna) VALUES ($1, $2)
+	ON CONFLICT (korisnik_id) DO NOTHING
+	RETURNING kreiran_datuma
+`)
+
+// Begin discards an unconfirmed factor first, so the insert only conflicts
+// with a confirmed one and then returns no row.
+func (r *TwoFactorRepository) Begin(ctx context.Context, factor *models.TwoFactor) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, twoFactorDiscardQuery, factor.KorisnikID); err != nil {
+		return err
+	}
+
+	err = tx.QueryRowContext(ctx, twoFactorBeginQuery, factor.KorisnikID, factor.Tajna).Scan(&factor.KreiranDatuma)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("user %d already has a second factor: %w", factor.KorisnikID, ErrConflict)
+	}
+	if err != nil {
+		return err
+	}
+
+	factor.Potvrdjen = nil
+	factor.PoslednjiKorak = 0
+	return tx.Commit()
+}
+
+var twoFactorConfirmQuery = schemacheck.Register("TwoFactorRepository.Confirm", `
+	UPDATE DvaFaktora SET potvrdjen = $1, poslednji_korak = $2
+	WHERE korisnik_id = $3 AND potvrdjen IS NULL
+`)
+
+var recoveryCodeDeleteQuery = schemacheck.Register("TwoFactorRepository.DeleteRecoveryCodes", `
+	DELETE FROM RezervniKodovi WHERE korisnik_id = $1
+`)
+
+var recoveryCodeInsertQuery = schemacheck.Register("TwoFactorRepository.InsertRecoveryCode", `
+	INSERT INTO RezervniKodovi (korisnik_id, hash_koda) VALUES ($1, $2)
+`)
+
+func (r *TwoFactorRepository) Confirm(ctx context.Context, userID int, step int64, recoveryHashes []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, twoFactorConfirmQuery, time.Now().UTC(), step, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("user %d has no second factor to confirm: %w", userID, ErrConflict)
+	}
+
+	if _, err := tx.ExecContext(ctx, recoveryCodeDeleteQuery, userID); err != nil {
+		return err
+	}
+	for _, hash := range recoveryHashes {
+		if _, err := tx.ExecContext(ctx, recoveryCodeInsertQuery, userID, hash); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+var twoFactorUseStepQuery = schemacheck.Register("TwoFactorRepository.UseStep", `
+	UPDATE DvaFaktora SET poslednji_korak = $1
+	WHERE korisnik_id = $2 AND potvrdjen IS NOT NULL AND poslednji_korak < $1
+`)
+
+// UseStep relies on the poslednji_korak < $1 condition, so of two concurrent
+// sign-ins with the same code only one changes the row.
+func (r *TwoFactorRepository) UseStep(ctx context.Context, userID int, step int64) error {
+	result, err := r.db.ExecContext(ctx, twoFactorUseStepQuery, step, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("time step %d of user %d is already used: %w", step, userID, ErrConflict)
+	}
+
+	return nil
+}
+
+var recoveryCodeUseQuery = schemacheck.Register("TwoFactorRepository.UseRecoveryCode", `
+	UPDATE RezervniKodovi SET iskoriscen = $1
+	WHERE kod_id = (
+		SELECT kod_id FROM RezervniKodovi
+		WHERE korisnik_id = $2 AND hash_koda = $3 AND iskoriscen IS NULL
+		LIMIT 1
+	) AND iskoriscen IS NULL
+`)
+
+func (r *TwoFactorRepository) UseRecoveryCode(ctx context.Context, userID int, hash string) error {
+	result, err := r.db.ExecContext(ctx, recoveryCodeUseQuery, time.Now().UTC(), userID, hash)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("user %d has no such unused recovery code: %w", userID, ErrConflict)
+	}
+
+	return nil
+}
+
+var recoveryCodesLeftQuery = schemacheck.Register("TwoFactorRepository.RecoveryCodesLeft", `
+	SELECT COUNT(*) FROM RezervniKodovi WHERE korisnik_id = $1 AND iskoriscen IS NULL
+`)
+
+func (r *TwoFactorRepository) RecoveryCodesLeft(ctx context.Context, userID int) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, recoveryCodesLeftQuery, userID).Scan(&count)
+	return count, err
+}
+
+var twoFactorDeleteQuery = schemacheck.Register("TwoFactorRepository.Delete", `
+	DELETE FROM DvaFaktora WHERE korisnik_id = $1
+`)
+
+func (r *TwoFactorRepository) Delete(ctx context.Context, userID int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, recoveryCodeDeleteQuery, userID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, twoFactorDeleteQuery, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,184 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/schemacheck"
+)
+
+type UnitRepository struct {
+	db *sql.DB
+}
+
+func NewUnitRepository(db *sql.DB) *UnitRepository {
+	return &UnitRepository{db: db}
+}
+
+const unitSelect = `
+	SELECT j.jedinica_id, j.naziv, j.tip, j.nadredjena_jedinica_id, j.rukovodilac_id, j.kreiran_datuma,
+	       COALESCE(k.korisnicko_ime, '') as rukovodilac_ime,
+	       (SELECT COUNT(*) FROM Korisnici c WHERE c.jedinica_id = j.jedinica_id) as broj_clanova,
+	       (SELECT COUNT(*) FROM Projekti p WHERE p.jedinica_id = j.jedinica_id) as broj_projekata
+	FROM OrganizacioneJedinice j
+	LEFT JOIN Korisnici k ON j.rukovodilac_id = k.korisnik_id`
+
+func scanUnit(row rowScanner) (*models.Unit, error) {
+	var unit models.Unit
+	err := row.Scan(
+		&unit.JedinicaID, &unit.Naziv, &unit.Tip, &unit.NadredjenaJedinicaID, &unit.RukovodilacID,
+		&unit.KreiranDatuma, &unit.RukovodilacIme, &unit.BrojClanova, &unit.BrojProjekata,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &unit, nil
+}
+
+var unitGetAllQuery = schemacheck.Register("UnitRepository.GetAll", unitSelect+`
+	ORDER BY j.naziv
+`)
+
+func (r *UnitRepository) GetAll(ctx context.Context) ([]models.Unit, error) {
+	rows, err := r.db.QueryContext(ctx, unitGetAllQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	units := []models.Unit{}
+	for rows.Next() {
+		unit, err := scanUnit(rows)
+		if err != nil {
+			return nil, err
+		}
+		units = append(units, *unit)
+	}
+	return units, rows.Err()
+}
+
+var unitGetByIDQuery = schemacheck.Register("UnitRepository.GetByID", unitSelect+`
+	WHERE j.jedinica_id = $1
+`)
+
+func (r *UnitRepository) GetByID(ctx context.Context, id int) (*models.Unit, error) {
+	unit, err := scanUnit(r.db.QueryRowContext(ctx, unitGetByIDQuery, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound("unit", id)
+	}
+	return unit, err
+}
+
+var unitCreateQuery = schemacheck.Register("UnitRepository.Create", `
+	INSERT INTO OrganizacioneJedinice (naziv, tip, nadredjena_jedinica_id, rukovodilac_id)
+	VALUES ($1, $2, $3, $4)
+	RETURNING jedinica_id, kreiran_datuma
+`)
+
+func (r *UnitRepository) Create(ctx context.Context, unit *models.Unit) error {
+	return r.db.QueryRowContext(ctx, unitCreateQuery, unit.Naziv, unit.Tip, unit.NadredjenaJedinicaID,
+		unit.RukovodilacID).Scan(&unit.JedinicaID, &unit.KreiranDatuma)
+}
+
+var unitUpdateQuery = schemacheck.Register("UnitRepository.Update", `
+	UPDATE OrganizacioneJedinice
+	SET naziv = $1, tip = $2, nadredjena_jedinica_id = $3, rukovodilac_id = $4
+	WHERE jedinica_id = $5
+`)
+
+func (r *UnitRepository) Update(ctx context.Context, unit *models.Unit) error {
+	result, err := r.db.ExecContext(ctx, unitUpdateQuery, unit.Naziv, unit.Tip, unit.NadredjenaJedinicaID,
+		unit.RukovodilacID, unit.JedinicaID)
+	if err != nil {
+		return err
+	}
+
+	return expectAffected(result, "unit", unit.JedinicaID)
+}
+
+var unitDeleteQuery = schemacheck.Register("UnitRepository.Delete", `
+	DELETE FROM OrganizacioneJedinice
+	WHERE jedinica_id = $1
+	  AND NOT EXISTS (SELECT 1 FROM OrganizacioneJedinice WHERE nadredjena_jedinica_id = $1)
+`)
+
+func (r *UnitRepository) Delete(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, unitDeleteQuery, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		if _, err := r.GetByID(ctx, id); err != nil {
+			return err
+		}
+		return fmt.Errorf("unit %d has subunits: %w", id, ErrConflict)
+	}
+
+	return nil
+}
+
+var unitGetMembersQuery = schemacheck.Register("UnitRepository.GetMembers", `
+	SELECT k.korisnik_id, k.korisnicko_ime, k.email, k.ime, k.prezime,
+	       k.uloga_id, k.status, k.jedinica_id, u.naziv_uloge
+	FROM Korisnici k
+	JOIN Uloge u ON k.uloga_id = u.uloga_id
+	WHERE k.jedinica_id = $1
+	ORDER BY k.korisnicko_ime
+`)
+
+func (r *UnitRepository) GetMembers(ctx context.Context, unitID int) ([]models.User, error) {
+	rows, err := r.db.QueryContext(ctx, unitGetMembersQuery, unitID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []models.User{}
+	for rows.Next() {
+		var member models.User
+		err := rows.Scan(
+			&member.KorisnikID, &member.KorisnickoIme, &member.Email,
+			&member.Ime, &member.Prezime, &member.UlogaID, &member.Status,
+			&member.JedinicaID, &member.NazivUloge,
+		)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+
+	return members, rows.Err()
+}
+
+var (
+	unitSetUserQuery = schemacheck.Register("UnitRepository.SetUserUnit",
+		`UPDATE Korisnici SET jedinica_id = $1 WHERE korisnik_id = $2`)
+	unitSetProjectQuery = schemacheck.Register("UnitRepository.SetProjectUnit",
+		`UPDATE Projekti SET jedinica_id = $1 WHERE projekat_id = $2`)
+)
+
+func (r *UnitRepository) SetUserUnit(ctx context.Context, userID int, unitID *int) error {
+	result, err := r.db.ExecContext(ctx, unitSetUserQuery, unitID, userID)
+	if err != nil {
+		return err
+	}
+
+	return expectAffected(result, "user", userID)
+}
+
+func (r *UnitRepository) SetProjectUnit(ctx context.Context, projectID int, unitID *int) error {
+	result, err := r.db.ExecContext(ctx, unitSetProjectQuery, unitID, projectID)
+	if err != nil {
+		return err
+	}
+
+	return expectAffected(result, "project", projectID)
+}
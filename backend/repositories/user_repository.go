@@ -1,10 +1,13 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"time"
 
 	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/schemacheck"
 )
 
 type UserRepository struct {
@@ -15,26 +18,31 @@ func NewUserRepository(db *sql.DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
-func (r *UserRepository) GetByID(id int) (*models.User, error) {
-	query := `
-		SELECT k.korisnik_id, k.korisnicko_ime, k.email, k.hash_sifre, k.ime, k.prezime, 
-		       k.uloga_id, k.status, k.poslednja_prijava, k.kreiran_datuma,
-		       u.naziv_uloge
-		FROM Korisnici k
-		JOIN Uloge u ON k.uloga_id = u.uloga_id
-		WHERE k.korisnik_id = $1
-	`
-
+var userGetByIDQuery = schemacheck.Register("UserRepository.GetByID", `
+	SELECT k.korisnik_id, k.korisnicko_ime, k.email, k.hash_sifre, k.ime, k.prezime, 
+	       k.uloga_id, k.status, k.poslednja_prijava, k.kreiran_datuma,
+	       k.mora_promeniti_lozinku, k.neuspesne_prijave, k.poslednja_neuspesna_prijava, k.zakljucan_do,
+	       k.izvor_prijave, k.jedinica_id, k.gost_do, k.sesije_nevazece_od, u.naziv_uloge
+	FROM Korisnici k
+	JOIN Uloge u ON k.uloga_id = u.uloga_id
+	WHERE k.korisnik_id = $1
+`)
+
+func (r *UserRepository) GetByID(ctx context.Context, id int) (*models.User, error) {
 	var user models.User
 	var role models.Role
-	var lastLogin sql.NullTime
+	var lastLogin, lastFailure, lockedUntil, guestUntil, revokedBefore sql.NullTime
 
-	err := r.db.QueryRow(query, id).Scan(
+	err := r.db.QueryRowContext(ctx, userGetByIDQuery, id).Scan(
 		&user.KorisnikID, &user.KorisnickoIme, &user.Email, &user.HashSifre,
 		&user.Ime, &user.Prezime, &user.UlogaID, &user.Status,
-		&lastLogin, &user.KreiranDatuma, &role.NazivUloge,
+		&lastLogin, &user.KreiranDatuma, &user.MoraPromenitiLozinku,
+		&user.NeuspesnePrijave, &lastFailure, &lockedUntil, &user.IzvorPrijave, &user.JedinicaID, &guestUntil, &revokedBefore, &role.NazivUloge,
 	)
 
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound("user", id)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -42,6 +50,10 @@ func (r *UserRepository) GetByID(id int) (*models.User, error) {
 	if lastLogin.Valid {
 		user.PoslednajaPrijava = &lastLogin.Time
 	}
+	user.PoslednjaNeuspesnaPrijava = nullTime(lastFailure)
+	user.ZakljucanDo = nullTime(lockedUntil)
+	user.GostDo = nullTime(guestUntil)
+	user.SesijeNevazeceOd = nullTime(revokedBefore)
 
 	role.UlogaID = user.UlogaID
 	user.NazivUloge = role.NazivUloge
@@ -49,26 +61,31 @@ func (r *UserRepository) GetByID(id int) (*models.User, error) {
 	return &user, nil
 }
 
-func (r *UserRepository) GetByUsername(username string) (*models.User, error) {
-	query := `
-		SELECT k.korisnik_id, k.korisnicko_ime, k.email, k.hash_sifre, k.ime, k.prezime, 
-		       k.uloga_id, k.status, k.poslednja_prijava, k.kreiran_datuma,
-		       u.naziv_uloge
-		FROM Korisnici k
-		JOIN Uloge u ON k.uloga_id = u.uloga_id
-		WHERE k.korisnicko_ime = $1
-	`
-
+var userGetByUsernameQuery = schemacheck.Register("UserRepository.GetByUsername", `
+	SELECT k.korisnik_id, k.korisnicko_ime, k.email, k.hash_sifre, k.ime, k.prezime, 
+	       k.uloga_id, k.status, k.poslednja_prijava, k.kreiran_datuma,
+	       k.mora_promeniti_lozinku, k.neuspesne_prijave, k.poslednja_neuspesna_prijava, k.zakljucan_do,
+	       k.izvor_prijave, k.jedinica_id, k.gost_do, k.sesije_nevazece_od, u.naziv_uloge
+	FROM Korisnici k
+	JOIN Uloge u ON k.uloga_id = u.uloga_id
+	WHERE k.korisnicko_ime = $1
+`)
+
+func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*models.User, error) {
 	var user models.User
 	var role models.Role
-	var lastLogin sql.NullTime
+	var lastLogin, lastFailure, lockedUntil, guestUntil, revokedBefore sql.NullTime
 
-	err := r.db.QueryRow(query, username).Scan(
+	err := r.db.QueryRowContext(ctx, userGetByUsernameQuery, username).Scan(
 		&user.KorisnikID, &user.KorisnickoIme, &user.Email, &user.HashSifre,
 		&user.Ime, &user.Prezime, &user.UlogaID, &user.Status,
-		&lastLogin, &user.KreiranDatuma, &role.NazivUloge,
+		&lastLogin, &user.KreiranDatuma, &user.MoraPromenitiLozinku,
+		&user.NeuspesnePrijave, &lastFailure, &lockedUntil, &user.IzvorPrijave, &user.JedinicaID, &guestUntil, &revokedBefore, &role.NazivUloge,
 	)
 
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound("user", username)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -76,6 +93,10 @@ func (r *UserRepository) GetByUsername(username string) (*models.User, error) {
 	if lastLogin.Valid {
 		user.PoslednajaPrijava = &lastLogin.Time
 	}
+	user.PoslednjaNeuspesnaPrijava = nullTime(lastFailure)
+	user.ZakljucanDo = nullTime(lockedUntil)
+	user.GostDo = nullTime(guestUntil)
+	user.SesijeNevazeceOd = nullTime(revokedBefore)
 
 	role.UlogaID = user.UlogaID
 	user.NazivUloge = role.NazivUloge
@@ -83,55 +104,314 @@ func (r *UserRepository) GetByUsername(username string) (*models.User, error) {
 	return &user, nil
 }
 
-func (r *UserRepository) Create(user *models.User) error {
-	query := `
-		INSERT INTO Korisnici (korisnicko_ime, email, hash_sifre, ime, prezime, uloga_id, status)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		RETURNING korisnik_id, kreiran_datuma
-	`
+var userCreateQuery = schemacheck.Register("UserRepository.Create", `
+	INSERT INTO Korisnici (korisnicko_ime, email, hash_sifre, ime, prezime, uloga_id, status, mora_promeniti_lozinku, izvor_prijave, gost_do)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	RETURNING korisnik_id, kreiran_datuma
+`)
 
-	err := r.db.QueryRow(query, user.KorisnickoIme, user.Email, user.HashSifre,
-		user.Ime, user.Prezime, user.UlogaID, user.Status).Scan(&user.KorisnikID, &user.KreiranDatuma)
+// Create stores a user without an izvor_prijave as a local account.
+func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
+	if user.IzvorPrijave == "" {
+		user.IzvorPrijave = models.IzvorLokalni
+	}
+	var guestUntil *time.Time
+	if user.GostDo != nil {
+		until := user.GostDo.UTC()
+		guestUntil = &until
+	}
+
+	err := r.db.QueryRowContext(ctx, userCreateQuery, user.KorisnickoIme, user.Email, user.HashSifre,
+		user.Ime, user.Prezime, user.UlogaID, user.Status, user.MoraPromenitiLozinku, user.IzvorPrijave, guestUntil).Scan(&user.KorisnikID, &user.KreiranDatuma)
 
 	return err
 }
 
-func (r *UserRepository) Update(user *models.User) error {
-	query := `
-		UPDATE Korisnici 
-		SET korisnicko_ime = $1, email = $2, ime = $3, prezime = $4, uloga_id = $5, status = $6
-		WHERE korisnik_id = $7
-	`
+var userUpdateQuery = schemacheck.Register("UserRepository.Update", `
+	UPDATE Korisnici 
+	SET korisnicko_ime = $1, email = $2, ime = $3, prezime = $4, uloga_id = $5, status = $6
+	WHERE korisnik_id = $7
+`)
 
-	_, err := r.db.Exec(query, user.KorisnickoIme, user.Email, user.Ime,
+func (r *UserRepository) Update(ctx context.Context, user *models.User) error {
+	result, err := r.db.ExecContext(ctx, userUpdateQuery, user.KorisnickoIme, user.Email, user.Ime,
 		user.Prezime, user.UlogaID, user.Status, user.KorisnikID)
+	if err != nil {
+		return err
+	}
 
-	return err
+	return expectAffected(result, "user", user.KorisnikID)
 }
 
-func (r *UserRepository) UpdatePassword(userID int, passwordHash string) error {
-	query := `UPDATE Korisnici SET hash_sifre = $1 WHERE korisnik_id = $2`
-	_, err := r.db.Exec(query, passwordHash, userID)
-	return err
+var userUpdatePasswordQuery = schemacheck.Register("UserRepository.UpdatePassword", `UPDATE Korisnici SET hash_sifre = $1, mora_promeniti_lozinku = $2 WHERE korisnik_id = $3`)
+
+func (r *UserRepository) UpdatePassword(ctx context.Context, userID int, passwordHash string, mustChange bool) error {
+	result, err := r.db.ExecContext(ctx, userUpdatePasswordQuery, passwordHash, mustChange, userID)
+	if err != nil {
+		return err
+	}
+
+	return expectAffected(result, "user", userID)
 }
 
-func (r *UserRepository) UpdateLastLogin(userID int) error {
-	query := `UPDATE Korisnici SET poslednja_prijava = $1 WHERE korisnik_id = $2`
-	_, err := r.db.Exec(query, time.Now(), userID)
+var userUpdateLastLoginQuery = schemacheck.Register("UserRepository.UpdateLastLogin", `UPDATE Korisnici SET poslednja_prijava = $1 WHERE korisnik_id = $2`)
+
+func (r *UserRepository) UpdateLastLogin(ctx context.Context, userID int) error {
+	_, err := r.db.ExecContext(ctx, userUpdateLastLoginQuery, time.Now(), userID)
 	return err
 }
 
-func (r *UserRepository) GetAll() ([]models.User, error) {
-	query := `
-		SELECT k.korisnik_id, k.korisnicko_ime, k.email, k.ime, k.prezime, 
-		       k.uloga_id, k.status, k.poslednja_prijava, k.kreiran_datuma,
-		       u.naziv_uloge
-		FROM Korisnici k
-		JOIN Uloge u ON k.uloga_id = u.uloga_id
-		ORDER BY k.kreiran_datuma DESC
-	`
+var userRecordLoginFailureQuery = schemacheck.Register("UserRepository.RecordLoginFailure", `
+	UPDATE Korisnici SET neuspesne_prijave = neuspesne_prijave + 1, poslednja_neuspesna_prijava = $1
+	WHERE korisnik_id = $2
+	RETURNING neuspesne_prijave
+`)
+
+func (r *UserRepository) RecordLoginFailure(ctx context.Context, userID int, at time.Time) (int, error) {
+	var failures int
+	err := r.db.QueryRowContext(ctx, userRecordLoginFailureQuery, at.UTC(), userID).Scan(&failures)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, notFound("user", userID)
+	}
+	return failures, err
+}
+
+var userLockQuery = schemacheck.Register("UserRepository.Lock", `UPDATE Korisnici SET zakljucan_do = $1 WHERE korisnik_id = $2`)
+
+func (r *UserRepository) Lock(ctx context.Context, userID int, until time.Time) error {
+	result, err := r.db.ExecContext(ctx, userLockQuery, until.UTC(), userID)
+	if err != nil {
+		return err
+	}
+
+	return expectAffected(result, "user", userID)
+}
+
+var userRevokeSessionsQuery = schemacheck.Register("UserRepository.RevokeSessions", `UPDATE Korisnici SET sesije_nevazece_od = $1 WHERE korisnik_id = $2`)
+
+func (r *UserRepository) RevokeSessions(ctx context.Context, userID int, before time.Time) error {
+	result, err := r.db.ExecContext(ctx, userRevokeSessionsQuery, before.UTC(), userID)
+	if err != nil {
+		return err
+	}
+
+	return expectAffected(result, "user", userID)
+}
+
+var userResetLoginFailuresQuery = schemacheck.Register("UserRepository.ResetLoginFailures", `
+	UPDATE Korisnici SET neuspesne_prijave = 0, poslednja_neuspesna_prijava = NULL, zakljucan_do = NULL
+	WHERE korisnik_id = $1
+`)
+
+func (r *UserRepository) ResetLoginFailures(ctx context.Context, userID int) error {
+	result, err := r.db.ExecContext(ctx, userResetLoginFailuresQuery, userID)
+	if err != nil {
+		return err
+	}
+
+	return expectAffected(result, "user", userID)
+}
+
+// nullTime converts a nullable column. The lockout columns are written in
+// UTC, which is how the driver reads a TIMESTAMP without time zone.
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+var userDeleteQuery = schemacheck.Register("UserRepository.Delete", `DELETE FROM Korisnici WHERE korisnik_id = $1`)
+
+func (r *UserRepository) Delete(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, userDeleteQuery, id)
+	if err != nil {
+		return err
+	}
+
+	return expectAffected(result, "user", id)
+}
+
+var (
+	userOffboardQuery = schemacheck.Register("UserRepository.Offboard", `
+		UPDATE Korisnici SET status = 'neaktivan' WHERE korisnik_id = $1
+	`)
+	userOffboardProjectsQuery = schemacheck.Register("UserRepository.Offboard:projects", `
+		UPDATE Projekti SET rukovodilac_id = $1 WHERE rukovodilac_id = $2
+		RETURNING projekat_id
+	`)
+	userOffboardTasksQuery = schemacheck.Register("UserRepository.Offboard:tasks", `
+		UPDATE Zadaci SET dodeljen_korisniku_id = $1
+		WHERE dodeljen_korisniku_id = $2 AND COALESCE(progres, 0) < 100
+		RETURNING zadatak_id
+	`)
+	userOffboardFoldersQuery = schemacheck.Register("UserRepository.Offboard:folders", `
+		UPDATE Folderi SET vlasnik_id = $1 WHERE vlasnik_id = $2
+		RETURNING folder_id
+	`)
+	userOffboardDocumentsQuery = schemacheck.Register("UserRepository.Offboard:documents", `
+		UPDATE Dokumenti SET kreirao_korisnik_id = $1 WHERE kreirao_korisnik_id = $2
+		RETURNING dokument_id
+	`)
+	userOffboardTokensQuery = schemacheck.Register("UserRepository.Offboard:tokens", `
+		UPDATE PristupniTokeni SET opozvan = $1 WHERE korisnik_id = $2 AND opozvan IS NULL
+	`)
+)
+
+func (r *UserRepository) Offboard(ctx context.Context, userID int, handover models.Handover) (*models.HandoverReport, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, userOffboardQuery, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := expectAffected(result, "user", userID); err != nil {
+		return nil, err
+	}
+
+	report := &models.HandoverReport{KorisnikID: userID, Primopredaja: handover}
+	moves := []struct {
+		query  string
+		target *int
+		ids    *[]int
+	}{
+		{userOffboardProjectsQuery, handover.RukovodilacID, &report.Projekti},
+		{userOffboardTasksQuery, handover.IzvrsilacID, &report.Zadaci},
+		{userOffboardFoldersQuery, handover.VlasnikID, &report.Folderi},
+		{userOffboardDocumentsQuery, handover.VlasnikID, &report.Dokumenti},
+	}
+	for _, move := range moves {
+		*move.ids = []int{}
+		if move.target == nil {
+			continue
+		}
+		if *move.ids, err = returnedIDs(tx.QueryContext(ctx, move.query, *move.target, userID)); err != nil {
+			return nil, err
+		}
+	}
+
+	result, err = tx.ExecContext(ctx, userOffboardTokensQuery, time.Now().UTC(), userID)
+	if err != nil {
+		return nil, err
+	}
+	revoked, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	report.OpozvaniTokeni = int(revoked)
+
+	return report, tx.Commit()
+}
+
+// returnedIDs collects the IDs of an UPDATE ... RETURNING.
+func returnedIDs(rows *sql.Rows, err error) ([]int, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// userReferencesQuery counts the rows of every table whose foreign key to
+// Korisnici does not cascade.
+var userReferencesQuery = schemacheck.Register("UserRepository.References", `
+	SELECT 'Projekti', COUNT(*) FROM Projekti WHERE rukovodilac_id = $1
+	UNION ALL SELECT 'Zadaci', COUNT(*) FROM Zadaci WHERE dodeljen_korisniku_id = $1
+	UNION ALL SELECT 'KomentariZadataka', COUNT(*) FROM KomentariZadataka WHERE korisnik_id = $1
+	UNION ALL SELECT 'ZahteviPromeneFaze', COUNT(*) FROM ZahteviPromeneFaze WHERE podnosilac_zahteva_id = $1
+	UNION ALL SELECT 'Folderi', COUNT(*) FROM Folderi WHERE vlasnik_id = $1
+	UNION ALL SELECT 'Dokumenti', COUNT(*) FROM Dokumenti WHERE kreirao_korisnik_id = $1
+	UNION ALL SELECT 'VerzijeDokumenata', COUNT(*) FROM VerzijeDokumenata WHERE postavio_korisnik_id = $1
+	UNION ALL SELECT 'IstorijaFazaDokumenta', COUNT(*) FROM IstorijaFazaDokumenta WHERE korisnik_id = $1
+	UNION ALL SELECT 'LogAktivnosti', COUNT(*) FROM LogAktivnosti WHERE korisnik_id = $1 OR stvarni_korisnik_id = $1
+`)
+
+func (r *UserRepository) References(ctx context.Context, userID int) (map[string]int, error) {
+	return countReferences(ctx, r.db, userID)
+}
+
+var userLockRowQuery = schemacheck.Register("UserRepository.DeleteUnreferenced", `
+	SELECT korisnik_id FROM Korisnici WHERE korisnik_id = $1 FOR UPDATE
+`)
+
+// DeleteUnreferenced counts the references and deletes the user in one
+// transaction. The user's row stays locked in between, so no new row can
+// refer to it.
+func (r *UserRepository) DeleteUnreferenced(ctx context.Context, id int) (map[string]int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var locked int
+	if err := tx.QueryRowContext(ctx, userLockRowQuery, id).Scan(&locked); errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound("user", id)
+	} else if err != nil {
+		return nil, err
+	}
+
+	references, err := countReferences(ctx, tx, id)
+	if err != nil || len(references) > 0 {
+		return references, err
+	}
+
+	if _, err := tx.ExecContext(ctx, userDeleteQuery, id); err != nil {
+		return nil, err
+	}
+	return nil, tx.Commit()
+}
+
+type rowsQueryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func countReferences(ctx context.Context, db rowsQueryer, userID int) (map[string]int, error) {
+	rows, err := db.QueryContext(ctx, userReferencesQuery, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	references := map[string]int{}
+	for rows.Next() {
+		var table string
+		var count int
+		if err := rows.Scan(&table, &count); err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			references[table] = count
+		}
+	}
+	return references, rows.Err()
+}
 
-	rows, err := r.db.Query(query)
+var userGetAllQuery = schemacheck.Register("UserRepository.GetAll", `
+	SELECT k.korisnik_id, k.korisnicko_ime, k.email, k.ime, k.prezime, 
+	       k.uloga_id, k.status, k.poslednja_prijava, k.kreiran_datuma,
+	       k.mora_promeniti_lozinku, k.neuspesne_prijave, k.poslednja_neuspesna_prijava, k.zakljucan_do,
+	       k.izvor_prijave, k.jedinica_id, k.gost_do, u.naziv_uloge
+	FROM Korisnici k
+	JOIN Uloge u ON k.uloga_id = u.uloga_id
+	ORDER BY k.kreiran_datuma DESC, k.korisnik_id DESC
+`)
+
+func (r *UserRepository) GetAll(ctx context.Context) ([]models.User, error) {
+	rows, err := r.db.QueryContext(ctx, userGetAllQuery)
 	if err != nil {
 		return nil, err
 	}
@@ -142,12 +422,13 @@ func (r *UserRepository) GetAll() ([]models.User, error) {
 	for rows.Next() {
 		var user models.User
 		var role models.Role
-		var lastLogin sql.NullTime
+		var lastLogin, lastFailure, lockedUntil, guestUntil sql.NullTime
 
 		err := rows.Scan(
 			&user.KorisnikID, &user.KorisnickoIme, &user.Email, &user.Ime,
 			&user.Prezime, &user.UlogaID, &user.Status, &lastLogin,
-			&user.KreiranDatuma, &role.NazivUloge,
+			&user.KreiranDatuma, &user.MoraPromenitiLozinku,
+			&user.NeuspesnePrijave, &lastFailure, &lockedUntil, &user.IzvorPrijave, &user.JedinicaID, &guestUntil, &role.NazivUloge,
 		)
 
 		if err != nil {
@@ -157,6 +438,9 @@ func (r *UserRepository) GetAll() ([]models.User, error) {
 		if lastLogin.Valid {
 			user.PoslednajaPrijava = &lastLogin.Time
 		}
+		user.PoslednjaNeuspesnaPrijava = nullTime(lastFailure)
+		user.ZakljucanDo = nullTime(lockedUntil)
+		user.GostDo = nullTime(guestUntil)
 
 		role.UlogaID = user.UlogaID
 		user.NazivUloge = role.NazivUloge
@@ -167,10 +451,10 @@ func (r *UserRepository) GetAll() ([]models.User, error) {
 	return users, nil
 }
 
-func (r *UserRepository) GetRoles() ([]models.Role, error) {
-	query := `SELECT uloga_id, naziv_uloge FROM Uloge ORDER BY uloga_id`
+var userGetRolesQuery = schemacheck.Register("UserRepository.GetRoles", `SELECT uloga_id, naziv_uloge FROM Uloge WHERE povucena IS NULL ORDER BY uloga_id`)
 
-	rows, err := r.db.Query(query)
+func (r *UserRepository) GetRoles(ctx context.Context) ([]models.Role, error) {
+	rows, err := r.db.QueryContext(ctx, userGetRolesQuery)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,106 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/schemacheck"
+)
+
+type WorkflowRepository struct {
+	db *sql.DB
+}
+
+func NewWorkflowRepository(db *sql.DB) *WorkflowRepository {
+	return &WorkflowRepository{db: db}
+}
+
+var workflowGetAllQuery = schemacheck.Register("WorkflowRepository.GetAll", `
+	SELECT radni_tok_id, naziv, tip_toka, opis, da_li_je_sablon
+	FROM RadniTokovi
+	ORDER BY naziv
+`)
+
+func (r *WorkflowRepository) GetAll(ctx context.Context) ([]models.Workflow, error) {
+	rows, err := r.db.QueryContext(ctx, workflowGetAllQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	workflows := []models.Workflow{}
+	for rows.Next() {
+		var workflow models.Workflow
+		err := rows.Scan(
+			&workflow.RadniTokID, &workflow.Naziv, &workflow.TipToka,
+			&workflow.Opis, &workflow.DaLiJeSablon,
+		)
+		if err != nil {
+			return nil, err
+		}
+		workflows = append(workflows, workflow)
+	}
+
+	return workflows, rows.Err()
+}
+
+var workflowGetPhasesQuery = schemacheck.Register("WorkflowRepository.GetPhases", `
+	SELECT faza_id, radni_tok_id, naziv_faze, redosled
+	FROM faze
+	WHERE radni_tok_id = $1
+	ORDER BY redosled
+`)
+
+func (r *WorkflowRepository) GetPhases(ctx context.Context, workflowID int) ([]models.Phase, error) {
+	rows, err := r.db.QueryContext(ctx, workflowGetPhasesQuery, workflowID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	phases := []models.Phase{}
+	for rows.Next() {
+		var phase models.Phase
+		err := rows.Scan(&phase.FazaID, &phase.RadniTokID, &phase.NazivFaze, &phase.Redosled)
+		if err != nil {
+			return nil, err
+		}
+		phases = append(phases, phase)
+	}
+
+	return phases, rows.Err()
+}
+
+var workflowCreateQuery = schemacheck.Register("WorkflowRepository.Create", `
+	INSERT INTO RadniTokovi (naziv, tip_toka, opis, da_li_je_sablon)
+	VALUES ($1, $2, $3, $4)
+	RETURNING radni_tok_id
+`)
+
+func (r *WorkflowRepository) Create(ctx context.Context, workflow *models.Workflow) error {
+	return r.db.QueryRowContext(ctx, workflowCreateQuery, workflow.Naziv, workflow.TipToka,
+		workflow.Opis, workflow.DaLiJeSablon).Scan(&workflow.RadniTokID)
+}
+
+var workflowCreatePhaseQuery = schemacheck.Register("WorkflowRepository.CreatePhase", `
+	INSERT INTO faze (radni_tok_id, naziv_faze, redosled)
+	VALUES ($1, $2, $3)
+	RETURNING faza_id
+`)
+
+func (r *WorkflowRepository) CreatePhase(ctx context.Context, phase *models.Phase) error {
+	return r.db.QueryRowContext(ctx, workflowCreatePhaseQuery, phase.RadniTokID,
+		phase.NazivFaze, phase.Redosled).Scan(&phase.FazaID)
+}
+
+var workflowDeleteQuery = schemacheck.Register("WorkflowRepository.Delete", `DELETE FROM RadniTokovi WHERE radni_tok_id = $1`)
+
+func (r *WorkflowRepository) Delete(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, workflowDeleteQuery, id)
+	if err != nil {
+		return err
+	}
+
+	return expectAffected(result, "workflow", id)
+}
@@ -0,0 +1,145 @@
+// ============================================================================
+// schemacheck.go - Service SQL conformance checker
+// ============================================================================
+
+// Package schemacheck keeps a registry of the SQL statements used by services
+// and repositories and prepares each of them against a live database, so a
+// wrong table or column name is reported at startup instead of when a user
+// first reaches the broken screen.
+package schemacheck
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Query is one registered SQL statement.
+type Query struct {
+	Name string `json:"name"` // e.g. "TaskService.GetTaskComments"
+	SQL  string `json:"sql"`
+}
+
+// Problem kinds reported by Check.
+const (
+	KindTable  = "table"
+	KindColumn = "column"
+	KindSQL    = "sql"
+)
+
+// Problem is a registered query the database refused to prepare.
+type Problem struct {
+	Query   string `json:"query"`
+	Kind    string `json:"kind"`
+	Object  string `json:"object,omitempty"` // missing table or column
+	Message string `json:"message"`
+}
+
+// Report is the outcome of checking every registered query.
+type Report struct {
+	CheckedAt time.Time `json:"checked_at" ts_type:"string"`
+	Checked   int       `json:"checked"`
+	Problems  []Problem `json:"problems"`
+}
+
+// OK reports whether every query prepared cleanly.
+func (r Report) OK() bool {
+	return len(r.Problems) == 0
+}
+
+var (
+	mu       sync.Mutex
+	registry = map[string]string{}
+)
+
+// Register records query under name and returns it unchanged, so it can wrap
+// the package-level declaration of a statement. Registering the same name
+// twice is a programming error and panics.
+func Register(name, query string) string {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, exists := registry[name]; exists {
+		panic(fmt.Sprintf("schemacheck: query %q registered twice", name))
+	}
+	registry[name] = query
+
+	return query
+}
+
+// Queries returns the registered queries sorted by name.
+func Queries() []Query {
+	mu.Lock()
+	defer mu.Unlock()
+
+	queries := make([]Query, 0, len(registry))
+	for name, query := range registry {
+		queries = append(queries, Query{Name: name, SQL: query})
+	}
+	sort.Slice(queries, func(i, j int) bool { return queries[i].Name < queries[j].Name })
+
+	return queries
+}
+
+// Check prepares every registered query against db.
+func Check(ctx context.Context, db *sql.DB) (Report, error) {
+	return CheckQueries(ctx, db, Queries())
+}
+
+// CheckQueries prepares the given queries on one connection without running
+// them. The returned error is set only when the database itself could not be
+// used; rejected statements are listed in the report.
+func CheckQueries(ctx context.Context, db *sql.DB, queries []Query) (Report, error) {
+	report := Report{CheckedAt: time.Now(), Problems: []Problem{}}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return report, err
+	}
+	defer conn.Close()
+
+	for _, query := range queries {
+		stmt, err := conn.PrepareContext(ctx, query.SQL)
+		if err == nil {
+			stmt.Close()
+			report.Checked++
+			continue
+		}
+
+		var pqErr *pq.Error
+		if !errors.As(err, &pqErr) {
+			return report, err
+		}
+
+		report.Checked++
+		report.Problems = append(report.Problems, classify(query.Name, pqErr))
+	}
+
+	return report, nil
+}
+
+var missingObject = regexp.MustCompile(`^(?:relation|column) "?([^" ]+)"? does not exist`)
+
+func classify(name string, err *pq.Error) Problem {
+	problem := Problem{Query: name, Kind: KindSQL, Message: err.Message}
+
+	switch err.Code {
+	case "42P01": // undefined_table
+		problem.Kind = KindTable
+	case "42703": // undefined_column
+		problem.Kind = KindColumn
+	}
+
+	if match := missingObject.FindStringSubmatch(err.Message); match != nil {
+		problem.Object = match[1]
+	}
+
+	return problem
+}
@@ -0,0 +1,244 @@
+// ============================================================================
+// access_token.go - Personal access tokens for scripted access
+// ============================================================================
+
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/repositories"
+)
+
+// AccessTokenPrefix starts every personal access token, so Authenticate
+// tells tokens from session tokens and leaked tokens are easy to search for.
+const AccessTokenPrefix = "riis_"
+
+const (
+	// accessTokenShown is how much of a token is kept in clear, to tell
+	// tokens apart in lists.
+	accessTokenShown = len(AccessTokenPrefix) + 6
+	// tokenUseResolution is how often the last use of a token is written,
+	// so busy scripts do not write on every request.
+	tokenUseResolution = time.Minute
+)
+
+var ErrTokenExpired = errors.New("pristupni token je istekao")
+
+// AccessTokenRequest describes a new personal access token. The scopes are
+// permissions, and the user's role must grant each of them, everywhere or in
+// some projects. A scope the role grants only in some projects works only in
+// those projects.
+type AccessTokenRequest struct {
+	Naziv  string       `json:"naziv"`
+	Opsezi []Permission `json:"opsezi"`
+	// Istice is at most auth.access_token_max_ttl away; the token is valid
+	// that long when it is empty.
+	Istice *time.Time `json:"istice,omitempty" ts_type:"string"`
+}
+
+// NewAccessToken is a created token. Token is shown only this once; only its
+// hash is stored.
+type NewAccessToken struct {
+	Token  string             `json:"token"`
+	Podaci models.AccessToken `json:"podaci"`
+}
+
+// CreateAccessToken creates a personal access token of the caller. Tokens
+// act for their user with the permissions in their scopes that the user's
+// role still grants.
+func (s *AuthService) CreateAccessToken(ctx context.Context, req AccessTokenRequest) (*NewAccessToken, error) {
+	principal, ok := PrincipalFrom(ctx)
+	if !ok {
+		return nil, ErrNoSession
+	}
+	if principal.System {
+		return nil, invalidInput("pristupni token pripada korisniku, a ne sistemu")
+	}
+	user, err := s.manageTokens(ctx, principal.User.KorisnikID)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(req.Naziv)
+	if name == "" || utf8.RuneCountInString(name) > 100 {
+		return nil, invalidInput("naziv tokena je obavezan i ima najviše 100 znakova")
+	}
+	scopes, err := s.tokenScopes(user, req.Opsezi)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	expires := now.Add(s.cfg.AccessTokenMaxTTL)
+	if req.Istice != nil {
+		if !req.Istice.After(now) {
+			return nil, invalidInput("token mora isticati u budućnosti")
+		}
+		if req.Istice.After(expires) {
+			return nil, invalidInput(fmt.Sprintf("token može važiti najviše do %s", expires.Format("2006-01-02 15:04")))
+		}
+		expires = *req.Istice
+	}
+
+	raw := make([]byte, 20)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	token := AccessTokenPrefix + strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(raw))
+
+	stored := &models.AccessToken{
+		KorisnikID: user.KorisnikID,
+		Naziv:      name,
+		Prefiks:    token[:accessTokenShown],
+		HashTokena: hashAccessToken(token),
+		Opsezi:     scopes,
+		Istice:     expires,
+	}
+	if err := s.tokens.Create(ctx, stored); err != nil {
+		return nil, err
+	}
+
+	audit(ctx, s.activity, ActivityAccessTokenCreated, user.KorisnikID, fmt.Sprintf("Kreiran pristupni token %q (%s), važi do %s",
+		name, strings.Join(scopes, ", "), expires.Format("2006-01-02 15:04")))
+	slog.InfoContext(ctx, "access token created", "token_id", stored.TokenID, "scopes", scopes, "expires", expires)
+
+	return &NewAccessToken{Token: token, Podaci: *stored}, nil
+}
+
+// tokenScopes checks the requested scopes against the user's role and
+// returns them without duplicates. Grants limited to projects are accepted;
+// RequireInProject keeps them to those projects when the token is used.
+func (s *AuthService) tokenScopes(user *models.User, requested []Permission) ([]string, error) {
+	if len(requested) == 0 {
+		return nil, invalidInput("token mora imati bar jedan opseg")
+	}
+
+	known := make(map[Permission]bool, len(AllPermissions))
+	for _, perm := range AllPermissions {
+		known[perm] = true
+	}
+
+	scopes := []string{}
+	seen := map[Permission]bool{}
+	for _, perm := range requested {
+		if !known[perm] {
+			return nil, invalidInput(fmt.Sprintf("nepoznat opseg %q", perm))
+		}
+		if !s.authz.CanSomewhere(user, perm) {
+			return nil, invalidInput(fmt.Sprintf("vaša uloga nema dozvolu %s", perm))
+		}
+		if !seen[perm] {
+			seen[perm] = true
+			scopes = append(scopes, string(perm))
+		}
+	}
+	return scopes, nil
+}
+
+// ListAccessTokens returns the tokens of a user, newest first, revoked and
+// expired ones included.
+func (s *AuthService) ListAccessTokens(ctx context.Context, userID int) ([]models.AccessToken, error) {
+	if _, err := s.manageTokens(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.tokens.ListByUser(ctx, userID)
+}
+
+// RevokeAccessToken ends a token. Users revoke their own tokens;
+// administrators revoke anyone's.
+func (s *AuthService) RevokeAccessToken(ctx context.Context, tokenID int) error {
+	token, err := s.tokens.GetByID(ctx, tokenID)
+	if err != nil {
+		return err
+	}
+	if _, err := s.manageTokens(ctx, token.KorisnikID); err != nil {
+		return err
+	}
+
+	if err := s.tokens.Revoke(ctx, tokenID); err != nil {
+		if errors.Is(err, repositories.ErrConflict) {
+			return conflict("token je već opozvan")
+		}
+		return err
+	}
+
+	audit(ctx, s.activity, ActivityAccessTokenRevoked, token.KorisnikID, fmt.Sprintf("Opozvan pristupni token %q", token.Naziv))
+	slog.InfoContext(ctx, "access token revoked", "token_id", tokenID, "target_user_id", token.KorisnikID)
+	return nil
+}
+
+// manageTokens checks that the caller may manage the tokens of userID: its
+// own from a session, anyone's with PermUserManage. Access tokens manage no
+// tokens, so a leaked token cannot mint another, and neither do
+// impersonation sessions.
+func (s *AuthService) manageTokens(ctx context.Context, userID int) (*models.User, error) {
+	principal, ok := PrincipalFrom(ctx)
+	if !ok {
+		return nil, ErrNoSession
+	}
+	if principal.Token != nil {
+		return nil, fmt.Errorf("%w (pristupnim tokenom se ne upravlja tokenima)", ErrForbidden)
+	}
+	if err := refuseImpersonated(ctx); err != nil {
+		return nil, err
+	}
+	if principal.System || principal.User.KorisnikID != userID {
+		return s.authz.Require(ctx, PermUserManage)
+	}
+	return principal.User, nil
+}
+
+// authenticateToken resolves a personal access token. Revoked tokens and
+// tokens of inactive or guest accounts are refused like an unknown token;
+// guests sign in only with a session, which carries their shares.
+func (s *AuthService) authenticateToken(ctx context.Context, token string) (*Principal, error) {
+	stored, err := s.tokens.GetByHash(ctx, hashAccessToken(token))
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, ErrNoSession
+	}
+	if err != nil {
+		return nil, err
+	}
+	if stored.Opozvan != nil {
+		return nil, ErrNoSession
+	}
+
+	now := s.now()
+	if !now.Before(stored.Istice) {
+		return nil, ErrTokenExpired
+	}
+
+	user, err := s.userRepo.GetByID(ctx, stored.KorisnikID)
+	if err != nil || user.Status != "aktivan" || user.GostDo != nil {
+		slog.InfoContext(ctx, "access token refused, account unavailable", "token_id", stored.TokenID, "user_id", stored.KorisnikID)
+		return nil, ErrNoSession
+	}
+
+	if stored.PoslednjeKorisceno == nil || now.Sub(*stored.PoslednjeKorisceno) >= tokenUseResolution {
+		if err := s.tokens.MarkUsed(ctx, stored.TokenID, now); err != nil {
+			slog.WarnContext(ctx, "access token use not recorded", "token_id", stored.TokenID, "error", err)
+		}
+	}
+
+	user.HashSifre = ""
+	return &Principal{User: user, Token: stored}, nil
+}
+
+// hashAccessToken hashes a token for storage. Tokens are long and random, so
+// a fast hash is enough.
+func hashAccessToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,123 @@
+// ============================================================================
+// audit.go - Security events in the activity log
+// ============================================================================
+
+package services
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/repositories"
+)
+
+// Activity types of account security events.
+const (
+	ActivityUserCreated         = "KREIRAN_KORISNIK"
+	ActivityUserUpdated         = "IZMENJEN_KORISNIK"
+	ActivityActivationIssued    = "IZDAT_AKTIVACIONI_KOD"
+	ActivityActivationVerified  = "POTVRDJEN_AKTIVACIONI_KOD"
+	ActivityActivationFailed    = "NEUSPESNA_AKTIVACIJA"
+	ActivityActivationCompleted = "AKTIVIRAN_NALOG"
+	ActivityPasswordReset       = "RESETOVANA_LOZINKA"
+	ActivityLoginFailed         = "NEUSPESNA_PRIJAVA"
+	ActivityAccountLocked       = "ZAKLJUCAN_NALOG"
+	ActivityAccountUnlocked     = "OTKLJUCAN_NALOG"
+	ActivitySourceBlocked       = "BLOKIRANA_ADRESA"
+	ActivityTwoFactorEnabled    = "UKLJUCEN_DRUGI_FAKTOR"
+	ActivityTwoFactorFailed     = "NEUSPESAN_DRUGI_FAKTOR"
+	ActivityRecoveryCodeUsed    = "ISKORISCEN_REZERVNI_KOD"
+	ActivityTwoFactorReset      = "RESETOVAN_DRUGI_FAKTOR"
+	ActivityAccessTokenCreated  = "KREIRAN_PRISTUPNI_TOKEN"
+	ActivityAccessTokenRevoked  = "OPOZVAN_PRISTUPNI_TOKEN"
+	ActivityUserOffboarded      = "PREDAT_POSAO_KORISNIKA"
+	ActivityRoleAssigned        = "DODELJENA_ULOGA"
+)
+
+// Activity types of administrators acting as other users.
+const (
+	ActivityImpersonationStarted = "POCETAK_ZASTUPANJA"
+	ActivityImpersonationEnded   = "KRAJ_ZASTUPANJA"
+	ActivityImpersonatedCall     = "RADNJA_U_IME_KORISNIKA"
+)
+
+// Activity types of changes users make to their own accounts.
+const (
+	ActivityProfileUpdated       = "IZMENJEN_PROFIL"
+	ActivityEmailChangeRequested = "ZATRAZENA_PROMENA_EMAILA"
+	ActivityEmailChangeFailed    = "NEUSPESNA_PROMENA_EMAILA"
+	ActivityEmailChanged         = "PROMENJEN_EMAIL"
+	ActivityPasswordChanged      = "PROMENJENA_LOZINKA"
+	ActivityPasswordChangeFailed = "NEUSPESNA_PROMENA_LOZINKE"
+)
+
+// Activity types of changes to roles and their permissions.
+const (
+	ActivityRoleCreated = "KREIRANA_ULOGA"
+	ActivityRoleChanged = "IZMENJENA_ULOGA"
+	ActivityRoleRetired = "POVUCENA_ULOGA"
+)
+
+// Activity types of changes to organizational units and who and what
+// belongs to them.
+const (
+	ActivityUnitCreated        = "KREIRANA_JEDINICA"
+	ActivityUnitChanged        = "IZMENJENA_JEDINICA"
+	ActivityUnitDeleted        = "OBRISANA_JEDINICA"
+	ActivityUserUnitChanged    = "PROMENJENA_JEDINICA_KORISNIKA"
+	ActivityProjectUnitChanged = "PROMENJENA_JEDINICA_PROJEKTA"
+)
+
+// Activity types of guest accounts of external partners.
+const (
+	ActivityGuestExtended = "PRODUZEN_GOSTUJUCI_NALOG"
+	ActivityGuestShared   = "PODELJENO_SA_GOSTOM"
+	ActivityGuestUnshared = "UKINUTO_DELJENJE_SA_GOSTOM"
+	ActivityGuestExpired  = "ISTEKAO_GOSTUJUCI_NALOG"
+)
+
+// auditEntity is the ciljani_entitet of account events; ciljani_id is the
+// account they concern. Role and unit events concern a role or a unit, and
+// moving a project between units concerns the project.
+const (
+	auditEntity        = "Korisnik"
+	auditRoleEntity    = "Uloga"
+	auditUnitEntity    = "OrganizacionaJedinica"
+	auditProjectEntity = "Projekat"
+)
+
+// audit records an event about a user account, or about no account when
+// userID is 0. The acting user is the caller in ctx; steps taken before
+// signing in, and operator tools, have none. While an administrator acts as
+// another user, the entry names the administrator too. A failed write is logged and
+// does not undo the step it describes.
+func audit(ctx context.Context, log repositories.AnalyticsStore, activity string, userID int, description string) {
+	record(ctx, log, activity, auditEntity, userID, description)
+}
+
+// auditRole records an event about a role.
+func auditRole(ctx context.Context, log repositories.AnalyticsStore, activity string, roleID int, description string) {
+	record(ctx, log, activity, auditRoleEntity, roleID, description)
+}
+
+func record(ctx context.Context, log repositories.AnalyticsStore, activity, entity string, targetID int, description string) {
+	entry := &models.ActivityLog{
+		TipAktivnosti: activity,
+		Opis:          &description,
+	}
+	if targetID != 0 {
+		entry.CiljaniEntitet = &entity
+		entry.CiljaniID = &targetID
+	}
+	if principal, ok := PrincipalFrom(ctx); ok && !principal.System {
+		entry.KorisnikID = &principal.User.KorisnikID
+		if principal.Impersonation != nil {
+			entry.StvarniKorisnikID = &principal.Impersonation.AdministratorID
+		}
+	}
+
+	if err := log.LogActivity(ctx, entry); err != nil {
+		slog.ErrorContext(ctx, "audit entry not written", "activity", activity, "target_entity", entity, "target_id", targetID, "error", err)
+	}
+}
@@ -0,0 +1,108 @@
+// ============================================================================
+// auth_provider.go - Password checks per account source
+// ============================================================================
+
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/repositories"
+)
+
+// ErrInvalidCredentials is wrapped by providers when the username or the
+// password is wrong. Login answers it like any failed sign-in; other
+// provider errors mean the source could not be asked.
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// AuthProvider checks passwords against one source of accounts. Every
+// account names its provider in IzvorPrijave; usernames without an account
+// are offered to every provider in the order they were added, so one that
+// provisions accounts can create it.
+type AuthProvider interface {
+	// Name is the IzvorPrijave of the accounts the provider checks.
+	Name() string
+	// Authenticate checks the password of username. user is the account,
+	// or nil if there is none yet. It returns the account to sign in, which
+	// the provider may have created or updated from its source, or an error
+	// wrapping ErrInvalidCredentials.
+	Authenticate(ctx context.Context, user *models.User, username, password string) (*models.User, error)
+}
+
+// localProvider checks the password hash stored with the account.
+type localProvider struct {
+	users  repositories.UserStore
+	hasher *PasswordHasher
+}
+
+func (p *localProvider) Name() string {
+	return models.IzvorLokalni
+}
+
+// Authenticate replaces an outdated hash of a password it has just
+// verified. Accounts awaiting activation are handled by Login before any
+// provider.
+func (p *localProvider) Authenticate(ctx context.Context, user *models.User, username, password string) (*models.User, error) {
+	if user == nil {
+		return nil, fmt.Errorf("unknown user: %w", ErrInvalidCredentials)
+	}
+
+	ok, outdated := p.hasher.Verify(password, user.HashSifre)
+	if !ok {
+		return nil, fmt.Errorf("wrong password: %w", ErrInvalidCredentials)
+	}
+	if outdated {
+		p.upgrade(ctx, user.KorisnikID, password)
+	}
+	return user, nil
+}
+
+// upgrade replaces the outdated hash of a password that was just verified.
+// A failure is logged and the old hash keeps working.
+func (p *localProvider) upgrade(ctx context.Context, userID int, password string) {
+	hash, err := p.hasher.Hash(password)
+	if err == nil {
+		err = p.users.UpdatePassword(ctx, userID, hash, false)
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "password hash not upgraded", "user_id", userID, "error", err)
+		return
+	}
+	slog.InfoContext(ctx, "password hash upgraded", "user_id", userID)
+}
+
+// AddProvider lets accounts of another source sign in. A provider with the
+// name of an earlier one replaces it.
+func (s *AuthService) AddProvider(provider AuthProvider) {
+	for i, existing := range s.providers {
+		if existing.Name() == provider.Name() {
+			s.providers[i] = provider
+			return
+		}
+	}
+	s.providers = append(s.providers, provider)
+}
+
+// provider returns the provider that checks the account.
+func (s *AuthService) provider(user *models.User) AuthProvider {
+	name := user.IzvorPrijave
+	if name == "" {
+		name = models.IzvorLokalni
+	}
+	for _, provider := range s.providers {
+		if provider.Name() == name {
+			return provider
+		}
+	}
+	return nil
+}
+
+// isLocal reports whether the password of the account is kept here, so it
+// can be changed, reset and activated.
+func isLocal(user *models.User) bool {
+	return user.IzvorPrijave == "" || user.IzvorPrijave == models.IzvorLokalni
+}
@@ -1,39 +1,113 @@
 package services
 
 import (
+	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"crypto/subtle"
-	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"log/slog"
 	"strings"
+	"time"
+	"unicode"
 
+	"github.com/cane/research-institute-system/backend/config"
+	"github.com/cane/research-institute-system/backend/logging"
 	"github.com/cane/research-institute-system/backend/models"
 	"github.com/cane/research-institute-system/backend/repositories"
-
-	"golang.org/x/crypto/argon2"
 )
 
 type AuthService struct {
-	userRepo *repositories.UserRepository
+	userRepo    repositories.UserStore
+	roles       repositories.RoleStore
+	activations repositories.ActivationStore
+	factors     repositories.TwoFactorStore
+	tokens      repositories.AccessTokenStore
+	guests      repositories.GuestStore
+	activity    repositories.AnalyticsStore
+	sessions    *SessionManager
+	authz       *Authorizer
+	throttle    *LoginThrottle
+	hasher      *PasswordHasher
+	passwords   *PasswordPolicy
+	providers   []AuthProvider
+	cfg         config.AuthConfig
+	now         func() time.Time
+}
+
+func NewAuthService(stores repositories.Stores, sessions *SessionManager, authz *Authorizer, cfg config.AuthConfig) *AuthService {
+	hasher := NewPasswordHasher(cfg)
+	return &AuthService{
+		userRepo:    stores.Users,
+		roles:       stores.Roles,
+		activations: stores.Activations,
+		factors:     stores.TwoFactor,
+		tokens:      stores.Tokens,
+		guests:      stores.Guests,
+		activity:    stores.Analytics,
+		sessions:    sessions,
+		authz:       authz,
+		throttle:    NewLoginThrottle(cfg),
+		hasher:      hasher,
+		passwords:   NewPasswordPolicy(cfg, stores.Passwords, hasher),
+		providers:   []AuthProvider{&localProvider{users: stores.Users, hasher: hasher}},
+		cfg:         cfg,
+		now:         time.Now,
+	}
 }
 
-func NewAuthService(userRepo *repositories.UserRepository) *AuthService {
-	return &AuthService{userRepo: userRepo}
+// SetClock replaces the time source of the sign-in limits, used by tests.
+func (s *AuthService) SetClock(now func() time.Time) {
+	s.now = now
+	s.throttle.SetClock(now)
 }
 
 type LoginRequest struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
+
+	// Source identifies the client for the brute-force limits: the remote
+	// address for the REST API, LocalSource when empty.
+	Source string `json:"-"`
 }
 
 type LoginResponse struct {
 	User    *models.User `json:"user"`
 	Success bool         `json:"success"`
 	Message string       `json:"message"`
+	Token   string       `json:"token,omitempty"`
+	Expires *time.Time   `json:"expires,omitempty" ts_type:"string"`
+
+	// RetryAfter is set, in seconds, when the attempt was refused without
+	// checking the password because of earlier failures.
+	RetryAfter int `json:"retry_after,omitempty"`
+
+	// Zastupanje is set when the session acts as User on behalf of an
+	// administrator.
+	Zastupanje *Impersonation `json:"zastupanje,omitempty"`
+}
+
+// ActivationCode is the one-time credential of a new or reset account. It
+// is shown to the administrator once; only its hash is stored.
+type ActivationCode struct {
+	KorisnikID    int       `json:"korisnik_id"`
+	KorisnickoIme string    `json:"korisnicko_ime"`
+	Kod           string    `json:"kod"`
+	Istice        time.Time `json:"istice" ts_type:"string"`
 }
 
-func (s *AuthService) Login(req LoginRequest) (*LoginResponse, error) {
+// FirstTimeLogin is the message of a login that opened an activation
+// session; the client must ask for a new password and call
+// CompleteActivation.
+const FirstTimeLogin = "FIRST_TIME_LOGIN"
+
+// unusablePassword is stored as the hash of accounts awaiting activation. It
+// is not in the argon2id format, so no password verifies against it.
+const unusablePassword = "!"
+
+func (s *AuthService) Login(ctx context.Context, req LoginRequest) (*LoginResponse, error) {
 	if req.Username == "" || req.Password == "" {
 		return &LoginResponse{
 			Success: false,
@@ -41,189 +115,626 @@ func (s *AuthService) Login(req LoginRequest) (*LoginResponse, error) {
 		}, nil
 	}
 
-	user, err := s.userRepo.GetByUsername(req.Username)
+	source := req.Source
+	if source == "" {
+		source = LocalSource
+	}
+
+	// A throttled source is refused before anything is checked, so waiting
+	// out the delay is the only way to learn anything about a password
+	if wait, blocked := s.throttle.Wait(source); wait > 0 {
+		slog.WarnContext(ctx, "login refused", "username", req.Username, "source", source, "blocked", blocked, "retry_after", wait)
+		if blocked {
+			return refused("Previše neuspešnih prijava sa ove adrese, pokušajte ponovo za %d min", wait, time.Minute), nil
+		}
+		return refused("Previše neuspešnih pokušaja, pokušajte ponovo za %d s", wait, time.Second), nil
+	}
+
+	user, err := s.userRepo.GetByUsername(ctx, req.Username)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return s.provision(ctx, req.Username, req.Password, source)
+	}
 	if err != nil {
-		return &LoginResponse{
-			Success: false,
-			Message: "Neispravno korisničko ime ili lozinka",
-		}, nil
+		return nil, err
 	}
 
 	if user.Status != "aktivan" {
+		s.loginFailed(ctx, user, req.Username, source, ActivityLoginFailed, "inactive account")
 		return &LoginResponse{
 			Success: false,
 			Message: "Nalog nije aktivan",
 		}, nil
 	}
-
-	// Check if this is first-time login (no previous login recorded)
-	if user.PoslednajaPrijava == nil {
+	if s.guestExpired(user) {
+		s.loginFailed(ctx, user, req.Username, source, ActivityLoginFailed, "guest account expired")
 		return &LoginResponse{
-			User:    user,
-			Success: true,
-			Message: "FIRST_TIME_LOGIN", // Special message indicating first-time login
+			Success: false,
+			Message: "Gostujući nalog je istekao",
 		}, nil
 	}
 
-	if !s.verifyPassword(req.Password, user.HashSifre) {
+	if response, err := s.checkLockout(ctx, user); response != nil || err != nil {
+		return response, err
+	}
+
+	if user.MoraPromenitiLozinku && isLocal(user) {
+		return s.activationLogin(ctx, user, req.Password, source)
+	}
+
+	provider := s.provider(user)
+	if provider == nil {
+		slog.ErrorContext(ctx, "login refused, auth provider not configured", "username", user.KorisnickoIme, "provider", user.IzvorPrijave)
+		return unavailable(), nil
+	}
+
+	verified, err := provider.Authenticate(ctx, user, req.Username, req.Password)
+	if errors.Is(err, ErrInvalidCredentials) {
+		s.loginFailed(ctx, user, req.Username, source, ActivityLoginFailed, err.Error())
 		return &LoginResponse{
 			Success: false,
 			Message: "Neispravno korisničko ime ili lozinka",
 		}, nil
 	}
+	if err != nil {
+		slog.ErrorContext(ctx, "auth provider failed", "username", user.KorisnickoIme, "provider", provider.Name(), "error", err)
+		return unavailable(), nil
+	}
 
-	// Update last login
-	s.userRepo.UpdateLastLogin(user.KorisnikID)
+	if err := s.clearFailures(ctx, user); err != nil {
+		return nil, err
+	}
 
-	// Clear password hash from response
-	user.HashSifre = ""
+	return s.signIn(ctx, verified, "Uspešna prijava")
+}
 
+// provision offers a username without an account to every provider, so one
+// that knows the user can create the account.
+func (s *AuthService) provision(ctx context.Context, username, password, source string) (*LoginResponse, error) {
+	reason := "unknown user"
+	for _, provider := range s.providers {
+		user, err := provider.Authenticate(ctx, nil, username, password)
+		if errors.Is(err, ErrInvalidCredentials) {
+			if provider.Name() != models.IzvorLokalni {
+				reason = provider.Name() + ": " + err.Error()
+			}
+			continue
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "auth provider failed", "username", username, "provider", provider.Name(), "error", err)
+			return unavailable(), nil
+		}
+
+		audit(ctx, s.activity, ActivityUserCreated, user.KorisnikID, "Kreiran korisnik "+user.KorisnickoIme+" iz izvora "+provider.Name())
+		slog.InfoContext(ctx, "user provisioned", "target_user_id", user.KorisnikID, "username", user.KorisnickoIme,
+			"provider", provider.Name(), "role_id", user.UlogaID)
+		return s.signIn(ctx, user, "Uspešna prijava")
+	}
+
+	s.loginFailed(ctx, nil, username, source, ActivityLoginFailed, reason)
 	return &LoginResponse{
-		User:    user,
-		Success: true,
-		Message: "Uspešna prijava",
+		Success: false,
+		Message: "Neispravno korisničko ime ili lozinka",
 	}, nil
 }
 
-func (s *AuthService) CreateUser(user *models.User, tempPassword string) error {
-	if user.KorisnickoIme == "" || user.Email == "" {
-		return errors.New("korisničko ime i email su obavezni")
+// unavailable answers a sign-in whose provider could not be asked. It is not
+// counted as a failure.
+func unavailable() *LoginResponse {
+	return &LoginResponse{
+		Success: false,
+		Message: "Provera lozinke trenutno nije moguća, pokušajte ponovo kasnije",
+	}
+}
+
+// checkLockout refuses sign-in to a locked account and enforces the delay
+// after its last failure. An expired lock is cleared, so the account gets the
+// full number of attempts again.
+func (s *AuthService) checkLockout(ctx context.Context, user *models.User) (*LoginResponse, error) {
+	now := s.now()
+
+	if user.ZakljucanDo != nil {
+		if now.Before(*user.ZakljucanDo) {
+			slog.WarnContext(ctx, "login refused", "username", user.KorisnickoIme, "reason", "account locked", "locked_until", *user.ZakljucanDo)
+			return refused("Nalog je privremeno zaključan zbog neuspešnih prijava, pokušajte ponovo za %d min ili se obratite administratoru", user.ZakljucanDo.Sub(now), time.Minute), nil
+		}
+		if err := s.userRepo.ResetLoginFailures(ctx, user.KorisnikID); err != nil {
+			return nil, err
+		}
+		user.NeuspesnePrijave, user.PoslednjaNeuspesnaPrijava, user.ZakljucanDo = 0, nil, nil
+	}
+
+	if user.PoslednjaNeuspesnaPrijava != nil {
+		next := user.PoslednjaNeuspesnaPrijava.Add(loginDelay(s.cfg, user.NeuspesnePrijave))
+		if now.Before(next) {
+			slog.WarnContext(ctx, "login refused", "username", user.KorisnickoIme, "reason", "login delay", "retry_after", next.Sub(now))
+			return refused("Previše neuspešnih pokušaja, pokušajte ponovo za %d s", next.Sub(now), time.Second), nil
+		}
 	}
 
-	// Check if user already exists
-	existingUser, _ := s.userRepo.GetByUsername(user.KorisnickoIme)
-	if existingUser != nil {
-		return errors.New("korisnik sa tim korisničkim imenom već postoji")
+	return nil, nil
+}
+
+// loginFailed counts a failed sign-in against the source and, if the
+// account exists, against the account, locking either once it reaches its
+// threshold. Every failure and lockout is written to the activity log.
+func (s *AuthService) loginFailed(ctx context.Context, user *models.User, username, source, activity, reason string) {
+	slog.WarnContext(ctx, "login failed", "username", username, "source", source, "reason", reason)
+
+	if s.throttle.Fail(source) {
+		slog.WarnContext(ctx, "login source blocked", "source", source, "duration", s.cfg.LockoutDuration)
+		audit(ctx, s.activity, ActivitySourceBlocked, 0,
+			fmt.Sprintf("Adresa %s blokirana na %v zbog neuspešnih prijava", source, s.cfg.LockoutDuration))
 	}
 
-	// Hash the temporary password
-	hashedPassword, err := s.HashPassword(tempPassword)
+	if user == nil {
+		audit(ctx, s.activity, activity, 0, fmt.Sprintf("Neuspešna prijava kao %q sa %s: %s", username, source, reason))
+		return
+	}
+	audit(ctx, s.activity, activity, user.KorisnikID, fmt.Sprintf("Neuspešna prijava sa %s: %s", source, reason))
+
+	now := s.now()
+	failures, err := s.userRepo.RecordLoginFailure(ctx, user.KorisnikID, now)
 	if err != nil {
+		slog.ErrorContext(ctx, "login failure not recorded", "user_id", user.KorisnikID, "error", err)
+		return
+	}
+	if s.cfg.LockoutThreshold == 0 || failures < s.cfg.LockoutThreshold {
+		return
+	}
+
+	until := now.Add(s.cfg.LockoutDuration)
+	if err := s.userRepo.Lock(ctx, user.KorisnikID, until); err != nil {
+		slog.ErrorContext(ctx, "account not locked", "user_id", user.KorisnikID, "error", err)
+		return
+	}
+	slog.WarnContext(ctx, "account locked", "username", user.KorisnickoIme, "user_id", user.KorisnikID, "failures", failures, "locked_until", until)
+	audit(ctx, s.activity, ActivityAccountLocked, user.KorisnikID,
+		fmt.Sprintf("Nalog zaključan do %s posle %d neuspešnih prijava", until.Format("2006-01-02 15:04"), failures))
+}
+
+// clearFailures resets the failure count after a successful sign-in.
+func (s *AuthService) clearFailures(ctx context.Context, user *models.User) error {
+	if user.NeuspesnePrijave == 0 && user.ZakljucanDo == nil {
+		return nil
+	}
+	return s.userRepo.ResetLoginFailures(ctx, user.KorisnikID)
+}
+
+// refused answers an attempt that was not checked because of earlier
+// failures. The message gets the wait rounded up to whole units.
+func refused(format string, wait, unit time.Duration) *LoginResponse {
+	return &LoginResponse{
+		Success:    false,
+		Message:    fmt.Sprintf(format, int((wait+unit-1)/unit)),
+		RetryAfter: int((wait + time.Second - 1) / time.Second),
+	}
+}
+
+// UnlockAccount lifts a lockout and clears the failed sign-in count of a
+// user.
+func (s *AuthService) UnlockAccount(ctx context.Context, userID int) error {
+	if _, err := s.authz.Require(ctx, PermUserManage); err != nil {
 		return err
 	}
 
-	user.HashSifre = hashedPassword
-	user.Status = "aktivan"
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.userRepo.ResetLoginFailures(ctx, userID); err != nil {
+		return err
+	}
 
-	return s.userRepo.Create(user)
+	audit(ctx, s.activity, ActivityAccountUnlocked, userID, "Nalog otključan")
+	slog.InfoContext(ctx, "account unlocked", "target_user_id", userID)
+	return nil
 }
 
-func (s *AuthService) ResetPassword(userID int) (string, error) {
-	// Generate temporary password
-	tempPassword := s.generateTempPassword()
+// activationLogin signs in an account that must set its password. The
+// credential is the pending activation code, which is consumed here so it
+// works only once. Accounts flagged by the migration from the old first-login
+// flow have no code and sign in with the temporary password they were given.
+// Either way the result is an activation session, good only for
+// CompleteActivation.
+func (s *AuthService) activationLogin(ctx context.Context, user *models.User, credential, source string) (*LoginResponse, error) {
+	failed := func(reason, message string) (*LoginResponse, error) {
+		s.loginFailed(ctx, user, user.KorisnickoIme, source, ActivityActivationFailed, reason)
+		return &LoginResponse{Success: false, Message: message}, nil
+	}
+
+	activation, err := s.activations.GetPending(ctx, user.KorisnikID)
+	switch {
+	case err == nil:
+		if !activationCodeMatches(credential, activation.HashKoda) {
+			return failed("wrong activation code", "Neispravno korisničko ime ili lozinka")
+		}
+		if !s.now().Before(activation.Istice) {
+			return failed("activation code expired", "Aktivacioni kod je istekao, zatražite novi od administratora")
+		}
+		if err := s.activations.Consume(ctx, activation.AktivacijaID); err != nil {
+			if errors.Is(err, repositories.ErrConflict) {
+				return failed("activation code already used", "Neispravno korisničko ime ili lozinka")
+			}
+			return nil, err
+		}
+	case errors.Is(err, repositories.ErrNotFound):
+		if user.HashSifre == unusablePassword {
+			return failed("no pending activation code", "Neispravno korisničko ime ili lozinka")
+		}
+		if ok, _ := s.hasher.Verify(credential, user.HashSifre); !ok {
+			return failed("wrong temporary password", "Neispravno korisničko ime ili lozinka")
+		}
+	default:
+		return nil, err
+	}
+
+	if err := s.clearFailures(ctx, user); err != nil {
+		return nil, err
+	}
 
-	hashedPassword, err := s.HashPassword(tempPassword)
+	audit(ctx, s.activity, ActivityActivationVerified, user.KorisnikID, "Prijava aktivacionim kodom, čeka se nova lozinka")
+	slog.InfoContext(ctx, "first-time login", "username", user.KorisnickoIme, "user_id", user.KorisnikID)
+
+	user.HashSifre = ""
+	return s.issueSession(user, FirstTimeLogin, s.sessions.CreateActivation)
+}
+
+// issueSession creates a server-side session of the kind made by create
+// for an authenticated user.
+func (s *AuthService) issueSession(user *models.User, message string, create func(*models.User) (string, Session, error)) (*LoginResponse, error) {
+	token, session, err := create(user)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	err = s.userRepo.UpdatePassword(userID, hashedPassword)
+	return &LoginResponse{
+		User:    user,
+		Success: true,
+		Message: message,
+		Token:   token,
+		Expires: &session.Istice,
+	}, nil
+}
+
+// Authenticate resolves a session token, or a personal access token, and
+// reloads its user, so expired sessions and deactivated accounts are rejected
+// on every call. A session whose user is gone, inactive or an expired guest
+// is revoked, and so is an impersonation session whose administrator may no
+// longer impersonate. Guests get what is shared with them.
+// Activation sessions are refused with ErrPasswordChangeRequired and second
+// factor sessions with ErrTwoFactorRequired.
+func (s *AuthService) Authenticate(ctx context.Context, token string) (*Principal, error) {
+	// Role changes made by other processes apply from the next request
+	if err := s.authz.Refresh(ctx); err != nil {
+		slog.WarnContext(ctx, "permissions not refreshed, using the loaded ones", "error", err)
+	}
+
+	if strings.HasPrefix(token, AccessTokenPrefix) {
+		return s.authenticateToken(ctx, token)
+	}
+
+	session, err := s.sessions.Resolve(token)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	if session.Aktivacija {
+		return nil, ErrPasswordChangeRequired
+	}
+	if session.DrugiFaktor {
+		return nil, ErrTwoFactorRequired
+	}
+
+	user, err := s.userRepo.GetByID(ctx, session.KorisnikID)
+	if err != nil || user.Status != "aktivan" || s.guestExpired(user) || session.revokedBefore(user) {
+		s.sessions.Revoke(token)
+		slog.InfoContext(ctx, "session revoked, account unavailable", "user_id", session.KorisnikID)
+		return nil, ErrNoSession
+	}
+	guest, err := s.guestAccess(ctx, user)
+	if err != nil {
+		return nil, err
 	}
 
-	return tempPassword, nil
+	user.HashSifre = ""
+	if session.Zastupanje != nil {
+		principal, err := s.impersonationPrincipal(ctx, token, session, user)
+		if err != nil {
+			return nil, err
+		}
+		principal.Guest = guest
+		return principal, nil
+	}
+	return &Principal{User: user, Guest: guest}, nil
 }
 
-func (s *AuthService) ChangePassword(userID int, newPassword string) error {
-	if len(newPassword) < 8 {
-		return errors.New("lozinka mora imati najmanje 8 karaktera")
+// revokeUserSessions is SessionManager.RevokeUser for every process sharing
+// the database: the sessions of this process end at once, those of the
+// others at their next request through the user's sesije_nevazece_od.
+func (s *AuthService) revokeUserSessions(ctx context.Context, userID int, exceptToken string) int {
+	if err := s.userRepo.RevokeSessions(ctx, userID, s.sessions.clock().Truncate(time.Microsecond)); err != nil {
+		slog.ErrorContext(ctx, "sessions not revoked in other processes", "target_user_id", userID, "error", err)
 	}
+	return s.sessions.RevokeUser(userID, exceptToken)
+}
 
-	hashedPassword, err := s.HashPassword(newPassword)
+// guestExpired reports whether user is a guest whose account has ended. The
+// sweep deactivates such accounts; until it runs they are refused here.
+func (s *AuthService) guestExpired(user *models.User) bool {
+	return user.GostDo != nil && !s.now().Before(*user.GostDo)
+}
+
+// guestAccess reads the projects shared with a guest, or returns nil for
+// other users.
+func (s *AuthService) guestAccess(ctx context.Context, user *models.User) (*GuestAccess, error) {
+	if user.GostDo == nil {
+		return nil, nil
+	}
+
+	shares, err := s.guests.GetShares(ctx, user.KorisnikID)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	access := &GuestAccess{Istice: *user.GostDo, Projekti: map[int]bool{}}
+	for _, share := range shares {
+		if share.ProjekatID != nil {
+			access.Projekti[*share.ProjekatID] = true
+		}
 	}
+	return access, nil
+}
 
-	return s.userRepo.UpdatePassword(userID, hashedPassword)
+// Logout ends the session identified by token.
+func (s *AuthService) Logout(token string) {
+	s.sessions.Revoke(token)
 }
 
-func (s *AuthService) CompleteFirstTimeSetup(userID int, newPassword string) error {
-	hashedPassword, err := s.HashPassword(newPassword)
+// CompleteActivation sets the first password of the account signed in with
+// the activation session token. The activation session ends and a normal
+// session, or a second factor session if the role requires one, is returned
+// in its place.
+func (s *AuthService) CompleteActivation(ctx context.Context, token, newPassword string) (*LoginResponse, error) {
+	session, err := s.sessions.Resolve(token)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if !session.Aktivacija {
+		return nil, invalidInput("nalog je već aktiviran")
+	}
+
+	user, err := s.userRepo.GetByID(ctx, session.KorisnikID)
+	if err != nil || user.Status != "aktivan" || session.revokedBefore(user) {
+		s.sessions.Revoke(token)
+		return nil, ErrNoSession
 	}
+	ctx = logging.WithUser(ctx, user.KorisnikID, user.KorisnickoIme)
 
-	// Update password and set first login timestamp
-	err = s.userRepo.UpdatePassword(userID, hashedPassword)
+	if err := s.passwords.Validate(ctx, user, newPassword); err != nil {
+		return nil, err
+	}
+	hashedPassword, err := s.HashPassword(newPassword)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	if err := s.userRepo.UpdatePassword(ctx, user.KorisnikID, hashedPassword, false); err != nil {
+		return nil, err
+	}
+	s.passwords.Remember(ctx, user.KorisnikID, hashedPassword)
+	if err := s.activations.Revoke(ctx, user.KorisnikID); err != nil {
+		return nil, err
 	}
+	s.revokeUserSessions(ctx, user.KorisnikID, "")
 
-	// Mark as having completed first login
-	return s.userRepo.UpdateLastLogin(userID)
+	audit(ctx, s.activity, ActivityActivationCompleted, user.KorisnikID, "Nalog aktiviran, lozinka postavljena")
+	slog.InfoContext(ctx, "account activated", "target_user_id", user.KorisnikID)
+
+	user.MoraPromenitiLozinku = false
+	return s.signIn(ctx, user, "Lozinka je uspešno postavljena")
 }
 
-func (s *AuthService) CompleteFirstTimeSetupByUsername(username, newPassword string) error {
-	// Get user by username
-	user, err := s.userRepo.GetByUsername(username)
-	if err != nil {
-		return errors.New("korisnik nije pronađen")
+// CreateUser creates an account that must be activated: it has no usable
+// password until the user signs in with the returned code and sets one.
+func (s *AuthService) CreateUser(ctx context.Context, user *models.User) (*ActivationCode, error) {
+	if _, err := s.authz.Require(ctx, PermUserManage); err != nil {
+		return nil, err
 	}
 
-	// Use existing function with userID
-	return s.CompleteFirstTimeSetup(user.KorisnikID, newPassword)
+	return s.createUser(ctx, user)
 }
 
-func (s *AuthService) HashPassword(password string) (string, error) {
-	salt := make([]byte, 32)
-	_, err := rand.Read(salt)
-	if err != nil {
-		return "", err
+// createUser creates an account awaiting activation and issues its code.
+func (s *AuthService) createUser(ctx context.Context, user *models.User) (*ActivationCode, error) {
+	if user.KorisnickoIme == "" || user.Email == "" {
+		return nil, invalidInput("korisničko ime i email su obavezni")
+	}
+	if _, err := assignableRole(ctx, s.roles, s.authz, user.UlogaID); err != nil {
+		return nil, err
 	}
 
-	hash := argon2.IDKey([]byte(password), salt, 1, 64*1024, 4, 32)
+	if _, err := s.userRepo.GetByUsername(ctx, user.KorisnickoIme); err == nil {
+		return nil, conflict("korisnik sa tim korisničkim imenom već postoji")
+	} else if !errors.Is(err, repositories.ErrNotFound) {
+		return nil, err
+	}
+	if err := checkEmailFree(ctx, s.userRepo, 0, user.Email); err != nil {
+		return nil, err
+	}
 
-	b64Salt := base64.RawStdEncoding.EncodeToString(salt)
-	b64Hash := base64.RawStdEncoding.EncodeToString(hash)
+	user.HashSifre = unusablePassword
+	user.MoraPromenitiLozinku = true
+	user.Status = "aktivan"
 
-	format := "$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s"
-	full := fmt.Sprintf(format, argon2.Version, 64*1024, 1, 4, b64Salt, b64Hash)
-	return full, nil
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		return nil, err
+	}
+	user.HashSifre = ""
+
+	audit(ctx, s.activity, ActivityUserCreated, user.KorisnikID, "Kreiran korisnik "+user.KorisnickoIme)
+	slog.InfoContext(ctx, "user created", "target_user_id", user.KorisnikID, "username", user.KorisnickoIme, "role_id", user.UlogaID)
+
+	return s.issueActivation(ctx, user)
 }
 
-func (s *AuthService) verifyPassword(password, hash string) bool {
-	parts := strings.Split(hash, "$")
-	if len(parts) != 6 {
-		return false
+// ResetPassword disables the user's password, ends all of the user's
+// sessions and returns a new activation code.
+func (s *AuthService) ResetPassword(ctx context.Context, userID int) (*ActivationCode, error) {
+	if _, err := s.authz.Require(ctx, PermUserManage); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !isLocal(user) {
+		return nil, externalPassword()
+	}
+
+	if err := s.userRepo.UpdatePassword(ctx, userID, unusablePassword, true); err != nil {
+		return nil, err
 	}
 
-	var version int
-	_, err := fmt.Sscanf(parts[2], "v=%d", &version)
+	// A reset password invalidates every open session of the user
+	s.revokeUserSessions(ctx, userID, "")
+	audit(ctx, s.activity, ActivityPasswordReset, userID, "Lozinka resetovana, nalog čeka aktivaciju")
+	slog.InfoContext(ctx, "password reset", "target_user_id", userID)
+
+	return s.issueActivation(ctx, user)
+}
+
+// issueActivation stores a new activation code for user, replacing any
+// earlier one, and returns the code in clear.
+func (s *AuthService) issueActivation(ctx context.Context, user *models.User) (*ActivationCode, error) {
+	code, err := newActivationCode()
 	if err != nil {
-		return false
+		return nil, err
+	}
+
+	activation := &models.Activation{
+		KorisnikID: user.KorisnikID,
+		HashKoda:   hashActivationCode(code),
+		Istice:     s.now().Add(s.cfg.ActivationTTL),
 	}
-	if version != argon2.Version {
-		return false
+	if principal, ok := PrincipalFrom(ctx); ok && !principal.System {
+		activation.KreiraoKorisnikID = &principal.User.KorisnikID
+	}
+
+	if err := s.activations.Create(ctx, activation); err != nil {
+		return nil, err
 	}
 
-	var memory, iterations, parallelism int
-	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &parallelism)
+	audit(ctx, s.activity, ActivityActivationIssued, user.KorisnikID,
+		"Izdat aktivacioni kod, važi do "+activation.Istice.Format("2006-01-02 15:04"))
+
+	return &ActivationCode{
+		KorisnikID:    user.KorisnikID,
+		KorisnickoIme: user.KorisnickoIme,
+		Kod:           code,
+		Istice:        activation.Istice,
+	}, nil
+}
+
+// ChangePassword sets a new password and ends all other sessions of the
+// user. The session holding currentToken stays valid. A pending activation
+// of the account is cancelled. Access tokens and impersonation sessions
+// cannot change passwords.
+func (s *AuthService) ChangePassword(ctx context.Context, userID int, newPassword, currentToken string) error {
+	if principal, ok := PrincipalFrom(ctx); ok && principal.Token != nil {
+		return fmt.Errorf("%w (lozinka se ne menja pristupnim tokenom)", ErrForbidden)
+	}
+	if err := refuseImpersonated(ctx); err != nil {
+		return err
+	}
+	user, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
-		return false
+		return err
+	}
+	if !isLocal(user) {
+		return externalPassword()
+	}
+	if err := s.passwords.Validate(ctx, user, newPassword); err != nil {
+		return err
 	}
 
-	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	hashedPassword, err := s.HashPassword(newPassword)
 	if err != nil {
-		return false
+		return err
+	}
+
+	if err := s.userRepo.UpdatePassword(ctx, userID, hashedPassword, false); err != nil {
+		return err
 	}
+	s.passwords.Remember(ctx, userID, hashedPassword)
+	if err := s.activations.Revoke(ctx, userID); err != nil {
+		return err
+	}
+
+	s.revokeUserSessions(ctx, userID, currentToken)
+	slog.InfoContext(ctx, "password changed", "target_user_id", userID)
+	return nil
+}
+
+// externalPassword refuses to set the password of an account whose password
+// is kept by another provider.
+func externalPassword() error {
+	return invalidInput("lozinka ovog naloga menja se u imeniku korisnika")
+}
 
-	decodedHash, err := base64.RawStdEncoding.DecodeString(parts[5])
+// CheckPassword returns the password policy violations of password as the
+// new password of the user, so clients can show them while it is typed.
+func (s *AuthService) CheckPassword(ctx context.Context, userID int, password string) ([]PasswordViolation, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
-		return false
+		return nil, err
 	}
+	return s.passwords.Check(ctx, user, password)
+}
+
+// HashPassword hashes a password the way every stored password is hashed.
+func (s *AuthService) HashPassword(password string) (string, error) {
+	return s.hasher.Hash(password)
+}
 
-	comparisonHash := argon2.IDKey([]byte(password), salt, uint32(iterations), uint32(memory), uint8(parallelism), uint32(len(decodedHash)))
+// activationAlphabet leaves out characters that are easily confused (0/O,
+// 1/I). Its 32 symbols divide 256, so mapping random bytes onto it is
+// unbiased.
+const activationAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
 
-	return subtle.ConstantTimeCompare(decodedHash, comparisonHash) == 1
+// newActivationCode returns a random code such as "K7QM-3XWD-P9HE".
+func newActivationCode() (string, error) {
+	return newCode(3)
 }
 
-func (s *AuthService) generateTempPassword() string {
-	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	b := make([]byte, 12)
-	rand.Read(b)
-	for i := range b {
-		b[i] = charset[b[i]%byte(len(charset))]
+// newCode returns a random code of groups of four symbols.
+func newCode(groups int) (string, error) {
+	raw := make([]byte, 4*groups)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
 	}
-	return string(b)
+
+	var code strings.Builder
+	for i, b := range raw {
+		if i > 0 && i%4 == 0 {
+			code.WriteByte('-')
+		}
+		code.WriteByte(activationAlphabet[int(b)%len(activationAlphabet)])
+	}
+	return code.String(), nil
+}
+
+// hashActivationCode hashes the code as typed, ignoring case, spaces and
+// dashes. The code is random and single-use, so a fast hash is enough.
+func hashActivationCode(code string) string {
+	normalized := strings.Map(func(r rune) rune {
+		if r == '-' || unicode.IsSpace(r) {
+			return -1
+		}
+		return unicode.ToUpper(r)
+	}, code)
+
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+func activationCodeMatches(code, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(hashActivationCode(code)), []byte(hash)) == 1
 }
@@ -0,0 +1,368 @@
+// ============================================================================
+// authorization_service.go - Role Based Permission Policy
+// ============================================================================
+
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/repositories"
+)
+
+// Permission names a single operation that can be granted to a role.
+type Permission string
+
+const (
+	PermAll Permission = "*"
+
+	PermUserView        Permission = "user.view"
+	PermUserManage      Permission = "user.manage"
+	PermUserImpersonate Permission = "user.impersonate"
+	PermSessionManage   Permission = "session.manage"
+	PermPolicyManage    Permission = "policy.manage"
+	PermGuestManage     Permission = "guest.manage"
+
+	PermProjectView    Permission = "project.view"
+	PermProjectCreate  Permission = "project.create"
+	PermProjectUpdate  Permission = "project.update"
+	PermProjectDelete  Permission = "project.delete"
+	PermProjectMembers Permission = "project.members"
+
+	PermTaskView    Permission = "task.view"
+	PermTaskCreate  Permission = "task.create"
+	PermTaskUpdate  Permission = "task.update"
+	PermTaskDelete  Permission = "task.delete"
+	PermTaskComment Permission = "task.comment"
+
+	PermWorkflowView   Permission = "workflow.view"
+	PermWorkflowManage Permission = "workflow.manage"
+
+	PermDocumentView   Permission = "document.view"
+	PermDocumentUpload Permission = "document.upload"
+	PermDocumentUpdate Permission = "document.update"
+	PermDocumentDelete Permission = "document.delete"
+
+	PermUnitView   Permission = "unit.view"
+	PermUnitManage Permission = "unit.manage"
+
+	PermAnalyticsView Permission = "analytics.view"
+	PermAuditView     Permission = "audit.view"
+)
+
+// AllPermissions lists every permission known to the system.
+var AllPermissions = []Permission{
+	PermUserView, PermUserManage, PermUserImpersonate, PermSessionManage, PermPolicyManage, PermGuestManage,
+	PermProjectView, PermProjectCreate, PermProjectUpdate, PermProjectDelete, PermProjectMembers,
+	PermTaskView, PermTaskCreate, PermTaskUpdate, PermTaskDelete, PermTaskComment,
+	PermWorkflowView, PermWorkflowManage,
+	PermDocumentView, PermDocumentUpload, PermDocumentUpdate, PermDocumentDelete,
+	PermUnitView, PermUnitManage,
+	PermAnalyticsView, PermAuditView,
+}
+
+var ErrForbidden = errors.New("nemate dozvolu za ovu operaciju")
+
+// securityPermissions change accounts, roles and sessions. Impersonation
+// sessions may not use them, whatever the role of the user acted as.
+var securityPermissions = map[Permission]bool{
+	PermUserManage:      true,
+	PermUserImpersonate: true,
+	PermSessionManage:   true,
+	PermPolicyManage:    true,
+	PermGuestManage:     true,
+}
+
+// guestPermissions are all that guest accounts may use, and only in what is
+// shared with them, whatever their role grants.
+var guestPermissions = map[Permission]bool{
+	PermProjectView:  true,
+	PermTaskView:     true,
+	PermDocumentView: true,
+}
+
+// Policy maps role names (Uloge.naziv_uloge) to the permissions they grant
+// in every project.
+type Policy struct {
+	Roles map[string][]Permission `json:"roles"`
+}
+
+// roleGrants are the permissions of one role, granted everywhere or only
+// within some projects.
+type roleGrants struct {
+	global   map[Permission]bool
+	projects map[Permission]map[int]bool
+}
+
+// Authorizer checks permissions of callers against the roles stored in the
+// database (Uloge and DozvoleUloga). It keeps the permissions in memory and
+// Refresh reloads them when the stored version changes, so a change made by
+// another process applies from the next request.
+type Authorizer struct {
+	roles repositories.RoleStore
+
+	mu      sync.RWMutex
+	version int64
+	grants  map[string]roleGrants
+	keys    map[string]string // system role keys by role name
+}
+
+func NewAuthorizer(ctx context.Context, roles repositories.RoleStore) (*Authorizer, error) {
+	a := &Authorizer{roles: roles}
+	if err := a.Reload(ctx); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Reload reads the permissions of every role that is not retired.
+func (a *Authorizer) Reload(ctx context.Context) error {
+	// The version is read first; a change made in between is read now and
+	// again at the next Refresh
+	version, err := a.roles.Version(ctx)
+	if err != nil {
+		return fmt.Errorf("role version: %w", err)
+	}
+	roles, err := a.roles.GetAll(ctx)
+	if err != nil {
+		return fmt.Errorf("roles: %w", err)
+	}
+
+	grants := make(map[string]roleGrants, len(roles))
+	keys := map[string]string{}
+	for _, role := range roles {
+		if role.Povucena != nil {
+			continue
+		}
+		if role.Sistemska != nil {
+			keys[role.NazivUloge] = *role.Sistemska
+		}
+		set := roleGrants{global: map[Permission]bool{}, projects: map[Permission]map[int]bool{}}
+		for _, grant := range role.Dozvole {
+			perm := Permission(grant.Dozvola)
+			if grant.ProjekatID == nil {
+				set.global[perm] = true
+				continue
+			}
+			if set.projects[perm] == nil {
+				set.projects[perm] = map[int]bool{}
+			}
+			set.projects[perm][*grant.ProjekatID] = true
+		}
+		grants[role.NazivUloge] = set
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.version = version
+	a.grants = grants
+	a.keys = keys
+	return nil
+}
+
+// Refresh reloads the permissions if a role changed since they were read.
+func (a *Authorizer) Refresh(ctx context.Context) error {
+	version, err := a.roles.Version(ctx)
+	if err != nil {
+		return fmt.Errorf("role version: %w", err)
+	}
+
+	a.mu.RLock()
+	current := a.version
+	a.mu.RUnlock()
+	if version == current {
+		return nil
+	}
+
+	slog.DebugContext(ctx, "roles changed, reloading permissions", "version", version)
+	return a.Reload(ctx)
+}
+
+// Can reports whether the user's role grants the permission everywhere.
+func (a *Authorizer) Can(user *models.User, perm Permission) bool {
+	if user == nil {
+		return false
+	}
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	grants := a.grants[user.NazivUloge]
+	return grants.global[PermAll] || grants.global[perm]
+}
+
+// CanSomewhere reports whether the user's role grants the permission
+// everywhere or in at least one project.
+func (a *Authorizer) CanSomewhere(user *models.User, perm Permission) bool {
+	if user == nil {
+		return false
+	}
+	if a.Can(user, perm) {
+		return true
+	}
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	grants := a.grants[user.NazivUloge]
+	return len(grants.projects[PermAll]) > 0 || len(grants.projects[perm]) > 0
+}
+
+// Covers reports whether the user's role grants at least what the named
+// role grants, everywhere and in every project.
+func (a *Authorizer) Covers(user *models.User, roleName string) bool {
+	if user == nil {
+		return false
+	}
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	mine, theirs := a.grants[user.NazivUloge], a.grants[roleName]
+	if mine.global[PermAll] {
+		return true
+	}
+	for perm := range theirs.global {
+		if !mine.global[perm] {
+			return false
+		}
+	}
+	for perm, projects := range theirs.projects {
+		if mine.global[perm] {
+			continue
+		}
+		for projectID := range projects {
+			if !mine.projects[PermAll][projectID] && !mine.projects[perm][projectID] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// SystemRole returns the key of the user's role (Uloge.sistemska), or ""
+// when it is not a system role.
+func (a *Authorizer) SystemRole(user *models.User) string {
+	if user == nil {
+		return ""
+	}
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return a.keys[user.NazivUloge]
+}
+
+// CanInProject reports whether the user's role grants the permission within
+// the project, everywhere or in that project only.
+func (a *Authorizer) CanInProject(user *models.User, perm Permission, projectID int) bool {
+	if user == nil {
+		return false
+	}
+	if a.Can(user, perm) {
+		return true
+	}
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	grants := a.grants[user.NazivUloge]
+	return grants.projects[PermAll][projectID] || grants.projects[perm][projectID]
+}
+
+// Require returns the caller stored in ctx if it holds the permission.
+func (a *Authorizer) Require(ctx context.Context, perm Permission) (*models.User, error) {
+	return a.requireIn(ctx, perm, nil)
+}
+
+// RequireInProject is Require for an operation within a project, which
+// grants scoped to that project allow as well.
+func (a *Authorizer) RequireInProject(ctx context.Context, perm Permission, projectID int) (*models.User, error) {
+	return a.requireIn(ctx, perm, func() (int, error) { return projectID, nil })
+}
+
+// requireIn checks the permission everywhere and, failing that, within the
+// project projectOf returns. projectOf is called only then, so callers can
+// look the project up lazily; it returns 0 for things outside any project.
+func (a *Authorizer) requireIn(ctx context.Context, perm Permission, projectOf func() (int, error)) (*models.User, error) {
+	principal, ok := PrincipalFrom(ctx)
+	if !ok {
+		return nil, ErrNoSession
+	}
+	if principal.Guest != nil {
+		return requireShared(ctx, principal, perm, projectOf)
+	}
+
+	if !principal.System && !a.Can(principal.User, perm) {
+		projectID := 0
+		if projectOf != nil {
+			id, err := projectOf()
+			if err != nil {
+				return nil, err
+			}
+			projectID = id
+		}
+		if projectID == 0 || !a.CanInProject(principal.User, perm, projectID) {
+			slog.WarnContext(ctx, "permission denied", "permission", perm, "role", principal.User.NazivUloge, "project_id", projectID)
+			return nil, fmt.Errorf("%w (%s)", ErrForbidden, perm)
+		}
+	}
+	if principal.Impersonation != nil && securityPermissions[perm] {
+		slog.WarnContext(ctx, "permission denied while impersonating", "permission", perm)
+		return nil, refuseImpersonated(ctx)
+	}
+	if !principal.Allows(perm) {
+		slog.WarnContext(ctx, "permission denied, outside token scopes", "permission", perm, "token_id", principal.Token.TokenID)
+		return nil, fmt.Errorf("%w (%s)", ErrForbidden, perm)
+	}
+
+	return principal.User, nil
+}
+
+// requireShared allows a guest the read permissions within the projects
+// shared with it and nothing else.
+func requireShared(ctx context.Context, principal *Principal, perm Permission, projectOf func() (int, error)) (*models.User, error) {
+	projectID := 0
+	if guestPermissions[perm] && projectOf != nil {
+		id, err := projectOf()
+		if err != nil {
+			return nil, err
+		}
+		projectID = id
+	}
+	if projectID == 0 || !principal.Guest.Projekti[projectID] {
+		slog.WarnContext(ctx, "permission denied, not shared with guest", "permission", perm, "project_id", projectID)
+		return nil, fmt.Errorf("%w (%s)", ErrForbidden, perm)
+	}
+	return principal.User, nil
+}
+
+// PermissionsFor returns the permissions the user's role grants everywhere,
+// sorted.
+func (a *Authorizer) PermissionsFor(user *models.User) []Permission {
+	perms := []Permission{}
+	for _, perm := range AllPermissions {
+		if a.Can(user, perm) {
+			perms = append(perms, perm)
+		}
+	}
+	return perms
+}
+
+// PermissionsOf returns the permissions the caller may use: those of the
+// user's role, narrowed to the scopes of an access token or, while an
+// administrator acts as the user, without the security permissions.
+func (a *Authorizer) PermissionsOf(principal *Principal) []Permission {
+	perms := []Permission{}
+	for _, perm := range a.PermissionsFor(principal.User) {
+		if principal.Allows(perm) {
+			perms = append(perms, perm)
+		}
+	}
+	return perms
+}
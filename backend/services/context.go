@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/cane/research-institute-system/backend/logging"
+	"github.com/cane/research-institute-system/backend/models"
+)
+
+type principalKey struct{}
+
+// Principal is the authenticated caller on whose behalf a service call runs.
+type Principal struct {
+	User *models.User
+
+	// Token is the personal access token the caller authenticated with, or
+	// nil for a session. A token may use only the permissions in its scopes.
+	Token *models.AccessToken
+
+	// Impersonation is set when an administrator acts as User. Security
+	// changes are refused and the activity log names both of them.
+	Impersonation *Impersonation
+
+	// Guest is set for guest accounts of external partners. They may only
+	// read what is shared with them, until the account expires.
+	Guest *GuestAccess
+
+	// System marks operator tools such as riis-admin. They connect with the
+	// database credentials, so the permission policy adds nothing for them.
+	System bool
+}
+
+// GuestAccess is what is shared with a guest account when it signs in.
+// Documents shared directly or through a folder are checked when they are
+// read.
+type GuestAccess struct {
+	Istice   time.Time
+	Projekti map[int]bool
+}
+
+// SystemContext returns a context for a call made by an operator tool
+// rather than by a logged-in user.
+func SystemContext(ctx context.Context) context.Context {
+	return WithPrincipal(ctx, &Principal{User: &models.User{KorisnickoIme: "system"}, System: true})
+}
+
+// WithPrincipal returns a context carrying the caller of a service call. Log
+// lines written with the context name the caller.
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	if principal != nil && principal.User != nil {
+		ctx = logging.WithUser(ctx, principal.User.KorisnikID, principal.User.KorisnickoIme)
+	}
+	if principal != nil && principal.Impersonation != nil {
+		ctx = logging.WithImpersonator(ctx, principal.Impersonation.AdministratorID, principal.Impersonation.Administrator)
+	}
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// Allows reports whether the credential of the caller may use perm. A
+// session may use every permission of the user's role, an access token only
+// those among its scopes and an impersonation session none that change
+// accounts, roles or sessions.
+func (p *Principal) Allows(perm Permission) bool {
+	if p.Impersonation != nil && securityPermissions[perm] {
+		return false
+	}
+	if p.Token == nil {
+		return true
+	}
+	for _, scope := range p.Token.Opsezi {
+		if Permission(scope) == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// PrincipalFrom returns the caller stored in ctx, if any.
+func PrincipalFrom(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok && principal != nil && principal.User != nil
+}
@@ -5,298 +5,250 @@
 package services
 
 import (
-	"database/sql"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/cane/research-institute-system/backend/config"
 	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/repositories"
 )
 
 type DocumentService struct {
-	db         *sql.DB
-	uploadPath string
+	documents repositories.DocumentStore
+	guests    repositories.GuestStore
+	projects  repositories.ProjectStore
+	units     repositories.UnitStore
+	authz     *Authorizer
+	storage   config.StorageConfig
 }
 
-func NewDocumentService(db *sql.DB) *DocumentService {
-	cfg := config.LoadConfig()
+func NewDocumentService(stores repositories.Stores, authz *Authorizer, storage config.StorageConfig) *DocumentService {
 	return &DocumentService{
-		db:         db,
-		uploadPath: cfg.UploadPath,
+		documents: stores.Documents,
+		guests:    stores.Guests,
+		projects:  stores.Projects,
+		units:     stores.Units,
+		authz:     authz,
+		storage:   storage,
 	}
 }
 
-func (s *DocumentService) GetAllDocuments() ([]models.Dokumenti, error) {
-	query := `
-		SELECT d.dokument_id, d.projekat_id, d.naziv_dokumenta, d.folder_id,
-		       d.opis, d.tip_dokumenta, d.jezik_dokumenta, d.radni_tok_id,
-		       d.trenutna_faza_id, d.kreirao_korisnik_id, d.datuma_postavke,
-		       d.poslednja_izmena,
-		       COALESCE(p.naziv_projekta, '') as naziv_projekta,
-		       k.korisnicko_ime as ime_kreirao,
-		       COALESCE(f.naziv_faze, '') as naziv_faze,
-		       COALESCE(v.version_count, 0) as broj_verzija
-		FROM dokumenti d
-		LEFT JOIN projekti p ON d.projekat_id = p.projekat_id
-		JOIN korisnici k ON d.kreirao_korisnik_id = k.korisnik_id
-		LEFT JOIN faze f ON d.trenutna_faza_id = f.faza_id
-		LEFT JOIN (
-			SELECT dokument_id, COUNT(*) as version_count 
-			FROM verzijedokumenata 
-			GROUP BY dokument_id
-		) v ON d.dokument_id = v.dokument_id
-		ORDER BY d.datuma_postavke DESC
-	`
-
-	rows, err := s.db.Query(query)
+// GetAllDocuments returns the documents outside projects and those of the
+// projects the caller sees.
+func (s *DocumentService) GetAllDocuments(ctx context.Context) ([]models.Dokumenti, error) {
+	if _, err := s.authz.Require(ctx, PermDocumentView); err != nil {
+		return nil, err
+	}
+
+	visible, err := visibleProjectIDs(ctx, s.projects, s.units, s.authz)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var documents []models.Dokumenti
-	for rows.Next() {
-		var doc models.Dokumenti
-		err := rows.Scan(
-			&doc.DokumentID, &doc.ProjekatID, &doc.NazivDokumenta, &doc.FolderID,
-			&doc.Opis, &doc.TipDokumenta, &doc.JezikDokumenta, &doc.RadniTokID,
-			&doc.TrenutnaFazaID, &doc.KreiraoKorisnikID, &doc.DatumaPostavke,
-			&doc.PoslednjaIzmena, &doc.NazivProjekta, &doc.ImeKreirao,
-			&doc.NazivFaze, &doc.BrojVerzija,
-		)
-		if err != nil {
-			return nil, err
-		}
-		documents = append(documents, doc)
+	docs, err := s.documents.GetAll(ctx)
+	if err != nil || visible == nil {
+		return docs, err
 	}
 
-	return documents, nil
+	scoped := []models.Dokumenti{}
+	for _, doc := range docs {
+		if doc.ProjekatID == nil || visible[*doc.ProjekatID] {
+			scoped = append(scoped, doc)
+		}
+	}
+	return scoped, nil
 }
 
-func (s *DocumentService) GetDocumentsByProject(projectID int) ([]models.Dokumenti, error) {
-	query := `
-		SELECT d.dokument_id, d.projekat_id, d.naziv_dokumenta, d.folder_id,
-		       d.opis, d.tip_dokumenta, d.jezik_dokumenta, d.radni_tok_id,
-		       d.trenutna_faza_id, d.kreirao_korisnik_id, d.datuma_postavke,
-		       d.poslednja_izmena,
-		       p.naziv_projekta, k.korisnicko_ime as ime_kreirao,
-		       COALESCE(f.naziv_faze, '') as naziv_faze,
-		       COALESCE(v.version_count, 0) as broj_verzija
-		FROM dokumenti d
-		JOIN projekti p ON d.projekat_id = p.projekat_id
-		JOIN korisnici k ON d.kreirao_korisnik_id = k.korisnik_id
-		LEFT JOIN faze f ON d.trenutna_faza_id = f.faza_id
-		LEFT JOIN (
-			SELECT dokument_id, COUNT(*) as version_count 
-			FROM verzijedokumenata 
-			GROUP BY dokument_id
-		) v ON d.dokument_id = v.dokument_id
-		WHERE d.projekat_id = $1
-		ORDER BY d.datuma_postavke DESC
-	`
-
-	rows, err := s.db.Query(query, projectID)
-	if err != nil {
+func (s *DocumentService) GetDocumentsByProject(ctx context.Context, projectID int) ([]models.Dokumenti, error) {
+	if _, err := s.authz.RequireInProject(ctx, PermDocumentView, projectID); err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var documents []models.Dokumenti
-	for rows.Next() {
-		var doc models.Dokumenti
-		err := rows.Scan(
-			&doc.DokumentID, &doc.ProjekatID, &doc.NazivDokumenta, &doc.FolderID,
-			&doc.Opis, &doc.TipDokumenta, &doc.JezikDokumenta, &doc.RadniTokID,
-			&doc.TrenutnaFazaID, &doc.KreiraoKorisnikID, &doc.DatumaPostavke,
-			&doc.PoslednjaIzmena, &doc.NazivProjekta, &doc.ImeKreirao,
-			&doc.NazivFaze, &doc.BrojVerzija,
-		)
-		if err != nil {
-			return nil, err
-		}
-		documents = append(documents, doc)
+	if err := requireVisibleProject(ctx, s.projects, s.units, s.authz, projectID); err != nil {
+		return nil, err
 	}
 
-	return documents, nil
+	return s.documents.GetByProject(ctx, projectID)
 }
 
-func (s *DocumentService) GetDocumentByID(documentID int) (models.Dokumenti, error) {
-	var doc models.Dokumenti
-	query := `
-		SELECT d.dokument_id, d.projekat_id, d.naziv_dokumenta, d.folder_id,
-		       d.opis, d.tip_dokumenta, d.jezik_dokumenta, d.radni_tok_id,
-		       d.trenutna_faza_id, d.kreirao_korisnik_id, d.datuma_postavke,
-		       d.poslednja_izmena,
-		       COALESCE(p.naziv_projekta, '') as naziv_projekta,
-		       k.korisnicko_ime as ime_kreirao,
-		       COALESCE(f.naziv_faze, '') as naziv_faze
-		FROM dokumenti d
-		LEFT JOIN projekti p ON d.projekat_id = p.projekat_id
-		JOIN korisnici k ON d.kreirao_korisnik_id = k.korisnik_id
-		LEFT JOIN faze f ON d.trenutna_faza_id = f.faza_id
-		WHERE d.dokument_id = $1
-	`
-
-	err := s.db.QueryRow(query, documentID).Scan(
-		&doc.DokumentID, &doc.ProjekatID, &doc.NazivDokumenta, &doc.FolderID,
-		&doc.Opis, &doc.TipDokumenta, &doc.JezikDokumenta, &doc.RadniTokID,
-		&doc.TrenutnaFazaID, &doc.KreiraoKorisnikID, &doc.DatumaPostavke,
-		&doc.PoslednjaIzmena, &doc.NazivProjekta, &doc.ImeKreirao, &doc.NazivFaze,
-	)
-
-	return doc, err
-}
-
-func (s *DocumentService) UploadDocument(req models.UploadDocumentRequest, fileData []byte, fileName string, userID int) error {
-	// Create upload directory if it doesn't exist
-	if err := os.MkdirAll(s.uploadPath, 0755); err != nil {
-		return fmt.Errorf("failed to create upload directory: %w", err)
+func (s *DocumentService) GetDocumentByID(ctx context.Context, documentID int) (models.Dokumenti, error) {
+	if _, err := s.requireDocument(ctx, PermDocumentView, documentID); err != nil {
+		return models.Dokumenti{}, err
 	}
 
-	tx, err := s.db.Begin()
+	doc, err := s.documents.GetByID(ctx, documentID)
 	if err != nil {
-		return err
+		return models.Dokumenti{}, err
+	}
+	if doc.ProjekatID != nil {
+		if err := requireVisibleProject(ctx, s.projects, s.units, s.authz, *doc.ProjekatID); err != nil {
+			return models.Dokumenti{}, err
+		}
 	}
-	defer tx.Rollback()
 
-	// Insert document record
-	var documentID int
-	docQuery := `
-		INSERT INTO dokumenti (projekat_id, naziv_dokumenta, folder_id, opis, 
-		                      tip_dokumenta, jezik_dokumenta, kreirao_korisnik_id)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		RETURNING dokument_id
-	`
+	return *doc, nil
+}
 
-	err = tx.QueryRow(docQuery, req.ProjekatID, req.NazivDokumenta, req.FolderID,
-		req.Opis, req.TipDokumenta, req.JezikDokumenta, userID).Scan(&documentID)
+func (s *DocumentService) UploadDocument(ctx context.Context, req models.UploadDocumentRequest, fileData []byte, fileName string) error {
+	caller, err := s.authz.requireIn(ctx, PermDocumentUpload, func() (int, error) { return projectOf(req.ProjekatID), nil })
 	if err != nil {
 		return err
 	}
 
-	// Generate unique file path
-	timestamp := time.Now().Format("20060102_150405")
-	fileExt := filepath.Ext(fileName)
-	uniqueFileName := fmt.Sprintf("%d_%s_%s%s", documentID, timestamp,
-		strings.ReplaceAll(req.NazivDokumenta, " ", "_"), fileExt)
-	filePath := filepath.Join(s.uploadPath, uniqueFileName)
+	if err := s.checkFile(fileName, len(fileData)); err != nil {
+		return err
+	}
 
-	// Save file to disk
-	file, err := os.Create(filePath)
-	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
+	// Create upload directory if it doesn't exist
+	if err := os.MkdirAll(s.storage.UploadPath, 0755); err != nil {
+		return fmt.Errorf("failed to create upload directory: %w", err)
 	}
-	defer file.Close()
 
-	_, err = io.Copy(file, strings.NewReader(string(fileData)))
-	if err != nil {
+	// The file is written before the record exists, so its name cannot use
+	// the document ID; a random suffix keeps concurrent uploads apart
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	uniqueFileName := fmt.Sprintf("%s_%s_%s", time.Now().Format("20060102_150405"),
+		hex.EncodeToString(suffix), storedFileName(req.NazivDokumenta+filepath.Ext(fileName)))
+	filePath := filepath.Join(s.storage.UploadPath, uniqueFileName)
+
+	if err := os.WriteFile(filePath, fileData, 0644); err != nil {
 		return fmt.Errorf("failed to write file: %w", err)
 	}
 
-	// Calculate file size in MB
+	versionLabel := "1.0"
 	fileSizeMB := float64(len(fileData)) / (1024 * 1024)
 
-	// Insert document version
-	versionQuery := `
-		INSERT INTO verzijedokumenata (dokument_id, verzija_oznaka, putanja_do_fajla, 
-		                               velicina_fajla_mb, postavio_korisnik_id)
-		VALUES ($1, '1.0', $2, $3, $4)
-	`
+	doc := &models.Document{
+		ProjekatID:        req.ProjekatID,
+		NazivDokumenta:    req.NazivDokumenta,
+		FolderID:          req.FolderID,
+		Opis:              &req.Opis,
+		TipDokumenta:      &req.TipDokumenta,
+		JezikDokumenta:    &req.JezikDokumenta,
+		KreiraoKorisnikID: caller.KorisnikID,
+	}
+	version := &models.DocumentVersion{
+		VerzijaOznaka:      &versionLabel,
+		PutanjaDoFajla:     filePath,
+		VelicinafajlaMB:    &fileSizeMB,
+		PostavioKorisnikID: caller.KorisnikID,
+	}
 
-	_, err = tx.Exec(versionQuery, documentID, filePath, fileSizeMB, userID)
-	if err != nil {
+	if err := s.documents.Create(ctx, doc, version, req.Tagovi); err != nil {
+		os.Remove(filePath)
 		return err
 	}
 
-	// Add tags if provided
-	for _, tagName := range req.Tagovi {
-		if err := s.addDocumentTagInTx(tx, documentID, tagName); err != nil {
-			return err
-		}
-	}
+	slog.InfoContext(ctx, "document uploaded", "document_id", doc.DokumentID, "project_id", req.ProjekatID, "bytes", len(fileData))
+	return nil
+}
 
-	return tx.Commit()
+// storedFileName makes name safe to use as part of a file name: path
+// separators and everything else but letters, digits, '-' and '.' become
+// '_', so the file is always written directly into the upload directory.
+func storedFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '.' {
+			return r
+		}
+		return '_'
+	}, name)
 }
 
-func (s *DocumentService) addDocumentTagInTx(tx *sql.Tx, documentID int, tagName string) error {
-	// Check if tag exists, if not create it
-	var tagID int
-	err := tx.QueryRow("SELECT tag_id FROM tagovi WHERE naziv_taga = $1", tagName).Scan(&tagID)
-	if err == sql.ErrNoRows {
-		// Create new tag
-		err = tx.QueryRow("INSERT INTO tagovi (naziv_taga) VALUES ($1) RETURNING tag_id", tagName).Scan(&tagID)
+// requireDocument checks perm within the project of the document. Unknown
+// documents and documents outside projects are in no project. Guests may
+// read the documents shared with them, directly, through a folder or
+// through a project.
+func (s *DocumentService) requireDocument(ctx context.Context, perm Permission, documentID int) (*models.User, error) {
+	if principal, ok := PrincipalFrom(ctx); ok && principal.Guest != nil && guestPermissions[perm] {
+		shared, err := s.guests.GetSharedDocuments(ctx, principal.User.KorisnikID)
 		if err != nil {
-			return err
+			return nil, err
+		}
+		for _, doc := range shared {
+			if doc.DokumentID == documentID {
+				return principal.User, nil
+			}
 		}
-	} else if err != nil {
-		return err
 	}
 
-	// Link tag to document
-	_, err = tx.Exec("INSERT INTO dokumenttagovi (dokument_id, tag_id) VALUES ($1, $2)", documentID, tagID)
-	return err
+	return s.authz.requireIn(ctx, perm, func() (int, error) {
+		doc, err := s.documents.GetByID(ctx, documentID)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		return projectOf(doc.ProjekatID), nil
+	})
 }
 
-func (s *DocumentService) UpdateDocument(documentID int, req models.UploadDocumentRequest) error {
-	query := `
-		UPDATE dokumenti 
-		SET naziv_dokumenta = $1, projekat_id = $2, folder_id = $3, opis = $4,
-		    tip_dokumenta = $5, jezik_dokumenta = $6, poslednja_izmena = CURRENT_TIMESTAMP
-		WHERE dokument_id = $7
-	`
-
-	_, err := s.db.Exec(query, req.NazivDokumenta, req.ProjekatID, req.FolderID,
-		req.Opis, req.TipDokumenta, req.JezikDokumenta, documentID)
-
-	return err
+func projectOf(projectID *int) int {
+	if projectID == nil {
+		return 0
+	}
+	return *projectID
 }
 
-func (s *DocumentService) DeleteDocument(documentID int) error {
-	tx, err := s.db.Begin()
-	if err != nil {
-		return err
+// checkFile applies the storage limits from the configuration.
+func (s *DocumentService) checkFile(fileName string, size int) error {
+	if size == 0 {
+		return invalidInput("fajl je prazan")
 	}
-	defer tx.Rollback()
-
-	// Get all file paths for deletion
-	var filePaths []string
-	versionQuery := `SELECT putanja_do_fajla FROM verzijedokumenata WHERE dokument_id = $1`
-	rows, err := tx.Query(versionQuery, documentID)
-	if err != nil {
-		return err
+	if s.storage.MaxFileSize > 0 && int64(size) > s.storage.MaxFileSize {
+		return invalidInput(fmt.Sprintf("fajl je veći od dozvoljenih %.1f MB", float64(s.storage.MaxFileSize)/(1024*1024)))
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		var filePath string
-		if err := rows.Scan(&filePath); err != nil {
-			return err
+	if len(s.storage.AllowedFileTypes) == 0 {
+		return nil
+	}
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(fileName), "."))
+	for _, allowed := range s.storage.AllowedFileTypes {
+		if ext == allowed {
+			return nil
 		}
-		filePaths = append(filePaths, filePath)
 	}
+	return invalidInput(fmt.Sprintf("tip fajla %q nije dozvoljen (dozvoljeni: %s)", ext, strings.Join(s.storage.AllowedFileTypes, ", ")))
+}
 
-	// Delete document (cascade will handle related records)
-	deleteQuery := `DELETE FROM dokumenti WHERE dokument_id = $1`
-	result, err := tx.Exec(deleteQuery, documentID)
-	if err != nil {
+func (s *DocumentService) UpdateDocument(ctx context.Context, documentID int, req models.UploadDocumentRequest) error {
+	if _, err := s.requireDocument(ctx, PermDocumentUpdate, documentID); err != nil {
 		return err
 	}
-
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
+	// Moving the document needs the permission in the new project as well
+	if _, err := s.authz.requireIn(ctx, PermDocumentUpdate, func() (int, error) { return projectOf(req.ProjekatID), nil }); err != nil {
 		return err
 	}
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("document with ID %d not found", documentID)
+	return s.documents.Update(ctx, &models.Document{
+		DokumentID:     documentID,
+		NazivDokumenta: req.NazivDokumenta,
+		ProjekatID:     req.ProjekatID,
+		FolderID:       req.FolderID,
+		Opis:           &req.Opis,
+		TipDokumenta:   &req.TipDokumenta,
+		JezikDokumenta: &req.JezikDokumenta,
+	})
+}
+
+func (s *DocumentService) DeleteDocument(ctx context.Context, documentID int) error {
+	if _, err := s.requireDocument(ctx, PermDocumentDelete, documentID); err != nil {
+		return err
 	}
 
-	// Commit transaction before deleting files
-	if err := tx.Commit(); err != nil {
+	filePaths, err := s.documents.Delete(ctx, documentID)
+	if err != nil {
 		return err
 	}
 
@@ -304,180 +256,82 @@ func (s *DocumentService) DeleteDocument(documentID int) error {
 	for _, filePath := range filePaths {
 		if err := os.Remove(filePath); err != nil {
 			// Log error but don't fail the operation
-			fmt.Printf("Warning: failed to delete file %s: %v\n", filePath, err)
+			slog.WarnContext(ctx, "document file not removed", "document_id", documentID, "path", filePath, "error", err)
 		}
 	}
 
+	slog.InfoContext(ctx, "document deleted", "document_id", documentID, "files", len(filePaths))
 	return nil
 }
 
-func (s *DocumentService) GetDocumentVersions(documentID int) ([]models.VerzijeDokumenata, error) {
-	query := `
-		SELECT v.verzija_id, v.dokument_id, v.verzija_oznaka, v.putanja_do_fajla,
-		       v.velicina_fajla_mb, v.postavio_korisnik_id, v.datuma_postavke
-		FROM verzijedokumenata v
-		WHERE v.dokument_id = $1
-		ORDER BY v.datuma_postavke DESC
-	`
-
-	rows, err := s.db.Query(query, documentID)
-	if err != nil {
+func (s *DocumentService) GetDocumentVersions(ctx context.Context, documentID int) ([]models.VerzijeDokumenata, error) {
+	if _, err := s.GetDocumentByID(ctx, documentID); err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	var versions []models.VerzijeDokumenata
-	for rows.Next() {
-		var version models.VerzijeDokumenata
-		err := rows.Scan(
-			&version.VerzijaID, &version.DokumentID, &version.VerzijaOznaka,
-			&version.PutanjaDoFajla, &version.VelicinafajlaMB, &version.PostavioKorisnikID,
-			&version.DatumaPostavke,
-		)
-		if err != nil {
-			return nil, err
-		}
-		versions = append(versions, version)
-	}
-
-	return versions, nil
+	return s.documents.GetVersions(ctx, documentID)
 }
 
-func (s *DocumentService) GetDocumentTags(documentID int) ([]models.Tagovi, error) {
-	query := `
-		SELECT t.tag_id, t.naziv_taga
-		FROM tagovi t
-		JOIN dokumenttagovi dt ON t.tag_id = dt.tag_id
-		WHERE dt.dokument_id = $1
-		ORDER BY t.naziv_taga
-	`
-
-	rows, err := s.db.Query(query, documentID)
-	if err != nil {
+func (s *DocumentService) GetDocumentTags(ctx context.Context, documentID int) ([]models.Tagovi, error) {
+	if _, err := s.GetDocumentByID(ctx, documentID); err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	var tags []models.Tagovi
-	for rows.Next() {
-		var tag models.Tagovi
-		err := rows.Scan(&tag.TagID, &tag.NazivTaga)
-		if err != nil {
-			return nil, err
-		}
-		tags = append(tags, tag)
-	}
-
-	return tags, nil
+	return s.documents.GetTags(ctx, documentID)
 }
 
-func (s *DocumentService) AddDocumentTag(documentID int, tagName string) error {
-	tx, err := s.db.Begin()
-	if err != nil {
+func (s *DocumentService) AddDocumentTag(ctx context.Context, documentID int, tagName string) error {
+	if _, err := s.requireDocument(ctx, PermDocumentUpdate, documentID); err != nil {
 		return err
 	}
-	defer tx.Rollback()
 
-	err = s.addDocumentTagInTx(tx, documentID, tagName)
-	if err != nil {
+	return s.documents.AddTag(ctx, documentID, tagName)
+}
+
+func (s *DocumentService) RemoveDocumentTag(ctx context.Context, documentID, tagID int) error {
+	if _, err := s.requireDocument(ctx, PermDocumentUpdate, documentID); err != nil {
 		return err
 	}
 
-	return tx.Commit()
+	return s.documents.RemoveTag(ctx, documentID, tagID)
 }
 
-func (s *DocumentService) RemoveDocumentTag(documentID, tagID int) error {
-	query := `DELETE FROM dokumenttagovi WHERE dokument_id = $1 AND tag_id = $2`
-	_, err := s.db.Exec(query, documentID, tagID)
-	return err
-}
-
-func (s *DocumentService) GetDocumentMetadata(documentID int) ([]models.MetaPodaci, error) {
-	query := `
-		SELECT meta_id, dokument_id, kljuc, vrednost
-		FROM metapodaci
-		WHERE dokument_id = $1
-		ORDER BY kljuc
-	`
-
-	rows, err := s.db.Query(query, documentID)
-	if err != nil {
+func (s *DocumentService) GetDocumentMetadata(ctx context.Context, documentID int) ([]models.MetaPodaci, error) {
+	if _, err := s.GetDocumentByID(ctx, documentID); err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	var metadata []models.MetaPodaci
-	for rows.Next() {
-		var meta models.MetaPodaci
-		err := rows.Scan(&meta.MetaID, &meta.DokumentID, &meta.Kljuc, &meta.Vrednost)
-		if err != nil {
-			return nil, err
-		}
-		metadata = append(metadata, meta)
-	}
-
-	return metadata, nil
+	return s.documents.GetMetadata(ctx, documentID)
 }
 
-func (s *DocumentService) UpdateDocumentMetadata(documentID int, metadata []models.MetaPodaci) error {
-	tx, err := s.db.Begin()
-	if err != nil {
+func (s *DocumentService) UpdateDocumentMetadata(ctx context.Context, documentID int, metadata []models.MetaPodaci) error {
+	if _, err := s.requireDocument(ctx, PermDocumentUpdate, documentID); err != nil {
 		return err
 	}
-	defer tx.Rollback()
 
-	// Delete existing metadata
-	_, err = tx.Exec("DELETE FROM metapodaci WHERE dokument_id = $1", documentID)
-	if err != nil {
-		return err
-	}
+	return s.documents.ReplaceMetadata(ctx, documentID, metadata)
+}
 
-	// Insert new metadata
-	for _, meta := range metadata {
-		_, err = tx.Exec("INSERT INTO metapodaci (dokument_id, kljuc, vrednost) VALUES ($1, $2, $3)",
-			documentID, meta.Kljuc, meta.Vrednost)
-		if err != nil {
-			return err
-		}
+// GetAllFolders returns the folders owned by the caller
+func (s *DocumentService) GetAllFolders(ctx context.Context) ([]models.Folderi, error) {
+	caller, err := s.authz.Require(ctx, PermDocumentView)
+	if err != nil {
+		return nil, err
 	}
 
-	return tx.Commit()
+	return s.documents.GetFolders(ctx, caller.KorisnikID)
 }
 
-func (s *DocumentService) GetAllFolders(userID int) ([]models.Folderi, error) {
-	query := `
-		SELECT folder_id, naziv_foldera, roditelj_folder_id, vlasnik_id
-		FROM folderi
-		WHERE vlasnik_id = $1
-		ORDER BY naziv_foldera
-	`
-
-	rows, err := s.db.Query(query, userID)
+func (s *DocumentService) CreateFolder(ctx context.Context, folder models.Folderi) error {
+	caller, err := s.authz.Require(ctx, PermDocumentUpload)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer rows.Close()
 
-	var folders []models.Folderi
-	for rows.Next() {
-		var folder models.Folderi
-		err := rows.Scan(&folder.FolderID, &folder.NazivFoldera,
-			&folder.RoditeljFolderID, &folder.VlasnikID)
-		if err != nil {
-			return nil, err
-		}
-		folders = append(folders, folder)
+	// Folders without an explicit owner belong to the caller
+	if folder.VlasnikID == 0 {
+		folder.VlasnikID = caller.KorisnikID
 	}
 
-	return folders, nil
-}
-
-func (s *DocumentService) CreateFolder(folder models.Folderi) error {
-	query := `
-		INSERT INTO folderi (naziv_foldera, roditelj_folder_id, vlasnik_id)
-		VALUES ($1, $2, $3)
-	`
-
-	_, err := s.db.Exec(query, folder.NazivFoldera, folder.RoditeljFolderID, folder.VlasnikID)
-	return err
+	return s.documents.CreateFolder(ctx, &folder)
 }
@@ -0,0 +1,37 @@
+// ============================================================================
+// errors.go - Error kinds shared by the services
+// ============================================================================
+
+package services
+
+import (
+	"errors"
+
+	"github.com/cane/research-institute-system/backend/repositories"
+)
+
+// ErrInvalidInput marks (wrapped) errors caused by the caller's input. Their
+// message is meant for the user and is shown as is.
+var ErrInvalidInput = errors.New("neispravan unos")
+
+// userError carries a message for the user and the kind of failure it is, so
+// callers can match it with errors.Is without parsing the text.
+type userError struct {
+	message string
+	kind    error
+}
+
+func (e *userError) Error() string { return e.message }
+
+func (e *userError) Is(target error) bool { return target == e.kind }
+
+// invalidInput reports a validation failure.
+func invalidInput(message string) error {
+	return &userError{message: message, kind: ErrInvalidInput}
+}
+
+// conflict reports a change that contradicts stored data. It matches
+// repositories.ErrConflict, like conflicts reported by the stores.
+func conflict(message string) error {
+	return &userError{message: message, kind: repositories.ErrConflict}
+}
@@ -0,0 +1,360 @@
+// ============================================================================
+// guest_service.go - Time-limited guest accounts of external partners
+// ============================================================================
+
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/cane/research-institute-system/backend/config"
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/repositories"
+)
+
+// GuestRequest describes a new guest account and when it ends.
+type GuestRequest struct {
+	KorisnickoIme string    `json:"korisnicko_ime"`
+	Email         string    `json:"email"`
+	Ime           *string   `json:"ime"`
+	Prezime       *string   `json:"prezime"`
+	Istice        time.Time `json:"istice" ts_type:"string"`
+}
+
+// GuestShareRequest names the one project, folder or document to share.
+type GuestShareRequest struct {
+	ProjekatID *int `json:"projekat_id"`
+	FolderID   *int `json:"folder_id"`
+	DokumentID *int `json:"dokument_id"`
+}
+
+// GuestOverview is an active guest account with everything shared with it.
+type GuestOverview struct {
+	Korisnik models.User         `json:"korisnik"`
+	Deljenja []models.GuestShare `json:"deljenja"`
+}
+
+// SharedWithGuest is what a guest sees: the shared projects, whose tasks
+// and documents it may read, and every document it may read.
+type SharedWithGuest struct {
+	Istice    time.Time          `json:"istice" ts_type:"string"`
+	Projekti  []models.Projekti  `json:"projekti"`
+	Dokumenti []models.Dokumenti `json:"dokumenti"`
+}
+
+// GuestService creates guest accounts for project partners, shares
+// projects, folders and documents with them and ends them when they expire.
+type GuestService struct {
+	guests    repositories.GuestStore
+	users     repositories.UserStore
+	roles     repositories.RoleStore
+	projects  repositories.ProjectStore
+	documents repositories.DocumentStore
+	activity  repositories.AnalyticsStore
+	auth      *AuthService
+	authz     *Authorizer
+	cfg       config.AuthConfig
+}
+
+func NewGuestService(stores repositories.Stores, auth *AuthService, authz *Authorizer, cfg config.AuthConfig) *GuestService {
+	return &GuestService{
+		guests:    stores.Guests,
+		users:     stores.Users,
+		roles:     stores.Roles,
+		projects:  stores.Projects,
+		documents: stores.Documents,
+		activity:  stores.Analytics,
+		auth:      auth,
+		authz:     authz,
+		cfg:       cfg,
+	}
+}
+
+// CreateGuest creates a guest account that ends at req.Istice, at most
+// auth.guest_max_ttl from now. Like other new accounts it is activated with
+// the returned code. Nothing is shared with it yet.
+func (s *GuestService) CreateGuest(ctx context.Context, req GuestRequest) (*ActivationCode, error) {
+	if _, err := s.authz.Require(ctx, PermGuestManage); err != nil {
+		return nil, err
+	}
+	if err := s.checkExpiry(req.Istice); err != nil {
+		return nil, err
+	}
+
+	// The guest role grants nothing; guests read only what is shared with them
+	role, err := systemRole(ctx, s.roles, RoleGuest)
+	if err != nil {
+		return nil, err
+	}
+
+	until := req.Istice.UTC()
+	user := &models.User{
+		KorisnickoIme: req.KorisnickoIme,
+		Email:         req.Email,
+		Ime:           req.Ime,
+		Prezime:       req.Prezime,
+		UlogaID:       role.UlogaID,
+		GostDo:        &until,
+	}
+	code, err := s.auth.createUser(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "guest account created", "target_user_id", user.KorisnikID, "expires", until)
+	return code, nil
+}
+
+// ExtendGuest moves the end of an active guest account, earlier or later,
+// within auth.guest_max_ttl from now.
+func (s *GuestService) ExtendGuest(ctx context.Context, userID int, until time.Time) error {
+	if _, err := s.authz.Require(ctx, PermGuestManage); err != nil {
+		return err
+	}
+	guest, err := s.activeGuest(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkExpiry(until); err != nil {
+		return err
+	}
+
+	if err := s.guests.SetExpiry(ctx, userID, until); err != nil {
+		return err
+	}
+
+	audit(ctx, s.activity, ActivityGuestExtended, userID, fmt.Sprintf("Gostujući nalog %s važi do %s",
+		guest.KorisnickoIme, until.Format("2006-01-02 15:04")))
+	slog.InfoContext(ctx, "guest account extended", "target_user_id", userID, "expires", until)
+	return nil
+}
+
+// ShareWithGuest shares one project, folder or document with an active
+// guest. A project brings its tasks and documents, a folder its subfolders.
+// The guest sees it from its next request.
+func (s *GuestService) ShareWithGuest(ctx context.Context, userID int, req GuestShareRequest) (*models.GuestShare, error) {
+	caller, err := s.authz.Require(ctx, PermGuestManage)
+	if err != nil {
+		return nil, err
+	}
+	guest, err := s.activeGuest(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := 0
+	for _, id := range []*int{req.ProjekatID, req.FolderID, req.DokumentID} {
+		if id != nil {
+			targets++
+		}
+	}
+	if targets != 1 {
+		return nil, invalidInput("podelite tačno jedan projekat, folder ili dokument")
+	}
+	switch {
+	case req.ProjekatID != nil:
+		_, err = s.projects.GetByID(ctx, *req.ProjekatID)
+	case req.FolderID != nil:
+		_, err = s.documents.GetFolder(ctx, *req.FolderID)
+	default:
+		_, err = s.documents.GetByID(ctx, *req.DokumentID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	share := &models.GuestShare{
+		KorisnikID: userID,
+		ProjekatID: req.ProjekatID,
+		FolderID:   req.FolderID,
+		DokumentID: req.DokumentID,
+	}
+	if caller.KorisnikID != 0 {
+		share.OdobrioID = &caller.KorisnikID
+	}
+	if err := s.guests.Share(ctx, share); err != nil {
+		if errors.Is(err, repositories.ErrConflict) {
+			return nil, conflict("ovo je već podeljeno sa gostom")
+		}
+		return nil, err
+	}
+	stored, err := s.guests.GetShare(ctx, share.PristupID)
+	if err != nil {
+		return nil, err
+	}
+
+	audit(ctx, s.activity, ActivityGuestShared, userID, fmt.Sprintf("Gostu %s podeljen %s %s",
+		guest.KorisnickoIme, shareKind(stored), stored.Naziv))
+	slog.InfoContext(ctx, "shared with guest", "target_user_id", userID, "share_id", stored.PristupID)
+	return stored, nil
+}
+
+// RevokeGuestShare takes back one share. The guest loses it from its next
+// request.
+func (s *GuestService) RevokeGuestShare(ctx context.Context, shareID int) error {
+	if _, err := s.authz.Require(ctx, PermGuestManage); err != nil {
+		return err
+	}
+
+	share, err := s.guests.GetShare(ctx, shareID)
+	if err != nil {
+		return err
+	}
+	if err := s.guests.Unshare(ctx, shareID); err != nil {
+		return err
+	}
+
+	audit(ctx, s.activity, ActivityGuestUnshared, share.KorisnikID, fmt.Sprintf("Gostu %s ukinut pristup: %s %s",
+		share.KorisnickoIme, shareKind(share), share.Naziv))
+	slog.InfoContext(ctx, "guest share revoked", "target_user_id", share.KorisnikID, "share_id", shareID)
+	return nil
+}
+
+// GetGuestAccess returns every active guest account with its shares, the
+// ones that end first at the top.
+func (s *GuestService) GetGuestAccess(ctx context.Context) ([]GuestOverview, error) {
+	if _, err := s.authz.Require(ctx, PermGuestManage); err != nil {
+		return nil, err
+	}
+
+	users, err := s.users.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	shares, err := s.guests.GetAllShares(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byGuest := map[int][]models.GuestShare{}
+	for _, share := range shares {
+		byGuest[share.KorisnikID] = append(byGuest[share.KorisnikID], share)
+	}
+
+	guests := []GuestOverview{}
+	for _, user := range users {
+		if user.GostDo == nil || user.Status != "aktivan" {
+			continue
+		}
+		deljenja := byGuest[user.KorisnikID]
+		if deljenja == nil {
+			deljenja = []models.GuestShare{}
+		}
+		guests = append(guests, GuestOverview{Korisnik: user, Deljenja: deljenja})
+	}
+	sort.SliceStable(guests, func(i, j int) bool { return guests[i].Korisnik.GostDo.Before(*guests[j].Korisnik.GostDo) })
+	return guests, nil
+}
+
+// GetSharedWithMe returns what is shared with the calling guest.
+func (s *GuestService) GetSharedWithMe(ctx context.Context) (*SharedWithGuest, error) {
+	principal, ok := PrincipalFrom(ctx)
+	if !ok {
+		return nil, ErrNoSession
+	}
+	if principal.Guest == nil {
+		return nil, invalidInput("samo gostujući nalozi imaju podeljen sadržaj")
+	}
+
+	shared := &SharedWithGuest{Istice: principal.Guest.Istice, Projekti: []models.Projekti{}}
+	for projectID := range principal.Guest.Projekti {
+		project, err := s.projects.GetByID(ctx, projectID)
+		if errors.Is(err, repositories.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		shared.Projekti = append(shared.Projekti, *project)
+	}
+	sort.Slice(shared.Projekti, func(i, j int) bool { return shared.Projekti[i].NazivProjekta < shared.Projekti[j].NazivProjekta })
+
+	documents, err := s.guests.GetSharedDocuments(ctx, principal.User.KorisnikID)
+	if err != nil {
+		return nil, err
+	}
+	shared.Dokumenti = documents
+	return shared, nil
+}
+
+// SweepExpiredGuests deactivates the guest accounts that have ended, removes
+// their shares and ends their sessions. It returns how many ended.
+func (s *GuestService) SweepExpiredGuests(ctx context.Context) (int, error) {
+	if _, err := s.authz.Require(ctx, PermGuestManage); err != nil {
+		return 0, err
+	}
+
+	expired, err := s.guests.ExpireGuests(ctx, s.auth.now())
+	if err != nil {
+		return 0, err
+	}
+	for _, userID := range expired {
+		sessions := s.auth.revokeUserSessions(ctx, userID, "")
+		audit(ctx, s.activity, ActivityGuestExpired, userID, "Gostujući nalog je istekao, pristup je uklonjen")
+		slog.InfoContext(ctx, "guest account expired", "target_user_id", userID, "sessions", sessions)
+	}
+	return len(expired), nil
+}
+
+// RunGuestSweep calls SweepExpiredGuests at once and then every interval
+// until ctx ends. The server and the desktop app run it in the background.
+func (s *GuestService) RunGuestSweep(ctx context.Context, interval time.Duration) {
+	ctx = SystemContext(ctx)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.SweepExpiredGuests(ctx); err != nil {
+			slog.ErrorContext(ctx, "guest sweep failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// activeGuest returns the user if it is a guest account that has not ended.
+func (s *GuestService) activeGuest(ctx context.Context, userID int) (*models.User, error) {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.GostDo == nil {
+		return nil, invalidInput(fmt.Sprintf("korisnik %s nema gostujući nalog", user.KorisnickoIme))
+	}
+	if user.Status != "aktivan" || s.auth.guestExpired(user) {
+		return nil, conflict(fmt.Sprintf("gostujući nalog %s je istekao", user.KorisnickoIme))
+	}
+	return user, nil
+}
+
+// checkExpiry accepts an end of a guest account in the future and within
+// auth.guest_max_ttl.
+func (s *GuestService) checkExpiry(until time.Time) error {
+	now := s.auth.now()
+	if !until.After(now) {
+		return invalidInput("gostujući nalog mora isticati u budućnosti")
+	}
+	if limit := now.Add(s.cfg.GuestMaxTTL); until.After(limit) {
+		return invalidInput(fmt.Sprintf("gostujući nalog može važiti najviše do %s", limit.Format("2006-01-02 15:04")))
+	}
+	return nil
+}
+
+// shareKind names what a share shares, for the activity log.
+func shareKind(share *models.GuestShare) string {
+	switch {
+	case share.ProjekatID != nil:
+		return "projekat"
+	case share.FolderID != nil:
+		return "folder"
+	default:
+		return "dokument"
+	}
+}
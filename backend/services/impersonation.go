@@ -0,0 +1,140 @@
+// ============================================================================
+// impersonation.go - Administrators acting as other users
+// ============================================================================
+
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/cane/research-institute-system/backend/models"
+)
+
+// Impersonation describes a session in which an administrator acts as
+// another user, to see the system the way that user does.
+type Impersonation struct {
+	AdministratorID int       `json:"administrator_id"`
+	Administrator   string    `json:"administrator"`
+	KorisnikID      int       `json:"korisnik_id"`
+	KorisnickoIme   string    `json:"korisnicko_ime"`
+	Razlog          string    `json:"razlog"`
+	Pocetak         time.Time `json:"pocetak" ts_type:"string"`
+	Istice          time.Time `json:"istice" ts_type:"string"`
+}
+
+// StartImpersonation opens a session in which the calling administrator
+// acts as the user, for at most auth.impersonation_ttl. The caller's own
+// session stays valid. Accounts that may impersonate others cannot be
+// impersonated, so an administrator cannot borrow another one's identity.
+func (s *AuthService) StartImpersonation(ctx context.Context, userID int, reason string) (*LoginResponse, error) {
+	admin, err := s.authz.Require(ctx, PermUserImpersonate)
+	if err != nil {
+		return nil, err
+	}
+	principal, _ := PrincipalFrom(ctx)
+	if principal.System {
+		return nil, invalidInput("sistemski pozivalac ne radi u ime korisnika")
+	}
+	if principal.Token != nil {
+		return nil, fmt.Errorf("%w (pristupnim tokenom se ne radi u ime drugog korisnika)", ErrForbidden)
+	}
+
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, invalidInput("razlog je obavezan")
+	}
+	if userID == admin.KorisnikID {
+		return nil, invalidInput("ne možete raditi u svoje ime")
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Status != "aktivan" {
+		return nil, invalidInput("nalog korisnika nije aktivan")
+	}
+	if s.authz.Can(user, PermUserImpersonate) {
+		return nil, fmt.Errorf("%w (ne radi se u ime korisnika koji i sam može da radi u ime drugih)", ErrForbidden)
+	}
+
+	now := s.now()
+	impersonation := Impersonation{
+		AdministratorID: admin.KorisnikID,
+		Administrator:   admin.KorisnickoIme,
+		KorisnikID:      user.KorisnikID,
+		KorisnickoIme:   user.KorisnickoIme,
+		Razlog:          reason,
+		Pocetak:         now,
+		Istice:          now.Add(s.cfg.ImpersonationTTL),
+	}
+
+	user.HashSifre = ""
+	response, err := s.issueSession(user, "Radite u ime korisnika "+user.KorisnickoIme,
+		func(u *models.User) (string, Session, error) { return s.sessions.CreateImpersonation(u, impersonation) })
+	if err != nil {
+		return nil, err
+	}
+	response.Zastupanje = &impersonation
+
+	audit(ctx, s.activity, ActivityImpersonationStarted, user.KorisnikID,
+		fmt.Sprintf("%s radi u ime korisnika %s do %s: %s", admin.KorisnickoIme, user.KorisnickoIme,
+			impersonation.Istice.Format("2006-01-02 15:04"), reason))
+	slog.InfoContext(ctx, "impersonation started", "target_user_id", user.KorisnikID, "until", impersonation.Istice)
+	return response, nil
+}
+
+// StopImpersonation ends the impersonation session holding token, which must
+// be the caller's. The administrator continues in the own session.
+func (s *AuthService) StopImpersonation(ctx context.Context, token string) error {
+	principal, ok := PrincipalFrom(ctx)
+	if !ok {
+		return ErrNoSession
+	}
+	if principal.Impersonation == nil {
+		return invalidInput("ne radite u ime drugog korisnika")
+	}
+
+	s.sessions.Revoke(token)
+	audit(ctx, s.activity, ActivityImpersonationEnded, principal.User.KorisnikID,
+		fmt.Sprintf("%s više ne radi u ime korisnika %s", principal.Impersonation.Administrator, principal.User.KorisnickoIme))
+	slog.InfoContext(ctx, "impersonation ended", "target_user_id", principal.User.KorisnikID)
+	return nil
+}
+
+// RecordImpersonatedCall writes a call made in an impersonation session to
+// the activity log, under the user acted as and the administrator. Other
+// calls are not recorded.
+func (s *AuthService) RecordImpersonatedCall(ctx context.Context, call string) {
+	if principal, ok := PrincipalFrom(ctx); ok && principal.Impersonation != nil {
+		audit(ctx, s.activity, ActivityImpersonatedCall, principal.User.KorisnikID, call)
+	}
+}
+
+// impersonationPrincipal returns the caller of an impersonation session. The
+// administrator is checked on every call too, so the session ends once the
+// administrator is deactivated or loses the permission.
+func (s *AuthService) impersonationPrincipal(ctx context.Context, token string, session *Session, user *models.User) (*Principal, error) {
+	admin, err := s.userRepo.GetByID(ctx, session.Zastupanje.AdministratorID)
+	if err != nil || admin.Status != "aktivan" || session.revokedBefore(admin) || !s.authz.Can(admin, PermUserImpersonate) || s.authz.Can(user, PermUserImpersonate) {
+		s.sessions.Revoke(token)
+		slog.InfoContext(ctx, "impersonation session revoked, no longer allowed",
+			"user_id", session.KorisnikID, "impersonator_id", session.Zastupanje.AdministratorID)
+		return nil, ErrNoSession
+	}
+
+	return &Principal{User: user, Impersonation: session.Zastupanje}, nil
+}
+
+// refuseImpersonated refuses password and other security changes while an
+// administrator acts as another user.
+func refuseImpersonated(ctx context.Context) error {
+	if principal, ok := PrincipalFrom(ctx); ok && principal.Impersonation != nil {
+		return fmt.Errorf("%w (nije dozvoljeno dok radite u ime drugog korisnika)", ErrForbidden)
+	}
+	return nil
+}
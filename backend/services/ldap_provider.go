@@ -0,0 +1,234 @@
+// ============================================================================
+// ldap_provider.go - Sign-in against an LDAP directory
+// ============================================================================
+
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+
+	"github.com/cane/research-institute-system/backend/config"
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/repositories"
+)
+
+// LDAPProvider checks passwords by binding to the directory as the user. It
+// creates the accounts of directory users at their first sign-in and keeps
+// their names, e-mail and role in step with the directory.
+type LDAPProvider struct {
+	cfg      config.LDAPConfig
+	users    repositories.UserStore
+	activity repositories.AnalyticsStore
+}
+
+func NewLDAPProvider(cfg config.LDAPConfig, stores repositories.Stores) *LDAPProvider {
+	return &LDAPProvider{cfg: cfg, users: stores.Users, activity: stores.Analytics}
+}
+
+func (p *LDAPProvider) Name() string {
+	return models.IzvorLDAP
+}
+
+// directoryUser is the directory entry of a user who has just bound. Names
+// missing from the entry are nil.
+type directoryUser struct {
+	email               string
+	firstName, lastName *string
+	groups              []string
+}
+
+func (p *LDAPProvider) Authenticate(ctx context.Context, user *models.User, username, password string) (*models.User, error) {
+	// An empty password is an anonymous bind, which most directories accept
+	if password == "" {
+		return nil, fmt.Errorf("empty password: %w", ErrInvalidCredentials)
+	}
+
+	entry, err := p.bind(username, password)
+	if err != nil {
+		return nil, err
+	}
+
+	manageRoles := len(p.cfg.GroupRoles) > 0 || p.cfg.DefaultRole != ""
+	roleID := 0
+	if manageRoles {
+		roleID, err = p.role(ctx, entry.groups)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if user == nil {
+		return p.create(ctx, username, entry, roleID)
+	}
+
+	if !manageRoles {
+		roleID = user.UlogaID
+	}
+	email := entry.email
+	if email == "" {
+		email = user.Email
+	}
+	if user.Email == email && sameName(user.Ime, entry.firstName) && sameName(user.Prezime, entry.lastName) && user.UlogaID == roleID {
+		return user, nil
+	}
+
+	previousRoleID, previousRole := user.UlogaID, user.NazivUloge
+	user.Email, user.Ime, user.Prezime, user.UlogaID = email, entry.firstName, entry.lastName, roleID
+	if err := p.users.Update(ctx, user); err != nil {
+		return nil, err
+	}
+	slog.InfoContext(ctx, "directory user synced", "target_user_id", user.KorisnikID, "role_id", roleID)
+
+	// The role name was read with the old role
+	synced, err := p.users.GetByID(ctx, user.KorisnikID)
+	if err != nil {
+		return nil, err
+	}
+	if roleID != previousRoleID {
+		audit(ctx, p.activity, ActivityRoleAssigned, user.KorisnikID,
+			fmt.Sprintf("Dodeljena uloga %s iz imenika, ranije %s", synced.NazivUloge, previousRole))
+	}
+	return synced, nil
+}
+
+// bind finds the entry of username and binds as it with password.
+func (p *LDAPProvider) bind(username, password string) (*directoryUser, error) {
+	conn, err := ldap.DialURL(p.cfg.URL, ldap.DialWithDialer(&net.Dialer{Timeout: p.cfg.Timeout}))
+	if err != nil {
+		return nil, fmt.Errorf("connect to directory: %w", err)
+	}
+	defer conn.Close()
+	conn.SetTimeout(p.cfg.Timeout)
+
+	if p.cfg.StartTLS {
+		if err := conn.StartTLS(nil); err != nil {
+			return nil, fmt.Errorf("start TLS: %w", err)
+		}
+	}
+
+	if p.cfg.BindDN != "" {
+		if err := conn.Bind(p.cfg.BindDN, p.cfg.BindPassword); err != nil {
+			return nil, fmt.Errorf("bind as %s: %w", p.cfg.BindDN, err)
+		}
+	}
+
+	result, err := conn.Search(ldap.NewSearchRequest(
+		p.cfg.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, 0, false,
+		fmt.Sprintf(p.cfg.UserFilter, ldap.EscapeFilter(username)),
+		[]string{"mail", "givenName", "sn", p.cfg.GroupAttribute},
+		nil,
+	))
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, fmt.Errorf("search for %s: %w", username, err)
+	}
+	if len(result.Entries) != 1 {
+		return nil, fmt.Errorf("%d directory entries for %s: %w", len(result.Entries), username, ErrInvalidCredentials)
+	}
+
+	entry := result.Entries[0]
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, fmt.Errorf("wrong directory password: %w", ErrInvalidCredentials)
+		}
+		return nil, fmt.Errorf("bind as %s: %w", entry.DN, err)
+	}
+
+	return &directoryUser{
+		email:     entry.GetAttributeValue("mail"),
+		firstName: attribute(entry, "givenName"),
+		lastName:  attribute(entry, "sn"),
+		groups:    entry.GetAttributeValues(p.cfg.GroupAttribute),
+	}, nil
+}
+
+// attribute returns the first value of name, or nil if the entry has none.
+func attribute(entry *ldap.Entry, name string) *string {
+	value := entry.GetAttributeValue(name)
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+func sameName(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// role returns the ID of the role the groups map to.
+func (p *LDAPProvider) role(ctx context.Context, groups []string) (int, error) {
+	name := p.cfg.DefaultRole
+	for _, mapping := range p.cfg.GroupRoles {
+		group, role, _ := strings.Cut(mapping, ":")
+		if memberOf(groups, group) {
+			name = role
+			break
+		}
+	}
+	if name == "" {
+		return 0, fmt.Errorf("no mapped group: %w", ErrInvalidCredentials)
+	}
+
+	roles, err := p.users.GetRoles(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, role := range roles {
+		if strings.EqualFold(role.NazivUloge, name) {
+			return role.UlogaID, nil
+		}
+	}
+	return 0, fmt.Errorf("mapped role %q does not exist", name)
+}
+
+// memberOf reports whether groups holds group, by DN or by the first value
+// of the DN.
+func memberOf(groups []string, group string) bool {
+	for _, value := range groups {
+		name := value
+		if dn, err := ldap.ParseDN(value); err == nil && len(dn.RDNs) > 0 && len(dn.RDNs[0].Attributes) > 0 {
+			name = dn.RDNs[0].Attributes[0].Value
+		}
+		if strings.EqualFold(name, group) || strings.EqualFold(value, group) {
+			return true
+		}
+	}
+	return false
+}
+
+// create provisions the account of a directory user signing in for the
+// first time.
+func (p *LDAPProvider) create(ctx context.Context, username string, entry *directoryUser, roleID int) (*models.User, error) {
+	if !p.cfg.Provision {
+		return nil, fmt.Errorf("provisioning disabled: %w", ErrInvalidCredentials)
+	}
+	if roleID == 0 {
+		return nil, fmt.Errorf("no role to provision with: %w", ErrInvalidCredentials)
+	}
+	if entry.email == "" {
+		return nil, fmt.Errorf("directory entry of %s has no mail", username)
+	}
+
+	user := &models.User{
+		KorisnickoIme: username,
+		Email:         entry.email,
+		HashSifre:     unusablePassword,
+		Ime:           entry.firstName,
+		Prezime:       entry.lastName,
+		UlogaID:       roleID,
+		Status:        "aktivan",
+		IzvorPrijave:  models.IzvorLDAP,
+	}
+	if err := p.users.Create(ctx, user); err != nil {
+		return nil, fmt.Errorf("provision %s: %w", username, err)
+	}
+	return p.users.GetByID(ctx, user.KorisnikID)
+}
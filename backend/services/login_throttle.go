@@ -0,0 +1,160 @@
+// ============================================================================
+// login_throttle.go - Failed sign-in limits per client address
+// ============================================================================
+
+package services
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cane/research-institute-system/backend/config"
+)
+
+// LocalSource is the source of sign-ins from the desktop app.
+const LocalSource = "local"
+
+// LoginThrottle counts failed sign-ins per source (the client address of an
+// API request, LocalSource for the desktop app), so one client cannot try
+// passwords against many accounts. Counts live in memory and are forgotten
+// once a source has had no failure for the lockout duration; stale sources
+// are swept as new ones arrive, so the map stays near the number of live
+// sources. It is safe for concurrent use.
+type LoginThrottle struct {
+	mu      sync.Mutex
+	sources map[string]*sourceFailures
+	sweepAt int // map size at which Fail next drops stale sources
+	cfg     config.AuthConfig
+	now     func() time.Time
+}
+
+// minSweep is the smallest map size that triggers a sweep.
+const minSweep = 64
+
+type sourceFailures struct {
+	count       int
+	last        time.Time
+	lockedUntil time.Time
+}
+
+func NewLoginThrottle(cfg config.AuthConfig) *LoginThrottle {
+	return &LoginThrottle{
+		sources: make(map[string]*sourceFailures),
+		sweepAt: minSweep,
+		cfg:     cfg,
+		now:     time.Now,
+	}
+}
+
+// Wait returns how long the source must wait before its next attempt is
+// considered, and whether the wait is a lockout rather than a delay.
+func (t *LoginThrottle) Wait(source string) (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	failures := t.current(source)
+	if failures == nil {
+		return 0, false
+	}
+
+	now := t.now()
+	if now.Before(failures.lockedUntil) {
+		return failures.lockedUntil.Sub(now), true
+	}
+	if next := failures.last.Add(loginDelay(t.cfg, failures.count)); now.Before(next) {
+		return next.Sub(now), false
+	}
+	return 0, false
+}
+
+// Fail counts a failed sign-in from the source and reports whether it has
+// just been blocked.
+func (t *LoginThrottle) Fail(source string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	failures := t.current(source)
+	if failures == nil {
+		if len(t.sources) >= t.sweepAt {
+			t.sweep()
+		}
+		failures = &sourceFailures{}
+		t.sources[source] = failures
+	}
+
+	failures.count++
+	failures.last = t.now()
+	if t.cfg.SourceLockoutThreshold > 0 && failures.count >= t.cfg.SourceLockoutThreshold {
+		failures.lockedUntil = failures.last.Add(t.cfg.LockoutDuration)
+		failures.count = 0
+		return true
+	}
+	return false
+}
+
+// current returns the live counters of the source, dropping stale ones.
+func (t *LoginThrottle) current(source string) *sourceFailures {
+	failures, ok := t.sources[source]
+	if !ok {
+		return nil
+	}
+
+	if t.live(failures, t.now()) {
+		return failures
+	}
+	delete(t.sources, source)
+	return nil
+}
+
+func (t *LoginThrottle) live(failures *sourceFailures, now time.Time) bool {
+	return now.Before(failures.lockedUntil) || now.Sub(failures.last) < t.cfg.LockoutDuration
+}
+
+// sweep drops every stale source. The next sweep waits until the map has
+// doubled, so the cost per failure stays constant.
+func (t *LoginThrottle) sweep() {
+	now := t.now()
+	for source, failures := range t.sources {
+		if !t.live(failures, now) {
+			delete(t.sources, source)
+		}
+	}
+	t.sweepAt = 2*len(t.sources) + minSweep
+}
+
+// Sources returns the number of sources whose counters are kept.
+func (t *LoginThrottle) Sources() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.sources)
+}
+
+// SetClock replaces the time source, used by tests.
+func (t *LoginThrottle) SetClock(now func() time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.now = now
+}
+
+// loginDelay is the wait after the given number of consecutive failures:
+// LoginDelay doubled for every failure after the first, capped at the
+// lockout duration.
+func loginDelay(cfg config.AuthConfig, failures int) time.Duration {
+	if cfg.LoginDelay <= 0 || failures <= 0 {
+		return 0
+	}
+
+	limit := cfg.LockoutDuration
+	if limit <= 0 {
+		limit = time.Hour
+	}
+
+	delay := cfg.LoginDelay
+	for i := 1; i < failures && delay < limit; i++ {
+		delay *= 2
+	}
+	if delay > limit {
+		delay = limit
+	}
+	return delay
+}
@@ -0,0 +1,74 @@
+// ============================================================================
+// offboarding.go - Deactivating users and handing their work over
+// ============================================================================
+
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/repositories"
+)
+
+// OffboardUser deactivates a user, ends the user's sessions and access
+// tokens and hands the user's work over to the users named in handover. It
+// may be repeated for an inactive user to hand over what was kept the first
+// time.
+func (s *AuthService) OffboardUser(ctx context.Context, userID int, handover models.Handover) (*models.HandoverReport, error) {
+	principal, err := s.authz.Require(ctx, PermUserManage)
+	if err != nil {
+		return nil, err
+	}
+	if principal.KorisnikID == userID {
+		return nil, invalidInput("ne možete predati sopstveni nalog")
+	}
+
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+	for _, target := range []*int{handover.RukovodilacID, handover.IzvrsilacID, handover.VlasnikID} {
+		if target == nil {
+			continue
+		}
+		if err := s.handoverTarget(ctx, userID, *target); err != nil {
+			return nil, err
+		}
+	}
+
+	report, err := s.userRepo.Offboard(ctx, userID, handover)
+	if err != nil {
+		return nil, err
+	}
+	report.ZavrseneSesije = s.revokeUserSessions(ctx, userID, "")
+
+	audit(ctx, s.activity, ActivityUserOffboarded, userID, fmt.Sprintf(
+		"Nalog deaktiviran; predato projekata: %d, zadataka: %d, foldera: %d, dokumenata: %d",
+		len(report.Projekti), len(report.Zadaci), len(report.Folderi), len(report.Dokumenti)))
+	slog.InfoContext(ctx, "user offboarded", "target_user_id", userID,
+		"projects", len(report.Projekti), "tasks", len(report.Zadaci), "folders", len(report.Folderi),
+		"documents", len(report.Dokumenti), "sessions", report.ZavrseneSesije, "tokens", report.OpozvaniTokeni)
+	return report, nil
+}
+
+// handoverTarget checks that work can be handed over to targetID.
+func (s *AuthService) handoverTarget(ctx context.Context, userID, targetID int) error {
+	if targetID == userID {
+		return invalidInput("posao se ne može predati korisniku koji odlazi")
+	}
+
+	target, err := s.userRepo.GetByID(ctx, targetID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return invalidInput(fmt.Sprintf("korisnik %d ne postoji", targetID))
+	}
+	if err != nil {
+		return err
+	}
+	if target.Status != "aktivan" {
+		return invalidInput(fmt.Sprintf("korisnik %s nije aktivan", target.KorisnickoIme))
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+// ============================================================================
+// password_hasher.go - Password hashing with argon2id, legacy bcrypt
+// ============================================================================
+
+package services
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cane/research-institute-system/backend/config"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	argon2SaltLength = 32
+	argon2KeyLength  = 32
+)
+
+// argon2Params are the cost parameters of an argon2id hash.
+type argon2Params struct {
+	memory      uint32 // KiB
+	iterations  uint32
+	parallelism uint8
+	keyLength   uint32
+}
+
+// PasswordHasher creates argon2id hashes with the configured cost. It
+// verifies argon2id hashes of any parameters and the bcrypt hashes that
+// UserService used to store, and reports hashes that differ from what it
+// would create now, so they can be replaced while the password is known.
+type PasswordHasher struct {
+	params argon2Params
+}
+
+func NewPasswordHasher(cfg config.AuthConfig) *PasswordHasher {
+	return &PasswordHasher{params: argon2Params{
+		memory:      uint32(cfg.Argon2Memory),
+		iterations:  uint32(cfg.Argon2Iterations),
+		parallelism: uint8(cfg.Argon2Parallelism),
+		keyLength:   argon2KeyLength,
+	}}
+}
+
+// Hash returns the password in PHC string format, e.g.
+// "$argon2id$v=19$m=65536,t=1,p=4$<salt>$<key>".
+func (h *PasswordHasher) Hash(password string) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	p := h.params
+	key := argon2.IDKey([]byte(password), salt, p.iterations, p.memory, p.parallelism, p.keyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, p.memory, p.iterations, p.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify reports whether password matches hash and, if it does, whether the
+// hash is outdated: bcrypt, or argon2id with other parameters than Hash
+// uses. Unrecognised and malformed hashes match nothing.
+func (h *PasswordHasher) Verify(password, hash string) (ok, outdated bool) {
+	switch {
+	case strings.HasPrefix(hash, "$argon2id$"):
+		params, salt, key, err := decodeArgon2(hash)
+		if err != nil {
+			return false, false
+		}
+		computed := argon2.IDKey([]byte(password), salt, params.iterations, params.memory, params.parallelism, params.keyLength)
+		if subtle.ConstantTimeCompare(key, computed) != 1 {
+			return false, false
+		}
+		return true, params != h.params
+
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+			return false, false
+		}
+		return true, true
+	}
+
+	return false, false
+}
+
+// decodeArgon2 parses a hash created by Hash, with any parameters.
+func decodeArgon2(hash string) (argon2Params, []byte, []byte, error) {
+	var params argon2Params
+
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return params, nil, nil, errors.New("malformed argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return params, nil, nil, err
+	}
+	if version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2 version %d", version)
+	}
+
+	var memory, iterations, parallelism int
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &parallelism); err != nil {
+		return params, nil, nil, err
+	}
+	if memory <= 0 || iterations <= 0 || parallelism <= 0 || parallelism > 255 {
+		return params, nil, nil, errors.New("invalid argon2id parameters")
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, err
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, errors.New("malformed argon2id key")
+	}
+
+	params = argon2Params{
+		memory:      uint32(memory),
+		iterations:  uint32(iterations),
+		parallelism: uint8(parallelism),
+		keyLength:   uint32(len(key)),
+	}
+	return params, salt, key, nil
+}
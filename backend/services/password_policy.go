@@ -0,0 +1,206 @@
+// ============================================================================
+// password_policy.go - Rules for new passwords and password history
+// ============================================================================
+
+package services
+
+import (
+	"bufio"
+	"context"
+	_ "embed"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/cane/research-institute-system/backend/config"
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/repositories"
+)
+
+// Codes of password policy violations.
+const (
+	PasswordTooShort         = "too_short"
+	PasswordTooFewClasses    = "too_few_classes"
+	PasswordContainsUsername = "contains_username"
+	PasswordContainsEmail    = "contains_email"
+	PasswordCommon           = "common"
+	PasswordReused           = "reused"
+)
+
+// PasswordViolation is one rule a rejected password breaks. Code is stable
+// for clients; Message is meant for the user.
+type PasswordViolation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// PasswordPolicyError lists every rule a rejected password breaks. It
+// matches ErrInvalidInput, and its message joins the violations.
+type PasswordPolicyError struct {
+	Violations []PasswordViolation `json:"violations"`
+}
+
+func (e *PasswordPolicyError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		messages[i] = violation.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e *PasswordPolicyError) Is(target error) bool { return target == ErrInvalidInput }
+
+//go:embed common_passwords.txt
+var commonPasswordList string
+
+// commonPasswords holds the entries of common_passwords.txt in lower case.
+var commonPasswords = func() map[string]bool {
+	passwords := make(map[string]bool)
+	scanner := bufio.NewScanner(strings.NewReader(commonPasswordList))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			passwords[strings.ToLower(line)] = true
+		}
+	}
+	return passwords
+}()
+
+// PasswordPolicy checks new passwords against the configured rules and keeps
+// the history of the passwords users set. Every path that sets a password
+// calls Validate before and Remember after storing it.
+type PasswordPolicy struct {
+	cfg     config.AuthConfig
+	history repositories.PasswordHistoryStore
+	hasher  *PasswordHasher
+}
+
+func NewPasswordPolicy(cfg config.AuthConfig, history repositories.PasswordHistoryStore, hasher *PasswordHasher) *PasswordPolicy {
+	return &PasswordPolicy{cfg: cfg, history: history, hasher: hasher}
+}
+
+// Check returns every rule password breaks as the new password of user, in
+// a fixed order. A user without an ID is an account not created yet, which
+// has no history. The error reports a failure to read the history.
+func (p *PasswordPolicy) Check(ctx context.Context, user *models.User, password string) ([]PasswordViolation, error) {
+	var violations []PasswordViolation
+	add := func(code, format string, args ...interface{}) {
+		violations = append(violations, PasswordViolation{Code: code, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if utf8.RuneCountInString(password) < p.cfg.PasswordMinLength {
+		add(PasswordTooShort, "lozinka mora imati najmanje %d karaktera", p.cfg.PasswordMinLength)
+	}
+	if characterClasses(password) < p.cfg.PasswordMinClasses {
+		add(PasswordTooFewClasses, "lozinka mora sadržati bar %d od sledećih vrsta znakova: mala slova, velika slova, cifre, ostali znakovi", p.cfg.PasswordMinClasses)
+	}
+
+	lower := strings.ToLower(password)
+	if containsName(lower, user.KorisnickoIme) {
+		add(PasswordContainsUsername, "lozinka ne sme sadržati korisničko ime")
+	}
+	if local, _, _ := strings.Cut(user.Email, "@"); containsName(lower, local) {
+		add(PasswordContainsEmail, "lozinka ne sme sadržati email adresu")
+	}
+	if commonPasswords[lower] || commonPasswords[strings.TrimRightFunc(lower, isNotLetter)] {
+		add(PasswordCommon, "lozinka je među često korišćenim lozinkama")
+	}
+
+	reused, err := p.reused(ctx, user, password)
+	if err != nil {
+		return nil, err
+	}
+	if reused {
+		add(PasswordReused, "lozinka ne sme biti jedna od poslednjih %d korišćenih", p.cfg.PasswordHistory)
+	}
+
+	return violations, nil
+}
+
+// Validate reports the violations found by Check as a *PasswordPolicyError.
+func (p *PasswordPolicy) Validate(ctx context.Context, user *models.User, password string) error {
+	violations, err := p.Check(ctx, user, password)
+	if err != nil {
+		return err
+	}
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// Remember adds the hash of a password just set to the user's history. A
+// failed write is logged; the password stays set.
+func (p *PasswordPolicy) Remember(ctx context.Context, userID int, hash string) {
+	if p.cfg.PasswordHistory == 0 {
+		return
+	}
+	if err := p.history.Add(ctx, userID, hash, p.cfg.PasswordHistory); err != nil {
+		slog.ErrorContext(ctx, "password history not written", "target_user_id", userID, "error", err)
+	}
+}
+
+// reused reports whether password matches the current password of user or
+// one of the earlier ones kept in the history.
+func (p *PasswordPolicy) reused(ctx context.Context, user *models.User, password string) (bool, error) {
+	if p.cfg.PasswordHistory == 0 || user.KorisnikID == 0 {
+		return false, nil
+	}
+
+	hashes, err := p.history.Recent(ctx, user.KorisnikID, p.cfg.PasswordHistory)
+	if err != nil {
+		return false, err
+	}
+	// Accounts that set their password before the history existed
+	if user.HashSifre != "" && user.HashSifre != unusablePassword && !slices.Contains(hashes, user.HashSifre) {
+		hashes = append(hashes, user.HashSifre)
+	}
+
+	for _, hash := range hashes {
+		if ok, _ := p.hasher.Verify(password, hash); ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// characterClasses counts which of lower case, upper case, digits and other
+// characters occur in password.
+func characterClasses(password string) int {
+	var lower, upper, digit, other int
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			other = 1
+		}
+	}
+	return lower + upper + digit + other
+}
+
+// containsName reports whether the lower case password contains name or one
+// of its parts of four or more characters, such as the surname in
+// "marko.petrovic".
+func containsName(password, name string) bool {
+	name = strings.ToLower(name)
+	if len(name) >= 3 && strings.Contains(password, name) {
+		return true
+	}
+
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		if utf8.RuneCountInString(part) >= 4 && strings.Contains(password, part) {
+			return true
+		}
+	}
+	return false
+}
+
+func isNotLetter(r rune) bool { return !unicode.IsLetter(r) }
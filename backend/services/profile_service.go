@@ -0,0 +1,386 @@
+// ============================================================================
+// profile_service.go - Self-service profile, email, password and preferences
+// ============================================================================
+
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/repositories"
+)
+
+// Languages and date formats the clients offer. The first of each is the
+// default.
+var (
+	PreferenceLanguages   = []string{"sr", "en"}
+	PreferenceDateFormats = []string{"dd.MM.yyyy", "yyyy-MM-dd", "dd/MM/yyyy", "MM/dd/yyyy"}
+)
+
+// maxEmailCodeAttempts wrong codes cancel a pending email change.
+const maxEmailCodeAttempts = 5
+
+// Profile is the account of the caller with its preferences and the email
+// change waiting for confirmation, if any.
+type Profile struct {
+	Korisnik      models.User         `json:"korisnik"`
+	Podesavanja   models.Preferences  `json:"podesavanja"`
+	PromenaEmaila *models.EmailChange `json:"promena_emaila"`
+}
+
+// ProfileUpdate holds the personal fields users change themselves. An empty
+// field is cleared.
+type ProfileUpdate struct {
+	Ime     string `json:"ime"`
+	Prezime string `json:"prezime"`
+}
+
+// ProfileService lets users change their own account. It needs no
+// permission: every call acts on the caller's account only. Access tokens
+// read the profile and preferences but change nothing.
+type ProfileService struct {
+	users    repositories.UserStore
+	profiles repositories.ProfileStore
+	projects repositories.ProjectStore
+	activity repositories.AnalyticsStore
+	auth     *AuthService
+	authz    *Authorizer
+	mailer   Mailer
+}
+
+func NewProfileService(stores repositories.Stores, auth *AuthService, authz *Authorizer, mailer Mailer) *ProfileService {
+	return &ProfileService{
+		users:    stores.Users,
+		profiles: stores.Profiles,
+		projects: stores.Projects,
+		activity: stores.Analytics,
+		auth:     auth,
+		authz:    authz,
+		mailer:   mailer,
+	}
+}
+
+// SetMailer replaces the mailer that sends email confirmation codes, used
+// by tests.
+func (s *ProfileService) SetMailer(mailer Mailer) {
+	s.mailer = mailer
+}
+
+func (s *ProfileService) GetProfile(ctx context.Context) (*Profile, error) {
+	user, err := s.self(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	prefs, err := s.preferences(ctx, user.KorisnikID)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := &Profile{Korisnik: *user, Podesavanja: *prefs}
+	change, err := s.profiles.GetEmailChange(ctx, user.KorisnikID)
+	if err == nil && s.auth.now().Before(change.Istice) {
+		profile.PromenaEmaila = change
+	} else if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// UpdateProfile changes the name of the caller. Accounts of a directory keep
+// the name the directory gives them.
+func (s *ProfileService) UpdateProfile(ctx context.Context, update ProfileUpdate) (*models.User, error) {
+	user, err := s.self(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	if !isLocal(user) {
+		return nil, invalidInput("ime i prezime ovog naloga menjaju se u imeniku korisnika")
+	}
+
+	first, last := strings.TrimSpace(update.Ime), strings.TrimSpace(update.Prezime)
+	if utf8.RuneCountInString(first) > 100 || utf8.RuneCountInString(last) > 100 {
+		return nil, invalidInput("ime i prezime imaju najviše 100 znakova")
+	}
+	if optional(user.Ime) == first && optional(user.Prezime) == last {
+		return user, nil
+	}
+
+	user.Ime, user.Prezime = optionalString(first), optionalString(last)
+	if err := s.users.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	audit(ctx, s.activity, ActivityProfileUpdated, user.KorisnikID, fmt.Sprintf("Izmenjeno ime i prezime: %s %s", first, last))
+	slog.InfoContext(ctx, "profile updated", "target_user_id", user.KorisnikID)
+	return user, nil
+}
+
+// RequestEmailChange sends a confirmation code to a new address. The address
+// changes once ConfirmEmailChange receives the code; until then the old one
+// stays in use. A new request replaces an earlier one.
+func (s *ProfileService) RequestEmailChange(ctx context.Context, email string) (*models.EmailChange, error) {
+	user, err := s.self(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	if !isLocal(user) {
+		return nil, invalidInput("email adresa ovog naloga menja se u imeniku korisnika")
+	}
+	if s.mailer == nil {
+		return nil, invalidInput("slanje pošte nije podešeno (mail.host), pa email adresu menja administrator")
+	}
+
+	email = strings.TrimSpace(email)
+	if !validEmail(email) {
+		return nil, invalidInput("neispravna email adresa")
+	}
+	if strings.EqualFold(email, user.Email) {
+		return nil, invalidInput("to je već vaša email adresa")
+	}
+	if err := checkEmailFree(ctx, s.users, user.KorisnikID, email); err != nil {
+		return nil, err
+	}
+
+	code, err := newActivationCode()
+	if err != nil {
+		return nil, err
+	}
+	change := &models.EmailChange{
+		KorisnikID: user.KorisnikID,
+		NoviEmail:  email,
+		HashKoda:   hashActivationCode(code),
+		Istice:     s.auth.now().Add(s.auth.cfg.ActivationTTL),
+	}
+	if err := s.profiles.BeginEmailChange(ctx, change); err != nil {
+		return nil, err
+	}
+
+	body := fmt.Sprintf("Zatražena je promena email adrese naloga %s na ovu adresu.\n\n"+
+		"Kod za potvrdu: %s\nKod važi do %s.\n\nAko niste vi zatražili promenu, zanemarite ovu poruku.\n",
+		user.KorisnickoIme, code, change.Istice.Format("2006-01-02 15:04"))
+	if err := s.mailer.Send(ctx, email, "Potvrda nove email adrese", body); err != nil {
+		s.profiles.CancelEmailChange(ctx, user.KorisnikID)
+		slog.ErrorContext(ctx, "email confirmation not sent", "target_user_id", user.KorisnikID, "error", err)
+		return nil, fmt.Errorf("kod za potvrdu nije poslat, pokušajte kasnije: %w", err)
+	}
+
+	audit(ctx, s.activity, ActivityEmailChangeRequested, user.KorisnikID, "Zatražena promena email adrese na "+email)
+	slog.InfoContext(ctx, "email change requested", "target_user_id", user.KorisnikID)
+	return change, nil
+}
+
+// ConfirmEmailChange gives the caller the address of the pending change if
+// code is the code sent there. The old address is told about the change.
+func (s *ProfileService) ConfirmEmailChange(ctx context.Context, code string) (*models.User, error) {
+	user, err := s.self(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	change, err := s.profiles.GetEmailChange(ctx, user.KorisnikID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, invalidInput("nema zahteva za promenu email adrese")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !s.auth.now().Before(change.Istice) {
+		s.profiles.CancelEmailChange(ctx, user.KorisnikID)
+		return nil, invalidInput("kod je istekao; zatražite promenu ponovo")
+	}
+
+	if !activationCodeMatches(code, change.HashKoda) {
+		attempts, err := s.profiles.FailEmailChange(ctx, user.KorisnikID)
+		if err != nil {
+			return nil, err
+		}
+		audit(ctx, s.activity, ActivityEmailChangeFailed, user.KorisnikID, fmt.Sprintf("Pogrešan kod za promenu email adrese (%d. pokušaj)", attempts))
+		if attempts >= maxEmailCodeAttempts {
+			s.profiles.CancelEmailChange(ctx, user.KorisnikID)
+			return nil, invalidInput("previše pogrešnih kodova; zatražite promenu ponovo")
+		}
+		return nil, invalidInput("neispravan kod")
+	}
+
+	if err := checkEmailFree(ctx, s.users, user.KorisnikID, change.NoviEmail); err != nil {
+		s.profiles.CancelEmailChange(ctx, user.KorisnikID)
+		return nil, err
+	}
+	if err := s.profiles.CompleteEmailChange(ctx, user.KorisnikID); err != nil {
+		return nil, err
+	}
+
+	previous := user.Email
+	user.Email = change.NoviEmail
+	audit(ctx, s.activity, ActivityEmailChanged, user.KorisnikID, fmt.Sprintf("Email adresa promenjena sa %s na %s", previous, user.Email))
+	slog.InfoContext(ctx, "email changed", "target_user_id", user.KorisnikID)
+
+	body := fmt.Sprintf("Email adresa naloga %s promenjena je u %s.\n\n"+
+		"Ako niste vi promenili adresu, odmah se obratite administratoru.\n", user.KorisnickoIme, user.Email)
+	if err := s.mailer.Send(ctx, previous, "Promenjena email adresa", body); err != nil {
+		slog.WarnContext(ctx, "email change notice not sent", "target_user_id", user.KorisnikID, "error", err)
+	}
+	return user, nil
+}
+
+// CancelEmailChange drops the pending email change of the caller.
+func (s *ProfileService) CancelEmailChange(ctx context.Context) error {
+	user, err := s.self(ctx, false)
+	if err != nil {
+		return err
+	}
+	return s.profiles.CancelEmailChange(ctx, user.KorisnikID)
+}
+
+// checkEmailFree refuses an address an account other than userID uses,
+// compared without regard to case. A new account passes userID 0.
+func checkEmailFree(ctx context.Context, users repositories.UserStore, userID int, email string) error {
+	all, err := users.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+	for _, other := range all {
+		if other.KorisnikID != userID && strings.EqualFold(other.Email, email) {
+			return conflict("email adresu " + email + " već koristi drugi nalog")
+		}
+	}
+	return nil
+}
+
+// ChangePassword replaces the password of the caller after checking the
+// current one. A wrong current password counts as a failed sign-in from
+// source, so it cannot be guessed through a session left open.
+func (s *ProfileService) ChangePassword(ctx context.Context, currentPassword, newPassword, source, currentToken string) error {
+	user, err := s.self(ctx, false)
+	if err != nil {
+		return err
+	}
+	if !isLocal(user) {
+		return externalPassword()
+	}
+	if source == "" {
+		source = LocalSource
+	}
+
+	if response, err := s.auth.checkLockout(ctx, user); err != nil {
+		return err
+	} else if response != nil {
+		return invalidInput(response.Message)
+	}
+	if ok, _ := s.auth.hasher.Verify(currentPassword, user.HashSifre); !ok {
+		s.auth.loginFailed(ctx, user, user.KorisnickoIme, source, ActivityPasswordChangeFailed, "wrong current password")
+		return invalidInput("trenutna lozinka nije ispravna")
+	}
+	if err := s.auth.clearFailures(ctx, user); err != nil {
+		return err
+	}
+
+	if err := s.auth.ChangePassword(ctx, user.KorisnikID, newPassword, currentToken); err != nil {
+		return err
+	}
+	audit(ctx, s.activity, ActivityPasswordChanged, user.KorisnikID, "Lozinka promenjena; ostale sesije su završene")
+	return nil
+}
+
+// GetPreferences returns the preferences of the caller, the defaults until
+// the caller saves any.
+func (s *ProfileService) GetPreferences(ctx context.Context) (*models.Preferences, error) {
+	user, err := s.self(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	return s.preferences(ctx, user.KorisnikID)
+}
+
+// UpdatePreferences replaces the preferences of the caller. The default
+// project must be one the caller can see.
+func (s *ProfileService) UpdatePreferences(ctx context.Context, prefs models.Preferences) (*models.Preferences, error) {
+	user, err := s.self(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if !contains(PreferenceLanguages, prefs.Jezik) {
+		return nil, invalidInput("jezik mora biti jedan od: " + strings.Join(PreferenceLanguages, ", "))
+	}
+	if !contains(PreferenceDateFormats, prefs.FormatDatuma) {
+		return nil, invalidInput("format datuma mora biti jedan od: " + strings.Join(PreferenceDateFormats, ", "))
+	}
+	if id := prefs.PodrazumevaniProjekatID; id != nil {
+		_, err := s.projects.GetByID(ctx, *id)
+		if errors.Is(err, repositories.ErrNotFound) || (err == nil && !s.authz.CanInProject(user, PermProjectView, *id)) {
+			return nil, invalidInput(fmt.Sprintf("projekat %d ne postoji ili vam nije dostupan", *id))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	prefs.KorisnikID = user.KorisnikID
+	if err := s.profiles.SavePreferences(ctx, &prefs); err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "preferences updated", "target_user_id", user.KorisnikID, "language", prefs.Jezik)
+	return &prefs, nil
+}
+
+func (s *ProfileService) preferences(ctx context.Context, userID int) (*models.Preferences, error) {
+	prefs, err := s.profiles.GetPreferences(ctx, userID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return &models.Preferences{
+			KorisnikID:           userID,
+			Jezik:                PreferenceLanguages[0],
+			FormatDatuma:         PreferenceDateFormats[0],
+			ObavestenjaEmail:     true,
+			ObavestenjaZadaci:    true,
+			ObavestenjaKomentari: true,
+		}, nil
+	}
+	return prefs, err
+}
+
+// self returns the stored account of the caller. Operator tools have no
+// account of their own. Access tokens act for scripts and impersonation
+// sessions for an administrator, so both may only make the calls that pass
+// reading.
+func (s *ProfileService) self(ctx context.Context, reading bool) (*models.User, error) {
+	principal, ok := PrincipalFrom(ctx)
+	if !ok {
+		return nil, ErrNoSession
+	}
+	if principal.System {
+		return nil, invalidInput("sistemski pozivalac nema svoj profil")
+	}
+	if principal.Token != nil && !reading {
+		return nil, fmt.Errorf("%w (nalog se ne menja pristupnim tokenom)", ErrForbidden)
+	}
+	if !reading {
+		if err := refuseImpersonated(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return s.users.GetByID(ctx, principal.User.KorisnikID)
+}
+
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
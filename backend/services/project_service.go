@@ -5,194 +5,280 @@
 package services
 
 import (
-	"database/sql"
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 
 	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/repositories"
 )
 
 type ProjectService struct {
-	db *sql.DB
-}
-
-func NewProjectService(db *sql.DB) *ProjectService {
-	return &ProjectService{db: db}
-}
-
-func (s *ProjectService) GetAllProjects() ([]models.Projekti, error) {
-	query := `
-		SELECT p.projekat_id, p.naziv_projekta, p.opis, p.datum_pocetka,
-		       p.datum_zavrsetka, p.status, p.rukovodilac_id, p.radni_tok_id,
-		       COALESCE(k.korisnicko_ime, '') as rukovodilac_ime,
-		       COALESCE(task_count.cnt, 0) as broj_zadataka,
-		       COALESCE(member_count.cnt, 0) as broj_clanova
-		FROM projekti p
-		LEFT JOIN korisnici k ON p.rukovodilac_id = k.korisnik_id
-		LEFT JOIN (
-			SELECT projekat_id, COUNT(*) as cnt 
-			FROM zadaci 
-			GROUP BY projekat_id
-		) task_count ON p.projekat_id = task_count.projekat_id
-		LEFT JOIN (
-			SELECT projekat_id, COUNT(*) as cnt 
-			FROM clanovi_projekta 
-			GROUP BY projekat_id
-		) member_count ON p.projekat_id = member_count.projekat_id
-		ORDER BY p.projekat_id DESC
-	`
-
-	rows, err := s.db.Query(query)
+	projects repositories.ProjectStore
+	units    repositories.UnitStore
+	authz    *Authorizer
+}
+
+func NewProjectService(projects repositories.ProjectStore, units repositories.UnitStore, authz *Authorizer) *ProjectService {
+	return &ProjectService{projects: projects, units: units, authz: authz}
+}
+
+// GetAllProjects returns the projects the caller sees within the unit
+// hierarchy
+func (s *ProjectService) GetAllProjects(ctx context.Context) ([]models.Projekti, error) {
+	if _, err := s.authz.Require(ctx, PermProjectView); err != nil {
+		return nil, err
+	}
+
+	projects, err := s.projects.GetAll(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var projects []models.Projekti
-	for rows.Next() {
-		var project models.Projekti
-		err := rows.Scan(
-			&project.ProjekatID, &project.NazivProjekta, &project.Opis,
-			&project.DatumPocetka, &project.DatumZavrsetka, &project.Status,
-			&project.RukovodilaID, &project.RadniTokID, &project.RukovodilaIme,
-			&project.BrojZadataka, &project.BrojClanova,
-		)
-		if err != nil {
-			return nil, err
-		}
-		projects = append(projects, project)
+	return visibleProjects(ctx, s.projects, s.units, s.authz, projects)
+}
+
+// GetMyProjects returns the projects the caller leads or is a member of
+func (s *ProjectService) GetMyProjects(ctx context.Context) ([]models.Projekti, error) {
+	caller, err := s.authz.Require(ctx, PermProjectView)
+	if err != nil {
+		return nil, err
 	}
 
-	return projects, nil
+	return s.projects.GetByUserID(ctx, caller.KorisnikID)
 }
 
-func (s *ProjectService) GetProjectByID(projectID int) (models.Projekti, error) {
-	var project models.Projekti
-	query := `
-		SELECT p.projekat_id, p.naziv_projekta, p.opis, p.datum_pocetka,
-		       p.datum_zavrsetka, p.status, p.rukovodilac_id, p.radni_tok_id,
-		       COALESCE(k.korisnicko_ime, '') as rukovodilac_ime
-		FROM projekti p
-		LEFT JOIN korisnici k ON p.rukovodilac_id = k.korisnik_id
-		WHERE p.projekat_id = $1
-	`
+func (s *ProjectService) GetProjectByID(ctx context.Context, projectID int) (models.Projekti, error) {
+	if _, err := s.authz.RequireInProject(ctx, PermProjectView, projectID); err != nil {
+		return models.Projekti{}, err
+	}
 
-	err := s.db.QueryRow(query, projectID).Scan(
-		&project.ProjekatID, &project.NazivProjekta, &project.Opis,
-		&project.DatumPocetka, &project.DatumZavrsetka, &project.Status,
-		&project.RukovodilaID, &project.RadniTokID, &project.RukovodilaIme,
-	)
+	project, err := s.projects.GetByID(ctx, projectID)
+	if err != nil {
+		return models.Projekti{}, err
+	}
+	if err := requireVisible(ctx, s.projects, s.units, s.authz, project); err != nil {
+		return models.Projekti{}, err
+	}
 
-	return project, err
+	return *project, nil
 }
 
-func (s *ProjectService) CreateProject(req models.CreateProjectRequest) error {
-	tx, err := s.db.Begin()
+// CreateProject creates a project led by the caller together with its team
+func (s *ProjectService) CreateProject(ctx context.Context, req models.CreateProjectRequest) (models.Projekti, error) {
+	caller, err := s.authz.Require(ctx, PermProjectCreate)
 	if err != nil {
-		return err
+		return models.Projekti{}, err
+	}
+
+	leaderID := caller.KorisnikID
+	project := models.Projekti{
+		NazivProjekta:  req.NazivProjekta,
+		Opis:           &req.Opis,
+		DatumPocetka:   req.DatumPocetka,
+		DatumZavrsetka: req.DatumZavrsetka,
+		Status:         "Aktivan",
+		RukovodilaID:   &leaderID,
+		RadniTokID:     req.RadniTokID,
+		JedinicaID:     req.JedinicaID,
+	}
+
+	if req.JedinicaID != nil {
+		scope, err := unitScope(ctx, s.units, s.authz)
+		if err != nil {
+			return models.Projekti{}, err
+		}
+		if _, err := s.units.GetByID(ctx, *req.JedinicaID); errors.Is(err, repositories.ErrNotFound) {
+			return models.Projekti{}, invalidInput(fmt.Sprintf("jedinica %d ne postoji", *req.JedinicaID))
+		} else if err != nil {
+			return models.Projekti{}, err
+		}
+		if scope != nil && !scope[*req.JedinicaID] {
+			return models.Projekti{}, fmt.Errorf("%w (projekat se može kreirati samo u jedinici iz vaše nadležnosti)", ErrForbidden)
+		}
 	}
-	defer tx.Rollback()
 
-	// Insert project
-	var projectID int
-	query := `
-		INSERT INTO projekti (naziv_projekta, opis, datum_pocetka, datum_zavrsetka, radni_tok_id, status)
-		VALUES ($1, $2, $3, $4, $5, 'aktivan')
-		RETURNING projekat_id
-	`
+	if err := s.projects.Create(ctx, &project, req.ClanoviTima); err != nil {
+		return models.Projekti{}, err
+	}
+
+	slog.InfoContext(ctx, "project created", "project_id", project.ProjekatID)
+	return project, nil
+}
 
-	err = tx.QueryRow(query, req.NazivProjekta, req.Opis, req.DatumPocetka,
-		req.DatumZavrsetka, req.RadniTokID).Scan(&projectID)
+// UpdateProject saves the project's details. The leader stays unless the
+// caller may create projects globally; a new leader joins the team.
+func (s *ProjectService) UpdateProject(ctx context.Context, projectID int, project models.Projekti) error {
+	caller, err := s.authz.RequireInProject(ctx, PermProjectUpdate, projectID)
 	if err != nil {
 		return err
 	}
 
-	// Add team members
-	for _, memberID := range req.ClanoviTima {
-		memberQuery := `INSERT INTO clanovi_projekta (projekat_id, korisnik_id) VALUES ($1, $2)`
-		_, err = tx.Exec(memberQuery, projectID, memberID)
-		if err != nil {
-			return err
-		}
+	current, err := s.projects.GetByID(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	if err := requireVisible(ctx, s.projects, s.units, s.authz, current); err != nil {
+		return err
 	}
 
-	return tx.Commit()
+	if project.RukovodilaID == nil {
+		project.RukovodilaID = current.RukovodilaID
+	} else if !sameID(project.RukovodilaID, current.RukovodilaID) && !s.authz.Can(caller, PermProjectCreate) {
+		return fmt.Errorf("%w (rukovodioca projekta može promeniti samo korisnik koji sme da kreira projekte)", ErrForbidden)
+	}
+
+	project.ProjekatID = projectID
+	return s.projects.Update(ctx, &project)
 }
 
-func (s *ProjectService) UpdateProject(projectID int, project models.Projekti) error {
-	query := `
-		UPDATE projekti 
-		SET naziv_projekta = $1, opis = $2, datum_pocetka = $3, 
-		    datum_zavrsetka = $4, status = $5, rukovodilac_id = $6, radni_tok_id = $7
-		WHERE projekat_id = $8
-	`
+func sameID(a, b *int) bool {
+	return (a == nil) == (b == nil) && (a == nil || *a == *b)
+}
 
-	_, err := s.db.Exec(query, project.NazivProjekta, project.Opis,
-		project.DatumPocetka, project.DatumZavrsetka, project.Status,
-		project.RukovodilaID, project.RadniTokID, projectID)
+// SetProjectWorkflow links a project to a project workflow, or unlinks it when workflowID is nil
+func (s *ProjectService) SetProjectWorkflow(ctx context.Context, projectID int, workflowID *int) error {
+	if _, err := s.authz.RequireInProject(ctx, PermProjectUpdate, projectID); err != nil {
+		return err
+	}
+	if err := requireVisibleProject(ctx, s.projects, s.units, s.authz, projectID); err != nil {
+		return err
+	}
 
-	return err
+	return s.projects.SetWorkflow(ctx, projectID, workflowID)
 }
 
-func (s *ProjectService) DeleteProject(projectID int) error {
-	query := `DELETE FROM projekti WHERE projekat_id = $1`
-	result, err := s.db.Exec(query, projectID)
-	if err != nil {
+func (s *ProjectService) DeleteProject(ctx context.Context, projectID int) error {
+	if _, err := s.authz.RequireInProject(ctx, PermProjectDelete, projectID); err != nil {
+		return err
+	}
+	if err := requireVisibleProject(ctx, s.projects, s.units, s.authz, projectID); err != nil {
+		return err
+	}
+
+	if err := s.projects.Delete(ctx, projectID); err != nil {
 		return err
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	slog.InfoContext(ctx, "project deleted", "project_id", projectID)
+	return nil
+}
+
+func (s *ProjectService) GetProjectMembers(ctx context.Context, projectID int) ([]models.Korisnici, error) {
+	if _, err := s.authz.RequireInProject(ctx, PermProjectView, projectID); err != nil {
+		return nil, err
+	}
+
+	project, err := s.projects.GetByID(ctx, projectID)
 	if err != nil {
+		return nil, err
+	}
+	if err := requireVisible(ctx, s.projects, s.units, s.authz, project); err != nil {
+		return nil, err
+	}
+
+	return s.projects.GetMembers(ctx, projectID)
+}
+
+func (s *ProjectService) AddProjectMember(ctx context.Context, projectID, userID int) error {
+	if _, err := s.authz.RequireInProject(ctx, PermProjectMembers, projectID); err != nil {
+		return err
+	}
+	if err := requireVisibleProject(ctx, s.projects, s.units, s.authz, projectID); err != nil {
 		return err
 	}
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("project with ID %d not found", projectID)
+	return s.projects.AddMember(ctx, projectID, userID)
+}
+
+func (s *ProjectService) RemoveProjectMember(ctx context.Context, projectID, userID int) error {
+	if _, err := s.authz.RequireInProject(ctx, PermProjectMembers, projectID); err != nil {
+		return err
+	}
+	if err := requireVisibleProject(ctx, s.projects, s.units, s.authz, projectID); err != nil {
+		return err
 	}
 
-	return nil
+	return s.projects.RemoveMember(ctx, projectID, userID)
 }
 
-func (s *ProjectService) GetProjectMembers(projectID int) ([]models.Korisnici, error) {
-	query := `
-		SELECT k.korisnik_id, k.korisnicko_ime, k.email, k.ime, k.prezime,
-		       k.uloga_id, u.naziv_uloge
-		FROM korisnici k
-		JOIN uloge u ON k.uloga_id = u.uloga_id
-		JOIN clanovi_projekta cp ON k.korisnik_id = cp.korisnik_id
-		WHERE cp.projekat_id = $1
-		ORDER BY k.korisnicko_ime
-	`
+// visibleProjects keeps the projects the caller sees within the unit
+// hierarchy: those outside any unit or in the caller's units, those the
+// caller leads or works on, and those a role grants project.view in.
+func visibleProjects(ctx context.Context, projects repositories.ProjectStore, units repositories.UnitStore, authz *Authorizer, all []models.Projekti) ([]models.Projekti, error) {
+	scope, err := unitScope(ctx, units, authz)
+	if err != nil || scope == nil {
+		return all, err
+	}
 
-	rows, err := s.db.Query(query, projectID)
+	principal, _ := PrincipalFrom(ctx)
+	caller := principal.User
+	own, err := projects.GetByUserID(ctx, caller.KorisnikID)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	mine := make(map[int]bool, len(own))
+	for _, project := range own {
+		mine[project.ProjekatID] = true
+	}
+	global := authz.Can(caller, PermProjectView)
 
-	var members []models.Korisnici
-	for rows.Next() {
-		var member models.Korisnici
-		err := rows.Scan(
-			&member.KorisnikID, &member.KorisnickoIme, &member.Email,
-			&member.Ime, &member.Prezime, &member.UlogaID, &member.NazivUloge,
-		)
-		if err != nil {
-			return nil, err
+	visible := []models.Projekti{}
+	for _, project := range all {
+		if project.JedinicaID == nil || scope[*project.JedinicaID] || mine[project.ProjekatID] ||
+			!global && authz.CanInProject(caller, PermProjectView, project.ProjekatID) {
+			visible = append(visible, project)
 		}
-		members = append(members, member)
+	}
+	return visible, nil
+}
+
+// visibleProjectIDs returns the projects the caller sees, or nil when the
+// unit hierarchy does not limit the caller.
+func visibleProjectIDs(ctx context.Context, projects repositories.ProjectStore, units repositories.UnitStore, authz *Authorizer) (map[int]bool, error) {
+	scope, err := unitScope(ctx, units, authz)
+	if err != nil || scope == nil {
+		return nil, err
 	}
 
-	return members, nil
+	all, err := projects.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	visible, err := visibleProjects(ctx, projects, units, authz, all)
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[int]bool, len(visible))
+	for _, project := range visible {
+		ids[project.ProjekatID] = true
+	}
+	return ids, nil
 }
 
-func (s *ProjectService) AddProjectMember(projectID, userID int) error {
-	query := `INSERT INTO clanovi_projekta (projekat_id, korisnik_id) VALUES ($1, $2)`
-	_, err := s.db.Exec(query, projectID, userID)
-	return err
+// requireVisibleProject is requireVisible for the project with the ID, for
+// the tasks and documents in it and for writes to it. Unknown projects are
+// left to the caller.
+func requireVisibleProject(ctx context.Context, projects repositories.ProjectStore, units repositories.UnitStore, authz *Authorizer, projectID int) error {
+	project, err := projects.GetByID(ctx, projectID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return requireVisible(ctx, projects, units, authz, project)
 }
 
-func (s *ProjectService) RemoveProjectMember(projectID, userID int) error {
-	query := `DELETE FROM clanovi_projekta WHERE projekat_id = $1 AND korisnik_id = $2`
-	_, err := s.db.Exec(query, projectID, userID)
-	return err
+// requireVisible refuses a project outside the caller's part of the unit
+// hierarchy as if it did not exist.
+func requireVisible(ctx context.Context, projects repositories.ProjectStore, units repositories.UnitStore, authz *Authorizer, project *models.Projekti) error {
+	visible, err := visibleProjects(ctx, projects, units, authz, []models.Projekti{*project})
+	if err != nil {
+		return err
+	}
+	if len(visible) == 0 {
+		return fmt.Errorf("project with ID %d %w", project.ProjekatID, repositories.ErrNotFound)
+	}
+	return nil
 }
@@ -0,0 +1,490 @@
+// ============================================================================
+// role_service.go - Custom roles and their permissions
+// ============================================================================
+
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/cane/research-institute-system/backend/config"
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/repositories"
+)
+
+// Keys of the system roles (Uloge.sistemska). The application finds these
+// roles by key, so they keep their name and are never retired.
+const (
+	RoleAdministrator = "administrator"
+	RoleProjectLeader = "rukovodilac"
+	RoleResearcher    = "istrazivac"
+	RoleOrganizer     = "organizator"
+	RoleGuest         = "gost"
+)
+
+// RoleRequest describes a new role or the new state of a role. A grant with
+// projekat_id applies only within that project.
+type RoleRequest struct {
+	Naziv   string             `json:"naziv"`
+	Opis    string             `json:"opis"`
+	Dozvole []models.RoleGrant `json:"dozvole"`
+}
+
+// RoleService manages roles and the permissions they grant. Every change is
+// audited and applies to running sessions from their next request.
+type RoleService struct {
+	roles    repositories.RoleStore
+	users    repositories.UserStore
+	projects repositories.ProjectStore
+	activity repositories.AnalyticsStore
+	authz    *Authorizer
+	cfg      config.AuthConfig
+}
+
+func NewRoleService(stores repositories.Stores, authz *Authorizer, cfg config.AuthConfig) *RoleService {
+	return &RoleService{
+		roles:    stores.Roles,
+		users:    stores.Users,
+		projects: stores.Projects,
+		activity: stores.Analytics,
+		authz:    authz,
+		cfg:      cfg,
+	}
+}
+
+// GetRoles returns every role with its permissions, retired roles included.
+func (s *RoleService) GetRoles(ctx context.Context) ([]models.Role, error) {
+	if _, err := s.authz.Require(ctx, PermPolicyManage); err != nil {
+		return nil, err
+	}
+	return s.roles.GetAll(ctx)
+}
+
+func (s *RoleService) GetRole(ctx context.Context, roleID int) (*models.Role, error) {
+	if _, err := s.authz.Require(ctx, PermPolicyManage); err != nil {
+		return nil, err
+	}
+	return s.roles.GetByID(ctx, roleID)
+}
+
+// CreateRole creates a role with the requested permissions.
+func (s *RoleService) CreateRole(ctx context.Context, req RoleRequest) (*models.Role, error) {
+	if _, err := s.authz.Require(ctx, PermPolicyManage); err != nil {
+		return nil, err
+	}
+
+	role, err := s.checkRequest(ctx, 0, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.roles.Create(ctx, role); err != nil {
+		return nil, err
+	}
+
+	s.changed(ctx, ActivityRoleCreated, role, fmt.Sprintf("Kreirana uloga %s (%s)", role.NazivUloge, describeGrants(role.Dozvole)))
+	return role, nil
+}
+
+// CloneRole creates a role named name with the description and permissions
+// of an existing role, retired or not.
+func (s *RoleService) CloneRole(ctx context.Context, roleID int, name string) (*models.Role, error) {
+	if _, err := s.authz.Require(ctx, PermPolicyManage); err != nil {
+		return nil, err
+	}
+
+	source, err := s.roles.GetByID(ctx, roleID)
+	if err != nil {
+		return nil, err
+	}
+	role, err := s.checkRequest(ctx, 0, RoleRequest{Naziv: name, Opis: optional(source.Opis), Dozvole: source.Dozvole})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.roles.Create(ctx, role); err != nil {
+		return nil, err
+	}
+
+	s.changed(ctx, ActivityRoleCreated, role, fmt.Sprintf("Kreirana uloga %s kao kopija uloge %s (%s)",
+		role.NazivUloge, source.NazivUloge, describeGrants(role.Dozvole)))
+	return role, nil
+}
+
+// UpdateRole renames a role and replaces its description and permissions.
+// System roles and roles named in auth.two_factor_roles keep their name.
+func (s *RoleService) UpdateRole(ctx context.Context, roleID int, req RoleRequest) (*models.Role, error) {
+	if _, err := s.authz.Require(ctx, PermPolicyManage); err != nil {
+		return nil, err
+	}
+
+	current, err := s.roles.GetByID(ctx, roleID)
+	if err != nil {
+		return nil, err
+	}
+	role, err := s.checkRequest(ctx, roleID, req)
+	if err != nil {
+		return nil, err
+	}
+	if role.NazivUloge != current.NazivUloge {
+		if current.Sistemska != nil {
+			return nil, invalidInput(fmt.Sprintf("sistemska uloga %s se ne može preimenovati", current.NazivUloge))
+		}
+		// Renaming would silently drop the second factor the role requires
+		if twoFactorRole(s.cfg.TwoFactorRoles, current.NazivUloge, "") {
+			return nil, invalidInput(fmt.Sprintf("uloga %s je navedena u auth.two_factor_roles; izmenite podešavanje pre preimenovanja", current.NazivUloge))
+		}
+	}
+	role.Sistemska = current.Sistemska
+	role.Povucena = current.Povucena
+	if err := s.keepsPolicyManager(ctx, roleID, role); err != nil {
+		return nil, err
+	}
+	if err := s.roles.Update(ctx, role); err != nil {
+		return nil, err
+	}
+
+	description := fmt.Sprintf("Izmenjena uloga %s: %s", role.NazivUloge, describeGrants(role.Dozvole))
+	if current.NazivUloge != role.NazivUloge {
+		description = fmt.Sprintf("Izmenjena uloga %s, ranije %s: %s", role.NazivUloge, current.NazivUloge, describeGrants(role.Dozvole))
+	}
+	s.changed(ctx, ActivityRoleChanged, role, description)
+	return role, nil
+}
+
+// RetireRole keeps a role from being assigned. Users holding the role get
+// another role first; system roles are never retired.
+func (s *RoleService) RetireRole(ctx context.Context, roleID int) error {
+	if _, err := s.authz.Require(ctx, PermPolicyManage); err != nil {
+		return err
+	}
+
+	role, err := s.roles.GetByID(ctx, roleID)
+	if err != nil {
+		return err
+	}
+	if role.Sistemska != nil {
+		return invalidInput(fmt.Sprintf("sistemska uloga %s se ne može povući", role.NazivUloge))
+	}
+	if role.Povucena != nil {
+		return conflict("uloga je već povučena")
+	}
+
+	users, err := s.users.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+	holders := 0
+	for _, user := range users {
+		if user.UlogaID == roleID {
+			holders++
+		}
+	}
+	if holders > 0 {
+		return conflict(fmt.Sprintf("ulogu %s ima %d korisnika; dodelite im drugu ulogu pre povlačenja", role.NazivUloge, holders))
+	}
+
+	if err := s.keepsPolicyManager(ctx, roleID, nil); err != nil {
+		return err
+	}
+
+	if err := s.roles.Retire(ctx, roleID); err != nil {
+		if errors.Is(err, repositories.ErrConflict) {
+			return conflict("uloga je u međuvremenu dodeljena ili povučena")
+		}
+		return err
+	}
+
+	s.changed(ctx, ActivityRoleRetired, role, "Povučena uloga "+role.NazivUloge)
+	return nil
+}
+
+// AssignRole gives a user another role. The user's sessions have the
+// permissions of the new role from their next request.
+func (s *RoleService) AssignRole(ctx context.Context, userID, roleID int) error {
+	if _, err := s.authz.Require(ctx, PermUserManage); err != nil {
+		return err
+	}
+
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	role, err := assignableRole(ctx, s.roles, s.authz, roleID)
+	if err != nil {
+		return err
+	}
+	if user.UlogaID == roleID {
+		return nil
+	}
+
+	previous := user.NazivUloge
+	user.UlogaID = roleID
+	if err := s.users.Update(ctx, user); err != nil {
+		return err
+	}
+
+	audit(ctx, s.activity, ActivityRoleAssigned, userID, fmt.Sprintf("Dodeljena uloga %s, ranije %s", role.NazivUloge, previous))
+	slog.InfoContext(ctx, "role assigned", "target_user_id", userID, "role_id", roleID)
+	return nil
+}
+
+// GetPolicy returns the permissions every role that is not retired grants
+// in all projects.
+func (s *RoleService) GetPolicy(ctx context.Context) (Policy, error) {
+	roles, err := s.GetRoles(ctx)
+	if err != nil {
+		return Policy{}, err
+	}
+
+	policy := Policy{Roles: map[string][]Permission{}}
+	for _, role := range roles {
+		if role.Povucena != nil {
+			continue
+		}
+		perms := []Permission{}
+		for _, grant := range role.Dozvole {
+			if grant.ProjekatID == nil {
+				perms = append(perms, Permission(grant.Dozvola))
+			}
+		}
+		policy.Roles[role.NazivUloge] = perms
+	}
+	return policy, nil
+}
+
+// UpdatePolicy replaces the permissions the named roles grant in all
+// projects. Their grants scoped to projects and the roles not named stay as
+// they are.
+func (s *RoleService) UpdatePolicy(ctx context.Context, policy Policy) error {
+	if _, err := s.authz.Require(ctx, PermPolicyManage); err != nil {
+		return err
+	}
+
+	roles, err := s.roles.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]models.Role, len(roles))
+	for _, role := range roles {
+		if role.Povucena == nil {
+			byName[role.NazivUloge] = role
+		}
+	}
+
+	names := make([]string, 0, len(policy.Roles))
+	for name := range policy.Roles {
+		if _, ok := byName[name]; !ok {
+			return invalidInput(fmt.Sprintf("uloga %q ne postoji", name))
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// Check every role before changing any
+	updated := make([]*models.Role, 0, len(names))
+	for _, name := range names {
+		role := byName[name]
+		grants := []models.RoleGrant{}
+		for _, perm := range policy.Roles[name] {
+			grants = append(grants, models.RoleGrant{Dozvola: string(perm)})
+		}
+		for _, grant := range role.Dozvole {
+			if grant.ProjekatID != nil {
+				grants = append(grants, grant)
+			}
+		}
+		next, err := s.checkRequest(ctx, role.UlogaID, RoleRequest{Naziv: role.NazivUloge, Opis: optional(role.Opis), Dozvole: grants})
+		if err != nil {
+			return err
+		}
+		byName[name] = *next
+		updated = append(updated, next)
+	}
+	if !grantsPolicyManage(byName) {
+		return invalidInput("bar jedna uloga mora imati dozvolu policy.manage")
+	}
+
+	for _, role := range updated {
+		if err := s.roles.Update(ctx, role); err != nil {
+			return err
+		}
+		s.changed(ctx, ActivityRoleChanged, role, fmt.Sprintf("Izmenjena uloga %s: %s", role.NazivUloge, describeGrants(role.Dozvole)))
+	}
+	return nil
+}
+
+// checkRequest validates req and returns the role it describes. roleID is
+// the role being changed, 0 for a new one.
+func (s *RoleService) checkRequest(ctx context.Context, roleID int, req RoleRequest) (*models.Role, error) {
+	name := strings.TrimSpace(req.Naziv)
+	if name == "" || utf8.RuneCountInString(name) > 50 {
+		return nil, invalidInput("naziv uloge je obavezan i ima najviše 50 znakova")
+	}
+
+	roles, err := s.roles.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, other := range roles {
+		if other.UlogaID != roleID && strings.EqualFold(other.NazivUloge, name) {
+			return nil, conflict(fmt.Sprintf("uloga %s već postoji", other.NazivUloge))
+		}
+	}
+
+	known := make(map[Permission]bool, len(AllPermissions)+1)
+	known[PermAll] = true
+	for _, perm := range AllPermissions {
+		known[perm] = true
+	}
+
+	role := &models.Role{UlogaID: roleID, NazivUloge: name, Dozvole: []models.RoleGrant{}}
+	if description := strings.TrimSpace(req.Opis); description != "" {
+		role.Opis = &description
+	}
+	seen := map[string]bool{}
+	checked := map[int]bool{}
+	for _, grant := range req.Dozvole {
+		if !known[Permission(grant.Dozvola)] {
+			return nil, invalidInput(fmt.Sprintf("nepoznata dozvola %q", grant.Dozvola))
+		}
+		key := grant.Dozvola
+		if grant.ProjekatID != nil {
+			projectID := *grant.ProjekatID
+			if !checked[projectID] {
+				if _, err := s.projects.GetByID(ctx, projectID); errors.Is(err, repositories.ErrNotFound) {
+					return nil, invalidInput(fmt.Sprintf("projekat %d ne postoji", projectID))
+				} else if err != nil {
+					return nil, err
+				}
+				checked[projectID] = true
+			}
+			key = fmt.Sprintf("%s@%d", key, projectID)
+		}
+		if !seen[key] {
+			seen[key] = true
+			role.Dozvole = append(role.Dozvole, models.RoleGrant{Dozvola: grant.Dozvola, ProjekatID: grant.ProjekatID})
+		}
+	}
+	return role, nil
+}
+
+// keepsPolicyManager refuses a change that would leave no role able to
+// manage roles. changed is the new state of the role roleID, nil when the
+// role is retired.
+func (s *RoleService) keepsPolicyManager(ctx context.Context, roleID int, changed *models.Role) error {
+	roles, err := s.roles.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	active := make(map[string]models.Role, len(roles))
+	for _, role := range roles {
+		if role.UlogaID == roleID {
+			if changed == nil {
+				continue
+			}
+			role = *changed
+		}
+		if role.Povucena == nil {
+			active[role.NazivUloge] = role
+		}
+	}
+	if !grantsPolicyManage(active) {
+		return invalidInput("bar jedna uloga mora imati dozvolu policy.manage")
+	}
+	return nil
+}
+
+// grantsPolicyManage reports whether some role may manage roles in all
+// projects.
+func grantsPolicyManage(roles map[string]models.Role) bool {
+	for _, role := range roles {
+		for _, grant := range role.Dozvole {
+			perm := Permission(grant.Dozvola)
+			if grant.ProjekatID == nil && (perm == PermAll || perm == PermPolicyManage) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// changed audits a change of a role and applies it to this process at once;
+// other processes pick it up with Authorizer.Refresh.
+func (s *RoleService) changed(ctx context.Context, activity string, role *models.Role, description string) {
+	auditRole(ctx, s.activity, activity, role.UlogaID, description)
+	slog.InfoContext(ctx, "role changed", "activity", activity, "role_id", role.UlogaID, "role", role.NazivUloge)
+
+	if err := s.authz.Reload(ctx); err != nil {
+		slog.ErrorContext(ctx, "permissions not reloaded after role change", "role_id", role.UlogaID, "error", err)
+	}
+}
+
+// describeGrants lists grants for the activity log.
+func describeGrants(grants []models.RoleGrant) string {
+	if len(grants) == 0 {
+		return "bez dozvola"
+	}
+
+	parts := make([]string, 0, len(grants))
+	for _, grant := range grants {
+		if grant.ProjekatID == nil {
+			parts = append(parts, grant.Dozvola)
+		} else {
+			parts = append(parts, fmt.Sprintf("%s u projektu %d", grant.Dozvola, *grant.ProjekatID))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// systemRole returns the system role with the key, whatever it is called.
+func systemRole(ctx context.Context, roles repositories.RoleStore, key string) (*models.Role, error) {
+	all, err := roles.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range all {
+		if role.Sistemska != nil && *role.Sistemska == key {
+			return &role, nil
+		}
+	}
+	return nil, fmt.Errorf("system role %q not found", key)
+}
+
+// assignableRole returns the role roleID if it exists and is not retired.
+func assignableRole(ctx context.Context, roles repositories.RoleStore, authz *Authorizer, roleID int) (*models.Role, error) {
+	role, err := roles.GetByID(ctx, roleID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, invalidInput(fmt.Sprintf("uloga %d ne postoji", roleID))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if role.Povucena != nil {
+		return nil, invalidInput(fmt.Sprintf("uloga %s je povučena i ne može se dodeliti", role.NazivUloge))
+	}
+	if !mayAssign(ctx, authz, role.NazivUloge) {
+		return nil, fmt.Errorf("%w (uloga %s daje dozvole koje nemate; dodeljuje je samo ko upravlja politikom)", ErrForbidden, role.NazivUloge)
+	}
+	return role, nil
+}
+
+// mayAssign reports whether the caller may hand out the named role: one
+// that grants nothing beyond the caller's own role, or any role when the
+// caller manages the policy. Otherwise user.manage would be enough to make
+// anyone an administrator.
+func mayAssign(ctx context.Context, authz *Authorizer, roleName string) bool {
+	principal, ok := PrincipalFrom(ctx)
+	if !ok {
+		return false
+	}
+	if principal.System {
+		return true
+	}
+	return authz.Can(principal.User, PermPolicyManage) || authz.Covers(principal.User, roleName)
+}
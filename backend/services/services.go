@@ -0,0 +1,56 @@
+// ============================================================================
+// services.go - Service layer wiring shared by the desktop app and the server
+// ============================================================================
+
+package services
+
+import (
+	"github.com/cane/research-institute-system/backend/config"
+	"github.com/cane/research-institute-system/backend/repositories"
+)
+
+// Services is the complete service layer built on one set of stores. The
+// desktop app and the HTTP server both build it with New, so they apply the
+// same rules and the same authorization policy.
+type Services struct {
+	Auth      *AuthService
+	Users     *UserService
+	Roles     *RoleService
+	Profile   *ProfileService
+	Projects  *ProjectService
+	Tasks     *TaskService
+	Documents *DocumentService
+	Workflows *WorkflowService
+	Units     *UnitService
+	Guests    *GuestService
+	Analytics *AnalyticsService
+
+	Sessions *SessionManager
+	Authz    *Authorizer
+	Config   *config.Config
+}
+
+// New builds every service on the given stores. Each service receives the
+// section of cfg it depends on.
+func New(cfg *config.Config, stores repositories.Stores, sessions *SessionManager, authz *Authorizer) *Services {
+	auth := NewAuthService(stores, sessions, authz, cfg.Auth)
+	if cfg.LDAP.URL != "" {
+		auth.AddProvider(NewLDAPProvider(cfg.LDAP, stores))
+	}
+	return &Services{
+		Auth:      auth,
+		Users:     NewUserService(stores, auth, authz),
+		Roles:     NewRoleService(stores, authz, cfg.Auth),
+		Profile:   NewProfileService(stores, auth, authz, NewMailer(cfg.Mail)),
+		Projects:  NewProjectService(stores.Projects, stores.Units, authz),
+		Tasks:     NewTaskService(stores.Tasks, stores.Projects, stores.Units, authz),
+		Documents: NewDocumentService(stores, authz, cfg.Storage),
+		Workflows: NewWorkflowService(stores.Workflows, authz),
+		Units:     NewUnitService(stores, authz),
+		Guests:    NewGuestService(stores, auth, authz, cfg.Auth),
+		Analytics: NewAnalyticsService(stores.Analytics, stores.Units, authz),
+		Sessions:  sessions,
+		Authz:     authz,
+		Config:    cfg,
+	}
+}
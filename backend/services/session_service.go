@@ -0,0 +1,285 @@
+// ============================================================================
+// session_service.go - Server-side Session Management
+// ============================================================================
+
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cane/research-institute-system/backend/models"
+)
+
+const (
+	// DefaultSessionIdleTimeout is how long a session survives without activity.
+	DefaultSessionIdleTimeout = 30 * time.Minute
+	// DefaultSessionMaxLifetime is the absolute lifetime of a session.
+	DefaultSessionMaxLifetime = 12 * time.Hour
+	// ActivationSessionLifetime bounds the session opened with an activation
+	// code, which only allows setting a password, and the session waiting
+	// for a second factor.
+	ActivationSessionLifetime = 15 * time.Minute
+)
+
+var (
+	ErrNoSession      = errors.New("niste prijavljeni")
+	ErrSessionExpired = errors.New("sesija je istekla, prijavite se ponovo")
+	// ErrPasswordChangeRequired is returned for activation sessions, which
+	// may do nothing but set the account's password.
+	ErrPasswordChangeRequired = errors.New("potrebno je postaviti novu lozinku")
+	// ErrTwoFactorRequired is returned for sessions whose password was
+	// verified but whose second factor was not yet.
+	ErrTwoFactorRequired = errors.New("potrebna je potvrda drugim faktorom")
+)
+
+// Session describes an active login session. The token itself is never
+// stored; sessions are keyed by the SHA-256 hash of the token.
+type Session struct {
+	ID            string    `json:"id"`
+	KorisnikID    int       `json:"korisnik_id"`
+	KorisnickoIme string    `json:"korisnicko_ime"`
+	Kreirana      time.Time `json:"kreirana"`
+	PoslednjaAkt  time.Time `json:"poslednja_aktivnost"`
+	Istice        time.Time `json:"istice"`
+	Aktivacija    bool      `json:"aktivacija,omitempty"`   // opened with an activation code
+	DrugiFaktor   bool      `json:"drugi_faktor,omitempty"` // waiting for the second factor
+
+	// Zastupanje is set when an administrator acts as the user in this
+	// session; the session ends at Zastupanje.Istice at the latest.
+	Zastupanje *Impersonation `json:"zastupanje,omitempty"`
+
+	tokenHash string
+	// since is when the session was opened or last kept by RevokeUser; the
+	// user's sesije_nevazece_od ends the sessions from before it
+	since time.Time
+}
+
+// SessionManager issues, resolves and revokes sessions. It is safe for
+// concurrent use.
+type SessionManager struct {
+	mu          sync.Mutex
+	sessions    map[string]*Session
+	idleTimeout time.Duration
+	maxLifetime time.Duration
+	now         func() time.Time
+}
+
+func NewSessionManager(idleTimeout, maxLifetime time.Duration) *SessionManager {
+	if idleTimeout <= 0 {
+		idleTimeout = DefaultSessionIdleTimeout
+	}
+	if maxLifetime <= 0 {
+		maxLifetime = DefaultSessionMaxLifetime
+	}
+	return &SessionManager{
+		sessions:    make(map[string]*Session),
+		idleTimeout: idleTimeout,
+		maxLifetime: maxLifetime,
+		now:         time.Now,
+	}
+}
+
+// SetClock replaces the time source, used by tests.
+func (m *SessionManager) SetClock(now func() time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.now = now
+}
+
+// clock returns the current time of the session manager.
+func (m *SessionManager) clock() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.now()
+}
+
+// revokedBefore reports whether the user's sessions opened before
+// user.SesijeNevazeceOd, by any process, include this one. The database
+// keeps microseconds, so the session is compared at that precision.
+func (s *Session) revokedBefore(user *models.User) bool {
+	return user.SesijeNevazeceOd != nil && s.since.Truncate(time.Microsecond).Before(*user.SesijeNevazeceOd)
+}
+
+// Create issues a new session for the user and returns the bearer token.
+func (m *SessionManager) Create(user *models.User) (string, Session, error) {
+	return m.create(user, Session{})
+}
+
+// CreateActivation issues a session that is only good for completing the
+// activation of the account. It lasts at most ActivationSessionLifetime.
+func (m *SessionManager) CreateActivation(user *models.User) (string, Session, error) {
+	return m.create(user, Session{Aktivacija: true})
+}
+
+// CreateSecondFactor issues a session that is only good for verifying or
+// setting up the second factor of the account. It lasts at most
+// ActivationSessionLifetime.
+func (m *SessionManager) CreateSecondFactor(user *models.User) (string, Session, error) {
+	return m.create(user, Session{DrugiFaktor: true})
+}
+
+// CreateImpersonation issues a session in which the administrator described
+// by impersonation acts as the user, until impersonation.Istice at the
+// latest.
+func (m *SessionManager) CreateImpersonation(user *models.User, impersonation Impersonation) (string, Session, error) {
+	return m.create(user, Session{Zastupanje: &impersonation})
+}
+
+// create issues a session of the kind described by the flags of kind.
+func (m *SessionManager) create(user *models.User, kind Session) (string, Session, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", Session{}, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", Session{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	session := &Session{
+		ID:            hex.EncodeToString(id),
+		KorisnikID:    user.KorisnikID,
+		KorisnickoIme: user.KorisnickoIme,
+		Kreirana:      now,
+		PoslednjaAkt:  now,
+		Aktivacija:    kind.Aktivacija,
+		DrugiFaktor:   kind.DrugiFaktor,
+		Zastupanje:    kind.Zastupanje,
+		tokenHash:     hashToken(token),
+		since:         now,
+	}
+	session.Istice = m.expiry(session)
+	m.sessions[session.tokenHash] = session
+
+	return token, *session, nil
+}
+
+// Resolve looks up the session for a token and extends its idle deadline.
+func (m *SessionManager) Resolve(token string) (*Session, error) {
+	if token == "" {
+		return nil, ErrNoSession
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := hashToken(token)
+	session, ok := m.sessions[key]
+	if !ok {
+		return nil, ErrNoSession
+	}
+
+	now := m.now()
+	if !now.Before(session.Istice) {
+		delete(m.sessions, key)
+		return nil, ErrSessionExpired
+	}
+
+	session.PoslednjaAkt = now
+	session.Istice = m.expiry(session)
+
+	resolved := *session
+	return &resolved, nil
+}
+
+// Revoke ends the session identified by its token.
+func (m *SessionManager) Revoke(token string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, hashToken(token))
+}
+
+// RevokeByID ends the session with the given public ID.
+func (m *SessionManager) RevokeByID(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for key, session := range m.sessions {
+		if session.ID == id {
+			delete(m.sessions, key)
+			return true
+		}
+	}
+	return false
+}
+
+// RevokeUser ends all sessions of a user except the one holding exceptToken,
+// and the sessions in which the user acts as someone else. The kept session
+// counts as opened now.
+func (m *SessionManager) RevokeUser(userID int, exceptToken string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keep := ""
+	if exceptToken != "" {
+		keep = hashToken(exceptToken)
+		if session, ok := m.sessions[keep]; ok {
+			session.since = m.now()
+		}
+	}
+
+	revoked := 0
+	for key, session := range m.sessions {
+		acting := session.Zastupanje != nil && session.Zastupanje.AdministratorID == userID
+		if (session.KorisnikID == userID || acting) && key != keep {
+			delete(m.sessions, key)
+			revoked++
+		}
+	}
+	return revoked
+}
+
+// List returns all sessions that have not expired, newest first.
+func (m *SessionManager) List() []Session {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	sessions := make([]Session, 0, len(m.sessions))
+	for key, session := range m.sessions {
+		if !now.Before(session.Istice) {
+			delete(m.sessions, key)
+			continue
+		}
+		sessions = append(sessions, *session)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Kreirana.After(sessions[j].Kreirana)
+	})
+	return sessions
+}
+
+// expiry returns the earlier of the idle and absolute deadlines.
+func (m *SessionManager) expiry(session *Session) time.Time {
+	idle := session.PoslednjaAkt.Add(m.idleTimeout)
+	absolute := session.Kreirana.Add(m.maxLifetime)
+	if (session.Aktivacija || session.DrugiFaktor) && m.maxLifetime > ActivationSessionLifetime {
+		absolute = session.Kreirana.Add(ActivationSessionLifetime)
+	}
+	if session.Zastupanje != nil && session.Zastupanje.Istice.Before(absolute) {
+		absolute = session.Zastupanje.Istice
+	}
+	if idle.Before(absolute) {
+		return idle
+	}
+	return absolute
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -5,270 +5,156 @@
 package services
 
 import (
-	"database/sql"
-	"fmt"
+	"context"
+	"errors"
 
 	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/repositories"
 )
 
 type TaskService struct {
-	db *sql.DB
+	tasks    repositories.TaskStore
+	projects repositories.ProjectStore
+	units    repositories.UnitStore
+	authz    *Authorizer
 }
 
-func NewTaskService(db *sql.DB) *TaskService {
-	return &TaskService{db: db}
+func NewTaskService(tasks repositories.TaskStore, projects repositories.ProjectStore, units repositories.UnitStore, authz *Authorizer) *TaskService {
+	return &TaskService{tasks: tasks, projects: projects, units: units, authz: authz}
 }
 
-func (s *TaskService) GetTasksByProject(projectID int) ([]models.Zadaci, error) {
-	query := `
-		SELECT z.zadatak_id, z.projekat_id, z.faza_id, z.naziv_zadatka, z.opis,
-		       z.dodeljen_korisniku_id, z.rok, z.prioritet, z.progres, z.kreiran_datuma,
-		       p.naziv_projekta, f.naziv_faze,
-		       COALESCE(k.korisnicko_ime, '') as dodeljen_korisniku
-		FROM zadaci z
-		JOIN projekti p ON z.projekat_id = p.projekat_id
-		JOIN faze f ON z.faza_id = f.faza_id
-		LEFT JOIN korisnici k ON z.dodeljen_korisniku_id = k.korisnik_id
-		WHERE z.projekat_id = $1
-		ORDER BY z.kreiran_datuma DESC
-	`
-
-	rows, err := s.db.Query(query, projectID)
-	if err != nil {
+func (s *TaskService) GetTasksByProject(ctx context.Context, projectID int) ([]models.Zadaci, error) {
+	if _, err := s.authz.RequireInProject(ctx, PermTaskView, projectID); err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var tasks []models.Zadaci
-	for rows.Next() {
-		var task models.Zadaci
-		err := rows.Scan(
-			&task.ZadatakID, &task.ProjekatID, &task.FazaID, &task.NazivZadatka,
-			&task.Opis, &task.DodjeljenKorisnikuID, &task.Rok, &task.Prioritet,
-			&task.Progres, &task.KreiranDatuma, &task.NazivProjekta,
-			&task.NazivFaze, &task.DodjeljenKorisniku,
-		)
-		if err != nil {
-			return nil, err
-		}
-		tasks = append(tasks, task)
+	if err := requireVisibleProject(ctx, s.projects, s.units, s.authz, projectID); err != nil {
+		return nil, err
 	}
 
-	return tasks, nil
+	return s.tasks.GetByProject(ctx, projectID)
 }
 
-func (s *TaskService) GetTasksByUser(userID int) ([]models.Zadaci, error) {
-	query := `
-		SELECT z.zadatak_id, z.projekat_id, z.faza_id, z.naziv_zadatka, z.opis,
-		       z.dodeljen_korisniku_id, z.rok, z.prioritet, z.progres, z.kreiran_datuma,
-		       p.naziv_projekta, f.naziv_faze,
-		       COALESCE(k.korisnicko_ime, '') as dodeljen_korisniku
-		FROM zadaci z
-		JOIN projekti p ON z.projekat_id = p.projekat_id
-		JOIN faze f ON z.faza_id = f.faza_id
-		LEFT JOIN korisnici k ON z.dodeljen_korisniku_id = k.korisnik_id
-		WHERE z.dodeljen_korisniku_id = $1
-		ORDER BY z.rok ASC NULLS LAST, z.prioritet DESC
-	`
-
-	rows, err := s.db.Query(query, userID)
+// GetTasksByUser returns the tasks assigned to a user. The tasks of other
+// users need user.view and are limited to the projects the caller sees.
+func (s *TaskService) GetTasksByUser(ctx context.Context, userID int) ([]models.Zadaci, error) {
+	caller, err := s.authz.Require(ctx, PermTaskView)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var tasks []models.Zadaci
-	for rows.Next() {
-		var task models.Zadaci
-		err := rows.Scan(
-			&task.ZadatakID, &task.ProjekatID, &task.FazaID, &task.NazivZadatka,
-			&task.Opis, &task.DodjeljenKorisnikuID, &task.Rok, &task.Prioritet,
-			&task.Progres, &task.KreiranDatuma, &task.NazivProjekta,
-			&task.NazivFaze, &task.DodjeljenKorisniku,
-		)
-		if err != nil {
-			return nil, err
-		}
-		tasks = append(tasks, task)
+	if userID == caller.KorisnikID {
+		return s.tasks.GetByUser(ctx, userID)
 	}
 
-	return tasks, nil
-}
-
-func (s *TaskService) GetTaskByID(taskID int) (models.Zadaci, error) {
-	var task models.Zadaci
-	query := `
-		SELECT z.zadatak_id, z.projekat_id, z.faza_id, z.naziv_zadatka, z.opis,
-		       z.dodeljen_korisniku_id, z.rok, z.prioritet, z.progres, z.kreiran_datuma,
-		       p.naziv_projekta, f.naziv_faze,
-		       COALESCE(k.korisnicko_ime, '') as dodeljen_korisniku
-		FROM zadaci z
-		JOIN projekti p ON z.projekat_id = p.projekat_id
-		JOIN faze f ON z.faza_id = f.faza_id
-		LEFT JOIN korisnici k ON z.dodeljen_korisniku_id = k.korisnik_id
-		WHERE z.zadatak_id = $1
-	`
-
-	err := s.db.QueryRow(query, taskID).Scan(
-		&task.ZadatakID, &task.ProjekatID, &task.FazaID, &task.NazivZadatka,
-		&task.Opis, &task.DodjeljenKorisnikuID, &task.Rok, &task.Prioritet,
-		&task.Progres, &task.KreiranDatuma, &task.NazivProjekta,
-		&task.NazivFaze, &task.DodjeljenKorisniku,
-	)
-
-	return task, err
-}
-
-func (s *TaskService) CreateTask(req models.CreateTaskRequest) error {
-	// Get first phase of project workflow
-	var faseID int
-	phaseQuery := `
-		SELECT f.faza_id 
-		FROM faze f
-		JOIN projekti p ON f.radni_tok_id = p.radni_tok_id
-		WHERE p.projekat_id = $1
-		ORDER BY f.redosled ASC
-		LIMIT 1
-	`
-	err := s.db.QueryRow(phaseQuery, req.ProjekatID).Scan(&faseID)
+	if _, err := s.authz.Require(ctx, PermUserView); err != nil {
+		return nil, err
+	}
+	visible, err := visibleProjectIDs(ctx, s.projects, s.units, s.authz)
 	if err != nil {
-		// If no workflow, use default phase 1
-		faseID = 1
+		return nil, err
+	}
+	tasks, err := s.tasks.GetByUser(ctx, userID)
+	if err != nil || visible == nil {
+		return tasks, err
 	}
 
-	query := `
-		INSERT INTO zadaci (projekat_id, faza_id, naziv_zadatka, opis, 
-		                   dodeljen_korisniku_id, rok, prioritet)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-	`
-
-	_, err = s.db.Exec(query, req.ProjekatID, faseID, req.NazivZadatka,
-		req.Opis, req.DodjeljenKorisnikuID, req.Rok, req.Prioritet)
-
-	return err
+	scoped := []models.Zadaci{}
+	for _, task := range tasks {
+		if visible[task.ProjekatID] {
+			scoped = append(scoped, task)
+		}
+	}
+	return scoped, nil
 }
 
-func (s *TaskService) UpdateTask(taskID int, req models.UpdateTaskRequest) error {
-	// Build dynamic update query
-	setParts := []string{}
-	args := []interface{}{}
-	argCount := 1
-
-	if req.NazivZadatka != nil {
-		setParts = append(setParts, fmt.Sprintf("naziv_zadatka = $%d", argCount))
-		args = append(args, *req.NazivZadatka)
-		argCount++
+func (s *TaskService) GetTaskByID(ctx context.Context, taskID int) (models.Zadaci, error) {
+	if _, err := s.requireTask(ctx, PermTaskView, taskID); err != nil {
+		return models.Zadaci{}, err
 	}
 
-	if req.Opis != nil {
-		setParts = append(setParts, fmt.Sprintf("opis = $%d", argCount))
-		args = append(args, *req.Opis)
-		argCount++
+	task, err := s.tasks.GetByID(ctx, taskID)
+	if err != nil {
+		return models.Zadaci{}, err
 	}
-
-	if req.DodjeljenKorisnikuID != nil {
-		setParts = append(setParts, fmt.Sprintf("dodeljen_korisniku_id = $%d", argCount))
-		args = append(args, *req.DodjeljenKorisnikuID)
-		argCount++
+	if err := requireVisibleProject(ctx, s.projects, s.units, s.authz, task.ProjekatID); err != nil {
+		return models.Zadaci{}, err
 	}
 
-	if req.Rok != nil {
-		setParts = append(setParts, fmt.Sprintf("rok = $%d", argCount))
-		args = append(args, *req.Rok)
-		argCount++
-	}
+	return *task, nil
+}
 
-	if req.Prioritet != nil {
-		setParts = append(setParts, fmt.Sprintf("prioritet = $%d", argCount))
-		args = append(args, *req.Prioritet)
-		argCount++
+func (s *TaskService) CreateTask(ctx context.Context, req models.CreateTaskRequest) error {
+	if _, err := s.authz.RequireInProject(ctx, PermTaskCreate, req.ProjekatID); err != nil {
+		return err
 	}
 
-	if req.Progres != nil {
-		setParts = append(setParts, fmt.Sprintf("progres = $%d", argCount))
-		args = append(args, *req.Progres)
-		argCount++
+	task := &models.Task{
+		ProjekatID:           req.ProjekatID,
+		NazivZadatka:         req.NazivZadatka,
+		Opis:                 &req.Opis,
+		DodjeljenKorisnikuID: req.DodjeljenKorisnikuID,
+		Rok:                  req.Rok,
+		Prioritet:            &req.Prioritet,
 	}
 
-	if req.FazaID != nil {
-		setParts = append(setParts, fmt.Sprintf("faza_id = $%d", argCount))
-		args = append(args, *req.FazaID)
-		argCount++
-	}
+	// The store places the task in the first phase of the project workflow
+	return s.tasks.Create(ctx, task)
+}
 
-	if len(setParts) == 0 {
-		return fmt.Errorf("no fields to update")
+// UpdateTask changes only the fields set in req
+func (s *TaskService) UpdateTask(ctx context.Context, taskID int, req models.UpdateTaskRequest) error {
+	if _, err := s.requireTask(ctx, PermTaskUpdate, taskID); err != nil {
+		return err
 	}
 
-	query := fmt.Sprintf("UPDATE zadaci SET %s WHERE zadatak_id = $%d",
-		fmt.Sprintf("%s", setParts[0]), argCount)
-	for i := 1; i < len(setParts); i++ {
-		query = query[:len(query)-len(fmt.Sprintf(" WHERE zadatak_id = $%d", argCount))] +
-			fmt.Sprintf(", %s WHERE zadatak_id = $%d", setParts[i], argCount)
+	if req.NazivZadatka == nil && req.Opis == nil && req.DodjeljenKorisnikuID == nil &&
+		req.Rok == nil && req.Prioritet == nil && req.Progres == nil && req.FazaID == nil {
+		return invalidInput("nema izmena za upis")
 	}
-	args = append(args, taskID)
 
-	_, err := s.db.Exec(query, args...)
-	return err
+	return s.tasks.Update(ctx, taskID, req)
 }
 
-func (s *TaskService) DeleteTask(taskID int) error {
-	query := `DELETE FROM zadaci WHERE zadatak_id = $1`
-	result, err := s.db.Exec(query, taskID)
-	if err != nil {
+func (s *TaskService) DeleteTask(ctx context.Context, taskID int) error {
+	if _, err := s.requireTask(ctx, PermTaskDelete, taskID); err != nil {
 		return err
 	}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
+	return s.tasks.Delete(ctx, taskID)
+}
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("task with ID %d not found", taskID)
+func (s *TaskService) GetTaskComments(ctx context.Context, taskID int) ([]models.KomentariZadataka, error) {
+	if _, err := s.GetTaskByID(ctx, taskID); err != nil {
+		return nil, err
 	}
 
-	return nil
+	return s.tasks.GetComments(ctx, taskID)
 }
 
-func (s *TaskService) GetTaskComments(taskID int) ([]models.KomentariZadataka, error) {
-	query := `
-		SELECT kz.komentar_id, kz.zadatak_id, kz.korisnik_id, kz.tekst_komentara,
-		       kz.datuma_kreiranja, k.korisnicko_ime as ime_korisnika
-		FROM komentari_zadataka kz
-		JOIN korisnici k ON kz.korisnik_id = k.korisnik_id
-		WHERE kz.zadatak_id = $1
-		ORDER BY kz.datuma_kreiranja DESC
-	`
-
-	rows, err := s.db.Query(query, taskID)
+// AddTaskComment adds a comment to a task on behalf of the caller
+func (s *TaskService) AddTaskComment(ctx context.Context, taskID int, comment string) error {
+	caller, err := s.requireTask(ctx, PermTaskComment, taskID)
 	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var comments []models.KomentariZadataka
-	for rows.Next() {
-		var comment models.KomentariZadataka
-		err := rows.Scan(
-			&comment.KomentarID, &comment.ZadatakID, &comment.KorisnikID,
-			&comment.TekstKomentara, &comment.DatumaKreiranja, &comment.ImeKorisnika,
-		)
-		if err != nil {
-			return nil, err
-		}
-		comments = append(comments, comment)
+		return err
 	}
 
-	return comments, nil
+	return s.tasks.AddComment(ctx, &models.TaskComment{
+		ZadatakID:      taskID,
+		KorisnikID:     caller.KorisnikID,
+		TekstKomentara: comment,
+	})
 }
 
-func (s *TaskService) AddTaskComment(taskID, userID int, comment string) error {
-	query := `
-		INSERT INTO komentari_zadataka (zadatak_id, korisnik_id, tekst_komentara)
-		VALUES ($1, $2, $3)
-	`
-
-	_, err := s.db.Exec(query, taskID, userID, comment)
-	return err
+// requireTask checks perm within the project of the task. An unknown task
+// is in no project.
+func (s *TaskService) requireTask(ctx context.Context, perm Permission, taskID int) (*models.User, error) {
+	return s.authz.requireIn(ctx, perm, func() (int, error) {
+		task, err := s.tasks.GetByID(ctx, taskID)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		return task.ProjekatID, nil
+	})
 }
@@ -0,0 +1,441 @@
+// ============================================================================
+// two_factor.go - TOTP second factor and recovery codes
+// ============================================================================
+
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cane/research-institute-system/backend/logging"
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/repositories"
+)
+
+// Messages of a password sign-in that opened a second factor session. With
+// TwoFactorRequired the client asks for a code and calls VerifyTwoFactor;
+// with TwoFactorSetupRequired the account's role needs a second factor the
+// account does not have yet, so the client calls EnrollTwoFactor and
+// ConfirmTwoFactor.
+const (
+	TwoFactorRequired      = "TWO_FACTOR_REQUIRED"
+	TwoFactorSetupRequired = "TWO_FACTOR_SETUP_REQUIRED"
+)
+
+// RFC 6238 parameters, the defaults of every authenticator app.
+const (
+	totpPeriod = 30 // seconds
+	totpDigits = 6
+	// totpSkew is how many time steps before and after the current one are
+	// accepted, for clocks that are slightly off.
+	totpSkew = 1
+
+	recoveryCodeCount = 10
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TwoFactorEnrollment is the provisioning data of a new, unconfirmed
+// second factor. URL is the otpauth:// link for a QR code; Tajna is the
+// same secret for typing in by hand.
+type TwoFactorEnrollment struct {
+	Tajna string `json:"tajna"`
+	URL   string `json:"url"`
+}
+
+// TwoFactorSetup is the result of confirming a second factor. The recovery
+// codes are shown once; only their hashes are stored. Prijava is the full
+// session that replaces a second factor session, nil otherwise.
+type TwoFactorSetup struct {
+	RezervniKodovi []string       `json:"rezervni_kodovi"`
+	Prijava        *LoginResponse `json:"prijava,omitempty"`
+}
+
+// TwoFactorStatus describes the second factor of an account.
+type TwoFactorStatus struct {
+	Ukljucen        bool `json:"ukljucen"`
+	Obavezan        bool `json:"obavezan"` // required by the account's role
+	PreostaloKodova int  `json:"preostalo_kodova"`
+}
+
+// TOTPCode returns the code of the base32 secret for the time step holding
+// t, as an authenticator app shows it.
+func TOTPCode(secret string, t time.Time) (string, error) {
+	return totpAt(secret, totpStep(t))
+}
+
+func totpStep(t time.Time) int64 {
+	return t.Unix() / totpPeriod
+}
+
+func totpAt(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", fmt.Errorf("invalid TOTP secret: %w", err)
+	}
+
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation, RFC 4226 section 5.3
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000), nil
+}
+
+// matchTOTP returns the time step within the allowed skew whose code is
+// code, skipping steps up to last, which were already used.
+func (s *AuthService) matchTOTP(secret, code string, last int64) (int64, bool) {
+	code = strings.ReplaceAll(code, " ", "")
+	current := totpStep(s.now())
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step <= last {
+			continue
+		}
+		want, err := totpAt(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(want), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// isTOTP tells a code from the authenticator app from a recovery code.
+func isTOTP(code string) bool {
+	code = strings.ReplaceAll(code, " ", "")
+	if len(code) != totpDigits {
+		return false
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// requiresTwoFactor reports whether the role of the user must sign in with
+// a second factor.
+func (s *AuthService) requiresTwoFactor(user *models.User) bool {
+	return twoFactorRole(s.cfg.TwoFactorRoles, user.NazivUloge, s.authz.SystemRole(user))
+}
+
+// twoFactorRole reports whether auth.two_factor_roles lists the role, a
+// system role by its key or any role by its name.
+func twoFactorRole(roles []string, name, key string) bool {
+	for _, role := range roles {
+		if (key != "" && role == key) || strings.EqualFold(role, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// signIn finishes a sign-in whose password or activation is verified. An
+// account with a second factor, or whose role requires one, gets a second
+// factor session; everyone else a full session.
+func (s *AuthService) signIn(ctx context.Context, user *models.User, message string) (*LoginResponse, error) {
+	factor, err := s.factors.Get(ctx, user.KorisnikID)
+	if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+		return nil, err
+	}
+
+	user.HashSifre = ""
+	if factor != nil && factor.Potvrdjen != nil {
+		slog.InfoContext(ctx, "second factor requested", "username", user.KorisnickoIme, "user_id", user.KorisnikID)
+		return s.issueSession(user, TwoFactorRequired, s.sessions.CreateSecondFactor)
+	}
+	if s.requiresTwoFactor(user) {
+		slog.InfoContext(ctx, "second factor setup requested", "username", user.KorisnickoIme, "user_id", user.KorisnikID)
+		return s.issueSession(user, TwoFactorSetupRequired, s.sessions.CreateSecondFactor)
+	}
+
+	if err := s.userRepo.UpdateLastLogin(ctx, user.KorisnikID); err != nil {
+		return nil, err
+	}
+	slog.InfoContext(ctx, "login succeeded", "username", user.KorisnickoIme, "user_id", user.KorisnikID)
+	return s.issueSession(user, message, s.sessions.Create)
+}
+
+// VerifyTwoFactor completes the sign-in of a second factor session with a
+// code from the authenticator app or an unused recovery code, and returns a
+// full session in its place. Wrong codes count as failed sign-ins of the
+// account and of source; when they lock the account the second factor
+// session ends too.
+func (s *AuthService) VerifyTwoFactor(ctx context.Context, token, code, source string) (*LoginResponse, error) {
+	session, err := s.sessions.Resolve(token)
+	if err != nil {
+		return nil, err
+	}
+	if !session.DrugiFaktor {
+		return nil, invalidInput("prijava ne čeka drugi faktor")
+	}
+	if source == "" {
+		source = LocalSource
+	}
+
+	if wait, _ := s.throttle.Wait(source); wait > 0 {
+		return refused("Previše neuspešnih pokušaja, pokušajte ponovo za %d s", wait, time.Second), nil
+	}
+
+	user, err := s.userRepo.GetByID(ctx, session.KorisnikID)
+	if err != nil || user.Status != "aktivan" {
+		s.sessions.Revoke(token)
+		return nil, ErrNoSession
+	}
+	ctx = logging.WithUser(ctx, user.KorisnikID, user.KorisnickoIme)
+
+	if response, err := s.checkLockout(ctx, user); response != nil || err != nil {
+		return response, err
+	}
+
+	factor, err := s.factors.Get(ctx, user.KorisnikID)
+	if errors.Is(err, repositories.ErrNotFound) || (err == nil && factor.Potvrdjen == nil) {
+		return nil, invalidInput("drugi faktor nije uključen, najpre ga podesite")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	failed := func(reason string) (*LoginResponse, error) {
+		s.loginFailed(ctx, user, user.KorisnickoIme, source, ActivityTwoFactorFailed, reason)
+		if locked, err := s.userRepo.GetByID(ctx, user.KorisnikID); err == nil && locked.ZakljucanDo != nil {
+			s.sessions.Revoke(token)
+		}
+		return &LoginResponse{Success: false, Message: "Neispravan kod"}, nil
+	}
+
+	if isTOTP(code) {
+		step, ok := s.matchTOTP(factor.Tajna, code, factor.PoslednjiKorak)
+		if !ok {
+			return failed("wrong TOTP code")
+		}
+		if err := s.factors.UseStep(ctx, user.KorisnikID, step); err != nil {
+			if errors.Is(err, repositories.ErrConflict) {
+				return failed("TOTP code already used")
+			}
+			return nil, err
+		}
+	} else {
+		if err := s.factors.UseRecoveryCode(ctx, user.KorisnikID, hashActivationCode(code)); err != nil {
+			if errors.Is(err, repositories.ErrConflict) {
+				return failed("wrong recovery code")
+			}
+			return nil, err
+		}
+		left, _ := s.factors.RecoveryCodesLeft(ctx, user.KorisnikID)
+		audit(ctx, s.activity, ActivityRecoveryCodeUsed, user.KorisnikID, fmt.Sprintf("Prijava rezervnim kodom, preostalo %d", left))
+		slog.WarnContext(ctx, "recovery code used", "target_user_id", user.KorisnikID, "left", left)
+	}
+
+	if err := s.clearFailures(ctx, user); err != nil {
+		return nil, err
+	}
+	return s.finishSecondFactor(ctx, user, token, "Uspešna prijava")
+}
+
+// finishSecondFactor replaces the second factor session holding token with
+// a full session.
+func (s *AuthService) finishSecondFactor(ctx context.Context, user *models.User, token, message string) (*LoginResponse, error) {
+	s.sessions.Revoke(token)
+	if err := s.userRepo.UpdateLastLogin(ctx, user.KorisnikID); err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "login succeeded", "username", user.KorisnickoIme, "user_id", user.KorisnikID, "second_factor", true)
+	user.HashSifre = ""
+	return s.issueSession(user, message, s.sessions.Create)
+}
+
+// EnrollTwoFactor starts setting up a second factor for the account signed
+// in with token, which may be a full or a second factor session. A new
+// secret replaces an unconfirmed one; an account whose factor is already
+// confirmed must have it reset by an administrator first.
+func (s *AuthService) EnrollTwoFactor(ctx context.Context, token string) (*TwoFactorEnrollment, error) {
+	user, _, err := s.twoFactorSession(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	ctx = logging.WithUser(ctx, user.KorisnikID, user.KorisnickoIme)
+
+	raw := make([]byte, 20)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	secret := totpEncoding.EncodeToString(raw)
+
+	if err := s.factors.Begin(ctx, &models.TwoFactor{KorisnikID: user.KorisnikID, Tajna: secret}); err != nil {
+		if errors.Is(err, repositories.ErrConflict) {
+			return nil, conflict("drugi faktor je već uključen")
+		}
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "second factor enrollment started", "target_user_id", user.KorisnikID)
+	return &TwoFactorEnrollment{Tajna: secret, URL: s.provisioningURL(user, secret)}, nil
+}
+
+// provisioningURL returns the otpauth:// link authenticator apps read from
+// a QR code.
+func (s *AuthService) provisioningURL(user *models.User, secret string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", s.cfg.TwoFactorIssuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(totpDigits))
+	query.Set("period", fmt.Sprint(totpPeriod))
+
+	link := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + s.cfg.TwoFactorIssuer + ":" + user.KorisnickoIme,
+		RawQuery: query.Encode(),
+	}
+	return link.String()
+}
+
+// ConfirmTwoFactor puts the enrolled second factor in effect once the user
+// proves the app has it by sending a current code, and returns new
+// recovery codes. Other sessions of the account end. A second factor
+// session is replaced by a full session.
+func (s *AuthService) ConfirmTwoFactor(ctx context.Context, token, code string) (*TwoFactorSetup, error) {
+	user, session, err := s.twoFactorSession(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	ctx = logging.WithUser(ctx, user.KorisnikID, user.KorisnickoIme)
+
+	factor, err := s.factors.Get(ctx, user.KorisnikID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, invalidInput("najpre započnite podešavanje drugog faktora")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if factor.Potvrdjen != nil {
+		return nil, conflict("drugi faktor je već uključen")
+	}
+
+	step, ok := s.matchTOTP(factor.Tajna, code, 0)
+	if !ok {
+		return nil, invalidInput("neispravan kod iz aplikacije za autentifikaciju")
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		if codes[i], err = newCode(2); err != nil {
+			return nil, err
+		}
+		hashes[i] = hashActivationCode(codes[i])
+	}
+
+	if err := s.factors.Confirm(ctx, user.KorisnikID, step, hashes); err != nil {
+		if errors.Is(err, repositories.ErrConflict) {
+			return nil, conflict("drugi faktor je već uključen")
+		}
+		return nil, err
+	}
+
+	audit(ctx, s.activity, ActivityTwoFactorEnabled, user.KorisnikID, "Uključen drugi faktor")
+	slog.InfoContext(ctx, "second factor enabled", "target_user_id", user.KorisnikID)
+
+	s.revokeUserSessions(ctx, user.KorisnikID, token)
+	setup := &TwoFactorSetup{RezervniKodovi: codes}
+	if session.DrugiFaktor {
+		if setup.Prijava, err = s.finishSecondFactor(ctx, user, token, "Drugi faktor je uključen"); err != nil {
+			return nil, err
+		}
+	}
+	return setup, nil
+}
+
+// twoFactorSession resolves the full or second factor session of token
+// and loads its user. Impersonation sessions are refused: an administrator
+// acting as the user could otherwise lock the user out behind a secret only
+// the administrator knows.
+func (s *AuthService) twoFactorSession(ctx context.Context, token string) (*models.User, *Session, error) {
+	session, err := s.sessions.Resolve(token)
+	if err != nil {
+		return nil, nil, err
+	}
+	if session.Aktivacija {
+		return nil, nil, ErrPasswordChangeRequired
+	}
+	if session.Zastupanje != nil {
+		ctx = WithPrincipal(ctx, &Principal{User: &models.User{KorisnikID: session.KorisnikID}, Impersonation: session.Zastupanje})
+		s.RecordImpersonatedCall(ctx, "Odbijeno podešavanje drugog faktora")
+		return nil, nil, refuseImpersonated(ctx)
+	}
+
+	user, err := s.userRepo.GetByID(ctx, session.KorisnikID)
+	if err != nil || user.Status != "aktivan" || session.revokedBefore(user) {
+		s.sessions.Revoke(token)
+		return nil, nil, ErrNoSession
+	}
+	return user, session, nil
+}
+
+// GetTwoFactorStatus describes the second factor of a user.
+func (s *AuthService) GetTwoFactorStatus(ctx context.Context, userID int) (*TwoFactorStatus, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &TwoFactorStatus{Obavezan: s.requiresTwoFactor(user)}
+	factor, err := s.factors.Get(ctx, userID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return status, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	status.Ukljucen = factor.Potvrdjen != nil
+	if status.Ukljucen {
+		if status.PreostaloKodova, err = s.factors.RecoveryCodesLeft(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+// ResetTwoFactor removes the second factor and recovery codes of a user who
+// lost them and ends the user's sessions. If the role requires a second
+// factor, the next sign-in sets up a new one.
+func (s *AuthService) ResetTwoFactor(ctx context.Context, userID int) error {
+	if _, err := s.authz.Require(ctx, PermUserManage); err != nil {
+		return err
+	}
+
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.factors.Delete(ctx, userID); err != nil {
+		return err
+	}
+	s.revokeUserSessions(ctx, userID, "")
+
+	audit(ctx, s.activity, ActivityTwoFactorReset, userID, "Drugi faktor i rezervni kodovi uklonjeni")
+	slog.InfoContext(ctx, "second factor reset", "target_user_id", userID)
+	return nil
+}
@@ -0,0 +1,347 @@
+// ============================================================================
+// unit_service.go - Organizational units and the reporting hierarchy
+// ============================================================================
+
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/repositories"
+)
+
+// UnitTypes are the kinds of organizational units, from the largest.
+var UnitTypes = []string{"institut", "odeljenje", "laboratorija", "grupa"}
+
+// UnitRequest describes a new unit or the new state of a unit. A unit
+// without nadredjena_jedinica_id is at the top of the hierarchy.
+type UnitRequest struct {
+	Naziv                string `json:"naziv"`
+	Tip                  string `json:"tip"`
+	NadredjenaJedinicaID *int   `json:"nadredjena_jedinica_id"`
+	RukovodilacID        *int   `json:"rukovodilac_id"`
+}
+
+// UnitService manages the organizational units of the institute and which
+// unit users and projects belong to.
+type UnitService struct {
+	units    repositories.UnitStore
+	users    repositories.UserStore
+	projects repositories.ProjectStore
+	activity repositories.AnalyticsStore
+	authz    *Authorizer
+}
+
+func NewUnitService(stores repositories.Stores, authz *Authorizer) *UnitService {
+	return &UnitService{
+		units:    stores.Units,
+		users:    stores.Users,
+		projects: stores.Projects,
+		activity: stores.Analytics,
+		authz:    authz,
+	}
+}
+
+// GetUnits returns every unit, by name. Callers build the hierarchy from
+// nadredjena_jedinica_id.
+func (s *UnitService) GetUnits(ctx context.Context) ([]models.Unit, error) {
+	if _, err := s.authz.Require(ctx, PermUnitView); err != nil {
+		return nil, err
+	}
+	return s.units.GetAll(ctx)
+}
+
+func (s *UnitService) GetUnit(ctx context.Context, unitID int) (*models.Unit, error) {
+	if _, err := s.authz.Require(ctx, PermUnitView); err != nil {
+		return nil, err
+	}
+	return s.units.GetByID(ctx, unitID)
+}
+
+// GetUnitMembers returns the users who belong directly to the unit.
+func (s *UnitService) GetUnitMembers(ctx context.Context, unitID int) ([]models.User, error) {
+	if _, err := s.authz.Require(ctx, PermUnitView); err != nil {
+		return nil, err
+	}
+	if _, err := s.units.GetByID(ctx, unitID); err != nil {
+		return nil, err
+	}
+	return s.units.GetMembers(ctx, unitID)
+}
+
+func (s *UnitService) CreateUnit(ctx context.Context, req UnitRequest) (*models.Unit, error) {
+	if _, err := s.authz.Require(ctx, PermUnitManage); err != nil {
+		return nil, err
+	}
+
+	unit, err := s.checkRequest(ctx, 0, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.units.Create(ctx, unit); err != nil {
+		return nil, err
+	}
+
+	s.changed(ctx, ActivityUnitCreated, unit.JedinicaID, fmt.Sprintf("Kreirana jedinica %s (%s)", unit.Naziv, unit.Tip))
+	return s.units.GetByID(ctx, unit.JedinicaID)
+}
+
+// UpdateUnit renames a unit, changes its type or head, or moves it with its
+// subunits under another unit.
+func (s *UnitService) UpdateUnit(ctx context.Context, unitID int, req UnitRequest) (*models.Unit, error) {
+	if _, err := s.authz.Require(ctx, PermUnitManage); err != nil {
+		return nil, err
+	}
+
+	current, err := s.units.GetByID(ctx, unitID)
+	if err != nil {
+		return nil, err
+	}
+	unit, err := s.checkRequest(ctx, unitID, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.units.Update(ctx, unit); err != nil {
+		return nil, err
+	}
+
+	description := fmt.Sprintf("Izmenjena jedinica %s (%s)", unit.Naziv, unit.Tip)
+	if current.Naziv != unit.Naziv {
+		description = fmt.Sprintf("Izmenjena jedinica %s, ranije %s (%s)", unit.Naziv, current.Naziv, unit.Tip)
+	}
+	s.changed(ctx, ActivityUnitChanged, unitID, description)
+	return s.units.GetByID(ctx, unitID)
+}
+
+// DeleteUnit removes a unit without subunits. Its members and projects are
+// kept without a unit.
+func (s *UnitService) DeleteUnit(ctx context.Context, unitID int) error {
+	if _, err := s.authz.Require(ctx, PermUnitManage); err != nil {
+		return err
+	}
+
+	unit, err := s.units.GetByID(ctx, unitID)
+	if err != nil {
+		return err
+	}
+	if err := s.units.Delete(ctx, unitID); err != nil {
+		if errors.Is(err, repositories.ErrConflict) {
+			return conflict(fmt.Sprintf("jedinica %s ima podjedinice; premestite ih ili obrišite pre brisanja", unit.Naziv))
+		}
+		return err
+	}
+
+	s.changed(ctx, ActivityUnitDeleted, unitID, fmt.Sprintf("Obrisana jedinica %s (%d članova, %d projekata bez jedinice)",
+		unit.Naziv, unit.BrojClanova, unit.BrojProjekata))
+	return nil
+}
+
+// SetUserUnit moves a user to a unit, or out of any unit when unitID is nil.
+func (s *UnitService) SetUserUnit(ctx context.Context, userID int, unitID *int) error {
+	if _, err := s.authz.Require(ctx, PermUnitManage); err != nil {
+		return err
+	}
+
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	name, err := s.unitName(ctx, unitID)
+	if err != nil {
+		return err
+	}
+	if err := s.units.SetUserUnit(ctx, userID, unitID); err != nil {
+		return err
+	}
+
+	audit(ctx, s.activity, ActivityUserUnitChanged, userID, fmt.Sprintf("Korisnik %s premešten u jedinicu %s", user.KorisnickoIme, name))
+	slog.InfoContext(ctx, "user unit changed", "target_user_id", userID, "unit", name)
+	return nil
+}
+
+// SetProjectUnit moves a project to a unit, or out of any unit when unitID
+// is nil. Unit managers may move any project; others need project.update in
+// the project and may move it only into units they see.
+func (s *UnitService) SetProjectUnit(ctx context.Context, projectID int, unitID *int) error {
+	if _, err := s.authz.RequireInProject(ctx, PermProjectUpdate, projectID); err != nil {
+		return err
+	}
+
+	project, err := s.projects.GetByID(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	if err := requireVisible(ctx, s.projects, s.units, s.authz, project); err != nil {
+		return err
+	}
+	scope, err := unitScope(ctx, s.units, s.authz)
+	if err != nil {
+		return err
+	}
+	if scope != nil && (unitID == nil || !scope[*unitID]) {
+		return fmt.Errorf("%w (projekat se može premestiti samo u jedinicu iz vaše nadležnosti)", ErrForbidden)
+	}
+	name, err := s.unitName(ctx, unitID)
+	if err != nil {
+		return err
+	}
+	if err := s.units.SetProjectUnit(ctx, projectID, unitID); err != nil {
+		return err
+	}
+
+	record(ctx, s.activity, ActivityProjectUnitChanged, auditProjectEntity, projectID,
+		fmt.Sprintf("Projekat %s premešten u jedinicu %s", project.NazivProjekta, name))
+	slog.InfoContext(ctx, "project unit changed", "project_id", projectID, "unit", name)
+	return nil
+}
+
+// unitName returns the name of the unit for the activity log, checking that
+// it exists.
+func (s *UnitService) unitName(ctx context.Context, unitID *int) (string, error) {
+	if unitID == nil {
+		return "(bez jedinice)", nil
+	}
+	unit, err := s.units.GetByID(ctx, *unitID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return "", invalidInput(fmt.Sprintf("jedinica %d ne postoji", *unitID))
+	}
+	if err != nil {
+		return "", err
+	}
+	return unit.Naziv, nil
+}
+
+// checkRequest validates req and returns the unit it describes. unitID is
+// the unit being changed, 0 for a new one.
+func (s *UnitService) checkRequest(ctx context.Context, unitID int, req UnitRequest) (*models.Unit, error) {
+	name := strings.TrimSpace(req.Naziv)
+	if name == "" || utf8.RuneCountInString(name) > 100 {
+		return nil, invalidInput("naziv jedinice je obavezan i ima najviše 100 znakova")
+	}
+	kind := req.Tip
+	if kind == "" {
+		kind = "odeljenje"
+	}
+	known := false
+	for _, t := range UnitTypes {
+		known = known || t == kind
+	}
+	if !known {
+		return nil, invalidInput(fmt.Sprintf("nepoznat tip jedinice %q; dozvoljeni su %s", kind, strings.Join(UnitTypes, ", ")))
+	}
+
+	units, err := s.units.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	parents := make(map[int]*int, len(units))
+	for _, other := range units {
+		if other.JedinicaID != unitID && strings.EqualFold(other.Naziv, name) {
+			return nil, conflict(fmt.Sprintf("jedinica %s već postoji", other.Naziv))
+		}
+		parents[other.JedinicaID] = other.NadredjenaJedinicaID
+	}
+
+	if req.NadredjenaJedinicaID != nil {
+		if _, ok := parents[*req.NadredjenaJedinicaID]; !ok {
+			return nil, invalidInput(fmt.Sprintf("nadređena jedinica %d ne postoji", *req.NadredjenaJedinicaID))
+		}
+		// Walking up from the new parent must not reach the unit itself
+		for id := req.NadredjenaJedinicaID; id != nil; id = parents[*id] {
+			if *id == unitID {
+				return nil, invalidInput("jedinica ne može biti podređena sama sebi ni svojim podjedinicama")
+			}
+		}
+	}
+	if req.RukovodilacID != nil {
+		head, err := s.users.GetByID(ctx, *req.RukovodilacID)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, invalidInput(fmt.Sprintf("korisnik %d ne postoji", *req.RukovodilacID))
+		}
+		if err != nil {
+			return nil, err
+		}
+		if head.Status != "aktivan" {
+			return nil, invalidInput("rukovodilac jedinice mora imati aktivan nalog")
+		}
+	}
+
+	return &models.Unit{
+		JedinicaID:           unitID,
+		Naziv:                name,
+		Tip:                  kind,
+		NadredjenaJedinicaID: req.NadredjenaJedinicaID,
+		RukovodilacID:        req.RukovodilacID,
+	}, nil
+}
+
+func (s *UnitService) changed(ctx context.Context, activity string, unitID int, description string) {
+	record(ctx, s.activity, activity, auditUnitEntity, unitID, description)
+	slog.InfoContext(ctx, "unit changed", "activity", activity, "unit_id", unitID)
+}
+
+// unitScope returns the units whose projects and figures the caller sees:
+// the subtrees of the caller's own unit and of every unit the caller heads.
+// It returns nil, meaning all units, for system callers, unit managers and
+// users placed in no unit and heading none, so the hierarchy narrows what
+// people see only once they are placed in it.
+func unitScope(ctx context.Context, units repositories.UnitStore, authz *Authorizer) (map[int]bool, error) {
+	principal, ok := PrincipalFrom(ctx)
+	if !ok {
+		return nil, ErrNoSession
+	}
+	// Guests see only what is shared with them, wherever it is
+	if principal.System || principal.Guest != nil || authz.Can(principal.User, PermUnitManage) {
+		return nil, nil
+	}
+
+	all, err := units.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	children := map[int][]int{}
+	var roots []int
+	for _, unit := range all {
+		if unit.NadredjenaJedinicaID != nil {
+			children[*unit.NadredjenaJedinicaID] = append(children[*unit.NadredjenaJedinicaID], unit.JedinicaID)
+		}
+		if unit.RukovodilacID != nil && *unit.RukovodilacID == principal.User.KorisnikID {
+			roots = append(roots, unit.JedinicaID)
+		}
+	}
+	if principal.User.JedinicaID != nil {
+		roots = append(roots, *principal.User.JedinicaID)
+	}
+	if len(roots) == 0 {
+		return nil, nil
+	}
+
+	scope := map[int]bool{}
+	for len(roots) > 0 {
+		id := roots[len(roots)-1]
+		roots = roots[:len(roots)-1]
+		if !scope[id] {
+			scope[id] = true
+			roots = append(roots, children[id]...)
+		}
+	}
+	return scope, nil
+}
+
+// scopeIDs lists the units of a scope for the stores, nil for all units.
+func scopeIDs(scope map[int]bool) []int {
+	if scope == nil {
+		return nil
+	}
+	ids := make([]int, 0, len(scope))
+	for id := range scope {
+		ids = append(ids, id)
+	}
+	return ids
+}
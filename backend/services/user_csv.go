@@ -0,0 +1,372 @@
+// ============================================================================
+// user_csv.go - Bulk user import and export as CSV
+// ============================================================================
+
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/mail"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/cane/research-institute-system/backend/models"
+)
+
+// MaxImportRows limits the users one CSV import creates.
+const MaxImportRows = 1000
+
+// userColumns are the CSV columns of the user export. Import reads the first
+// five and ignores the rest, so an exported file can be edited and imported.
+var userColumns = []string{"korisnicko_ime", "email", "ime", "prezime", "uloga",
+	"status", "izvor_prijave", "poslednja_prijava", "kreiran_datuma"}
+
+// requiredImportColumns must be in the header of an imported file.
+var requiredImportColumns = []string{"korisnicko_ime", "email", "uloga"}
+
+// UserImportOptions selects how ImportUsers treats the file.
+type UserImportOptions struct {
+	// DryRun validates the file and creates nothing.
+	DryRun bool
+	// PerRow creates the valid rows even if other rows are invalid. By
+	// default one invalid row rejects the whole file.
+	PerRow bool
+}
+
+// UserImportRow is the outcome of one row of an imported file.
+type UserImportRow struct {
+	Red           int    `json:"red"` // line of the file, the header is line 1
+	KorisnickoIme string `json:"korisnicko_ime"`
+	Email         string `json:"email"`
+	Ime           string `json:"ime,omitempty"`
+	Prezime       string `json:"prezime,omitempty"`
+	Uloga         string `json:"uloga"`
+	// Greske lists what is wrong with the row; such rows are not created.
+	Greske []string `json:"greske,omitempty"`
+	// Aktivacija is the activation code of a created account.
+	Aktivacija *ActivationCode `json:"aktivacija,omitempty"`
+}
+
+// UserImportReport is the outcome of a CSV import. Kreirani is 0 for a dry
+// run and for a file rejected as a whole.
+type UserImportReport struct {
+	Proba    bool            `json:"proba"`
+	Redovi   []UserImportRow `json:"redovi"`
+	Ispravni int             `json:"ispravni"`
+	Kreirani int             `json:"kreirani"`
+}
+
+// ImportUsers creates accounts from CSV with the columns korisnicko_ime,
+// email, ime, prezime and uloga (a role name or ID), separated by commas or
+// semicolons. Every created account awaits activation with its own code.
+// Rows are checked for missing and malformed values, unknown roles, and
+// usernames and e-mail addresses that exist or repeat in the file, ignoring
+// case. Invalid rows are reported, not returned as an error; errors are for
+// files that cannot be read.
+func (s *AuthService) ImportUsers(ctx context.Context, data io.Reader, opts UserImportOptions) (*UserImportReport, error) {
+	if _, err := s.authz.Require(ctx, PermUserManage); err != nil {
+		return nil, err
+	}
+
+	rows, err := readUserCSV(data)
+	if err != nil {
+		return nil, err
+	}
+	users, err := s.checkImport(ctx, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &UserImportReport{Proba: opts.DryRun, Redovi: rows}
+	for _, row := range rows {
+		if len(row.Greske) == 0 {
+			report.Ispravni++
+		}
+	}
+	if opts.DryRun || report.Ispravni == 0 || (!opts.PerRow && report.Ispravni < len(rows)) {
+		slog.InfoContext(ctx, "user import checked", "rows", len(rows), "valid", report.Ispravni, "dry_run", opts.DryRun)
+		return report, nil
+	}
+
+	for i := range report.Redovi {
+		row := &report.Redovi[i]
+		if len(row.Greske) > 0 {
+			continue
+		}
+		activation, err := s.createUser(ctx, users[i])
+		if err == nil {
+			row.Aktivacija = activation
+			report.Kreirani++
+			continue
+		}
+		if !opts.PerRow {
+			// All or nothing: remove the accounts created so far
+			s.undoImport(ctx, report.Redovi[:i])
+			return nil, fmt.Errorf("red %d: %w", row.Red, err)
+		}
+		row.Greske = append(row.Greske, err.Error())
+		report.Ispravni--
+	}
+
+	slog.InfoContext(ctx, "users imported", "rows", len(rows), "created", report.Kreirani, "per_row", opts.PerRow)
+	return report, nil
+}
+
+// undoImport deletes the accounts created for rows. They are new, so only
+// their activation codes refer to them, and those cascade.
+func (s *AuthService) undoImport(ctx context.Context, rows []UserImportRow) {
+	for _, row := range rows {
+		if row.Aktivacija == nil {
+			continue
+		}
+		if err := s.userRepo.Delete(ctx, row.Aktivacija.KorisnikID); err != nil {
+			slog.ErrorContext(ctx, "imported user not removed", "target_user_id", row.Aktivacija.KorisnikID, "error", err)
+		}
+	}
+	slog.WarnContext(ctx, "user import rolled back", "rows", len(rows))
+}
+
+// readUserCSV parses the file into rows. The delimiter is the first comma
+// or semicolon of the header, since spreadsheets in many locales export
+// semicolons.
+func readUserCSV(data io.Reader) ([]UserImportRow, error) {
+	content, err := io.ReadAll(data)
+	if err != nil {
+		return nil, err
+	}
+	content = bytes.TrimPrefix(content, []byte("\ufeff"))
+	if !utf8.Valid(content) {
+		return nil, invalidInput("CSV fajl mora biti u UTF-8 kodiranju")
+	}
+
+	reader := csv.NewReader(bytes.NewReader(content))
+	first, _, _ := bytes.Cut(content, []byte("\n"))
+	if i := bytes.IndexAny(first, ",;"); i >= 0 {
+		reader.Comma = rune(first[i])
+	}
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, invalidInput("CSV fajl je prazan")
+	}
+	if err != nil {
+		return nil, invalidInput("neispravan CSV: " + err.Error())
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !hasColumn(userColumns, name) {
+			return nil, invalidInput(fmt.Sprintf("nepoznata kolona %q", name))
+		}
+		if _, ok := columns[name]; ok {
+			return nil, invalidInput(fmt.Sprintf("kolona %q se ponavlja", name))
+		}
+		columns[name] = i
+	}
+	for _, name := range requiredImportColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, invalidInput(fmt.Sprintf("nedostaje kolona %q", name))
+		}
+	}
+
+	rows := []UserImportRow{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, invalidInput("neispravan CSV: " + err.Error())
+		}
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+		if len(rows) == MaxImportRows {
+			return nil, invalidInput(fmt.Sprintf("CSV fajl može imati najviše %d korisnika", MaxImportRows))
+		}
+
+		field := func(name string) string {
+			if column, ok := columns[name]; ok && column < len(record) {
+				return strings.TrimSpace(record[column])
+			}
+			return ""
+		}
+		line, _ := reader.FieldPos(0)
+		rows = append(rows, UserImportRow{
+			Red:           line,
+			KorisnickoIme: field("korisnicko_ime"),
+			Email:         field("email"),
+			Ime:           field("ime"),
+			Prezime:       field("prezime"),
+			Uloga:         field("uloga"),
+		})
+	}
+	if len(rows) == 0 {
+		return nil, invalidInput("CSV fajl nema nijednog korisnika")
+	}
+	return rows, nil
+}
+
+func hasColumn(columns []string, name string) bool {
+	for _, column := range columns {
+		if column == name {
+			return true
+		}
+	}
+	return false
+}
+
+// checkImport records the errors of each row and returns the user each row
+// describes.
+func (s *AuthService) checkImport(ctx context.Context, rows []UserImportRow) ([]*models.User, error) {
+	existing, err := s.userRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	roles, err := s.userRepo.GetRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	usernames, emails := map[string]string{}, map[string]string{}
+	for _, user := range existing {
+		usernames[strings.ToLower(user.KorisnickoIme)] = "već postoji"
+		emails[strings.ToLower(user.Email)] = "već postoji"
+	}
+
+	users := make([]*models.User, len(rows))
+	for i := range rows {
+		row := &rows[i]
+		user := &models.User{KorisnickoIme: row.KorisnickoIme, Email: row.Email}
+		users[i] = user
+		fail := func(format string, args ...interface{}) {
+			row.Greske = append(row.Greske, fmt.Sprintf(format, args...))
+		}
+
+		username := strings.ToLower(row.KorisnickoIme)
+		switch {
+		case row.KorisnickoIme == "":
+			fail("korisničko ime je obavezno")
+		case utf8.RuneCountInString(row.KorisnickoIme) > 100 || strings.IndexFunc(row.KorisnickoIme, unicode.IsSpace) >= 0:
+			fail("korisničko ime ima najviše 100 znakova i nema razmake")
+		case usernames[username] != "":
+			fail("korisničko ime %s %s", row.KorisnickoIme, usernames[username])
+		default:
+			usernames[username] = fmt.Sprintf("se ponavlja u redu %d", row.Red)
+		}
+
+		email := strings.ToLower(row.Email)
+		switch {
+		case row.Email == "":
+			fail("email je obavezan")
+		case !validEmail(row.Email):
+			fail("neispravna email adresa %q", row.Email)
+		case emails[email] != "":
+			fail("email %s %s", row.Email, emails[email])
+		default:
+			emails[email] = fmt.Sprintf("se ponavlja u redu %d", row.Red)
+		}
+
+		if utf8.RuneCountInString(row.Ime) > 100 || utf8.RuneCountInString(row.Prezime) > 100 {
+			fail("ime i prezime imaju najviše 100 znakova")
+		}
+		if row.Ime != "" {
+			user.Ime = &row.Ime
+		}
+		if row.Prezime != "" {
+			user.Prezime = &row.Prezime
+		}
+
+		if row.Uloga == "" {
+			fail("uloga je obavezna")
+		} else if role := importRole(roles, row.Uloga); role == nil {
+			fail("nepoznata uloga %q", row.Uloga)
+		} else if user.UlogaID = role.UlogaID; !mayAssign(ctx, s.authz, role.NazivUloge) {
+			fail("uloga %s daje dozvole koje nemate", role.NazivUloge)
+		}
+	}
+	return users, nil
+}
+
+// validEmail accepts a bare address of at most 100 characters.
+func validEmail(email string) bool {
+	address, err := mail.ParseAddress(email)
+	return err == nil && address.Address == email && len(email) <= 100
+}
+
+// importRole returns the role named or numbered by role, or nil.
+func importRole(roles []models.Role, role string) *models.Role {
+	id, err := strconv.Atoi(role)
+	for i, r := range roles {
+		if (err == nil && r.UlogaID == id) || strings.EqualFold(r.NazivUloge, role) {
+			return &roles[i]
+		}
+	}
+	return nil
+}
+
+// ExportUsers returns every user with its role as CSV, in the columns the
+// import reads followed by status, sign-in source, last sign-in and creation
+// time. Values a spreadsheet would run as a formula are prefixed with '.
+func (s *UserService) ExportUsers(ctx context.Context) ([]byte, error) {
+	if _, err := s.authz.Require(ctx, PermUserView); err != nil {
+		return nil, err
+	}
+
+	users, err := s.users.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write(userColumns)
+	for _, user := range users {
+		lastLogin := ""
+		if user.PoslednajaPrijava != nil {
+			lastLogin = user.PoslednajaPrijava.Format("2006-01-02 15:04")
+		}
+		w.Write([]string{
+			spreadsheetSafe(user.KorisnickoIme),
+			spreadsheetSafe(user.Email),
+			spreadsheetSafe(optional(user.Ime)),
+			spreadsheetSafe(optional(user.Prezime)),
+			user.NazivUloge,
+			user.Status,
+			user.IzvorPrijave,
+			lastLogin,
+			user.KreiranDatuma.Format("2006-01-02 15:04"),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "users exported", "users", len(users))
+	return buf.Bytes(), nil
+}
+
+func optional(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+// spreadsheetSafe keeps a spreadsheet from reading a value as a formula.
+func spreadsheetSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -5,122 +5,167 @@
 package services
 
 import (
-	"database/sql"
+	"context"
 	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/cane/research-institute-system/backend/models"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/cane/research-institute-system/backend/repositories"
 )
 
 type UserService struct {
-	db *sql.DB
+	users    repositories.UserStore
+	activity repositories.AnalyticsStore
+	auth     *AuthService
+	authz    *Authorizer
 }
 
-func NewUserService(db *sql.DB) *UserService {
-	return &UserService{db: db}
+func NewUserService(stores repositories.Stores, auth *AuthService, authz *Authorizer) *UserService {
+	return &UserService{users: stores.Users, activity: stores.Analytics, auth: auth, authz: authz}
 }
 
-func (s *UserService) GetAllUsers() ([]models.Korisnici, error) {
-	query := `
-		SELECT k.korisnik_id, k.korisnicko_ime, k.email, k.ime, k.prezime,
-		       k.uloga_id, k.status, k.poslednja_prijava, k.kreiran_datuma,
-		       u.naziv_uloge
-		FROM korisnici k
-		JOIN uloge u ON k.uloga_id = u.uloga_id
-		ORDER BY k.kreiran_datuma DESC
-	`
-
-	rows, err := s.db.Query(query)
-	if err != nil {
+func (s *UserService) GetAllUsers(ctx context.Context) ([]models.Korisnici, error) {
+	if _, err := s.authz.Require(ctx, PermUserView); err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var users []models.Korisnici
-	for rows.Next() {
-		var user models.Korisnici
-		err := rows.Scan(
-			&user.KorisnikID, &user.KorisnickoIme, &user.Email,
-			&user.Ime, &user.Prezime, &user.UlogaID, &user.Status,
-			&user.PoslednajaPrijava, &user.KreiranDatuma, &user.NazivUloge,
-		)
-		if err != nil {
-			return nil, err
-		}
-		users = append(users, user)
-	}
 
-	return users, nil
+	return s.users.GetAll(ctx)
 }
 
-func (s *UserService) CreateUser(user models.Korisnici, password string) error {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+// UpdateUser changes the account data of a user. Fields missing from the
+// request keep their current values. The role is changed with
+// RoleService.AssignRole, which writes the change to the activity log; a
+// deactivated user's sessions end at once.
+func (s *UserService) UpdateUser(ctx context.Context, userID int, user models.Korisnici) error {
+	if _, err := s.authz.Require(ctx, PermUserManage); err != nil {
+		return err
+	}
+
+	current, err := s.users.GetByID(ctx, userID)
 	if err != nil {
 		return err
 	}
+	if user.UlogaID == 0 {
+		user.UlogaID = current.UlogaID
+	}
+	if user.UlogaID != current.UlogaID {
+		return invalidInput("uloga se menja dodelom uloge (PUT /users/{id}/role)")
+	}
+	if user.KorisnickoIme == "" {
+		user.KorisnickoIme = current.KorisnickoIme
+	}
+	if user.Email == "" {
+		user.Email = current.Email
+	}
+	if user.Ime == nil {
+		user.Ime = current.Ime
+	}
+	if user.Prezime == nil {
+		user.Prezime = current.Prezime
+	}
+	if user.Status == "" {
+		user.Status = current.Status
+	}
+	if err := s.checkUpdate(ctx, current, &user); err != nil {
+		return err
+	}
 
-	query := `
-		INSERT INTO korisnici (korisnicko_ime, email, hash_sifre, ime, prezime, uloga_id, status)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-	`
+	user.KorisnikID = userID
+	if err := s.users.Update(ctx, &user); err != nil {
+		return err
+	}
 
-	_, err = s.db.Exec(query, user.KorisnickoIme, user.Email, string(hashedPassword),
-		user.Ime, user.Prezime, user.UlogaID, user.Status)
+	changes := []string{}
+	if user.KorisnickoIme != current.KorisnickoIme {
+		changes = append(changes, fmt.Sprintf("korisničko ime %s (ranije %s)", user.KorisnickoIme, current.KorisnickoIme))
+	}
+	if user.Email != current.Email {
+		changes = append(changes, fmt.Sprintf("email %s (ranije %s)", user.Email, current.Email))
+	}
+	if !sameName(user.Ime, current.Ime) || !sameName(user.Prezime, current.Prezime) {
+		changes = append(changes, "ime i prezime")
+	}
+	if user.Status != current.Status {
+		changes = append(changes, fmt.Sprintf("status %s (ranije %s)", user.Status, current.Status))
+		if user.Status != "aktivan" {
+			s.auth.revokeUserSessions(ctx, userID, "")
+		}
+	}
+	if len(changes) > 0 {
+		audit(ctx, s.activity, ActivityUserUpdated, userID, "Izmenjeno: "+strings.Join(changes, ", "))
+	}
 
-	return err
+	slog.InfoContext(ctx, "user updated", "target_user_id", userID, "status", user.Status)
+	return nil
 }
 
-func (s *UserService) UpdateUser(userID int, user models.Korisnici) error {
-	query := `
-		UPDATE korisnici 
-		SET korisnicko_ime = $1, email = $2, ime = $3, prezime = $4, 
-		    uloga_id = $5, status = $6
-		WHERE korisnik_id = $7
-	`
-
-	_, err := s.db.Exec(query, user.KorisnickoIme, user.Email, user.Ime,
-		user.Prezime, user.UlogaID, user.Status, userID)
+// checkUpdate validates the merged user data and keeps usernames and email
+// addresses unique, so a clash is a conflict rather than a database error.
+func (s *UserService) checkUpdate(ctx context.Context, current, user *models.Korisnici) error {
+	if utf8.RuneCountInString(user.KorisnickoIme) > 100 || strings.IndexFunc(user.KorisnickoIme, unicode.IsSpace) >= 0 {
+		return invalidInput("korisničko ime ima najviše 100 znakova i nema razmake")
+	}
+	if user.Email != current.Email && !validEmail(user.Email) {
+		return invalidInput(fmt.Sprintf("neispravna email adresa %q", user.Email))
+	}
+	if user.Status != "aktivan" && user.Status != "neaktivan" {
+		return invalidInput("status mora biti aktivan ili neaktivan")
+	}
 
-	return err
+	others, err := s.users.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+	for _, other := range others {
+		if other.KorisnikID == current.KorisnikID {
+			continue
+		}
+		if strings.EqualFold(other.KorisnickoIme, user.KorisnickoIme) {
+			return conflict("korisnik sa tim korisničkim imenom već postoji")
+		}
+		if strings.EqualFold(other.Email, user.Email) {
+			return conflict("korisnik sa tom email adresom već postoji")
+		}
+	}
+	return nil
 }
 
-func (s *UserService) DeleteUser(userID int) error {
-	query := `DELETE FROM korisnici WHERE korisnik_id = $1`
-	result, err := s.db.Exec(query, userID)
-	if err != nil {
+// DeleteUser removes a user that nothing refers to any more, checked in the
+// transaction that deletes. Entries in the activity log count too, so only
+// accounts that never did anything logged there, such as ones created by
+// mistake, can be deleted. Users with work or history are offboarded instead
+// (AuthService.OffboardUser), which keeps the history and hands the work over.
+func (s *UserService) DeleteUser(ctx context.Context, userID int) error {
+	if _, err := s.authz.Require(ctx, PermUserManage); err != nil {
 		return err
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	references, err := s.users.DeleteUnreferenced(ctx, userID)
 	if err != nil {
 		return err
 	}
-
-	if rowsAffected == 0 {
-		return fmt.Errorf("user with ID %d not found", userID)
+	if len(references) > 0 {
+		tables := make([]string, 0, len(references))
+		for table, count := range references {
+			tables = append(tables, fmt.Sprintf("%s (%d)", table, count))
+		}
+		sort.Strings(tables)
+		return conflict("korisnik se ne može obrisati jer ga koriste: " + strings.Join(tables, ", ") +
+			"; deaktivirajte ga i predajte njegov posao")
 	}
 
+	slog.InfoContext(ctx, "user deleted", "target_user_id", userID)
 	return nil
 }
 
-func (s *UserService) GetAllRoles() ([]models.Uloge, error) {
-	query := `SELECT uloga_id, naziv_uloge FROM uloge ORDER BY naziv_uloge`
-
-	rows, err := s.db.Query(query)
-	if err != nil {
+func (s *UserService) GetAllRoles(ctx context.Context) ([]models.Uloge, error) {
+	if _, err := s.authz.Require(ctx, PermUserView); err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var roles []models.Uloge
-	for rows.Next() {
-		var role models.Uloge
-		err := rows.Scan(&role.UlogaID, &role.NazivUloge)
-		if err != nil {
-			return nil, err
-		}
-		roles = append(roles, role)
-	}
 
-	return roles, nil
+	return s.users.GetRoles(ctx)
 }
@@ -0,0 +1,1016 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cane/research-institute-system/backend/api"
+	"github.com/cane/research-institute-system/backend/config"
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/repositories"
+	"github.com/cane/research-institute-system/backend/repositories/memory"
+	"github.com/cane/research-institute-system/backend/services"
+)
+
+// apiClient šalje zahteve REST API-ju nad memorijskim repozitorijumima
+type apiClient struct {
+	t      *testing.T
+	server *httptest.Server
+	stores repositories.Stores
+	svc    *services.Services
+}
+
+func newAPIClient(t *testing.T) *apiClient {
+	t.Helper()
+
+	// Svi zahtevi dolaze sa iste adrese, pa bi kašnjenje posle pogrešne
+	// lozinke usporilo i ostale prijave u testu
+	cfg := config.Default()
+	cfg.Auth.LoginDelay = 0
+	// Administratori se u ovim testovima prijavljuju samo lozinkom
+	cfg.Auth.TwoFactorRoles = nil
+	return newAPIClientWithConfig(t, cfg)
+}
+
+func newAPIClientWithConfig(t *testing.T, cfg *config.Config) *apiClient {
+	t.Helper()
+
+	stores := memory.NewStores()
+	sessions := services.NewSessionManager(services.DefaultSessionIdleTimeout, services.DefaultSessionMaxLifetime)
+	cfg.Storage.UploadPath = filepath.Join(t.TempDir(), "uploads")
+	svc := services.New(cfg, stores, sessions, newTestAuthorizer(t, stores))
+
+	server := httptest.NewServer(api.New(svc))
+	t.Cleanup(server.Close)
+
+	return &apiClient{t: t, server: server, stores: stores, svc: svc}
+}
+
+// login kreira korisnika sa lozinkom koji se već prijavljivao i vraća njegov token
+func (c *apiClient) login(username string, roleID int) string {
+	c.t.Helper()
+
+	user, _ := newMemoryUser(c.t, c.stores, username, roleID)
+	hash, err := c.svc.Auth.HashPassword("lozinka123")
+	if err != nil {
+		c.t.Fatalf("Greška pri heširanju: %v", err)
+	}
+	ctx := context.Background()
+	c.stores.Users.UpdatePassword(ctx, user.KorisnikID, hash, false)
+	c.stores.Users.UpdateLastLogin(ctx, user.KorisnikID)
+
+	var response struct {
+		Data services.LoginResponse `json:"data"`
+	}
+	status := c.do("POST", "/auth/login", "", map[string]string{"username": username, "password": "lozinka123"}, &response)
+	if status != http.StatusOK || response.Data.Token == "" {
+		c.t.Fatalf("Prijava nije uspela: status %d, %+v", status, response)
+	}
+	return response.Data.Token
+}
+
+// do šalje JSON zahtev i dekodira odgovor u out, ako je zadat
+func (c *apiClient) do(method, path, token string, body, out interface{}) int {
+	c.t.Helper()
+
+	var reader *bytes.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req, _ := http.NewRequest(method, c.server.URL+api.Prefix+path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	return c.send(req, token, out)
+}
+
+func (c *apiClient) send(req *http.Request, token string, out interface{}) int {
+	c.t.Helper()
+
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		c.t.Fatalf("Zahtev %s %s nije uspeo: %v", req.Method, req.URL.Path, err)
+	}
+	defer resp.Body.Close()
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			c.t.Fatalf("Neispravan JSON odgovor na %s %s: %v", req.Method, req.URL.Path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+type apiErrorBody struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Test prijave, autentifikacije i oblika grešaka
+func TestAPIAuthentication(t *testing.T) {
+	c := newAPIClient(t)
+
+	var errBody apiErrorBody
+	if status := c.do("GET", "/projects", "", nil, &errBody); status != http.StatusUnauthorized || errBody.Error.Code != "unauthorized" {
+		t.Errorf("Zahtev bez tokena mora vratiti 401 unauthorized, dobijeno %d %+v", status, errBody)
+	}
+	if status := c.do("GET", "/projects", "nepostojeci", nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("Nepoznat token mora vratiti 401, dobijeno %d", status)
+	}
+
+	c.login("istrazivac", 3)
+	errBody = apiErrorBody{}
+	status := c.do("POST", "/auth/login", "", map[string]string{"username": "istrazivac", "password": "pogresna"}, &errBody)
+	if status != http.StatusUnauthorized || errBody.Error.Code != "invalid_credentials" {
+		t.Errorf("Pogrešna lozinka mora vratiti 401 invalid_credentials, dobijeno %d %+v", status, errBody)
+	}
+
+	token := c.login("admin", 1)
+	var me struct {
+		Data struct {
+			KorisnickoIme string `json:"korisnicko_ime"`
+		} `json:"data"`
+	}
+	if status := c.do("GET", "/me", token, nil, &me); status != http.StatusOK || me.Data.KorisnickoIme != "admin" {
+		t.Errorf("GET /me: status %d, %+v", status, me)
+	}
+
+	if status := c.do("POST", "/auth/logout", token, nil, nil); status != http.StatusNoContent {
+		t.Errorf("Odjava mora vratiti 204, dobijeno %d", status)
+	}
+	if status := c.do("GET", "/me", token, nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("Token posle odjave ne sme važiti, dobijeno %d", status)
+	}
+}
+
+// Test aktivacije naloga: kod se menja za aktivacionu sesiju, a ona za pravu
+func TestAPIActivation(t *testing.T) {
+	c := newAPIClient(t)
+	admin := c.login("admin", 1)
+
+	var created struct {
+		Data services.ActivationCode `json:"data"`
+	}
+	status := c.do("POST", "/users", admin, map[string]interface{}{"korisnicko_ime": "nova", "email": "nova@test.local", "uloga_id": 3}, &created)
+	if status != http.StatusCreated || created.Data.Kod == "" || created.Data.KorisnickoIme != "nova" {
+		t.Fatalf("Kreiranje korisnika: status %d, %+v", status, created)
+	}
+
+	var login struct {
+		Data services.LoginResponse `json:"data"`
+	}
+	status = c.do("POST", "/auth/login", "", map[string]string{"username": "nova", "password": created.Data.Kod}, &login)
+	if status != http.StatusOK || login.Data.Message != services.FirstTimeLogin {
+		t.Fatalf("Prijava kodom: status %d, %+v", status, login)
+	}
+
+	var errBody apiErrorBody
+	if status := c.do("GET", "/me", login.Data.Token, nil, &errBody); status != http.StatusForbidden || errBody.Error.Code != "password_change_required" {
+		t.Errorf("Aktivaciona sesija mora vratiti 403 password_change_required, dobijeno %d %+v", status, errBody)
+	}
+	if status := c.do("POST", "/auth/activate", admin, map[string]string{"nova_lozinka": "nova-lozinka"}, nil); status != http.StatusBadRequest {
+		t.Errorf("Obična sesija ne aktivira nalog, dobijeno %d", status)
+	}
+
+	var policyErr struct {
+		Error struct {
+			Code       string                       `json:"code"`
+			Violations []services.PasswordViolation `json:"violations"`
+		} `json:"error"`
+	}
+	status = c.do("POST", "/auth/activate", login.Data.Token, map[string]string{"nova_lozinka": "nova-lozinka"}, &policyErr)
+	if status != http.StatusBadRequest || policyErr.Error.Code != "password_policy" ||
+		len(policyErr.Error.Violations) != 2 || policyErr.Error.Violations[0].Code != services.PasswordContainsUsername {
+		t.Errorf("Lozinka sa korisničkim imenom mora vratiti 400 password_policy sa prekršajima, dobijeno %d %+v", status, policyErr)
+	}
+
+	var activated struct {
+		Data services.LoginResponse `json:"data"`
+	}
+	status = c.do("POST", "/auth/activate", login.Data.Token, map[string]string{"nova_lozinka": "plavo-nebo-42"}, &activated)
+	if status != http.StatusOK || activated.Data.Token == "" {
+		t.Fatalf("Aktivacija: status %d, %+v", status, activated)
+	}
+	if status := c.do("GET", "/me", activated.Data.Token, nil, nil); status != http.StatusOK {
+		t.Errorf("Nova sesija mora važiti, dobijeno %d", status)
+	}
+	if status := c.do("POST", "/auth/activate", login.Data.Token, map[string]string{"nova_lozinka": "druga-lozinka"}, nil); status != http.StatusUnauthorized {
+		t.Errorf("Aktivaciona sesija se koristi samo jednom, dobijeno %d", status)
+	}
+}
+
+// Test odgovora 429 sa Retry-After i otključavanja naloga preko API-ja
+func TestAPILoginThrottle(t *testing.T) {
+	cfg := config.Default()
+	cfg.Auth.TwoFactorRoles = nil
+	c := newAPIClientWithConfig(t, cfg)
+	admin := c.login("admin", 1)
+	c.login("ana", 3)
+	ctx := context.Background()
+
+	loginStatus := func(password string) (int, string, apiErrorBody) {
+		t.Helper()
+		data, _ := json.Marshal(map[string]string{"username": "ana", "password": password})
+		resp, err := http.Post(c.server.URL+api.Prefix+"/auth/login", "application/json", bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("Zahtev nije uspeo: %v", err)
+		}
+		defer resp.Body.Close()
+
+		var errBody apiErrorBody
+		json.NewDecoder(resp.Body).Decode(&errBody)
+		return resp.StatusCode, resp.Header.Get("Retry-After"), errBody
+	}
+
+	ana, _ := c.stores.Users.GetByUsername(ctx, "ana")
+	c.stores.Users.Lock(ctx, ana.KorisnikID, time.Now().Add(time.Hour))
+	if status, retry, body := loginStatus("lozinka123"); status != http.StatusTooManyRequests || body.Error.Code != "too_many_attempts" || retry == "" {
+		t.Errorf("Zaključan nalog mora vratiti 429 sa Retry-After, dobijeno %d %q %+v", status, retry, body)
+	}
+
+	if status := c.do("POST", "/users/"+strconv.Itoa(ana.KorisnikID)+"/unlock", admin, nil, nil); status != http.StatusNoContent {
+		t.Fatalf("Otključavanje: status %d", status)
+	}
+	if status, _, body := loginStatus("lozinka123"); status != http.StatusOK {
+		t.Errorf("Otključan nalog mora se prijaviti, dobijeno %d %+v", status, body)
+	}
+
+	if status, _, _ := loginStatus("pogresna"); status != http.StatusUnauthorized {
+		t.Errorf("Pogrešna lozinka mora vratiti 401, dobijeno %d", status)
+	}
+	if status, retry, _ := loginStatus("lozinka123"); status != http.StatusTooManyRequests || retry != "1" {
+		t.Errorf("Pokušaj odmah posle neuspeha mora sačekati, dobijeno %d %q", status, retry)
+	}
+}
+
+// Test drugog faktora preko API-ja: podešavanje pri prijavi administratora
+// i potvrda rezervnim kodom pri sledećoj prijavi
+func TestAPITwoFactor(t *testing.T) {
+	cfg := config.Default()
+	cfg.Auth.LoginDelay = 0
+	c := newAPIClientWithConfig(t, cfg)
+	ctx := context.Background()
+
+	admin, _ := newMemoryUser(t, c.stores, "admin", 1)
+	hash, _ := c.svc.Auth.HashPassword("lozinka123")
+	c.stores.Users.UpdatePassword(ctx, admin.KorisnikID, hash, false)
+
+	type loginBody struct {
+		Data services.LoginResponse `json:"data"`
+	}
+	credentials := map[string]string{"username": "admin", "password": "lozinka123"}
+
+	var login loginBody
+	if status := c.do("POST", "/auth/login", "", credentials, &login); status != http.StatusOK || login.Data.Message != services.TwoFactorSetupRequired {
+		t.Fatalf("Očekivana poruka %s, dobijeno %d %+v", services.TwoFactorSetupRequired, status, login)
+	}
+	var errBody apiErrorBody
+	if status := c.do("GET", "/me", login.Data.Token, nil, &errBody); status != http.StatusForbidden || errBody.Error.Code != "two_factor_required" {
+		t.Errorf("Sesija bez drugog faktora mora vratiti 403 two_factor_required, dobijeno %d %+v", status, errBody)
+	}
+
+	var enrollment struct {
+		Data services.TwoFactorEnrollment `json:"data"`
+	}
+	if status := c.do("POST", "/auth/2fa/enroll", login.Data.Token, nil, &enrollment); status != http.StatusOK || enrollment.Data.Tajna == "" {
+		t.Fatalf("Podešavanje: status %d, %+v", status, enrollment)
+	}
+	code, _ := services.TOTPCode(enrollment.Data.Tajna, time.Now())
+	var setup struct {
+		Data services.TwoFactorSetup `json:"data"`
+	}
+	if status := c.do("POST", "/auth/2fa/confirm", login.Data.Token, map[string]string{"kod": code}, &setup); status != http.StatusOK || setup.Data.Prijava == nil {
+		t.Fatalf("Potvrda: status %d, %+v", status, setup)
+	}
+	var twoFactor struct {
+		Data services.TwoFactorStatus `json:"data"`
+	}
+	if status := c.do("GET", "/me/2fa", setup.Data.Prijava.Token, nil, &twoFactor); status != http.StatusOK || !twoFactor.Data.Ukljucen || twoFactor.Data.PreostaloKodova != 10 {
+		t.Errorf("Stanje drugog faktora: status %d, %+v", status, twoFactor)
+	}
+
+	if status := c.do("POST", "/auth/login", "", credentials, &login); status != http.StatusOK || login.Data.Message != services.TwoFactorRequired {
+		t.Fatalf("Očekivana poruka %s, dobijeno %d %+v", services.TwoFactorRequired, status, login)
+	}
+	if status := c.do("POST", "/auth/2fa/verify", login.Data.Token, map[string]string{"kod": "ABCD-EFGH"}, &errBody); status != http.StatusUnauthorized || errBody.Error.Code != "invalid_code" {
+		t.Errorf("Pogrešan kod mora vratiti 401 invalid_code, dobijeno %d %+v", status, errBody)
+	}
+	var verified loginBody
+	if status := c.do("POST", "/auth/2fa/verify", login.Data.Token, map[string]string{"kod": setup.Data.RezervniKodovi[0]}, &verified); status != http.StatusOK {
+		t.Fatalf("Rezervni kod: status %d, %+v", status, verified)
+	}
+	if status := c.do("GET", "/me", verified.Data.Token, nil, nil); status != http.StatusOK {
+		t.Errorf("Sesija posle drugog faktora mora važiti, dobijeno %d", status)
+	}
+}
+
+// Test pristupnih tokena: token radi kao sesija, ali samo u svojim opsezima
+func TestAPIAccessTokens(t *testing.T) {
+	c := newAPIClient(t)
+	session := c.login("istrazivac", 3)
+
+	var created struct {
+		Data services.NewAccessToken `json:"data"`
+	}
+	request := map[string]interface{}{"naziv": "skripta", "opsezi": []string{"task.view", "document.upload"}}
+	if status := c.do("POST", "/me/tokens", session, request, &created); status != http.StatusCreated || !strings.HasPrefix(created.Data.Token, services.AccessTokenPrefix) {
+		t.Fatalf("Kreiranje tokena: status %d, %+v", status, created)
+	}
+	token := created.Data.Token
+
+	var errBody apiErrorBody
+	if status := c.do("POST", "/me/tokens", session, map[string]interface{}{"naziv": "admin", "opsezi": []string{"user.manage"}}, &errBody); status != http.StatusBadRequest {
+		t.Errorf("Opseg van dozvola uloge mora vratiti 400, dobijeno %d %+v", status, errBody)
+	}
+
+	var permissions struct {
+		Data []services.Permission `json:"data"`
+	}
+	if status := c.do("GET", "/me/permissions", token, nil, &permissions); status != http.StatusOK || len(permissions.Data) != 2 {
+		t.Errorf("Token mora imati samo svoje opsege: status %d, %v", status, permissions.Data)
+	}
+	if status := c.do("GET", "/me/tasks", token, nil, nil); status != http.StatusOK {
+		t.Errorf("Token sa opsegom task.view mora čitati zadatke, dobijeno %d", status)
+	}
+	if status := c.do("GET", "/projects", token, nil, &errBody); status != http.StatusForbidden {
+		t.Errorf("Dozvola van opsega mora vratiti 403, dobijeno %d", status)
+	}
+	if status := c.do("GET", "/projects", session, nil, nil); status != http.StatusOK {
+		t.Errorf("Sesija zadržava sve dozvole uloge, dobijeno %d", status)
+	}
+	if status := c.do("POST", "/me/password", token, map[string]string{"nova_lozinka": "Nova-Lozinka-42"}, nil); status != http.StatusForbidden {
+		t.Errorf("Token ne sme menjati lozinku, dobijeno %d", status)
+	}
+	if status := c.do("POST", "/me/tokens", token, request, nil); status != http.StatusForbidden {
+		t.Errorf("Token ne sme kreirati tokene, dobijeno %d", status)
+	}
+
+	var list struct {
+		Data []models.AccessToken `json:"data"`
+	}
+	if status := c.do("GET", "/me/tokens", session, nil, &list); status != http.StatusOK || len(list.Data) != 1 ||
+		list.Data[0].PoslednjeKorisceno == nil || !strings.HasPrefix(token, list.Data[0].Prefiks) {
+		t.Fatalf("Lista tokena: status %d, %+v", status, list)
+	}
+
+	path := fmt.Sprintf("/tokens/%d", list.Data[0].TokenID)
+	if status := c.do("DELETE", path, session, nil, nil); status != http.StatusNoContent {
+		t.Fatalf("Opoziv tokena: status %d", status)
+	}
+	if status := c.do("GET", "/me", token, nil, &errBody); status != http.StatusUnauthorized {
+		t.Errorf("Opozvan token mora vratiti 401, dobijeno %d", status)
+	}
+	if status := c.do("DELETE", path, session, nil, nil); status != http.StatusConflict {
+		t.Errorf("Ponovni opoziv mora vratiti 409, dobijeno %d", status)
+	}
+}
+
+// Test primopredaje: brisanje korisnika sa poslom vraća 409, a primopredaja
+// završava njegove sesije i vraća izveštaj
+func TestAPIUserOffboarding(t *testing.T) {
+	c := newAPIClient(t)
+	admin := c.login("admin", 1)
+	leavingToken := c.login("odlazi", 2)
+	c.login("preuzima", 2)
+
+	ctx := context.Background()
+	leaving, _ := c.stores.Users.GetByUsername(ctx, "odlazi")
+	successor, _ := c.stores.Users.GetByUsername(ctx, "preuzima")
+	project := &models.Project{NazivProjekta: "Projekat", RukovodilaID: &leaving.KorisnikID}
+	if err := c.stores.Projects.Create(ctx, project, nil); err != nil {
+		t.Fatalf("Greška pri kreiranju projekta: %v", err)
+	}
+
+	path := fmt.Sprintf("/users/%d", leaving.KorisnikID)
+	var errBody apiErrorBody
+	if status := c.do("DELETE", path, admin, nil, &errBody); status != http.StatusConflict || !strings.Contains(errBody.Error.Message, "Projekti") {
+		t.Errorf("Brisanje korisnika sa poslom mora vratiti 409, dobijeno %d %+v", status, errBody)
+	}
+
+	var report struct {
+		Data models.HandoverReport `json:"data"`
+	}
+	handover := models.Handover{RukovodilacID: &successor.KorisnikID}
+	if status := c.do("POST", path+"/offboard", admin, handover, &report); status != http.StatusOK ||
+		len(report.Data.Projekti) != 1 || report.Data.ZavrseneSesije != 1 {
+		t.Fatalf("Primopredaja: status %d, %+v", status, report)
+	}
+	if status := c.do("GET", "/me", leavingToken, nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("Sesija deaktiviranog korisnika mora vratiti 401, dobijeno %d", status)
+	}
+	if status := c.do("DELETE", path, admin, nil, nil); status != http.StatusNoContent {
+		t.Errorf("Korisnik bez posla mora moći da se obriše, dobijeno %d", status)
+	}
+}
+
+// Test uvoza i izvoza korisnika: CSV telo zahteva i CSV odgovor
+func TestAPIUserImportExport(t *testing.T) {
+	c := newAPIClient(t)
+	admin := c.login("admin", 1)
+
+	importCSV := func(query, body string, out interface{}) int {
+		t.Helper()
+		req, _ := http.NewRequest("POST", c.server.URL+api.Prefix+"/users/import"+query, strings.NewReader(body))
+		req.Header.Set("Content-Type", "text/csv")
+		return c.send(req, admin, out)
+	}
+
+	file := "korisnicko_ime,email,uloga\nana,ana@institut.rs,Istrazivac\nivan,ivan@institut,Istrazivac\n"
+	var report struct {
+		Data services.UserImportReport `json:"data"`
+	}
+	if status := importCSV("?dry_run=true", file, &report); status != http.StatusOK || report.Data.Ispravni != 2 || report.Data.Kreirani != 0 {
+		t.Fatalf("Proba uvoza: status %d, %+v", status, report)
+	}
+	var errBody apiErrorBody
+	if status := importCSV("?dry_run=možda", file, &errBody); status != http.StatusBadRequest {
+		t.Errorf("Neispravan parametar mora vratiti 400, dobijeno %d", status)
+	}
+	if status := importCSV("", "ime\nana\n", &errBody); status != http.StatusBadRequest || !strings.Contains(errBody.Error.Message, "korisnicko_ime") {
+		t.Errorf("Fajl bez obaveznih kolona mora vratiti 400, dobijeno %d %+v", status, errBody)
+	}
+	if status := importCSV("", file, &report); status != http.StatusOK || report.Data.Kreirani != 2 || report.Data.Redovi[1].Aktivacija == nil {
+		t.Fatalf("Uvoz: status %d, %+v", status, report)
+	}
+
+	req, _ := http.NewRequest("GET", c.server.URL+api.Prefix+"/users/export", nil)
+	req.Header.Set("Authorization", "Bearer "+admin)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Izvoz nije uspeo: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/csv") ||
+		!strings.Contains(string(body), "ivan,ivan@institut,,,Istrazivac,aktivan") {
+		t.Errorf("Izvoz: status %d, %s\n%s", resp.StatusCode, resp.Header.Get("Content-Type"), body)
+	}
+}
+
+// Test profila: promena lozinke traži trenutnu lozinku, a podešavanja se
+// čuvaju i vraćaju svakom klijentu
+func TestAPIProfile(t *testing.T) {
+	c := newAPIClient(t)
+	token := c.login("jelena", 3)
+
+	var errBody apiErrorBody
+	change := map[string]string{"trenutna_lozinka": "pogresna", "nova_lozinka": "Nova-Lozinka-2"}
+	if status := c.do("POST", "/me/password", token, change, &errBody); status != http.StatusBadRequest {
+		t.Errorf("Pogrešna trenutna lozinka mora vratiti 400, dobijeno %d %+v", status, errBody)
+	}
+	change["trenutna_lozinka"] = "lozinka123"
+	if status := c.do("POST", "/me/password", token, change, nil); status != http.StatusNoContent {
+		t.Errorf("Promena lozinke mora vratiti 204, dobijeno %d", status)
+	}
+
+	var prefs struct {
+		Data models.Preferences `json:"data"`
+	}
+	if status := c.do("GET", "/me/preferences", token, nil, &prefs); status != http.StatusOK || prefs.Data.Jezik != "sr" {
+		t.Fatalf("Podrazumevana podešavanja: status %d, %+v", status, prefs)
+	}
+	prefs.Data.Jezik = "en"
+	if status := c.do("PUT", "/me/preferences", token, prefs.Data, &prefs); status != http.StatusOK || prefs.Data.Jezik != "en" {
+		t.Errorf("Čuvanje podešavanja: status %d, %+v", status, prefs)
+	}
+
+	var profile struct {
+		Data services.Profile `json:"data"`
+	}
+	if status := c.do("PUT", "/me/profile", token, services.ProfileUpdate{Ime: "Jelena"}, nil); status != http.StatusOK {
+		t.Errorf("Izmena imena mora vratiti 200, dobijeno %d", status)
+	}
+	if status := c.do("GET", "/me/profile", token, nil, &profile); status != http.StatusOK ||
+		profile.Data.Podesavanja.Jezik != "en" || profile.Data.Korisnik.Ime == nil || *profile.Data.Korisnik.Ime != "Jelena" {
+		t.Errorf("Profil: status %d, %+v", status, profile)
+	}
+
+	// Bez podešene pošte adresu menja administrator
+	if status := c.do("POST", "/me/email", token, map[string]string{"email": "jelena@institut.rs"}, &errBody); status != http.StatusBadRequest {
+		t.Errorf("Promena adrese bez pošte mora vratiti 400, dobijeno %d %+v", status, errBody)
+	}
+}
+
+// Test rada u ime korisnika: oznaka u odgovorima, zabrana izmena bezbednosti
+// i zapis svakog zahteva
+func TestAPIImpersonation(t *testing.T) {
+	c := newAPIClient(t)
+	admin := c.login("admin", 1)
+	user := c.login("jelena", 3)
+	me, _ := c.stores.Users.GetByUsername(context.Background(), "jelena")
+
+	var errBody apiErrorBody
+	path := fmt.Sprintf("/users/%d/impersonate", me.KorisnikID)
+	if status := c.do("POST", path, user, map[string]string{"razlog": "provera"}, &errBody); status != http.StatusForbidden {
+		t.Errorf("Istraživač ne sme raditi u ime drugih, dobijeno %d %+v", status, errBody)
+	}
+	var response struct {
+		Data services.LoginResponse `json:"data"`
+	}
+	if status := c.do("POST", path, admin, map[string]string{"razlog": "ne vidi dokument"}, &response); status != http.StatusOK || response.Data.Zastupanje == nil {
+		t.Fatalf("Rad u ime korisnika: status %d, %+v", status, response)
+	}
+	token := response.Data.Token
+
+	req, _ := http.NewRequest("GET", c.server.URL+api.Prefix+"/me", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Zahtev nije uspeo: %v", err)
+	}
+	resp.Body.Close()
+	if got := resp.Header.Get(api.ImpersonatedByHeader); got != "admin" {
+		t.Errorf("Odgovor mora imenovati administratora, dobijeno %q", got)
+	}
+
+	var impersonation struct {
+		Data services.Impersonation `json:"data"`
+	}
+	if status := c.do("GET", "/me/impersonation", token, nil, &impersonation); status != http.StatusOK || impersonation.Data.Administrator != "admin" {
+		t.Errorf("Stanje rada u ime korisnika: status %d, %+v", status, impersonation)
+	}
+	if status := c.do("GET", "/me/impersonation", admin, nil, nil); status != http.StatusNoContent {
+		t.Errorf("Van rada u ime korisnika očekivano 204, dobijeno %d", status)
+	}
+	change := map[string]string{"trenutna_lozinka": "lozinka123", "nova_lozinka": "Nova-Lozinka-2"}
+	if status := c.do("POST", "/me/password", token, change, nil); status != http.StatusForbidden {
+		t.Errorf("Promena lozinke u ime korisnika mora vratiti 403, dobijeno %d", status)
+	}
+	if status := c.do("POST", "/auth/2fa/enroll", token, nil, nil); status != http.StatusForbidden {
+		t.Errorf("Podešavanje drugog faktora u ime korisnika mora vratiti 403, dobijeno %d", status)
+	}
+	if status := c.do("POST", "/auth/2fa/confirm", token, map[string]string{"kod": "123456"}, nil); status != http.StatusForbidden {
+		t.Errorf("Uključivanje drugog faktora u ime korisnika mora vratiti 403, dobijeno %d", status)
+	}
+	var factor struct {
+		Data services.TwoFactorStatus `json:"data"`
+	}
+	if status := c.do("GET", "/me/2fa", user, nil, &factor); status != http.StatusOK || factor.Data.Ukljucen {
+		t.Errorf("Korisniku ne sme biti uključen drugi faktor: status %d, %+v", status, factor)
+	}
+
+	if status := c.do("DELETE", "/me/impersonation", token, nil, nil); status != http.StatusNoContent {
+		t.Errorf("Kraj rada u ime korisnika mora vratiti 204, dobijeno %d", status)
+	}
+	if status := c.do("GET", "/me", token, nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("Posle kraja token ne sme važiti, dobijeno %d", status)
+	}
+	if status := c.do("GET", "/me", admin, nil, nil); status != http.StatusOK {
+		t.Errorf("Sesija administratora mora ostati, dobijeno %d", status)
+	}
+
+	logs, _ := c.stores.Analytics.GetActivityLogs(context.Background(), -1)
+	calls := []string{}
+	for _, entry := range logs {
+		if entry.TipAktivnosti == services.ActivityImpersonatedCall {
+			calls = append(calls, *entry.Opis)
+		}
+	}
+	joined := strings.Join(calls, "\n")
+	if len(calls) != 6 || !strings.Contains(joined, "POST /api/v1/me/password: 403") || !strings.Contains(joined, "Odbijeno podešavanje drugog faktora") {
+		t.Errorf("Svaki zahtev u ime korisnika mora biti zapisan: %q", calls)
+	}
+}
+
+// Test uloga: dozvola ograničena na projekat i dodela uloge koja važi od
+// sledećeg zahteva postojeće sesije
+func TestAPIRoles(t *testing.T) {
+	c := newAPIClient(t)
+	admin := c.login("admin", 1)
+	researcherToken := c.login("istrazivac", 3)
+
+	ctx := context.Background()
+	researcher, _ := c.stores.Users.GetByUsername(ctx, "istrazivac")
+	first := &models.Project{NazivProjekta: "Prvi"}
+	second := &models.Project{NazivProjekta: "Drugi"}
+	for _, project := range []*models.Project{first, second} {
+		if err := c.stores.Projects.Create(ctx, project, nil); err != nil {
+			t.Fatalf("Greška pri kreiranju projekta: %v", err)
+		}
+	}
+
+	if status := c.do("GET", "/roles/matrix", researcherToken, nil, nil); status != http.StatusForbidden {
+		t.Errorf("Istraživač ne sme videti matricu dozvola, dobijeno %d", status)
+	}
+
+	var created struct {
+		Data models.Role `json:"data"`
+	}
+	req := services.RoleRequest{Naziv: "Laborant", Dozvole: []models.RoleGrant{
+		{Dozvola: string(services.PermProjectView)},
+		{Dozvola: string(services.PermTaskCreate), ProjekatID: &first.ProjekatID},
+	}}
+	if status := c.do("POST", "/roles", admin, req, &created); status != http.StatusCreated || len(created.Data.Dozvole) != 2 {
+		t.Fatalf("Kreiranje uloge: status %d, %+v", status, created)
+	}
+	var errBody apiErrorBody
+	if status := c.do("POST", "/roles", admin, req, &errBody); status != http.StatusConflict {
+		t.Errorf("Uloga sa istim nazivom mora vratiti 409, dobijeno %d %+v", status, errBody)
+	}
+
+	assignment := map[string]int{"uloga_id": created.Data.UlogaID}
+	if status := c.do("PUT", fmt.Sprintf("/users/%d/role", researcher.KorisnikID), admin, assignment, nil); status != http.StatusNoContent {
+		t.Fatalf("Dodela uloge mora vratiti 204, dobijeno %d", status)
+	}
+
+	task := func(projectID int) int {
+		return c.do("POST", "/tasks", researcherToken, models.CreateTaskRequest{ProjekatID: projectID, NazivZadatka: "Uzorci"}, nil)
+	}
+	if status := task(first.ProjekatID); status != http.StatusCreated {
+		t.Errorf("Nova uloga mora važiti u postojećoj sesiji, dobijeno %d", status)
+	}
+	if status := task(second.ProjekatID); status != http.StatusForbidden {
+		t.Errorf("Dozvola prvog projekta ne važi u drugom, dobijeno %d", status)
+	}
+
+	retire := fmt.Sprintf("/roles/%d/retire", created.Data.UlogaID)
+	if status := c.do("POST", retire, admin, nil, &errBody); status != http.StatusConflict || !strings.Contains(errBody.Error.Message, "1 korisnika") {
+		t.Errorf("Povlačenje dodeljene uloge mora vratiti 409, dobijeno %d %+v", status, errBody)
+	}
+}
+
+// Test organizacionih jedinica: upravljanje, premeštanje i vidljivost
+func TestAPIUnits(t *testing.T) {
+	c := newAPIClient(t)
+	admin := c.login("admin", 1)
+	head := c.login("rukovodilac", 2)
+	researcher := c.login("istrazivac", 3)
+
+	ctx := context.Background()
+	leader, _ := c.stores.Users.GetByUsername(ctx, "rukovodilac")
+
+	type unitResponse struct {
+		Data models.Unit `json:"data"`
+	}
+	var dept, lab, other unitResponse
+	if status := c.do("POST", "/units", researcher, services.UnitRequest{Naziv: "Odeljenje"}, nil); status != http.StatusForbidden {
+		t.Errorf("Istraživač ne sme kreirati jedinicu, dobijeno %d", status)
+	}
+	if status := c.do("POST", "/units", admin, services.UnitRequest{Naziv: "Odeljenje", RukovodilacID: &leader.KorisnikID}, &dept); status != http.StatusCreated {
+		t.Fatalf("Kreiranje jedinice: status %d", status)
+	}
+	req := services.UnitRequest{Naziv: "Laboratorija", Tip: "laboratorija", NadredjenaJedinicaID: &dept.Data.JedinicaID}
+	if status := c.do("POST", "/units", admin, req, &lab); status != http.StatusCreated {
+		t.Fatalf("Kreiranje jedinice: status %d", status)
+	}
+	if status := c.do("POST", "/units", admin, services.UnitRequest{Naziv: "Drugo odeljenje"}, &other); status != http.StatusCreated {
+		t.Fatalf("Kreiranje jedinice: status %d", status)
+	}
+	var errBody apiErrorBody
+	if status := c.do("POST", "/units", admin, req, &errBody); status != http.StatusConflict {
+		t.Errorf("Jedinica sa istim nazivom mora vratiti 409, dobijeno %d %+v", status, errBody)
+	}
+
+	var list struct {
+		Data []models.Unit `json:"data"`
+	}
+	if status := c.do("GET", "/units", researcher, nil, &list); status != http.StatusOK || len(list.Data) != 3 {
+		t.Errorf("Istraživač vidi jedinice: status %d, %d jedinica", status, len(list.Data))
+	}
+
+	project := &models.Project{NazivProjekta: "Tuđi projekat", Status: "Aktivan"}
+	if err := c.stores.Projects.Create(ctx, project, nil); err != nil {
+		t.Fatalf("Greška pri kreiranju projekta: %v", err)
+	}
+	path := fmt.Sprintf("/projects/%d", project.ProjekatID)
+	if status := c.do("GET", path, head, nil, nil); status != http.StatusOK {
+		t.Errorf("Projekat bez jedinice vide svi, dobijeno %d", status)
+	}
+	move := func(token string, unitID int) int {
+		return c.do("PUT", path+"/unit", token, map[string]int{"jedinica_id": unitID}, nil)
+	}
+	if status := move(head, other.Data.JedinicaID); status != http.StatusForbidden {
+		t.Errorf("Premeštanje u jedinicu van nadležnosti mora vratiti 403, dobijeno %d", status)
+	}
+	if status := move(admin, other.Data.JedinicaID); status != http.StatusNoContent {
+		t.Fatalf("Premeštanje projekta mora vratiti 204, dobijeno %d", status)
+	}
+	if status := c.do("GET", path, head, nil, nil); status != http.StatusNotFound {
+		t.Errorf("Projekat drugog odeljenja ne sme biti vidljiv rukovodiocu, dobijeno %d", status)
+	}
+
+	user := fmt.Sprintf("/users/%d/unit", leader.KorisnikID)
+	if status := c.do("PUT", user, admin, map[string]int{"jedinica_id": lab.Data.JedinicaID}, nil); status != http.StatusNoContent {
+		t.Errorf("Premeštanje korisnika mora vratiti 204, dobijeno %d", status)
+	}
+	var members struct {
+		Data []models.User `json:"data"`
+	}
+	if status := c.do("GET", fmt.Sprintf("/units/%d/members", lab.Data.JedinicaID), head, nil, &members); status != http.StatusOK ||
+		len(members.Data) != 1 || members.Data[0].KorisnikID != leader.KorisnikID {
+		t.Errorf("Članovi jedinice: status %d, %+v", status, members.Data)
+	}
+
+	if status := c.do("DELETE", fmt.Sprintf("/units/%d", dept.Data.JedinicaID), admin, nil, &errBody); status != http.StatusConflict {
+		t.Errorf("Brisanje jedinice sa podjedinicama mora vratiti 409, dobijeno %d %+v", status, errBody)
+	}
+	if status := c.do("DELETE", fmt.Sprintf("/units/%d", lab.Data.JedinicaID), admin, nil, nil); status != http.StatusNoContent {
+		t.Errorf("Brisanje jedinice mora vratiti 204, dobijeno %d", status)
+	}
+}
+
+// Test gostujućih naloga: kreiranje, deljenje, pregled i istek preko API-ja
+func TestAPIGuests(t *testing.T) {
+	c := newAPIClient(t)
+	admin := c.login("admin", 1)
+	researcher := c.login("istrazivac", 3)
+
+	ctx := context.Background()
+	shared := &models.Project{NazivProjekta: "Konzorcijum", Status: "Aktivan"}
+	hidden := &models.Project{NazivProjekta: "Interni", Status: "Aktivan"}
+	for _, project := range []*models.Project{shared, hidden} {
+		if err := c.stores.Projects.Create(ctx, project, nil); err != nil {
+			t.Fatalf("Greška pri kreiranju projekta: %v", err)
+		}
+	}
+
+	guest := services.GuestRequest{KorisnickoIme: "partner", Email: "partner@partner.eu", Istice: time.Now().Add(48 * time.Hour)}
+	if status := c.do("POST", "/guests", researcher, guest, nil); status != http.StatusForbidden {
+		t.Errorf("Istraživač ne sme kreirati gosta, dobijeno %d", status)
+	}
+	var created struct {
+		Data services.ActivationCode `json:"data"`
+	}
+	if status := c.do("POST", "/guests", admin, guest, &created); status != http.StatusCreated || created.Data.Kod == "" {
+		t.Fatalf("Kreiranje gosta: status %d, %+v", status, created)
+	}
+
+	var login, activated struct {
+		Data services.LoginResponse `json:"data"`
+	}
+	if status := c.do("POST", "/auth/login", "", map[string]string{"username": "partner", "password": created.Data.Kod}, &login); status != http.StatusOK {
+		t.Fatalf("Prijava kodom: status %d, %+v", status, login)
+	}
+	if status := c.do("POST", "/auth/activate", login.Data.Token, map[string]string{"nova_lozinka": "plavo-nebo-42"}, &activated); status != http.StatusOK {
+		t.Fatalf("Aktivacija: status %d, %+v", status, activated)
+	}
+	token := activated.Data.Token
+
+	shares := fmt.Sprintf("/guests/%d/shares", created.Data.KorisnikID)
+	var share struct {
+		Data models.GuestShare `json:"data"`
+	}
+	if status := c.do("POST", shares, admin, services.GuestShareRequest{ProjekatID: &shared.ProjekatID}, &share); status != http.StatusCreated || share.Data.Naziv != "Konzorcijum" {
+		t.Fatalf("Deljenje projekta: status %d, %+v", status, share)
+	}
+	if status := c.do("POST", shares, admin, services.GuestShareRequest{ProjekatID: &shared.ProjekatID}, nil); status != http.StatusConflict {
+		t.Errorf("Ponovljeno deljenje mora vratiti 409, dobijeno %d", status)
+	}
+
+	if status := c.do("GET", fmt.Sprintf("/projects/%d", shared.ProjekatID), token, nil, nil); status != http.StatusOK {
+		t.Errorf("Gost mora videti podeljen projekat, dobijeno %d", status)
+	}
+	if status := c.do("GET", fmt.Sprintf("/projects/%d", hidden.ProjekatID), token, nil, nil); status != http.StatusForbidden {
+		t.Errorf("Gost ne sme videti nepodeljen projekat, dobijeno %d", status)
+	}
+
+	var mine struct {
+		Data services.SharedWithGuest `json:"data"`
+	}
+	if status := c.do("GET", "/me/shared", token, nil, &mine); status != http.StatusOK || len(mine.Data.Projekti) != 1 {
+		t.Errorf("Gost vidi podeljen projekat: status %d, %+v", status, mine)
+	}
+	if status := c.do("GET", "/me/shared", researcher, nil, nil); status != http.StatusBadRequest {
+		t.Errorf("Podeljen sadržaj postoji samo za goste, dobijeno %d", status)
+	}
+
+	var access struct {
+		Data []services.GuestOverview `json:"data"`
+	}
+	if status := c.do("GET", "/guests/access", admin, nil, &access); status != http.StatusOK ||
+		len(access.Data) != 1 || len(access.Data[0].Deljenja) != 1 {
+		t.Errorf("Pregled gostujućeg pristupa: status %d, %+v", status, access)
+	}
+
+	expiry := fmt.Sprintf("/guests/%d/expiry", created.Data.KorisnikID)
+	if status := c.do("PUT", expiry, admin, map[string]time.Time{"istice": time.Now().Add(-time.Hour)}, nil); status != http.StatusBadRequest {
+		t.Errorf("Istek u prošlosti mora vratiti 400, dobijeno %d", status)
+	}
+	if status := c.do("PUT", expiry, admin, map[string]time.Time{"istice": time.Now().Add(72 * time.Hour)}, nil); status != http.StatusNoContent {
+		t.Errorf("Produženje gosta mora vratiti 204, dobijeno %d", status)
+	}
+
+	if status := c.do("DELETE", fmt.Sprintf("/guests/shares/%d", share.Data.PristupID), admin, nil, nil); status != http.StatusNoContent {
+		t.Fatalf("Ukidanje deljenja mora vratiti 204, dobijeno %d", status)
+	}
+	if status := c.do("GET", fmt.Sprintf("/projects/%d", shared.ProjekatID), token, nil, nil); status != http.StatusForbidden {
+		t.Errorf("Posle ukidanja deljenja projekat nije dostupan, dobijeno %d", status)
+	}
+}
+
+// Test projekata: kreiranje, dozvole, straničenje i mapiranje grešaka
+func TestAPIProjects(t *testing.T) {
+	c := newAPIClient(t)
+	leader := c.login("rukovodilac", 2)
+	researcher := c.login("istrazivac", 3)
+
+	var created struct {
+		Data struct {
+			ProjekatID int `json:"projekat_id"`
+		} `json:"data"`
+	}
+	for _, name := range []string{"Prvi", "Drugi", "Treći"} {
+		status := c.do("POST", "/projects", leader, map[string]interface{}{"naziv_projekta": name}, &created)
+		if status != http.StatusCreated || created.Data.ProjekatID == 0 {
+			t.Fatalf("Kreiranje projekta: status %d, %+v", status, created)
+		}
+	}
+
+	var page struct {
+		Data []struct {
+			NazivProjekta string `json:"naziv_projekta"`
+		} `json:"data"`
+		Meta struct {
+			Page    int `json:"page"`
+			PerPage int `json:"per_page"`
+			Total   int `json:"total"`
+		} `json:"meta"`
+	}
+	if status := c.do("GET", "/projects?page=2&per_page=2", researcher, nil, &page); status != http.StatusOK {
+		t.Fatalf("Lista projekata: status %d", status)
+	}
+	if len(page.Data) != 1 || page.Meta.Total != 3 || page.Meta.Page != 2 || page.Meta.PerPage != 2 {
+		t.Errorf("Pogrešna druga stranica: %+v", page)
+	}
+
+	var errBody apiErrorBody
+	if status := c.do("GET", "/projects?per_page=0", researcher, nil, &errBody); status != http.StatusBadRequest || errBody.Error.Code != "bad_request" {
+		t.Errorf("Neispravan per_page mora vratiti 400, dobijeno %d %+v", status, errBody)
+	}
+	errBody = apiErrorBody{}
+	if status := c.do("GET", "/projects?page=9223372036854775807&per_page=500", researcher, nil, &errBody); status != http.StatusBadRequest || errBody.Error.Code != "bad_request" {
+		t.Errorf("Prevelika stranica mora vratiti 400, dobijeno %d %+v", status, errBody)
+	}
+	page.Data = nil
+	if status := c.do("GET", "/projects?page=1000000&per_page=500", researcher, nil, &page); status != http.StatusOK || len(page.Data) != 0 || page.Meta.Total != 3 {
+		t.Errorf("Poslednja dozvoljena stranica mora biti prazna, dobijeno %d %+v", status, page)
+	}
+	if status := c.do("GET", "/projects/abc", researcher, nil, nil); status != http.StatusBadRequest {
+		t.Errorf("Neispravan ID mora vratiti 400, dobijeno %d", status)
+	}
+	errBody = apiErrorBody{}
+	if status := c.do("GET", "/projects/999", researcher, nil, &errBody); status != http.StatusNotFound || errBody.Error.Code != "not_found" {
+		t.Errorf("Nepostojeći projekat mora vratiti 404, dobijeno %d %+v", status, errBody)
+	}
+	errBody = apiErrorBody{}
+	if status := c.do("DELETE", "/projects/1", researcher, nil, &errBody); status != http.StatusForbidden || errBody.Error.Code != "forbidden" {
+		t.Errorf("Istraživač ne sme brisati projekte, dobijeno %d %+v", status, errBody)
+	}
+
+	// Tok dokumentacije se ne može dodeliti projektu
+	errBody = apiErrorBody{}
+	workflows, _ := c.stores.Workflows.GetAll(context.Background())
+	for _, workflow := range workflows {
+		if workflow.TipToka == "DOKUMENTACIJA" {
+			status := c.do("PUT", "/projects/1/workflow", leader, map[string]interface{}{"radni_tok_id": workflow.RadniTokID}, &errBody)
+			if status != http.StatusConflict || errBody.Error.Code != "conflict" {
+				t.Errorf("Dodela toka dokumentacije mora vratiti 409, dobijeno %d %+v", status, errBody)
+			}
+			break
+		}
+	}
+
+	if status := c.do("POST", "/tasks", leader, map[string]interface{}{"projekat_id": created.Data.ProjekatID, "naziv_zadatka": "Analiza"}, nil); status != http.StatusCreated {
+		t.Fatalf("Kreiranje zadatka: status %d", status)
+	}
+	var tasks struct {
+		Data []struct {
+			ZadatakID int `json:"zadatak_id"`
+		} `json:"data"`
+	}
+	c.do("GET", "/projects/"+strconv.Itoa(created.Data.ProjekatID)+"/tasks", researcher, nil, &tasks)
+	if len(tasks.Data) != 1 {
+		t.Fatalf("Očekivan jedan zadatak, dobijeno %+v", tasks)
+	}
+	errBody = apiErrorBody{}
+	if status := c.do("PATCH", "/tasks/"+strconv.Itoa(tasks.Data[0].ZadatakID), researcher, map[string]interface{}{}, &errBody); status != http.StatusBadRequest || errBody.Error.Code != "invalid_input" {
+		t.Errorf("Izmena bez polja mora vratiti 400 invalid_input, dobijeno %d %+v", status, errBody)
+	}
+
+	if status := c.do("DELETE", "/projects/1", leader, nil, nil); status != http.StatusNoContent {
+		t.Errorf("Brisanje projekta mora vratiti 204, dobijeno %d", status)
+	}
+}
+
+// Test otpremanja dokumenta preko multipart zahteva
+func TestAPIDocumentUpload(t *testing.T) {
+	c := newAPIClient(t)
+	token := c.login("istrazivac", 3)
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("document", `{"naziv_dokumenta": "Izveštaj", "tagovi": ["izvestaj"]}`)
+	part, _ := form.CreateFormFile("file", "izvestaj.pdf")
+	part.Write([]byte("sadrzaj"))
+	form.Close()
+
+	req, _ := http.NewRequest("POST", c.server.URL+api.Prefix+"/documents", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	if status := c.send(req, token, nil); status != http.StatusCreated {
+		t.Fatalf("Otpremanje dokumenta: status %d", status)
+	}
+
+	var docs struct {
+		Data []struct {
+			NazivDokumenta string `json:"naziv_dokumenta"`
+		} `json:"data"`
+	}
+	c.do("GET", "/documents", token, nil, &docs)
+	if len(docs.Data) != 1 || docs.Data[0].NazivDokumenta != "Izveštaj" {
+		t.Errorf("Očekivan otpremljen dokument, dobijeno %+v", docs)
+	}
+
+	// Naziv dokumenta i fajla ne smeju izvesti fajl iz direktorijuma za otpremanje
+	ctx := context.Background()
+	all, _ := c.stores.Documents.GetAll(ctx)
+	versions, _ := c.stores.Documents.GetVersions(ctx, all[0].DokumentID)
+	uploadDir := filepath.Dir(versions[0].PutanjaDoFajla)
+
+	body.Reset()
+	form = multipart.NewWriter(&body)
+	form.WriteField("document", `{"naziv_dokumenta": "x/../../izvan"}`)
+	part, _ = form.CreateFormFile("file", "../../izvan.pdf")
+	part.Write([]byte("sadrzaj"))
+	form.Close()
+	req, _ = http.NewRequest("POST", c.server.URL+api.Prefix+"/documents", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	if status := c.send(req, token, nil); status != http.StatusCreated {
+		t.Fatalf("Otpremanje dokumenta: status %d", status)
+	}
+	all, _ = c.stores.Documents.GetAll(ctx)
+	for _, doc := range all {
+		versions, _ := c.stores.Documents.GetVersions(ctx, doc.DokumentID)
+		if len(versions) == 0 || filepath.Dir(versions[0].PutanjaDoFajla) != uploadDir {
+			t.Errorf("Fajl dokumenta %q mora biti u direktorijumu za otpremanje: %+v", doc.NazivDokumenta, versions)
+		}
+	}
+	if escaped, _ := filepath.Glob(filepath.Join(uploadDir, "..", "..", "*izvan*")); len(escaped) != 0 {
+		t.Errorf("Fajl je upisan van direktorijuma za otpremanje: %v", escaped)
+	}
+
+	req, _ = http.NewRequest("POST", c.server.URL+api.Prefix+"/documents", strings.NewReader("x"))
+	req.Header.Set("Content-Type", "multipart/form-data; boundary=x")
+	if status := c.send(req, token, nil); status != http.StatusBadRequest {
+		t.Errorf("Neispravan multipart zahtev mora vratiti 400, dobijeno %d", status)
+	}
+}
+
+// Test OpenAPI dokumenta: svaka ruta mora biti opisana
+func TestAPIOpenAPIDocument(t *testing.T) {
+	c := newAPIClient(t)
+
+	var doc struct {
+		OpenAPI string                                `json:"openapi"`
+		Paths   map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if status := c.do("GET", "/openapi.json", "", nil, &doc); status != http.StatusOK {
+		t.Fatalf("OpenAPI dokument: status %d", status)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		t.Errorf("Očekivana OpenAPI verzija 3.x, dobijeno %q", doc.OpenAPI)
+	}
+
+	for path, methods := range map[string][]string{
+		"/projects":                  {"get", "post"},
+		"/projects/{id}":             {"get", "put", "delete"},
+		"/tasks/{id}":                {"get", "patch", "delete"},
+		"/documents":                 {"get", "post"},
+		"/auth/login":                {"post"},
+		"/auth/activate":             {"post"},
+		"/sessions/{sessionID}":      {"delete"},
+		"/workflows/{id}/phases":     {"get", "post"},
+		"/users/{id}/reset-password": {"post"},
+		"/users/{id}/unlock":         {"post"},
+		"/users/{id}/2fa":            {"delete"},
+		"/auth/2fa/verify":           {"post"},
+	} {
+		for _, method := range methods {
+			if _, ok := doc.Paths[api.Prefix+path][method]; !ok {
+				t.Errorf("OpenAPI dokument ne opisuje %s %s", strings.ToUpper(method), path)
+			}
+		}
+	}
+
+	var errBody apiErrorBody
+	if status := c.do("GET", "/nepostojeca-ruta", "", nil, &errBody); status != http.StatusNotFound || errBody.Error.Code != "not_found" {
+		t.Errorf("Nepoznata ruta mora vratiti 404 not_found, dobijeno %d %+v", status, errBody)
+	}
+}
@@ -0,0 +1,354 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cane/research-institute-system/backend/config"
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/repositories"
+	"github.com/cane/research-institute-system/backend/repositories/memory"
+	"github.com/cane/research-institute-system/backend/services"
+)
+
+// Test podrazumevanih dozvola za sve četiri uloge iz šeme
+func TestDefaultPolicy(t *testing.T) {
+	authz := newTestAuthorizer(t, memory.NewStores())
+
+	cases := []struct {
+		role    string
+		perm    services.Permission
+		allowed bool
+	}{
+		{"Administrator", services.PermUserManage, true},
+		{"Administrator", services.PermPolicyManage, true},
+		{"Rukovodilac projekta", services.PermProjectCreate, true},
+		{"Rukovodilac projekta", services.PermUserManage, false},
+		{"Organizator projekta", services.PermProjectMembers, true},
+		{"Organizator projekta", services.PermProjectCreate, false},
+		{"Istrazivac", services.PermTaskComment, true},
+		{"Istrazivac", services.PermDocumentDelete, false},
+		{"Nepostojeca uloga", services.PermProjectView, false},
+	}
+
+	for _, tc := range cases {
+		user := &models.User{NazivUloge: tc.role}
+		if got := authz.Can(user, tc.perm); got != tc.allowed {
+			t.Errorf("%s / %s: očekivano %v, dobijeno %v", tc.role, tc.perm, tc.allowed, got)
+		}
+	}
+}
+
+// Test izmene politike koja se čuva u bazi i važi bez ponovnog pokretanja
+func TestUpdatePolicy(t *testing.T) {
+	stores := memory.NewStores()
+	authz := newTestAuthorizer(t, stores)
+	roles := services.NewRoleService(stores, authz, config.Default().Auth)
+
+	admin := services.WithPrincipal(context.Background(), &services.Principal{
+		User: &models.User{KorisnikID: 1, NazivUloge: "Administrator"},
+	})
+	researcher := services.WithPrincipal(context.Background(), &services.Principal{
+		User: &models.User{KorisnikID: 2, NazivUloge: "Istrazivac"},
+	})
+
+	policy, err := roles.GetPolicy(admin)
+	if err != nil {
+		t.Fatalf("Greška pri čitanju politike: %v", err)
+	}
+	policy.Roles["Istrazivac"] = append(policy.Roles["Istrazivac"], services.PermDocumentDelete)
+
+	if err := roles.UpdatePolicy(researcher, policy); !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("Istraživač ne sme menjati politiku, dobijeno: %v", err)
+	}
+	if err := roles.UpdatePolicy(admin, policy); err != nil {
+		t.Fatalf("Greška pri izmeni politike: %v", err)
+	}
+	if _, err := authz.Require(researcher, services.PermDocumentDelete); err != nil {
+		t.Errorf("Izmena politike mora odmah važiti: %v", err)
+	}
+
+	// Nova instanca čita sačuvane dozvole
+	reloaded := newTestAuthorizer(t, stores)
+	if _, err := reloaded.Require(researcher, services.PermDocumentDelete); err != nil {
+		t.Errorf("Izmena politike nije sačuvana: %v", err)
+	}
+
+	invalid := services.Policy{Roles: map[string][]services.Permission{
+		"Administrator": {"document.shred"},
+	}}
+	if err := roles.UpdatePolicy(admin, invalid); err == nil {
+		t.Errorf("Nepoznata dozvola mora biti odbijena")
+	}
+
+	unknown := services.Policy{Roles: map[string][]services.Permission{
+		"Laborant": {services.PermTaskView},
+	}}
+	if err := roles.UpdatePolicy(admin, unknown); err == nil {
+		t.Errorf("Nepoznata uloga mora biti odbijena")
+	}
+
+	locked := services.Policy{Roles: map[string][]services.Permission{
+		"Administrator": {services.PermUserManage},
+	}}
+	if err := roles.UpdatePolicy(admin, locked); err == nil {
+		t.Errorf("Politika bez policy.manage mora biti odbijena")
+	}
+}
+
+// Test prilagođenih uloga: dozvole ograničene na projekat, kopiranje,
+// povlačenje, dodela i primena izmena u drugoj instanci bez ponovnog pokretanja
+func TestCustomRoles(t *testing.T) {
+	stores := memory.NewStores()
+	authz := newTestAuthorizer(t, stores)
+	svc := services.New(&config.Config{Auth: config.Default().Auth}, stores, services.NewSessionManager(time.Minute, time.Hour), authz)
+	_, adminCtx := newMemoryUser(t, stores, "admin", 1)
+	_, researcherCtx := newMemoryUser(t, stores, "istrazivac", 3)
+	ctx := context.Background()
+
+	first := &models.Project{NazivProjekta: "Prvi projekat"}
+	second := &models.Project{NazivProjekta: "Drugi projekat"}
+	for _, project := range []*models.Project{first, second} {
+		if err := stores.Projects.Create(ctx, project, nil); err != nil {
+			t.Fatalf("Greška pri kreiranju projekta: %v", err)
+		}
+	}
+
+	req := services.RoleRequest{Naziv: "Laborant", Opis: "Unosi zadatke u jednom projektu", Dozvole: []models.RoleGrant{
+		{Dozvola: string(services.PermTaskView)},
+		{Dozvola: string(services.PermTaskCreate), ProjekatID: &first.ProjekatID},
+	}}
+	if _, err := svc.Roles.CreateRole(researcherCtx, req); !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("Istraživač ne sme kreirati uloge, dobijeno: %v", err)
+	}
+	role, err := svc.Roles.CreateRole(adminCtx, req)
+	if err != nil {
+		t.Fatalf("Greška pri kreiranju uloge: %v", err)
+	}
+
+	invalid := []services.RoleRequest{
+		{Naziv: "laborant"},
+		{Naziv: "Tehničar", Dozvole: []models.RoleGrant{{Dozvola: "document.shred"}}},
+		{Naziv: "Tehničar", Dozvole: []models.RoleGrant{{Dozvola: string(services.PermTaskView), ProjekatID: &[]int{999}[0]}}},
+	}
+	for _, req := range invalid {
+		if _, err := svc.Roles.CreateRole(adminCtx, req); err == nil {
+			t.Errorf("Uloga %+v mora biti odbijena", req)
+		}
+	}
+
+	// Dozvola ograničena na projekat važi samo u tom projektu
+	_, labCtx := newMemoryUser(t, stores, "laborant", role.UlogaID)
+	if err := svc.Tasks.CreateTask(labCtx, models.CreateTaskRequest{ProjekatID: first.ProjekatID, NazivZadatka: "Uzorci"}); err != nil {
+		t.Errorf("Laborant mora moći da kreira zadatak u prvom projektu: %v", err)
+	}
+	if err := svc.Tasks.CreateTask(labCtx, models.CreateTaskRequest{ProjekatID: second.ProjekatID, NazivZadatka: "Uzorci"}); !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("Laborant ne sme kreirati zadatke u drugom projektu, dobijeno: %v", err)
+	}
+
+	// Druga instanca (npr. HTTP server) vidi izmenu pri sledećem osvežavanju
+	other := newTestAuthorizer(t, stores)
+	req.Dozvole = append(req.Dozvole, models.RoleGrant{Dozvola: string(services.PermTaskCreate), ProjekatID: &second.ProjekatID})
+	if _, err := svc.Roles.UpdateRole(adminCtx, role.UlogaID, req); err != nil {
+		t.Fatalf("Greška pri izmeni uloge: %v", err)
+	}
+	lab := &models.User{NazivUloge: "Laborant"}
+	if err := other.Refresh(ctx); err != nil {
+		t.Fatalf("Greška pri osvežavanju dozvola: %v", err)
+	}
+	if !other.CanInProject(lab, services.PermTaskCreate, second.ProjekatID) {
+		t.Errorf("Izmena uloge mora važiti posle osvežavanja")
+	}
+	if other.Can(lab, services.PermTaskCreate) {
+		t.Errorf("Dozvola ograničena na projekte ne sme važiti svuda")
+	}
+
+	clone, err := svc.Roles.CloneRole(adminCtx, role.UlogaID, "Laborant pripravnik")
+	if err != nil {
+		t.Fatalf("Greška pri kopiranju uloge: %v", err)
+	}
+	if len(clone.Dozvole) != 3 || clone.Opis == nil || *clone.Opis != req.Opis {
+		t.Errorf("Kopija mora imati opis i dozvole uloge: %+v", clone)
+	}
+
+	// Uloga koju korisnici imaju ne može se povući
+	if err := svc.Roles.RetireRole(adminCtx, role.UlogaID); !errors.Is(err, repositories.ErrConflict) {
+		t.Errorf("Povlačenje dodeljene uloge mora biti odbijeno, dobijeno: %v", err)
+	}
+	if err := svc.Roles.RetireRole(adminCtx, 1); err == nil {
+		t.Errorf("Povlačenje jedine uloge sa policy.manage mora biti odbijeno")
+	}
+	if err := svc.Roles.RetireRole(adminCtx, clone.UlogaID); err != nil {
+		t.Fatalf("Greška pri povlačenju uloge: %v", err)
+	}
+
+	researcher, _ := stores.Users.GetByUsername(ctx, "istrazivac")
+	if err := svc.Roles.AssignRole(adminCtx, researcher.KorisnikID, clone.UlogaID); err == nil {
+		t.Errorf("Povučena uloga ne sme biti dodeljena")
+	}
+	if err := svc.Roles.AssignRole(adminCtx, researcher.KorisnikID, role.UlogaID); err != nil {
+		t.Fatalf("Greška pri dodeli uloge: %v", err)
+	}
+	if stored, _ := stores.Users.GetByID(ctx, researcher.KorisnikID); stored.NazivUloge != "Laborant" {
+		t.Errorf("Korisnik mora imati novu ulogu, dobijeno %q", stored.NazivUloge)
+	}
+
+	// Izmena podataka korisnika ne menja ulogu mimo dnevnika
+	stored, _ := stores.Users.GetByID(ctx, researcher.KorisnikID)
+	changed := *stored
+	changed.UlogaID = 1
+	if err := svc.Users.UpdateUser(adminCtx, researcher.KorisnikID, changed); !errors.Is(err, services.ErrInvalidInput) {
+		t.Errorf("Promena uloge kroz UpdateUser mora biti odbijena, dobijeno %v", err)
+	}
+	changed.UlogaID = 0
+	changed.Email = "istrazivac.lab@test.local"
+	if err := svc.Users.UpdateUser(adminCtx, researcher.KorisnikID, changed); err != nil {
+		t.Fatalf("Izmena bez uloge zadržava postojeću ulogu: %v", err)
+	}
+	if stored, _ := stores.Users.GetByID(ctx, researcher.KorisnikID); stored.UlogaID != role.UlogaID || stored.Email != "istrazivac.lab@test.local" {
+		t.Errorf("Izmena mora zadržati ulogu i upisati email: %+v", stored)
+	}
+
+	assignable, _ := stores.Users.GetRoles(ctx)
+	for _, r := range assignable {
+		if r.UlogaID == clone.UlogaID {
+			t.Errorf("Povučena uloga ne sme biti ponuđena za dodelu")
+		}
+	}
+
+	logs, _ := stores.Analytics.GetActivityLogs(ctx, -1)
+	counts := map[string]int{}
+	for _, entry := range logs {
+		counts[entry.TipAktivnosti]++
+	}
+	for activity, want := range map[string]int{
+		services.ActivityRoleCreated:  2,
+		services.ActivityRoleChanged:  1,
+		services.ActivityRoleRetired:  1,
+		services.ActivityRoleAssigned: 1,
+	} {
+		if counts[activity] != want {
+			t.Errorf("%s: očekivano %d zapisa, dobijeno %d", activity, want, counts[activity])
+		}
+	}
+}
+
+// Ko ima samo user.manage ne može dodeliti ulogu sa dozvolama koje sam nema,
+// ni dodelom, ni kreiranjem, ni uvozom korisnika
+func TestRoleAssignmentEscalation(t *testing.T) {
+	stores := memory.NewStores()
+	svc := services.New(&config.Config{Auth: config.Default().Auth}, stores, services.NewSessionManager(time.Hour, 12*time.Hour), newTestAuthorizer(t, stores))
+	_, adminCtx := newMemoryUser(t, stores, "admin", 1)
+	researcher, _ := newMemoryUser(t, stores, "istrazivac", 3)
+
+	hr, err := svc.Roles.CreateRole(adminCtx, services.RoleRequest{Naziv: "Kadrovik", Dozvole: []models.RoleGrant{
+		{Dozvola: string(services.PermUserManage)},
+		{Dozvola: string(services.PermUserView)},
+	}})
+	if err != nil {
+		t.Fatalf("Greška pri kreiranju uloge: %v", err)
+	}
+	_, hrCtx := newMemoryUser(t, stores, "kadrovik", hr.UlogaID)
+
+	if err := svc.Roles.AssignRole(hrCtx, researcher.KorisnikID, 1); !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("Kadrovik ne sme dodeliti ulogu administratora, dobijeno %v", err)
+	}
+	if stored, _ := stores.Users.GetByID(context.Background(), researcher.KorisnikID); stored.UlogaID != 3 {
+		t.Errorf("Uloga se ne sme promeniti, dobijeno %d", stored.UlogaID)
+	}
+	if _, err := svc.Auth.CreateUser(hrCtx, &models.User{KorisnickoIme: "novi", Email: "novi@institut.rs", UlogaID: 1}); !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("Kadrovik ne sme kreirati administratora, dobijeno %v", err)
+	}
+	report, err := svc.Auth.ImportUsers(hrCtx, strings.NewReader("korisnicko_ime,email,uloga\nnovi,novi@institut.rs,Administrator\n"), services.UserImportOptions{})
+	if err != nil || report.Kreirani != 0 || len(report.Redovi[0].Greske) != 1 {
+		t.Errorf("Uvoz administratora mora biti odbijen: %+v, %v", report, err)
+	}
+
+	// Uloga koja ne daje više od sopstvene može se dodeliti
+	if err := svc.Roles.AssignRole(hrCtx, researcher.KorisnikID, hr.UlogaID); err != nil {
+		t.Errorf("Kadrovik sme dodeliti sopstvenu ulogu: %v", err)
+	}
+	if err := svc.Roles.AssignRole(adminCtx, researcher.KorisnikID, 1); err != nil {
+		t.Errorf("Administrator sme dodeliti svaku ulogu: %v", err)
+	}
+}
+
+// Test sistemskih uloga: aplikacija ih nalazi po ključu, pa se ne mogu
+// preimenovati ni povući, a preimenovane ranije i dalje rade
+func TestSystemRoles(t *testing.T) {
+	stores := memory.NewStores()
+	cfg := &config.Config{Auth: config.Default().Auth}
+	cfg.Auth.TwoFactorRoles = []string{"administrator", "Laborant"}
+	svc := services.New(cfg, stores, services.NewSessionManager(time.Hour, 12*time.Hour), newTestAuthorizer(t, stores))
+	ctx := context.Background()
+
+	admin, adminCtx := newMemoryUser(t, stores, "admin", 1)
+
+	if _, err := svc.Roles.UpdateRole(adminCtx, 1, services.RoleRequest{Naziv: "Direktor", Dozvole: []models.RoleGrant{{Dozvola: "*"}}}); !errors.Is(err, services.ErrInvalidInput) {
+		t.Errorf("Sistemska uloga se ne sme preimenovati, dobijeno %v", err)
+	}
+	if err := svc.Roles.RetireRole(adminCtx, 5); !errors.Is(err, services.ErrInvalidInput) {
+		t.Errorf("Sistemska uloga se ne sme povući, dobijeno %v", err)
+	}
+	guestRole, err := svc.Roles.UpdateRole(adminCtx, 5, services.RoleRequest{Naziv: "Gost", Opis: "Partner na projektu"})
+	if err != nil || guestRole.Sistemska == nil || *guestRole.Sistemska != services.RoleGuest {
+		t.Errorf("Opis sistemske uloge se menja, a ključ ostaje: %+v, %v", guestRole, err)
+	}
+	if clone, err := svc.Roles.CloneRole(adminCtx, 1, "Zamenik"); err != nil || clone.Sistemska != nil {
+		t.Errorf("Kopija sistemske uloge nije sistemska: %+v, %v", clone, err)
+	}
+
+	lab, err := svc.Roles.CreateRole(adminCtx, services.RoleRequest{Naziv: "Laborant", Dozvole: []models.RoleGrant{{Dozvola: "task.view"}}})
+	if err != nil {
+		t.Fatalf("Greška pri kreiranju uloge: %v", err)
+	}
+	if _, err := svc.Roles.UpdateRole(adminCtx, lab.UlogaID, services.RoleRequest{Naziv: "Tehnicar"}); !errors.Is(err, services.ErrInvalidInput) {
+		t.Errorf("Uloga iz auth.two_factor_roles se ne sme preimenovati, dobijeno %v", err)
+	}
+
+	// Uloge preimenovane pre nego što su postale sistemske
+	for id, name := range map[int]string{1: "Direktor", 5: "Spoljni saradnik"} {
+		role, _ := stores.Roles.GetByID(ctx, id)
+		role.NazivUloge = name
+		if err := stores.Roles.Update(ctx, role); err != nil {
+			t.Fatalf("Greška pri preimenovanju uloge: %v", err)
+		}
+	}
+	if err := svc.Authz.Reload(ctx); err != nil {
+		t.Fatalf("Greška pri učitavanju uloga: %v", err)
+	}
+	renamed, _ := stores.Users.GetByID(ctx, admin.KorisnikID)
+	adminCtx = services.WithPrincipal(ctx, &services.Principal{User: renamed})
+
+	activation, err := svc.Guests.CreateGuest(adminCtx, services.GuestRequest{KorisnickoIme: "partner", Email: "partner@partner.eu", Istice: time.Now().Add(24 * time.Hour)})
+	if err != nil {
+		t.Fatalf("Gost se kreira i kada je uloga gosta preimenovana: %v", err)
+	}
+	if guest, _ := stores.Users.GetByID(ctx, activation.KorisnikID); guest.UlogaID != 5 {
+		t.Errorf("Gost mora dobiti sistemsku ulogu gosta, dobijeno %d", guest.UlogaID)
+	}
+	if status, err := svc.Auth.GetTwoFactorStatus(ctx, admin.KorisnikID); err != nil || !status.Obavezan {
+		t.Errorf("Preimenovanom administratoru drugi faktor ostaje obavezan: %+v, %v", status, err)
+	}
+}
+
+// Test sistemskog pozivaoca koji koriste administratorske alatke
+func TestSystemPrincipal(t *testing.T) {
+	authz := newTestAuthorizer(t, memory.NewStores())
+
+	ctx := services.SystemContext(context.Background())
+	for _, perm := range []services.Permission{services.PermUserManage, services.PermPolicyManage, services.PermAuditView} {
+		if _, err := authz.Require(ctx, perm); err != nil {
+			t.Errorf("Sistemski pozivalac mora imati dozvolu %s: %v", perm, err)
+		}
+	}
+
+	// Korisnik bez uloge i dalje nema nikakve dozvole
+	user := services.WithPrincipal(context.Background(), &services.Principal{User: &models.User{KorisnickoIme: "system"}})
+	if _, err := authz.Require(user, services.PermUserManage); !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("Korisnik bez uloge ne sme imati dozvole: %v", err)
+	}
+}
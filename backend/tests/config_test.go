@@ -0,0 +1,148 @@
+package tests
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cane/research-institute-system/backend/config"
+)
+
+// writeConfigFile upisuje JSON konfiguraciju u privremeni direktorijum
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "riis.json")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Greška pri upisu konfiguracije: %v", err)
+	}
+	return path
+}
+
+// clearConfigEnv uklanja promenljive okruženja koje bi uticale na test
+func clearConfigEnv(t *testing.T) {
+	t.Helper()
+
+	for _, s := range config.Default().Settings() {
+		if s.Env != "" {
+			t.Setenv(s.Env, "")
+		}
+	}
+	t.Setenv("RIIS_CONFIG", "")
+}
+
+// Test redosleda slojeva: podrazumevano < fajl < okruženje < -set
+func TestConfigLayers(t *testing.T) {
+	clearConfigEnv(t)
+	path := writeConfigFile(t, `{
+		"database": {"host": "db.fajl", "port": 6543, "name": "iz_fajla"},
+		"storage": {"allowed_file_types": ["PDF", "odt"]},
+		"log": {"level": "debug"}
+	}`)
+	t.Setenv("DB_HOST", "db.okruzenje")
+	t.Setenv("SESSION_IDLE_TIMEOUT", "10m")
+
+	cfg, rest, err := config.Load([]string{"-config", path, "-set", "log.level=warn", "health", "-json"})
+	if err != nil {
+		t.Fatalf("Greška pri učitavanju: %v", err)
+	}
+
+	if len(rest) != 2 || rest[0] != "health" {
+		t.Errorf("Komanda mora ostati neobrađena, dobijeno %q", rest)
+	}
+	if cfg.Database.Host != "db.okruzenje" || cfg.Database.Port != 6543 || cfg.Database.Name != "iz_fajla" {
+		t.Errorf("Pogrešna podešavanja baze: %+v", cfg.Database)
+	}
+	if cfg.Database.User != "postgres" {
+		t.Errorf("Nepodešena vrednost mora ostati podrazumevana, dobijeno %q", cfg.Database.User)
+	}
+	if cfg.Log.Level != "warn" {
+		t.Errorf("-set mora imati prednost, dobijeno %q", cfg.Log.Level)
+	}
+	if cfg.Auth.SessionIdleTimeout != 10*time.Minute {
+		t.Errorf("Trajanje iz okruženja nije primenjeno: %v", cfg.Auth.SessionIdleTimeout)
+	}
+	if strings.Join(cfg.Storage.AllowedFileTypes, ",") != "pdf,odt" {
+		t.Errorf("Lista tipova mora biti normalizovana, dobijeno %v", cfg.Storage.AllowedFileTypes)
+	}
+
+	sources := map[string]string{
+		"database.host": "env DB_HOST",
+		"database.port": "file " + path,
+		"database.user": "default",
+		"log.level":     "flag",
+	}
+	for key, want := range sources {
+		if got := cfg.Source(key); got != want {
+			t.Errorf("Izvor za %s: očekivano %q, dobijeno %q", key, want, got)
+		}
+	}
+}
+
+// Test da su tajne vrednosti maskirane u pregledu podešavanja
+func TestConfigSettingsMaskSecrets(t *testing.T) {
+	clearConfigEnv(t)
+	t.Setenv("DB_PASSWORD", "tajna")
+
+	cfg, _, err := config.Load([]string{"-config", writeConfigFile(t, `{}`)})
+	if err != nil {
+		t.Fatalf("Greška pri učitavanju: %v", err)
+	}
+
+	for _, s := range cfg.Settings() {
+		if strings.Contains(s.Value, "tajna") {
+			t.Errorf("Tajna vrednost %s nije maskirana", s.Key)
+		}
+	}
+}
+
+// Test da se sve greške prijavljuju odjednom
+func TestConfigValidation(t *testing.T) {
+	clearConfigEnv(t)
+	t.Setenv("DB_PORT", "nije-broj")
+	t.Setenv("LOG_FORMAT", "xml")
+
+	_, _, err := config.Load([]string{
+		"-config", writeConfigFile(t, `{}`),
+		"-set", "auth.session_max_lifetime=1m",
+		"-set", "auth.password_min_classes=5",
+		"-set", "auth.argon2_memory=16",
+		"-set", "auth.two_factor_issuer=RIIS:test",
+		"-set", "ldap.url=http://ldap.institut.rs",
+		"-set", "ldap.group_roles=rukovodioci",
+		"-set", "nepostojeci.kljuc=1",
+	})
+
+	var cfgErr *config.Error
+	if !errors.As(err, &cfgErr) {
+		t.Fatalf("Očekivana greška konfiguracije, dobijeno %v", err)
+	}
+	for _, want := range []string{"DB_PORT", "log.format", "auth.session_max_lifetime", "auth.password_min_classes", "auth.argon2_memory", "auth.two_factor_issuer", "ldap.url", "ldap.base_dn", "ldap.group_roles", "nepostojeci.kljuc"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Poruka mora pomenuti %s:\n%v", want, err)
+		}
+	}
+}
+
+// Test da nepoznat ključ u fajlu i nepostojeći zadati fajl nisu prećutani
+func TestConfigFileErrors(t *testing.T) {
+	clearConfigEnv(t)
+
+	path := writeConfigFile(t, `{"database": {"hots": "db"}}`)
+	if _, _, err := config.Load([]string{"-config", path}); err == nil || !strings.Contains(err.Error(), "database.hots") {
+		t.Errorf("Nepoznat ključ mora biti prijavljen, dobijeno %v", err)
+	}
+
+	missing := filepath.Join(t.TempDir(), "nema.json")
+	if _, _, err := config.Load([]string{"-config", missing}); err == nil {
+		t.Errorf("Zadat fajl koji ne postoji mora biti greška")
+	}
+
+	t.Setenv("RIIS_CONFIG", missing)
+	if _, _, err := config.Load(nil); err == nil {
+		t.Errorf("Fajl iz RIIS_CONFIG koji ne postoji mora biti greška")
+	}
+}
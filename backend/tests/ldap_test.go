@@ -0,0 +1,285 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+
+	"github.com/cane/research-institute-system/backend/config"
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/repositories/memory"
+	"github.com/cane/research-institute-system/backend/services"
+)
+
+// ldapEntry je jedan unos imenika zamenskog LDAP servera
+type ldapEntry struct {
+	password   string
+	attributes map[string][]string
+}
+
+// ldapServer je zamenski LDAP server koji razume samo ono što LDAPProvider
+// koristi: jednostavan bind, pretragu sa filterom jednakosti i unbind
+type ldapServer struct {
+	listener net.Listener
+
+	mu      sync.Mutex
+	entries map[string]*ldapEntry // DN -> unos
+}
+
+func newLDAPServer(t *testing.T) *ldapServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Greška pri pokretanju LDAP servera: %v", err)
+	}
+	server := &ldapServer{listener: listener, entries: map[string]*ldapEntry{}}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	return server
+}
+
+func (s *ldapServer) URL() string {
+	return "ldap://" + s.listener.Addr().String()
+}
+
+func (s *ldapServer) add(dn, password string, attributes map[string][]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[dn] = &ldapEntry{password: password, attributes: attributes}
+}
+
+func (s *ldapServer) setGroups(dn string, groups ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[dn].attributes["memberOf"] = groups
+}
+
+func (s *ldapServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		id, _ := packet.Children[0].Value.(int64)
+		request := packet.Children[1]
+
+		switch request.Tag {
+		case 0: // BindRequest
+			dn := request.Children[1].Value.(string)
+			password := request.Children[2].Data.String()
+			code := int64(49) // invalidCredentials
+			s.mu.Lock()
+			if entry, ok := s.entries[dn]; ok && entry.password == password {
+				code = 0
+			}
+			s.mu.Unlock()
+			conn.Write(ldapResult(id, 1, code).Bytes())
+
+		case 3: // SearchRequest
+			filter := request.Children[6]
+			attribute := filter.Children[0].Value.(string)
+			value := filter.Children[1].Value.(string)
+
+			s.mu.Lock()
+			for dn, entry := range s.entries {
+				values := entry.attributes[attribute]
+				if len(values) == 0 || !strings.EqualFold(values[0], value) {
+					continue
+				}
+				conn.Write(ldapSearchEntry(id, dn, entry.attributes).Bytes())
+			}
+			s.mu.Unlock()
+			conn.Write(ldapResult(id, 5, 0).Bytes())
+
+		case 2: // UnbindRequest
+			return
+		}
+	}
+}
+
+func ldapMessage(id int64, op *ber.Packet) *ber.Packet {
+	message := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+	message.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, ""))
+	message.AppendChild(op)
+	return message
+}
+
+func ldapResult(id int64, tag ber.Tag, code int64) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "")
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, ""))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", ""))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", ""))
+	return ldapMessage(id, op)
+}
+
+func ldapSearchEntry(id int64, dn string, attributes map[string][]string) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, 4, nil, "")
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, dn, ""))
+	list := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+	for name, values := range attributes {
+		attribute := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+		attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, ""))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "")
+		for _, value := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, ""))
+		}
+		attribute.AppendChild(set)
+		list.AppendChild(attribute)
+	}
+	op.AppendChild(list)
+	return ldapMessage(id, op)
+}
+
+// Test prijave preko LDAP imenika: nalog se kreira pri prvoj prijavi, uloga
+// prati grupe u imeniku, a lokalni nalozi rade kao i ranije
+func TestLDAPProvider(t *testing.T) {
+	server := newLDAPServer(t)
+	jovana := "uid=jovana,ou=ljudi,dc=institut,dc=rs"
+	server.add(jovana, "imenik-lozinka", map[string][]string{
+		"uid": {"jovana"}, "mail": {"jovana@institut.rs"}, "givenName": {"Jovana"}, "sn": {"Petrović"},
+		"memberOf": {"cn=rukovodioci,ou=grupe,dc=institut,dc=rs"},
+	})
+	server.add("uid=student,ou=ljudi,dc=institut,dc=rs", "imenik-lozinka", map[string][]string{
+		"uid": {"student"}, "mail": {"student@institut.rs"}, "memberOf": {"cn=studenti,ou=grupe,dc=institut,dc=rs"},
+	})
+	server.add("uid=milan,ou=ljudi,dc=institut,dc=rs", "imenik-lozinka", map[string][]string{
+		"uid": {"milan"}, "mail": {"milan@institut.rs"}, "memberOf": {"cn=istrazivaci,ou=grupe,dc=institut,dc=rs"},
+	})
+
+	stores := memory.NewStores()
+	cfg := config.Default().Auth
+	cfg.LoginDelay = 0
+	cfg.TwoFactorRoles = nil
+	auth := newTestAuthService(t, stores, cfg)
+
+	ldapCfg := config.Default().LDAP
+	ldapCfg.URL = server.URL()
+	ldapCfg.BaseDN = "dc=institut,dc=rs"
+	ldapCfg.Timeout = 2 * time.Second
+	// Podešavanja liste stižu malim slovima
+	ldapCfg.GroupRoles = []string{"rukovodioci:rukovodilac projekta", "istrazivaci:istrazivac"}
+	auth.AddProvider(services.NewLDAPProvider(ldapCfg, stores))
+
+	ctx := context.Background()
+	login := func(username, password string) *services.LoginResponse {
+		t.Helper()
+		response, err := auth.Login(ctx, services.LoginRequest{Username: username, Password: password})
+		if err != nil {
+			t.Fatalf("Greška pri prijavi %s: %v", username, err)
+		}
+		return response
+	}
+	account := func(username string) *models.User {
+		t.Helper()
+		user, err := stores.Users.GetByUsername(ctx, username)
+		if err != nil {
+			t.Fatalf("Nalog %s ne postoji: %v", username, err)
+		}
+		return user
+	}
+
+	// Pogrešna lozinka ne kreira nalog
+	if response := login("jovana", "pogresna"); response.Success {
+		t.Fatalf("Pogrešna lozinka ne sme proći")
+	}
+	if _, err := stores.Users.GetByUsername(ctx, "jovana"); err == nil {
+		t.Fatalf("Nalog ne sme nastati posle neuspele prijave")
+	}
+
+	// Prva prijava kreira nalog sa ulogom iz grupe
+	if response := login("jovana", "imenik-lozinka"); !response.Success || response.Token == "" {
+		t.Fatalf("Prva prijava iz imenika nije uspela: %+v", response)
+	}
+	user := account("jovana")
+	if user.IzvorPrijave != models.IzvorLDAP || user.UlogaID != 2 || user.Email != "jovana@institut.rs" ||
+		user.Ime == nil || *user.Ime != "Jovana" || user.MoraPromenitiLozinku {
+		t.Errorf("Neispravan kreiran nalog: %+v", user)
+	}
+
+	// Uloga prati promenu grupe u imeniku
+	server.setGroups(jovana, "cn=istrazivaci,ou=grupe,dc=institut,dc=rs")
+	if response := login("jovana", "imenik-lozinka"); !response.Success {
+		t.Fatalf("Ponovna prijava nije uspela: %+v", response)
+	}
+	if user := account("jovana"); user.UlogaID != 3 || user.NazivUloge != "Istrazivac" {
+		t.Errorf("Uloga nije usklađena sa imenikom: %d %q", user.UlogaID, user.NazivUloge)
+	}
+	logs, _ := stores.Analytics.GetActivityLogs(ctx, -1)
+	counts := map[string]int{}
+	for _, entry := range logs {
+		counts[entry.TipAktivnosti]++
+	}
+	if counts[services.ActivityUserCreated] != 1 || counts[services.ActivityRoleAssigned] != 1 {
+		t.Errorf("Kreiranje naloga i promena uloge iz imenika moraju biti u dnevniku: %v", counts)
+	}
+	if response := login("jovana", "imenik-lozinka"); !response.Success {
+		t.Fatalf("Ponovna prijava nije uspela: %+v", response)
+	}
+	if again, _ := stores.Analytics.GetActivityLogs(ctx, -1); len(again) != len(logs) {
+		t.Errorf("Prijava bez promene uloge ne beleži dodelu, dobijeno %d zapisa", len(again))
+	}
+
+	// Pogrešna lozinka postojećeg naloga računa se kao neuspela prijava
+	login("jovana", "pogresna")
+	if user := account("jovana"); user.NeuspesnePrijave != 1 {
+		t.Errorf("Očekivana jedna neuspela prijava, dobijeno %d", user.NeuspesnePrijave)
+	}
+
+	// Korisnik bez mapirane grupe ne može da se prijavi
+	if response := login("student", "imenik-lozinka"); response.Success {
+		t.Errorf("Korisnik bez mapirane grupe ne sme proći")
+	}
+
+	// Lozinka naloga iz imenika ne menja se ovde
+	user = account("jovana")
+	if err := auth.ChangePassword(ctx, user.KorisnikID, "Nova-Lozinka-42", ""); !errors.Is(err, services.ErrInvalidInput) {
+		t.Errorf("Promena lozinke naloga iz imenika mora biti odbijena: %v", err)
+	}
+	_, adminCtx := newMemoryUser(t, stores, "admin", 1)
+	if _, err := auth.ResetPassword(adminCtx, user.KorisnikID); !errors.Is(err, services.ErrInvalidInput) {
+		t.Errorf("Reset lozinke naloga iz imenika mora biti odbijen: %v", err)
+	}
+
+	// Lokalni nalozi se i dalje proveravaju lokalnom lozinkom
+	local, _ := newMemoryUser(t, stores, "marko", 3)
+	hash, _ := auth.HashPassword("plavi-kamen-9")
+	stores.Users.UpdatePassword(ctx, local.KorisnikID, hash, false)
+	if response := login("marko", "plavi-kamen-9"); !response.Success {
+		t.Errorf("Lokalna prijava nije uspela: %+v", response)
+	}
+
+	// Bez automatskog kreiranja nepoznati korisnik imenika ne može da se prijavi
+	ldapCfg.Provision = false
+	auth.AddProvider(services.NewLDAPProvider(ldapCfg, stores))
+	if response := login("milan", "imenik-lozinka"); response.Success {
+		t.Errorf("Bez automatskog kreiranja prijava mora biti odbijena")
+	}
+
+	// Nedostupan imenik ne računa se kao neuspela prijava
+	server.listener.Close()
+	before := account("jovana").NeuspesnePrijave
+	if response := login("jovana", "imenik-lozinka"); response.Success || !strings.Contains(response.Message, "nije moguća") {
+		t.Errorf("Nedostupan imenik mora odbiti prijavu: %+v", response)
+	}
+	if account("jovana").NeuspesnePrijave != before {
+		t.Errorf("Nedostupan imenik ne sme povećati broj neuspelih prijava")
+	}
+}
@@ -0,0 +1,158 @@
+package tests
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cane/research-institute-system/backend/api"
+	"github.com/cane/research-institute-system/backend/logging"
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/repositories/memory"
+	"github.com/cane/research-institute-system/backend/services"
+)
+
+// captureLog preusmerava podrazumevani logger u bafer sa JSON zapisima
+func captureLog(t *testing.T, level slog.Level) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(logging.New(&buf, "json", level))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return &buf
+}
+
+// logEntries parsira JSON zapise iz bafera
+func logEntries(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+
+	var entries []map[string]interface{}
+	scanner := bufio.NewScanner(bytes.NewReader(buf.Bytes()))
+	for scanner.Scan() {
+		var entry map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("Neispravan zapis u logu %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func findEntry(entries []map[string]interface{}, msg string) map[string]interface{} {
+	for _, entry := range entries {
+		if entry["msg"] == msg {
+			return entry
+		}
+	}
+	return nil
+}
+
+// Test da zapisi iz servisa nose ID zahteva i korisnika iz konteksta
+func TestLoggingContextFields(t *testing.T) {
+	buf := captureLog(t, slog.LevelDebug)
+
+	ctx := logging.WithRequestID(context.Background(), "zahtev-1")
+	ctx = services.WithPrincipal(ctx, &services.Principal{User: &models.User{KorisnikID: 7, KorisnickoIme: "marko", NazivUloge: "Istrazivac"}})
+
+	if _, err := newTestAuthorizer(t, memory.NewStores()).Require(ctx, services.PermUserManage); err == nil {
+		t.Fatalf("Istraživač ne sme upravljati korisnicima")
+	}
+
+	entry := findEntry(logEntries(t, buf), "permission denied")
+	if entry == nil {
+		t.Fatalf("Odbijena dozvola mora biti zabeležena:\n%s", buf)
+	}
+	if entry["request_id"] != "zahtev-1" || entry["user"] != "marko" || entry["user_id"] != float64(7) {
+		t.Errorf("Nedostaju polja konteksta: %v", entry)
+	}
+	if entry["level"] != "WARN" || entry["permission"] != string(services.PermUserManage) {
+		t.Errorf("Pogrešan nivo ili dozvola: %v", entry)
+	}
+}
+
+// Test da se zapisi ispod zadatog nivoa izostavljaju
+func TestLoggingLevel(t *testing.T) {
+	buf := captureLog(t, slog.LevelWarn)
+
+	slog.Info("informacija")
+	slog.Warn("upozorenje")
+
+	entries := logEntries(t, buf)
+	if len(entries) != 1 || entries[0]["msg"] != "upozorenje" {
+		t.Errorf("Očekivano samo upozorenje, dobijeno %v", entries)
+	}
+}
+
+// Test rotacije log fajla po veličini
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "riis.log")
+	file, err := logging.OpenRotating(path, 100, 2)
+	if err != nil {
+		t.Fatalf("Greška pri otvaranju: %v", err)
+	}
+	defer file.Close()
+
+	line := []byte(strings.Repeat("x", 59) + "\n")
+	for i := 0; i < 5; i++ {
+		if _, err := file.Write(line); err != nil {
+			t.Fatalf("Greška pri upisu: %v", err)
+		}
+	}
+
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatalf("Očekivan fajl %s: %v", name, err)
+		}
+		if info.Size() > 100 {
+			t.Errorf("%s je veći od ograničenja: %d", name, info.Size())
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("Zadržano je više od dve rezervne kopije")
+	}
+}
+
+// Test ID-a zahteva u REST API-ju: odgovor ga vraća, a log ga beleži uz korisnika
+func TestAPIRequestID(t *testing.T) {
+	c := newAPIClient(t)
+	token := c.login("ana", 1)
+	buf := captureLog(t, slog.LevelInfo)
+
+	req, _ := http.NewRequest("GET", c.server.URL+api.Prefix+"/me", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set(api.RequestIDHeader, "klijent-42")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Zahtev nije uspeo: %v", err)
+	}
+	resp.Body.Close()
+
+	if got := resp.Header.Get(api.RequestIDHeader); got != "klijent-42" {
+		t.Errorf("ID zahteva klijenta mora biti vraćen, dobijeno %q", got)
+	}
+	entry := findEntry(logEntries(t, buf), "api request")
+	if entry == nil || entry["request_id"] != "klijent-42" || entry["user"] != "ana" {
+		t.Errorf("Zapis zahteva mora imati ID i korisnika: %v", entry)
+	}
+
+	req, _ = http.NewRequest("GET", c.server.URL+api.Prefix+"/health", nil)
+	req.Header.Set(api.RequestIDHeader, "neispravan id")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Zahtev nije uspeo: %v", err)
+	}
+	resp.Body.Close()
+
+	if got := resp.Header.Get(api.RequestIDHeader); got == "" || strings.Contains(got, " ") {
+		t.Errorf("Neispravan ID mora biti zamenjen novim, dobijeno %q", got)
+	}
+}
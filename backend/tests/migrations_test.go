@@ -0,0 +1,200 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/cane/research-institute-system/backend/migrations"
+	"github.com/cane/research-institute-system/database"
+)
+
+// Test učitavanja ugrađenih migracija
+func TestEmbeddedMigrations(t *testing.T) {
+	list, err := migrations.Load(database.Migrations, "migrations")
+	if err != nil {
+		t.Fatalf("Greška pri učitavanju migracija: %v", err)
+	}
+	if len(list) == 0 || list[0].Version != 1 {
+		t.Fatalf("Prva migracija mora biti verzija 1")
+	}
+	if !strings.Contains(list[0].Up, "CREATE TABLE Korisnici") {
+		t.Errorf("Početna migracija mora kreirati tabelu Korisnici")
+	}
+
+	for i := 1; i < len(list); i++ {
+		if list[i].Version <= list[i-1].Version {
+			t.Errorf("Migracije nisu sortirane: %d posle %d", list[i].Version, list[i-1].Version)
+		}
+	}
+}
+
+// Test odbijanja neispravnih fajlova migracija
+func TestLoadMigrationsValidation(t *testing.T) {
+	cases := map[string]fstest.MapFS{
+		"bez down fajla": {
+			"m/0001_init.up.sql": {Data: []byte("SELECT 1;")},
+		},
+		"neispravno ime": {
+			"m/init.sql": {Data: []byte("SELECT 1;")},
+		},
+		"različita imena": {
+			"m/0002_a.up.sql":   {Data: []byte("SELECT 1;")},
+			"m/0002_b.down.sql": {Data: []byte("SELECT 1;")},
+		},
+	}
+
+	for name, fsys := range cases {
+		if _, err := migrations.Load(fsys, "m"); err == nil {
+			t.Errorf("%s: očekivana greška", name)
+		}
+	}
+
+	valid := fstest.MapFS{
+		"m/0010_b.up.sql":   {Data: []byte("SELECT 2;")},
+		"m/0010_b.down.sql": {Data: []byte("SELECT 2;")},
+		"m/0002_a.up.sql":   {Data: []byte("SELECT 1;")},
+		"m/0002_a.down.sql": {Data: []byte("SELECT 1;")},
+	}
+	list, err := migrations.Load(valid, "m")
+	if err != nil {
+		t.Fatalf("Greška pri učitavanju: %v", err)
+	}
+	if len(list) != 2 || list[0].Version != 2 || list[1].Name != "b" {
+		t.Errorf("Neočekivan redosled migracija: %+v", list)
+	}
+}
+
+// connectToEmptySchema vraća konekciju na novu, praznu šemu baze koja se
+// briše na kraju testa, tako da migracije ne diraju postojeće tabele
+func connectToEmptySchema(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db := connectToDatabase(t)
+	if db == nil {
+		t.Skip("Preskačem test - nema konekcije na bazu")
+	}
+	t.Cleanup(func() { db.Close() })
+
+	// Jedna konekcija čuva search_path za sve upite testa
+	db.SetMaxOpenConns(1)
+	schema := fmt.Sprintf("riis_migracije_%d", time.Now().UnixNano())
+	if _, err := db.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		t.Fatalf("Greška pri kreiranju šeme: %v", err)
+	}
+	t.Cleanup(func() { db.Exec(`DROP SCHEMA ` + schema + ` CASCADE`) })
+	if _, err := db.Exec(`SET search_path TO ` + schema); err != nil {
+		t.Fatalf("Greška pri izboru šeme: %v", err)
+	}
+	return db
+}
+
+// Test primene svih migracija na praznu bazu i njihovog povlačenja
+func TestMigrationsOnFreshDatabase(t *testing.T) {
+	db := connectToEmptySchema(t)
+	ctx := context.Background()
+
+	migrator, err := migrations.New(db, database.Migrations, "migrations")
+	if err != nil {
+		t.Fatalf("Greška pri učitavanju migracija: %v", err)
+	}
+	all := migrator.Migrations()
+
+	appliedNow, err := migrator.Up(ctx)
+	if err != nil {
+		t.Fatalf("Greška pri primeni migracija: %v", err)
+	}
+	if len(appliedNow) != len(all) {
+		t.Errorf("Na praznoj bazi se primenjuju sve migracije: %d od %d", len(appliedNow), len(all))
+	}
+	if pending, err := migrator.Pending(ctx); err != nil || len(pending) != 0 {
+		t.Errorf("Posle primene ne sme ostati migracija na čekanju: %d, %v", len(pending), err)
+	}
+	if again, err := migrator.Up(ctx); err != nil || len(again) != 0 {
+		t.Errorf("Ponovljena primena nema šta da uradi: %d, %v", len(again), err)
+	}
+
+	// Sve down migracije moraju se izvršiti, a posle njih i up ponovo
+	rolledBack, err := migrator.DownTo(ctx, 0)
+	if err != nil {
+		t.Fatalf("Greška pri povlačenju migracija: %v", err)
+	}
+	if len(rolledBack) != len(all) {
+		t.Errorf("Povlače se sve migracije: %d od %d", len(rolledBack), len(all))
+	}
+	var exists bool
+	if err := db.QueryRow(`SELECT to_regclass('korisnici') IS NOT NULL`).Scan(&exists); err != nil || exists {
+		t.Errorf("Posle povlačenja tabela Korisnici ne sme postojati: %v, %v", exists, err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Errorf("Migracije se moraju ponovo primeniti posle povlačenja: %v", err)
+	}
+}
+
+// Test baze kreirane ručno iz stare schema.sql: početna šema se ne primenjuje
+// ponovo, a novije migracije da
+func TestMigrationsBaselineLegacyDatabase(t *testing.T) {
+	db := connectToEmptySchema(t)
+	ctx := context.Background()
+
+	migrator, err := migrations.New(db, database.Migrations, "migrations")
+	if err != nil {
+		t.Fatalf("Greška pri učitavanju migracija: %v", err)
+	}
+	all := migrator.Migrations()
+	if _, err := db.Exec(all[0].Up); err != nil {
+		t.Fatalf("Greška pri kreiranju stare šeme: %v", err)
+	}
+
+	appliedNow, err := migrator.Up(ctx)
+	if err != nil {
+		t.Fatalf("Greška pri primeni migracija na staru bazu: %v", err)
+	}
+	if len(appliedNow) != len(all)-1 || appliedNow[0].Version != all[1].Version {
+		t.Errorf("Na staroj bazi se primenjuju samo migracije posle početne: %+v", appliedNow)
+	}
+
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("Greška pri čitanju stanja migracija: %v", err)
+	}
+	for _, status := range statuses {
+		if !status.Applied {
+			t.Errorf("Migracija %d mora biti primenjena", status.Version)
+		}
+	}
+}
+
+// Test baze u kojoj je uloga Gost napravljena ručno pre migracije 0012: ta
+// uloga postaje sistemska uloga gosta
+func TestMigrationsExistingGuestRole(t *testing.T) {
+	db := connectToEmptySchema(t)
+	ctx := context.Background()
+
+	migrator, err := migrations.New(db, database.Migrations, "migrations")
+	if err != nil {
+		t.Fatalf("Greška pri učitavanju migracija: %v", err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("Greška pri primeni migracija: %v", err)
+	}
+	if _, err := migrator.DownTo(ctx, 11); err != nil {
+		t.Fatalf("Greška pri povlačenju migracija: %v", err)
+	}
+	var roleID int
+	if err := db.QueryRow(`INSERT INTO Uloge (naziv_uloge) VALUES ('Gost') RETURNING uloga_id`).Scan(&roleID); err != nil {
+		t.Fatalf("Greška pri kreiranju uloge: %v", err)
+	}
+
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("Migracije se moraju primeniti i kada uloga Gost postoji: %v", err)
+	}
+	var key sql.NullString
+	if err := db.QueryRow(`SELECT sistemska FROM Uloge WHERE uloga_id = $1`, roleID).Scan(&key); err != nil || key.String != "gost" {
+		t.Errorf("Postojeća uloga Gost mora postati sistemska uloga gosta: %v, %v", key, err)
+	}
+}
@@ -0,0 +1,30 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/cane/research-institute-system/backend/repositories"
+	"github.com/cane/research-institute-system/backend/repositories/memory"
+	"github.com/cane/research-institute-system/backend/repositories/repotest"
+)
+
+// Test ugovora repozitorijuma nad memorijskom implementacijom
+func TestMemoryStoresContract(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repositories.Stores {
+		return memory.NewStores()
+	})
+}
+
+// Test ugovora repozitorijuma nad PostgreSQL bazom
+func TestPostgresStoresContract(t *testing.T) {
+	db := connectToDatabase(t)
+	if db == nil {
+		t.Skip("Preskačem test - nema konekcije na bazu")
+		return
+	}
+	defer db.Close()
+
+	repotest.Run(t, func(t *testing.T) repositories.Stores {
+		return repositories.NewPostgresStores(db)
+	})
+}
@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/cane/research-institute-system/backend/migrations"
+	_ "github.com/cane/research-institute-system/backend/repositories"
+	"github.com/cane/research-institute-system/backend/schemacheck"
+	_ "github.com/cane/research-institute-system/backend/services"
+	"github.com/cane/research-institute-system/database"
+)
+
+var (
+	createdRelation = regexp.MustCompile(`(?i)CREATE\s+(?:TABLE|(?:OR\s+REPLACE\s+)?VIEW)\s+(?:IF\s+NOT\s+EXISTS\s+)?([a-z_][a-z0-9_]*)`)
+	usedRelation    = regexp.MustCompile(`(?i)\b(?:FROM|JOIN|INTO|UPDATE)\s+([a-z_][a-z0-9_]*)`)
+)
+
+// Test da svi registrovani upiti koriste tabele koje postoje u migracijama.
+// Radi bez baze, pa hvata pogrešna imena tabela i na CI-ju.
+func TestRegisteredQueriesUseKnownTables(t *testing.T) {
+	list, err := migrations.Load(database.Migrations, "migrations")
+	if err != nil {
+		t.Fatalf("Greška pri učitavanju migracija: %v", err)
+	}
+
+	// Postgres spušta nenavodnjena imena na mala slova
+	known := map[string]bool{"schema_migrations": true}
+	for _, m := range list {
+		for _, match := range createdRelation.FindAllStringSubmatch(m.Up, -1) {
+			known[strings.ToLower(match[1])] = true
+		}
+	}
+
+	queries := schemacheck.Queries()
+	if len(queries) == 0 {
+		t.Fatal("Nijedan upit nije registrovan")
+	}
+
+	for _, query := range queries {
+		for _, match := range usedRelation.FindAllStringSubmatch(query.SQL, -1) {
+			// ON CONFLICT ... DO UPDATE SET ne imenuje tabelu
+			if strings.EqualFold(match[1], "SET") {
+				continue
+			}
+			if !known[strings.ToLower(match[1])] {
+				t.Errorf("%s koristi nepostojeću tabelu %q", query.Name, match[1])
+			}
+		}
+	}
+}
+
+// Test pripreme svih registrovanih upita nad stvarnom bazom
+func TestRegisteredQueriesPrepare(t *testing.T) {
+	db := connectToDatabase(t)
+	if db == nil {
+		t.Skip("Preskačem test - nema konekcije na bazu")
+		return
+	}
+	defer db.Close()
+
+	report, err := schemacheck.Check(context.Background(), db)
+	if err != nil {
+		t.Fatalf("Greška pri proveri šeme: %v", err)
+	}
+	for _, problem := range report.Problems {
+		t.Errorf("%s (%s %s): %s", problem.Query, problem.Kind, problem.Object, problem.Message)
+	}
+}
+
+// Test registra upita
+func TestSchemacheckRegister(t *testing.T) {
+	query := schemacheck.Register("tests.TestSchemacheckRegister", "SELECT 1")
+	if query != "SELECT 1" {
+		t.Errorf("Register mora vratiti upit nepromenjen")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Dupla registracija mora izazvati panic")
+		}
+	}()
+	schemacheck.Register("tests.TestSchemacheckRegister", "SELECT 2")
+}
@@ -0,0 +1,2144 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cane/research-institute-system/backend/config"
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/repositories"
+	"github.com/cane/research-institute-system/backend/repositories/memory"
+	"github.com/cane/research-institute-system/backend/services"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// newMemoryUser upisuje korisnika direktno u memorijski repozitorijum i
+// vraća kontekst sa tim korisnikom kao pozivaocem
+func newMemoryUser(t *testing.T, stores repositories.Stores, username string, roleID int) (*models.User, context.Context) {
+	t.Helper()
+
+	ctx := context.Background()
+	user := &models.User{KorisnickoIme: username, Email: username + "@test.local", UlogaID: roleID, Status: "aktivan"}
+	if err := stores.Users.Create(ctx, user); err != nil {
+		t.Fatalf("Greška pri kreiranju korisnika: %v", err)
+	}
+
+	stored, err := stores.Users.GetByID(ctx, user.KorisnikID)
+	if err != nil {
+		t.Fatalf("Greška pri čitanju korisnika: %v", err)
+	}
+
+	return stored, services.WithPrincipal(ctx, &services.Principal{User: stored})
+}
+
+// newTestAuthorizer čita dozvole uloga iz istih repozitorijuma koje test koristi
+func newTestAuthorizer(t *testing.T, stores repositories.Stores) *services.Authorizer {
+	t.Helper()
+
+	authz, err := services.NewAuthorizer(context.Background(), stores.Roles)
+	if err != nil {
+		t.Fatalf("Greška pri učitavanju dozvola uloga: %v", err)
+	}
+	return authz
+}
+
+func newTestAuthService(t *testing.T, stores repositories.Stores, cfg config.AuthConfig) *services.AuthService {
+	t.Helper()
+
+	sessions := services.NewSessionManager(services.DefaultSessionIdleTimeout, services.DefaultSessionMaxLifetime)
+	return services.NewAuthService(stores, sessions, newTestAuthorizer(t, stores), cfg)
+}
+
+// Test prijave preko AuthService bez baze podataka: novi nalog se aktivira
+// jednokratnim kodom pre prve prave prijave
+func TestAuthServiceLoginWithMemoryStores(t *testing.T) {
+	stores := memory.NewStores()
+	cfg := config.Default().Auth
+	cfg.LoginDelay = 0
+	auth := newTestAuthService(t, stores, cfg)
+
+	_, adminCtx := newMemoryUser(t, stores, "admin", 1)
+	ctx := context.Background()
+
+	user := &models.User{KorisnickoIme: "istrazivac", Email: "istrazivac@test.local", UlogaID: 3}
+	activation, err := auth.CreateUser(adminCtx, user)
+	if err != nil {
+		t.Fatalf("Greška pri kreiranju korisnika: %v", err)
+	}
+	if len(activation.Kod) != 14 || activation.KorisnikID != user.KorisnikID || !activation.Istice.After(time.Now()) {
+		t.Errorf("Neispravan aktivacioni kod: %+v", activation)
+	}
+	if _, err := auth.CreateUser(adminCtx, &models.User{KorisnickoIme: "istrazivac", Email: "drugi@test.local", UlogaID: 3}); !errors.Is(err, repositories.ErrConflict) {
+		t.Errorf("Dupli korisnik mora biti odbijen kao konflikt, dobijeno %v", err)
+	}
+	if _, err := auth.CreateUser(adminCtx, &models.User{KorisnickoIme: "drugi", Email: "ISTRAZIVAC@test.local", UlogaID: 3}); !errors.Is(err, repositories.ErrConflict) {
+		t.Errorf("Zauzeta email adresa mora biti odbijena kao konflikt, dobijeno %v", err)
+	}
+
+	// Poznato korisničko ime više nije dovoljno za prvu prijavu
+	response, err := auth.Login(ctx, services.LoginRequest{Username: "istrazivac", Password: "bilo-sta"})
+	if err != nil || response.Success {
+		t.Fatalf("Prva prijava bez ispravnog koda mora biti odbijena: %+v, %v", response, err)
+	}
+
+	// Kod se unosi bez obzira na velika slova i crtice
+	typed := strings.ToLower(strings.ReplaceAll(activation.Kod, "-", ""))
+	response, err = auth.Login(ctx, services.LoginRequest{Username: "istrazivac", Password: typed})
+	if err != nil || !response.Success || response.Message != services.FirstTimeLogin {
+		t.Fatalf("Prijava kodom mora tražiti postavljanje lozinke: %+v, %v", response, err)
+	}
+	activationToken := response.Token
+
+	if _, err := auth.Authenticate(ctx, activationToken); !errors.Is(err, services.ErrPasswordChangeRequired) {
+		t.Errorf("Aktivaciona sesija ne sme važiti za ostale pozive, dobijeno %v", err)
+	}
+	if response, _ := auth.Login(ctx, services.LoginRequest{Username: "istrazivac", Password: activation.Kod}); response.Success {
+		t.Errorf("Aktivacioni kod se sme iskoristiti samo jednom")
+	}
+
+	if _, err := auth.CompleteActivation(ctx, activationToken, "kratka"); !errors.Is(err, services.ErrInvalidInput) {
+		t.Errorf("Prekratka lozinka mora biti odbijena, dobijeno %v", err)
+	}
+	response, err = auth.CompleteActivation(ctx, activationToken, "nova-lozinka")
+	if err != nil || response.Token == "" || response.Token == activationToken {
+		t.Fatalf("Greška pri postavljanju lozinke: %+v, %v", response, err)
+	}
+	if _, err := auth.Authenticate(ctx, response.Token); err != nil {
+		t.Errorf("Posle aktivacije sesija mora važiti: %v", err)
+	}
+	if _, err := auth.Authenticate(ctx, activationToken); !errors.Is(err, services.ErrNoSession) {
+		t.Errorf("Aktivaciona sesija mora biti završena, dobijeno %v", err)
+	}
+
+	response, err = auth.Login(ctx, services.LoginRequest{Username: "istrazivac", Password: activation.Kod})
+	if err != nil || response.Success {
+		t.Errorf("Aktivacioni kod posle aktivacije ne sme važiti: %+v, %v", response, err)
+	}
+
+	response, err = auth.Login(ctx, services.LoginRequest{Username: "istrazivac", Password: "nova-lozinka"})
+	if err != nil || !response.Success || response.Token == "" || response.Message == services.FirstTimeLogin {
+		t.Fatalf("Prijava sa novom lozinkom nije uspela: %+v, %v", response, err)
+	}
+	if response.User.HashSifre != "" {
+		t.Errorf("Odgovor ne sme sadržati hash lozinke")
+	}
+
+	stored, _ := stores.Users.GetByUsername(ctx, "istrazivac")
+	if stored.PoslednajaPrijava == nil || stored.MoraPromenitiLozinku {
+		t.Errorf("Poslednja prijava mora biti zabeležena, a obaveza promene lozinke uklonjena: %+v", stored)
+	}
+
+	response, _ = auth.Login(ctx, services.LoginRequest{Username: "nepostojeci", Password: "x"})
+	if response.Success {
+		t.Errorf("Nepostojeći korisnik ne sme se prijaviti")
+	}
+}
+
+// Desktop aplikacija i REST server imaju svaka svoje sesije nad istom bazom:
+// promena lozinke ili deaktivacija u jednom procesu završava sesije i u drugom
+func TestSessionsRevokedInOtherProcesses(t *testing.T) {
+	stores := memory.NewStores()
+	cfg := config.Default().Auth
+	cfg.LoginDelay = 0
+	cfg.TwoFactorRoles = nil
+	desktop, server := newTestAuthService(t, stores, cfg), newTestAuthService(t, stores, cfg)
+	ctx := context.Background()
+
+	_, adminCtx := newMemoryUser(t, stores, "admin", 1)
+	user, _ := newMemoryUser(t, stores, "jelena", 3)
+	hash, _ := desktop.HashPassword("plavi-kamen-9")
+	stores.Users.UpdatePassword(ctx, user.KorisnikID, hash, false)
+
+	login := func(auth *services.AuthService, password string) string {
+		t.Helper()
+		response, err := auth.Login(ctx, services.LoginRequest{Username: "jelena", Password: password})
+		if err != nil || !response.Success {
+			t.Fatalf("Prijava nije uspela: %+v, %v", response, err)
+		}
+		return response.Token
+	}
+	desktopToken, serverToken := login(desktop, "plavi-kamen-9"), login(server, "plavi-kamen-9")
+
+	if err := desktop.ChangePassword(ctx, user.KorisnikID, "zeleni-most-4", desktopToken); err != nil {
+		t.Fatalf("Greška pri promeni lozinke: %v", err)
+	}
+	if _, err := server.Authenticate(ctx, serverToken); !errors.Is(err, services.ErrNoSession) {
+		t.Errorf("Promena lozinke mora završiti sesiju u drugom procesu, dobijeno %v", err)
+	}
+	if _, err := desktop.Authenticate(ctx, desktopToken); err != nil {
+		t.Errorf("Sesija iz koje je lozinka promenjena ostaje: %v", err)
+	}
+
+	serverToken = login(server, "zeleni-most-4")
+	if _, err := server.Authenticate(ctx, serverToken); err != nil {
+		t.Errorf("Nova sesija posle promene lozinke mora važiti: %v", err)
+	}
+	if _, err := desktop.OffboardUser(adminCtx, user.KorisnikID, models.Handover{}); err != nil {
+		t.Fatalf("Greška pri deaktivaciji: %v", err)
+	}
+	if _, err := server.Authenticate(ctx, serverToken); !errors.Is(err, services.ErrNoSession) {
+		t.Errorf("Deaktivacija mora završiti sesiju u drugom procesu, dobijeno %v", err)
+	}
+}
+
+// Test resetovanja lozinke, isteka koda i zapisa u dnevniku aktivnosti
+func TestAuthServiceActivationAudit(t *testing.T) {
+	stores := memory.NewStores()
+	cfg := config.Default().Auth
+	cfg.LoginDelay = 0
+	auth := newTestAuthService(t, stores, cfg)
+
+	admin, adminCtx := newMemoryUser(t, stores, "admin", 1)
+	ctx := context.Background()
+
+	user := &models.User{KorisnickoIme: "marko", Email: "marko@test.local", UlogaID: 3}
+	first, err := auth.CreateUser(adminCtx, user)
+	if err != nil {
+		t.Fatalf("Greška pri kreiranju korisnika: %v", err)
+	}
+	if err := auth.ChangePassword(ctx, user.KorisnikID, "plavi-kamen-9", ""); err != nil {
+		t.Fatalf("Greška pri promeni lozinke: %v", err)
+	}
+	if response, _ := auth.Login(ctx, services.LoginRequest{Username: "marko", Password: first.Kod}); response.Success {
+		t.Errorf("Promena lozinke mora poništiti aktivacioni kod")
+	}
+
+	session, err := auth.Login(ctx, services.LoginRequest{Username: "marko", Password: "plavi-kamen-9"})
+	if err != nil || !session.Success {
+		t.Fatalf("Prijava nije uspela: %+v, %v", session, err)
+	}
+
+	second, err := auth.ResetPassword(adminCtx, user.KorisnikID)
+	if err != nil || second.Kod == first.Kod {
+		t.Fatalf("Reset mora izdati novi kod: %+v, %v", second, err)
+	}
+	if _, err := auth.Authenticate(ctx, session.Token); err == nil {
+		t.Errorf("Reset mora završiti sesije korisnika")
+	}
+	if response, _ := auth.Login(ctx, services.LoginRequest{Username: "marko", Password: "plavi-kamen-9"}); response.Success {
+		t.Errorf("Stara lozinka posle reseta ne sme važiti")
+	}
+
+	// Istekao kod se odbija
+	expired := newTestAuthService(t, stores, config.AuthConfig{ActivationTTL: -time.Minute})
+	third, err := expired.ResetPassword(adminCtx, user.KorisnikID)
+	if err != nil {
+		t.Fatalf("Greška pri resetu: %v", err)
+	}
+	response, _ := auth.Login(ctx, services.LoginRequest{Username: "marko", Password: third.Kod})
+	if response.Success || !strings.Contains(response.Message, "istekao") {
+		t.Errorf("Istekao kod mora biti odbijen: %+v", response)
+	}
+
+	logs, err := stores.Analytics.GetActivityLogs(ctx, -1)
+	if err != nil {
+		t.Fatalf("Greška pri čitanju dnevnika: %v", err)
+	}
+	counts := map[string]int{}
+	for _, entry := range logs {
+		if entry.CiljaniID == nil || *entry.CiljaniID != user.KorisnikID {
+			continue
+		}
+		counts[entry.TipAktivnosti]++
+		switch entry.TipAktivnosti {
+		case services.ActivityActivationIssued, services.ActivityPasswordReset, services.ActivityUserCreated:
+			if entry.KorisnikID == nil || *entry.KorisnikID != admin.KorisnikID {
+				t.Errorf("%s mora beležiti administratora: %+v", entry.TipAktivnosti, entry)
+			}
+		case services.ActivityActivationFailed:
+			if entry.KorisnikID != nil {
+				t.Errorf("Neuspešna prijava nema izvršioca: %+v", entry)
+			}
+		}
+	}
+	want := map[string]int{
+		services.ActivityUserCreated:      1,
+		services.ActivityActivationIssued: 3,
+		services.ActivityPasswordReset:    2,
+		services.ActivityActivationFailed: 2,
+	}
+	for activity, n := range want {
+		if counts[activity] != n {
+			t.Errorf("Očekivano %d zapisa %s, dobijeno %d (%v)", n, activity, counts[activity], counts)
+		}
+	}
+
+	// Zapisi bez izvršioca ne sprečavaju brisanje korisnika
+	if err := stores.Users.Delete(ctx, user.KorisnikID); err != nil {
+		t.Errorf("Korisnik mora moći da se obriše: %v", err)
+	}
+}
+
+// Test progresivnog kašnjenja, zaključavanja naloga i otključavanja
+func TestAuthServiceLockout(t *testing.T) {
+	stores := memory.NewStores()
+	cfg := config.Default().Auth
+	cfg.LoginDelay = time.Second
+	cfg.LockoutThreshold = 3
+	cfg.LockoutDuration = 15 * time.Minute
+	auth := newTestAuthService(t, stores, cfg)
+
+	now := time.Now()
+	auth.SetClock(func() time.Time { return now })
+
+	admin, adminCtx := newMemoryUser(t, stores, "admin", 1)
+	user, userCtx := newMemoryUser(t, stores, "marko", 3)
+	ctx := context.Background()
+	hash, _ := auth.HashPassword("marko-lozinka")
+	stores.Users.UpdatePassword(ctx, user.KorisnikID, hash, false)
+
+	login := func(password string) *services.LoginResponse {
+		t.Helper()
+		response, err := auth.Login(ctx, services.LoginRequest{Username: "marko", Password: password})
+		if err != nil {
+			t.Fatalf("Greška pri prijavi: %v", err)
+		}
+		return response
+	}
+
+	// Kašnjenje se udvostručava posle svakog neuspeha
+	for i, delay := range []int{1, 2} {
+		if response := login("pogresna"); response.Success || response.RetryAfter != 0 {
+			t.Fatalf("Pokušaj %d mora biti proveren i odbijen: %+v", i+1, response)
+		}
+		if response := login("marko-lozinka"); response.Success || response.RetryAfter != delay {
+			t.Errorf("Posle %d. neuspeha očekivano čekanje %d s, dobijeno %+v", i+1, delay, response)
+		}
+		now = now.Add(time.Duration(delay) * time.Second)
+	}
+
+	// Treći neuspeh zaključava nalog; ni ispravna lozinka ne prolazi
+	login("pogresna")
+	now = now.Add(time.Minute)
+	response := login("marko-lozinka")
+	if response.Success || !strings.Contains(response.Message, "zaključan") || response.RetryAfter != 14*60 {
+		t.Errorf("Zaključan nalog mora biti odbijen: %+v", response)
+	}
+	stored, _ := stores.Users.GetByID(ctx, user.KorisnikID)
+	if stored.NeuspesnePrijave != 3 || stored.ZakljucanDo == nil {
+		t.Errorf("Neuspesi i zaključavanje moraju biti upisani: %+v", stored)
+	}
+
+	if err := auth.UnlockAccount(userCtx, user.KorisnikID); !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("Istraživač ne sme otključavati naloge, dobijeno %v", err)
+	}
+	if err := auth.UnlockAccount(adminCtx, user.KorisnikID); err != nil {
+		t.Fatalf("Greška pri otključavanju: %v", err)
+	}
+	if response := login("marko-lozinka"); !response.Success {
+		t.Errorf("Otključan nalog mora se prijaviti: %+v", response)
+	}
+
+	// Zaključavanje ističe samo od sebe, a uspešna prijava briše brojač
+	for i := 0; i < 3; i++ {
+		login("pogresna")
+		now = now.Add(time.Minute)
+	}
+	if response := login("marko-lozinka"); response.Success {
+		t.Errorf("Nalog mora ponovo biti zaključan: %+v", response)
+	}
+	now = now.Add(cfg.LockoutDuration)
+	if response := login("marko-lozinka"); !response.Success {
+		t.Errorf("Posle isteka zaključavanja prijava mora uspeti: %+v", response)
+	}
+	stored, _ = stores.Users.GetByID(ctx, user.KorisnikID)
+	if stored.NeuspesnePrijave != 0 || stored.ZakljucanDo != nil || stored.PoslednjaNeuspesnaPrijava != nil {
+		t.Errorf("Uspešna prijava mora obrisati neuspehe: %+v", stored)
+	}
+
+	logs, _ := stores.Analytics.GetActivityLogs(ctx, -1)
+	counts := map[string]int{}
+	for _, entry := range logs {
+		if entry.CiljaniID == nil || *entry.CiljaniID != user.KorisnikID {
+			continue
+		}
+		counts[entry.TipAktivnosti]++
+		if entry.TipAktivnosti == services.ActivityAccountUnlocked && (entry.KorisnikID == nil || *entry.KorisnikID != admin.KorisnikID) {
+			t.Errorf("Otključavanje mora beležiti administratora: %+v", entry)
+		}
+	}
+	want := map[string]int{
+		services.ActivityLoginFailed:     6,
+		services.ActivityAccountLocked:   2,
+		services.ActivityAccountUnlocked: 1,
+	}
+	for activity, n := range want {
+		if counts[activity] != n {
+			t.Errorf("Očekivano %d zapisa %s, dobijeno %d (%v)", n, activity, counts[activity], counts)
+		}
+	}
+}
+
+// Test blokade izvora posle neuspeha na više naloga
+func TestAuthServiceSourceLockout(t *testing.T) {
+	stores := memory.NewStores()
+	cfg := config.Default().Auth
+	cfg.LoginDelay = 0
+	cfg.SourceLockoutThreshold = 2
+	auth := newTestAuthService(t, stores, cfg)
+
+	now := time.Now()
+	auth.SetClock(func() time.Time { return now })
+	ctx := context.Background()
+
+	for _, username := range []string{"prvi", "drugi"} {
+		if response, _ := auth.Login(ctx, services.LoginRequest{Username: username, Password: "x", Source: "10.0.0.1"}); response.Success {
+			t.Fatalf("Nepostojeći korisnik ne sme se prijaviti")
+		}
+	}
+
+	response, _ := auth.Login(ctx, services.LoginRequest{Username: "treci", Password: "x", Source: "10.0.0.1"})
+	if response.RetryAfter != int(cfg.LockoutDuration/time.Second) {
+		t.Errorf("Blokiran izvor mora biti odbijen bez provere: %+v", response)
+	}
+	if response, _ := auth.Login(ctx, services.LoginRequest{Username: "treci", Password: "x", Source: "10.0.0.2"}); response.RetryAfter != 0 {
+		t.Errorf("Drugi izvor ne sme biti blokiran: %+v", response)
+	}
+
+	now = now.Add(cfg.LockoutDuration)
+	if response, _ := auth.Login(ctx, services.LoginRequest{Username: "treci", Password: "x", Source: "10.0.0.1"}); response.RetryAfter != 0 {
+		t.Errorf("Blokada izvora mora isteći: %+v", response)
+	}
+
+	logs, _ := stores.Analytics.GetActivityLogs(ctx, -1)
+	counts := map[string]int{}
+	for _, entry := range logs {
+		counts[entry.TipAktivnosti]++
+		if entry.CiljaniID != nil || entry.KorisnikID != nil {
+			t.Errorf("Zapis bez naloga nema cilj ni izvršioca: %+v", entry)
+		}
+	}
+	if counts[services.ActivityLoginFailed] != 4 || counts[services.ActivitySourceBlocked] != 1 {
+		t.Errorf("Neočekivani zapisi: %v", counts)
+	}
+}
+
+// Brojači izvora bez skorijih neuspeha se uklanjaju, pa mapa izvora ne raste
+// sa svakom adresom koja je ikada pogrešila lozinku
+func TestLoginThrottleForgetsStaleSources(t *testing.T) {
+	cfg := config.Default().Auth
+	throttle := services.NewLoginThrottle(cfg)
+	now := time.Now()
+	throttle.SetClock(func() time.Time { return now })
+
+	fail := func(prefix string) {
+		for i := 0; i < 1000; i++ {
+			throttle.Fail(fmt.Sprintf("%s.%d", prefix, i))
+		}
+	}
+	fail("10.0.0")
+	if throttle.Sources() != 1000 {
+		t.Fatalf("Svi skori izvori se pamte, dobijeno %d", throttle.Sources())
+	}
+
+	now = now.Add(cfg.LockoutDuration)
+	fail("10.0.1")
+	if throttle.Sources() > 1000+64 {
+		t.Errorf("Zastareli izvori moraju biti uklonjeni, ostalo %d", throttle.Sources())
+	}
+	if wait, _ := throttle.Wait("10.0.1.999"); wait == 0 {
+		t.Errorf("Skori izvor mora zadržati brojač")
+	}
+}
+
+// Test pravila za lozinke i istorije lozinki
+func TestPasswordPolicy(t *testing.T) {
+	stores := memory.NewStores()
+	cfg := config.Default().Auth
+	cfg.PasswordMinClasses = 3
+	cfg.PasswordHistory = 2
+	auth := newTestAuthService(t, stores, cfg)
+
+	user := &models.User{KorisnickoIme: "marko.petrovic", Email: "mpetrovic@test.local", UlogaID: 3, Status: "aktivan"}
+	ctx := context.Background()
+	if err := stores.Users.Create(ctx, user); err != nil {
+		t.Fatalf("Greška pri kreiranju korisnika: %v", err)
+	}
+
+	codes := func(password string) string {
+		t.Helper()
+		violations, err := auth.CheckPassword(ctx, user.KorisnikID, password)
+		if err != nil {
+			t.Fatalf("Greška pri proveri lozinke: %v", err)
+		}
+		var list []string
+		for _, violation := range violations {
+			if violation.Message == "" {
+				t.Errorf("Prekršaj bez poruke: %+v", violation)
+			}
+			list = append(list, violation.Code)
+		}
+		return strings.Join(list, ",")
+	}
+
+	for password, want := range map[string]string{
+		"Ab1!":            "too_short",
+		"dugacka-lozinka": "too_few_classes",
+		"Petrovic-2024":   "contains_username",
+		"Mpetrovic#2024":  "contains_username,contains_email",
+		"Lozinka123!":     "common",
+		"qwerty":          "too_short,too_few_classes,common",
+		"Sunce-i-Kiša7":   "",
+	} {
+		if got := codes(password); got != want {
+			t.Errorf("Lozinka %q: očekivano [%s], dobijeno [%s]", password, want, got)
+		}
+	}
+
+	err := auth.ChangePassword(ctx, user.KorisnikID, "kratka", "")
+	var policyErr *services.PasswordPolicyError
+	if !errors.As(err, &policyErr) || !errors.Is(err, services.ErrInvalidInput) || len(policyErr.Violations) != 2 {
+		t.Errorf("Loša lozinka mora biti odbijena sa svim prekršajima, dobijeno %v", err)
+	}
+
+	// Poslednje dve lozinke se ne smeju ponoviti, starije mogu
+	for _, password := range []string{"Prva-Lozinka1", "Druga-Lozinka2", "Treca-Lozinka3"} {
+		if err := auth.ChangePassword(ctx, user.KorisnikID, password, ""); err != nil {
+			t.Fatalf("Greška pri promeni lozinke: %v", err)
+		}
+	}
+	for password, want := range map[string]string{"Treca-Lozinka3": "reused", "Druga-Lozinka2": "reused", "Prva-Lozinka1": ""} {
+		if got := codes(password); got != want {
+			t.Errorf("Istorija za %q: očekivano [%s], dobijeno [%s]", password, want, got)
+		}
+	}
+}
+
+// Test provere bcrypt i starih argon2id heševa i njihove zamene pri prijavi
+func TestPasswordHashUpgrade(t *testing.T) {
+	stores := memory.NewStores()
+	cfg := config.Default().Auth
+	cfg.Argon2Memory = 16 * 1024
+	cfg.Argon2Iterations = 2
+	cfg.Argon2Parallelism = 2
+	cfg.LoginDelay = 0
+	auth := newTestAuthService(t, stores, cfg)
+	hasher := services.NewPasswordHasher(cfg)
+	ctx := context.Background()
+
+	current, err := hasher.Hash("Plavo-Nebo-42")
+	if err != nil || !strings.HasPrefix(current, "$argon2id$v=19$m=16384,t=2,p=2$") {
+		t.Fatalf("Neispravan heš %q: %v", current, err)
+	}
+	if ok, outdated := hasher.Verify("Plavo-Nebo-42", current); !ok || outdated {
+		t.Errorf("Trenutni heš mora važiti i ne sme biti zastareo")
+	}
+	if ok, _ := hasher.Verify("pogresna", current); ok {
+		t.Errorf("Pogrešna lozinka ne sme proći")
+	}
+	for _, malformed := range []string{"", "!", "$argon2id$v=19$m=0,t=1,p=1$AAAA$AAAA", "$2a$10$kratko"} {
+		if ok, _ := hasher.Verify("", malformed); ok {
+			t.Errorf("Neispravan heš %q ne sme proći", malformed)
+		}
+	}
+
+	legacy, _ := bcrypt.GenerateFromPassword([]byte("Stara-Lozinka-1"), bcrypt.MinCost)
+	weaker, _ := services.NewPasswordHasher(config.Default().Auth).Hash("Druga-Lozinka-2")
+
+	for _, tc := range []struct {
+		username, password, hash string
+	}{
+		{"bcrypt", "Stara-Lozinka-1", string(legacy)},
+		{"argon", "Druga-Lozinka-2", weaker},
+	} {
+		user, _ := newMemoryUser(t, stores, tc.username, 3)
+		stores.Users.UpdatePassword(ctx, user.KorisnikID, tc.hash, false)
+
+		if ok, outdated := hasher.Verify(tc.password, tc.hash); !ok || !outdated {
+			t.Errorf("%s: heš mora važiti i biti zastareo", tc.username)
+		}
+		if response, _ := auth.Login(ctx, services.LoginRequest{Username: tc.username, Password: "pogresna"}); response.Success {
+			t.Errorf("%s: pogrešna lozinka ne sme proći", tc.username)
+		}
+		stored, _ := stores.Users.GetByID(ctx, user.KorisnikID)
+		if stored.HashSifre != tc.hash {
+			t.Errorf("%s: neuspešna prijava ne sme menjati heš", tc.username)
+		}
+
+		response, err := auth.Login(ctx, services.LoginRequest{Username: tc.username, Password: tc.password})
+		if err != nil || !response.Success {
+			t.Fatalf("%s: prijava nije uspela: %+v, %v", tc.username, response, err)
+		}
+		stored, _ = stores.Users.GetByID(ctx, user.KorisnikID)
+		if ok, outdated := hasher.Verify(tc.password, stored.HashSifre); !ok || outdated {
+			t.Errorf("%s: heš mora biti zamenjen trenutnim, dobijeno %q", tc.username, stored.HashSifre)
+		}
+		if response, _ := auth.Login(ctx, services.LoginRequest{Username: tc.username, Password: tc.password}); !response.Success {
+			t.Errorf("%s: prijava sa novim hešom nije uspela: %+v", tc.username, response)
+		}
+	}
+}
+
+// Test drugog faktora sa fiksnim satom: podešavanje, prijava, ponovljeni
+// kod, rezervni kodovi, obavezni drugi faktor za ulogu i uklanjanje
+func TestTwoFactor(t *testing.T) {
+	// Test vektori iz RFC 6238, dodatak B (poslednjih šest cifara)
+	rfcSecret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	for unix, want := range map[int64]string{59: "287082", 1111111109: "081804", 2000000000: "279037"} {
+		if code, err := services.TOTPCode(rfcSecret, time.Unix(unix, 0)); err != nil || code != want {
+			t.Errorf("Kod u %d: očekivano %s, dobijeno %s (%v)", unix, want, code, err)
+		}
+	}
+
+	stores := memory.NewStores()
+	cfg := config.Default().Auth
+	cfg.LoginDelay = 0
+	auth := newTestAuthService(t, stores, cfg)
+
+	now := time.Now()
+	auth.SetClock(func() time.Time { return now })
+
+	admin, adminCtx := newMemoryUser(t, stores, "admin", 1)
+	user, userCtx := newMemoryUser(t, stores, "marko", 3)
+	ctx := context.Background()
+	hash, _ := auth.HashPassword("plavi-kamen-9")
+	stores.Users.UpdatePassword(ctx, user.KorisnikID, hash, false)
+	stores.Users.UpdatePassword(ctx, admin.KorisnikID, hash, false)
+
+	login := func(username string) *services.LoginResponse {
+		t.Helper()
+		response, err := auth.Login(ctx, services.LoginRequest{Username: username, Password: "plavi-kamen-9"})
+		if err != nil || !response.Success {
+			t.Fatalf("Prijava %s nije uspela: %+v, %v", username, response, err)
+		}
+		return response
+	}
+
+	// Istraživaču drugi faktor nije obavezan, pa ga uključuje iz obične sesije
+	response := login("marko")
+	if response.Message == services.TwoFactorRequired || response.Message == services.TwoFactorSetupRequired {
+		t.Fatalf("Bez drugog faktora prijava mora dati običnu sesiju: %+v", response)
+	}
+	session := response.Token
+
+	enrollment, err := auth.EnrollTwoFactor(ctx, session)
+	if err != nil {
+		t.Fatalf("Greška pri podešavanju: %v", err)
+	}
+	if !strings.HasPrefix(enrollment.URL, "otpauth://totp/RIIS:marko?") || !strings.Contains(enrollment.URL, "secret="+enrollment.Tajna) {
+		t.Errorf("Neispravan otpauth URL: %s", enrollment.URL)
+	}
+	if _, err := auth.ConfirmTwoFactor(ctx, session, "000000"); !errors.Is(err, services.ErrInvalidInput) {
+		t.Errorf("Pogrešan kod ne sme uključiti drugi faktor, dobijeno %v", err)
+	}
+	code, _ := services.TOTPCode(enrollment.Tajna, now)
+	setup, err := auth.ConfirmTwoFactor(ctx, session, code)
+	if err != nil || len(setup.RezervniKodovi) != 10 || setup.Prijava != nil {
+		t.Fatalf("Uključivanje nije uspelo: %+v, %v", setup, err)
+	}
+	if _, err := auth.EnrollTwoFactor(ctx, session); !errors.Is(err, repositories.ErrConflict) {
+		t.Errorf("Uključen drugi faktor se ne sme zameniti, dobijeno %v", err)
+	}
+
+	// Prijava sada traži kod; sesija do tada ne važi za ostale pozive
+	response = login("marko")
+	if response.Message != services.TwoFactorRequired {
+		t.Fatalf("Očekivana poruka %s, dobijeno %+v", services.TwoFactorRequired, response)
+	}
+	if _, err := auth.Authenticate(ctx, response.Token); !errors.Is(err, services.ErrTwoFactorRequired) {
+		t.Errorf("Sesija bez drugog faktora ne sme važiti, dobijeno %v", err)
+	}
+	// Kod korišćen pri uključivanju ne važi ponovo
+	if verified, _ := auth.VerifyTwoFactor(ctx, response.Token, code, ""); verified.Success {
+		t.Errorf("Iskorišćen kod ne sme važiti ponovo")
+	}
+	now = now.Add(30 * time.Second)
+	code, _ = services.TOTPCode(enrollment.Tajna, now)
+	verified, err := auth.VerifyTwoFactor(ctx, response.Token, code, "")
+	if err != nil || !verified.Success {
+		t.Fatalf("Ispravan kod mora proći: %+v, %v", verified, err)
+	}
+	if _, err := auth.Authenticate(ctx, verified.Token); err != nil {
+		t.Errorf("Nova sesija mora važiti: %v", err)
+	}
+	if _, err := auth.Authenticate(ctx, response.Token); !errors.Is(err, services.ErrNoSession) {
+		t.Errorf("Sesija za drugi faktor mora biti zamenjena, dobijeno %v", err)
+	}
+
+	// Rezervni kod važi jednom, bez obzira na velika slova i crtice
+	recovery := strings.ToLower(strings.ReplaceAll(setup.RezervniKodovi[0], "-", ""))
+	response = login("marko")
+	if verified, _ := auth.VerifyTwoFactor(ctx, response.Token, recovery, ""); !verified.Success {
+		t.Fatalf("Rezervni kod mora proći: %+v", verified)
+	}
+	response = login("marko")
+	if verified, _ := auth.VerifyTwoFactor(ctx, response.Token, setup.RezervniKodovi[0], ""); verified.Success {
+		t.Errorf("Iskorišćen rezervni kod ne sme proći")
+	}
+	status, err := auth.GetTwoFactorStatus(ctx, user.KorisnikID)
+	if err != nil || !status.Ukljucen || status.Obavezan || status.PreostaloKodova != 9 {
+		t.Errorf("Neispravno stanje drugog faktora: %+v, %v", status, err)
+	}
+
+	// Administrator mora podesiti drugi faktor pre obične sesije
+	response = login("admin")
+	if response.Message != services.TwoFactorSetupRequired {
+		t.Fatalf("Očekivana poruka %s, dobijeno %+v", services.TwoFactorSetupRequired, response)
+	}
+	adminEnrollment, err := auth.EnrollTwoFactor(ctx, response.Token)
+	if err != nil {
+		t.Fatalf("Greška pri podešavanju: %v", err)
+	}
+	code, _ = services.TOTPCode(adminEnrollment.Tajna, now)
+	setup, err = auth.ConfirmTwoFactor(ctx, response.Token, code)
+	if err != nil || setup.Prijava == nil || setup.Prijava.Token == "" {
+		t.Fatalf("Potvrda mora dati običnu sesiju: %+v, %v", setup, err)
+	}
+	if _, err := auth.Authenticate(ctx, setup.Prijava.Token); err != nil {
+		t.Errorf("Sesija posle podešavanja mora važiti: %v", err)
+	}
+
+	// Uklanjanje drugog faktora je administratorska operacija
+	if err := auth.ResetTwoFactor(userCtx, user.KorisnikID); !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("Istraživač ne sme uklanjati drugi faktor, dobijeno %v", err)
+	}
+	if err := auth.ResetTwoFactor(adminCtx, user.KorisnikID); err != nil {
+		t.Fatalf("Greška pri uklanjanju: %v", err)
+	}
+	if _, err := auth.Authenticate(ctx, verified.Token); err == nil {
+		t.Errorf("Uklanjanje drugog faktora mora završiti sesije korisnika")
+	}
+	if response := login("marko"); response.Message == services.TwoFactorRequired {
+		t.Errorf("Posle uklanjanja prijava ne sme tražiti kod: %+v", response)
+	}
+
+	logs, _ := stores.Analytics.GetActivityLogs(ctx, -1)
+	counts := map[string]int{}
+	for _, entry := range logs {
+		counts[entry.TipAktivnosti]++
+	}
+	want := map[string]int{
+		services.ActivityTwoFactorEnabled: 2,
+		services.ActivityTwoFactorFailed:  2,
+		services.ActivityRecoveryCodeUsed: 1,
+		services.ActivityTwoFactorReset:   1,
+	}
+	for activity, n := range want {
+		if counts[activity] != n {
+			t.Errorf("Očekivano %d zapisa %s, dobijeno %d (%v)", n, activity, counts[activity], counts)
+		}
+	}
+}
+
+// Test pristupnih tokena: rok važenja, dozvole uloge i ko sme da ih opozove
+func TestAccessTokens(t *testing.T) {
+	stores := memory.NewStores()
+	ctx := context.Background()
+	first, second := &models.Project{NazivProjekta: "Prvi"}, &models.Project{NazivProjekta: "Drugi"}
+	for _, project := range []*models.Project{first, second} {
+		if err := stores.Projects.Create(ctx, project, nil); err != nil {
+			t.Fatalf("Greška pri kreiranju projekta: %v", err)
+		}
+	}
+	lab := &models.Role{NazivUloge: "Laborant", Dozvole: []models.RoleGrant{
+		{Dozvola: string(services.PermTaskCreate), ProjekatID: &first.ProjekatID},
+	}}
+	if err := stores.Roles.Create(ctx, lab); err != nil {
+		t.Fatalf("Greška pri kreiranju uloge: %v", err)
+	}
+
+	cfg := config.Default().Auth
+	auth := newTestAuthService(t, stores, cfg)
+	authz := newTestAuthorizer(t, stores)
+
+	now := time.Now()
+	auth.SetClock(func() time.Time { return now })
+
+	user, userCtx := newMemoryUser(t, stores, "marko", 2)
+	_, otherCtx := newMemoryUser(t, stores, "ana", 3)
+	_, adminCtx := newMemoryUser(t, stores, "admin", 1)
+
+	tooLate := now.Add(cfg.AccessTokenMaxTTL + time.Hour)
+	for _, req := range []services.AccessTokenRequest{
+		{Naziv: "", Opsezi: []services.Permission{services.PermTaskView}},
+		{Naziv: "bez opsega"},
+		{Naziv: "nepoznat", Opsezi: []services.Permission{"task.fly"}},
+		{Naziv: "predugo", Opsezi: []services.Permission{services.PermTaskView}, Istice: &tooLate},
+	} {
+		if _, err := auth.CreateAccessToken(userCtx, req); !errors.Is(err, services.ErrInvalidInput) {
+			t.Errorf("Zahtev %+v mora biti odbijen: %v", req, err)
+		}
+	}
+
+	expires := now.Add(24 * time.Hour)
+	created, err := auth.CreateAccessToken(userCtx, services.AccessTokenRequest{
+		Naziv: "skripta", Opsezi: []services.Permission{services.PermProjectCreate, services.PermTaskView, services.PermTaskView}, Istice: &expires,
+	})
+	if err != nil {
+		t.Fatalf("Greška pri kreiranju tokena: %v", err)
+	}
+	if len(created.Podaci.Opsezi) != 2 || !created.Podaci.Istice.Equal(expires) {
+		t.Errorf("Neispravan token: %+v", created.Podaci)
+	}
+
+	principal, err := auth.Authenticate(ctx, created.Token)
+	if err != nil || principal.User.KorisnikID != user.KorisnikID || principal.Token == nil {
+		t.Fatalf("Token mora važiti: %+v, %v", principal, err)
+	}
+	tokenCtx := services.WithPrincipal(ctx, principal)
+	if _, err := authz.Require(tokenCtx, services.PermProjectCreate); err != nil {
+		t.Errorf("Opseg tokena mora važiti: %v", err)
+	}
+	if _, err := authz.Require(tokenCtx, services.PermProjectUpdate); !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("Dozvola uloge van opsega ne sme važiti: %v", err)
+	}
+
+	// Token ne daje više od trenutne uloge korisnika
+	user.UlogaID = 3
+	stores.Users.Update(ctx, user)
+	principal, _ = auth.Authenticate(ctx, created.Token)
+	if _, err := authz.Require(services.WithPrincipal(ctx, principal), services.PermProjectCreate); !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("Opseg koji uloga više nema ne sme važiti: %v", err)
+	}
+
+	// Dozvola uloge ograničena na projekat može biti opseg, ali važi samo u tom projektu
+	_, labCtx := newMemoryUser(t, stores, "laborant", lab.UlogaID)
+	if _, err := auth.CreateAccessToken(labCtx, services.AccessTokenRequest{Naziv: "van uloge", Opsezi: []services.Permission{services.PermTaskDelete}}); !errors.Is(err, services.ErrInvalidInput) {
+		t.Errorf("Opseg koji uloga nigde nema mora biti odbijen: %v", err)
+	}
+	labToken, err := auth.CreateAccessToken(labCtx, services.AccessTokenRequest{Naziv: "uzorci", Opsezi: []services.Permission{services.PermTaskCreate}})
+	if err != nil {
+		t.Fatalf("Opseg ograničen na projekat mora biti prihvaćen: %v", err)
+	}
+	principal, _ = auth.Authenticate(ctx, labToken.Token)
+	labTokenCtx := services.WithPrincipal(ctx, principal)
+	if _, err := authz.RequireInProject(labTokenCtx, services.PermTaskCreate, first.ProjekatID); err != nil {
+		t.Errorf("Opseg mora važiti u projektu uloge: %v", err)
+	}
+	if _, err := authz.RequireInProject(labTokenCtx, services.PermTaskCreate, second.ProjekatID); !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("Opseg ne sme važiti van projekta uloge: %v", err)
+	}
+
+	// Tuđi token opoziva samo administrator
+	if _, err := auth.ListAccessTokens(otherCtx, user.KorisnikID); !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("Tuđi tokeni ne smeju biti vidljivi: %v", err)
+	}
+	if err := auth.RevokeAccessToken(otherCtx, created.Podaci.TokenID); !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("Tuđi token ne sme biti opozvan: %v", err)
+	}
+	if tokens, err := auth.ListAccessTokens(adminCtx, user.KorisnikID); err != nil || len(tokens) != 1 {
+		t.Errorf("Administrator mora videti tokene: %v, %v", tokens, err)
+	}
+
+	// Neaktivan nalog i istekao token se odbijaju
+	user.Status = "neaktivan"
+	stores.Users.Update(ctx, user)
+	if _, err := auth.Authenticate(ctx, created.Token); !errors.Is(err, services.ErrNoSession) {
+		t.Errorf("Token neaktivnog naloga mora biti odbijen: %v", err)
+	}
+	user.Status = "aktivan"
+	stores.Users.Update(ctx, user)
+	now = now.Add(25 * time.Hour)
+	if _, err := auth.Authenticate(ctx, created.Token); !errors.Is(err, services.ErrTokenExpired) {
+		t.Errorf("Istekao token mora biti odbijen: %v", err)
+	}
+
+	if err := auth.RevokeAccessToken(adminCtx, created.Podaci.TokenID); err != nil {
+		t.Fatalf("Administrator mora moći da opozove token: %v", err)
+	}
+	if _, err := auth.Authenticate(ctx, created.Token); !errors.Is(err, services.ErrNoSession) {
+		t.Errorf("Opozvan token mora biti odbijen: %v", err)
+	}
+	if _, err := auth.Authenticate(ctx, services.AccessTokenPrefix+"nepostojeci"); !errors.Is(err, services.ErrNoSession) {
+		t.Errorf("Nepoznat token mora biti odbijen: %v", err)
+	}
+}
+
+// Test primopredaje: brisanje korisnika sa poslom se odbija, primopredaja
+// deaktivira nalog, završava sesije i prenosi posao, a zatim brisanje uspeva
+func TestUserOffboarding(t *testing.T) {
+	stores := memory.NewStores()
+	cfg := config.Default().Auth
+	cfg.LoginDelay = 0
+	cfg.TwoFactorRoles = nil
+	svc := services.New(&config.Config{Auth: cfg}, stores, services.NewSessionManager(time.Minute, time.Hour), newTestAuthorizer(t, stores))
+	ctx := context.Background()
+
+	admin, adminCtx := newMemoryUser(t, stores, "admin", 1)
+	leaving, _ := newMemoryUser(t, stores, "odlazi", 2)
+	leader, _ := newMemoryUser(t, stores, "rukovodilac", 2)
+	_, researcherCtx := newMemoryUser(t, stores, "istrazivac", 3)
+	inactive, _ := newMemoryUser(t, stores, "neaktivan", 3)
+	inactive.Status = "neaktivan"
+	stores.Users.Update(ctx, inactive)
+
+	project := &models.Project{NazivProjekta: "Projekat", RukovodilaID: &leaving.KorisnikID}
+	if err := stores.Projects.Create(ctx, project, nil); err != nil {
+		t.Fatalf("Greška pri kreiranju projekta: %v", err)
+	}
+	task := &models.Task{ProjekatID: project.ProjekatID, FazaID: 1, NazivZadatka: "Analiza", DodjeljenKorisnikuID: &leaving.KorisnikID}
+	if err := stores.Tasks.Create(ctx, task); err != nil {
+		t.Fatalf("Greška pri kreiranju zadatka: %v", err)
+	}
+
+	hash, _ := svc.Auth.HashPassword("plavi-kamen-9")
+	stores.Users.UpdatePassword(ctx, leaving.KorisnikID, hash, false)
+	response, _ := svc.Auth.Login(ctx, services.LoginRequest{Username: "odlazi", Password: "plavi-kamen-9"})
+	if !response.Success {
+		t.Fatalf("Prijava nije uspela: %+v", response)
+	}
+
+	// Korisnik sa projektom i zadatkom se ne briše
+	err := svc.Users.DeleteUser(adminCtx, leaving.KorisnikID)
+	if !errors.Is(err, repositories.ErrConflict) || !strings.Contains(err.Error(), "Projekti (1)") {
+		t.Fatalf("Brisanje korisnika sa poslom mora biti odbijeno: %v", err)
+	}
+
+	handover := models.Handover{RukovodilacID: &leader.KorisnikID, IzvrsilacID: &leader.KorisnikID}
+	if _, err := svc.Auth.OffboardUser(researcherCtx, leaving.KorisnikID, handover); !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("Istraživač ne sme predati tuđi posao: %v", err)
+	}
+	unknown := 999
+	for _, invalid := range []models.Handover{
+		{RukovodilacID: &leaving.KorisnikID},
+		{IzvrsilacID: &inactive.KorisnikID},
+		{VlasnikID: &unknown},
+	} {
+		if _, err := svc.Auth.OffboardUser(adminCtx, leaving.KorisnikID, invalid); !errors.Is(err, services.ErrInvalidInput) {
+			t.Errorf("Primopredaja %+v mora biti odbijena: %v", invalid, err)
+		}
+	}
+	if _, err := svc.Auth.OffboardUser(adminCtx, admin.KorisnikID, handover); !errors.Is(err, services.ErrInvalidInput) {
+		t.Errorf("Administrator ne sme predati sopstveni nalog: %v", err)
+	}
+	if _, err := svc.Auth.Authenticate(ctx, response.Token); err != nil {
+		t.Fatalf("Odbijena primopredaja ne sme završiti sesiju: %v", err)
+	}
+
+	report, err := svc.Auth.OffboardUser(adminCtx, leaving.KorisnikID, handover)
+	if err != nil {
+		t.Fatalf("Greška pri primopredaji: %v", err)
+	}
+	if len(report.Projekti) != 1 || len(report.Zadaci) != 1 || report.ZavrseneSesije != 1 {
+		t.Errorf("Neispravan izveštaj primopredaje: %+v", report)
+	}
+	if _, err := svc.Auth.Authenticate(ctx, response.Token); err == nil {
+		t.Errorf("Sesija deaktiviranog korisnika mora biti završena")
+	}
+	if response, _ := svc.Auth.Login(ctx, services.LoginRequest{Username: "odlazi", Password: "plavi-kamen-9"}); response.Success {
+		t.Errorf("Deaktiviran korisnik ne sme moći da se prijavi")
+	}
+
+	logs, _ := stores.Analytics.GetActivityLogs(ctx, -1)
+	found := false
+	for _, entry := range logs {
+		if entry.TipAktivnosti == services.ActivityUserOffboarded && entry.CiljaniID != nil && *entry.CiljaniID == leaving.KorisnikID {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Primopredaja mora biti zabeležena u dnevniku aktivnosti")
+	}
+
+	// Bez preostalih referenci korisnik može da se obriše
+	if err := svc.Users.DeleteUser(adminCtx, leaving.KorisnikID); err != nil {
+		t.Errorf("Korisnik bez referenci mora moći da se obriše: %v", err)
+	}
+}
+
+// Izmena korisnika zadržava polja koja zahtev ne navodi, odbija zauzeto
+// korisničko ime i email, deaktivacijom završava sesije i beleži se u dnevniku
+func TestUpdateUser(t *testing.T) {
+	stores := memory.NewStores()
+	cfg := &config.Config{Auth: config.Default().Auth}
+	cfg.Auth.LoginDelay = 0
+	cfg.Auth.TwoFactorRoles = nil
+	svc := services.New(cfg, stores, services.NewSessionManager(time.Hour, 12*time.Hour), newTestAuthorizer(t, stores))
+	ctx := context.Background()
+
+	_, adminCtx := newMemoryUser(t, stores, "admin", 1)
+	newMemoryUser(t, stores, "marko", 3)
+	user, _ := newMemoryUser(t, stores, "jelena", 3)
+	first := "Jelena"
+	stores.Users.Update(ctx, &models.User{KorisnikID: user.KorisnikID, KorisnickoIme: "jelena", Email: user.Email, Ime: &first, UlogaID: 3, Status: "aktivan"})
+	hash, _ := svc.Auth.HashPassword("plavi-kamen-9")
+	stores.Users.UpdatePassword(ctx, user.KorisnikID, hash, false)
+
+	if err := svc.Users.UpdateUser(adminCtx, user.KorisnikID, models.Korisnici{Email: "jelena.novi@test.local"}); err != nil {
+		t.Fatalf("Greška pri izmeni korisnika: %v", err)
+	}
+	stored, _ := stores.Users.GetByID(ctx, user.KorisnikID)
+	if stored.Email != "jelena.novi@test.local" || stored.KorisnickoIme != "jelena" || stored.Ime == nil || *stored.Ime != "Jelena" || stored.Status != "aktivan" {
+		t.Errorf("Delimična izmena mora zadržati ostala polja: %+v", stored)
+	}
+
+	for name, change := range map[string]models.Korisnici{
+		"zauzeto korisničko ime": {KorisnickoIme: "MARKO"},
+		"zauzet email":           {Email: "marko@test.local"},
+	} {
+		if err := svc.Users.UpdateUser(adminCtx, user.KorisnikID, change); !errors.Is(err, repositories.ErrConflict) {
+			t.Errorf("%s mora vratiti ErrConflict, dobijeno %v", name, err)
+		}
+	}
+	for name, change := range map[string]models.Korisnici{
+		"neispravan email": {Email: "nije-adresa"},
+		"nepoznat status":  {Status: "obrisan"},
+		"razmak u imenu":   {KorisnickoIme: "jelena p"},
+	} {
+		if err := svc.Users.UpdateUser(adminCtx, user.KorisnikID, change); !errors.Is(err, services.ErrInvalidInput) {
+			t.Errorf("%s mora vratiti ErrInvalidInput, dobijeno %v", name, err)
+		}
+	}
+
+	response, err := svc.Auth.Login(ctx, services.LoginRequest{Username: "jelena", Password: "plavi-kamen-9"})
+	if err != nil || !response.Success {
+		t.Fatalf("Prijava nije uspela: %+v, %v", response, err)
+	}
+	if err := svc.Users.UpdateUser(adminCtx, user.KorisnikID, models.Korisnici{Status: "neaktivan"}); err != nil {
+		t.Fatalf("Greška pri deaktivaciji: %v", err)
+	}
+	if _, err := svc.Auth.Authenticate(ctx, response.Token); !errors.Is(err, services.ErrNoSession) {
+		t.Errorf("Deaktivacija mora završiti sesije korisnika, dobijeno %v", err)
+	}
+
+	logs, _ := stores.Analytics.GetActivityLogs(ctx, -1)
+	updates := 0
+	for _, entry := range logs {
+		if entry.TipAktivnosti == services.ActivityUserUpdated {
+			updates++
+		}
+	}
+	if updates != 2 {
+		t.Errorf("Svaka izmena korisnika mora biti u dnevniku, dobijeno %d zapisa", updates)
+	}
+}
+
+// Test uvoza korisnika iz CSV-a: izveštaj probe, sve ili ništa, uvoz po
+// redovima i izvoz koji se može ponovo uvesti
+func TestUserImportExport(t *testing.T) {
+	stores := memory.NewStores()
+	cfg := config.Default().Auth
+	svc := services.New(&config.Config{Auth: cfg}, stores, services.NewSessionManager(time.Minute, time.Hour), newTestAuthorizer(t, stores))
+	ctx := context.Background()
+
+	_, adminCtx := newMemoryUser(t, stores, "admin", 1)
+	_, researcherCtx := newMemoryUser(t, stores, "postoji", 3)
+
+	// Zarez kao separator, greške u redovima 3, 4, 5 i 6
+	file := "korisnicko_ime,email,ime,prezime,uloga\n" +
+		"ana,ana@institut.rs,Ana,Jović,Istrazivac\n" +
+		"POSTOJI,nova@institut.rs,,,istrazivac\n" +
+		"ivan,nije-adresa,,,3\n" +
+		"ANA,ana2@institut.rs,,,Dekan\n" +
+		"marko,,,,\n" +
+		"jelena,jelena@institut.rs,Jelena,,2\n"
+	importFile := func(opts services.UserImportOptions) *services.UserImportReport {
+		t.Helper()
+		report, err := svc.Auth.ImportUsers(adminCtx, strings.NewReader(file), opts)
+		if err != nil {
+			t.Fatalf("Greška pri uvozu: %v", err)
+		}
+		return report
+	}
+
+	if _, err := svc.Auth.ImportUsers(researcherCtx, strings.NewReader(file), services.UserImportOptions{DryRun: true}); !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("Istraživač ne sme uvoziti korisnike: %v", err)
+	}
+
+	report := importFile(services.UserImportOptions{DryRun: true})
+	if report.Ispravni != 2 || report.Kreirani != 0 || len(report.Redovi) != 6 {
+		t.Fatalf("Neispravan izveštaj probe: %+v", report)
+	}
+	expected := map[int]string{3: "već postoji", 4: "neispravna email adresa", 5: "se ponavlja u redu 2", 6: "email je obavezan"}
+	for _, row := range report.Redovi {
+		want, invalid := expected[row.Red]
+		if invalid != (len(row.Greske) > 0) || (invalid && !strings.Contains(strings.Join(row.Greske, "; "), want)) {
+			t.Errorf("Red %d: očekivano %q, dobijeno %v", row.Red, want, row.Greske)
+		}
+	}
+	if row := report.Redovi[3]; len(row.Greske) != 2 || !strings.Contains(row.Greske[1], "nepoznata uloga") {
+		t.Errorf("Red 5 mora prijaviti i nepoznatu ulogu: %v", row.Greske)
+	}
+
+	// Sve ili ništa: greške u fajlu sprečavaju kreiranje
+	if report := importFile(services.UserImportOptions{}); report.Kreirani != 0 {
+		t.Errorf("Fajl sa greškama ne sme kreirati korisnike: %+v", report)
+	}
+	if _, err := stores.Users.GetByUsername(ctx, "ana"); err == nil {
+		t.Fatalf("Korisnik ne sme nastati iz odbijenog fajla")
+	}
+
+	// Po redovima: ispravni redovi dobijaju aktivacione kodove
+	report = importFile(services.UserImportOptions{PerRow: true})
+	if report.Kreirani != 2 || report.Redovi[0].Aktivacija == nil || report.Redovi[5].Aktivacija == nil || report.Redovi[1].Aktivacija != nil {
+		t.Fatalf("Neispravan uvoz po redovima: %+v", report)
+	}
+	ana, err := stores.Users.GetByUsername(ctx, "ana")
+	if err != nil || ana.UlogaID != 3 || ana.Ime == nil || *ana.Ime != "Ana" || !ana.MoraPromenitiLozinku {
+		t.Errorf("Uvezen korisnik nije ispravan: %+v, %v", ana, err)
+	}
+	response, _ := svc.Auth.Login(ctx, services.LoginRequest{Username: "ana", Password: report.Redovi[0].Aktivacija.Kod})
+	if !response.Success || response.Message != services.FirstTimeLogin {
+		t.Errorf("Uvezen korisnik mora moći da se prijavi aktivacionim kodom: %+v", response)
+	}
+
+	// Tačka-zarez kao separator i neispravna zaglavlja
+	if report, err := svc.Auth.ImportUsers(adminCtx, strings.NewReader("\ufeffkorisnicko_ime;email;uloga\nzoran;zoran@institut.rs;Administrator\n"),
+		services.UserImportOptions{DryRun: true}); err != nil || report.Ispravni != 1 {
+		t.Errorf("Fajl sa tačka-zarezom mora biti prihvaćen: %+v, %v", report, err)
+	}
+	for _, bad := range []string{"", "korisnicko_ime,email\nx,x@institut.rs\n", "korisnicko_ime,email,uloga,plata\n", "korisnicko_ime,email,uloga\n"} {
+		if _, err := svc.Auth.ImportUsers(adminCtx, strings.NewReader(bad), services.UserImportOptions{DryRun: true}); !errors.Is(err, services.ErrInvalidInput) {
+			t.Errorf("Fajl %q mora biti odbijen: %v", bad, err)
+		}
+	}
+
+	// Izvoz sadrži uloge i može se ponovo uvesti kao proba
+	jelena, _ := stores.Users.GetByUsername(ctx, "jelena")
+	formula := `=HYPERLINK("x")`
+	jelena.Prezime = &formula
+	stores.Users.Update(ctx, jelena)
+	exported, err := svc.Users.ExportUsers(adminCtx)
+	if err != nil {
+		t.Fatalf("Greška pri izvozu: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(exported)), "\n")
+	if len(lines) != 5 || !strings.HasPrefix(lines[0], "korisnicko_ime,email,ime,prezime,uloga,status") ||
+		!strings.Contains(string(exported), "ana,ana@institut.rs,Ana,Jović,Istrazivac,aktivan") {
+		t.Errorf("Neispravan izvoz:\n%s", exported)
+	}
+	if !strings.Contains(string(exported), `'=HYPERLINK`) {
+		t.Errorf("Formula mora biti neutralisana u izvozu:\n%s", exported)
+	}
+	report, err = svc.Auth.ImportUsers(adminCtx, bytes.NewReader(exported), services.UserImportOptions{DryRun: true})
+	if err != nil || report.Ispravni != 0 || len(report.Redovi) != 4 {
+		t.Errorf("Izvezeni korisnici već postoje: %+v, %v", report, err)
+	}
+}
+
+// testMailer pamti poslate poruke umesto da ih šalje
+type testMailer struct {
+	sent []testMail
+}
+
+type testMail struct {
+	to, subject, body string
+}
+
+func (m *testMailer) Send(ctx context.Context, to, subject, body string) error {
+	m.sent = append(m.sent, testMail{to: to, subject: subject, body: body})
+	return nil
+}
+
+// code vraća kod za potvrdu iz poslednje poslate poruke
+func (m *testMailer) code(t *testing.T) string {
+	t.Helper()
+
+	if len(m.sent) == 0 {
+		t.Fatalf("Nijedna poruka nije poslata")
+	}
+	for _, line := range strings.Split(m.sent[len(m.sent)-1].body, "\n") {
+		if code, ok := strings.CutPrefix(line, "Kod za potvrdu: "); ok {
+			return code
+		}
+	}
+	t.Fatalf("Poruka nema kod za potvrdu:\n%s", m.sent[len(m.sent)-1].body)
+	return ""
+}
+
+// Test samostalnog uređivanja profila: ime, lozinka uz trenutnu lozinku,
+// email adresa uz kod poslat na novu adresu i podešavanja
+func TestProfileSelfService(t *testing.T) {
+	stores := memory.NewStores()
+	cfg := config.Default().Auth
+	cfg.LoginDelay = 0
+	svc := services.New(&config.Config{Auth: cfg}, stores, services.NewSessionManager(time.Minute, time.Hour), newTestAuthorizer(t, stores))
+	mailer := &testMailer{}
+	svc.Profile.SetMailer(mailer)
+	ctx := context.Background()
+
+	newMemoryUser(t, stores, "admin", 1)
+	user, userCtx := newMemoryUser(t, stores, "jelena", 3)
+	hash, _ := svc.Auth.HashPassword("Stara-Lozinka-1")
+	stores.Users.UpdatePassword(ctx, user.KorisnikID, hash, false)
+
+	updated, err := svc.Profile.UpdateProfile(userCtx, services.ProfileUpdate{Ime: " Jelena ", Prezime: "Jović"})
+	if err != nil || *updated.Ime != "Jelena" || *updated.Prezime != "Jović" {
+		t.Fatalf("Izmena imena: %+v, %v", updated, err)
+	}
+	tokenCtx := services.WithPrincipal(ctx, &services.Principal{User: user, Token: &models.AccessToken{Opsezi: []string{"task.view"}}})
+	if _, err := svc.Profile.UpdateProfile(tokenCtx, services.ProfileUpdate{Ime: "Neko"}); !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("Pristupni token ne sme menjati profil, dobijeno: %v", err)
+	}
+
+	// Lozinka se menja samo uz ispravnu trenutnu lozinku
+	if err := svc.Profile.ChangePassword(userCtx, "pogresna", "Nova-Lozinka-2", "", ""); !errors.Is(err, services.ErrInvalidInput) {
+		t.Errorf("Pogrešna trenutna lozinka mora biti odbijena, dobijeno: %v", err)
+	}
+	if stored, _ := stores.Users.GetByID(ctx, user.KorisnikID); stored.NeuspesnePrijave != 1 {
+		t.Errorf("Pogrešna trenutna lozinka mora se brojati kao neuspešna prijava, dobijeno %d", stored.NeuspesnePrijave)
+	}
+	if err := svc.Profile.ChangePassword(userCtx, "Stara-Lozinka-1", "Nova-Lozinka-2", "", ""); err != nil {
+		t.Fatalf("Greška pri promeni lozinke: %v", err)
+	}
+	if response, _ := svc.Auth.Login(ctx, services.LoginRequest{Username: "jelena", Password: "Nova-Lozinka-2"}); !response.Success {
+		t.Errorf("Prijava novom lozinkom nije uspela: %+v", response)
+	}
+
+	// Email adresa se menja tek posle potvrde kodom sa nove adrese
+	if _, err := svc.Profile.RequestEmailChange(userCtx, "admin@test.local"); !errors.Is(err, repositories.ErrConflict) {
+		t.Errorf("Adresa drugog naloga mora biti odbijena, dobijeno: %v", err)
+	}
+	if _, err := svc.Profile.RequestEmailChange(userCtx, "nije adresa"); !errors.Is(err, services.ErrInvalidInput) {
+		t.Errorf("Neispravna adresa mora biti odbijena, dobijeno: %v", err)
+	}
+	if _, err := svc.Profile.RequestEmailChange(userCtx, "jelena@institut.rs"); err != nil {
+		t.Fatalf("Greška pri zahtevu za promenu adrese: %v", err)
+	}
+	if len(mailer.sent) != 1 || mailer.sent[0].to != "jelena@institut.rs" {
+		t.Fatalf("Kod mora biti poslat na novu adresu: %+v", mailer.sent)
+	}
+	code := mailer.code(t)
+	profile, err := svc.Profile.GetProfile(userCtx)
+	if err != nil || profile.Korisnik.Email != "jelena@test.local" || profile.PromenaEmaila == nil {
+		t.Fatalf("Adresa se ne menja pre potvrde: %+v, %v", profile, err)
+	}
+	if _, err := svc.Profile.ConfirmEmailChange(userCtx, "AAAA-BBBB-CCCC"); !errors.Is(err, services.ErrInvalidInput) {
+		t.Errorf("Pogrešan kod mora biti odbijen, dobijeno: %v", err)
+	}
+	if updated, err := svc.Profile.ConfirmEmailChange(userCtx, code); err != nil || updated.Email != "jelena@institut.rs" {
+		t.Fatalf("Potvrda adrese: %+v, %v", updated, err)
+	}
+	if len(mailer.sent) != 2 || mailer.sent[1].to != "jelena@test.local" {
+		t.Errorf("Stara adresa mora dobiti obaveštenje o promeni: %+v", mailer.sent)
+	}
+	if _, err := svc.Profile.ConfirmEmailChange(userCtx, code); err == nil {
+		t.Errorf("Kod važi samo jednom")
+	}
+
+	svc.Profile.SetMailer(nil)
+	if _, err := svc.Profile.RequestEmailChange(userCtx, "jelena@drugi.rs"); !errors.Is(err, services.ErrInvalidInput) {
+		t.Errorf("Bez podešene pošte adresa se ne može menjati, dobijeno: %v", err)
+	}
+
+	// Podešavanja imaju podrazumevane vrednosti dok se ne sačuvaju
+	prefs, err := svc.Profile.GetPreferences(userCtx)
+	if err != nil || prefs.Jezik != "sr" || prefs.FormatDatuma != "dd.MM.yyyy" || !prefs.ObavestenjaEmail {
+		t.Fatalf("Podrazumevana podešavanja: %+v, %v", prefs, err)
+	}
+	project := &models.Project{NazivProjekta: "Projekat"}
+	if err := stores.Projects.Create(ctx, project, nil); err != nil {
+		t.Fatalf("Greška pri kreiranju projekta: %v", err)
+	}
+	prefs.Jezik, prefs.FormatDatuma, prefs.PodrazumevaniProjekatID, prefs.ObavestenjaKomentari = "en", "yyyy-MM-dd", &project.ProjekatID, false
+	if _, err := svc.Profile.UpdatePreferences(userCtx, *prefs); err != nil {
+		t.Fatalf("Greška pri čuvanju podešavanja: %v", err)
+	}
+	if stored, _ := svc.Profile.GetPreferences(userCtx); stored.Jezik != "en" || stored.ObavestenjaKomentari || *stored.PodrazumevaniProjekatID != project.ProjekatID {
+		t.Errorf("Podešavanja nisu sačuvana: %+v", stored)
+	}
+	for _, invalid := range []models.Preferences{
+		{Jezik: "de", FormatDatuma: "dd.MM.yyyy"},
+		{Jezik: "sr", FormatDatuma: "yy"},
+		{Jezik: "sr", FormatDatuma: "dd.MM.yyyy", PodrazumevaniProjekatID: &[]int{999}[0]},
+	} {
+		if _, err := svc.Profile.UpdatePreferences(userCtx, invalid); !errors.Is(err, services.ErrInvalidInput) {
+			t.Errorf("Podešavanja %+v moraju biti odbijena, dobijeno: %v", invalid, err)
+		}
+	}
+
+	logs, _ := stores.Analytics.GetActivityLogs(ctx, -1)
+	counts := map[string]int{}
+	for _, entry := range logs {
+		counts[entry.TipAktivnosti]++
+	}
+	for activity, want := range map[string]int{
+		services.ActivityProfileUpdated:       1,
+		services.ActivityPasswordChangeFailed: 1,
+		services.ActivityPasswordChanged:      1,
+		services.ActivityEmailChangeRequested: 1,
+		services.ActivityEmailChangeFailed:    1,
+		services.ActivityEmailChanged:         1,
+	} {
+		if counts[activity] != want {
+			t.Errorf("%s: očekivano %d zapisa, dobijeno %d", activity, want, counts[activity])
+		}
+	}
+}
+
+// Test rada administratora u ime drugog korisnika
+func TestImpersonation(t *testing.T) {
+	stores := memory.NewStores()
+	sessions := services.NewSessionManager(time.Hour, 12*time.Hour)
+	svc := services.New(&config.Config{Auth: config.Default().Auth}, stores, sessions, newTestAuthorizer(t, stores))
+	ctx := context.Background()
+
+	admin, adminCtx := newMemoryUser(t, stores, "admin", 1)
+	other, _ := newMemoryUser(t, stores, "admin2", 1)
+	user, userCtx := newMemoryUser(t, stores, "jelena", 3)
+
+	for name, call := range map[string]func() error{
+		"bez dozvole":          func() error { _, err := svc.Auth.StartImpersonation(userCtx, admin.KorisnikID, "provera"); return err },
+		"drugi administrator":  func() error { _, err := svc.Auth.StartImpersonation(adminCtx, other.KorisnikID, "provera"); return err },
+		"pristupnim tokenom":   func() error { return startWithToken(svc, admin, user.KorisnikID) },
+		"bez razloga":          func() error { _, err := svc.Auth.StartImpersonation(adminCtx, user.KorisnikID, " "); return err },
+		"u svoje ime":          func() error { _, err := svc.Auth.StartImpersonation(adminCtx, admin.KorisnikID, "provera"); return err },
+		"nepostojeći korisnik": func() error { _, err := svc.Auth.StartImpersonation(adminCtx, 9999, "provera"); return err },
+	} {
+		if err := call(); err == nil {
+			t.Errorf("Rad u ime korisnika (%s) mora biti odbijen", name)
+		}
+	}
+
+	response, err := svc.Auth.StartImpersonation(adminCtx, user.KorisnikID, "ne vidi dokument 12")
+	if err != nil {
+		t.Fatalf("Greška pri početku rada u ime korisnika: %v", err)
+	}
+	if response.User.KorisnikID != user.KorisnikID || response.Zastupanje == nil || response.Zastupanje.AdministratorID != admin.KorisnikID {
+		t.Fatalf("Odgovor mora opisati rad u ime korisnika: %+v", response)
+	}
+	principal, err := svc.Auth.Authenticate(ctx, response.Token)
+	if err != nil || principal.User.KorisnikID != user.KorisnikID || principal.Impersonation == nil {
+		t.Fatalf("Token mora delovati kao korisnik uz oznaku administratora: %+v, %v", principal, err)
+	}
+	impersonatedCtx := services.WithPrincipal(ctx, principal)
+
+	// Korisnik se vidi onako kako ga vidi on sam, ali bez izmena bezbednosti
+	if _, err := svc.Profile.GetProfile(impersonatedCtx); err != nil {
+		t.Errorf("Čitanje profila mora biti dozvoljeno: %v", err)
+	}
+	for name, call := range map[string]func() error{
+		"promena lozinke": func() error {
+			return svc.Profile.ChangePassword(impersonatedCtx, "x", "Nova-Lozinka-2", "", response.Token)
+		},
+		"postavljanje lozinke": func() error {
+			return svc.Auth.ChangePassword(impersonatedCtx, user.KorisnikID, "Nova-Lozinka-2", response.Token)
+		},
+		"promena adrese": func() error {
+			_, err := svc.Profile.RequestEmailChange(impersonatedCtx, "nova@test.local")
+			return err
+		},
+		"pristupni token": func() error {
+			_, err := svc.Auth.CreateAccessToken(impersonatedCtx, services.AccessTokenRequest{Naziv: "skripta", Opsezi: []services.Permission{services.PermTaskView}})
+			return err
+		},
+		"novi rad u ime drugog": func() error {
+			_, err := svc.Auth.StartImpersonation(impersonatedCtx, other.KorisnikID, "provera")
+			return err
+		},
+		"podešavanje drugog faktora": func() error {
+			_, err := svc.Auth.EnrollTwoFactor(ctx, response.Token)
+			return err
+		},
+		"uključivanje drugog faktora": func() error {
+			_, err := svc.Auth.ConfirmTwoFactor(ctx, response.Token, "123456")
+			return err
+		},
+	} {
+		if err := call(); !errors.Is(err, services.ErrForbidden) {
+			t.Errorf("%s mora biti zabranjena u ime drugog korisnika, dobijeno: %v", name, err)
+		}
+	}
+	for _, perm := range svc.Authz.PermissionsOf(principal) {
+		if perm == services.PermUserManage || perm == services.PermSessionManage {
+			t.Errorf("Dozvola %s ne sme važiti u ime drugog korisnika", perm)
+		}
+	}
+
+	found := false
+	for _, session := range sessions.List() {
+		if session.Zastupanje != nil && session.KorisnikID == user.KorisnikID {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Lista sesija mora označiti rad u ime korisnika")
+	}
+
+	// Svaka radnja se beleži pod oba korisnika
+	svc.Auth.RecordImpersonatedCall(impersonatedCtx, "GetDocument")
+	svc.Auth.RecordImpersonatedCall(adminCtx, "GetDocument")
+	if err := svc.Auth.StopImpersonation(impersonatedCtx, response.Token); err != nil {
+		t.Fatalf("Greška pri završetku rada u ime korisnika: %v", err)
+	}
+	if _, err := svc.Auth.Authenticate(ctx, response.Token); !errors.Is(err, services.ErrNoSession) {
+		t.Errorf("Posle završetka token ne sme važiti, dobijeno: %v", err)
+	}
+
+	logs, _ := stores.Analytics.GetActivityLogs(ctx, -1)
+	counts := map[string]int{}
+	for _, entry := range logs {
+		counts[entry.TipAktivnosti]++
+		if entry.TipAktivnosti == services.ActivityImpersonatedCall &&
+			(*entry.KorisnikID != user.KorisnikID || entry.StvarniKorisnikID == nil || *entry.StvarniKorisnikID != admin.KorisnikID || entry.ImeStvarnogKorisnika != "admin") {
+			t.Errorf("Radnja mora biti zapisana pod korisnikom i administratorom: %+v", entry)
+		}
+	}
+	for activity, want := range map[string]int{
+		services.ActivityImpersonationStarted: 1,
+		services.ActivityImpersonatedCall:     3, // GetDocument i dva odbijena podešavanja drugog faktora
+		services.ActivityImpersonationEnded:   1,
+	} {
+		if counts[activity] != want {
+			t.Errorf("%s: očekivano %d zapisa, dobijeno %d", activity, want, counts[activity])
+		}
+	}
+
+	// Rad u ime korisnika vremenski je ograničen
+	response, err = svc.Auth.StartImpersonation(adminCtx, user.KorisnikID, "ne vidi dokument 12")
+	if err != nil {
+		t.Fatalf("Greška pri ponovnom radu u ime korisnika: %v", err)
+	}
+	sessions.SetClock(func() time.Time { return time.Now().Add(config.Default().Auth.ImpersonationTTL + time.Minute) })
+	if _, err := svc.Auth.Authenticate(ctx, response.Token); !errors.Is(err, services.ErrSessionExpired) {
+		t.Errorf("Rad u ime korisnika mora isteći posle auth.impersonation_ttl, dobijeno: %v", err)
+	}
+	sessions.SetClock(time.Now)
+
+	// Administrator koji izgubi nalog gubi i rad u ime korisnika
+	response, _ = svc.Auth.StartImpersonation(adminCtx, user.KorisnikID, "ne vidi dokument 12")
+	admin.Status = "neaktivan"
+	if err := stores.Users.Update(ctx, admin); err != nil {
+		t.Fatalf("Greška pri deaktivaciji administratora: %v", err)
+	}
+	if _, err := svc.Auth.Authenticate(ctx, response.Token); !errors.Is(err, services.ErrNoSession) {
+		t.Errorf("Rad u ime korisnika mora prestati sa nalogom administratora, dobijeno: %v", err)
+	}
+}
+
+// startWithToken pokušava rad u ime korisnika pristupnim tokenom administratora
+func startWithToken(svc *services.Services, admin *models.User, userID int) error {
+	ctx := services.WithPrincipal(context.Background(), &services.Principal{User: admin, Token: &models.AccessToken{Opsezi: []string{string(services.PermUserImpersonate)}}})
+	_, err := svc.Auth.StartImpersonation(ctx, userID, "provera")
+	return err
+}
+
+// Test otpremanja i brisanja dokumenta bez baze podataka
+// Test organizacionih jedinica: hijerarhija i vidljivost projekata i
+// statistike po jedinicama
+func TestOrganizationalUnits(t *testing.T) {
+	stores := memory.NewStores()
+	svc := services.New(&config.Config{}, stores, services.NewSessionManager(time.Hour, 12*time.Hour), newTestAuthorizer(t, stores))
+	ctx := context.Background()
+
+	admin, adminCtx := newMemoryUser(t, stores, "admin", 1)
+	head, headCtx := newMemoryUser(t, stores, "sef", 2)
+	researcher, _ := newMemoryUser(t, stores, "jelena", 3)
+	_, outsiderCtx := newMemoryUser(t, stores, "van_jedinica", 2)
+
+	institute, err := svc.Units.CreateUnit(adminCtx, services.UnitRequest{Naziv: "Institut", Tip: "institut"})
+	if err != nil {
+		t.Fatalf("Greška pri kreiranju jedinice: %v", err)
+	}
+	deptA, err := svc.Units.CreateUnit(adminCtx, services.UnitRequest{Naziv: "Odeljenje A",
+		NadredjenaJedinicaID: &institute.JedinicaID, RukovodilacID: &head.KorisnikID})
+	if err != nil {
+		t.Fatalf("Greška pri kreiranju jedinice: %v", err)
+	}
+	if deptA.Tip != "odeljenje" || deptA.RukovodilacIme != head.KorisnickoIme {
+		t.Errorf("Jedinica bez tipa je odeljenje sa imenom rukovodioca: %+v", deptA)
+	}
+	lab, err := svc.Units.CreateUnit(adminCtx, services.UnitRequest{Naziv: "Laboratorija A1", Tip: "laboratorija", NadredjenaJedinicaID: &deptA.JedinicaID})
+	if err != nil {
+		t.Fatalf("Greška pri kreiranju jedinice: %v", err)
+	}
+	deptB, err := svc.Units.CreateUnit(adminCtx, services.UnitRequest{Naziv: "Odeljenje B", NadredjenaJedinicaID: &institute.JedinicaID})
+	if err != nil {
+		t.Fatalf("Greška pri kreiranju jedinice: %v", err)
+	}
+
+	if _, err := svc.Units.CreateUnit(headCtx, services.UnitRequest{Naziv: "Nova"}); !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("Kreiranje jedinice bez unit.manage mora biti zabranjeno, dobijeno %v", err)
+	}
+	missing := 9999
+	for name, req := range map[string]services.UnitRequest{
+		"dupli naziv":             {Naziv: "odeljenje a"},
+		"nepoznat tip":            {Naziv: "Sektor", Tip: "sektor"},
+		"bez naziva":              {Naziv: " "},
+		"nepostojeća nadređena":   {Naziv: "Nova", NadredjenaJedinicaID: &missing},
+		"nepostojeći rukovodilac": {Naziv: "Nova", RukovodilacID: &missing},
+	} {
+		if _, err := svc.Units.CreateUnit(adminCtx, req); err == nil {
+			t.Errorf("Jedinica (%s) mora biti odbijena", name)
+		}
+	}
+	if _, err := svc.Units.UpdateUnit(adminCtx, deptA.JedinicaID, services.UnitRequest{Naziv: "Odeljenje A",
+		NadredjenaJedinicaID: &lab.JedinicaID}); !errors.Is(err, services.ErrInvalidInput) {
+		t.Errorf("Jedinica ne sme postati podređena svojoj podjedinici, dobijeno %v", err)
+	}
+
+	create := func(name string, unitID *int) *models.Project {
+		project := &models.Project{NazivProjekta: name, Status: "Aktivan", RukovodilaID: &admin.KorisnikID, JedinicaID: unitID}
+		if err := stores.Projects.Create(ctx, project, nil); err != nil {
+			t.Fatalf("Greška pri kreiranju projekta: %v", err)
+		}
+		return project
+	}
+	inLab := create("U laboratoriji", &lab.JedinicaID)
+	inB := create("U odeljenju B", &deptB.JedinicaID)
+	shared := create("Bez jedinice", nil)
+
+	names := func(callCtx context.Context) []string {
+		t.Helper()
+		projects, err := svc.Projects.GetAllProjects(callCtx)
+		if err != nil {
+			t.Fatalf("Greška pri čitanju projekata: %v", err)
+		}
+		names := []string{}
+		for _, project := range projects {
+			names = append(names, project.NazivProjekta)
+		}
+		sort.Strings(names)
+		return names
+	}
+	if got := names(headCtx); strings.Join(got, ",") != "Bez jedinice,U laboratoriji" {
+		t.Errorf("Rukovodilac odeljenja vidi projekte svog podstabla i one bez jedinice, dobijeno %v", got)
+	}
+	if got := names(outsiderCtx); len(got) != 3 {
+		t.Errorf("Korisnik van hijerarhije vidi sve projekte, dobijeno %v", got)
+	}
+	if _, err := svc.Projects.GetProjectByID(headCtx, inB.ProjekatID); !errors.Is(err, repositories.ErrNotFound) {
+		t.Errorf("Projekat van podstabla ne sme biti vidljiv, dobijeno %v", err)
+	}
+	writes := map[string]func() error{
+		"UpdateProject":       func() error { return svc.Projects.UpdateProject(headCtx, inB.ProjekatID, *inB) },
+		"SetProjectWorkflow":  func() error { return svc.Projects.SetProjectWorkflow(headCtx, inB.ProjekatID, nil) },
+		"AddProjectMember":    func() error { return svc.Projects.AddProjectMember(headCtx, inB.ProjekatID, head.KorisnikID) },
+		"RemoveProjectMember": func() error { return svc.Projects.RemoveProjectMember(headCtx, inB.ProjekatID, admin.KorisnikID) },
+		"DeleteProject":       func() error { return svc.Projects.DeleteProject(headCtx, inB.ProjekatID) },
+	}
+	for name, write := range writes {
+		if err := write(); !errors.Is(err, repositories.ErrNotFound) {
+			t.Errorf("%s nad projektom van podstabla mora vratiti ErrNotFound, dobijeno %v", name, err)
+		}
+	}
+	if _, err := stores.Projects.GetByID(ctx, inB.ProjekatID); err != nil {
+		t.Errorf("Projekat van podstabla ne sme biti obrisan: %v", err)
+	}
+
+	// A member of a unit sees its projects and those of the projects the member works on
+	if err := svc.Units.SetUserUnit(headCtx, researcher.KorisnikID, &deptB.JedinicaID); !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("Premeštanje korisnika bez unit.manage mora biti zabranjeno, dobijeno %v", err)
+	}
+	if err := svc.Units.SetUserUnit(adminCtx, researcher.KorisnikID, &deptB.JedinicaID); err != nil {
+		t.Fatalf("Greška pri premeštanju korisnika: %v", err)
+	}
+	if err := stores.Projects.AddMember(ctx, inLab.ProjekatID, researcher.KorisnikID); err != nil {
+		t.Fatalf("Greška pri dodavanju člana: %v", err)
+	}
+	placed, _ := stores.Users.GetByID(ctx, researcher.KorisnikID)
+	researcherCtx := services.WithPrincipal(ctx, &services.Principal{User: placed})
+	if got := names(researcherCtx); len(got) != 3 {
+		t.Errorf("Član jedinice vidi njene projekte, one bez jedinice i one na kojima radi, dobijeno %v", got)
+	}
+	if members, err := svc.Units.GetUnitMembers(researcherCtx, deptB.JedinicaID); err != nil || len(members) != 1 {
+		t.Errorf("GetUnitMembers vratio %+v (%v)", members, err)
+	}
+
+	stats, err := svc.Analytics.GetDashboardStats(headCtx)
+	if err != nil || stats.AktivniProjekti != 1 {
+		t.Errorf("Statistika rukovodioca obuhvata samo podstablo: %+v (%v)", stats, err)
+	}
+	if stats, err := svc.Analytics.GetDashboardStats(adminCtx); err != nil || stats.AktivniProjekti != 3 || stats.AktivniKorisnici != 0 {
+		t.Errorf("Statistika administratora obuhvata sve: %+v (%v)", stats, err)
+	}
+
+	if err := svc.Units.SetProjectUnit(headCtx, shared.ProjekatID, &deptB.JedinicaID); !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("Projekat se ne sme premestiti u jedinicu van nadležnosti, dobijeno %v", err)
+	}
+	if err := svc.Units.SetProjectUnit(headCtx, shared.ProjekatID, &deptA.JedinicaID); err != nil {
+		t.Fatalf("Greška pri premeštanju projekta: %v", err)
+	}
+	if _, err := svc.Projects.CreateProject(headCtx, models.CreateProjectRequest{NazivProjekta: "Tuđi", JedinicaID: &deptB.JedinicaID}); !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("Projekat se ne sme kreirati u jedinici van nadležnosti, dobijeno %v", err)
+	}
+	if stats, _ := svc.Analytics.GetDashboardStats(headCtx); stats.AktivniProjekti != 2 {
+		t.Errorf("Premešten projekat mora ući u statistiku odeljenja: %+v", stats)
+	}
+
+	if err := svc.Units.DeleteUnit(adminCtx, deptA.JedinicaID); !errors.Is(err, repositories.ErrConflict) {
+		t.Errorf("Jedinica sa podjedinicama ne sme biti obrisana, dobijeno %v", err)
+	}
+	if err := svc.Units.DeleteUnit(adminCtx, lab.JedinicaID); err != nil {
+		t.Fatalf("Greška pri brisanju jedinice: %v", err)
+	}
+	if project, _ := stores.Projects.GetByID(ctx, inLab.ProjekatID); project.JedinicaID != nil {
+		t.Errorf("Projekti obrisane jedinice ostaju bez jedinice")
+	}
+
+	logs, err := stores.Analytics.GetActivityLogs(ctx, 50)
+	if err != nil {
+		t.Fatalf("Greška pri čitanju dnevnika: %v", err)
+	}
+	seen := map[string]bool{}
+	for _, entry := range logs {
+		seen[entry.TipAktivnosti] = true
+	}
+	for _, activity := range []string{services.ActivityUnitCreated, services.ActivityUnitDeleted,
+		services.ActivityUserUnitChanged, services.ActivityProjectUnitChanged} {
+		if !seen[activity] {
+			t.Errorf("Dnevnik mora sadržati %s", activity)
+		}
+	}
+}
+
+// Zadaci i dokumenti projekata van podstabla jedinice su skriveni kao i sami
+// projekti, a tuđe zadatke vidi samo ko ima user.view
+func TestUnitScopedTaskAndDocumentReads(t *testing.T) {
+	stores := memory.NewStores()
+	svc := services.New(&config.Config{}, stores, services.NewSessionManager(time.Hour, 12*time.Hour), newTestAuthorizer(t, stores))
+	ctx := context.Background()
+
+	admin, adminCtx := newMemoryUser(t, stores, "admin", 1)
+	head, headCtx := newMemoryUser(t, stores, "sef", 2)
+	researcher, researcherCtx := newMemoryUser(t, stores, "jelena", 3)
+	_, otherCtx := newMemoryUser(t, stores, "marko", 3)
+
+	deptA, err := svc.Units.CreateUnit(adminCtx, services.UnitRequest{Naziv: "Odeljenje A", RukovodilacID: &head.KorisnikID})
+	if err != nil {
+		t.Fatalf("Greška pri kreiranju jedinice: %v", err)
+	}
+	deptB, err := svc.Units.CreateUnit(adminCtx, services.UnitRequest{Naziv: "Odeljenje B"})
+	if err != nil {
+		t.Fatalf("Greška pri kreiranju jedinice: %v", err)
+	}
+
+	create := func(name string, unitID int) *models.Project {
+		project := &models.Project{NazivProjekta: name, Status: "Aktivan", RukovodilaID: &admin.KorisnikID, JedinicaID: &unitID}
+		if err := stores.Projects.Create(ctx, project, nil); err != nil {
+			t.Fatalf("Greška pri kreiranju projekta: %v", err)
+		}
+		return project
+	}
+	own, hidden := create("Naš", deptA.JedinicaID), create("Tuđi", deptB.JedinicaID)
+
+	task := func(project *models.Project) *models.Task {
+		task := &models.Task{ProjekatID: project.ProjekatID, FazaID: 1, NazivZadatka: "Analiza", DodjeljenKorisnikuID: &researcher.KorisnikID}
+		if err := stores.Tasks.Create(ctx, task); err != nil {
+			t.Fatalf("Greška pri kreiranju zadatka: %v", err)
+		}
+		return task
+	}
+	task(own)
+	hiddenTask := task(hidden)
+	if err := svc.Tasks.AddTaskComment(adminCtx, hiddenTask.ZadatakID, "Komentar"); err != nil {
+		t.Fatalf("Greška pri dodavanju komentara: %v", err)
+	}
+
+	document := func(name string, projectID *int) *models.Document {
+		doc := &models.Document{NazivDokumenta: name, ProjekatID: projectID, KreiraoKorisnikID: admin.KorisnikID}
+		version := &models.DocumentVersion{PutanjaDoFajla: name, PostavioKorisnikID: admin.KorisnikID}
+		if err := stores.Documents.Create(ctx, doc, version, nil); err != nil {
+			t.Fatalf("Greška pri kreiranju dokumenta: %v", err)
+		}
+		return doc
+	}
+	document("Plan", &own.ProjekatID)
+	hiddenDoc := document("Budžet", &hidden.ProjekatID)
+	document("Pravilnik", nil)
+
+	if _, err := svc.Tasks.GetTasksByProject(headCtx, hidden.ProjekatID); !errors.Is(err, repositories.ErrNotFound) {
+		t.Errorf("Zadaci projekta van podstabla ne smeju biti vidljivi, dobijeno %v", err)
+	}
+	if _, err := svc.Tasks.GetTaskByID(headCtx, hiddenTask.ZadatakID); !errors.Is(err, repositories.ErrNotFound) {
+		t.Errorf("Zadatak projekta van podstabla ne sme biti vidljiv, dobijeno %v", err)
+	}
+	if _, err := svc.Tasks.GetTaskComments(headCtx, hiddenTask.ZadatakID); !errors.Is(err, repositories.ErrNotFound) {
+		t.Errorf("Komentari zadatka van podstabla ne smeju biti vidljivi, dobijeno %v", err)
+	}
+	if tasks, err := svc.Tasks.GetTasksByProject(headCtx, own.ProjekatID); err != nil || len(tasks) != 1 {
+		t.Errorf("Zadaci projekta u podstablu su vidljivi: %+v (%v)", tasks, err)
+	}
+
+	if _, err := svc.Documents.GetDocumentsByProject(headCtx, hidden.ProjekatID); !errors.Is(err, repositories.ErrNotFound) {
+		t.Errorf("Dokumenti projekta van podstabla ne smeju biti vidljivi, dobijeno %v", err)
+	}
+	if _, err := svc.Documents.GetDocumentByID(headCtx, hiddenDoc.DokumentID); !errors.Is(err, repositories.ErrNotFound) {
+		t.Errorf("Dokument projekta van podstabla ne sme biti vidljiv, dobijeno %v", err)
+	}
+	if _, err := svc.Documents.GetDocumentVersions(headCtx, hiddenDoc.DokumentID); !errors.Is(err, repositories.ErrNotFound) {
+		t.Errorf("Verzije dokumenta van podstabla ne smeju biti vidljive, dobijeno %v", err)
+	}
+	docs, err := svc.Documents.GetAllDocuments(headCtx)
+	if err != nil {
+		t.Fatalf("Greška pri čitanju dokumenata: %v", err)
+	}
+	names := []string{}
+	for _, doc := range docs {
+		names = append(names, doc.NazivDokumenta)
+	}
+	sort.Strings(names)
+	if strings.Join(names, ",") != "Plan,Pravilnik" {
+		t.Errorf("Rukovodilac vidi dokumente svog podstabla i one van projekata, dobijeno %v", names)
+	}
+	if docs, _ := svc.Documents.GetAllDocuments(adminCtx); len(docs) != 3 {
+		t.Errorf("Administrator vidi sve dokumente, dobijeno %d", len(docs))
+	}
+
+	// Svoje zadatke korisnik uvek vidi, tuđe samo sa user.view i u svom podstablu
+	if tasks, err := svc.Tasks.GetTasksByUser(researcherCtx, researcher.KorisnikID); err != nil || len(tasks) != 2 {
+		t.Errorf("Korisnik vidi sve svoje zadatke: %+v (%v)", tasks, err)
+	}
+	if _, err := svc.Tasks.GetTasksByUser(otherCtx, researcher.KorisnikID); !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("Tuđi zadaci bez user.view moraju biti zabranjeni, dobijeno %v", err)
+	}
+	if tasks, err := svc.Tasks.GetTasksByUser(headCtx, researcher.KorisnikID); err != nil || len(tasks) != 1 || tasks[0].ProjekatID != own.ProjekatID {
+		t.Errorf("Rukovodilac vidi tuđe zadatke samo u svom podstablu: %+v (%v)", tasks, err)
+	}
+	if tasks, err := svc.Tasks.GetTasksByUser(adminCtx, researcher.KorisnikID); err != nil || len(tasks) != 2 {
+		t.Errorf("Administrator vidi sve tuđe zadatke: %+v (%v)", tasks, err)
+	}
+}
+
+// Gostujući nalog vidi samo ono što je sa njim podeljeno i prestaje da važi
+// istekom, i pre nego što ga pozadinsko čišćenje deaktivira
+func TestGuestAccounts(t *testing.T) {
+	stores := memory.NewStores()
+	cfg := &config.Config{Auth: config.Default().Auth}
+	cfg.Auth.LoginDelay = 0
+	svc := services.New(cfg, stores, services.NewSessionManager(time.Hour, 12*time.Hour), newTestAuthorizer(t, stores))
+	ctx := context.Background()
+
+	admin, adminCtx := newMemoryUser(t, stores, "admin", 1)
+	researcher, researcherCtx := newMemoryUser(t, stores, "jelena", 3)
+
+	guest := services.GuestRequest{KorisnickoIme: "partner", Email: "partner@partner.eu", Istice: time.Now().Add(7 * 24 * time.Hour)}
+	if _, err := svc.Guests.CreateGuest(researcherCtx, guest); !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("Kreiranje gosta bez guest.manage mora biti zabranjeno, dobijeno %v", err)
+	}
+	for name, until := range map[string]time.Time{
+		"istek u prošlosti":     time.Now().Add(-time.Hour),
+		"istek preko maksimuma": time.Now().Add(cfg.Auth.GuestMaxTTL + time.Hour),
+	} {
+		req := guest
+		req.Istice = until
+		if _, err := svc.Guests.CreateGuest(adminCtx, req); !errors.Is(err, services.ErrInvalidInput) {
+			t.Errorf("Gost (%s) mora biti odbijen, dobijeno %v", name, err)
+		}
+	}
+	activation, err := svc.Guests.CreateGuest(adminCtx, guest)
+	if err != nil {
+		t.Fatalf("Greška pri kreiranju gosta: %v", err)
+	}
+	guestID := activation.KorisnikID
+
+	// Gost se aktivira kodom kao i ostali novi nalozi
+	response, err := svc.Auth.Login(ctx, services.LoginRequest{Username: "partner", Password: activation.Kod})
+	if err != nil || response.Message != services.FirstTimeLogin {
+		t.Fatalf("Prijava kodom mora tražiti postavljanje lozinke: %+v, %v", response, err)
+	}
+	response, err = svc.Auth.CompleteActivation(ctx, response.Token, "spoljna-saradnja")
+	if err != nil {
+		t.Fatalf("Greška pri postavljanju lozinke: %v", err)
+	}
+	token := response.Token
+	guestCtx := func() context.Context {
+		t.Helper()
+		principal, err := svc.Auth.Authenticate(ctx, token)
+		if err != nil {
+			t.Fatalf("Sesija gosta mora važiti: %v", err)
+		}
+		if principal.Guest == nil {
+			t.Fatalf("Prijavljeni gost mora imati ograničen pristup")
+		}
+		return services.WithPrincipal(ctx, principal)
+	}
+
+	create := func(name string) *models.Project {
+		project := &models.Project{NazivProjekta: name, Status: "Aktivan", RukovodilaID: &admin.KorisnikID}
+		if err := stores.Projects.Create(ctx, project, nil); err != nil {
+			t.Fatalf("Greška pri kreiranju projekta: %v", err)
+		}
+		return project
+	}
+	consortium, internal := create("Konzorcijum"), create("Interni")
+	folder := &models.Folder{NazivFoldera: "Izveštaji", VlasnikID: admin.KorisnikID}
+	if err := stores.Documents.CreateFolder(ctx, folder); err != nil {
+		t.Fatalf("Greška pri kreiranju foldera: %v", err)
+	}
+	subfolder := &models.Folder{NazivFoldera: "Kvartalni", RoditeljFolderID: &folder.FolderID, VlasnikID: admin.KorisnikID}
+	if err := stores.Documents.CreateFolder(ctx, subfolder); err != nil {
+		t.Fatalf("Greška pri kreiranju foldera: %v", err)
+	}
+	document := func(name string, projectID, folderID *int) *models.Document {
+		doc := &models.Document{NazivDokumenta: name, ProjekatID: projectID, FolderID: folderID, KreiraoKorisnikID: admin.KorisnikID}
+		version := &models.DocumentVersion{PutanjaDoFajla: name, PostavioKorisnikID: admin.KorisnikID}
+		if err := stores.Documents.Create(ctx, doc, version, nil); err != nil {
+			t.Fatalf("Greška pri kreiranju dokumenta: %v", err)
+		}
+		return doc
+	}
+	plan := document("Plan rada", &consortium.ProjekatID, nil)
+	budget := document("Budžet", &internal.ProjekatID, nil)
+	report := document("Izveštaj Q1", &internal.ProjekatID, &subfolder.FolderID)
+	contract := document("Ugovor", nil, nil)
+
+	// Ni jedan projekat nije podeljen, iako uloga inače ne ograničava
+	if _, err := svc.Projects.GetProjectByID(guestCtx(), consortium.ProjekatID); !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("Gost ne sme videti nepodeljen projekat, dobijeno %v", err)
+	}
+
+	share := func(req services.GuestShareRequest) *models.GuestShare {
+		t.Helper()
+		created, err := svc.Guests.ShareWithGuest(adminCtx, guestID, req)
+		if err != nil {
+			t.Fatalf("Greška pri deljenju sa gostom: %v", err)
+		}
+		return created
+	}
+	projectShare := share(services.GuestShareRequest{ProjekatID: &consortium.ProjekatID})
+	share(services.GuestShareRequest{FolderID: &folder.FolderID})
+	contractShare := share(services.GuestShareRequest{DokumentID: &contract.DokumentID})
+	if projectShare.Naziv != consortium.NazivProjekta || projectShare.OdobrioIme != admin.KorisnickoIme {
+		t.Errorf("Deljenje mora nositi naziv projekta i ko ga je odobrio: %+v", projectShare)
+	}
+
+	if _, err := svc.Guests.ShareWithGuest(adminCtx, guestID, services.GuestShareRequest{ProjekatID: &consortium.ProjekatID}); !errors.Is(err, repositories.ErrConflict) {
+		t.Errorf("Ponovljeno deljenje mora biti konflikt, dobijeno %v", err)
+	}
+	for name, req := range map[string]services.GuestShareRequest{
+		"bez objekta": {},
+		"dva objekta": {ProjekatID: &internal.ProjekatID, DokumentID: &budget.DokumentID},
+	} {
+		if _, err := svc.Guests.ShareWithGuest(adminCtx, guestID, req); !errors.Is(err, services.ErrInvalidInput) {
+			t.Errorf("Deljenje (%s) mora biti odbijeno, dobijeno %v", name, err)
+		}
+	}
+	if _, err := svc.Guests.ShareWithGuest(adminCtx, researcher.KorisnikID, services.GuestShareRequest{ProjekatID: &internal.ProjekatID}); !errors.Is(err, services.ErrInvalidInput) {
+		t.Errorf("Deljenje je moguće samo sa gostujućim nalogom, dobijeno %v", err)
+	}
+	if _, err := svc.Guests.ShareWithGuest(researcherCtx, guestID, services.GuestShareRequest{ProjekatID: &internal.ProjekatID}); !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("Deljenje bez guest.manage mora biti zabranjeno, dobijeno %v", err)
+	}
+
+	asGuest := guestCtx()
+	if _, err := svc.Projects.GetProjectByID(asGuest, consortium.ProjekatID); err != nil {
+		t.Errorf("Gost mora videti podeljen projekat: %v", err)
+	}
+	if _, err := svc.Tasks.GetTasksByProject(asGuest, consortium.ProjekatID); err != nil {
+		t.Errorf("Gost mora videti zadatke podeljenog projekta: %v", err)
+	}
+	if _, err := svc.Projects.GetProjectByID(asGuest, internal.ProjekatID); !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("Gost ne sme videti nepodeljen projekat, dobijeno %v", err)
+	}
+	if _, err := svc.Projects.GetAllProjects(asGuest); !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("Gost ne sme videti sve projekte, dobijeno %v", err)
+	}
+	for _, doc := range []*models.Document{plan, report, contract} {
+		if _, err := svc.Documents.GetDocumentByID(asGuest, doc.DokumentID); err != nil {
+			t.Errorf("Gost mora videti podeljen dokument %q: %v", doc.NazivDokumenta, err)
+		}
+	}
+	if _, err := svc.Documents.GetDocumentByID(asGuest, budget.DokumentID); !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("Gost ne sme videti nepodeljen dokument, dobijeno %v", err)
+	}
+	if err := svc.Documents.DeleteDocument(asGuest, contract.DokumentID); !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("Gost ne sme brisati podeljen dokument, dobijeno %v", err)
+	}
+
+	shared, err := svc.Guests.GetSharedWithMe(asGuest)
+	if err != nil {
+		t.Fatalf("Greška pri čitanju podeljenog sadržaja: %v", err)
+	}
+	if len(shared.Projekti) != 1 || len(shared.Dokumenti) != 3 || !shared.Istice.Equal(guest.Istice) {
+		t.Errorf("Gost vidi jedan projekat i tri dokumenta do isteka: %+v", shared)
+	}
+	if _, err := svc.Guests.GetSharedWithMe(researcherCtx); !errors.Is(err, services.ErrInvalidInput) {
+		t.Errorf("Podeljen sadržaj postoji samo za goste, dobijeno %v", err)
+	}
+
+	access, err := svc.Guests.GetGuestAccess(adminCtx)
+	if err != nil {
+		t.Fatalf("Greška pri pregledu gostujućeg pristupa: %v", err)
+	}
+	if len(access) != 1 || access[0].Korisnik.KorisnikID != guestID || len(access[0].Deljenja) != 3 {
+		t.Errorf("Pregled mora sadržati gosta sa tri deljenja: %+v", access)
+	}
+	if _, err := svc.Guests.GetGuestAccess(researcherCtx); !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("Pregled gostujućeg pristupa bez guest.manage mora biti zabranjen, dobijeno %v", err)
+	}
+
+	if err := svc.Guests.RevokeGuestShare(adminCtx, contractShare.PristupID); err != nil {
+		t.Fatalf("Greška pri ukidanju deljenja: %v", err)
+	}
+	if _, err := svc.Documents.GetDocumentByID(guestCtx(), contract.DokumentID); !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("Posle ukidanja deljenja dokument nije dostupan, dobijeno %v", err)
+	}
+
+	if err := svc.Guests.ExtendGuest(adminCtx, researcher.KorisnikID, time.Now().Add(time.Hour)); !errors.Is(err, services.ErrInvalidInput) {
+		t.Errorf("Istek se menja samo gostujućim nalozima, dobijeno %v", err)
+	}
+	extended := time.Now().Add(14 * 24 * time.Hour)
+	if err := svc.Guests.ExtendGuest(adminCtx, guestID, extended); err != nil {
+		t.Fatalf("Greška pri produženju gosta: %v", err)
+	}
+
+	// Posle isteka nalog ne važi ni pre pozadinskog čišćenja
+	svc.Auth.SetClock(func() time.Time { return extended.Add(time.Minute) })
+	if response, _ := svc.Auth.Login(ctx, services.LoginRequest{Username: "partner", Password: "spoljna-saradnja"}); response.Success {
+		t.Errorf("Istekao gost ne sme da se prijavi")
+	}
+	if _, err := svc.Auth.Authenticate(ctx, token); !errors.Is(err, services.ErrNoSession) {
+		t.Errorf("Sesija isteklog gosta mora biti ukinuta, dobijeno %v", err)
+	}
+
+	sweepCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	svc.Guests.RunGuestSweep(sweepCtx, time.Hour)
+	if user, _ := stores.Users.GetByID(ctx, guestID); user.Status != "neaktivan" {
+		t.Errorf("Čišćenje mora deaktivirati isteklog gosta, status %q", user.Status)
+	}
+	if shares, _ := stores.Guests.GetShares(ctx, guestID); len(shares) != 0 {
+		t.Errorf("Čišćenje mora ukloniti deljenja isteklog gosta: %+v", shares)
+	}
+	if access, _ := svc.Guests.GetGuestAccess(adminCtx); len(access) != 0 {
+		t.Errorf("Istekao gost ne sme biti u pregledu: %+v", access)
+	}
+	if swept, err := svc.Guests.SweepExpiredGuests(services.SystemContext(ctx)); err != nil || swept != 0 {
+		t.Errorf("Ponovljeno čišćenje nema šta da ukloni: %d, %v", swept, err)
+	}
+
+	logs, err := stores.Analytics.GetActivityLogs(ctx, 50)
+	if err != nil {
+		t.Fatalf("Greška pri čitanju dnevnika: %v", err)
+	}
+	seen := map[string]bool{}
+	for _, entry := range logs {
+		seen[entry.TipAktivnosti] = true
+	}
+	for _, activity := range []string{services.ActivityGuestShared, services.ActivityGuestUnshared,
+		services.ActivityGuestExtended, services.ActivityGuestExpired} {
+		if !seen[activity] {
+			t.Errorf("Dnevnik mora sadržati %s", activity)
+		}
+	}
+}
+
+func TestDocumentServiceUploadAndDelete(t *testing.T) {
+	stores := memory.NewStores()
+	storage := config.Default().Storage
+	storage.UploadPath = filepath.Join(t.TempDir(), "uploads")
+	documents := services.NewDocumentService(stores, newTestAuthorizer(t, stores), storage)
+
+	author, ctx := newMemoryUser(t, stores, "autor", 3)
+
+	req := models.UploadDocumentRequest{NazivDokumenta: "Izvestaj o radu", Tagovi: []string{"izvestaj", "2024"}}
+	if err := documents.UploadDocument(ctx, req, []byte("sadrzaj"), "izvestaj.pdf"); err != nil {
+		t.Fatalf("Greška pri otpremanju: %v", err)
+	}
+
+	docs, err := documents.GetAllDocuments(ctx)
+	if err != nil || len(docs) != 1 {
+		t.Fatalf("Očekivan jedan dokument, dobijeno %d (%v)", len(docs), err)
+	}
+	if docs[0].KreiraoKorisnikID != author.KorisnikID || docs[0].BrojVerzija != 1 {
+		t.Errorf("Pogrešni podaci dokumenta: %+v", docs[0])
+	}
+
+	versions, _ := documents.GetDocumentVersions(ctx, docs[0].DokumentID)
+	if len(versions) != 1 || filepath.Ext(versions[0].PutanjaDoFajla) != ".pdf" {
+		t.Fatalf("Očekivana jedna .pdf verzija, dobijeno %+v", versions)
+	}
+	content, err := os.ReadFile(versions[0].PutanjaDoFajla)
+	if err != nil || string(content) != "sadrzaj" {
+		t.Errorf("Fajl nije sačuvan: %q, %v", content, err)
+	}
+
+	tags, _ := documents.GetDocumentTags(ctx, docs[0].DokumentID)
+	if len(tags) != 2 {
+		t.Errorf("Očekivana dva taga, dobijeno %+v", tags)
+	}
+
+	// Istraživač nema dozvolu za brisanje
+	if err := documents.DeleteDocument(ctx, docs[0].DokumentID); err == nil {
+		t.Errorf("Istraživač ne sme brisati dokumente")
+	}
+
+	_, adminCtx := newMemoryUser(t, stores, "admin", 1)
+	if err := documents.DeleteDocument(adminCtx, docs[0].DokumentID); err != nil {
+		t.Fatalf("Greška pri brisanju: %v", err)
+	}
+	if _, err := os.Stat(versions[0].PutanjaDoFajla); !os.IsNotExist(err) {
+		t.Errorf("Fajl obrisanog dokumenta mora biti uklonjen")
+	}
+
+	// Neuspeli upis u repozitorijum ne sme ostaviti fajl na disku
+	missingProject := -1
+	req = models.UploadDocumentRequest{NazivDokumenta: "Bez projekta", ProjekatID: &missingProject}
+	if err := documents.UploadDocument(ctx, req, []byte("x"), "x.txt"); err == nil {
+		t.Errorf("Dokument nepostojećeg projekta mora biti odbijen")
+	}
+	entries, _ := os.ReadDir(storage.UploadPath)
+	if len(entries) != 0 {
+		t.Errorf("Očekivan prazan direktorijum, ostalo %d fajlova", len(entries))
+	}
+}
+
+// Test ograničenja veličine i tipa fajla iz konfiguracije
+func TestDocumentServiceStorageLimits(t *testing.T) {
+	stores := memory.NewStores()
+	storage := config.StorageConfig{
+		UploadPath:       filepath.Join(t.TempDir(), "uploads"),
+		MaxFileSize:      8,
+		AllowedFileTypes: []string{"pdf", "txt"},
+	}
+	documents := services.NewDocumentService(stores, newTestAuthorizer(t, stores), storage)
+	_, ctx := newMemoryUser(t, stores, "autor", 3)
+
+	req := models.UploadDocumentRequest{NazivDokumenta: "Prilog"}
+	cases := []struct {
+		name     string
+		fileName string
+		data     string
+	}{
+		{"prevelik fajl", "prilog.pdf", "123456789"},
+		{"nedozvoljen tip", "prilog.exe", "123"},
+		{"fajl bez ekstenzije", "prilog", "123"},
+		{"prazan fajl", "prilog.txt", ""},
+	}
+	for _, c := range cases {
+		err := documents.UploadDocument(ctx, req, []byte(c.data), c.fileName)
+		if !errors.Is(err, services.ErrInvalidInput) {
+			t.Errorf("%s: očekivana greška unosa, dobijeno %v", c.name, err)
+		}
+	}
+
+	if err := documents.UploadDocument(ctx, req, []byte("12345678"), "PRILOG.PDF"); err != nil {
+		t.Errorf("Fajl u okviru ograničenja mora biti prihvaćen: %v", err)
+	}
+	if entries, _ := os.ReadDir(storage.UploadPath); len(entries) != 1 {
+		t.Errorf("Očekivan jedan sačuvan fajl, dobijeno %d", len(entries))
+	}
+}
+
+// Test zadataka bez baze podataka
+func TestTaskServiceWithMemoryStores(t *testing.T) {
+	stores := memory.NewStores()
+	authz := newTestAuthorizer(t, stores)
+	tasks := services.NewTaskService(stores.Tasks, stores.Projects, stores.Units, authz)
+
+	leader, leaderCtx := newMemoryUser(t, stores, "rukovodilac", 2)
+	_, researcherCtx := newMemoryUser(t, stores, "istrazivac", 3)
+
+	workflowID := 1
+	project := &models.Project{NazivProjekta: "Projekat", RukovodilaID: &leader.KorisnikID, RadniTokID: &workflowID}
+	if err := stores.Projects.Create(leaderCtx, project, nil); err != nil {
+		t.Fatalf("Greška pri kreiranju projekta: %v", err)
+	}
+
+	if err := tasks.CreateTask(researcherCtx, models.CreateTaskRequest{ProjekatID: project.ProjekatID, NazivZadatka: "x"}); err == nil {
+		t.Errorf("Istraživač ne sme kreirati zadatke")
+	}
+	if err := tasks.CreateTask(leaderCtx, models.CreateTaskRequest{ProjekatID: project.ProjekatID, NazivZadatka: "Analiza"}); err != nil {
+		t.Fatalf("Greška pri kreiranju zadatka: %v", err)
+	}
+
+	list, err := tasks.GetTasksByProject(researcherCtx, project.ProjekatID)
+	if err != nil || len(list) != 1 {
+		t.Fatalf("Očekivan jedan zadatak, dobijeno %d (%v)", len(list), err)
+	}
+	if list[0].NazivFaze != "Planiranje" {
+		t.Errorf("Zadatak mora početi u prvoj fazi toka, dobijeno %q", list[0].NazivFaze)
+	}
+
+	if err := tasks.UpdateTask(researcherCtx, list[0].ZadatakID, models.UpdateTaskRequest{}); err == nil {
+		t.Errorf("Izmena bez polja mora biti odbijena")
+	}
+	progress := 50
+	if err := tasks.UpdateTask(researcherCtx, list[0].ZadatakID, models.UpdateTaskRequest{Progres: &progress}); err != nil {
+		t.Fatalf("Greška pri izmeni zadatka: %v", err)
+	}
+
+	if err := tasks.AddTaskComment(researcherCtx, list[0].ZadatakID, "Gotovo do pola"); err != nil {
+		t.Fatalf("Greška pri dodavanju komentara: %v", err)
+	}
+	comments, _ := tasks.GetTaskComments(leaderCtx, list[0].ZadatakID)
+	if len(comments) != 1 || comments[0].ImeKorisnika != "istrazivac" {
+		t.Errorf("Komentar mora biti upisan u ime pozivaoca: %+v", comments)
+	}
+
+	if err := tasks.DeleteTask(leaderCtx, list[0].ZadatakID); err != nil {
+		t.Fatalf("Greška pri brisanju zadatka: %v", err)
+	}
+	if _, err := tasks.GetTaskByID(leaderCtx, list[0].ZadatakID); err == nil {
+		t.Errorf("Obrisan zadatak ne sme biti pronađen")
+	}
+}
+
+// Servisi vezani za desktop aplikaciju rade samo sa prijavljenim korisnikom
+// i proveravaju njegove dozvole
+func TestBoundServicesRequireCaller(t *testing.T) {
+	stores := memory.NewStores()
+	svc := services.New(&config.Config{}, stores, services.NewSessionManager(time.Hour, 12*time.Hour), newTestAuthorizer(t, stores))
+	ctx := context.Background()
+
+	calls := map[string]func(context.Context) error{
+		"GetAllProjects":    func(c context.Context) error { _, err := svc.Projects.GetAllProjects(c); return err },
+		"GetTasksByUser":    func(c context.Context) error { _, err := svc.Tasks.GetTasksByUser(c, 1); return err },
+		"GetAllWorkflows":   func(c context.Context) error { _, err := svc.Workflows.GetAllWorkflows(c); return err },
+		"GetAllUsers":       func(c context.Context) error { _, err := svc.Users.GetAllUsers(c); return err },
+		"GetDashboardStats": func(c context.Context) error { _, err := svc.Analytics.GetDashboardStats(c); return err },
+	}
+	for name, call := range calls {
+		if err := call(ctx); !errors.Is(err, services.ErrNoSession) {
+			t.Errorf("%s bez prijavljenog korisnika mora vratiti ErrNoSession, dobijeno %v", name, err)
+		}
+	}
+
+	leader, leaderCtx := newMemoryUser(t, stores, "rukovodilac", 2)
+	_, researcherCtx := newMemoryUser(t, stores, "istrazivac", 3)
+
+	req := models.CreateProjectRequest{NazivProjekta: "Novi projekat", Opis: "Opis"}
+	if _, err := svc.Projects.CreateProject(researcherCtx, req); !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("Istraživač ne sme kreirati projekat, dobijeno %v", err)
+	}
+	project, err := svc.Projects.CreateProject(leaderCtx, req)
+	if err != nil {
+		t.Fatalf("Greška pri kreiranju projekta: %v", err)
+	}
+	if project.RukovodilaID == nil || *project.RukovodilaID != leader.KorisnikID {
+		t.Errorf("Rukovodilac novog projekta je korisnik koji ga je kreirao: %+v", project.RukovodilaID)
+	}
+	mine, err := svc.Projects.GetMyProjects(leaderCtx)
+	if err != nil || len(mine) != 1 || mine[0].ProjekatID != project.ProjekatID {
+		t.Errorf("Novi projekat mora biti među projektima rukovodioca: %+v, %v", mine, err)
+	}
+}
+
+// Test da izmena projekta ne menja rukovodioca bez ovlašćenja i da novi
+// rukovodilac postaje član tima
+func TestProjectLeaderChange(t *testing.T) {
+	stores := memory.NewStores()
+	svc := services.New(&config.Config{}, stores, services.NewSessionManager(time.Hour, 12*time.Hour), newTestAuthorizer(t, stores))
+
+	leader, leaderCtx := newMemoryUser(t, stores, "rukovodilac", 2)
+	organizer, organizerCtx := newMemoryUser(t, stores, "organizator", 4)
+	researcher, _ := newMemoryUser(t, stores, "istrazivac", 3)
+
+	project, err := svc.Projects.CreateProject(leaderCtx, models.CreateProjectRequest{NazivProjekta: "Projekat", Opis: "Opis"})
+	if err != nil {
+		t.Fatalf("Greška pri kreiranju projekta: %v", err)
+	}
+
+	update := project
+	update.NazivProjekta = "Preimenovan"
+	update.RukovodilaID = &organizer.KorisnikID
+	if err := svc.Projects.UpdateProject(organizerCtx, project.ProjekatID, update); !errors.Is(err, services.ErrForbidden) {
+		t.Errorf("Organizator ne sme sebe postaviti za rukovodioca, dobijeno %v", err)
+	}
+
+	update.RukovodilaID = nil
+	if err := svc.Projects.UpdateProject(organizerCtx, project.ProjekatID, update); err != nil {
+		t.Fatalf("Organizator mora moći da izmeni projekat: %v", err)
+	}
+	got, _ := svc.Projects.GetProjectByID(leaderCtx, project.ProjekatID)
+	if got.NazivProjekta != "Preimenovan" || got.RukovodilaID == nil || *got.RukovodilaID != leader.KorisnikID {
+		t.Errorf("Izmena bez rukovodioca mora zadržati postojećeg: %+v", got)
+	}
+
+	update.RukovodilaID = &researcher.KorisnikID
+	if err := svc.Projects.UpdateProject(leaderCtx, project.ProjekatID, update); err != nil {
+		t.Fatalf("Rukovodilac mora moći da preda projekat: %v", err)
+	}
+	if isMember, _ := stores.Projects.IsMember(context.Background(), project.ProjekatID, researcher.KorisnikID); !isMember {
+		t.Errorf("Novi rukovodilac mora postati član tima")
+	}
+}
@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/services"
+)
+
+// Test isteka sesije zbog neaktivnosti i apsolutnog roka
+func TestSessionExpiry(t *testing.T) {
+	now := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+	manager := services.NewSessionManager(30*time.Minute, 2*time.Hour)
+	manager.SetClock(func() time.Time { return now })
+
+	token, _, err := manager.Create(&models.User{KorisnikID: 1, KorisnickoIme: "admin"})
+	if err != nil {
+		t.Fatalf("Greška pri kreiranju sesije: %v", err)
+	}
+
+	// Aktivnost na svakih 20 minuta produžava sesiju do apsolutnog roka
+	for i := 0; i < 5; i++ {
+		now = now.Add(20 * time.Minute)
+		if _, err := manager.Resolve(token); err != nil {
+			t.Fatalf("Sesija je istekla pre roka (korak %d): %v", i, err)
+		}
+	}
+
+	now = now.Add(20 * time.Minute)
+	if _, err := manager.Resolve(token); err != services.ErrSessionExpired {
+		t.Errorf("Očekivan istek po apsolutnom roku, dobijeno: %v", err)
+	}
+
+	token, _, _ = manager.Create(&models.User{KorisnikID: 1, KorisnickoIme: "admin"})
+	now = now.Add(31 * time.Minute)
+	if _, err := manager.Resolve(token); err != services.ErrSessionExpired {
+		t.Errorf("Očekivan istek zbog neaktivnosti, dobijeno: %v", err)
+	}
+}
+
+// Test opoziva sesija jednog korisnika
+func TestSessionRevokeUser(t *testing.T) {
+	manager := services.NewSessionManager(0, 0)
+
+	keep, _, _ := manager.Create(&models.User{KorisnikID: 1})
+	other, _, _ := manager.Create(&models.User{KorisnikID: 1})
+	foreign, _, _ := manager.Create(&models.User{KorisnikID: 2})
+
+	if revoked := manager.RevokeUser(1, keep); revoked != 1 {
+		t.Errorf("Očekivan opoziv 1 sesije, opozvano: %d", revoked)
+	}
+
+	if _, err := manager.Resolve(keep); err != nil {
+		t.Errorf("Tekuća sesija ne sme biti opozvana: %v", err)
+	}
+	if _, err := manager.Resolve(other); err != services.ErrNoSession {
+		t.Errorf("Druga sesija korisnika mora biti opozvana, dobijeno: %v", err)
+	}
+	if _, err := manager.Resolve(foreign); err != nil {
+		t.Errorf("Sesija drugog korisnika ne sme biti opozvana: %v", err)
+	}
+
+	sessions := manager.List()
+	if len(sessions) != 2 {
+		t.Fatalf("Očekivane 2 aktivne sesije, dobijeno: %d", len(sessions))
+	}
+	if !manager.RevokeByID(sessions[0].ID) {
+		t.Errorf("Opoziv po ID-u nije uspeo")
+	}
+}
+
+// Test kratkog roka aktivacione sesije
+func TestSessionActivationLifetime(t *testing.T) {
+	now := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+	manager := services.NewSessionManager(30*time.Minute, 2*time.Hour)
+	manager.SetClock(func() time.Time { return now })
+
+	token, session, err := manager.CreateActivation(&models.User{KorisnikID: 1, KorisnickoIme: "nova"})
+	if err != nil {
+		t.Fatalf("Greška pri kreiranju sesije: %v", err)
+	}
+	if !session.Aktivacija || !session.Istice.Equal(now.Add(services.ActivationSessionLifetime)) {
+		t.Errorf("Aktivaciona sesija mora isteći posle %v: %+v", services.ActivationSessionLifetime, session)
+	}
+
+	now = now.Add(10 * time.Minute)
+	if _, err := manager.Resolve(token); err != nil {
+		t.Fatalf("Sesija je istekla pre roka: %v", err)
+	}
+	now = now.Add(6 * time.Minute)
+	if _, err := manager.Resolve(token); err != services.ErrSessionExpired {
+		t.Errorf("Aktivnost ne sme produžiti aktivacionu sesiju, dobijeno: %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/cane/research-institute-system/backend/migrations"
+	"github.com/cane/research-institute-system/backend/schemacheck"
+	"github.com/cane/research-institute-system/backend/services"
+	"github.com/cane/research-institute-system/database"
+)
+
+// check is one line of the health report.
+type check struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail"`
+}
+
+func (a *admin) migrate() error {
+	migrator, err := migrations.New(a.db, database.Migrations, "migrations")
+	if err != nil {
+		return err
+	}
+
+	applied, err := migrator.Up(a.ctx)
+	if len(applied) == 0 && err == nil {
+		fmt.Println("schema is up to date")
+	}
+	for _, m := range applied {
+		fmt.Printf("applied %04d_%s\n", m.Version, m.Name)
+	}
+	return err
+}
+
+// health checks the database connection, the schema version, the service
+// queries and the user accounts. It fails when any check fails, so it can
+// gate deployments and monitoring scripts.
+func (a *admin) health(args []string) error {
+	flags := flag.NewFlagSet("health", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print JSON instead of text")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	checks := a.runChecks()
+
+	failed := 0
+	for _, c := range checks {
+		if !c.OK {
+			failed++
+		}
+	}
+
+	if *asJSON {
+		if err := printJSON(checks); err != nil {
+			return err
+		}
+	} else {
+		for _, c := range checks {
+			status := "ok"
+			if !c.OK {
+				status = "FAIL"
+			}
+			fmt.Fprintf(os.Stdout, "%-10s %-4s %s\n", c.Name, status, c.Detail)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(checks))
+	}
+	return nil
+}
+
+func (a *admin) runChecks() []check {
+	if err := a.db.PingContext(a.ctx); err != nil {
+		return []check{{Name: "database", Detail: err.Error()}}
+	}
+	checks := []check{{Name: "database", OK: true, Detail: "connected"}}
+
+	migrator, err := migrations.New(a.db, database.Migrations, "migrations")
+	if err == nil {
+		var pending []migrations.Migration
+		if pending, err = migrator.Pending(a.ctx); err == nil {
+			detail := "up to date"
+			if len(pending) > 0 {
+				detail = fmt.Sprintf("%d pending migration(s), run riis-admin migrate", len(pending))
+			}
+			checks = append(checks, check{Name: "migrations", OK: len(pending) == 0, Detail: detail})
+		}
+	}
+	if err != nil {
+		checks = append(checks, check{Name: "migrations", Detail: err.Error()})
+	}
+
+	report, err := schemacheck.Check(a.ctx, a.db)
+	switch {
+	case err != nil:
+		checks = append(checks, check{Name: "schema", Detail: err.Error()})
+	case !report.OK():
+		checks = append(checks, check{Name: "schema", Detail: fmt.Sprintf("%d of %d queries do not match the database", len(report.Problems), report.Checked)})
+	default:
+		checks = append(checks, check{Name: "schema", OK: true, Detail: fmt.Sprintf("all %d queries match", report.Checked)})
+	}
+
+	users, err := a.svc.Users.GetAllUsers(a.ctx)
+	if err != nil {
+		return append(checks, check{Name: "admins", Detail: err.Error()})
+	}
+	roles, err := a.svc.Roles.GetRoles(a.ctx)
+	if err != nil {
+		return append(checks, check{Name: "admins", Detail: err.Error()})
+	}
+	adminRole := 0
+	for _, role := range roles {
+		if role.Sistemska != nil && *role.Sistemska == services.RoleAdministrator {
+			adminRole = role.UlogaID
+		}
+	}
+	admins := 0
+	for _, u := range users {
+		if u.Status == "aktivan" && u.UlogaID == adminRole {
+			admins++
+		}
+	}
+	detail := fmt.Sprintf("%d active administrator(s)", admins)
+	if admins == 0 {
+		detail = "no active administrator, create one with riis-admin user create -role Administrator"
+	}
+	return append(checks, check{Name: "admins", OK: admins > 0, Detail: detail})
+}
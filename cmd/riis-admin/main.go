@@ -0,0 +1,209 @@
+// Command riis-admin performs administrative operations on the institute
+// database through the same services the desktop app uses, so passwords are
+// hashed and accounts validated exactly as in the app.
+//
+//	riis-admin user list [-json]
+//	riis-admin user create -email E -role R [-first F] [-last L] [-password-stdin] USERNAME
+//	riis-admin user reset-password [-password-stdin] USERNAME
+//	riis-admin user activate USERNAME
+//	riis-admin user deactivate USERNAME
+//	riis-admin user unlock USERNAME
+//	riis-admin user reset-2fa USERNAME
+//	riis-admin user offboard [-leader U] [-assignee U] [-owner U] [-json] USERNAME
+//	riis-admin user import [-dry-run] [-per-row] [-json] FILE
+//	riis-admin user export
+//	riis-admin role list [-json]
+//	riis-admin role create [-description D] [-permissions LIST] NAME
+//	riis-admin role clone ROLE NAME
+//	riis-admin role update [-name N] [-description D] [-permissions LIST] ROLE
+//	riis-admin role retire ROLE
+//	riis-admin role assign USERNAME ROLE
+//	riis-admin role import-policy [FILE]
+//	riis-admin migrate
+//	riis-admin health [-json]
+//	riis-admin config [-json]
+//
+// offboard deactivates a user and hands the user's projects (-leader), open
+// tasks (-assignee) and folders and documents (-owner) to other users; work
+// without a target stays with the account.
+//
+// Roles keep their permissions in the database. LIST separates permissions
+// with commas, and PERMISSION@PROJECT_ID grants one only within a project,
+// e.g. -permissions task.view,document.update@12. A role is retired once no
+// user holds it. import-policy applies the policy file of earlier versions,
+// auth.policy_path by default, to the roles it names.
+//
+// import creates the users of a CSV file (- reads standard input) and prints
+// their activation codes; export prints all users as CSV.
+//
+// Without -password-stdin, create and reset-password print a one-time
+// activation code; the user signs in with it and must set a password. With
+// -password-stdin the first line of standard input becomes the password
+// instead, which keeps passwords out of the process list in scripts.
+//
+// Every command runs without prompts and exits non-zero on failure.
+// Settings come from riis.json, the environment or .env; -config FILE and
+// -set key=value may precede the command. config prints the effective
+// settings and where each one came from, with secrets masked.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/cane/research-institute-system/backend/config"
+	"github.com/cane/research-institute-system/backend/logging"
+	"github.com/cane/research-institute-system/backend/repositories"
+	"github.com/cane/research-institute-system/backend/services"
+
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+)
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "riis-admin:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `usage: riis-admin [-config FILE] [-set key=value] COMMAND
+       riis-admin user list [-json]
+       riis-admin user create -email E -role R [-first F] [-last L] [-password-stdin] USERNAME
+       riis-admin user reset-password [-password-stdin] USERNAME
+       riis-admin user activate | deactivate | unlock | reset-2fa USERNAME
+       riis-admin user offboard [-leader U] [-assignee U] [-owner U] [-json] USERNAME
+       riis-admin user import [-dry-run] [-per-row] [-json] FILE
+       riis-admin user export
+       riis-admin role list [-json]
+       riis-admin role create [-description D] [-permissions LIST] NAME
+       riis-admin role clone ROLE NAME
+       riis-admin role update [-name N] [-description D] [-permissions LIST] ROLE
+       riis-admin role retire ROLE
+       riis-admin role assign USERNAME ROLE
+       riis-admin role import-policy [FILE]
+       riis-admin migrate
+       riis-admin health [-json]
+       riis-admin config [-json]`)
+}
+
+// admin holds what the commands need: the database and the services built on
+// it, called as the system principal.
+type admin struct {
+	cfg    *config.Config
+	ctx    context.Context
+	db     *sql.DB
+	stores repositories.Stores
+	svc    *services.Services
+}
+
+func run(args []string) error {
+	_ = godotenv.Load()
+
+	cfg, args, err := config.Load(args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		usage()
+		return fmt.Errorf("missing command")
+	}
+	if args[0] == "config" {
+		return showConfig(cfg, args[1:])
+	}
+
+	// Service calls log what they change, like in the app, to stderr and
+	// log.file, so operator actions end up in the same log
+	_, logFile, err := logging.Setup(cfg.Log)
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+
+	db, err := cfg.Database.Open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	a, err := newAdmin(cfg, db, repositories.NewPostgresStores(db))
+	if err != nil {
+		return err
+	}
+	return a.dispatch(args)
+}
+
+// dispatch runs the command args names.
+func (a *admin) dispatch(args []string) error {
+	if len(args) == 0 {
+		usage()
+		return fmt.Errorf("missing command")
+	}
+
+	switch args[0] {
+	case "user":
+		return a.user(args[1:])
+	case "role":
+		return a.role(args[1:])
+	case "migrate":
+		return a.migrate()
+	case "health":
+		return a.health(args[1:])
+	default:
+		usage()
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+// newAdmin builds the services on stores. db is only used by migrate and
+// health.
+func newAdmin(cfg *config.Config, db *sql.DB, stores repositories.Stores) (*admin, error) {
+	authz, err := services.NewAuthorizer(context.Background(), stores.Roles)
+	if err != nil {
+		return nil, fmt.Errorf("role permissions: %w", err)
+	}
+
+	sessions := services.NewSessionManager(cfg.Auth.SessionIdleTimeout, cfg.Auth.SessionMaxLifetime)
+
+	return &admin{
+		cfg:    cfg,
+		ctx:    services.SystemContext(context.Background()),
+		db:     db,
+		stores: stores,
+		svc:    services.New(cfg, stores, sessions, authz),
+	}, nil
+}
+
+// showConfig prints the effective settings. It needs no database, so it also
+// helps when the connection settings are wrong.
+func showConfig(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("config", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print JSON instead of a table")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	settings := cfg.Settings()
+	if *asJSON {
+		return printJSON(settings)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tVALUE\tSOURCE")
+	for _, s := range settings {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", s.Key, s.Value, s.Source)
+	}
+	return w.Flush()
+}
+
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cane/research-institute-system/backend/config"
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/repositories"
+	"github.com/cane/research-institute-system/backend/repositories/memory"
+)
+
+// newTestAdmin vraća komande nad memorijskim repozitorijumom, bez baze
+func newTestAdmin(t *testing.T) (*admin, repositories.Stores) {
+	t.Helper()
+
+	stores := memory.NewStores()
+	cfg := config.Default()
+	a, err := newAdmin(cfg, nil, stores)
+	if err != nil {
+		t.Fatalf("Greška pri pravljenju komandi: %v", err)
+	}
+	return a, stores
+}
+
+// capture izvršava fn i vraća ono što je ispisala na standardni izlaz
+func capture(t *testing.T, fn func() error) (string, error) {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Greška pri pravljenju cevi: %v", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		output <- string(data)
+	}()
+
+	runErr := fn()
+	w.Close()
+	return <-output, runErr
+}
+
+// Test odbijanja nepoznatih komandi i neispravnih argumenata
+func TestCommandErrors(t *testing.T) {
+	a, _ := newTestAdmin(t)
+
+	cases := map[string]struct {
+		args []string
+		want string
+	}{
+		"bez komande":            {nil, "missing command"},
+		"nepoznata komanda":      {[]string{"frobnicate"}, `unknown command "frobnicate"`},
+		"user bez komande":       {[]string{"user"}, "missing user command"},
+		"nepoznata user komanda": {[]string{"user", "frobnicate"}, `unknown user command "frobnicate"`},
+		"nepoznata role komanda": {[]string{"role", "frobnicate"}, `unknown role command "frobnicate"`},
+		"create bez uloge":       {[]string{"user", "create", "-email", "ana@institut.rs", "ana"}, "-role is required"},
+		"create bez imena":       {[]string{"user", "create", "-role", "Istrazivac"}, "exactly one USERNAME"},
+		"nepoznata uloga":        {[]string{"user", "create", "-role", "Astronaut", "ana"}, `unknown role "Astronaut"`},
+		"nepoznat korisnik":      {[]string{"user", "deactivate", "niko"}, `user "niko"`},
+		"nepoznata opcija":       {[]string{"user", "list", "-xml"}, "flag provided but not defined"},
+		"export sa argumentom":   {[]string{"user", "export", "fajl.csv"}, "takes no arguments"},
+		"import bez fajla":       {[]string{"user", "import"}, "exactly one FILE"},
+		"nepoznata dozvola":      {[]string{"role", "create", "-permissions", "lab.fly", "Laborant"}, "lab.fly"},
+		"clone bez naziva":       {[]string{"role", "clone", "Istrazivac"}, "NAME of the copy"},
+	}
+	for name, tc := range cases {
+		_, err := capture(t, func() error { return a.dispatch(tc.args) })
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: očekivana greška %q, dobijeno %v", name, tc.want, err)
+		}
+	}
+}
+
+// Test komandi za korisnike i uloge nad istim servisima kao u aplikaciji
+func TestUserAndRoleCommands(t *testing.T) {
+	a, stores := newTestAdmin(t)
+	ctx := context.Background()
+
+	out, err := capture(t, func() error {
+		return a.dispatch([]string{"user", "create", "-email", "ana@institut.rs", "-role", "Istrazivac", "-first", "Ana", "ana"})
+	})
+	if err != nil || !strings.Contains(out, "created user ana") || !strings.Contains(out, "activation code: ") {
+		t.Fatalf("Kreiranje korisnika mora ispisati aktivacioni kod: %q, %v", out, err)
+	}
+	user, err := stores.Users.GetByUsername(ctx, "ana")
+	if err != nil || user.UlogaID != 3 || user.Ime == nil || *user.Ime != "Ana" {
+		t.Fatalf("Korisnik nije kreiran kako je zadato: %+v, %v", user, err)
+	}
+
+	out, err = capture(t, func() error { return a.dispatch([]string{"user", "list", "-json"}) })
+	var users []models.User
+	if err != nil || json.Unmarshal([]byte(out), &users) != nil || len(users) != 1 || users[0].KorisnickoIme != "ana" {
+		t.Errorf("user list -json mora ispisati korisnike kao JSON: %q, %v", out, err)
+	}
+
+	if out, err := capture(t, func() error { return a.dispatch([]string{"user", "deactivate", "ana"}) }); err != nil || !strings.Contains(out, "now neaktivan") {
+		t.Errorf("Deaktivacija korisnika: %q, %v", out, err)
+	}
+	if out, err := capture(t, func() error { return a.dispatch([]string{"user", "deactivate", "ana"}) }); err != nil || !strings.Contains(out, "already neaktivan") {
+		t.Errorf("Ponovljena deaktivacija nije greška: %q, %v", out, err)
+	}
+	if user, _ := stores.Users.GetByUsername(ctx, "ana"); user.Status != "neaktivan" {
+		t.Errorf("Korisnik mora biti deaktiviran, status %q", user.Status)
+	}
+
+	if _, err := capture(t, func() error {
+		return a.dispatch([]string{"role", "create", "-description", "Laboratorija", "-permissions", "project.view,task.view", "Laborant"})
+	}); err != nil {
+		t.Fatalf("Kreiranje uloge: %v", err)
+	}
+	if out, err := capture(t, func() error { return a.dispatch([]string{"role", "assign", "ana", "laborant"}) }); err != nil {
+		t.Fatalf("Dodela uloge: %q, %v", out, err)
+	}
+	user, _ = stores.Users.GetByUsername(ctx, "ana")
+	if user.NazivUloge != "Laborant" {
+		t.Errorf("Korisniku mora biti dodeljena uloga Laborant, dobijeno %q", user.NazivUloge)
+	}
+	out, err = capture(t, func() error { return a.dispatch([]string{"role", "list"}) })
+	if err != nil || !strings.Contains(out, "Laborant") || !strings.Contains(out, "project.view,task.view") {
+		t.Errorf("role list mora prikazati novu ulogu i njene dozvole: %q, %v", out, err)
+	}
+
+	out, err = capture(t, func() error { return a.dispatch([]string{"user", "export"}) })
+	if err != nil || !strings.HasPrefix(out, "korisnicko_ime,") || !strings.Contains(out, "ana,ana@institut.rs") {
+		t.Errorf("user export mora ispisati CSV: %q, %v", out, err)
+	}
+}
+
+// Test uvoza: neispravni redovi odbijaju ceo fajl i komanda se završava greškom
+func TestUserImportCommand(t *testing.T) {
+	a, stores := newTestAdmin(t)
+	ctx := context.Background()
+
+	file := filepath.Join(t.TempDir(), "korisnici.csv")
+	content := "korisnicko_ime,email,uloga\nana,ana@institut.rs,Istrazivac\nivan,ivan@institut.rs,Astronaut\n"
+	if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatalf("Greška pri upisu fajla: %v", err)
+	}
+
+	out, err := capture(t, func() error { return a.dispatch([]string{"user", "import", file}) })
+	if err == nil || !strings.Contains(err.Error(), "1 invalid rows") || !strings.Contains(out, "1 of 2 rows valid, 0 users created") {
+		t.Errorf("Fajl sa neispravnim redom mora biti odbijen: %q, %v", out, err)
+	}
+	if _, err := stores.Users.GetByUsername(ctx, "ana"); err == nil {
+		t.Errorf("Bez -per-row ne sme biti kreiran ni jedan korisnik")
+	}
+
+	out, err = capture(t, func() error { return a.dispatch([]string{"user", "import", "-per-row", file}) })
+	if err == nil || !strings.Contains(out, "1 users created") {
+		t.Errorf("Sa -per-row ispravni redovi se kreiraju, a komanda ipak javlja grešku: %q, %v", out, err)
+	}
+	if _, err := stores.Users.GetByUsername(ctx, "ana"); err != nil {
+		t.Errorf("Ispravan red mora biti kreiran: %v", err)
+	}
+}
+
+// Test komandi koje ne traže bazu
+func TestRunWithoutDatabase(t *testing.T) {
+	if _, err := capture(t, func() error { return run(nil) }); err == nil || err.Error() != "missing command" {
+		t.Errorf("Bez komande mora biti greška, dobijeno %v", err)
+	}
+
+	out, err := capture(t, func() error { return run([]string{"-set", "auth.login_delay=3s", "config", "-json"}) })
+	if err != nil {
+		t.Fatalf("config -json: %v", err)
+	}
+	var settings []config.Setting
+	if err := json.Unmarshal([]byte(out), &settings); err != nil {
+		t.Fatalf("config -json mora ispisati JSON: %v", err)
+	}
+	found := false
+	for _, s := range settings {
+		if s.Key == "auth.login_delay" {
+			found = s.Value == "3s"
+		}
+	}
+	if !found {
+		t.Errorf("Vrednost zadata sa -set mora biti prikazana: %+v", settings)
+	}
+
+	if _, err := capture(t, func() error { return run([]string{"-set", "auth.nepostojece=1", "config"}) }); err == nil {
+		t.Errorf("Nepoznat ključ u -set mora biti greška")
+	}
+}
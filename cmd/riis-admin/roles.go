@@ -0,0 +1,272 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/services"
+)
+
+func (a *admin) role(args []string) error {
+	if len(args) == 0 {
+		usage()
+		return fmt.Errorf("missing role command")
+	}
+
+	switch args[0] {
+	case "list":
+		return a.listRoles(args[1:])
+	case "create":
+		return a.createRole(args[1:])
+	case "clone":
+		return a.cloneRole(args[1:])
+	case "update":
+		return a.updateRole(args[1:])
+	case "retire":
+		return a.retireRole(args[1:])
+	case "assign":
+		return a.assignRole(args[1:])
+	case "import-policy":
+		return a.importPolicy(args[1:])
+	default:
+		usage()
+		return fmt.Errorf("unknown role command %q", args[0])
+	}
+}
+
+func (a *admin) listRoles(args []string) error {
+	flags := flag.NewFlagSet("role list", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print JSON instead of a table")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	roles, err := a.svc.Roles.GetRoles(a.ctx)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(roles)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tSTATUS\tPERMISSIONS")
+	for _, role := range roles {
+		status := "active"
+		if role.Povucena != nil {
+			status = "retired"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", role.UlogaID, role.NazivUloge, status, formatGrants(role.Dozvole))
+	}
+	return w.Flush()
+}
+
+// createRole creates a role. -permissions lists permissions separated by
+// commas; PERMISSION@PROJECT_ID grants one only within a project.
+func (a *admin) createRole(args []string) error {
+	flags := flag.NewFlagSet("role create", flag.ContinueOnError)
+	description := flags.String("description", "", "what the role is for")
+	permissions := flags.String("permissions", "", "comma separated permissions, PERMISSION@PROJECT_ID for one project")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("expected exactly one role NAME")
+	}
+	grants, err := parseGrants(*permissions)
+	if err != nil {
+		return err
+	}
+
+	role, err := a.svc.Roles.CreateRole(a.ctx, services.RoleRequest{Naziv: flags.Arg(0), Opis: *description, Dozvole: grants})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("role %s created with ID %d\n", role.NazivUloge, role.UlogaID)
+	return nil
+}
+
+func (a *admin) cloneRole(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("expected ROLE and the NAME of the copy")
+	}
+	source, err := a.findRole(args[0])
+	if err != nil {
+		return err
+	}
+
+	role, err := a.svc.Roles.CloneRole(a.ctx, source.UlogaID, args[1])
+	if err != nil {
+		return err
+	}
+	fmt.Printf("role %s created with ID %d from %s\n", role.NazivUloge, role.UlogaID, source.NazivUloge)
+	return nil
+}
+
+// updateRole changes what its flags name and keeps the rest of the role.
+func (a *admin) updateRole(args []string) error {
+	flags := flag.NewFlagSet("role update", flag.ContinueOnError)
+	name := flags.String("name", "", "new name of the role")
+	description := flags.String("description", "", "new description of the role")
+	permissions := flags.String("permissions", "", "replacement permissions, PERMISSION@PROJECT_ID for one project")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("expected exactly one ROLE")
+	}
+	role, err := a.findRole(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	req := services.RoleRequest{Naziv: role.NazivUloge, Dozvole: role.Dozvole}
+	if role.Opis != nil {
+		req.Opis = *role.Opis
+	}
+	var failed error
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "name":
+			req.Naziv = *name
+		case "description":
+			req.Opis = *description
+		case "permissions":
+			req.Dozvole, failed = parseGrants(*permissions)
+		}
+	})
+	if failed != nil {
+		return failed
+	}
+
+	updated, err := a.svc.Roles.UpdateRole(a.ctx, role.UlogaID, req)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("role %s updated: %s\n", updated.NazivUloge, formatGrants(updated.Dozvole))
+	return nil
+}
+
+func (a *admin) retireRole(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected exactly one ROLE")
+	}
+	role, err := a.findRole(args[0])
+	if err != nil {
+		return err
+	}
+
+	if err := a.svc.Roles.RetireRole(a.ctx, role.UlogaID); err != nil {
+		return err
+	}
+	fmt.Printf("role %s retired\n", role.NazivUloge)
+	return nil
+}
+
+func (a *admin) assignRole(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("expected USERNAME and ROLE")
+	}
+	user, err := a.lookupUser(args[:1])
+	if err != nil {
+		return err
+	}
+	roleID, err := a.resolveRole(args[1])
+	if err != nil {
+		return err
+	}
+
+	if err := a.svc.Roles.AssignRole(a.ctx, user.KorisnikID, roleID); err != nil {
+		return err
+	}
+	fmt.Printf("user %s now has role %s\n", user.KorisnickoIme, args[1])
+	return nil
+}
+
+// importPolicy applies a permission policy file of earlier versions, by
+// default auth.policy_path, to the roles it names.
+func (a *admin) importPolicy(args []string) error {
+	path := a.cfg.Auth.PolicyPath
+	switch len(args) {
+	case 0:
+	case 1:
+		path = args[0]
+	default:
+		return fmt.Errorf("expected at most one FILE")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var policy services.Policy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return fmt.Errorf("policy file %s: %w", path, err)
+	}
+
+	if err := a.svc.Roles.UpdatePolicy(a.ctx, policy); err != nil {
+		return err
+	}
+	fmt.Printf("permissions of %d roles imported from %s; the file is no longer read and can be removed\n", len(policy.Roles), path)
+	return nil
+}
+
+// findRole finds a role, retired or not, by ID or name.
+func (a *admin) findRole(role string) (*models.Role, error) {
+	roles, err := a.svc.Roles.GetRoles(a.ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	id, idErr := strconv.Atoi(role)
+	for _, r := range roles {
+		if (idErr == nil && r.UlogaID == id) || strings.EqualFold(r.NazivUloge, role) {
+			return &r, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown role %q (see riis-admin role list)", role)
+}
+
+// parseGrants reads "task.view,document.update@12".
+func parseGrants(list string) ([]models.RoleGrant, error) {
+	grants := []models.RoleGrant{}
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		perm, project, scoped := strings.Cut(item, "@")
+		grant := models.RoleGrant{Dozvola: perm}
+		if scoped {
+			id, err := strconv.Atoi(project)
+			if err != nil {
+				return nil, fmt.Errorf("permission %q: project must be an ID", item)
+			}
+			grant.ProjekatID = &id
+		}
+		grants = append(grants, grant)
+	}
+	return grants, nil
+}
+
+func formatGrants(grants []models.RoleGrant) string {
+	if len(grants) == 0 {
+		return "-"
+	}
+
+	parts := make([]string, 0, len(grants))
+	for _, grant := range grants {
+		if grant.ProjekatID == nil {
+			parts = append(parts, grant.Dozvola)
+		} else {
+			parts = append(parts, fmt.Sprintf("%s@%d", grant.Dozvola, *grant.ProjekatID))
+		}
+	}
+	return strings.Join(parts, ",")
+}
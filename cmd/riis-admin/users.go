@@ -0,0 +1,384 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cane/research-institute-system/backend/models"
+	"github.com/cane/research-institute-system/backend/services"
+)
+
+func (a *admin) user(args []string) error {
+	if len(args) == 0 {
+		usage()
+		return fmt.Errorf("missing user command")
+	}
+
+	switch args[0] {
+	case "list":
+		return a.listUsers(args[1:])
+	case "create":
+		return a.createUser(args[1:])
+	case "reset-password":
+		return a.resetPassword(args[1:])
+	case "activate":
+		return a.setStatus(args[1:], "aktivan")
+	case "deactivate":
+		return a.setStatus(args[1:], "neaktivan")
+	case "unlock":
+		return a.unlockUser(args[1:])
+	case "reset-2fa":
+		return a.resetTwoFactor(args[1:])
+	case "offboard":
+		return a.offboardUser(args[1:])
+	case "import":
+		return a.importUsers(args[1:])
+	case "export":
+		return a.exportUsers(args[1:])
+	default:
+		usage()
+		return fmt.Errorf("unknown user command %q", args[0])
+	}
+}
+
+func (a *admin) listUsers(args []string) error {
+	flags := flag.NewFlagSet("user list", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print JSON instead of a table")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	users, err := a.svc.Users.GetAllUsers(a.ctx)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(users)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tUSERNAME\tEMAIL\tROLE\tSTATUS\tLAST LOGIN")
+	for _, u := range users {
+		lastLogin := "never"
+		if u.PoslednajaPrijava != nil {
+			lastLogin = u.PoslednajaPrijava.Format("2006-01-02 15:04")
+		}
+		status := u.Status
+		if u.ZakljucanDo != nil && time.Now().Before(*u.ZakljucanDo) {
+			status += " (locked)"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", u.KorisnikID, u.KorisnickoIme, u.Email, u.NazivUloge, status, lastLogin)
+	}
+	return w.Flush()
+}
+
+func (a *admin) createUser(args []string) error {
+	flags := flag.NewFlagSet("user create", flag.ContinueOnError)
+	email := flags.String("email", "", "e-mail address (required)")
+	role := flags.String("role", "", "role name or ID (required)")
+	first := flags.String("first", "", "first name")
+	last := flags.String("last", "", "last name")
+	fromStdin := flags.Bool("password-stdin", false, "read the password from the first line of standard input")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("user create needs exactly one USERNAME")
+	}
+	if *role == "" {
+		return fmt.Errorf("-role is required")
+	}
+
+	roleID, err := a.resolveRole(*role)
+	if err != nil {
+		return err
+	}
+
+	user := &models.User{KorisnickoIme: flags.Arg(0), Email: *email, UlogaID: roleID}
+	if *first != "" {
+		user.Ime = first
+	}
+	if *last != "" {
+		user.Prezime = last
+	}
+
+	password := ""
+	if *fromStdin {
+		if password, err = readPassword(os.Stdin); err != nil {
+			return err
+		}
+	}
+
+	activation, err := a.svc.Auth.CreateUser(a.ctx, user)
+	if err != nil {
+		return err
+	}
+	if password == "" {
+		fmt.Printf("created user %s (ID %d)\n", user.KorisnickoIme, user.KorisnikID)
+		printActivation(activation)
+		return nil
+	}
+
+	// A password given by the operator replaces the activation step
+	if err := a.svc.Auth.ChangePassword(a.ctx, user.KorisnikID, password, ""); err != nil {
+		return fmt.Errorf("user %s created, but the password was not set (use user reset-password): %w", user.KorisnickoIme, err)
+	}
+	fmt.Printf("created user %s (ID %d)\n", user.KorisnickoIme, user.KorisnikID)
+	return nil
+}
+
+func (a *admin) resetPassword(args []string) error {
+	flags := flag.NewFlagSet("user reset-password", flag.ContinueOnError)
+	fromStdin := flags.Bool("password-stdin", false, "read the new password from the first line of standard input")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	user, err := a.lookupUser(flags.Args())
+	if err != nil {
+		return err
+	}
+
+	if *fromStdin {
+		password, err := readPassword(os.Stdin)
+		if err != nil {
+			return err
+		}
+		if err := a.svc.Auth.ChangePassword(a.ctx, user.KorisnikID, password, ""); err != nil {
+			return err
+		}
+		fmt.Printf("password of %s changed\n", user.KorisnickoIme)
+		return nil
+	}
+
+	activation, err := a.svc.Auth.ResetPassword(a.ctx, user.KorisnikID)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("password of %s reset\n", user.KorisnickoIme)
+	printActivation(activation)
+	return nil
+}
+
+// printActivation shows the one-time code the user signs in with; it cannot
+// be displayed again.
+func printActivation(activation *services.ActivationCode) {
+	fmt.Printf("activation code: %s (valid until %s)\n", activation.Kod, activation.Istice.Format("2006-01-02 15:04"))
+}
+
+// setStatus activates or deactivates an account. The app reloads the user on
+// every call, so a deactivated user loses access immediately.
+func (a *admin) setStatus(args []string, status string) error {
+	user, err := a.lookupUser(args)
+	if err != nil {
+		return err
+	}
+
+	if user.Status == status {
+		fmt.Printf("user %s is already %s\n", user.KorisnickoIme, status)
+		return nil
+	}
+
+	user.Status = status
+	if err := a.svc.Users.UpdateUser(a.ctx, user.KorisnikID, *user); err != nil {
+		return err
+	}
+	fmt.Printf("user %s is now %s\n", user.KorisnickoIme, status)
+	return nil
+}
+
+// unlockUser lifts a lockout caused by failed sign-ins before it expires.
+func (a *admin) unlockUser(args []string) error {
+	user, err := a.lookupUser(args)
+	if err != nil {
+		return err
+	}
+
+	if err := a.svc.Auth.UnlockAccount(a.ctx, user.KorisnikID); err != nil {
+		return err
+	}
+	fmt.Printf("user %s unlocked\n", user.KorisnickoIme)
+	return nil
+}
+
+// resetTwoFactor removes the second factor of a user who lost the
+// authenticator app and the recovery codes.
+func (a *admin) resetTwoFactor(args []string) error {
+	user, err := a.lookupUser(args)
+	if err != nil {
+		return err
+	}
+
+	if err := a.svc.Auth.ResetTwoFactor(a.ctx, user.KorisnikID); err != nil {
+		return err
+	}
+	fmt.Printf("second factor of user %s removed\n", user.KorisnickoIme)
+	return nil
+}
+
+// offboardUser deactivates a user who leaves and hands the user's work over.
+func (a *admin) offboardUser(args []string) error {
+	flags := flag.NewFlagSet("user offboard", flag.ContinueOnError)
+	leader := flags.String("leader", "", "user who takes over the projects the user leads")
+	assignee := flags.String("assignee", "", "user who takes over the user's open tasks")
+	owner := flags.String("owner", "", "user who takes over the user's folders and documents")
+	asJSON := flags.Bool("json", false, "print the report as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	user, err := a.lookupUser(flags.Args())
+	if err != nil {
+		return err
+	}
+
+	var handover models.Handover
+	for _, target := range []struct {
+		username string
+		id       **int
+	}{
+		{*leader, &handover.RukovodilacID},
+		{*assignee, &handover.IzvrsilacID},
+		{*owner, &handover.VlasnikID},
+	} {
+		if target.username == "" {
+			continue
+		}
+		successor, err := a.lookupUser([]string{target.username})
+		if err != nil {
+			return err
+		}
+		*target.id = &successor.KorisnikID
+	}
+
+	report, err := a.svc.Auth.OffboardUser(a.ctx, user.KorisnikID, handover)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(report)
+	}
+	fmt.Printf("user %s deactivated; handed over %d projects, %d tasks, %d folders, %d documents; revoked %d access tokens\n",
+		user.KorisnickoIme, len(report.Projekti), len(report.Zadaci), len(report.Folderi), len(report.Dokumenti), report.OpozvaniTokeni)
+	return nil
+}
+
+// importUsers creates users from a CSV file. A file with invalid rows is
+// rejected as a whole unless -per-row is given, and the command fails.
+func (a *admin) importUsers(args []string) error {
+	flags := flag.NewFlagSet("user import", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "only check the file")
+	perRow := flags.Bool("per-row", false, "create the valid rows even if other rows are invalid")
+	asJSON := flags.Bool("json", false, "print the report as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("user import needs exactly one FILE")
+	}
+
+	var input io.Reader = os.Stdin
+	if name := flags.Arg(0); name != "-" {
+		file, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		input = file
+	}
+
+	report, err := a.svc.Auth.ImportUsers(a.ctx, input, services.UserImportOptions{DryRun: *dryRun, PerRow: *perRow})
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		if err := printJSON(report); err != nil {
+			return err
+		}
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "LINE\tUSERNAME\tRESULT")
+		for _, row := range report.Redovi {
+			result := "ok"
+			switch {
+			case len(row.Greske) > 0:
+				result = strings.Join(row.Greske, "; ")
+			case row.Aktivacija != nil:
+				result = "activation code " + row.Aktivacija.Kod + ", valid until " + row.Aktivacija.Istice.Format("2006-01-02 15:04")
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", row.Red, row.KorisnickoIme, result)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		fmt.Printf("%d of %d rows valid, %d users created\n", report.Ispravni, len(report.Redovi), report.Kreirani)
+	}
+
+	if report.Ispravni < len(report.Redovi) {
+		return fmt.Errorf("%d invalid rows", len(report.Redovi)-report.Ispravni)
+	}
+	return nil
+}
+
+// exportUsers prints all users with their roles as CSV.
+func (a *admin) exportUsers(args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("user export takes no arguments")
+	}
+
+	data, err := a.svc.Users.ExportUsers(a.ctx)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(data)
+	return err
+}
+
+// lookupUser finds the account named by the single USERNAME argument.
+func (a *admin) lookupUser(args []string) (*models.User, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("expected exactly one USERNAME")
+	}
+
+	user, err := a.stores.Users.GetByUsername(a.ctx, args[0])
+	if err != nil {
+		return nil, fmt.Errorf("user %q: %w", args[0], err)
+	}
+	return user, nil
+}
+
+// resolveRole accepts a role ID or a role name.
+func (a *admin) resolveRole(role string) (int, error) {
+	roles, err := a.svc.Users.GetAllRoles(a.ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	id, idErr := strconv.Atoi(role)
+	for _, r := range roles {
+		if (idErr == nil && r.UlogaID == id) || strings.EqualFold(r.NazivUloge, role) {
+			return r.UlogaID, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown role %q (see riis-admin role list)", role)
+}
+
+func readPassword(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", fmt.Errorf("no password on standard input")
+	}
+	return password, nil
+}
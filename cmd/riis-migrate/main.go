@@ -0,0 +1,118 @@
+// Command riis-migrate inspects and changes the schema version of the
+// institute database using the migrations embedded in the binary.
+//
+//	riis-migrate status           list migrations and whether they are applied
+//	riis-migrate up               apply all pending migrations
+//	riis-migrate down [-steps N]  roll back the last N migrations (default 1)
+//	riis-migrate down -to V       roll back every migration newer than V
+//
+// Connection settings come from riis.json, the DB_* environment variables or
+// .env; -config FILE and -set key=value may precede the command.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/cane/research-institute-system/backend/config"
+	"github.com/cane/research-institute-system/backend/migrations"
+	"github.com/cane/research-institute-system/database"
+
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+)
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "riis-migrate:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: riis-migrate [-config FILE] [-set key=value] status | up | down [-steps N | -to VERSION]")
+}
+
+func run(args []string) error {
+	_ = godotenv.Load()
+
+	cfg, args, err := config.Load(args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		usage()
+		return fmt.Errorf("missing command")
+	}
+
+	db, err := cfg.Database.Open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := migrations.New(db, database.Migrations, "migrations")
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "status":
+		return status(ctx, migrator)
+	case "up":
+		applied, err := migrator.Up(ctx)
+		report("applied", applied)
+		return err
+	case "down":
+		flags := flag.NewFlagSet("down", flag.ContinueOnError)
+		steps := flags.Int("steps", 1, "number of migrations to roll back")
+		to := flags.Int("to", -1, "roll back every migration newer than this version")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		var rolledBack []migrations.Migration
+		if *to >= 0 {
+			rolledBack, err = migrator.DownTo(ctx, *to)
+		} else {
+			rolledBack, err = migrator.Down(ctx, *steps)
+		}
+		report("rolled back", rolledBack)
+		return err
+	default:
+		usage()
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+func status(ctx context.Context, migrator *migrations.Migrator) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+	for _, s := range statuses {
+		applied := "pending"
+		if s.AppliedAt != nil {
+			applied = s.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, applied)
+	}
+	return w.Flush()
+}
+
+func report(action string, done []migrations.Migration) {
+	if len(done) == 0 {
+		fmt.Printf("nothing %s\n", action)
+		return
+	}
+	for _, m := range done {
+		fmt.Printf("%s %04d_%s\n", action, m.Version, m.Name)
+	}
+}
@@ -0,0 +1,154 @@
+// Command server runs the REST API of the institute system without the
+// desktop app. It uses the same services, role permissions and upload
+// directory as the Wails build; see package backend/api for the protocol.
+//
+//	server [-config FILE] [-set key=value ...]
+//
+// It listens on server.addr (API_ADDR, default :8080). Settings come from
+// riis.json, the environment or .env, and -set; see package backend/config.
+// The server refuses to start while migrations are pending, unless
+// database.auto_migrate (DB_AUTO_MIGRATE=true) lets it apply them.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/cane/research-institute-system/backend/api"
+	"github.com/cane/research-institute-system/backend/config"
+	"github.com/cane/research-institute-system/backend/logging"
+	"github.com/cane/research-institute-system/backend/migrations"
+	"github.com/cane/research-institute-system/backend/repositories"
+	"github.com/cane/research-institute-system/backend/schemacheck"
+	"github.com/cane/research-institute-system/backend/services"
+	"github.com/cane/research-institute-system/database"
+
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+)
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "server:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	_ = godotenv.Load()
+
+	cfg, rest, err := config.Load(args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("unexpected arguments %q", rest)
+	}
+
+	_, logFile, err := logging.Setup(cfg.Log)
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	db, err := cfg.Database.Open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := db.PingContext(ctx); err != nil {
+		return fmt.Errorf("database %s is not reachable: %w", cfg.Database.Target(), err)
+	}
+
+	if err := migrate(ctx, db, cfg.Database.AutoMigrate); err != nil {
+		return err
+	}
+
+	report, err := schemacheck.Check(ctx, db)
+	if err != nil {
+		return fmt.Errorf("schema check: %w", err)
+	}
+	for _, problem := range report.Problems {
+		slog.Warn("schema check failed", "query", problem.Query, "problem", problem.Message)
+	}
+
+	stores := repositories.NewPostgresStores(db)
+	authz, err := services.NewAuthorizer(ctx, stores.Roles)
+	if err != nil {
+		return fmt.Errorf("role permissions: %w", err)
+	}
+	if _, err := os.Stat(cfg.Auth.PolicyPath); err == nil {
+		slog.Warn("permission policy file is no longer read, roles are kept in the database",
+			"path", cfg.Auth.PolicyPath, "hint", "apply it once with riis-admin role import-policy")
+	}
+
+	sessions := services.NewSessionManager(cfg.Auth.SessionIdleTimeout, cfg.Auth.SessionMaxLifetime)
+	svc := services.New(cfg, stores, sessions, authz)
+	go svc.Guests.RunGuestSweep(ctx, cfg.Auth.GuestSweepInterval)
+
+	server := &http.Server{
+		Addr:              cfg.Server.Addr,
+		Handler:           api.New(svc),
+		ReadHeaderTimeout: 10 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		slog.Info("listening", "addr", server.Addr)
+		errc <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// migrate applies pending migrations when auto is set and otherwise refuses
+// to serve an outdated schema; there is nobody to ask as in the desktop app.
+func migrate(ctx context.Context, db *sql.DB, auto bool) error {
+	migrator, err := migrations.New(db, database.Migrations, "migrations")
+	if err != nil {
+		return err
+	}
+
+	pending, err := migrator.Pending(ctx)
+	if err != nil {
+		return err
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	if !auto {
+		return fmt.Errorf("%d pending migration(s); run riis-migrate up or set DB_AUTO_MIGRATE=true", len(pending))
+	}
+
+	applied, err := migrator.Up(ctx)
+	for _, m := range applied {
+		slog.Info("applied migration", "version", m.Version, "name", m.Name)
+	}
+	return err
+}
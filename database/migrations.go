@@ -0,0 +1,10 @@
+// Package database embeds the versioned SQL migrations so every binary carries
+// the schema it expects.
+package database
+
+import "embed"
+
+// Migrations holds the numbered NNNN_name.up.sql / NNNN_name.down.sql pairs.
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
@@ -0,0 +1,25 @@
+-- Reverts 0001_initial_schema: drops every view and table of the initial schema
+
+DROP VIEW IF EXISTS v_zadaci_sa_detaljima;
+DROP VIEW IF EXISTS v_dokumenti_sa_verzijama;
+DROP VIEW IF EXISTS v_aktivni_projekti;
+
+DROP TABLE IF EXISTS LogAktivnosti;
+DROP TABLE IF EXISTS IstorijaFazaDokumenta;
+DROP TABLE IF EXISTS DozvoleDokumenata;
+DROP TABLE IF EXISTS DokumentTagovi;
+DROP TABLE IF EXISTS Tagovi;
+DROP TABLE IF EXISTS MetaPodaci;
+DROP TABLE IF EXISTS LLMSazeci;
+DROP TABLE IF EXISTS VerzijeDokumenata;
+DROP TABLE IF EXISTS Dokumenti;
+DROP TABLE IF EXISTS Folderi;
+DROP TABLE IF EXISTS ZahteviPromeneFaze;
+DROP TABLE IF EXISTS KomentariZadataka;
+DROP TABLE IF EXISTS Zadaci;
+DROP TABLE IF EXISTS ClanoviProjekta;
+DROP TABLE IF EXISTS Projekti;
+DROP TABLE IF EXISTS Faze;
+DROP TABLE IF EXISTS RadniTokovi;
+DROP TABLE IF EXISTS Korisnici;
+DROP TABLE IF EXISTS Uloge;
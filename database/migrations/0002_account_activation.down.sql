@@ -0,0 +1,4 @@
+-- Reverts 0002_account_activation
+
+DROP TABLE IF EXISTS AktivacijeNaloga;
+ALTER TABLE Korisnici DROP COLUMN IF EXISTS mora_promeniti_lozinku;
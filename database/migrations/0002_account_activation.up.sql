@@ -0,0 +1,24 @@
+-- Account activation: admin-created and reset accounts must change their
+-- password before they get a normal session, and sign in for the first time
+-- with a single-use, expiring activation code
+
+ALTER TABLE Korisnici ADD COLUMN mora_promeniti_lozinku BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Accounts that never signed in used to be recognised by a missing last login;
+-- they keep their temporary password but must replace it
+UPDATE Korisnici SET mora_promeniti_lozinku = TRUE WHERE poslednja_prijava IS NULL;
+
+-- Only the SHA-256 hash of a code is stored. A code is used at most once.
+CREATE TABLE AktivacijeNaloga (
+    aktivacija_id SERIAL PRIMARY KEY,
+    korisnik_id INT NOT NULL,
+    hash_koda VARCHAR(64) UNIQUE NOT NULL,
+    istice TIMESTAMP NOT NULL,
+    iskoriscena TIMESTAMP,
+    kreirao_korisnik_id INT,
+    kreiran_datuma TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (korisnik_id) REFERENCES Korisnici(korisnik_id) ON DELETE CASCADE,
+    FOREIGN KEY (kreirao_korisnik_id) REFERENCES Korisnici(korisnik_id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_aktivacije_korisnik ON AktivacijeNaloga(korisnik_id);
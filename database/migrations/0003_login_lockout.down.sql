@@ -0,0 +1,5 @@
+-- Reverts 0003_login_lockout
+
+ALTER TABLE Korisnici DROP COLUMN IF EXISTS zakljucan_do;
+ALTER TABLE Korisnici DROP COLUMN IF EXISTS poslednja_neuspesna_prijava;
+ALTER TABLE Korisnici DROP COLUMN IF EXISTS neuspesne_prijave;
@@ -0,0 +1,7 @@
+-- Brute-force protection: failed sign-ins are counted per account; enough of
+-- them lock the account until zakljucan_do or until an administrator
+-- unlocks it
+
+ALTER TABLE Korisnici ADD COLUMN neuspesne_prijave INT NOT NULL DEFAULT 0;
+ALTER TABLE Korisnici ADD COLUMN poslednja_neuspesna_prijava TIMESTAMP;
+ALTER TABLE Korisnici ADD COLUMN zakljucan_do TIMESTAMP;
@@ -0,0 +1,3 @@
+-- Reverts 0004_password_history
+
+DROP TABLE IF EXISTS IstorijaLozinki;
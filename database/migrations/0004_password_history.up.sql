@@ -0,0 +1,12 @@
+-- Password history: the hashes of the passwords each user has set, so the
+-- password policy can refuse reusing recent ones
+
+CREATE TABLE IstorijaLozinki (
+    istorija_id SERIAL PRIMARY KEY,
+    korisnik_id INT NOT NULL,
+    hash_sifre VARCHAR(255) NOT NULL,
+    kreiran_datuma TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (korisnik_id) REFERENCES Korisnici(korisnik_id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_istorija_lozinki_korisnik ON IstorijaLozinki(korisnik_id);
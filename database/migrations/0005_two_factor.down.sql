@@ -0,0 +1,4 @@
+-- Reverts 0005_two_factor
+
+DROP TABLE IF EXISTS RezervniKodovi;
+DROP TABLE IF EXISTS DvaFaktora;
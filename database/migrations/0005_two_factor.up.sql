@@ -0,0 +1,21 @@
+-- Two-factor authentication: a TOTP secret per account (RFC 6238) and
+-- single-use recovery codes, of which only SHA-256 hashes are stored
+
+CREATE TABLE DvaFaktora (
+    korisnik_id INT PRIMARY KEY,
+    tajna VARCHAR(64) NOT NULL,
+    potvrdjen TIMESTAMP,
+    poslednji_korak BIGINT NOT NULL DEFAULT 0,
+    kreiran_datuma TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (korisnik_id) REFERENCES Korisnici(korisnik_id) ON DELETE CASCADE
+);
+
+CREATE TABLE RezervniKodovi (
+    kod_id SERIAL PRIMARY KEY,
+    korisnik_id INT NOT NULL,
+    hash_koda VARCHAR(64) NOT NULL,
+    iskoriscen TIMESTAMP,
+    FOREIGN KEY (korisnik_id) REFERENCES Korisnici(korisnik_id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_rezervni_kodovi_korisnik ON RezervniKodovi(korisnik_id);
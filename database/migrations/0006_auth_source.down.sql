@@ -0,0 +1,3 @@
+-- Reverts 0006_auth_source
+
+ALTER TABLE Korisnici DROP COLUMN IF EXISTS izvor_prijave;
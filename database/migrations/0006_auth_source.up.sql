@@ -0,0 +1,5 @@
+-- Authentication providers: izvor_prijave names the provider that checks an
+-- account's password. Existing accounts keep theirs in hash_sifre; accounts
+-- provisioned from the LDAP directory are checked there
+
+ALTER TABLE Korisnici ADD COLUMN izvor_prijave VARCHAR(20) NOT NULL DEFAULT 'lokalni';
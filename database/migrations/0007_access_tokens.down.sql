@@ -0,0 +1,3 @@
+-- Reverts 0007_access_tokens
+
+DROP TABLE IF EXISTS PristupniTokeni;
@@ -0,0 +1,19 @@
+-- Personal access tokens for scripted access to the REST API. Only the
+-- SHA-256 hash of a token is stored; opsezi lists the permissions the token
+-- may use, comma separated
+
+CREATE TABLE PristupniTokeni (
+    token_id SERIAL PRIMARY KEY,
+    korisnik_id INT NOT NULL,
+    naziv VARCHAR(100) NOT NULL,
+    prefiks VARCHAR(16) NOT NULL,
+    hash_tokena VARCHAR(64) NOT NULL UNIQUE,
+    opsezi TEXT NOT NULL,
+    istice TIMESTAMP NOT NULL,
+    poslednje_korisceno TIMESTAMP,
+    opozvan TIMESTAMP,
+    kreiran_datuma TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (korisnik_id) REFERENCES Korisnici(korisnik_id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_pristupni_tokeni_korisnik ON PristupniTokeni(korisnik_id);
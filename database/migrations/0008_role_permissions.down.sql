@@ -0,0 +1,8 @@
+-- Reverts 0008_role_permissions
+
+DROP TABLE IF EXISTS DozvoleUloga;
+
+ALTER TABLE Uloge
+    DROP COLUMN IF EXISTS opis,
+    DROP COLUMN IF EXISTS povucena,
+    DROP COLUMN IF EXISTS verzija;
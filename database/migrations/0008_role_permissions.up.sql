@@ -0,0 +1,72 @@
+-- Custom roles: the permissions of each role are stored in DozvoleUloga
+-- instead of the policy file. A grant with projekat_id applies only within
+-- that project. Retired roles (povucena) can no longer be assigned; verzija
+-- is raised with every change of a role or its grants, so running processes
+-- notice the change
+
+ALTER TABLE Uloge
+    ADD COLUMN opis TEXT,
+    ADD COLUMN povucena TIMESTAMP,
+    ADD COLUMN verzija INT NOT NULL DEFAULT 1;
+
+CREATE TABLE DozvoleUloga (
+    dozvola_uloge_id SERIAL PRIMARY KEY,
+    uloga_id INT NOT NULL,
+    dozvola VARCHAR(50) NOT NULL,
+    projekat_id INT,
+    FOREIGN KEY (uloga_id) REFERENCES Uloge(uloga_id) ON DELETE CASCADE,
+    FOREIGN KEY (projekat_id) REFERENCES Projekti(projekat_id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX idx_dozvole_uloga_jedinstvene ON DozvoleUloga(uloga_id, dozvola, COALESCE(projekat_id, 0));
+CREATE INDEX idx_dozvole_uloga_projekat ON DozvoleUloga(projekat_id);
+
+-- The grants of the earlier built-in policy
+INSERT INTO DozvoleUloga (uloga_id, dozvola)
+SELECT u.uloga_id, d.dozvola
+FROM Uloge u
+JOIN (VALUES
+    ('Administrator', '*'),
+
+    ('Rukovodilac projekta', 'user.view'),
+    ('Rukovodilac projekta', 'project.view'),
+    ('Rukovodilac projekta', 'project.create'),
+    ('Rukovodilac projekta', 'project.update'),
+    ('Rukovodilac projekta', 'project.delete'),
+    ('Rukovodilac projekta', 'project.members'),
+    ('Rukovodilac projekta', 'task.view'),
+    ('Rukovodilac projekta', 'task.create'),
+    ('Rukovodilac projekta', 'task.update'),
+    ('Rukovodilac projekta', 'task.delete'),
+    ('Rukovodilac projekta', 'task.comment'),
+    ('Rukovodilac projekta', 'workflow.view'),
+    ('Rukovodilac projekta', 'workflow.manage'),
+    ('Rukovodilac projekta', 'document.view'),
+    ('Rukovodilac projekta', 'document.upload'),
+    ('Rukovodilac projekta', 'document.update'),
+    ('Rukovodilac projekta', 'document.delete'),
+    ('Rukovodilac projekta', 'analytics.view'),
+
+    ('Organizator projekta', 'user.view'),
+    ('Organizator projekta', 'project.view'),
+    ('Organizator projekta', 'project.update'),
+    ('Organizator projekta', 'project.members'),
+    ('Organizator projekta', 'task.view'),
+    ('Organizator projekta', 'task.create'),
+    ('Organizator projekta', 'task.update'),
+    ('Organizator projekta', 'task.comment'),
+    ('Organizator projekta', 'workflow.view'),
+    ('Organizator projekta', 'document.view'),
+    ('Organizator projekta', 'document.upload'),
+    ('Organizator projekta', 'document.update'),
+    ('Organizator projekta', 'analytics.view'),
+
+    ('Istrazivac', 'project.view'),
+    ('Istrazivac', 'task.view'),
+    ('Istrazivac', 'task.update'),
+    ('Istrazivac', 'task.comment'),
+    ('Istrazivac', 'workflow.view'),
+    ('Istrazivac', 'document.view'),
+    ('Istrazivac', 'document.upload'),
+    ('Istrazivac', 'document.update')
+) AS d (naziv_uloge, dozvola) ON d.naziv_uloge = u.naziv_uloge;
@@ -0,0 +1,4 @@
+-- Reverts 0009_user_profile
+
+DROP TABLE IF EXISTS PromeneEmaila;
+DROP TABLE IF EXISTS PodesavanjaKorisnika;
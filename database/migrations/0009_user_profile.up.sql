@@ -0,0 +1,26 @@
+-- Self-service profile: preferences every client shares, and changes of
+-- email address that wait for a code sent to the new address
+
+CREATE TABLE PodesavanjaKorisnika (
+    korisnik_id INT PRIMARY KEY,
+    jezik VARCHAR(10) NOT NULL DEFAULT 'sr',
+    podrazumevani_projekat_id INT,
+    format_datuma VARCHAR(20) NOT NULL DEFAULT 'dd.MM.yyyy',
+    obavestenja_email BOOLEAN NOT NULL DEFAULT TRUE,
+    obavestenja_zadaci BOOLEAN NOT NULL DEFAULT TRUE,
+    obavestenja_komentari BOOLEAN NOT NULL DEFAULT TRUE,
+    izmenjeno TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (korisnik_id) REFERENCES Korisnici(korisnik_id) ON DELETE CASCADE,
+    FOREIGN KEY (podrazumevani_projekat_id) REFERENCES Projekti(projekat_id) ON DELETE SET NULL
+);
+
+-- One pending change per user. Only the SHA-256 hash of the code is stored.
+CREATE TABLE PromeneEmaila (
+    korisnik_id INT PRIMARY KEY,
+    novi_email VARCHAR(100) NOT NULL,
+    hash_koda VARCHAR(64) NOT NULL,
+    istice TIMESTAMP NOT NULL,
+    pokusaji INT NOT NULL DEFAULT 0,
+    kreiran_datuma TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (korisnik_id) REFERENCES Korisnici(korisnik_id) ON DELETE CASCADE
+);
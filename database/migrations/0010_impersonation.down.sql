@@ -0,0 +1,4 @@
+-- Reverts 0010_impersonation
+
+DROP INDEX IF EXISTS idx_log_stvarni_korisnik;
+ALTER TABLE LogAktivnosti DROP COLUMN IF EXISTS stvarni_korisnik_id;
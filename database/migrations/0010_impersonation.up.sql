@@ -0,0 +1,8 @@
+-- Impersonation: while an administrator acts as another user, korisnik_id
+-- of an activity is the user acted as and stvarni_korisnik_id the
+-- administrator who actually did it
+
+ALTER TABLE LogAktivnosti
+    ADD COLUMN stvarni_korisnik_id INT REFERENCES Korisnici(korisnik_id);
+
+CREATE INDEX idx_log_stvarni_korisnik ON LogAktivnosti(stvarni_korisnik_id);
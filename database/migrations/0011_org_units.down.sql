@@ -0,0 +1,11 @@
+-- Reverts 0011_org_units
+
+UPDATE Uloge SET verzija = verzija + 1
+WHERE uloga_id IN (SELECT uloga_id FROM DozvoleUloga WHERE dozvola IN ('unit.view', 'unit.manage'));
+
+DELETE FROM DozvoleUloga WHERE dozvola IN ('unit.view', 'unit.manage');
+
+ALTER TABLE Projekti DROP COLUMN IF EXISTS jedinica_id;
+ALTER TABLE Korisnici DROP COLUMN IF EXISTS jedinica_id;
+
+DROP TABLE IF EXISTS OrganizacioneJedinice;
@@ -0,0 +1,35 @@
+-- Organizational units: the institute, its departments, labs and groups in
+-- one hierarchy. Every user and project belongs to at most one unit; heads
+-- of a unit see the projects of its whole subtree
+
+CREATE TABLE OrganizacioneJedinice (
+    jedinica_id SERIAL PRIMARY KEY,
+    naziv VARCHAR(100) NOT NULL UNIQUE,
+    tip VARCHAR(20) NOT NULL DEFAULT 'odeljenje'
+        CHECK (tip IN ('institut', 'odeljenje', 'laboratorija', 'grupa')),
+    nadredjena_jedinica_id INT,
+    rukovodilac_id INT,
+    kreiran_datuma TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (nadredjena_jedinica_id) REFERENCES OrganizacioneJedinice(jedinica_id),
+    FOREIGN KEY (rukovodilac_id) REFERENCES Korisnici(korisnik_id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_jedinice_nadredjena ON OrganizacioneJedinice(nadredjena_jedinica_id);
+CREATE INDEX idx_jedinice_rukovodilac ON OrganizacioneJedinice(rukovodilac_id);
+
+ALTER TABLE Korisnici
+    ADD COLUMN jedinica_id INT REFERENCES OrganizacioneJedinice(jedinica_id) ON DELETE SET NULL;
+ALTER TABLE Projekti
+    ADD COLUMN jedinica_id INT REFERENCES OrganizacioneJedinice(jedinica_id) ON DELETE SET NULL;
+
+CREATE INDEX idx_korisnici_jedinica ON Korisnici(jedinica_id);
+CREATE INDEX idx_projekti_jedinica ON Projekti(jedinica_id);
+
+-- Everyone who sees projects may see the units they belong to
+INSERT INTO DozvoleUloga (uloga_id, dozvola)
+SELECT DISTINCT uloga_id, 'unit.view'
+FROM DozvoleUloga
+WHERE dozvola = 'project.view' AND projekat_id IS NULL;
+
+UPDATE Uloge SET verzija = verzija + 1
+WHERE uloga_id IN (SELECT uloga_id FROM DozvoleUloga WHERE dozvola = 'unit.view');
@@ -0,0 +1,13 @@
+-- Reverts 0012_guest_accounts
+
+DROP TABLE IF EXISTS GostujuciPristup;
+
+ALTER TABLE Korisnici DROP COLUMN IF EXISTS gost_do;
+
+-- Only the Gost role the up migration inserted, recognised by its
+-- description, and only while nobody holds it
+DELETE FROM Uloge
+WHERE naziv_uloge = 'Gost'
+  AND opis = 'Spoljni partner: vidi samo ono što mu je podeljeno, do isteka naloga'
+  AND NOT EXISTS (SELECT 1 FROM Korisnici WHERE uloga_id = Uloge.uloga_id)
+  AND NOT EXISTS (SELECT 1 FROM DozvoleUloga WHERE uloga_id = Uloge.uloga_id);
@@ -0,0 +1,32 @@
+-- Guest accounts of external project partners: gost_do marks a guest and
+-- ends its access. A guest sees only the projects, folders and documents
+-- shared with it in GostujuciPristup; the Gost role grants nothing else
+
+ALTER TABLE Korisnici ADD COLUMN gost_do TIMESTAMP;
+
+CREATE INDEX idx_korisnici_gost_do ON Korisnici(gost_do) WHERE gost_do IS NOT NULL;
+
+CREATE TABLE GostujuciPristup (
+    pristup_id SERIAL PRIMARY KEY,
+    korisnik_id INT NOT NULL,
+    projekat_id INT,
+    folder_id INT,
+    dokument_id INT,
+    odobrio_id INT,
+    kreiran_datuma TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (num_nonnulls(projekat_id, folder_id, dokument_id) = 1),
+    FOREIGN KEY (korisnik_id) REFERENCES Korisnici(korisnik_id) ON DELETE CASCADE,
+    FOREIGN KEY (projekat_id) REFERENCES Projekti(projekat_id) ON DELETE CASCADE,
+    FOREIGN KEY (folder_id) REFERENCES Folderi(folder_id) ON DELETE CASCADE,
+    FOREIGN KEY (dokument_id) REFERENCES Dokumenti(dokument_id) ON DELETE CASCADE,
+    FOREIGN KEY (odobrio_id) REFERENCES Korisnici(korisnik_id) ON DELETE SET NULL
+);
+
+CREATE UNIQUE INDEX idx_gost_projekat ON GostujuciPristup(korisnik_id, projekat_id) WHERE projekat_id IS NOT NULL;
+CREATE UNIQUE INDEX idx_gost_folder ON GostujuciPristup(korisnik_id, folder_id) WHERE folder_id IS NOT NULL;
+CREATE UNIQUE INDEX idx_gost_dokument ON GostujuciPristup(korisnik_id, dokument_id) WHERE dokument_id IS NOT NULL;
+
+-- An existing role named Gost becomes the guest role
+INSERT INTO Uloge (naziv_uloge, opis)
+VALUES ('Gost', 'Spoljni partner: vidi samo ono što mu je podeljeno, do isteka naloga')
+ON CONFLICT (naziv_uloge) DO NOTHING;
@@ -0,0 +1,3 @@
+-- Reverts 0013_system_roles
+
+ALTER TABLE Uloge DROP COLUMN IF EXISTS sistemska;
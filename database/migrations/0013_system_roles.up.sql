@@ -0,0 +1,17 @@
+-- System roles: sistemska is a stable key of the roles the application
+-- relies on, so it finds them whatever they are called. System roles keep
+-- their name and cannot be retired; their permissions can still change.
+-- The built-in roles are found by the names 0001 and 0012 gave them
+
+ALTER TABLE Uloge ADD COLUMN sistemska VARCHAR(30) UNIQUE;
+
+UPDATE Uloge u
+SET sistemska = k.sistemska
+FROM (VALUES
+    ('Administrator', 'administrator'),
+    ('Rukovodilac projekta', 'rukovodilac'),
+    ('Istrazivac', 'istrazivac'),
+    ('Organizator projekta', 'organizator'),
+    ('Gost', 'gost')
+) AS k (naziv_uloge, sistemska)
+WHERE k.naziv_uloge = u.naziv_uloge;
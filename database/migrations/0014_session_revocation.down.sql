@@ -0,0 +1,3 @@
+-- Reverts 0014_session_revocation
+
+ALTER TABLE Korisnici DROP COLUMN IF EXISTS sesije_nevazece_od;
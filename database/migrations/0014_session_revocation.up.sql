@@ -0,0 +1,6 @@
+-- Sessions live in the memory of each process (desktop application, REST
+-- server). sesije_nevazece_od ends the user's sessions opened before it in
+-- every process at their next request, so a password change, reset or
+-- offboarding in one process also signs the user out of the others
+
+ALTER TABLE Korisnici ADD COLUMN sesije_nevazece_od TIMESTAMP;
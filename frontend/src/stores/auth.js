@@ -1,6 +1,12 @@
 import { defineStore } from 'pinia'
 import { ref, computed } from 'vue'
-import { Login, Logout, GetCurrentUser, TestConnection, CompleteFirstTimeSetup } from '../../wailsjs/go/main/App.js'
+import { Login, Logout, GetCurrentUser, TestConnection, CompleteFirstTimeSetup, VerifyTwoFactor, EnrollTwoFactor, ConfirmTwoFactor } from '../../wailsjs/go/main/App.js'
+
+// Messages of a login that still needs the second factor
+const twoFactorModes = {
+  TWO_FACTOR_REQUIRED: 'verify',
+  TWO_FACTOR_SETUP_REQUIRED: 'setup'
+}
 
 export const useAuthStore = defineStore('auth', () => {
   // State
@@ -31,7 +37,15 @@ export const useAuthStore = defineStore('auth', () => {
       
       console.log('Login response:', response)
       
-      if (response && response.success) {
+      if (response && response.success && twoFactorModes[response.message]) {
+        // The password is verified, but the session is not usable until
+        // the second factor is
+        return {
+          success: true,
+          message: response.message,
+          twoFactor: twoFactorModes[response.message]
+        }
+      } else if (response && response.success) {
         user.value = response.user
         
         // Store user data in localStorage for persistence
@@ -130,12 +144,14 @@ export const useAuthStore = defineStore('auth', () => {
       if (result && result.success) {
         return { 
           success: true, 
-          message: result.message 
+          message: result.message,
+          twoFactor: twoFactorModes[result.message] || null
         }
       } else {
         return { 
           success: false, 
-          error: result?.message || 'Greška pri postavljanju lozinke' 
+          error: result?.message || 'Greška pri postavljanju lozinke',
+          violations: result?.violations || []
         }
       }
     } catch (err) {
@@ -147,6 +163,44 @@ export const useAuthStore = defineStore('auth', () => {
     }
   }
 
+  async function verifyTwoFactor(code) {
+    try {
+      const response = await VerifyTwoFactor(code)
+      if (response && response.success) {
+        user.value = response.user
+        localStorage.setItem('user', JSON.stringify(user.value))
+        return { success: true }
+      }
+      return { success: false, error: response?.message || 'Neispravan kod' }
+    } catch (err) {
+      console.error('Second factor error:', err)
+      return { success: false, error: String(err) }
+    }
+  }
+
+  async function enrollTwoFactor() {
+    try {
+      return { success: true, enrollment: await EnrollTwoFactor() }
+    } catch (err) {
+      console.error('Second factor enrollment error:', err)
+      return { success: false, error: String(err) }
+    }
+  }
+
+  async function confirmTwoFactor(code) {
+    try {
+      const setup = await ConfirmTwoFactor(code)
+      if (setup.prijava) {
+        user.value = setup.prijava.user
+        localStorage.setItem('user', JSON.stringify(user.value))
+      }
+      return { success: true, recoveryCodes: setup.rezervni_kodovi }
+    } catch (err) {
+      console.error('Second factor confirmation error:', err)
+      return { success: false, error: String(err) }
+    }
+  }
+
   return {
     user,
     isLoading,
@@ -159,6 +213,9 @@ export const useAuthStore = defineStore('auth', () => {
     getCurrentUser,
     testBackendConnection,
     completeFirstTimeSetup,
+    verifyTwoFactor,
+    enrollTwoFactor,
+    confirmTwoFactor,
     initializeAuth
   }
 })
@@ -1,36 +1,205 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
-import {models} from '../models';
 import {services} from '../models';
+import {models} from '../models';
+import {time} from '../models';
+
+export function AddDocumentTag(arg1:number,arg2:string):Promise<void>;
+
+export function AddProjectMember(arg1:number,arg2:number):Promise<void>;
+
+export function AddTaskComment(arg1:number,arg2:string):Promise<void>;
+
+export function AssignRole(arg1:number,arg2:number):Promise<void>;
+
+export function CancelEmailChange():Promise<void>;
+
+export function ChangePassword(arg1:string,arg2:string):Promise<void>;
+
+export function CheckPassword(arg1:string):Promise<Array<services.PasswordViolation>>;
+
+export function CloneRole(arg1:number,arg2:string):Promise<models.Uloge>;
 
 export function CompleteFirstTimeSetup(arg1:string,arg2:string):Promise<Record<string, any>>;
 
-export function CreateProject(arg1:models.Projekti):Promise<void>;
+export function ConfirmEmailChange(arg1:string):Promise<models.Korisnici>;
+
+export function ConfirmTwoFactor(arg1:string):Promise<services.TwoFactorSetup>;
 
-export function CreateUser(arg1:models.Korisnici,arg2:string):Promise<void>;
+export function CreateAccessToken(arg1:services.AccessTokenRequest):Promise<services.NewAccessToken>;
+
+export function CreateFolder(arg1:models.Folderi):Promise<void>;
+
+export function CreateGuest(arg1:services.GuestRequest):Promise<services.ActivationCode>;
+
+export function CreatePhase(arg1:models.Faze):Promise<void>;
+
+export function CreateProject(arg1:models.CreateProjectRequest):Promise<models.Projekti>;
+
+export function CreateRole(arg1:services.RoleRequest):Promise<models.Uloge>;
+
+export function CreateTask(arg1:models.CreateTaskRequest):Promise<void>;
+
+export function CreateUnit(arg1:services.UnitRequest):Promise<models.OrganizacioneJedinice>;
+
+export function CreateUser(arg1:models.Korisnici):Promise<services.ActivationCode>;
+
+export function CreateWorkflow(arg1:models.RadniTokovi):Promise<void>;
 
 export function DeleteDocument(arg1:number):Promise<void>;
 
+export function DeleteProject(arg1:number):Promise<void>;
+
+export function DeleteTask(arg1:number):Promise<void>;
+
+export function DeleteUnit(arg1:number):Promise<void>;
+
+export function DeleteUser(arg1:number):Promise<void>;
+
+export function EnrollTwoFactor():Promise<services.TwoFactorEnrollment>;
+
+export function ExportUsers():Promise<string>;
+
+export function ExtendGuest(arg1:number,arg2:time.Time):Promise<void>;
+
+export function GetActiveSessions():Promise<Array<services.Session>>;
+
+export function GetActivityLogs(arg1:number):Promise<Array<models.LogAktivnosti>>;
+
 export function GetAllDocuments():Promise<Array<models.Dokumenti>>;
 
+export function GetAllPermissions():Promise<Array<services.Permission>>;
+
+export function GetAllProjects():Promise<Array<models.Projekti>>;
+
+export function GetAllRoles():Promise<Array<models.Uloge>>;
+
 export function GetAllUsers():Promise<Array<models.Korisnici>>;
 
+export function GetAllWorkflows():Promise<Array<models.RadniTokovi>>;
+
 export function GetCurrentUser():Promise<models.Korisnici>;
 
+export function GetDashboardStats():Promise<models.DashboardStats>;
+
 export function GetDocumentByID(arg1:number):Promise<models.Dokumenti>;
 
+export function GetDocumentMetadata(arg1:number):Promise<Array<models.MetaPodaci>>;
+
 export function GetDocumentTags(arg1:number):Promise<Array<models.Tagovi>>;
 
 export function GetDocumentVersions(arg1:number):Promise<Array<models.VerzijeDokumenata>>;
 
+export function GetDocumentsByProject(arg1:number):Promise<Array<models.Dokumenti>>;
+
+export function GetGuestAccess():Promise<Array<services.GuestOverview>>;
+
+export function GetImpersonation():Promise<services.Impersonation>;
+
+export function GetMyAccessTokens():Promise<Array<models.PristupniTokeni>>;
+
+export function GetMyFolders():Promise<Array<models.Folderi>>;
+
+export function GetMyPermissions():Promise<Array<services.Permission>>;
+
+export function GetMyPreferences():Promise<models.PodesavanjaKorisnika>;
+
+export function GetMyProfile():Promise<services.Profile>;
+
+export function GetMyTasks():Promise<Array<models.Zadaci>>;
+
+export function GetPermissionPolicy():Promise<services.Policy>;
+
+export function GetProjectByID(arg1:number):Promise<models.Projekti>;
+
+export function GetProjectMembers(arg1:number):Promise<Array<models.Korisnici>>;
+
+export function GetRoles():Promise<Array<models.Uloge>>;
+
+export function GetSharedWithMe():Promise<services.SharedWithGuest>;
+
+export function GetTaskByID(arg1:number):Promise<models.Zadaci>;
+
+export function GetTaskComments(arg1:number):Promise<Array<models.KomentariZadataka>>;
+
+export function GetTasksByProject(arg1:number):Promise<Array<models.Zadaci>>;
+
+export function GetTasksByUser(arg1:number):Promise<Array<models.Zadaci>>;
+
+export function GetTwoFactorStatus():Promise<services.TwoFactorStatus>;
+
+export function GetUnitMembers(arg1:number):Promise<Array<models.Korisnici>>;
+
+export function GetUnits():Promise<Array<models.OrganizacioneJedinice>>;
+
+export function GetUserAccessTokens(arg1:number):Promise<Array<models.PristupniTokeni>>;
+
 export function GetUserProjects():Promise<Array<models.Projekti>>;
 
+export function GetWorkflowPhases(arg1:number):Promise<Array<models.Faze>>;
+
+export function ImpersonateUser(arg1:number,arg2:string):Promise<services.LoginResponse>;
+
+export function ImportUsers(arg1:string,arg2:boolean,arg3:boolean):Promise<services.UserImportReport>;
+
 export function Login(arg1:string,arg2:string):Promise<services.LoginResponse>;
 
 export function Logout():Promise<void>;
 
+export function OffboardUser(arg1:number,arg2:models.Primopredaja):Promise<models.IzvestajPrimopredaje>;
+
+export function RemoveDocumentTag(arg1:number,arg2:number):Promise<void>;
+
+export function RemoveProjectMember(arg1:number,arg2:number):Promise<void>;
+
+export function RequestEmailChange(arg1:string):Promise<models.PromeneEmaila>;
+
+export function ResetUserPassword(arg1:number):Promise<services.ActivationCode>;
+
+export function ResetUserTwoFactor(arg1:number):Promise<void>;
+
+export function RetireRole(arg1:number):Promise<void>;
+
+export function RevokeAccessToken(arg1:number):Promise<void>;
+
+export function RevokeGuestShare(arg1:number):Promise<void>;
+
+export function RevokeSession(arg1:string):Promise<void>;
+
+export function SetProjectUnit(arg1:number,arg2:any):Promise<void>;
+
+export function SetProjectWorkflow(arg1:number,arg2:any):Promise<void>;
+
+export function SetUserUnit(arg1:number,arg2:any):Promise<void>;
+
+export function ShareWithGuest(arg1:number,arg2:services.GuestShareRequest):Promise<models.GostujuciPristup>;
+
+export function StopImpersonation():Promise<void>;
+
 export function TestConnection():Promise<Record<string, any>>;
 
+export function UnlockUser(arg1:number):Promise<void>;
+
 export function UpdateDocument(arg1:number,arg2:models.UploadDocumentRequest):Promise<void>;
 
+export function UpdateDocumentMetadata(arg1:number,arg2:Array<models.MetaPodaci>):Promise<void>;
+
+export function UpdateMyPreferences(arg1:models.PodesavanjaKorisnika):Promise<models.PodesavanjaKorisnika>;
+
+export function UpdateMyProfile(arg1:services.ProfileUpdate):Promise<models.Korisnici>;
+
+export function UpdatePermissionPolicy(arg1:services.Policy):Promise<void>;
+
+export function UpdateProject(arg1:number,arg2:models.Projekti):Promise<void>;
+
+export function UpdateRole(arg1:number,arg2:services.RoleRequest):Promise<models.Uloge>;
+
+export function UpdateTask(arg1:number,arg2:models.UpdateTaskRequest):Promise<void>;
+
+export function UpdateUnit(arg1:number,arg2:services.UnitRequest):Promise<models.OrganizacioneJedinice>;
+
+export function UpdateUser(arg1:number,arg2:models.Korisnici):Promise<void>;
+
 export function UploadDocument(arg1:models.UploadDocumentRequest,arg2:Array<number>,arg3:string):Promise<void>;
+
+export function VerifyTwoFactor(arg1:string):Promise<services.LoginResponse>;
@@ -2,38 +2,170 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function AddDocumentTag(arg1, arg2) {
+  return window['go']['main']['App']['AddDocumentTag'](arg1, arg2);
+}
+
+export function AddProjectMember(arg1, arg2) {
+  return window['go']['main']['App']['AddProjectMember'](arg1, arg2);
+}
+
+export function AddTaskComment(arg1, arg2) {
+  return window['go']['main']['App']['AddTaskComment'](arg1, arg2);
+}
+
+export function AssignRole(arg1, arg2) {
+  return window['go']['main']['App']['AssignRole'](arg1, arg2);
+}
+
+export function CancelEmailChange() {
+  return window['go']['main']['App']['CancelEmailChange']();
+}
+
+export function ChangePassword(arg1, arg2) {
+  return window['go']['main']['App']['ChangePassword'](arg1, arg2);
+}
+
+export function CheckPassword(arg1) {
+  return window['go']['main']['App']['CheckPassword'](arg1);
+}
+
+export function CloneRole(arg1, arg2) {
+  return window['go']['main']['App']['CloneRole'](arg1, arg2);
+}
+
 export function CompleteFirstTimeSetup(arg1, arg2) {
   return window['go']['main']['App']['CompleteFirstTimeSetup'](arg1, arg2);
 }
 
+export function ConfirmEmailChange(arg1) {
+  return window['go']['main']['App']['ConfirmEmailChange'](arg1);
+}
+
+export function ConfirmTwoFactor(arg1) {
+  return window['go']['main']['App']['ConfirmTwoFactor'](arg1);
+}
+
+export function CreateAccessToken(arg1) {
+  return window['go']['main']['App']['CreateAccessToken'](arg1);
+}
+
+export function CreateFolder(arg1) {
+  return window['go']['main']['App']['CreateFolder'](arg1);
+}
+
+export function CreateGuest(arg1) {
+  return window['go']['main']['App']['CreateGuest'](arg1);
+}
+
+export function CreatePhase(arg1) {
+  return window['go']['main']['App']['CreatePhase'](arg1);
+}
+
 export function CreateProject(arg1) {
   return window['go']['main']['App']['CreateProject'](arg1);
 }
 
-export function CreateUser(arg1, arg2) {
-  return window['go']['main']['App']['CreateUser'](arg1, arg2);
+export function CreateRole(arg1) {
+  return window['go']['main']['App']['CreateRole'](arg1);
+}
+
+export function CreateTask(arg1) {
+  return window['go']['main']['App']['CreateTask'](arg1);
+}
+
+export function CreateUnit(arg1) {
+  return window['go']['main']['App']['CreateUnit'](arg1);
+}
+
+export function CreateUser(arg1) {
+  return window['go']['main']['App']['CreateUser'](arg1);
+}
+
+export function CreateWorkflow(arg1) {
+  return window['go']['main']['App']['CreateWorkflow'](arg1);
 }
 
 export function DeleteDocument(arg1) {
   return window['go']['main']['App']['DeleteDocument'](arg1);
 }
 
+export function DeleteProject(arg1) {
+  return window['go']['main']['App']['DeleteProject'](arg1);
+}
+
+export function DeleteTask(arg1) {
+  return window['go']['main']['App']['DeleteTask'](arg1);
+}
+
+export function DeleteUnit(arg1) {
+  return window['go']['main']['App']['DeleteUnit'](arg1);
+}
+
+export function DeleteUser(arg1) {
+  return window['go']['main']['App']['DeleteUser'](arg1);
+}
+
+export function EnrollTwoFactor() {
+  return window['go']['main']['App']['EnrollTwoFactor']();
+}
+
+export function ExportUsers() {
+  return window['go']['main']['App']['ExportUsers']();
+}
+
+export function ExtendGuest(arg1, arg2) {
+  return window['go']['main']['App']['ExtendGuest'](arg1, arg2);
+}
+
+export function GetActiveSessions() {
+  return window['go']['main']['App']['GetActiveSessions']();
+}
+
+export function GetActivityLogs(arg1) {
+  return window['go']['main']['App']['GetActivityLogs'](arg1);
+}
+
 export function GetAllDocuments() {
   return window['go']['main']['App']['GetAllDocuments']();
 }
 
+export function GetAllPermissions() {
+  return window['go']['main']['App']['GetAllPermissions']();
+}
+
+export function GetAllProjects() {
+  return window['go']['main']['App']['GetAllProjects']();
+}
+
+export function GetAllRoles() {
+  return window['go']['main']['App']['GetAllRoles']();
+}
+
 export function GetAllUsers() {
   return window['go']['main']['App']['GetAllUsers']();
 }
 
+export function GetAllWorkflows() {
+  return window['go']['main']['App']['GetAllWorkflows']();
+}
+
 export function GetCurrentUser() {
   return window['go']['main']['App']['GetCurrentUser']();
 }
 
+export function GetDashboardStats() {
+  return window['go']['main']['App']['GetDashboardStats']();
+}
+
 export function GetDocumentByID(arg1) {
   return window['go']['main']['App']['GetDocumentByID'](arg1);
 }
 
+export function GetDocumentMetadata(arg1) {
+  return window['go']['main']['App']['GetDocumentMetadata'](arg1);
+}
+
 export function GetDocumentTags(arg1) {
   return window['go']['main']['App']['GetDocumentTags'](arg1);
 }
@@ -42,10 +174,110 @@ export function GetDocumentVersions(arg1) {
   return window['go']['main']['App']['GetDocumentVersions'](arg1);
 }
 
+export function GetDocumentsByProject(arg1) {
+  return window['go']['main']['App']['GetDocumentsByProject'](arg1);
+}
+
+export function GetGuestAccess() {
+  return window['go']['main']['App']['GetGuestAccess']();
+}
+
+export function GetImpersonation() {
+  return window['go']['main']['App']['GetImpersonation']();
+}
+
+export function GetMyAccessTokens() {
+  return window['go']['main']['App']['GetMyAccessTokens']();
+}
+
+export function GetMyFolders() {
+  return window['go']['main']['App']['GetMyFolders']();
+}
+
+export function GetMyPermissions() {
+  return window['go']['main']['App']['GetMyPermissions']();
+}
+
+export function GetMyPreferences() {
+  return window['go']['main']['App']['GetMyPreferences']();
+}
+
+export function GetMyProfile() {
+  return window['go']['main']['App']['GetMyProfile']();
+}
+
+export function GetMyTasks() {
+  return window['go']['main']['App']['GetMyTasks']();
+}
+
+export function GetPermissionPolicy() {
+  return window['go']['main']['App']['GetPermissionPolicy']();
+}
+
+export function GetProjectByID(arg1) {
+  return window['go']['main']['App']['GetProjectByID'](arg1);
+}
+
+export function GetProjectMembers(arg1) {
+  return window['go']['main']['App']['GetProjectMembers'](arg1);
+}
+
+export function GetRoles() {
+  return window['go']['main']['App']['GetRoles']();
+}
+
+export function GetSharedWithMe() {
+  return window['go']['main']['App']['GetSharedWithMe']();
+}
+
+export function GetTaskByID(arg1) {
+  return window['go']['main']['App']['GetTaskByID'](arg1);
+}
+
+export function GetTaskComments(arg1) {
+  return window['go']['main']['App']['GetTaskComments'](arg1);
+}
+
+export function GetTasksByProject(arg1) {
+  return window['go']['main']['App']['GetTasksByProject'](arg1);
+}
+
+export function GetTasksByUser(arg1) {
+  return window['go']['main']['App']['GetTasksByUser'](arg1);
+}
+
+export function GetTwoFactorStatus() {
+  return window['go']['main']['App']['GetTwoFactorStatus']();
+}
+
+export function GetUnitMembers(arg1) {
+  return window['go']['main']['App']['GetUnitMembers'](arg1);
+}
+
+export function GetUnits() {
+  return window['go']['main']['App']['GetUnits']();
+}
+
+export function GetUserAccessTokens(arg1) {
+  return window['go']['main']['App']['GetUserAccessTokens'](arg1);
+}
+
 export function GetUserProjects() {
   return window['go']['main']['App']['GetUserProjects']();
 }
 
+export function GetWorkflowPhases(arg1) {
+  return window['go']['main']['App']['GetWorkflowPhases'](arg1);
+}
+
+export function ImpersonateUser(arg1, arg2) {
+  return window['go']['main']['App']['ImpersonateUser'](arg1, arg2);
+}
+
+export function ImportUsers(arg1, arg2, arg3) {
+  return window['go']['main']['App']['ImportUsers'](arg1, arg2, arg3);
+}
+
 export function Login(arg1, arg2) {
   return window['go']['main']['App']['Login'](arg1, arg2);
 }
@@ -54,14 +286,118 @@ export function Logout() {
   return window['go']['main']['App']['Logout']();
 }
 
+export function OffboardUser(arg1, arg2) {
+  return window['go']['main']['App']['OffboardUser'](arg1, arg2);
+}
+
+export function RemoveDocumentTag(arg1, arg2) {
+  return window['go']['main']['App']['RemoveDocumentTag'](arg1, arg2);
+}
+
+export function RemoveProjectMember(arg1, arg2) {
+  return window['go']['main']['App']['RemoveProjectMember'](arg1, arg2);
+}
+
+export function RequestEmailChange(arg1) {
+  return window['go']['main']['App']['RequestEmailChange'](arg1);
+}
+
+export function ResetUserPassword(arg1) {
+  return window['go']['main']['App']['ResetUserPassword'](arg1);
+}
+
+export function ResetUserTwoFactor(arg1) {
+  return window['go']['main']['App']['ResetUserTwoFactor'](arg1);
+}
+
+export function RetireRole(arg1) {
+  return window['go']['main']['App']['RetireRole'](arg1);
+}
+
+export function RevokeAccessToken(arg1) {
+  return window['go']['main']['App']['RevokeAccessToken'](arg1);
+}
+
+export function RevokeGuestShare(arg1) {
+  return window['go']['main']['App']['RevokeGuestShare'](arg1);
+}
+
+export function RevokeSession(arg1) {
+  return window['go']['main']['App']['RevokeSession'](arg1);
+}
+
+export function SetProjectUnit(arg1, arg2) {
+  return window['go']['main']['App']['SetProjectUnit'](arg1, arg2);
+}
+
+export function SetProjectWorkflow(arg1, arg2) {
+  return window['go']['main']['App']['SetProjectWorkflow'](arg1, arg2);
+}
+
+export function SetUserUnit(arg1, arg2) {
+  return window['go']['main']['App']['SetUserUnit'](arg1, arg2);
+}
+
+export function ShareWithGuest(arg1, arg2) {
+  return window['go']['main']['App']['ShareWithGuest'](arg1, arg2);
+}
+
+export function StopImpersonation() {
+  return window['go']['main']['App']['StopImpersonation']();
+}
+
 export function TestConnection() {
   return window['go']['main']['App']['TestConnection']();
 }
 
+export function UnlockUser(arg1) {
+  return window['go']['main']['App']['UnlockUser'](arg1);
+}
+
 export function UpdateDocument(arg1, arg2) {
   return window['go']['main']['App']['UpdateDocument'](arg1, arg2);
 }
 
+export function UpdateDocumentMetadata(arg1, arg2) {
+  return window['go']['main']['App']['UpdateDocumentMetadata'](arg1, arg2);
+}
+
+export function UpdateMyPreferences(arg1) {
+  return window['go']['main']['App']['UpdateMyPreferences'](arg1);
+}
+
+export function UpdateMyProfile(arg1) {
+  return window['go']['main']['App']['UpdateMyProfile'](arg1);
+}
+
+export function UpdatePermissionPolicy(arg1) {
+  return window['go']['main']['App']['UpdatePermissionPolicy'](arg1);
+}
+
+export function UpdateProject(arg1, arg2) {
+  return window['go']['main']['App']['UpdateProject'](arg1, arg2);
+}
+
+export function UpdateRole(arg1, arg2) {
+  return window['go']['main']['App']['UpdateRole'](arg1, arg2);
+}
+
+export function UpdateTask(arg1, arg2) {
+  return window['go']['main']['App']['UpdateTask'](arg1, arg2);
+}
+
+export function UpdateUnit(arg1, arg2) {
+  return window['go']['main']['App']['UpdateUnit'](arg1, arg2);
+}
+
+export function UpdateUser(arg1, arg2) {
+  return window['go']['main']['App']['UpdateUser'](arg1, arg2);
+}
+
 export function UploadDocument(arg1, arg2, arg3) {
   return window['go']['main']['App']['UploadDocument'](arg1, arg2, arg3);
 }
+
+export function VerifyTwoFactor(arg1) {
+  return window['go']['main']['App']['VerifyTwoFactor'](arg1);
+}
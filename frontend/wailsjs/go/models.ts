@@ -1,5 +1,105 @@
 export namespace models {
 	
+	export class CreateProjectRequest {
+	    naziv_projekta: string;
+	    opis: string;
+	    datum_pocetka?: time.Time;
+	    datum_zavrsetka?: time.Time;
+	    radni_tok_id?: number;
+	    jedinica_id?: number;
+	    clanovi_tima: number[];
+	
+	    static createFrom(source: any = {}) {
+	        return new CreateProjectRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.naziv_projekta = source["naziv_projekta"];
+	        this.opis = source["opis"];
+	        this.datum_pocetka = this.convertValues(source["datum_pocetka"], time.Time);
+	        this.datum_zavrsetka = this.convertValues(source["datum_zavrsetka"], time.Time);
+	        this.radni_tok_id = source["radni_tok_id"];
+	        this.jedinica_id = source["jedinica_id"];
+	        this.clanovi_tima = source["clanovi_tima"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class CreateTaskRequest {
+	    projekat_id: number;
+	    naziv_zadatka: string;
+	    opis: string;
+	    dodeljen_korisniku_id?: number;
+	    rok?: time.Time;
+	    prioritet: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new CreateTaskRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.projekat_id = source["projekat_id"];
+	        this.naziv_zadatka = source["naziv_zadatka"];
+	        this.opis = source["opis"];
+	        this.dodeljen_korisniku_id = source["dodeljen_korisniku_id"];
+	        this.rok = this.convertValues(source["rok"], time.Time);
+	        this.prioritet = source["prioritet"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class DashboardStats {
+	    aktivni_projekti: number;
+	    ukupno_dokumenata: number;
+	    zadaci_u_toku: number;
+	    aktivni_korisnici: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new DashboardStats(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.aktivni_projekti = source["aktivni_projekti"];
+	        this.ukupno_dokumenata = source["ukupno_dokumenata"];
+	        this.zadaci_u_toku = source["zadaci_u_toku"];
+	        this.aktivni_korisnici = source["aktivni_korisnici"];
+	    }
+	}
 	export class Dokumenti {
 	    dokument_id: number;
 	    projekat_id?: number;
@@ -11,10 +111,8 @@ export namespace models {
 	    radni_tok_id?: number;
 	    trenutna_faza_id?: number;
 	    kreirao_korisnik_id: number;
-	    // Go type: time
-	    datuma_postavke: any;
-	    // Go type: time
-	    poslednja_izmena?: any;
+	    datuma_postavke: time.Time;
+	    poslednja_izmena?: time.Time;
 	    naziv_projekta?: string;
 	    ime_kreirao?: string;
 	    naziv_faze?: string;
@@ -36,8 +134,8 @@ export namespace models {
 	        this.radni_tok_id = source["radni_tok_id"];
 	        this.trenutna_faza_id = source["trenutna_faza_id"];
 	        this.kreirao_korisnik_id = source["kreirao_korisnik_id"];
-	        this.datuma_postavke = this.convertValues(source["datuma_postavke"], null);
-	        this.poslednja_izmena = this.convertValues(source["poslednja_izmena"], null);
+	        this.datuma_postavke = this.convertValues(source["datuma_postavke"], time.Time);
+	        this.poslednja_izmena = this.convertValues(source["poslednja_izmena"], time.Time);
 	        this.naziv_projekta = source["naziv_projekta"];
 	        this.ime_kreirao = source["ime_kreirao"];
 	        this.naziv_faze = source["naziv_faze"];
@@ -62,129 +160,128 @@ export namespace models {
 		    return a;
 		}
 	}
-	export class Korisnici {
-	    korisnik_id: number;
-	    korisnicko_ime: string;
-	    email: string;
-	    ime?: string;
-	    prezime?: string;
-	    uloga_id: number;
-	    status: string;
-	    poslednja_prijava?: string;
-	    kreiran_datuma: string;
-	    naziv_uloge?: string;
+	export class DozvolaUloge {
+	    dozvola: string;
+	    projekat_id?: number;
 	
 	    static createFrom(source: any = {}) {
-	        return new Korisnici(source);
+	        return new DozvolaUloge(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.korisnik_id = source["korisnik_id"];
-	        this.korisnicko_ime = source["korisnicko_ime"];
-	        this.email = source["email"];
-	        this.ime = source["ime"];
-	        this.prezime = source["prezime"];
-	        this.uloga_id = source["uloga_id"];
-	        this.status = source["status"];
-	        this.poslednja_prijava = source["poslednja_prijava"];
-	        this.kreiran_datuma = source["kreiran_datuma"];
-	        this.naziv_uloge = source["naziv_uloge"];
+	        this.dozvola = source["dozvola"];
+	        this.projekat_id = source["projekat_id"];
 	    }
 	}
-	export class Projekti {
-	    projekat_id: number;
-	    naziv_projekta: string;
-	    opis?: string;
-	    datum_pocetka?: string;
-	    datum_zavrsetka?: string;
-	    status: string;
-	    rukovodilac_id?: number;
-	    radni_tok_id?: number;
-	    rukovodilac_ime?: string;
-	    broj_zadataka?: number;
-	    broj_clanova?: number;
+	export class Faze {
+	    faza_id: number;
+	    radni_tok_id: number;
+	    naziv_faze: string;
+	    redosled: number;
 	
 	    static createFrom(source: any = {}) {
-	        return new Projekti(source);
+	        return new Faze(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.projekat_id = source["projekat_id"];
-	        this.naziv_projekta = source["naziv_projekta"];
-	        this.opis = source["opis"];
-	        this.datum_pocetka = source["datum_pocetka"];
-	        this.datum_zavrsetka = source["datum_zavrsetka"];
-	        this.status = source["status"];
-	        this.rukovodilac_id = source["rukovodilac_id"];
+	        this.faza_id = source["faza_id"];
 	        this.radni_tok_id = source["radni_tok_id"];
-	        this.rukovodilac_ime = source["rukovodilac_ime"];
-	        this.broj_zadataka = source["broj_zadataka"];
-	        this.broj_clanova = source["broj_clanova"];
+	        this.naziv_faze = source["naziv_faze"];
+	        this.redosled = source["redosled"];
 	    }
 	}
-	export class Tagovi {
-	    tag_id: number;
-	    naziv_taga: string;
+	export class Folderi {
+	    folder_id: number;
+	    naziv_foldera: string;
+	    roditelj_folder_id?: number;
+	    vlasnik_id: number;
 	
 	    static createFrom(source: any = {}) {
-	        return new Tagovi(source);
+	        return new Folderi(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.tag_id = source["tag_id"];
-	        this.naziv_taga = source["naziv_taga"];
+	        this.folder_id = source["folder_id"];
+	        this.naziv_foldera = source["naziv_foldera"];
+	        this.roditelj_folder_id = source["roditelj_folder_id"];
+	        this.vlasnik_id = source["vlasnik_id"];
 	    }
 	}
-	export class UploadDocumentRequest {
-	    naziv_dokumenta: string;
+	export class GostujuciPristup {
+	    pristup_id: number;
+	    korisnik_id: number;
 	    projekat_id?: number;
 	    folder_id?: number;
-	    opis: string;
-	    tip_dokumenta: string;
-	    jezik_dokumenta: string;
-	    tagovi: string[];
+	    dokument_id?: number;
+	    odobrio_id?: number;
+	    kreiran_datuma: string;
+	    korisnicko_ime?: string;
+	    gost_do?: string;
+	    naziv: string;
+	    odobrio_ime?: string;
 	
 	    static createFrom(source: any = {}) {
-	        return new UploadDocumentRequest(source);
+	        return new GostujuciPristup(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.naziv_dokumenta = source["naziv_dokumenta"];
+	        this.pristup_id = source["pristup_id"];
+	        this.korisnik_id = source["korisnik_id"];
 	        this.projekat_id = source["projekat_id"];
 	        this.folder_id = source["folder_id"];
-	        this.opis = source["opis"];
-	        this.tip_dokumenta = source["tip_dokumenta"];
-	        this.jezik_dokumenta = source["jezik_dokumenta"];
-	        this.tagovi = source["tagovi"];
+	        this.dokument_id = source["dokument_id"];
+	        this.odobrio_id = source["odobrio_id"];
+	        this.kreiran_datuma = source["kreiran_datuma"];
+	        this.korisnicko_ime = source["korisnicko_ime"];
+	        this.gost_do = source["gost_do"];
+	        this.naziv = source["naziv"];
+	        this.odobrio_ime = source["odobrio_ime"];
 	    }
 	}
-	export class VerzijeDokumenata {
-	    verzija_id: number;
-	    dokument_id: number;
-	    verzija_oznaka?: string;
-	    putanja_do_fajla: string;
-	    velicina_fajla_mb?: number;
-	    postavio_korisnik_id: number;
-	    // Go type: time
-	    datuma_postavke: any;
+	export class Primopredaja {
+	    rukovodilac_id?: number;
+	    izvrsilac_id?: number;
+	    vlasnik_id?: number;
 	
 	    static createFrom(source: any = {}) {
-	        return new VerzijeDokumenata(source);
+	        return new Primopredaja(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.verzija_id = source["verzija_id"];
-	        this.dokument_id = source["dokument_id"];
-	        this.verzija_oznaka = source["verzija_oznaka"];
-	        this.putanja_do_fajla = source["putanja_do_fajla"];
-	        this.velicina_fajla_mb = source["velicina_fajla_mb"];
-	        this.postavio_korisnik_id = source["postavio_korisnik_id"];
-	        this.datuma_postavke = this.convertValues(source["datuma_postavke"], null);
+	        this.rukovodilac_id = source["rukovodilac_id"];
+	        this.izvrsilac_id = source["izvrsilac_id"];
+	        this.vlasnik_id = source["vlasnik_id"];
+	    }
+	}
+	export class IzvestajPrimopredaje {
+	    korisnik_id: number;
+	    primopredaja: Primopredaja;
+	    projekti: number[];
+	    zadaci: number[];
+	    folderi: number[];
+	    dokumenti: number[];
+	    opozvani_tokeni: number;
+	    zavrsene_sesije: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new IzvestajPrimopredaje(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.korisnik_id = source["korisnik_id"];
+	        this.primopredaja = this.convertValues(source["primopredaja"], Primopredaja);
+	        this.projekti = source["projekti"];
+	        this.zadaci = source["zadaci"];
+	        this.folderi = source["folderi"];
+	        this.dokumenti = source["dokumenti"];
+	        this.opozvani_tokeni = source["opozvani_tokeni"];
+	        this.zavrsene_sesije = source["zavrsene_sesije"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -205,25 +302,118 @@ export namespace models {
 		    return a;
 		}
 	}
-
-}
-
-export namespace services {
+	export class KomentariZadataka {
+	    komentar_id: number;
+	    zadatak_id: number;
+	    korisnik_id: number;
+	    tekst_komentara: string;
+	    datuma_kreiranja: time.Time;
+	    ime_korisnika?: string;
 	
-	export class LoginResponse {
-	    user?: models.Korisnici;
-	    success: boolean;
-	    message: string;
+	    static createFrom(source: any = {}) {
+	        return new KomentariZadataka(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.komentar_id = source["komentar_id"];
+	        this.zadatak_id = source["zadatak_id"];
+	        this.korisnik_id = source["korisnik_id"];
+	        this.tekst_komentara = source["tekst_komentara"];
+	        this.datuma_kreiranja = this.convertValues(source["datuma_kreiranja"], time.Time);
+	        this.ime_korisnika = source["ime_korisnika"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Korisnici {
+	    korisnik_id: number;
+	    korisnicko_ime: string;
+	    email: string;
+	    ime?: string;
+	    prezime?: string;
+	    uloga_id: number;
+	    status: string;
+	    poslednja_prijava?: string;
+	    kreiran_datuma: string;
+	    mora_promeniti_lozinku: boolean;
+	    neuspesne_prijave: number;
+	    poslednja_neuspesna_prijava?: string;
+	    zakljucan_do?: string;
+	    izvor_prijave: string;
+	    jedinica_id?: number;
+	    gost_do?: string;
+	    naziv_uloge?: string;
 	
 	    static createFrom(source: any = {}) {
-	        return new LoginResponse(source);
+	        return new Korisnici(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.user = this.convertValues(source["user"], models.Korisnici);
-	        this.success = source["success"];
-	        this.message = source["message"];
+	        this.korisnik_id = source["korisnik_id"];
+	        this.korisnicko_ime = source["korisnicko_ime"];
+	        this.email = source["email"];
+	        this.ime = source["ime"];
+	        this.prezime = source["prezime"];
+	        this.uloga_id = source["uloga_id"];
+	        this.status = source["status"];
+	        this.poslednja_prijava = source["poslednja_prijava"];
+	        this.kreiran_datuma = source["kreiran_datuma"];
+	        this.mora_promeniti_lozinku = source["mora_promeniti_lozinku"];
+	        this.neuspesne_prijave = source["neuspesne_prijave"];
+	        this.poslednja_neuspesna_prijava = source["poslednja_neuspesna_prijava"];
+	        this.zakljucan_do = source["zakljucan_do"];
+	        this.izvor_prijave = source["izvor_prijave"];
+	        this.jedinica_id = source["jedinica_id"];
+	        this.gost_do = source["gost_do"];
+	        this.naziv_uloge = source["naziv_uloge"];
+	    }
+	}
+	export class LogAktivnosti {
+	    log_id: number;
+	    korisnik_id?: number;
+	    tip_aktivnosti: string;
+	    opis?: string;
+	    ciljani_entitet?: string;
+	    ciljani_id?: number;
+	    datuma: time.Time;
+	    stvarni_korisnik_id?: number;
+	    ime_korisnika?: string;
+	    ime_stvarnog_korisnika?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new LogAktivnosti(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.log_id = source["log_id"];
+	        this.korisnik_id = source["korisnik_id"];
+	        this.tip_aktivnosti = source["tip_aktivnosti"];
+	        this.opis = source["opis"];
+	        this.ciljani_entitet = source["ciljani_entitet"];
+	        this.ciljani_id = source["ciljani_id"];
+	        this.datuma = this.convertValues(source["datuma"], time.Time);
+	        this.stvarni_korisnik_id = source["stvarni_korisnik_id"];
+	        this.ime_korisnika = source["ime_korisnika"];
+	        this.ime_stvarnog_korisnika = source["ime_stvarnog_korisnika"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -244,6 +434,965 @@ export namespace services {
 		    return a;
 		}
 	}
+	export class MetaPodaci {
+	    meta_id: number;
+	    dokument_id: number;
+	    kljuc: string;
+	    vrednost?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new MetaPodaci(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.meta_id = source["meta_id"];
+	        this.dokument_id = source["dokument_id"];
+	        this.kljuc = source["kljuc"];
+	        this.vrednost = source["vrednost"];
+	    }
+	}
+	export class OrganizacioneJedinice {
+	    jedinica_id: number;
+	    naziv: string;
+	    tip: string;
+	    nadredjena_jedinica_id?: number;
+	    rukovodilac_id?: number;
+	    kreiran_datuma: string;
+	    rukovodilac_ime?: string;
+	    broj_clanova: number;
+	    broj_projekata: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new OrganizacioneJedinice(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.jedinica_id = source["jedinica_id"];
+	        this.naziv = source["naziv"];
+	        this.tip = source["tip"];
+	        this.nadredjena_jedinica_id = source["nadredjena_jedinica_id"];
+	        this.rukovodilac_id = source["rukovodilac_id"];
+	        this.kreiran_datuma = source["kreiran_datuma"];
+	        this.rukovodilac_ime = source["rukovodilac_ime"];
+	        this.broj_clanova = source["broj_clanova"];
+	        this.broj_projekata = source["broj_projekata"];
+	    }
+	}
+	export class PodesavanjaKorisnika {
+	    korisnik_id: number;
+	    jezik: string;
+	    podrazumevani_projekat_id?: number;
+	    format_datuma: string;
+	    obavestenja_email: boolean;
+	    obavestenja_zadaci: boolean;
+	    obavestenja_komentari: boolean;
+	    izmenjeno: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new PodesavanjaKorisnika(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.korisnik_id = source["korisnik_id"];
+	        this.jezik = source["jezik"];
+	        this.podrazumevani_projekat_id = source["podrazumevani_projekat_id"];
+	        this.format_datuma = source["format_datuma"];
+	        this.obavestenja_email = source["obavestenja_email"];
+	        this.obavestenja_zadaci = source["obavestenja_zadaci"];
+	        this.obavestenja_komentari = source["obavestenja_komentari"];
+	        this.izmenjeno = source["izmenjeno"];
+	    }
+	}
+	
+	export class PristupniTokeni {
+	    token_id: number;
+	    korisnik_id: number;
+	    naziv: string;
+	    prefiks: string;
+	    opsezi: string[];
+	    istice: string;
+	    poslednje_korisceno?: string;
+	    opozvan?: string;
+	    kreiran_datuma: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new PristupniTokeni(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.token_id = source["token_id"];
+	        this.korisnik_id = source["korisnik_id"];
+	        this.naziv = source["naziv"];
+	        this.prefiks = source["prefiks"];
+	        this.opsezi = source["opsezi"];
+	        this.istice = source["istice"];
+	        this.poslednje_korisceno = source["poslednje_korisceno"];
+	        this.opozvan = source["opozvan"];
+	        this.kreiran_datuma = source["kreiran_datuma"];
+	    }
+	}
+	export class Projekti {
+	    projekat_id: number;
+	    naziv_projekta: string;
+	    opis?: string;
+	    datum_pocetka?: string;
+	    datum_zavrsetka?: string;
+	    status: string;
+	    rukovodilac_id?: number;
+	    radni_tok_id?: number;
+	    jedinica_id?: number;
+	    rukovodilac_ime?: string;
+	    broj_zadataka?: number;
+	    broj_clanova?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new Projekti(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.projekat_id = source["projekat_id"];
+	        this.naziv_projekta = source["naziv_projekta"];
+	        this.opis = source["opis"];
+	        this.datum_pocetka = source["datum_pocetka"];
+	        this.datum_zavrsetka = source["datum_zavrsetka"];
+	        this.status = source["status"];
+	        this.rukovodilac_id = source["rukovodilac_id"];
+	        this.radni_tok_id = source["radni_tok_id"];
+	        this.jedinica_id = source["jedinica_id"];
+	        this.rukovodilac_ime = source["rukovodilac_ime"];
+	        this.broj_zadataka = source["broj_zadataka"];
+	        this.broj_clanova = source["broj_clanova"];
+	    }
+	}
+	export class PromeneEmaila {
+	    korisnik_id: number;
+	    novi_email: string;
+	    istice: string;
+	    kreiran_datuma: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new PromeneEmaila(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.korisnik_id = source["korisnik_id"];
+	        this.novi_email = source["novi_email"];
+	        this.istice = source["istice"];
+	        this.kreiran_datuma = source["kreiran_datuma"];
+	    }
+	}
+	export class RadniTokovi {
+	    radni_tok_id: number;
+	    naziv: string;
+	    tip_toka: string;
+	    opis?: string;
+	    da_li_je_sablon: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new RadniTokovi(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.radni_tok_id = source["radni_tok_id"];
+	        this.naziv = source["naziv"];
+	        this.tip_toka = source["tip_toka"];
+	        this.opis = source["opis"];
+	        this.da_li_je_sablon = source["da_li_je_sablon"];
+	    }
+	}
+	export class Tagovi {
+	    tag_id: number;
+	    naziv_taga: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Tagovi(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.tag_id = source["tag_id"];
+	        this.naziv_taga = source["naziv_taga"];
+	    }
+	}
+	export class Uloge {
+	    uloga_id: number;
+	    naziv_uloge: string;
+	    opis?: string;
+	    sistemska?: string;
+	    povucena?: string;
+	    dozvole?: DozvolaUloge[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Uloge(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.uloga_id = source["uloga_id"];
+	        this.naziv_uloge = source["naziv_uloge"];
+	        this.opis = source["opis"];
+	        this.sistemska = source["sistemska"];
+	        this.povucena = source["povucena"];
+	        this.dozvole = this.convertValues(source["dozvole"], DozvolaUloge);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class UpdateTaskRequest {
+	    naziv_zadatka?: string;
+	    opis?: string;
+	    dodeljen_korisniku_id?: number;
+	    rok?: time.Time;
+	    prioritet?: string;
+	    progres?: number;
+	    faza_id?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new UpdateTaskRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.naziv_zadatka = source["naziv_zadatka"];
+	        this.opis = source["opis"];
+	        this.dodeljen_korisniku_id = source["dodeljen_korisniku_id"];
+	        this.rok = this.convertValues(source["rok"], time.Time);
+	        this.prioritet = source["prioritet"];
+	        this.progres = source["progres"];
+	        this.faza_id = source["faza_id"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class UploadDocumentRequest {
+	    naziv_dokumenta: string;
+	    projekat_id?: number;
+	    folder_id?: number;
+	    opis: string;
+	    tip_dokumenta: string;
+	    jezik_dokumenta: string;
+	    tagovi: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new UploadDocumentRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.naziv_dokumenta = source["naziv_dokumenta"];
+	        this.projekat_id = source["projekat_id"];
+	        this.folder_id = source["folder_id"];
+	        this.opis = source["opis"];
+	        this.tip_dokumenta = source["tip_dokumenta"];
+	        this.jezik_dokumenta = source["jezik_dokumenta"];
+	        this.tagovi = source["tagovi"];
+	    }
+	}
+	export class VerzijeDokumenata {
+	    verzija_id: number;
+	    dokument_id: number;
+	    verzija_oznaka?: string;
+	    putanja_do_fajla: string;
+	    velicina_fajla_mb?: number;
+	    postavio_korisnik_id: number;
+	    datuma_postavke: time.Time;
+	
+	    static createFrom(source: any = {}) {
+	        return new VerzijeDokumenata(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.verzija_id = source["verzija_id"];
+	        this.dokument_id = source["dokument_id"];
+	        this.verzija_oznaka = source["verzija_oznaka"];
+	        this.putanja_do_fajla = source["putanja_do_fajla"];
+	        this.velicina_fajla_mb = source["velicina_fajla_mb"];
+	        this.postavio_korisnik_id = source["postavio_korisnik_id"];
+	        this.datuma_postavke = this.convertValues(source["datuma_postavke"], time.Time);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Zadaci {
+	    zadatak_id: number;
+	    projekat_id: number;
+	    faza_id: number;
+	    naziv_zadatka: string;
+	    opis?: string;
+	    dodeljen_korisniku_id?: number;
+	    rok?: time.Time;
+	    prioritet?: string;
+	    progres: number;
+	    kreiran_datuma: time.Time;
+	    naziv_projekta?: string;
+	    naziv_faze?: string;
+	    dodeljen_korisniku?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Zadaci(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.zadatak_id = source["zadatak_id"];
+	        this.projekat_id = source["projekat_id"];
+	        this.faza_id = source["faza_id"];
+	        this.naziv_zadatka = source["naziv_zadatka"];
+	        this.opis = source["opis"];
+	        this.dodeljen_korisniku_id = source["dodeljen_korisniku_id"];
+	        this.rok = this.convertValues(source["rok"], time.Time);
+	        this.prioritet = source["prioritet"];
+	        this.progres = source["progres"];
+	        this.kreiran_datuma = this.convertValues(source["kreiran_datuma"], time.Time);
+	        this.naziv_projekta = source["naziv_projekta"];
+	        this.naziv_faze = source["naziv_faze"];
+	        this.dodeljen_korisniku = source["dodeljen_korisniku"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
+export namespace services {
+	
+	export class AccessTokenRequest {
+	    naziv: string;
+	    opsezi: string[];
+	    istice?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new AccessTokenRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.naziv = source["naziv"];
+	        this.opsezi = source["opsezi"];
+	        this.istice = source["istice"];
+	    }
+	}
+	export class ActivationCode {
+	    korisnik_id: number;
+	    korisnicko_ime: string;
+	    kod: string;
+	    istice: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ActivationCode(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.korisnik_id = source["korisnik_id"];
+	        this.korisnicko_ime = source["korisnicko_ime"];
+	        this.kod = source["kod"];
+	        this.istice = source["istice"];
+	    }
+	}
+	export class GuestOverview {
+	    korisnik: models.Korisnici;
+	    deljenja: models.GostujuciPristup[];
+	
+	    static createFrom(source: any = {}) {
+	        return new GuestOverview(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.korisnik = this.convertValues(source["korisnik"], models.Korisnici);
+	        this.deljenja = this.convertValues(source["deljenja"], models.GostujuciPristup);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class GuestRequest {
+	    korisnicko_ime: string;
+	    email: string;
+	    ime?: string;
+	    prezime?: string;
+	    istice: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new GuestRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.korisnicko_ime = source["korisnicko_ime"];
+	        this.email = source["email"];
+	        this.ime = source["ime"];
+	        this.prezime = source["prezime"];
+	        this.istice = source["istice"];
+	    }
+	}
+	export class GuestShareRequest {
+	    projekat_id?: number;
+	    folder_id?: number;
+	    dokument_id?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new GuestShareRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.projekat_id = source["projekat_id"];
+	        this.folder_id = source["folder_id"];
+	        this.dokument_id = source["dokument_id"];
+	    }
+	}
+	export class Impersonation {
+	    administrator_id: number;
+	    administrator: string;
+	    korisnik_id: number;
+	    korisnicko_ime: string;
+	    razlog: string;
+	    pocetak: string;
+	    istice: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Impersonation(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.administrator_id = source["administrator_id"];
+	        this.administrator = source["administrator"];
+	        this.korisnik_id = source["korisnik_id"];
+	        this.korisnicko_ime = source["korisnicko_ime"];
+	        this.razlog = source["razlog"];
+	        this.pocetak = source["pocetak"];
+	        this.istice = source["istice"];
+	    }
+	}
+	export class LoginResponse {
+	    user?: models.Korisnici;
+	    success: boolean;
+	    message: string;
+	    token?: string;
+	    expires?: string;
+	    retry_after?: number;
+	    zastupanje?: Impersonation;
+	
+	    static createFrom(source: any = {}) {
+	        return new LoginResponse(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.user = this.convertValues(source["user"], models.Korisnici);
+	        this.success = source["success"];
+	        this.message = source["message"];
+	        this.token = source["token"];
+	        this.expires = source["expires"];
+	        this.retry_after = source["retry_after"];
+	        this.zastupanje = this.convertValues(source["zastupanje"], Impersonation);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class NewAccessToken {
+	    token: string;
+	    podaci: models.PristupniTokeni;
+	
+	    static createFrom(source: any = {}) {
+	        return new NewAccessToken(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.token = source["token"];
+	        this.podaci = this.convertValues(source["podaci"], models.PristupniTokeni);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class PasswordViolation {
+	    code: string;
+	    message: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new PasswordViolation(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.code = source["code"];
+	        this.message = source["message"];
+	    }
+	}
+	export class Policy {
+	    roles: Record<string, Array<string>>;
+	
+	    static createFrom(source: any = {}) {
+	        return new Policy(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.roles = source["roles"];
+	    }
+	}
+	export class Profile {
+	    korisnik: models.Korisnici;
+	    podesavanja: models.PodesavanjaKorisnika;
+	    promena_emaila?: models.PromeneEmaila;
+	
+	    static createFrom(source: any = {}) {
+	        return new Profile(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.korisnik = this.convertValues(source["korisnik"], models.Korisnici);
+	        this.podesavanja = this.convertValues(source["podesavanja"], models.PodesavanjaKorisnika);
+	        this.promena_emaila = this.convertValues(source["promena_emaila"], models.PromeneEmaila);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ProfileUpdate {
+	    ime: string;
+	    prezime: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ProfileUpdate(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.ime = source["ime"];
+	        this.prezime = source["prezime"];
+	    }
+	}
+	export class RoleRequest {
+	    naziv: string;
+	    opis: string;
+	    dozvole: models.DozvolaUloge[];
+	
+	    static createFrom(source: any = {}) {
+	        return new RoleRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.naziv = source["naziv"];
+	        this.opis = source["opis"];
+	        this.dozvole = this.convertValues(source["dozvole"], models.DozvolaUloge);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Session {
+	    id: string;
+	    korisnik_id: number;
+	    korisnicko_ime: string;
+	    kreirana: time.Time;
+	    poslednja_aktivnost: time.Time;
+	    istice: time.Time;
+	    aktivacija?: boolean;
+	    drugi_faktor?: boolean;
+	    zastupanje?: Impersonation;
+	
+	    static createFrom(source: any = {}) {
+	        return new Session(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.korisnik_id = source["korisnik_id"];
+	        this.korisnicko_ime = source["korisnicko_ime"];
+	        this.kreirana = this.convertValues(source["kreirana"], time.Time);
+	        this.poslednja_aktivnost = this.convertValues(source["poslednja_aktivnost"], time.Time);
+	        this.istice = this.convertValues(source["istice"], time.Time);
+	        this.aktivacija = source["aktivacija"];
+	        this.drugi_faktor = source["drugi_faktor"];
+	        this.zastupanje = this.convertValues(source["zastupanje"], Impersonation);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SharedWithGuest {
+	    istice: string;
+	    projekti: models.Projekti[];
+	    dokumenti: models.Dokumenti[];
+	
+	    static createFrom(source: any = {}) {
+	        return new SharedWithGuest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.istice = source["istice"];
+	        this.projekti = this.convertValues(source["projekti"], models.Projekti);
+	        this.dokumenti = this.convertValues(source["dokumenti"], models.Dokumenti);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class TwoFactorEnrollment {
+	    tajna: string;
+	    url: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new TwoFactorEnrollment(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.tajna = source["tajna"];
+	        this.url = source["url"];
+	    }
+	}
+	export class TwoFactorSetup {
+	    rezervni_kodovi: string[];
+	    prijava?: LoginResponse;
+	
+	    static createFrom(source: any = {}) {
+	        return new TwoFactorSetup(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.rezervni_kodovi = source["rezervni_kodovi"];
+	        this.prijava = this.convertValues(source["prijava"], LoginResponse);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class TwoFactorStatus {
+	    ukljucen: boolean;
+	    obavezan: boolean;
+	    preostalo_kodova: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new TwoFactorStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.ukljucen = source["ukljucen"];
+	        this.obavezan = source["obavezan"];
+	        this.preostalo_kodova = source["preostalo_kodova"];
+	    }
+	}
+	export class UnitRequest {
+	    naziv: string;
+	    tip: string;
+	    nadredjena_jedinica_id?: number;
+	    rukovodilac_id?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new UnitRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.naziv = source["naziv"];
+	        this.tip = source["tip"];
+	        this.nadredjena_jedinica_id = source["nadredjena_jedinica_id"];
+	        this.rukovodilac_id = source["rukovodilac_id"];
+	    }
+	}
+	export class UserImportRow {
+	    red: number;
+	    korisnicko_ime: string;
+	    email: string;
+	    ime?: string;
+	    prezime?: string;
+	    uloga: string;
+	    greske?: string[];
+	    aktivacija?: ActivationCode;
+	
+	    static createFrom(source: any = {}) {
+	        return new UserImportRow(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.red = source["red"];
+	        this.korisnicko_ime = source["korisnicko_ime"];
+	        this.email = source["email"];
+	        this.ime = source["ime"];
+	        this.prezime = source["prezime"];
+	        this.uloga = source["uloga"];
+	        this.greske = source["greske"];
+	        this.aktivacija = this.convertValues(source["aktivacija"], ActivationCode);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class UserImportReport {
+	    proba: boolean;
+	    redovi: UserImportRow[];
+	    ispravni: number;
+	    kreirani: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new UserImportReport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.proba = source["proba"];
+	        this.redovi = this.convertValues(source["redovi"], UserImportRow);
+	        this.ispravni = source["ispravni"];
+	        this.kreirani = source["kreirani"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
+export namespace time {
+	
+	export class Time {
+	
+	
+	    static createFrom(source: any = {}) {
+	        return new Time(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	
+	    }
+	}
 
 }
 
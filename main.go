@@ -6,16 +6,25 @@ import (
 	"embed"
 	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"os"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/cane/research-institute-system/backend/config"
+	"github.com/cane/research-institute-system/backend/logging"
+	"github.com/cane/research-institute-system/backend/migrations"
 	"github.com/cane/research-institute-system/backend/models"
 	"github.com/cane/research-institute-system/backend/repositories"
+	"github.com/cane/research-institute-system/backend/schemacheck"
 	"github.com/cane/research-institute-system/backend/services"
+	"github.com/cane/research-institute-system/database"
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
 	"github.com/wailsapp/wails/v2/pkg/options/assetserver"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
 
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
@@ -24,15 +33,31 @@ import (
 //go:embed all:frontend/dist
 var assets embed.FS
 
+var errNotConnected = errors.New("sistem nije povezan sa bazom podataka")
+
 // App struct
 type App struct {
-	ctx             context.Context
-	db              *sql.DB
-	authService     *services.AuthService
-	documentService *services.DocumentService
-	userRepo        *repositories.UserRepository
-	projectRepo     *repositories.ProjectRepository
-	currentUser     *models.User
+	ctx              context.Context
+	cfg              *config.Config
+	logFile          io.Closer
+	db               *sql.DB
+	authService      *services.AuthService
+	documentService  *services.DocumentService
+	projectService   *services.ProjectService
+	taskService      *services.TaskService
+	workflowService  *services.WorkflowService
+	userService      *services.UserService
+	roleService      *services.RoleService
+	unitService      *services.UnitService
+	guestService     *services.GuestService
+	profileService   *services.ProfileService
+	analyticsService *services.AnalyticsService
+	sessions         *services.SessionManager
+	authz            *services.Authorizer
+
+	mu           sync.RWMutex
+	sessionToken string // token of the session opened in this window
+	adminToken   string // administrator's own session while sessionToken acts as another user
 }
 
 // NewApp creates a new App application struct
@@ -40,25 +65,9 @@ func NewApp() *App {
 	return &App{}
 }
 
-// getEnvOrDefault gets environment variable or returns default value
-func getEnvOrDefault(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return defaultValue
-}
-
-// maskPassword masks password for logging
-func maskPassword(password string) string {
-	if len(password) <= 2 {
-		return "***"
-	}
-	return password[:2] + "***"
-}
-
 // testDatabaseConnections tries different connection configurations
 func (a *App) testDatabaseConnections() {
-	log.Printf("=== DIJAGNOSTIKA KONEKCIJE ===")
+	slog.Info("connection diagnostics started")
 
 	// Test različitih konfiguracija
 	configs := []struct {
@@ -75,14 +84,14 @@ func (a *App) testDatabaseConnections() {
 	}
 
 	for _, config := range configs {
-		log.Printf("Testiram %s: %s@%s/%s", config.name, config.user, config.host, config.dbname)
+		probe := slog.With("probe", config.name, "target", config.user+"@"+config.host+"/"+config.dbname)
 
 		connStr := fmt.Sprintf("postgres://%s:%s@%s/%s?sslmode=disable",
 			config.user, config.password, config.host, config.dbname)
 
 		db, err := sql.Open("postgres", connStr)
 		if err != nil {
-			log.Printf("  ❌ Open greška: %v", err)
+			probe.Info("connection probe failed", "stage", "open", "error", err)
 			continue
 		}
 
@@ -90,27 +99,44 @@ func (a *App) testDatabaseConnections() {
 		db.Close()
 
 		if err != nil {
-			log.Printf("  ❌ Ping greška: %v", err)
+			probe.Info("connection probe failed", "stage", "ping", "error", err)
 		} else {
-			log.Printf("  ✅ USPEŠNO!")
+			probe.Info("connection probe succeeded")
 		}
 	}
 
-	log.Printf("=== KRAJ DIJAGNOSTIKE ===")
+	slog.Info("connection diagnostics finished")
 }
 
 // OnStartup is called when the app starts up
 func (a *App) OnStartup(ctx context.Context) {
 	a.ctx = ctx
 
-	log.Printf("=== POKRETANJE APLIKACIJE ===")
-
 	// Load .env file if it exists
-	if err := godotenv.Load(); err != nil {
-		log.Printf("Upozorenje: .env fajl nije pronađen (%v), koristim default/environment varijable", err)
+	envErr := godotenv.Load()
+
+	// Defaults < riis.json / RIIS_CONFIG < environment; the desktop app has no flags
+	cfg, _, err := config.Load(nil)
+	if err != nil {
+		slog.Error("configuration rejected", "error", err)
+		runtime.MessageDialog(ctx, runtime.MessageDialogOptions{
+			Type:    runtime.ErrorDialog,
+			Title:   "Neispravna konfiguracija",
+			Message: fmt.Sprintf("Aplikacija ne može da se poveže sa bazom dok se konfiguracija ne ispravi.\n\n%v", err),
+		})
+		return
+	}
+	a.cfg = cfg
+
+	// From here on the log has the configured level, format and file
+	if _, logFile, err := logging.Setup(cfg.Log); err != nil {
+		slog.Error("log setup failed, logging to stderr", "error", err)
 	} else {
-		log.Printf("✅ .env fajl uspešno učitan")
+		a.logFile = logFile
 	}
+	slog.Info("application starting", "env_file_loaded", envErr == nil, "log_level", cfg.Log.Level)
+
+	a.sessions = services.NewSessionManager(cfg.Auth.SessionIdleTimeout, cfg.Auth.SessionMaxLifetime)
 
 	// Initialize database
 	a.initializeDatabase()
@@ -127,58 +153,136 @@ func (a *App) OnShutdown(ctx context.Context) {
 	if a.db != nil {
 		a.db.Close()
 	}
+	if a.logFile != nil {
+		a.logFile.Close()
+	}
 }
 
 // initializeDatabase initializes database connection
 func (a *App) initializeDatabase() {
 	// Initialize database connection with better error handling
-	// Prioritet: .env file > environment variables > defaults
-	dbHost := getEnvOrDefault("DB_HOST", "localhost")
-	dbPort := getEnvOrDefault("DB_PORT", "5432")
-	dbUser := getEnvOrDefault("DB_USER", "postgres")
-	dbPassword := getEnvOrDefault("DB_PASSWORD", "123")
-	dbName := getEnvOrDefault("DB_NAME", "research_institute")
-
-	log.Printf("Pokušavam konekciju na bazu:")
-	log.Printf("  Host: %s", dbHost)
-	log.Printf("  Port: %s", dbPort)
-	log.Printf("  User: %s", dbUser)
-	log.Printf("  Database: %s", dbName)
-	log.Printf("  Password: %s", maskPassword(dbPassword))
-
-	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		dbHost, dbPort, dbUser, dbPassword, dbName)
-
-	db, err := sql.Open("postgres", connStr)
+	dbConfig := a.cfg.Database
+
+	logger := slog.With("database", dbConfig.Target())
+	logger.Info("connecting to database")
+
+	db, err := dbConfig.Open()
 	if err != nil {
-		log.Printf("❌ GREŠKA: Failed to open database connection: %v", err)
-		log.Printf("Application will continue without database. To configure database:")
-		log.Printf("1. Install PostgreSQL")
-		log.Printf("2. Create database 'research_institute'")
-		log.Printf("3. Set environment variables: DB_HOST, DB_USER, DB_PASSWORD, DB_NAME")
-		log.Printf("4. Run the SQL schema from database/schema.sql")
+		logger.Error("database connection not opened, continuing without database", "error", err,
+			"hint", "install PostgreSQL, create the database and set DB_* or DATABASE_URL in .env or riis.json; migrations create the schema")
 		a.testDatabaseConnections()
 		return
 	}
 
 	// Test the connection
-	log.Printf("Testiram konekciju...")
 	if err := db.Ping(); err != nil {
-		log.Printf("❌ GREŠKA: Failed to ping database: %v", err)
-		log.Printf("Database connection string (masked): postgres://%s:***@%s/%s?sslmode=disable", dbUser, dbHost, dbName)
-		log.Printf("Application will continue without database.")
+		logger.Error("database not reachable, continuing without database", "error", err)
 		a.testDatabaseConnections()
 		return
 	}
 
 	a.db = db
-	log.Printf("Successfully connected to PostgreSQL database: %s", dbName) // Initialize repositories
-	a.userRepo = repositories.NewUserRepository(db)
-	a.projectRepo = repositories.NewProjectRepository(db)
+	logger.Info("connected to database")
+
+	a.migrateDatabase(dbConfig.AutoMigrate)
+	a.checkSchema()
+
+	// Initialize repositories and services
+	stores := repositories.NewPostgresStores(db)
+	authz, err := services.NewAuthorizer(a.ctx, stores.Roles)
+	if err != nil {
+		logger.Error("role permissions not loaded, continuing without services", "error", err)
+		return
+	}
+	if _, err := os.Stat(a.cfg.Auth.PolicyPath); err == nil {
+		slog.Warn("permission policy file is no longer read, roles are kept in the database",
+			"path", a.cfg.Auth.PolicyPath, "hint", "apply it once with riis-admin role import-policy")
+	}
+	a.authz = authz
+	svc := services.New(a.cfg, stores, a.sessions, authz)
+
+	a.authService = svc.Auth
+	a.documentService = svc.Documents
+	a.projectService = svc.Projects
+	a.taskService = svc.Tasks
+	a.workflowService = svc.Workflows
+	a.userService = svc.Users
+	a.roleService = svc.Roles
+	a.unitService = svc.Units
+	a.guestService = svc.Guests
+	a.profileService = svc.Profile
+	a.analyticsService = svc.Analytics
+
+	// Guest accounts end on time even if no server process runs the sweep
+	go svc.Guests.RunGuestSweep(a.ctx, a.cfg.Auth.GuestSweepInterval)
+}
+
+// migrateDatabase applies pending schema migrations, either automatically or
+// after the user confirms them in a dialog
+func (a *App) migrateDatabase(auto bool) {
+	migrator, err := migrations.New(a.db, database.Migrations, "migrations")
+	if err != nil {
+		slog.Error("migrations not loaded", "error", err)
+		return
+	}
+
+	pending, err := migrator.Pending(a.ctx)
+	if err != nil {
+		slog.Error("migration state not read", "error", err)
+		return
+	}
+	if len(pending) == 0 {
+		slog.Info("database schema is up to date")
+		return
+	}
+
+	names := make([]string, 0, len(pending))
+	for _, m := range pending {
+		names = append(names, fmt.Sprintf("%04d_%s", m.Version, m.Name))
+	}
+	slog.Info("pending migrations", "migrations", strings.Join(names, ", "))
+
+	if !auto {
+		answer, err := runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
+			Type:          runtime.QuestionDialog,
+			Title:         "Ažuriranje baze podataka",
+			Message:       fmt.Sprintf("Baza zahteva %d migracij(e):\n%s\n\nPrimeniti ih sada?", len(pending), strings.Join(names, "\n")),
+			Buttons:       []string{"Yes", "No"},
+			DefaultButton: "Yes",
+		})
+		if err != nil || answer != "Yes" {
+			slog.Warn("migrations declined, the application may not work correctly")
+			return
+		}
+	}
+
+	applied, err := migrator.Up(a.ctx)
+	for _, m := range applied {
+		slog.Info("applied migration", "version", m.Version, "name", m.Name)
+	}
+	if err != nil {
+		slog.Error("migration failed", "error", err)
+	}
+}
+
+// checkSchema prepares every registered service query and logs the ones the
+// database rejects
+func (a *App) checkSchema() {
+	report, err := schemacheck.Check(a.ctx, a.db)
+	if err != nil {
+		slog.Error("schema check not run", "error", err)
+		return
+	}
+
+	if report.OK() {
+		slog.Info("schema check passed", "queries", report.Checked)
+		return
+	}
 
-	// Initialize services
-	a.authService = services.NewAuthService(a.userRepo)
-	a.documentService = services.NewDocumentService(db)
+	slog.Error("schema check failed", "failed", len(report.Problems), "queries", report.Checked)
+	for _, problem := range report.Problems {
+		slog.Error("query does not match the database", "query", problem.Query, "problem", problem.Message)
+	}
 }
 
 // Login authenticates a user
@@ -190,13 +294,22 @@ func (a *App) Login(username, password string) (*services.LoginResponse, error)
 		}, nil
 	}
 
-	response, err := a.authService.Login(services.LoginRequest{
+	response, err := a.authService.Login(a.requestContext(), services.LoginRequest{
 		Username: username,
 		Password: password,
 	})
 
 	if err == nil && response.Success {
-		a.currentUser = response.User
+		a.mu.Lock()
+		previous, admin := a.sessionToken, a.adminToken
+		a.sessionToken, a.adminToken = response.Token, ""
+		a.mu.Unlock()
+
+		for _, token := range []string{previous, admin} {
+			if token != "" {
+				a.authService.Logout(token)
+			}
+		}
 	}
 
 	return response, err
@@ -204,225 +317,370 @@ func (a *App) Login(username, password string) (*services.LoginResponse, error)
 
 // Logout logs out the current user
 func (a *App) Logout() {
-	a.currentUser = nil
+	a.mu.Lock()
+	token, admin := a.sessionToken, a.adminToken
+	a.sessionToken, a.adminToken = "", ""
+	a.mu.Unlock()
+
+	for _, token := range []string{token, admin} {
+		if token != "" {
+			a.sessions.Revoke(token)
+		}
+	}
 }
 
 // GetCurrentUser returns the currently logged in user
 func (a *App) GetCurrentUser() *models.User {
-	return a.currentUser
+	user, err := a.currentUser()
+	if err != nil {
+		return nil
+	}
+	return user
 }
 
-// TestConnection tests if the backend is working
-func (a *App) TestConnection() map[string]interface{} {
-	result := make(map[string]interface{})
-
-	result["backend_status"] = "ok"
-	result["timestamp"] = time.Now().Format("2006-01-02 15:04:05")
-
-	if a.db != nil {
-		err := a.db.Ping()
-		if err != nil {
-			result["database_status"] = "error"
-			result["database_error"] = err.Error()
-		} else {
-			result["database_status"] = "connected"
+// currentToken returns the session token held by this window
+func (a *App) currentToken() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.sessionToken
+}
 
-			// Test basic query
-			var count int
-			err = a.db.QueryRow("SELECT COUNT(*) FROM Korisnici").Scan(&count)
-			if err != nil {
-				result["query_test"] = "error: " + err.Error()
-			} else {
-				result["query_test"] = fmt.Sprintf("ok - %d users in database", count)
-			}
-		}
-	} else {
-		result["database_status"] = "not_connected"
+// currentUser resolves the session of this window and reloads its user, so
+// expired sessions and deactivated accounts are rejected on every call
+func (a *App) currentUser() (*models.User, error) {
+	if a.authService == nil {
+		return nil, errNotConnected
 	}
 
-	if a.authService != nil {
-		result["auth_service"] = "initialized"
-	} else {
-		result["auth_service"] = "not_initialized"
+	principal, err := a.authService.Authenticate(a.requestContext(), a.currentToken())
+	if err != nil {
+		return nil, err
 	}
-
-	return result
+	return principal.User, nil
 }
 
-// CreateUser creates a new user (Admin only)
-func (a *App) CreateUser(user *models.User, tempPassword string) error {
-	if a.currentUser == nil || a.currentUser.NazivUloge != "Administrator" {
-		return errors.New("nemate dozvolu za kreiranje korisnika")
+// callContext resolves the current user and returns the context for service
+// calls made on the user's behalf. Its log lines carry a fresh request ID and
+// the user. While an administrator acts as the user, the call is written to
+// the activity log
+func (a *App) callContext() (context.Context, error) {
+	if a.authService == nil {
+		return nil, errNotConnected
 	}
 
-	if a.authService == nil {
-		return errors.New("sistem nije povezan sa bazom podataka")
+	ctx := a.requestContext()
+	principal, err := a.authService.Authenticate(ctx, a.currentToken())
+	if err != nil {
+		return nil, err
 	}
 
-	return a.authService.CreateUser(user, tempPassword)
+	ctx = services.WithPrincipal(ctx, principal)
+	a.authService.RecordImpersonatedCall(ctx, boundMethod())
+	return ctx, nil
 }
 
-// CompleteFirstTimeSetup completes first-time login setup
-func (a *App) CompleteFirstTimeSetup(username, newPassword string) map[string]interface{} {
-	result := make(map[string]interface{})
+// twoFactorContext is callContext for setting up the second factor, which a
+// login waiting for the setup may do before it has a full session
+func (a *App) twoFactorContext() (context.Context, error) {
+	ctx, err := a.callContext()
+	if errors.Is(err, services.ErrTwoFactorRequired) {
+		return a.requestContext(), nil
+	}
+	return ctx, err
+}
 
-	log.Printf("🔧 CompleteFirstTimeSetup pozvana za korisnika: %s", username)
+// requestContext returns the base context with a new request ID, one per
+// call from the frontend
+func (a *App) requestContext() context.Context {
+	return logging.WithRequestID(a.baseContext(), logging.NewRequestID())
+}
 
-	if a.authService == nil {
-		log.Printf("❌ AuthService nije inicijalizovan")
-		result["success"] = false
-		result["message"] = "Sistem nije povezan sa bazom podataka"
-		return result
+// baseContext returns the Wails context, or a background context before
+// startup has run
+func (a *App) baseContext() context.Context {
+	if a.ctx == nil {
+		return context.Background()
 	}
+	return a.ctx
+}
 
-	log.Printf("🔍 Pozivam CompleteFirstTimeSetupByUsername...")
-	err := a.authService.CompleteFirstTimeSetupByUsername(username, newPassword)
+// authorize checks a permission for operations that go straight to a repository
+func (a *App) authorize(perm services.Permission) (*models.User, error) {
+	ctx, err := a.callContext()
 	if err != nil {
-		log.Printf("❌ Greška u CompleteFirstTimeSetupByUsername: %v", err)
-		result["success"] = false
-		result["message"] = err.Error()
-		return result
+		return nil, err
 	}
 
-	log.Printf("✅ CompleteFirstTimeSetup uspešno završena")
-	result["success"] = true
-	result["message"] = "Lozinka je uspešno postavljena"
-	return result
+	return a.authz.Require(ctx, perm)
 }
 
-// GetAllUsers returns all users (Admin only)
-func (a *App) GetAllUsers() ([]models.User, error) {
-	if a.currentUser == nil || a.currentUser.NazivUloge != "Administrator" {
-		return nil, errors.New("nemate dozvolu za pregled korisnika")
+// ChangePassword changes the password of the current user after checking
+// the current one, and ends all of the user's other sessions
+func (a *App) ChangePassword(currentPassword, newPassword string) error {
+	ctx, err := a.callContext()
+	if err != nil {
+		return err
 	}
 
-	if a.userRepo == nil {
-		return nil, errors.New("sistem nije povezan sa bazom podataka")
+	if a.profileService == nil {
+		return errNotConnected
 	}
 
-	return a.userRepo.GetAll()
+	return a.profileService.ChangePassword(ctx, currentPassword, newPassword, services.LocalSource, a.currentToken())
 }
 
-// GetUserProjects returns projects for the current user
-func (a *App) GetUserProjects() ([]models.Project, error) {
-	if a.currentUser == nil {
-		return nil, errors.New("niste prijavljeni")
+// CheckPassword returns the password policy violations of a new password
+// for the signed-in user, also during first-time setup
+func (a *App) CheckPassword(password string) ([]services.PasswordViolation, error) {
+	if a.authService == nil {
+		return nil, errNotConnected
 	}
 
-	if a.projectRepo == nil {
-		return nil, errors.New("sistem nije povezan sa bazom podataka")
+	session, err := a.sessions.Resolve(a.currentToken())
+	if err != nil {
+		return nil, err
 	}
-
-	return a.projectRepo.GetByUserID(a.currentUser.KorisnikID)
+	return a.authService.CheckPassword(a.requestContext(), session.KorisnikID, password)
 }
 
-// CreateProject creates a new project
-func (a *App) CreateProject(project *models.Project) error {
-	if a.currentUser == nil || (a.currentUser.NazivUloge != "Rukovodilac projekta" && a.currentUser.NazivUloge != "Administrator") {
-		return errors.New("nemate dozvolu za kreiranje projekata")
+// VerifyTwoFactor completes a login that returned TWO_FACTOR_REQUIRED with
+// a code from the authenticator app or a recovery code
+func (a *App) VerifyTwoFactor(code string) (*services.LoginResponse, error) {
+	if a.authService == nil {
+		return nil, errNotConnected
 	}
 
-	if a.projectRepo == nil {
-		return errors.New("sistem nije povezan sa bazom podataka")
+	response, err := a.authService.VerifyTwoFactor(a.requestContext(), a.currentToken(), code, services.LocalSource)
+	if err == nil && response.Success {
+		a.setSessionToken(response.Token)
 	}
+	return response, err
+}
 
-	project.RukovodilaID = &a.currentUser.KorisnikID
-	project.Status = "Aktivan"
+// EnrollTwoFactor starts setting up the second factor of the signed-in
+// user, also when the login returned TWO_FACTOR_SETUP_REQUIRED
+func (a *App) EnrollTwoFactor() (*services.TwoFactorEnrollment, error) {
+	ctx, err := a.twoFactorContext()
+	if err != nil {
+		return nil, err
+	}
 
-	return a.projectRepo.Create(project)
+	return a.authService.EnrollTwoFactor(ctx, a.currentToken())
 }
 
-// Document Management Methods
+// ConfirmTwoFactor turns the enrolled second factor on and returns the
+// recovery codes. A login waiting for the setup continues with a normal
+// session
+func (a *App) ConfirmTwoFactor(code string) (*services.TwoFactorSetup, error) {
+	ctx, err := a.twoFactorContext()
+	if err != nil {
+		return nil, err
+	}
 
-// GetAllDocuments returns all documents
-func (a *App) GetAllDocuments() ([]models.Dokumenti, error) {
-	if a.currentUser == nil {
-		return nil, errors.New("niste prijavljeni")
+	setup, err := a.authService.ConfirmTwoFactor(ctx, a.currentToken(), code)
+	if err == nil && setup.Prijava != nil {
+		a.setSessionToken(setup.Prijava.Token)
 	}
+	return setup, err
+}
 
-	if a.documentService == nil {
-		return nil, errors.New("sistem nije povezan sa bazom podataka")
+// GetTwoFactorStatus describes the second factor of the current user
+func (a *App) GetTwoFactorStatus() (*services.TwoFactorStatus, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return nil, err
 	}
 
-	return a.documentService.GetAllDocuments()
+	principal, _ := services.PrincipalFrom(ctx)
+	return a.authService.GetTwoFactorStatus(ctx, principal.User.KorisnikID)
 }
 
-// GetDocumentByID returns a specific document by ID
-func (a *App) GetDocumentByID(documentID int) (models.Dokumenti, error) {
-	if a.currentUser == nil {
-		return models.Dokumenti{}, errors.New("niste prijavljeni")
+// CreateAccessToken creates a personal access token of the current user for
+// scripts using the REST API. The token is shown only in this result
+func (a *App) CreateAccessToken(req services.AccessTokenRequest) (*services.NewAccessToken, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return nil, err
+	}
+
+	return a.authService.CreateAccessToken(ctx, req)
+}
+
+// GetMyAccessTokens lists the personal access tokens of the current user
+func (a *App) GetMyAccessTokens() ([]models.AccessToken, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return nil, err
 	}
 
-	if a.documentService == nil {
-		return models.Dokumenti{}, errors.New("sistem nije povezan sa bazom podataka")
+	principal, _ := services.PrincipalFrom(ctx)
+	return a.authService.ListAccessTokens(ctx, principal.User.KorisnikID)
+}
+
+// RevokeAccessToken ends a personal access token
+func (a *App) RevokeAccessToken(tokenID int) error {
+	ctx, err := a.callContext()
+	if err != nil {
+		return err
 	}
 
-	return a.documentService.GetDocumentByID(documentID)
+	return a.authService.RevokeAccessToken(ctx, tokenID)
 }
 
-// GetDocumentVersions returns all versions of a document
-func (a *App) GetDocumentVersions(documentID int) ([]models.VerzijeDokumenata, error) {
-	if a.currentUser == nil {
-		return nil, errors.New("niste prijavljeni")
+// setSessionToken replaces the session of this window with a newer one
+func (a *App) setSessionToken(token string) {
+	a.mu.Lock()
+	a.sessionToken = token
+	a.mu.Unlock()
+}
+
+// GetMyPermissions returns the permissions the current session may use,
+// without the security permissions while an administrator acts as the user
+func (a *App) GetMyPermissions() ([]services.Permission, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return nil, err
 	}
 
-	if a.documentService == nil {
-		return nil, errors.New("sistem nije povezan sa bazom podataka")
+	principal, _ := services.PrincipalFrom(ctx)
+	return a.authz.PermissionsOf(principal), nil
+}
+
+// GetAllPermissions returns every permission known to the system
+func (a *App) GetAllPermissions() []services.Permission {
+	return services.AllPermissions
+}
+
+// GetPermissionPolicy returns the permissions each role grants in all
+// projects
+func (a *App) GetPermissionPolicy() (services.Policy, error) {
+	ctx, err := a.callContext()
+	if err != nil {
+		return services.Policy{}, err
 	}
 
-	return a.documentService.GetDocumentVersions(documentID)
+	return a.roleService.GetPolicy(ctx)
 }
 
-// GetDocumentTags returns all tags for a document
-func (a *App) GetDocumentTags(documentID int) ([]models.Tagovi, error) {
-	if a.currentUser == nil {
-		return nil, errors.New("niste prijavljeni")
+// UpdatePermissionPolicy replaces the permissions the named roles grant in
+// all projects
+func (a *App) UpdatePermissionPolicy(policy services.Policy) error {
+	ctx, err := a.callContext()
+	if err != nil {
+		return err
 	}
 
-	if a.documentService == nil {
-		return nil, errors.New("sistem nije povezan sa bazom podataka")
+	return a.roleService.UpdatePolicy(ctx, policy)
+}
+
+// GetActiveSessions lists all active sessions
+func (a *App) GetActiveSessions() ([]services.Session, error) {
+	if _, err := a.authorize(services.PermSessionManage); err != nil {
+		return nil, err
 	}
 
-	return a.documentService.GetDocumentTags(documentID)
+	return a.sessions.List(), nil
 }
 
-// UploadDocument uploads a new document
-func (a *App) UploadDocument(req models.UploadDocumentRequest, fileData []byte, fileName string) error {
-	if a.currentUser == nil {
-		return errors.New("niste prijavljeni")
+// RevokeSession ends the session with the given ID
+func (a *App) RevokeSession(sessionID string) error {
+	if _, err := a.authorize(services.PermSessionManage); err != nil {
+		return err
 	}
 
-	if a.documentService == nil {
-		return errors.New("sistem nije povezan sa bazom podataka")
+	if !a.sessions.RevokeByID(sessionID) {
+		return fmt.Errorf("sesija %s nije pronađena", sessionID)
 	}
 
-	return a.documentService.UploadDocument(req, fileData, fileName, a.currentUser.KorisnikID)
+	return nil
 }
 
-// UpdateDocument updates an existing document
-func (a *App) UpdateDocument(documentID int, req models.UploadDocumentRequest) error {
-	if a.currentUser == nil {
-		return errors.New("niste prijavljeni")
+// TestConnection tests if the backend is working
+func (a *App) TestConnection() map[string]interface{} {
+	result := make(map[string]interface{})
+
+	result["backend_status"] = "ok"
+	result["timestamp"] = time.Now().Format("2006-01-02 15:04:05")
+
+	if a.db != nil {
+		err := a.db.Ping()
+		if err != nil {
+			result["database_status"] = "error"
+			result["database_error"] = err.Error()
+		} else {
+			result["database_status"] = "connected"
+
+			// Test basic query
+			var count int
+			err = a.db.QueryRow("SELECT COUNT(*) FROM Korisnici").Scan(&count)
+			if err != nil {
+				result["query_test"] = "error: " + err.Error()
+			} else {
+				result["query_test"] = fmt.Sprintf("ok - %d users in database", count)
+			}
+
+			// Prepare every registered service query against the live schema
+			report, err := schemacheck.Check(context.Background(), a.db)
+			if err != nil {
+				result["schema_check"] = "error: " + err.Error()
+			} else {
+				result["schema_check"] = report
+			}
+		}
+	} else {
+		result["database_status"] = "not_connected"
 	}
 
-	if a.documentService == nil {
-		return errors.New("sistem nije povezan sa bazom podataka")
+	if a.authService != nil {
+		result["auth_service"] = "initialized"
+	} else {
+		result["auth_service"] = "not_initialized"
 	}
 
-	return a.documentService.UpdateDocument(documentID, req)
+	return result
 }
 
-// DeleteDocument deletes a document
-func (a *App) DeleteDocument(documentID int) error {
-	if a.currentUser == nil {
-		return errors.New("niste prijavljeni")
+// CompleteFirstTimeSetup sets the first password of the account signed in
+// with an activation code and replaces the activation session of this window
+// with a normal one. The account is the one that signed in; username only
+// has to match it
+func (a *App) CompleteFirstTimeSetup(username, newPassword string) map[string]interface{} {
+	result := make(map[string]interface{})
+
+	if a.authService == nil {
+		result["success"] = false
+		result["message"] = "Sistem nije povezan sa bazom podataka"
+		return result
 	}
 
-	if a.documentService == nil {
-		return errors.New("sistem nije povezan sa bazom podataka")
+	ctx := a.requestContext()
+	token := a.currentToken()
+
+	var response *services.LoginResponse
+	session, err := a.sessions.Resolve(token)
+	if err == nil && session.KorisnickoIme != username {
+		err = services.ErrNoSession
+	}
+	if err == nil {
+		response, err = a.authService.CompleteActivation(ctx, token, newPassword)
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "first-time setup failed", "username", username, "error", err)
+		result["success"] = false
+		result["message"] = err.Error()
+		var policyErr *services.PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			result["violations"] = policyErr.Violations
+		}
+		return result
 	}
 
-	return a.documentService.DeleteDocument(documentID)
+	a.setSessionToken(response.Token)
+
+	result["success"] = true
+	result["message"] = response.Message
+	result["user"] = response.User
+	return result
 }
 
 func main() {
@@ -448,6 +706,6 @@ func main() {
 	})
 
 	if err != nil {
-		println("Error:", err.Error())
+		slog.Error("application failed", "error", err)
 	}
 }
@@ -9,8 +9,7 @@ $dbUser = "postgres"
 $dbName = "research_institute"
 $dbPassword = "123" # IMPORTANT: Change this to your actual PostgreSQL password
 
-# Path to the SQL files
-$schemaFile = ".\database\schema.sql"
+# Path to the SQL files (the schema itself comes from database\migrations)
 $dummyFile = ".\database\dummy_data.sql"
 
 # --- Execution ---
@@ -40,8 +39,14 @@ try {
     psql -h $dbHost -p $dbPort -U $dbUser -d $dbName -c $dropCmd
     Write-Host "All tables dropped." -ForegroundColor Yellow
 
-    # Recreate schema
-    psql -h $dbHost -p $dbPort -U $dbUser -d $dbName -f $schemaFile
+    # Recreate schema by applying every migration
+    $env:DB_HOST = $dbHost
+    $env:DB_PORT = $dbPort
+    $env:DB_USER = $dbUser
+    $env:DB_PASSWORD = $dbPassword
+    $env:DB_NAME = $dbName
+    go run ./cmd/riis-migrate up
+    if ($LASTEXITCODE -ne 0) { throw "Migrations failed." }
     Write-Host "Schema recreated." -ForegroundColor Yellow
 
     # Load dummy data